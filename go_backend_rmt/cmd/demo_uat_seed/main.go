@@ -221,11 +221,11 @@ func seedDemoDataset(db *sql.DB) (*datasetSummary, error) {
 		return nil, err
 	}
 
-	roleIDs, err := collectRoleIDs(db)
+	roleIDs, err := collectRoleIDs(db, company.CompanyID)
 	if err != nil {
 		return nil, err
 	}
-	viewerRoleID, err := ensureViewerRole(db, roleSvc)
+	viewerRoleID, err := ensureViewerRole(db, roleSvc, company.CompanyID)
 	if err != nil {
 		return nil, err
 	}
@@ -291,7 +291,7 @@ func seedDemoDataset(db *sql.DB) (*datasetSummary, error) {
 		return nil, err
 	}
 
-	if _, err := cashSvc.OpenCashRegister(company.CompanyID, mainStoreID, adminResp.UserID, 500, "", "demo-open-main", nil, nil); err != nil {
+	if _, err := cashSvc.OpenCashRegister(company.CompanyID, mainStoreID, adminResp.UserID, &models.OpenCashRegisterRequest{OpeningBalance: 500}, "", "demo-open-main", nil, nil); err != nil {
 		return nil, err
 	}
 	moveNote := "Float top-up"
@@ -304,10 +304,13 @@ func seedDemoDataset(db *sql.DB) (*datasetSummary, error) {
 		return nil, err
 	}
 	denoms := models.JSONB{"100": 4, "50": 2, "20": 5}
-	if err := cashSvc.CloseCashRegister(company.CompanyID, mainStoreID, adminResp.UserID, 650, &denoms, "", "demo-close-main", nil, nil); err != nil {
+	if _, err := cashSvc.CloseCashRegister(company.CompanyID, mainStoreID, adminResp.UserID, &models.CloseCashRegisterRequest{
+		ClosingBalance: 650,
+		Denominations:  &denoms,
+	}, "", "demo-close-main", nil, nil); err != nil {
 		return nil, err
 	}
-	if _, err := cashSvc.OpenCashRegister(company.CompanyID, secondaryStoreID, adminResp.UserID, 300, "", "demo-open-secondary", nil, nil); err != nil {
+	if _, err := cashSvc.OpenCashRegister(company.CompanyID, secondaryStoreID, adminResp.UserID, &models.OpenCashRegisterRequest{OpeningBalance: 300}, "", "demo-open-secondary", nil, nil); err != nil {
 		return nil, err
 	}
 
@@ -748,8 +751,8 @@ func renameDefaultLocation(db *sql.DB, companyID int, name, address string) (int
 	return locationID, nil
 }
 
-func collectRoleIDs(db *sql.DB) (map[string]int, error) {
-	rows, err := db.Query(`SELECT name, role_id FROM roles`)
+func collectRoleIDs(db *sql.DB, companyID int) (map[string]int, error) {
+	rows, err := db.Query(`SELECT name, role_id FROM roles WHERE company_id = $1`, companyID)
 	if err != nil {
 		return nil, err
 	}
@@ -766,16 +769,16 @@ func collectRoleIDs(db *sql.DB) (map[string]int, error) {
 	return result, rows.Err()
 }
 
-func ensureViewerRole(db *sql.DB, roleSvc *services.RoleService) (int, error) {
+func ensureViewerRole(db *sql.DB, roleSvc *services.RoleService, companyID int) (int, error) {
 	var roleID int
-	err := db.QueryRow(`SELECT role_id FROM roles WHERE name = 'Viewer'`).Scan(&roleID)
+	err := db.QueryRow(`SELECT role_id FROM roles WHERE company_id = $1 AND name = 'Viewer'`, companyID).Scan(&roleID)
 	if err == nil {
 		return roleID, nil
 	}
 	if err != sql.ErrNoRows {
 		return 0, err
 	}
-	role, err := roleSvc.CreateRole(&models.CreateRoleRequest{
+	role, err := roleSvc.CreateRole(companyID, &models.CreateRoleRequest{
 		Name:        "Viewer",
 		Description: "Read-only demo viewer",
 	})
@@ -792,7 +795,7 @@ func ensureViewerRole(db *sql.DB, roleSvc *services.RoleService) (int, error) {
 			permissionIDs = append(permissionIDs, perm.PermissionID)
 		}
 	}
-	if err := roleSvc.AssignPermissions(role.RoleID, &models.AssignPermissionsRequest{PermissionIDs: permissionIDs}); err != nil {
+	if err := roleSvc.AssignPermissions(companyID, role.RoleID, &models.AssignPermissionsRequest{PermissionIDs: permissionIDs}); err != nil {
 		return 0, err
 	}
 	return role.RoleID, nil
@@ -14,6 +14,7 @@ import (
 	"erp-backend/internal/database"
 	"erp-backend/internal/middleware"
 	"erp-backend/internal/routes"
+	"erp-backend/internal/services"
 	"erp-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -97,6 +98,35 @@ func main() {
 
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
+
+	// Background report jobs
+	reportJobs := services.NewReportJobRunner(cfg.ReportJobWorkers)
+	reportJobs.Start(shutdownCtx)
+
+	// Scheduled report emails
+	reportSubscriptions := services.NewReportSubscriptionRunner()
+	if cfg.ReportSubscriptionsEnabled {
+		reportSubscriptions.Start(shutdownCtx)
+	}
+
+	// Recurring vouchers and expenses
+	recurringTemplates := services.NewRecurringTemplateRunner()
+	if cfg.RecurringTemplatesEnabled {
+		recurringTemplates.Start(shutdownCtx)
+	}
+
+	// Notification deliveries, condition sweeps and retention
+	notifications := services.NewNotificationRunner(cfg.NotificationRetentionDays, cfg.NotificationExpiryWarningDays)
+	if cfg.NotificationsEnabled {
+		notifications.Start(shutdownCtx)
+	}
+
+	// Outbound webhook deliveries
+	webhooks := services.NewWebhookRunner()
+	if cfg.WebhooksEnabled {
+		webhooks.Start(shutdownCtx)
+	}
+
 	<-shutdownCtx.Done()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -104,5 +134,10 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Graceful shutdown failed: %v", err)
 	}
+	reportJobs.Shutdown(5 * time.Second)
+	reportSubscriptions.Shutdown(5 * time.Second)
+	recurringTemplates.Shutdown(5 * time.Second)
+	notifications.Shutdown(5 * time.Second)
+	webhooks.Shutdown(5 * time.Second)
 	log.Println("Server stopped")
 }
@@ -35,6 +35,9 @@ type Config struct {
 	RateLimitKeyPrefix string
 	RateLimitFailOpen  bool
 
+	// Default per-minute limit for API keys without their own
+	APIKeyRateLimitPerMinute int
+
 	// MQTT
 	MQTTBroker   string
 	MQTTClientID string
@@ -52,6 +55,38 @@ type Config struct {
 	MaxUploadSize int64
 	UploadPath    string
 
+	// Object storage
+	StorageBackend      string
+	StorageSigningKey   string
+	StorageSignedURLTTL time.Duration
+	S3Endpoint          string
+	S3Region            string
+	S3Bucket            string
+	S3AccessKeyID       string
+	S3SecretAccessKey   string
+	S3UsePathStyle      bool
+
+	// E-invoicing: seals company signing keys at rest
+	EInvoiceKeySecret string
+
+	// Background report jobs
+	ReportJobWorkers   int
+	ReportJobResultTTL time.Duration
+
+	// Scheduled report emails
+	ReportSubscriptionsEnabled bool
+
+	// Recurring vouchers and expenses
+	RecurringTemplatesEnabled bool
+
+	// Notification center
+	NotificationsEnabled          bool
+	NotificationRetentionDays     int
+	NotificationExpiryWarningDays int
+
+	// Outbound webhooks
+	WebhooksEnabled bool
+
 	// Migrations
 	RunMigrations bool
 	MigrationsDir string
@@ -102,6 +137,8 @@ func Load() *Config {
 		RateLimitKeyPrefix: getEnv("RATE_LIMIT_KEY_PREFIX", "rate_limit"),
 		RateLimitFailOpen:  parseBool("RATE_LIMIT_FAIL_OPEN", true),
 
+		APIKeyRateLimitPerMinute: parseInt("API_KEY_RATE_LIMIT_PER_MINUTE", 600),
+
 		// MQTT
 		MQTTBroker:   getEnv("MQTT_BROKER", "tcp://localhost:1883"),
 		MQTTClientID: getEnv("MQTT_CLIENT_ID", "erp-backend"),
@@ -119,6 +156,32 @@ func Load() *Config {
 		MaxUploadSize: parseSize("MAX_UPLOAD_SIZE", "10MB"),
 		UploadPath:    getEnv("UPLOAD_PATH", "./uploads"),
 
+		// Object storage
+		StorageBackend:      getEnv("STORAGE_BACKEND", "local"),
+		StorageSigningKey:   getEnv("STORAGE_SIGNING_KEY", ""),
+		StorageSignedURLTTL: parseDuration("STORAGE_SIGNED_URL_TTL", "15m"),
+		S3Endpoint:          getEnv("S3_ENDPOINT", ""),
+		S3Region:            getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:            getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:       getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:   getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3UsePathStyle:      parseBool("S3_USE_PATH_STYLE", false),
+
+		EInvoiceKeySecret: getEnv("EINVOICE_KEY_SECRET", ""),
+
+		// Background report jobs
+		ReportJobWorkers:   parseInt("REPORT_JOB_WORKERS", 2),
+		ReportJobResultTTL: parseDuration("REPORT_JOB_RESULT_TTL", "24h"),
+
+		ReportSubscriptionsEnabled: parseBool("REPORT_SUBSCRIPTIONS_ENABLED", true),
+		RecurringTemplatesEnabled:  parseBool("RECURRING_TEMPLATES_ENABLED", true),
+
+		NotificationsEnabled:          parseBool("NOTIFICATIONS_ENABLED", true),
+		NotificationRetentionDays:     parseInt("NOTIFICATION_RETENTION_DAYS", 90),
+		NotificationExpiryWarningDays: parseInt("NOTIFICATION_EXPIRY_WARNING_DAYS", 30),
+
+		WebhooksEnabled: parseBool("WEBHOOKS_ENABLED", true),
+
 		// Migrations
 		RunMigrations: parseBool("RUN_MIGRATIONS", true),
 		MigrationsDir: getEnv("MIGRATIONS_DIR", "migrations"),
@@ -240,6 +303,13 @@ func (c *Config) ProductionReadinessIssues() []string {
 	if (c.RateLimitEnabled || c.SessionLastSeenUseRedis || c.ReadyCheckRedis) && strings.TrimSpace(c.RedisURL) == "" {
 		issues = append(issues, "REDIS_URL is required for the configured production posture")
 	}
+	if len(strings.TrimSpace(c.EInvoiceKeySecret)) < 32 {
+		issues = append(issues, "EINVOICE_KEY_SECRET must be set to at least 32 characters in production")
+	}
+	if strings.EqualFold(strings.TrimSpace(c.StorageBackend), "s3") &&
+		(strings.TrimSpace(c.S3Bucket) == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "") {
+		issues = append(issues, "S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when STORAGE_BACKEND=s3")
+	}
 	return issues
 }
 
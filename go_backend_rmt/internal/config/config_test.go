@@ -30,6 +30,7 @@ func TestValidateProductionReadiness_SucceedsForStrictConfig(t *testing.T) {
 		RedisURL:                "redis://cache.example.com:6379",
 		SessionLastSeenUseRedis: true,
 		ReadyCheckRedis:         true,
+		EInvoiceKeySecret:       "abcdefghijklmnopqrstuvwxyz012345",
 	}
 
 	if err := cfg.ValidateProductionReadiness(); err != nil {
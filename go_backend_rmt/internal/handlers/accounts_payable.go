@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountsPayableHandler exposes supplier aging, payment proposals and payment runs
+type AccountsPayableHandler struct {
+	service *services.AccountsPayableService
+}
+
+func NewAccountsPayableHandler() *AccountsPayableHandler {
+	return &AccountsPayableHandler{service: services.NewAccountsPayableService()}
+}
+
+// GET /accounts-payable/aging
+func (h *AccountsPayableHandler) GetAging(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	var locationID *int
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		id, err := strconv.Atoi(locationParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location_id", err)
+			return
+		}
+		locationID = &id
+	}
+	var asOf *string
+	if value := c.Query("as_of"); value != "" {
+		asOf = &value
+	}
+
+	report, err := h.service.GetAging(companyID, locationID, asOf)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid date") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid as_of date", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get payables aging", err)
+		return
+	}
+	utils.SuccessResponse(c, "Payables aging retrieved successfully", report)
+}
+
+// POST /accounts-payable/payment-proposals
+func (h *AccountsPayableHandler) BuildPaymentProposal(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.PaymentProposalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	proposal, err := h.service.BuildPaymentProposal(companyID, &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid date") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid payment date", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to build payment proposal", err)
+		return
+	}
+	utils.SuccessResponse(c, "Payment proposal built successfully", proposal)
+}
+
+// POST /accounts-payable/payment-runs
+func (h *AccountsPayableHandler) CreatePaymentRun(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.CreateSupplierPaymentRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	run, err := h.service.CreatePaymentRun(companyID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create payment run", err)
+		return
+	}
+	utils.CreatedResponse(c, "Payment run created successfully", run)
+}
+
+// GET /accounts-payable/payment-runs
+func (h *AccountsPayableHandler) ListPaymentRuns(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	runs, err := h.service.ListPaymentRuns(companyID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get payment runs", err)
+		return
+	}
+	utils.SuccessResponse(c, "Payment runs retrieved successfully", runs)
+}
+
+// GET /accounts-payable/payment-runs/:id
+func (h *AccountsPayableHandler) GetPaymentRun(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	runID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid payment run ID", err)
+		return
+	}
+
+	run, err := h.service.GetPaymentRun(companyID, runID)
+	if err != nil {
+		if err.Error() == "payment run not found" {
+			utils.NotFoundResponse(c, "Payment run not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get payment run", err)
+		return
+	}
+	utils.SuccessResponse(c, "Payment run retrieved successfully", run)
+}
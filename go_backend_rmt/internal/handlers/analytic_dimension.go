@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticDimensionHandler configures analytic dimensions (cost centers,
+// projects, ...) and the defaults that tag ledger postings.
+type AnalyticDimensionHandler struct {
+	service *services.AnalyticDimensionService
+}
+
+func NewAnalyticDimensionHandler() *AnalyticDimensionHandler {
+	return &AnalyticDimensionHandler{service: services.NewAnalyticDimensionService()}
+}
+
+// GET /analytic-dimensions
+func (h *AnalyticDimensionHandler) ListDimensions(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	includeInactive := c.Query("include_inactive") == "true"
+
+	dims, err := h.service.ListDimensions(companyID, includeInactive)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to list analytic dimensions", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic dimensions retrieved successfully", dims)
+}
+
+// POST /analytic-dimensions
+func (h *AnalyticDimensionHandler) CreateDimension(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateAnalyticDimensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	dim, err := h.service.CreateDimension(companyID, userID, &req)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to create analytic dimension", err)
+		return
+	}
+	utils.CreatedResponse(c, "Analytic dimension created successfully", dim)
+}
+
+// GET /analytic-dimensions/:id
+func (h *AnalyticDimensionHandler) GetDimension(c *gin.Context) {
+	companyID, _, id, ok := analyticDimensionContext(c)
+	if !ok {
+		return
+	}
+
+	dim, err := h.service.GetDimension(companyID, id)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to get analytic dimension", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic dimension retrieved successfully", dim)
+}
+
+// PUT /analytic-dimensions/:id
+func (h *AnalyticDimensionHandler) UpdateDimension(c *gin.Context) {
+	companyID, userID, id, ok := analyticDimensionContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAnalyticDimensionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	dim, err := h.service.UpdateDimension(companyID, userID, id, &req)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to update analytic dimension", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic dimension updated successfully", dim)
+}
+
+// POST /analytic-dimensions/:id/values
+func (h *AnalyticDimensionHandler) CreateValue(c *gin.Context) {
+	companyID, _, id, ok := analyticDimensionContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAnalyticDimensionValueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	value, err := h.service.CreateValue(companyID, id, &req)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to create analytic dimension value", err)
+		return
+	}
+	utils.CreatedResponse(c, "Analytic dimension value created successfully", value)
+}
+
+// PUT /analytic-dimensions/:id/values/:value_id
+func (h *AnalyticDimensionHandler) UpdateValue(c *gin.Context) {
+	companyID, _, id, ok := analyticDimensionContext(c)
+	if !ok {
+		return
+	}
+	valueID, err := strconv.Atoi(c.Param("value_id"))
+	if err != nil || valueID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid value ID", err)
+		return
+	}
+
+	var req models.UpdateAnalyticDimensionValueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	value, err := h.service.UpdateValue(companyID, id, valueID, &req)
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to update analytic dimension value", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic dimension value updated successfully", value)
+}
+
+// GET /analytic-dimensions/defaults
+func (h *AnalyticDimensionHandler) ListDefaults(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	defaults, err := h.service.ListDefaults(companyID, c.Query("source_type"))
+	if err != nil {
+		respondAnalyticDimensionError(c, "Failed to list analytic defaults", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic defaults retrieved successfully", defaults)
+}
+
+// PUT /analytic-dimensions/defaults
+// Sets the value a location, department, employee or expense category
+// defaults to for one dimension; omit value_id to clear it.
+func (h *AnalyticDimensionHandler) SetDefault(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.SetAnalyticDimensionDefaultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	if err := h.service.SetDefault(companyID, &req); err != nil {
+		respondAnalyticDimensionError(c, "Failed to set analytic default", err)
+		return
+	}
+	utils.SuccessResponse(c, "Analytic default saved successfully", nil)
+}
+
+func analyticDimensionContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid analytic dimension ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondAnalyticDimensionError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler manages the company's API keys for machine-to-machine
+// integrations. Keys are sent as X-API-Key or as a bearer token.
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{service: services.NewAPIKeyService()}
+}
+
+// GET /api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(companyID)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to list API keys", err)
+		return
+	}
+	utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+// POST /api-keys
+// The response carries the key, which is not shown again.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+	if !requireHumanCaller(c) {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(companyID, userID, c.GetInt("role_id"), &req)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to create API key", err)
+		return
+	}
+	utils.CreatedResponse(c, "API key created successfully", key)
+}
+
+// GET /api-keys/:id
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	companyID, _, id, ok := apiKeyContext(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.service.GetAPIKey(companyID, id)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to get API key", err)
+		return
+	}
+	utils.SuccessResponse(c, "API key retrieved successfully", key)
+}
+
+// PUT /api-keys/:id
+func (h *APIKeyHandler) UpdateAPIKey(c *gin.Context) {
+	companyID, userID, id, ok := apiKeyContext(c)
+	if !ok || !requireHumanCaller(c) {
+		return
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	key, err := h.service.UpdateAPIKey(companyID, userID, c.GetInt("role_id"), id, &req)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to update API key", err)
+		return
+	}
+	utils.SuccessResponse(c, "API key updated successfully", key)
+}
+
+// POST /api-keys/:id/rotate
+// The old key keeps working for grace_hours (default 24).
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	companyID, userID, id, ok := apiKeyContext(c)
+	if !ok || !requireHumanCaller(c) {
+		return
+	}
+
+	var req models.RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	key, err := h.service.RotateAPIKey(companyID, userID, id, &req)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to rotate API key", err)
+		return
+	}
+	utils.SuccessResponse(c, "API key rotated successfully", key)
+}
+
+// POST /api-keys/:id/revoke
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	companyID, userID, id, ok := apiKeyContext(c)
+	if !ok || !requireHumanCaller(c) {
+		return
+	}
+
+	key, err := h.service.RevokeAPIKey(companyID, userID, id)
+	if err != nil {
+		respondAPIKeyError(c, "Failed to revoke API key", err)
+		return
+	}
+	utils.SuccessResponse(c, "API key revoked successfully", key)
+}
+
+// requireHumanCaller stops API keys from minting or widening other keys.
+func requireHumanCaller(c *gin.Context) bool {
+	if c.GetInt("api_key_id") != 0 {
+		utils.ForbiddenResponse(c, "API keys cannot manage API keys")
+		return false
+	}
+	return true
+}
+
+func apiKeyContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid API key ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondAPIKeyError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	case strings.HasPrefix(msg, "cannot grant"):
+		utils.ForbiddenResponse(c, msg)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
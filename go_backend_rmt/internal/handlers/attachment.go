@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttachmentHandler struct {
+	service     *services.AttachmentService
+	permissions *services.PermissionService
+}
+
+func NewAttachmentHandler() *AttachmentHandler {
+	return &AttachmentHandler{
+		service:     services.NewAttachmentService(),
+		permissions: services.NewPermissionService(),
+	}
+}
+
+// authorize applies the per-entity permission; routes only require a company.
+func (h *AttachmentHandler) authorize(c *gin.Context, entityType string, manage bool) bool {
+	permission, err := services.AttachmentPermission(entityType, manage)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid entity type", err)
+		return false
+	}
+	allowed, isAPIKey := utils.APIKeyPermission(c, permission)
+	if !isAPIKey {
+		allowed, err = h.permissions.UserCan(c.GetInt("user_id"), c.GetInt("role_id"), permission)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to check permissions", err)
+			return false
+		}
+	}
+	if !allowed {
+		utils.ForbiddenResponse(c, "Insufficient permissions")
+		return false
+	}
+	return true
+}
+
+// POST /attachments
+// Multipart form: file, entity_type, entity_id and optional description.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	entityType := strings.TrimSpace(c.PostForm("entity_type"))
+	entityID, err := strconv.Atoi(c.PostForm("entity_id"))
+	if err != nil || entityID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid entity ID", err)
+		return
+	}
+	if !h.authorize(c, entityType, true) {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		if utils.IsRequestBodyTooLarge(err) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Upload too large", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Missing file", err)
+		return
+	}
+
+	description := utils.EmptyToNil(strings.TrimSpace(c.PostForm("description")))
+	ip := c.ClientIP()
+	ua := c.Request.UserAgent()
+	att, err := h.service.Upload(companyID, userID, entityType, entityID, file, description, &ip, &ua)
+	if err != nil {
+		respondAttachmentError(c, "Failed to upload attachment", err)
+		return
+	}
+	utils.CreatedResponse(c, "Attachment uploaded successfully", att)
+}
+
+// GET /attachments?entity_type=&entity_id=
+func (h *AttachmentHandler) ListAttachments(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var filter models.AttachmentFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if err := utils.ValidateStruct(&filter); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+	if !h.authorize(c, filter.EntityType, false) {
+		return
+	}
+
+	attachments, err := h.service.ListAttachments(companyID, filter.EntityType, filter.EntityID)
+	if err != nil {
+		respondAttachmentError(c, "Failed to list attachments", err)
+		return
+	}
+	utils.SuccessResponse(c, "Attachments retrieved successfully", attachments)
+}
+
+// loadAttachment fetches :id and checks the caller may act on its entity.
+func (h *AttachmentHandler) loadAttachment(c *gin.Context, manage bool) (*models.Attachment, bool) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return nil, false
+	}
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || attachmentID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid attachment ID", err)
+		return nil, false
+	}
+	att, err := h.service.GetAttachment(companyID, attachmentID)
+	if err != nil {
+		respondAttachmentError(c, "Failed to get attachment", err)
+		return nil, false
+	}
+	if !h.authorize(c, att.EntityType, manage) {
+		return nil, false
+	}
+	return att, true
+}
+
+// GET /attachments/:id
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	att, ok := h.loadAttachment(c, false)
+	if !ok {
+		return
+	}
+	utils.SuccessResponse(c, "Attachment retrieved successfully", att)
+}
+
+// GET /attachments/:id/download
+// Redirects to a short-lived signed URL.
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	att, ok := h.loadAttachment(c, false)
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, att.DownloadURL)
+}
+
+// DELETE /attachments/:id
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	att, ok := h.loadAttachment(c, true)
+	if !ok {
+		return
+	}
+	ip := c.ClientIP()
+	ua := c.Request.UserAgent()
+	if err := h.service.DeleteAttachment(c.GetInt("company_id"), att.AttachmentID, c.GetInt("user_id"), &ip, &ua); err != nil {
+		respondAttachmentError(c, "Failed to delete attachment", err)
+		return
+	}
+	utils.SuccessResponse(c, "Attachment deleted successfully", nil)
+}
+
+func respondAttachmentError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case errors.Is(err, services.ErrInvalidUploadType), errors.Is(err, services.ErrInvalidUploadExt):
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
+
+// GET /uploads/*filepath
+// Serves legacy upload paths (logos, purchase invoices, return receipts) from
+// the configured object storage. Anything else, such as attachments and report
+// job results, is only reachable through signed URLs.
+func ServeUpload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+	if !services.IsPublicUploadKey(key) {
+		utils.NotFoundResponse(c, "File not found")
+		return
+	}
+	streamStoredObject(c, key)
+}
+
+// GET /files/*filepath?expires=&signature=
+// Serves signed download links issued by the local storage backend.
+func ServeSignedFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+	err := services.VerifyStorageSignature(key, c.Query("expires"), c.Query("signature"), time.Now())
+	if errors.Is(err, services.ErrExpiredSignedURL) {
+		utils.ErrorResponse(c, http.StatusGone, "Download link expired", err)
+		return
+	}
+	if err != nil {
+		utils.ForbiddenResponse(c, "Invalid download link")
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=60")
+	streamStoredObject(c, key)
+}
+
+func streamStoredObject(c *gin.Context, key string) {
+	store, err := services.GetObjectStorage()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Object storage unavailable", err)
+		return
+	}
+	rc, info, err := store.Get(key)
+	if err != nil {
+		if errors.Is(err, services.ErrObjectNotFound) || errors.Is(err, services.ErrInvalidObjectKey) ||
+			errors.Is(err, services.ErrUnsafeUploadPath) {
+			utils.NotFoundResponse(c, "File not found")
+			return
+		}
+		utils.InternalServerErrorResponse(c, "Failed to read file", err)
+		return
+	}
+	defer rc.Close()
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	if info.Size >= 0 {
+		c.DataFromReader(http.StatusOK, info.Size, info.ContentType, rc, nil)
+		return
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", info.ContentType)
+	io.Copy(c.Writer, rc)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServeUploadServesOnlyPublicPrefixes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STORAGE_BACKEND", "local")
+	t.Setenv("UPLOAD_PATH", t.TempDir())
+	t.Setenv("STORAGE_SIGNING_KEY", "test-signing-key")
+
+	store, err := services.GetObjectStorage()
+	if err != nil {
+		t.Fatalf("object storage: %v", err)
+	}
+	for _, key := range []string{"logos/a.png", "report-jobs/1/2/result.pdf", "attachments/1/ab/abc.pdf"} {
+		body := []byte("content of " + key)
+		if err := store.Put(key, bytes.NewReader(body), int64(len(body)), "application/octet-stream"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/uploads/*filepath", ServeUpload)
+	router.GET("/files/*filepath", ServeSignedFile)
+	get := func(target string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder.Code
+	}
+
+	if code := get("/uploads/logos/a.png"); code != http.StatusOK {
+		t.Fatalf("expected logo to be served, got %d", code)
+	}
+	for _, target := range []string{
+		"/uploads/report-jobs/1/2/result.pdf",
+		"/uploads/attachments/1/ab/abc.pdf",
+		"/uploads/logos/../report-jobs/1/2/result.pdf",
+	} {
+		if code := get(target); code != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s, got %d", target, code)
+		}
+	}
+
+	signed, err := store.SignedURL("report-jobs/1/2/result.pdf", time.Minute)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if code := get(signed); code != http.StatusOK {
+		t.Fatalf("expected signed report download to be served, got %d", code)
+	}
+}
+
+func TestAttachmentAuthorizeUsesAPIKeyPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// The API-key path must not fall back to the role lookup, so the handler
+	// gets a permission service without a database.
+	h := &AttachmentHandler{permissions: &services.PermissionService{}}
+	caller := func() (*gin.Context, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Set("user_id", 7)
+		c.Set("company_id", 1)
+		c.Set("api_key_id", 3)
+		c.Set("api_key_permissions", []string{"VIEW_SALES"})
+		return c, recorder
+	}
+
+	c, recorder := caller()
+	if !h.authorize(c, models.AttachmentEntitySale, false) {
+		t.Fatalf("view with VIEW_SALES key: denied with %d", recorder.Code)
+	}
+
+	c, recorder = caller()
+	if h.authorize(c, models.AttachmentEntitySale, true) {
+		t.Fatal("manage without UPDATE_SALES: allowed")
+	}
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("manage without UPDATE_SALES: status %d, want 403", recorder.Code)
+	}
+}
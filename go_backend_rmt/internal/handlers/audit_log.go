@@ -21,10 +21,11 @@ func NewAuditLogHandler() *AuditLogHandler {
 // GET /audit-logs
 func (h *AuditLogHandler) GetAuditLogs(c *gin.Context) {
 	filters := map[string]string{
-		"user_id":   c.Query("user_id"),
-		"action":    c.Query("action"),
-		"from_date": c.Query("from_date"),
-		"to_date":   c.Query("to_date"),
+		"user_id":    c.Query("user_id"),
+		"api_key_id": c.Query("api_key_id"),
+		"action":     c.Query("action"),
+		"from_date":  c.Query("from_date"),
+		"to_date":    c.Query("to_date"),
 	}
 
 	logs, err := h.service.GetAuditLogs(filters)
@@ -150,7 +150,11 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 		return
 	}
 
-	me, err := h.authService.GetMe(userID)
+	var activeLocationID *int
+	if id := c.GetInt("location_id"); id != 0 {
+		activeLocationID = &id
+	}
+	me, err := h.authService.GetMe(userID, activeLocationID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get user details", err)
 		return
@@ -159,6 +163,54 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	utils.SuccessResponse(c, "User details retrieved successfully", me)
 }
 
+// GET /auth/locations
+func (h *AuthHandler) GetMyLocations(c *gin.Context) {
+	locations, err := h.authService.GetMyLocations(c.GetInt("user_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get locations", err)
+		return
+	}
+	activeLocationID := c.GetInt("location_id")
+	for i := range locations {
+		locations[i].IsActive = locations[i].LocationID == activeLocationID
+	}
+
+	utils.SuccessResponse(c, "Locations retrieved successfully", locations)
+}
+
+// POST /auth/switch-location
+func (h *AuthHandler) SwitchLocation(c *gin.Context) {
+	var req models.SwitchLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	resp, err := h.authService.SwitchLocation(c.GetInt("user_id"), c.GetString("session_id"), req.LocationID)
+	if err != nil {
+		switch err.Error() {
+		case "location access denied":
+			utils.ForbiddenResponse(c, "Location access denied")
+		case "location not found", "session not found":
+			utils.NotFoundResponse(c, err.Error())
+		case "session ID required":
+			utils.ForbiddenResponse(c, "User session not found")
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to switch location", err)
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie("accessToken", resp.AccessToken, 24*60*60, "/", "", true, true)
+
+	utils.SuccessResponse(c, "Location switched successfully", resp)
+}
+
 // POST /auth/forgot-password
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req models.ForgotPasswordRequest
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BillOfMaterialsHandler manages BOM versions and the assembly/disassembly
+// orders built from them
+type BillOfMaterialsHandler struct {
+	bomService      *services.BillOfMaterialsService
+	assemblyService *services.AssemblyOrderService
+}
+
+func NewBillOfMaterialsHandler() *BillOfMaterialsHandler {
+	return &BillOfMaterialsHandler{
+		bomService:      services.NewBillOfMaterialsService(),
+		assemblyService: services.NewAssemblyOrderService(),
+	}
+}
+
+// GET /inventory/boms
+func (h *BillOfMaterialsHandler) GetBOMs(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var productID *int
+	if productParam := c.Query("product_id"); productParam != "" {
+		id, err := strconv.Atoi(productParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+			return
+		}
+		productID = &id
+	}
+
+	boms, err := h.bomService.GetBOMs(companyID, productID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get bills of materials", err)
+		return
+	}
+	utils.SuccessResponse(c, "Bills of materials retrieved successfully", boms)
+}
+
+// GET /inventory/boms/:id
+func (h *BillOfMaterialsHandler) GetBOM(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	bomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid BOM ID", err)
+		return
+	}
+
+	bom, err := h.bomService.GetBOM(companyID, bomID)
+	if err != nil {
+		if err.Error() == "bill of materials not found" {
+			utils.NotFoundResponse(c, "Bill of materials not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get bill of materials", err)
+		return
+	}
+	utils.SuccessResponse(c, "Bill of materials retrieved successfully", bom)
+}
+
+// POST /inventory/boms
+func (h *BillOfMaterialsHandler) CreateBOM(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.CreateBillOfMaterialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	bom, err := h.bomService.CreateBOM(companyID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create bill of materials", err)
+		return
+	}
+	utils.CreatedResponse(c, "Bill of materials created successfully", bom)
+}
+
+// POST /inventory/boms/:id/activate
+func (h *BillOfMaterialsHandler) ActivateBOM(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	bomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid BOM ID", err)
+		return
+	}
+
+	bom, err := h.bomService.ActivateBOM(companyID, bomID)
+	if err != nil {
+		if err.Error() == "bill of materials not found" {
+			utils.NotFoundResponse(c, "Bill of materials not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to activate bill of materials", err)
+		return
+	}
+	utils.SuccessResponse(c, "Bill of materials activated successfully", bom)
+}
+
+// GET /inventory/assembly-orders
+func (h *BillOfMaterialsHandler) GetAssemblyOrders(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var locationID *int
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		id, err := strconv.Atoi(locationParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &id
+	}
+
+	orders, err := h.assemblyService.GetAssemblyOrders(companyID, locationID, c.Query("status"), c.Query("order_type"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get assembly orders", err)
+		return
+	}
+	utils.SuccessResponse(c, "Assembly orders retrieved successfully", orders)
+}
+
+// GET /inventory/assembly-orders/:id
+func (h *BillOfMaterialsHandler) GetAssemblyOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid assembly order ID", err)
+		return
+	}
+
+	order, err := h.assemblyService.GetAssemblyOrder(companyID, orderID)
+	if err != nil {
+		if err.Error() == "assembly order not found" {
+			utils.NotFoundResponse(c, "Assembly order not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get assembly order", err)
+		return
+	}
+	utils.SuccessResponse(c, "Assembly order retrieved successfully", order)
+}
+
+// POST /inventory/assembly-orders
+func (h *BillOfMaterialsHandler) CreateAssemblyOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.CreateAssemblyOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	order, err := h.assemblyService.CreateAssemblyOrder(companyID, locationID, userID, &req)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create assembly order", err)
+		return
+	}
+	utils.CreatedResponse(c, "Assembly order created successfully", order)
+}
+
+// POST /inventory/assembly-orders/:id/complete
+func (h *BillOfMaterialsHandler) CompleteAssemblyOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid assembly order ID", err)
+		return
+	}
+
+	var req models.CompleteAssemblyOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	order, err := h.assemblyService.CompleteAssemblyOrder(companyID, orderID, userID, &req)
+	if err != nil {
+		if err.Error() == "assembly order not found" {
+			utils.NotFoundResponse(c, "Assembly order not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to complete assembly order", err)
+		return
+	}
+	utils.SuccessResponse(c, "Assembly order completed successfully", order)
+}
+
+// POST /inventory/assembly-orders/:id/cancel
+func (h *BillOfMaterialsHandler) CancelAssemblyOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid assembly order ID", err)
+		return
+	}
+
+	if err := h.assemblyService.CancelAssemblyOrder(companyID, orderID); err != nil {
+		if err.Error() == "assembly order not found" {
+			utils.NotFoundResponse(c, "Assembly order not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to cancel assembly order", err)
+		return
+	}
+	utils.SuccessResponse(c, "Assembly order cancelled successfully", nil)
+}
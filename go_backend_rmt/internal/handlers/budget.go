@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BudgetHandler manages budget versions, their spreadsheet round trip and
+// the budget vs actual report.
+type BudgetHandler struct {
+	service *services.BudgetService
+}
+
+func NewBudgetHandler() *BudgetHandler {
+	return &BudgetHandler{service: services.NewBudgetService()}
+}
+
+// GET /budgets
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var filter models.BudgetFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if err := utils.ValidateStruct(&filter); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	budgets, err := h.service.ListBudgets(companyID, filter)
+	if err != nil {
+		respondBudgetError(c, "Failed to list budgets", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budgets retrieved successfully", budgets)
+}
+
+// POST /budgets
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	budget, err := h.service.CreateBudget(companyID, userID, &req)
+	if err != nil {
+		respondBudgetError(c, "Failed to create budget", err)
+		return
+	}
+	utils.CreatedResponse(c, "Budget created successfully", budget)
+}
+
+// GET /budgets/:id
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	companyID, _, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	budget, err := h.service.GetBudget(companyID, id)
+	if err != nil {
+		respondBudgetError(c, "Failed to get budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget retrieved successfully", budget)
+}
+
+// PUT /budgets/:id
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	budget, err := h.service.UpdateBudget(companyID, userID, id, &req)
+	if err != nil {
+		respondBudgetError(c, "Failed to update budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget updated successfully", budget)
+}
+
+// DELETE /budgets/:id
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteBudget(companyID, userID, id); err != nil {
+		respondBudgetError(c, "Failed to delete budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget deleted successfully", nil)
+}
+
+// POST /budgets/:id/revise
+// Copies the budget into a new draft version of the same lineage.
+func (h *BudgetHandler) ReviseBudget(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviseBudgetRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		if err := utils.ValidateStruct(&req); err != nil {
+			utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+			return
+		}
+	}
+
+	budget, err := h.service.ReviseBudget(companyID, userID, id, &req)
+	if err != nil {
+		respondBudgetError(c, "Failed to revise budget", err)
+		return
+	}
+	utils.CreatedResponse(c, "Budget revision created successfully", budget)
+}
+
+// POST /budgets/:id/activate
+// Activates the version and archives the one it replaces.
+func (h *BudgetHandler) ActivateBudget(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	budget, err := h.service.ActivateBudget(companyID, userID, id)
+	if err != nil {
+		respondBudgetError(c, "Failed to activate budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget activated successfully", budget)
+}
+
+// POST /budgets/:id/archive
+func (h *BudgetHandler) ArchiveBudget(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	budget, err := h.service.ArchiveBudget(companyID, userID, id)
+	if err != nil {
+		respondBudgetError(c, "Failed to archive budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget archived successfully", budget)
+}
+
+// GET /budgets/:id/sheet
+func (h *BudgetHandler) ExportBudgetSheet(c *gin.Context) {
+	companyID, _, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	data, filename, err := h.service.ExportBudgetSheetXLSX(companyID, id)
+	if err != nil {
+		respondBudgetError(c, "Failed to export budget", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", data)
+}
+
+// POST /budgets/:id/sheet
+// Replaces a draft budget's lines from an uploaded sheet.
+func (h *BudgetHandler) ImportBudgetSheet(c *gin.Context) {
+	companyID, userID, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "File is required", err)
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to open file", err)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read file", err)
+		return
+	}
+
+	res, err := h.service.ImportBudgetSheetXLSX(companyID, userID, id, data)
+	if err != nil {
+		respondBudgetError(c, "Failed to import budget", err)
+		return
+	}
+	utils.SuccessResponse(c, "Budget import completed", res)
+}
+
+// GET /budgets/:id/vs-actual
+// ?format=excel|pdf|csv exports the report.
+func (h *BudgetHandler) GetBudgetVsActual(c *gin.Context) {
+	companyID, _, id, ok := budgetContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.BudgetVsActualFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if err := utils.ValidateStruct(&filter); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	report, err := h.service.BudgetVsActual(companyID, id, filter)
+	if err != nil {
+		respondBudgetError(c, "Failed to generate budget vs actual", err)
+		return
+	}
+
+	var (
+		content     []byte
+		ext, ctype  string
+		failMessage string
+	)
+	table := services.BudgetVsActualTable(report)
+	switch c.Query("format") {
+	case "excel":
+		content, err = utils.GenerateExcel("", table)
+		ext, ctype, failMessage = "xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "Failed to generate Excel"
+	case "pdf":
+		content, err = utils.GeneratePDF("", table)
+		ext, ctype, failMessage = "pdf", "application/pdf", "Failed to generate PDF"
+	case "csv":
+		content, err = utils.GenerateCSV("", table)
+		ext, ctype, failMessage = "csv", "text/csv; charset=utf-8", "Failed to generate CSV"
+	default:
+		utils.SuccessResponse(c, "Budget vs actual generated successfully", report)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, failMessage, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportTableFilename(table, ext)))
+	c.Data(http.StatusOK, ctype, content)
+}
+
+func budgetContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid budget ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondBudgetError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"erp-backend/internal/models"
 	"erp-backend/internal/services"
@@ -91,7 +93,7 @@ func (h *CashRegisterHandler) OpenCashRegister(c *gin.Context) {
 		companyID,
 		locationID,
 		userID,
-		req.OpeningBalance,
+		&req,
 		sessionID,
 		requestID,
 		&ip,
@@ -143,12 +145,11 @@ func (h *CashRegisterHandler) CloseCashRegister(c *gin.Context) {
 		return
 	}
 
-	err := h.service.CloseCashRegister(
+	result, err := h.service.CloseCashRegister(
 		companyID,
 		locationID,
 		userID,
-		req.ClosingBalance,
-		req.Denominations,
+		&req,
 		sessionID,
 		requestID,
 		&ip,
@@ -163,7 +164,7 @@ func (h *CashRegisterHandler) CloseCashRegister(c *gin.Context) {
 		return
 	}
 
-	utils.SuccessResponse(c, "Cash register closed successfully", nil)
+	utils.SuccessResponse(c, "Cash register closed successfully", result)
 }
 
 // POST /cash-registers/training/enable
@@ -257,9 +258,7 @@ func (h *CashRegisterHandler) RecordTally(c *gin.Context) {
 		companyID,
 		locationID,
 		userID,
-		req.Count,
-		req.Notes,
-		req.Denominations,
+		&req,
 		sessionID,
 		requestID,
 		&ip,
@@ -396,3 +395,264 @@ func (h *CashRegisterHandler) GetEvents(c *gin.Context) {
 
 	utils.SuccessResponse(c, "Cash register events retrieved successfully", events)
 }
+
+// POST /cash-registers/shifts/start
+func (h *CashRegisterHandler) StartShift(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	sessionID := c.GetString("session_id")
+	requestID := c.GetString("request_id")
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := strconv.Atoi(loc); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.StartCashShiftRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+
+	shift, err := h.service.StartShift(companyID, locationID, userID, &req, sessionID, requestID, &ip, &ua)
+	if err != nil {
+		respondCashRegisterError(c, "Failed to start shift", err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Shift started", shift)
+}
+
+// POST /cash-registers/shifts/end
+func (h *CashRegisterHandler) EndShift(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	sessionID := c.GetString("session_id")
+	requestID := c.GetString("request_id")
+	ip := c.ClientIP()
+	ua := c.GetHeader("User-Agent")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := strconv.Atoi(loc); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.EndCashShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	result, err := h.service.EndShift(companyID, locationID, userID, &req, sessionID, requestID, &ip, &ua)
+	if err != nil {
+		respondCashRegisterError(c, "Failed to end shift", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Shift ended", result)
+}
+
+// GET /cash-registers/shifts
+func (h *CashRegisterHandler) GetShifts(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := strconv.Atoi(loc); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var regID *int
+	if v := c.Query("register_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			regID = &id
+		}
+	}
+	limit := 200
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+
+	shifts, err := h.service.GetShifts(companyID, locationID, regID, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get shifts", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Shifts retrieved successfully", shifts)
+}
+
+// GET /cash-registers/x-report
+func (h *CashRegisterHandler) GetXReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	sessionID := c.GetString("session_id")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := strconv.Atoi(loc); err == nil {
+			locationID = id
+		}
+	}
+
+	var regID, shiftID *int
+	if v := c.Query("register_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			regID = &id
+		}
+	}
+	if v := c.Query("shift_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			shiftID = &id
+		}
+	}
+	if regID == nil && locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	report, err := h.service.GetXReport(companyID, locationID, userID, sessionID, regID, shiftID)
+	if err != nil {
+		respondCashRegisterError(c, "Failed to build X report", err)
+		return
+	}
+
+	respondCashRegisterReport(c, "/reports/cash-register-x", "X report generated", report)
+}
+
+// GET /cash-registers/z-reports
+func (h *CashRegisterHandler) GetZReports(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if loc := c.Query("location_id"); loc != "" {
+		if id, err := strconv.Atoi(loc); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+	limit := 200
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+
+	reports, err := h.service.ListZReports(companyID, locationID, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get Z reports", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Z reports retrieved successfully", reports)
+}
+
+// GET /cash-registers/z-reports/:id
+func (h *CashRegisterHandler) GetZReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid Z report ID", err)
+		return
+	}
+
+	report, err := h.service.GetZReport(companyID, id)
+	if err != nil {
+		respondCashRegisterError(c, "Failed to get Z report", err)
+		return
+	}
+
+	respondCashRegisterReport(c, "/reports/cash-register-z", "Z report retrieved successfully", report)
+}
+
+// respondCashRegisterReport returns the report as JSON, or exports its
+// flattened lines through the shared report exporters when format is set.
+func respondCashRegisterReport(c *gin.Context, endpoint, message string, report *models.CashRegisterReport) {
+	switch c.Query("format") {
+	case "excel":
+		content, err := utils.GenerateExcel(endpoint, services.CashRegisterReportLines(report))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate Excel", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportExportFilename(endpoint, "xlsx")))
+		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", content)
+	case "pdf":
+		content, err := utils.GeneratePDF(endpoint, services.CashRegisterReportLines(report))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate PDF", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportExportFilename(endpoint, "pdf")))
+		c.Data(http.StatusOK, "application/pdf", content)
+	default:
+		utils.SuccessResponse(c, message, report)
+	}
+}
+
+func respondCashRegisterError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
@@ -161,7 +161,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 }
 
 func (h *CompanyHandler) saveAndSetCompanyLogo(c *gin.Context, companyID int, fh *multipart.FileHeader) error {
-	served, err := services.SaveUploadedFile(h.cfg.UploadPath, "logos", fh, services.AllowlistCompanyLogo())
+	served, err := services.StoreUploadedFile("logos", fh, services.AllowlistCompanyLogo())
 	if err != nil {
 		return err
 	}
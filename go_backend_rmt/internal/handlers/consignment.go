@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsignmentHandler manages vendor-owned consignment-in stock, its supplier
+// settlements, and consignment-out stock held at dealer locations
+type ConsignmentHandler struct {
+	service *services.ConsignmentService
+}
+
+func NewConsignmentHandler() *ConsignmentHandler {
+	return &ConsignmentHandler{service: services.NewConsignmentService()}
+}
+
+// GET /consignments/receipts
+func (h *ConsignmentHandler) GetReceipts(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var supplierID *int
+	if param := c.Query("supplier_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid supplier ID", err)
+			return
+		}
+		supplierID = &id
+	}
+
+	receipts, err := h.service.GetReceipts(companyID, supplierID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consignment receipts", err)
+		return
+	}
+	utils.SuccessResponse(c, "Consignment receipts retrieved successfully", receipts)
+}
+
+// GET /consignments/receipts/:id
+func (h *ConsignmentHandler) GetReceipt(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	receiptID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid consignment receipt ID", err)
+		return
+	}
+
+	receipt, err := h.service.GetReceipt(companyID, receiptID)
+	if err != nil {
+		if err.Error() == "consignment receipt not found" {
+			utils.NotFoundResponse(c, "Consignment receipt not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consignment receipt", err)
+		return
+	}
+	utils.SuccessResponse(c, "Consignment receipt retrieved successfully", receipt)
+}
+
+// POST /consignments/receipts
+func (h *ConsignmentHandler) CreateReceipt(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.CreateConsignmentReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	receipt, err := h.service.CreateReceipt(companyID, locationID, userID, &req)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to receive consignment stock", err)
+		return
+	}
+	utils.CreatedResponse(c, "Consignment stock received successfully", receipt)
+}
+
+// GET /consignments/statement
+func (h *ConsignmentHandler) GetStatement(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	supplierID, err := strconv.Atoi(c.Query("supplier_id"))
+	if err != nil || supplierID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "supplier_id is required", err)
+		return
+	}
+	periodEnd := time.Now()
+	if param := c.Query("period_end"); param != "" {
+		parsed, err := time.Parse("2006-01-02", param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "period_end must be YYYY-MM-DD", err)
+			return
+		}
+		periodEnd = parsed
+	}
+	var periodStart *time.Time
+	if param := c.Query("period_start"); param != "" {
+		parsed, err := time.Parse("2006-01-02", param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "period_start must be YYYY-MM-DD", err)
+			return
+		}
+		periodStart = &parsed
+	}
+
+	statement, err := h.service.GetStatement(companyID, supplierID, periodStart, periodEnd)
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			utils.NotFoundResponse(c, "Supplier not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consignment statement", err)
+		return
+	}
+	utils.SuccessResponse(c, "Consignment statement retrieved successfully", statement)
+}
+
+// GET /consignments/settlements
+func (h *ConsignmentHandler) GetSettlements(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var supplierID *int
+	if param := c.Query("supplier_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid supplier ID", err)
+			return
+		}
+		supplierID = &id
+	}
+
+	settlements, err := h.service.GetSettlements(companyID, supplierID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consignment settlements", err)
+		return
+	}
+	utils.SuccessResponse(c, "Consignment settlements retrieved successfully", settlements)
+}
+
+// GET /consignments/settlements/:id
+func (h *ConsignmentHandler) GetSettlement(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	settlementID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid settlement ID", err)
+		return
+	}
+
+	settlement, err := h.service.GetSettlement(companyID, settlementID)
+	if err != nil {
+		if err.Error() == "consignment settlement not found" {
+			utils.NotFoundResponse(c, "Consignment settlement not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consignment settlement", err)
+		return
+	}
+	utils.SuccessResponse(c, "Consignment settlement retrieved successfully", settlement)
+}
+
+// POST /consignments/settlements
+func (h *ConsignmentHandler) CreateSettlement(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.CreateConsignmentSettlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	settlement, err := h.service.CreateSettlement(companyID, locationID, userID, &req)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create consignment settlement", err)
+		return
+	}
+	utils.CreatedResponse(c, "Consignment settlement created successfully", settlement)
+}
+
+// GET /consignments/dealer-locations
+func (h *ConsignmentHandler) GetDealerLocations(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	locations, err := h.service.GetDealerLocations(companyID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get dealer locations", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dealer locations retrieved successfully", locations)
+}
+
+// PUT /consignments/dealer-locations/:id
+func (h *ConsignmentHandler) SetDealerLocation(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	locationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+		return
+	}
+
+	var req models.SetDealerLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	if err := h.service.SetDealerLocation(companyID, locationID, req.CustomerID); err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update dealer location", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dealer location updated successfully", nil)
+}
+
+// POST /consignments/dealer-locations/:id/sales-reports
+func (h *ConsignmentHandler) ReportDealerSales(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	locationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+		return
+	}
+
+	var req models.DealerSalesReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sale, err := h.service.ReportDealerSales(companyID, locationID, userID, &req)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to record dealer sales", err)
+		return
+	}
+	utils.CreatedResponse(c, "Dealer sales recorded successfully", sale)
+}
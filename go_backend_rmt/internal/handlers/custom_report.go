@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomReportHandler exposes the report builder: the semantic model catalog,
+// ad hoc queries and saved report definitions.
+type CustomReportHandler struct {
+	service *services.CustomReportService
+}
+
+func NewCustomReportHandler() *CustomReportHandler {
+	return &CustomReportHandler{service: services.NewCustomReportService()}
+}
+
+// GET /custom-reports/model
+func (h *CustomReportHandler) GetSemanticModels(c *gin.Context) {
+	utils.SuccessResponse(c, "Report models retrieved successfully", h.service.Models())
+}
+
+// POST /custom-reports/query
+// Runs an unsaved definition; ?format=excel|pdf|csv exports the result.
+func (h *CustomReportHandler) QueryCustomReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var def models.CustomReportDefinition
+	if err := c.ShouldBindJSON(&def); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&def); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+	opts, ok := bindCustomReportRunOptions(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Query(companyID, userID, c.GetInt("role_id"), def, opts)
+	if err != nil {
+		respondCustomReportError(c, "Failed to run custom report", err)
+		return
+	}
+	respondCustomReportResult(c, result)
+}
+
+// POST /custom-reports
+func (h *CustomReportHandler) CreateCustomReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateCustomReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	report, err := h.service.CreateReport(companyID, userID, &req)
+	if err != nil {
+		respondCustomReportError(c, "Failed to create custom report", err)
+		return
+	}
+	utils.CreatedResponse(c, "Custom report created successfully", report)
+}
+
+// GET /custom-reports
+// Lists the user's own reports and those shared with the company.
+func (h *CustomReportHandler) ListCustomReports(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	reports, err := h.service.ListReports(companyID, userID)
+	if err != nil {
+		respondCustomReportError(c, "Failed to list custom reports", err)
+		return
+	}
+	utils.SuccessResponse(c, "Custom reports retrieved successfully", reports)
+}
+
+// GET /custom-reports/:id
+func (h *CustomReportHandler) GetCustomReport(c *gin.Context) {
+	companyID, userID, id, ok := customReportContext(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReport(companyID, userID, id)
+	if err != nil {
+		respondCustomReportError(c, "Failed to get custom report", err)
+		return
+	}
+	utils.SuccessResponse(c, "Custom report retrieved successfully", report)
+}
+
+// PUT /custom-reports/:id
+func (h *CustomReportHandler) UpdateCustomReport(c *gin.Context) {
+	companyID, userID, id, ok := customReportContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCustomReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	report, err := h.service.UpdateReport(companyID, userID, id, &req)
+	if err != nil {
+		respondCustomReportError(c, "Failed to update custom report", err)
+		return
+	}
+	utils.SuccessResponse(c, "Custom report updated successfully", report)
+}
+
+// DELETE /custom-reports/:id
+func (h *CustomReportHandler) DeleteCustomReport(c *gin.Context) {
+	companyID, userID, id, ok := customReportContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReport(companyID, userID, id); err != nil {
+		respondCustomReportError(c, "Failed to delete custom report", err)
+		return
+	}
+	utils.SuccessResponse(c, "Custom report deleted successfully", nil)
+}
+
+// GET /custom-reports/:id/run
+// Runs a saved report; date_range or from_date/to_date replace the saved dates.
+func (h *CustomReportHandler) RunCustomReport(c *gin.Context) {
+	companyID, userID, id, ok := customReportContext(c)
+	if !ok {
+		return
+	}
+	opts, ok := bindCustomReportRunOptions(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RunReport(companyID, userID, c.GetInt("role_id"), id, opts)
+	if err != nil {
+		respondCustomReportError(c, "Failed to run custom report", err)
+		return
+	}
+	respondCustomReportResult(c, result)
+}
+
+func bindCustomReportRunOptions(c *gin.Context) (models.CustomReportRunOptions, bool) {
+	var opts models.CustomReportRunOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return opts, false
+	}
+	if err := utils.ValidateStruct(&opts); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return opts, false
+	}
+	return opts, true
+}
+
+func respondCustomReportResult(c *gin.Context, result *models.CustomReportResult) {
+	var (
+		content     []byte
+		err         error
+		ext, ctype  string
+		failMessage string
+	)
+	table := services.CustomReportTable(result)
+	switch c.Query("format") {
+	case "excel":
+		content, err = utils.GenerateExcel("", table)
+		ext, ctype, failMessage = "xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "Failed to generate Excel"
+	case "pdf":
+		content, err = utils.GeneratePDF("", table)
+		ext, ctype, failMessage = "pdf", "application/pdf", "Failed to generate PDF"
+	case "csv":
+		content, err = utils.GenerateCSV("", table)
+		ext, ctype, failMessage = "csv", "text/csv; charset=utf-8", "Failed to generate CSV"
+	default:
+		utils.SuccessResponse(c, "Custom report generated successfully", result)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, failMessage, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportTableFilename(table, ext)))
+	c.Data(http.StatusOK, ctype, content)
+}
+
+func customReportContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid custom report ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondCustomReportError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "only the owner"), strings.Contains(msg, "requires access to all locations"):
+		utils.ForbiddenResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomerStatementHandler exposes customer statements of account
+type CustomerStatementHandler struct {
+	service *services.CustomerStatementService
+}
+
+func NewCustomerStatementHandler() *CustomerStatementHandler {
+	return &CustomerStatementHandler{service: services.NewCustomerStatementService()}
+}
+
+func isStatementInputError(err error) bool {
+	msg := err.Error()
+	return strings.HasPrefix(msg, "invalid date") || strings.HasPrefix(msg, "from_date")
+}
+
+func optionalQuery(c *gin.Context, key string) *string {
+	if value := c.Query(key); value != "" {
+		return &value
+	}
+	return nil
+}
+
+// GET /customers/:id/statement
+func (h *CustomerStatementHandler) GetStatement(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid customer ID", err)
+		return
+	}
+
+	statement, err := h.service.GetStatement(companyID, customerID, optionalQuery(c, "from_date"), optionalQuery(c, "to_date"))
+	if err != nil {
+		if err.Error() == "customer not found" {
+			utils.NotFoundResponse(c, "Customer not found")
+			return
+		}
+		if isStatementInputError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid statement period", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get customer statement", err)
+		return
+	}
+
+	if strings.EqualFold(c.Query("format"), "pdf") {
+		content, err := h.service.RenderStatementPDF(companyID, statement)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate PDF", err)
+			return
+		}
+		filename := fmt.Sprintf("statement-%d-%s.pdf", customerID, statement.ToDate.Format("20060102"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+		c.Data(http.StatusOK, "application/pdf", content)
+		return
+	}
+	utils.SuccessResponse(c, "Customer statement retrieved successfully", statement)
+}
+
+// POST /customers/:id/statement/email
+func (h *CustomerStatementHandler) EmailStatement(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid customer ID", err)
+		return
+	}
+
+	var req models.EmailCustomerStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	delivery, err := h.service.EmailStatement(companyID, customerID, &req)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			utils.NotFoundResponse(c, "Customer not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to email customer statement", err)
+		return
+	}
+	utils.SuccessResponse(c, "Customer statement emailed successfully", delivery)
+}
+
+// POST /customers/statements/email
+func (h *CustomerStatementHandler) EmailStatements(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.BulkEmailCustomerStatementsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	deliveries, err := h.service.EmailStatements(companyID, &req)
+	if err != nil {
+		if isStatementInputError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid statement period", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to email customer statements", err)
+		return
+	}
+	utils.SuccessResponse(c, "Customer statements processed successfully", deliveries)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DocumentHandler serves server-rendered documents and template previews
+type DocumentHandler struct {
+	service *services.DocumentRenderService
+}
+
+func NewDocumentHandler() *DocumentHandler {
+	return &DocumentHandler{service: services.NewDocumentRenderService()}
+}
+
+// respondDocumentError maps missing documents and templates to 404, storage
+// failures to 500 and everything else to a rejected request.
+func respondDocumentError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case !strings.HasPrefix(msg, "failed to") && strings.HasSuffix(msg, " not found"):
+		utils.NotFoundResponse(c, strings.ToUpper(msg[:1])+msg[1:])
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
+
+func writeRenderedDocument(c *gin.Context, doc *models.RenderedDocument) {
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%s", disposition, doc.Filename))
+	c.Data(http.StatusOK, doc.ContentType, doc.Content)
+}
+
+// RenderDocument returns a handler for one document type.
+// GET /documents/{sales|quotes|sale-returns|purchases|payslips|warranties}/:id?format=html|pdf&template_id=
+func (h *DocumentHandler) RenderDocument(docType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyID := c.GetInt("company_id")
+		if companyID == 0 {
+			utils.ForbiddenResponse(c, "Company access required")
+			return
+		}
+		docID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid document ID", err)
+			return
+		}
+		var templateID *int
+		if v := c.Query("template_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template ID", err)
+				return
+			}
+			templateID = &id
+		}
+
+		doc, err := h.service.RenderDocument(companyID, docType, docID, templateID, c.Query("format"))
+		if err != nil {
+			respondDocumentError(c, "Failed to render document", err)
+			return
+		}
+		writeRenderedDocument(c, doc)
+	}
+}
+
+// EmailDocument returns a handler that emails one document type as PDF.
+// POST /documents/{type}/:id/email
+func (h *DocumentHandler) EmailDocument(docType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		companyID := c.GetInt("company_id")
+		if companyID == 0 {
+			utils.ForbiddenResponse(c, "Company access required")
+			return
+		}
+		docID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid document ID", err)
+			return
+		}
+
+		var req models.EmailDocumentRequest
+		if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+		if err := utils.ValidateStruct(&req); err != nil {
+			utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+			return
+		}
+
+		result, err := h.service.EmailDocument(companyID, docType, docID, &req)
+		if err != nil {
+			respondDocumentError(c, "Failed to email document", err)
+			return
+		}
+		utils.SuccessResponse(c, "Document emailed successfully", result)
+	}
+}
+
+// POST /invoice-templates/preview
+func (h *DocumentHandler) PreviewInvoiceTemplate(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.PreviewInvoiceTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	doc, err := h.service.PreviewTemplate(companyID, &req)
+	if err != nil {
+		respondDocumentError(c, "Failed to preview invoice template", err)
+		return
+	}
+	writeRenderedDocument(c, doc)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DunningHandler exposes dunning runs and reminder history
+type DunningHandler struct {
+	service *services.DunningService
+}
+
+func NewDunningHandler() *DunningHandler {
+	return &DunningHandler{service: services.NewDunningService()}
+}
+
+// POST /dunning/runs
+func (h *DunningHandler) RunDunning(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.RunDunningRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.RunDunning(companyID, userID, &req)
+	if err != nil {
+		if err.Error() == "dunning is not enabled" || strings.HasPrefix(err.Error(), "invalid date") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to run dunning", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to run dunning", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dunning run completed successfully", result)
+}
+
+// GET /dunning/reminders
+func (h *DunningHandler) GetReminders(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	filters := map[string]string{}
+	if v := c.Query("customer_id"); v != "" {
+		filters["customer_id"] = v
+	}
+	if v := c.Query("sale_id"); v != "" {
+		filters["sale_id"] = v
+	}
+
+	reminders, err := h.service.ListReminders(companyID, filters)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid filter", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get dunning reminders", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dunning reminders retrieved successfully", reminders)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EInvoiceHandler exposes issued e-invoices, chain verification and signing keys
+type EInvoiceHandler struct {
+	service *services.EInvoiceService
+}
+
+func NewEInvoiceHandler() *EInvoiceHandler {
+	return &EInvoiceHandler{service: services.NewEInvoiceService()}
+}
+
+// respondEInvoiceError maps missing records to 404, storage failures to 500
+// and everything else to a rejected request.
+func respondEInvoiceError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case !strings.HasPrefix(msg, "failed to") && strings.HasSuffix(msg, " not found"):
+		utils.NotFoundResponse(c, strings.ToUpper(msg[:1])+msg[1:])
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
+
+func einvoiceIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid e-invoice ID", err)
+		return 0, false
+	}
+	return id, true
+}
+
+// GET /einvoices
+func (h *EInvoiceHandler) GetEInvoices(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	filters := map[string]string{}
+	for _, key := range []string{"document_type", "submission_status", "location_id", "sale_id", "from_date", "to_date"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+
+	invoices, err := h.service.ListEInvoices(companyID, filters)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid filter", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get e-invoices", err)
+		return
+	}
+	utils.SuccessResponse(c, "E-invoices retrieved successfully", invoices)
+}
+
+// GET /einvoices/:id
+func (h *EInvoiceHandler) GetEInvoice(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	id, ok := einvoiceIDParam(c)
+	if !ok {
+		return
+	}
+
+	inv, err := h.service.GetEInvoice(companyID, id)
+	if err != nil {
+		respondEInvoiceError(c, "Failed to get e-invoice", err)
+		return
+	}
+	utils.SuccessResponse(c, "E-invoice retrieved successfully", inv)
+}
+
+// GET /einvoices/:id/xml
+func (h *EInvoiceHandler) GetEInvoiceXML(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	id, ok := einvoiceIDParam(c)
+	if !ok {
+		return
+	}
+
+	inv, err := h.service.GetEInvoice(companyID, id)
+	if err != nil {
+		respondEInvoiceError(c, "Failed to get e-invoice", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xml", inv.UUID))
+	c.Data(http.StatusOK, "application/xml", []byte(inv.XML))
+}
+
+// GET /sales/:id/einvoice
+func (h *EInvoiceHandler) GetSaleEInvoice(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sale ID", err)
+		return
+	}
+
+	inv, err := h.service.GetEInvoiceForSale(companyID, saleID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get e-invoice", err)
+		return
+	}
+	if inv == nil {
+		utils.NotFoundResponse(c, "E-invoice not found")
+		return
+	}
+	utils.SuccessResponse(c, "E-invoice retrieved successfully", inv)
+}
+
+// POST /einvoices/issue
+func (h *EInvoiceHandler) IssueEInvoice(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var req models.IssueEInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	inv, err := h.service.IssueEInvoiceOnRequest(companyID, &req)
+	if err != nil {
+		respondEInvoiceError(c, "Failed to issue e-invoice", err)
+		return
+	}
+	utils.CreatedResponse(c, "E-invoice issued successfully", inv)
+}
+
+// POST /einvoices/:id/submit
+func (h *EInvoiceHandler) SubmitEInvoice(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	id, ok := einvoiceIDParam(c)
+	if !ok {
+		return
+	}
+
+	inv, err := h.service.SubmitEInvoice(companyID, id)
+	if err != nil {
+		respondEInvoiceError(c, "Failed to submit e-invoice", err)
+		return
+	}
+	utils.SuccessResponse(c, "E-invoice submitted", inv)
+}
+
+// GET /einvoices/verify?location_id=
+func (h *EInvoiceHandler) VerifyChain(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	locationID, err := strconv.Atoi(c.Query("location_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "location_id is required", err)
+		return
+	}
+
+	result, err := h.service.VerifyChain(companyID, locationID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify e-invoice chain", err)
+		return
+	}
+	utils.SuccessResponse(c, "E-invoice chain verified", result)
+}
+
+// GET /einvoices/signing-keys
+func (h *EInvoiceHandler) GetSigningKeys(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	keys, err := h.service.ListSigningKeys(companyID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get signing keys", err)
+		return
+	}
+	utils.SuccessResponse(c, "Signing keys retrieved successfully", keys)
+}
+
+// POST /einvoices/signing-keys/rotate
+func (h *EInvoiceHandler) RotateSigningKey(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	key, err := h.service.RotateSigningKey(companyID, c.GetInt("user_id"))
+	if err != nil {
+		respondEInvoiceError(c, "Failed to rotate signing key", err)
+		return
+	}
+	utils.CreatedResponse(c, "Signing key rotated", key)
+}
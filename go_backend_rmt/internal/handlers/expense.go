@@ -101,12 +101,20 @@ func (h *ExpenseHandler) CreateExpense(c *gin.Context) {
 		idemKey = c.GetHeader("X-Idempotency-Key")
 	}
 
-	id, err := h.service.CreateExpense(companyID, locationID, userID, &req, idemKey)
+	id, budgetWarnings, err := h.service.CreateExpenseWithBudgetWarnings(companyID, locationID, userID, &req, idemKey)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create expense", err)
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "budget exceeded") || err.Error() == "department not found" {
+			status = http.StatusBadRequest
+		}
+		utils.ErrorResponse(c, status, "Failed to create expense", err)
 		return
 	}
-	utils.CreatedResponse(c, "Expense recorded successfully", gin.H{"expense_id": id})
+	resp := gin.H{"expense_id": id}
+	if len(budgetWarnings) > 0 {
+		resp["budget_warnings"] = budgetWarnings
+	}
+	utils.CreatedResponse(c, "Expense recorded successfully", resp)
 }
 
 func parseFlexibleDate(raw string) (time.Time, error) {
@@ -804,3 +804,118 @@ func (h *InventoryHandler) CompleteStockTransfer(c *gin.Context) {
 
 	utils.SuccessResponse(c, "Stock transfer completed successfully", nil)
 }
+
+// POST /inventory/transfers/:id/receive
+func (h *InventoryHandler) ReceiveStockTransfer(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	actingLocationID := c.GetInt("location_id")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	transferID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid transfer ID", err)
+		return
+	}
+
+	if locParam := c.Query("location_id"); locParam != "" {
+		if id, convErr := strconv.Atoi(locParam); convErr == nil {
+			actingLocationID = id
+		}
+	}
+	if actingLocationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required to receive", nil)
+		return
+	}
+
+	var req models.ReceiveStockTransferRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		utils.ValidationErrorResponse(c, validationErrors)
+		return
+	}
+
+	receipt, err := h.inventoryService.ReceiveStockTransfer(transferID, companyID, actingLocationID, userID, &req)
+	if err != nil {
+		if err.Error() == "transfer not found" {
+			utils.NotFoundResponse(c, "Transfer not found")
+			return
+		}
+		if err.Error() == "transfer is not in transit" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Transfer is not in transit", err)
+			return
+		}
+		if err.Error() == "completion must be done at destination location" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Receipt must be done at destination location", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to receive transfer", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Stock transfer received successfully", receipt)
+}
+
+// GET /inventory/transfer-discrepancies
+func (h *InventoryHandler) GetTransferDiscrepancies(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var locationID *int
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		id, err := strconv.Atoi(locationParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &id
+	}
+
+	discrepancies, err := h.inventoryService.GetTransferDiscrepancies(companyID, c.Query("status"), locationID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get transfer discrepancies", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Transfer discrepancies retrieved successfully", discrepancies)
+}
+
+// GET /inventory/transfer-discrepancies/:id
+func (h *InventoryHandler) GetTransferDiscrepancy(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	discrepancyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid discrepancy ID", err)
+		return
+	}
+
+	discrepancy, err := h.inventoryService.GetTransferDiscrepancy(companyID, discrepancyID)
+	if err != nil {
+		if err.Error() == "transfer discrepancy not found" {
+			utils.NotFoundResponse(c, "Transfer discrepancy not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get transfer discrepancy", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Transfer discrepancy retrieved successfully", discrepancy)
+}
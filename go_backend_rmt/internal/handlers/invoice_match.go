@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceMatchHandler exposes three-way matching of supplier invoices
+type InvoiceMatchHandler struct {
+	service *services.InvoiceMatchService
+}
+
+func NewInvoiceMatchHandler() *InvoiceMatchHandler {
+	return &InvoiceMatchHandler{service: services.NewInvoiceMatchService()}
+}
+
+// GET /purchases/:id/invoice-match
+func (h *InvoiceMatchHandler) GetInvoiceMatch(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid purchase ID", err)
+		return
+	}
+
+	match, err := h.service.GetMatch(companyID, purchaseID)
+	if err != nil {
+		if err.Error() == "invoice match not found" {
+			utils.NotFoundResponse(c, "Invoice match not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get invoice match", err)
+		return
+	}
+	utils.SuccessResponse(c, "Invoice match retrieved successfully", match)
+}
+
+// POST /purchases/:id/invoice-match
+func (h *InvoiceMatchHandler) RecordInvoice(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid purchase ID", err)
+		return
+	}
+
+	var req models.RecordSupplierInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	match, err := h.service.RecordInvoice(companyID, userID, purchaseID, &req)
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			utils.NotFoundResponse(c, "Purchase not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to match supplier invoice", err)
+		return
+	}
+	utils.SuccessResponse(c, "Supplier invoice matched successfully", match)
+}
+
+// POST /purchases/:id/invoice-match/override
+func (h *InvoiceMatchHandler) RequestOverride(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid purchase ID", err)
+		return
+	}
+
+	var req models.RequestInvoiceMatchOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	request, err := h.service.RequestOverride(companyID, userID, purchaseID, req.Reason)
+	if err != nil {
+		if err.Error() == "invoice match not found" {
+			utils.NotFoundResponse(c, "Invoice match not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to request invoice match override", err)
+		return
+	}
+	utils.CreatedResponse(c, "Invoice match override requested successfully", request)
+}
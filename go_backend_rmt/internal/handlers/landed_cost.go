@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LandedCostHandler posts freight, duty and clearing charges over goods receipts
+type LandedCostHandler struct {
+	service *services.LandedCostService
+}
+
+func NewLandedCostHandler() *LandedCostHandler {
+	return &LandedCostHandler{service: services.NewLandedCostService()}
+}
+
+// GET /landed-cost-vouchers
+func (h *LandedCostHandler) GetVouchers(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var supplierID, goodsReceiptID *int
+	if param := c.Query("supplier_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid supplier ID", err)
+			return
+		}
+		supplierID = &id
+	}
+	if param := c.Query("goods_receipt_id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid goods receipt ID", err)
+			return
+		}
+		goodsReceiptID = &id
+	}
+
+	vouchers, err := h.service.GetVouchers(companyID, supplierID, goodsReceiptID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get landed cost vouchers", err)
+		return
+	}
+	utils.SuccessResponse(c, "Landed cost vouchers retrieved successfully", vouchers)
+}
+
+// GET /landed-cost-vouchers/:id
+func (h *LandedCostHandler) GetVoucher(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	voucherID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid voucher ID", err)
+		return
+	}
+
+	voucher, err := h.service.GetVoucher(companyID, voucherID)
+	if err != nil {
+		if err.Error() == "landed cost voucher not found" {
+			utils.NotFoundResponse(c, "Landed cost voucher not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get landed cost voucher", err)
+		return
+	}
+	utils.SuccessResponse(c, "Landed cost voucher retrieved successfully", voucher)
+}
+
+// POST /landed-cost-vouchers
+func (h *LandedCostHandler) CreateVoucher(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.CreateLandedCostVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	voucher, err := h.service.CreateVoucher(companyID, locationID, userID, &req)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "not found") {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create landed cost voucher", err)
+		return
+	}
+	utils.CreatedResponse(c, "Landed cost voucher created successfully", voucher)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"erp-backend/internal/models"
 	"erp-backend/internal/services"
@@ -21,86 +22,110 @@ func NewNotificationsHandler() *NotificationsHandler {
 
 // GET /notifications
 func (h *NotificationsHandler) List(c *gin.Context) {
-	companyID := c.GetInt("company_id")
-	userID := c.GetInt("user_id")
-	locationID := c.GetInt("location_id")
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
+		return
+	}
 
-	var locPtr *int
-	// Allow overriding by query param (useful for admin dashboards).
-	if v := c.Query("location_id"); v != "" {
-		if id, err := strconv.Atoi(v); err == nil {
-			locationID = id
-		}
+	filter := models.NotificationFilter{
+		LocationID: notificationsLocation(c),
+		UnreadOnly: c.Query("unread_only") == "true",
 	}
-	if locationID != 0 {
-		locPtr = &locationID
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		filter.Limit = limit
 	}
 
-	if companyID == 0 {
-		utils.ForbiddenResponse(c, "Company access required")
+	list, err := h.service.ListNotifications(companyID, userID, filter)
+	if err != nil {
+		respondNotificationsError(c, "Failed to load notifications", err)
 		return
 	}
-	if userID == 0 {
-		utils.ForbiddenResponse(c, "User access required")
+	utils.SuccessResponse(c, "Notifications retrieved", list)
+}
+
+// GET /notifications/unread-count
+func (h *NotificationsHandler) UnreadCount(c *gin.Context) {
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
 		return
 	}
 
-	list, err := h.service.ListNotifications(companyID, userID, locPtr)
+	n, err := h.service.UnreadCount(companyID, userID, notificationsLocation(c))
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to load notifications", err)
+		respondNotificationsError(c, "Failed to get unread count", err)
 		return
 	}
-	utils.SuccessResponse(c, "Notifications retrieved", list)
+	utils.SuccessResponse(c, "Unread count retrieved", gin.H{"unread": n})
 }
 
-// GET /notifications/unread-count
-func (h *NotificationsHandler) UnreadCount(c *gin.Context) {
-	companyID := c.GetInt("company_id")
-	userID := c.GetInt("user_id")
-	locationID := c.GetInt("location_id")
+// POST /notifications/mark-read
+func (h *NotificationsHandler) MarkRead(c *gin.Context) {
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
+		return
+	}
 
-	var locPtr *int
-	if v := c.Query("location_id"); v != "" {
-		if id, err := strconv.Atoi(v); err == nil {
-			locationID = id
-		}
+	var req models.MarkNotificationsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
 	}
-	if locationID != 0 {
-		locPtr = &locationID
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
 	}
 
-	if companyID == 0 {
-		utils.ForbiddenResponse(c, "Company access required")
+	if err := h.service.MarkRead(companyID, userID, req.Keys); err != nil {
+		respondNotificationsError(c, "Failed to mark notifications read", err)
 		return
 	}
-	if userID == 0 {
-		utils.ForbiddenResponse(c, "User access required")
+
+	utils.SuccessResponse(c, "Notifications marked read", nil)
+}
+
+// POST /notifications/mark-all-read
+func (h *NotificationsHandler) MarkAllRead(c *gin.Context) {
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
 		return
 	}
 
-	n, err := h.service.UnreadCount(companyID, userID, locPtr)
+	n, err := h.service.MarkAllRead(companyID, userID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get unread count", err)
+		respondNotificationsError(c, "Failed to mark notifications read", err)
 		return
 	}
-	utils.SuccessResponse(c, "Unread count retrieved", gin.H{"unread": n})
+	utils.SuccessResponse(c, "Notifications marked read", gin.H{"updated": n})
 }
 
-// POST /notifications/mark-read
-func (h *NotificationsHandler) MarkRead(c *gin.Context) {
-	companyID := c.GetInt("company_id")
-	userID := c.GetInt("user_id")
+// GET /notifications/preferences
+func (h *NotificationsHandler) GetPreferences(c *gin.Context) {
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
+		return
+	}
 
-	if companyID == 0 {
-		utils.ForbiddenResponse(c, "Company access required")
+	prefs, err := h.service.GetPreferences(companyID, userID)
+	if err != nil {
+		respondNotificationsError(c, "Failed to get notification preferences", err)
 		return
 	}
-	if userID == 0 {
-		utils.ForbiddenResponse(c, "User access required")
+	utils.SuccessResponse(c, "Notification preferences retrieved", prefs)
+}
+
+// PUT /notifications/preferences
+func (h *NotificationsHandler) UpdatePreferences(c *gin.Context) {
+	companyID, userID, ok := notificationsContext(c)
+	if !ok {
 		return
 	}
 
-	var req models.MarkNotificationsReadRequest
+	var req models.UpdateNotificationPreferencesRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
 		return
@@ -110,10 +135,47 @@ func (h *NotificationsHandler) MarkRead(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.MarkRead(companyID, userID, req.Keys); err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to mark notifications read", err)
+	prefs, err := h.service.UpdatePreferences(companyID, userID, &req)
+	if err != nil {
+		respondNotificationsError(c, "Failed to update notification preferences", err)
 		return
 	}
+	utils.SuccessResponse(c, "Notification preferences updated", prefs)
+}
 
-	utils.SuccessResponse(c, "Notifications marked read", nil)
+func notificationsContext(c *gin.Context) (companyID, userID int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return 0, 0, false
+	}
+	if userID == 0 {
+		utils.ForbiddenResponse(c, "User access required")
+		return 0, 0, false
+	}
+	return companyID, userID, true
+}
+
+// notificationsLocation is the user's location, overridable by query param
+// (useful for admin dashboards).
+func notificationsLocation(c *gin.Context) *int {
+	locationID := c.GetInt("location_id")
+	if v := c.Query("location_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		return nil
+	}
+	return &locationID
+}
+
+func respondNotificationsError(c *gin.Context, message string, err error) {
+	if strings.HasPrefix(err.Error(), "failed to") {
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+		return
+	}
+	utils.ErrorResponse(c, http.StatusBadRequest, message, err)
 }
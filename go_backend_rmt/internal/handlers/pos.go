@@ -17,12 +17,14 @@ import (
 type POSHandler struct {
 	posService      *services.POSService
 	settingsService *services.SettingsService
+	variantService  *services.ProductVariantService
 }
 
 func NewPOSHandler() *POSHandler {
 	return &POSHandler{
 		posService:      services.NewPOSService(),
 		settingsService: services.NewSettingsService(),
+		variantService:  services.NewProductVariantService(),
 	}
 }
 
@@ -73,6 +75,42 @@ func (h *POSHandler) GetPOSProducts(c *gin.Context) {
 	utils.SuccessResponse(c, "POS products retrieved successfully", products)
 }
 
+// GET /pos/products/:id/variant-grid
+func (h *POSHandler) GetVariantGrid(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		return
+	}
+
+	grid, err := h.variantService.GetVariantMatrix(companyID, productID, &locationID, "", "", true)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.NotFoundResponse(c, "Product not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get variant grid", err)
+		return
+	}
+	utils.SuccessResponse(c, "Variant grid retrieved successfully", grid)
+}
+
 // GET /pos/customers
 func (h *POSHandler) GetPOSCustomers(c *gin.Context) {
 	companyID := c.GetInt("company_id")
@@ -177,6 +215,14 @@ func (h *POSHandler) ProcessCheckout(c *gin.Context) {
 			}, nil)
 			return
 		}
+		var dunningBlocked *services.CustomerDunningBlockedError
+		if errors.As(err, &dunningBlocked) {
+			utils.JSONResponse(c, http.StatusBadRequest, false, "Customer credit blocked by dunning", gin.H{
+				"code":          "CUSTOMER_DUNNING_BLOCKED",
+				"dunning_level": dunningBlocked.DunningLevel,
+			}, nil)
+			return
+		}
 		var cl *services.CreditLimitExceededError
 		if errors.As(err, &cl) {
 			utils.JSONResponse(c, http.StatusBadRequest, false, "Credit limit exceeded", gin.H{
@@ -285,6 +331,14 @@ func (h *POSHandler) EditSale(c *gin.Context) {
 			}, nil)
 			return
 		}
+		var dunningBlocked *services.CustomerDunningBlockedError
+		if errors.As(err, &dunningBlocked) {
+			utils.JSONResponse(c, http.StatusBadRequest, false, "Customer credit blocked by dunning", gin.H{
+				"code":          "CUSTOMER_DUNNING_BLOCKED",
+				"dunning_level": dunningBlocked.DunningLevel,
+			}, nil)
+			return
+		}
 		var cl *services.CreditLimitExceededError
 		if errors.As(err, &cl) {
 			utils.JSONResponse(c, http.StatusBadRequest, false, "Credit limit exceeded", gin.H{
@@ -417,10 +471,16 @@ func (h *POSHandler) PrintInvoice(c *gin.Context) {
 		raffleCoupons = nil
 	}
 
+	einvoice, err := services.NewEInvoiceService().GetEInvoiceForSale(companyID, sale.SaleID)
+	if err != nil {
+		einvoice = nil
+	}
+
 	utils.SuccessResponse(c, "Print data", models.POSPrintDataResponse{
 		Sale:          *sale,
 		Company:       *company,
 		RaffleCoupons: raffleCoupons,
+		EInvoice:      einvoice,
 	})
 }
 
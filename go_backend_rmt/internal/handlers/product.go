@@ -15,6 +15,7 @@ type ProductHandler struct {
 	productService   *services.ProductService
 	inventoryService *services.InventoryService
 	storageService   *services.ProductStorageService
+	variantService   *services.ProductVariantService
 }
 
 func NewProductHandler() *ProductHandler {
@@ -22,6 +23,7 @@ func NewProductHandler() *ProductHandler {
 		productService:   services.NewProductService(),
 		inventoryService: services.NewInventoryService(),
 		storageService:   services.NewProductStorageService(),
+		variantService:   services.NewProductVariantService(),
 	}
 }
 
@@ -276,6 +278,96 @@ func (h *ProductHandler) ReplaceStorageAssignments(c *gin.Context) {
 	utils.SuccessResponse(c, "Storage assignments updated successfully", items)
 }
 
+// GET /products/:id/variant-matrix
+func (h *ProductHandler) GetVariantMatrix(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		return
+	}
+	var locationID *int
+	if raw := c.Query("location_id"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &parsed
+	}
+	matrix, err := h.variantService.GetVariantMatrix(companyID, productID, locationID, c.Query("from_date"), c.Query("to_date"), c.Query("active_only") == "true")
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.NotFoundResponse(c, "Product not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get variant matrix", err)
+		return
+	}
+	utils.SuccessResponse(c, "Variant matrix retrieved successfully", matrix)
+}
+
+// PUT /products/:id/variant-dimensions
+func (h *ProductHandler) SetVariantDimensions(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		return
+	}
+	var req models.SetProductVariantDimensionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+	matrix, err := h.variantService.SetDimensions(companyID, productID, &req)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.NotFoundResponse(c, "Product not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update variant dimensions", err)
+		return
+	}
+	utils.SuccessResponse(c, "Variant dimensions updated successfully", matrix)
+}
+
+// POST /products/:id/variant-matrix/sync
+func (h *ProductHandler) SyncVariantMatrix(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid product ID", err)
+		return
+	}
+	matrix, err := h.variantService.SyncMatrix(companyID, productID)
+	if err != nil {
+		if err.Error() == "product not found" {
+			utils.NotFoundResponse(c, "Product not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to sync variant matrix", err)
+		return
+	}
+	utils.SuccessResponse(c, "Variant matrix synced successfully", matrix)
+}
+
 // GET /categories
 func (h *ProductHandler) GetCategories(c *gin.Context) {
 	companyID := c.GetInt("company_id")
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,11 +13,15 @@ import (
 )
 
 type ProductAttributeHandler struct {
-	service *services.ProductAttributeService
+	service        *services.ProductAttributeService
+	variantService *services.ProductVariantService
 }
 
 func NewProductAttributeHandler() *ProductAttributeHandler {
-	return &ProductAttributeHandler{service: services.NewProductAttributeService()}
+	return &ProductAttributeHandler{
+		service:        services.NewProductAttributeService(),
+		variantService: services.NewProductVariantService(),
+	}
 }
 
 // GET /product-attribute-definitions
@@ -79,6 +84,13 @@ func (h *ProductAttributeHandler) UpdateDefinition(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update product attribute definition", err)
 		return
 	}
+	if req.Options != nil {
+		// Products whose variant dimensions follow this attribute pick up
+		// added options straight away.
+		if err := h.variantService.SyncMatricesForAttribute(companyID, id); err != nil {
+			log.Printf("failed to sync variant matrices for attribute %d: %v", id, err)
+		}
+	}
 	utils.SuccessResponse(c, "Product attribute definition updated successfully", nil)
 }
 
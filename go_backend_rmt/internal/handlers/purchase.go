@@ -698,8 +698,7 @@ func (h *PurchaseHandler) UploadPurchaseInvoice(c *gin.Context) {
 	}
 
 	// Save file under /uploads/invoices
-	up := services.GetUploadPath()
-	path, err := services.SaveUploadedFile(up, "invoices", file, services.AllowlistInvoiceOrReceipt())
+	path, err := services.StoreUploadedFile("invoices", file, services.AllowlistInvoiceOrReceipt())
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to save file", err)
 		return
@@ -782,8 +781,7 @@ func (h *PurchaseHandler) UploadPurchaseReturnReceipt(c *gin.Context) {
 		return
 	}
 
-	up := services.GetUploadPath()
-	path, err := services.SaveUploadedFile(up, "returns", file, services.AllowlistInvoiceOrReceipt())
+	path, err := services.StoreUploadedFile("returns", file, services.AllowlistInvoiceOrReceipt())
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to save file", err)
 		return
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecurringTemplateHandler manages recurring voucher and expense templates.
+// Occurrences are created by the background scheduler.
+type RecurringTemplateHandler struct {
+	service *services.RecurringTemplateService
+}
+
+func NewRecurringTemplateHandler() *RecurringTemplateHandler {
+	return &RecurringTemplateHandler{service: services.NewRecurringTemplateService()}
+}
+
+// GET /recurring-templates
+func (h *RecurringTemplateHandler) ListTemplates(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	filters := map[string]string{
+		"document_type": c.Query("document_type"),
+		"is_active":     c.Query("is_active"),
+	}
+
+	templates, err := h.service.ListTemplates(companyID, filters)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to list recurring templates", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring templates retrieved successfully", templates)
+}
+
+// POST /recurring-templates
+func (h *RecurringTemplateHandler) CreateTemplate(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateRecurringTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	t, err := h.service.CreateTemplate(companyID, userID, &req)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to create recurring template", err)
+		return
+	}
+	utils.CreatedResponse(c, "Recurring template created successfully", t)
+}
+
+// GET /recurring-templates/:id
+func (h *RecurringTemplateHandler) GetTemplate(c *gin.Context) {
+	companyID, _, id, ok := recurringTemplateContext(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.service.GetTemplate(companyID, id)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to get recurring template", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring template retrieved successfully", t)
+}
+
+// PUT /recurring-templates/:id
+func (h *RecurringTemplateHandler) UpdateTemplate(c *gin.Context) {
+	companyID, userID, id, ok := recurringTemplateContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateRecurringTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	t, err := h.service.UpdateTemplate(companyID, userID, id, &req)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to update recurring template", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring template updated successfully", t)
+}
+
+// DELETE /recurring-templates/:id
+func (h *RecurringTemplateHandler) DeleteTemplate(c *gin.Context) {
+	companyID, _, id, ok := recurringTemplateContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(companyID, id); err != nil {
+		respondRecurringTemplateError(c, "Failed to delete recurring template", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring template deleted successfully", nil)
+}
+
+// GET /recurring-templates/:id/runs
+// Run log of created, skipped and failed occurrences; filter with status.
+func (h *RecurringTemplateHandler) ListRuns(c *gin.Context) {
+	companyID, _, id, ok := recurringTemplateContext(c)
+	if !ok {
+		return
+	}
+	filter := models.RecurringTemplateRunFilter{Status: c.Query("status")}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	runs, err := h.service.ListRuns(companyID, id, filter)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to list recurring runs", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring runs retrieved successfully", runs)
+}
+
+// GET /recurring-templates/:id/preview
+func (h *RecurringTemplateHandler) PreviewTemplate(c *gin.Context) {
+	companyID, _, id, ok := recurringTemplateContext(c)
+	if !ok {
+		return
+	}
+	count := 0
+	if raw := c.Query("count"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid count", err)
+			return
+		}
+		count = n
+	}
+
+	previews, err := h.service.PreviewTemplate(companyID, id, count)
+	if err != nil {
+		respondRecurringTemplateError(c, "Failed to preview recurring template", err)
+		return
+	}
+	utils.SuccessResponse(c, "Recurring template preview generated successfully", previews)
+}
+
+func recurringTemplateContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid recurring template ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondRecurringTemplateError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplenishmentHandler exposes reorder suggestions and draft generation
+type ReplenishmentHandler struct {
+	service *services.ReplenishmentService
+}
+
+func NewReplenishmentHandler() *ReplenishmentHandler {
+	return &ReplenishmentHandler{service: services.NewReplenishmentService()}
+}
+
+// GET /replenishment/suggestions
+func (h *ReplenishmentHandler) GetSuggestions(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+	windowDays := 0
+	if windowParam := c.Query("window_days"); windowParam != "" {
+		days, err := strconv.Atoi(windowParam)
+		if err != nil || days <= 0 || days > 365 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid window_days", err)
+			return
+		}
+		windowDays = days
+	}
+
+	plan, err := h.service.BuildPlan(companyID, locationID, windowDays)
+	if err != nil {
+		if err.Error() == "location not found" {
+			utils.NotFoundResponse(c, "Location not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to build replenishment suggestions", err)
+		return
+	}
+	utils.SuccessResponse(c, "Replenishment suggestions retrieved successfully", plan)
+}
+
+// POST /replenishment/purchase-drafts
+func (h *ReplenishmentHandler) CreatePurchaseDrafts(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+
+	var req models.CreateReplenishmentDraftsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	result, err := h.service.CreatePurchaseDrafts(companyID, locationID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create purchase drafts", err)
+		return
+	}
+	utils.CreatedResponse(c, "Purchase drafts created successfully", result)
+}
+
+// POST /replenishment/transfer-drafts
+func (h *ReplenishmentHandler) CreateTransferDrafts(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+
+	var req models.CreateReplenishmentDraftsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	result, err := h.service.CreateTransferDrafts(companyID, locationID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create transfer drafts", err)
+		return
+	}
+	utils.CreatedResponse(c, "Transfer drafts created successfully", result)
+}
+
+// GET /replenishment/rules
+func (h *ReplenishmentHandler) GetRules(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	locationID := 0
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	rules, err := h.service.ListRules(companyID, locationID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get replenishment rules", err)
+		return
+	}
+	utils.SuccessResponse(c, "Replenishment rules retrieved successfully", rules)
+}
+
+// PUT /replenishment/rules
+func (h *ReplenishmentHandler) UpsertRule(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+
+	var req models.UpsertReplenishmentRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	rule, err := h.service.UpsertRule(companyID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to save replenishment rule", err)
+		return
+	}
+	utils.SuccessResponse(c, "Replenishment rule saved successfully", rule)
+}
+
+// DELETE /replenishment/rules/:id
+func (h *ReplenishmentHandler) DeleteRule(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid rule ID", err)
+		return
+	}
+	if err := h.service.DeleteRule(companyID, id); err != nil {
+		if err.Error() == "replenishment rule not found" {
+			utils.NotFoundResponse(c, "Replenishment rule not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete replenishment rule", err)
+		return
+	}
+	utils.SuccessResponse(c, "Replenishment rule deleted successfully", nil)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportJobHandler struct {
+	service *services.ReportJobService
+}
+
+func NewReportJobHandler() *ReportJobHandler {
+	return &ReportJobHandler{service: services.NewReportJobService()}
+}
+
+// POST /report-jobs
+// Queues a heavy report. Identical requests against unchanged data return the
+// existing job with cache_hit set.
+func (h *ReportJobHandler) SubmitReportJob(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateReportJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	job, err := h.service.Submit(companyID, userID, c.GetInt("role_id"), &req)
+	if err != nil {
+		respondReportJobError(c, "Failed to submit report job", err)
+		return
+	}
+	if job.Status == models.ReportJobSucceeded {
+		utils.SuccessResponse(c, "Report served from cache", job)
+		return
+	}
+	utils.JSONResponse(c, http.StatusAccepted, true, "Report job queued", job, nil)
+}
+
+// GET /report-jobs
+func (h *ReportJobHandler) ListReportJobs(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var filter models.ReportJobFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if err := utils.ValidateStruct(&filter); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	jobs, err := h.service.ListJobs(companyID, filter)
+	if err != nil {
+		respondReportJobError(c, "Failed to list report jobs", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report jobs retrieved successfully", jobs)
+}
+
+// GET /report-jobs/:id
+func (h *ReportJobHandler) GetReportJob(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || jobID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report job ID", err)
+		return
+	}
+
+	job, err := h.service.GetJob(companyID, jobID)
+	if err != nil {
+		respondReportJobError(c, "Failed to get report job", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report job retrieved successfully", job)
+}
+
+// GET /report-jobs/:id/result?format=json|excel|pdf
+// Redirects to a signed URL for the stored output.
+func (h *ReportJobHandler) DownloadReportJobResult(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || jobID <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report job ID", err)
+		return
+	}
+
+	url, err := h.service.ResultURL(companyID, jobID, strings.ToLower(c.Query("format")))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "report job is ") {
+			utils.ConflictResponse(c, err.Error())
+			return
+		}
+		respondReportJobError(c, "Failed to get report result", err)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+func respondReportJobError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case msg == "location access denied":
+		utils.ForbiddenResponse(c, "Location access denied")
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
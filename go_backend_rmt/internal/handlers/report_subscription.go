@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportSubscriptionHandler manages a user's own scheduled report emails.
+type ReportSubscriptionHandler struct {
+	service *services.ReportSubscriptionService
+}
+
+func NewReportSubscriptionHandler() *ReportSubscriptionHandler {
+	return &ReportSubscriptionHandler{service: services.NewReportSubscriptionService()}
+}
+
+// POST /report-subscriptions
+func (h *ReportSubscriptionHandler) CreateReportSubscription(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(companyID, userID, &req)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to create report subscription", err)
+		return
+	}
+	utils.CreatedResponse(c, "Report subscription created successfully", sub)
+}
+
+// GET /report-subscriptions
+func (h *ReportSubscriptionHandler) ListReportSubscriptions(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	subs, err := h.service.ListSubscriptions(companyID, userID)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to list report subscriptions", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report subscriptions retrieved successfully", subs)
+}
+
+// GET /report-subscriptions/:id
+func (h *ReportSubscriptionHandler) GetReportSubscription(c *gin.Context) {
+	companyID, userID, id, ok := reportSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(companyID, userID, id)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to get report subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report subscription retrieved successfully", sub)
+}
+
+// PUT /report-subscriptions/:id
+func (h *ReportSubscriptionHandler) UpdateReportSubscription(c *gin.Context) {
+	companyID, userID, id, ok := reportSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateReportSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(companyID, userID, id, &req)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to update report subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report subscription updated successfully", sub)
+}
+
+// DELETE /report-subscriptions/:id
+func (h *ReportSubscriptionHandler) DeleteReportSubscription(c *gin.Context) {
+	companyID, userID, id, ok := reportSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(companyID, userID, id); err != nil {
+		respondReportSubscriptionError(c, "Failed to delete report subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report subscription deleted successfully", nil)
+}
+
+// POST /report-subscriptions/:id/run
+// Sends the report now; the regular schedule continues afterwards.
+func (h *ReportSubscriptionHandler) RunReportSubscription(c *gin.Context) {
+	companyID, userID, id, ok := reportSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.RunNow(companyID, userID, id)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to run report subscription", err)
+		return
+	}
+	utils.JSONResponse(c, http.StatusAccepted, true, "Report subscription queued for delivery", sub, nil)
+}
+
+// GET /report-subscriptions/:id/deliveries
+func (h *ReportSubscriptionHandler) ListReportSubscriptionDeliveries(c *gin.Context) {
+	companyID, userID, id, ok := reportSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	var filter models.ReportSubscriptionDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid query parameters", err)
+		return
+	}
+	if err := utils.ValidateStruct(&filter); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(companyID, userID, id, filter)
+	if err != nil {
+		respondReportSubscriptionError(c, "Failed to list report deliveries", err)
+		return
+	}
+	utils.SuccessResponse(c, "Report deliveries retrieved successfully", deliveries)
+}
+
+func reportSubscriptionContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report subscription ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func respondReportSubscriptionError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
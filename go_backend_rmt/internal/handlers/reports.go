@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"erp-backend/internal/models"
 	"erp-backend/internal/services"
 	"erp-backend/internal/utils"
 
@@ -23,7 +25,7 @@ func NewReportsHandler() *ReportsHandler {
 	}
 }
 
-// handleReportResponse streams report data or exports it to Excel/PDF based on
+// handleReportResponse streams report data or exports it to Excel/PDF/CSV based on
 // the requested format.
 func (h *ReportsHandler) handleReportResponse(c *gin.Context, message string, data interface{}) {
 	format := c.Query("format")
@@ -45,11 +47,41 @@ func (h *ReportsHandler) handleReportResponse(c *gin.Context, message string, da
 		}
 		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportExportFilename(endpoint, "pdf")))
 		c.Data(http.StatusOK, "application/pdf", content)
+	case "csv":
+		content, err := utils.GenerateCSV(endpoint, data)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate CSV", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", utils.ReportExportFilename(endpoint, "csv")))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", content)
 	default:
 		utils.SuccessResponse(c, message, data)
 	}
 }
 
+// reportLocations reads the location_id filter, limited to the locations the
+// caller may report on. It writes the error response and returns false when
+// the filter is not allowed.
+func (h *ReportsHandler) reportLocations(c *gin.Context) ([]int, bool) {
+	var requested *int
+	if val := c.Query("location_id"); val != "" {
+		if id, err := strconv.Atoi(val); err == nil {
+			requested = &id
+		}
+	}
+	locationIDs, err := h.reportsService.ReportLocations(c.GetInt("company_id"), c.GetInt("user_id"), c.GetInt("role_id"), requested)
+	if err != nil {
+		if err.Error() == "location access denied" {
+			utils.ForbiddenResponse(c, "Location access denied")
+		} else {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resolve report location", err)
+		}
+		return nil, false
+	}
+	return locationIDs, true
+}
+
 // GET /reports/sales-summary
 func (h *ReportsHandler) GetSalesSummary(c *gin.Context) {
 	companyID := c.GetInt("company_id")
@@ -84,11 +116,9 @@ func (h *ReportsHandler) GetStockSummary(c *gin.Context) {
 		return
 	}
 
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 
 	var productID *int
@@ -98,7 +128,7 @@ func (h *ReportsHandler) GetStockSummary(c *gin.Context) {
 		}
 	}
 
-	summary, err := h.reportsService.GetStockSummary(companyID, locationID, productID)
+	summary, err := h.reportsService.GetStockSummary(companyID, locationIDs, productID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get stock summary", err)
 		return
@@ -178,16 +208,14 @@ func (h *ReportsHandler) GetItemMovement(c *gin.Context) {
 		return
 	}
 
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetItemMovement(companyID, locationID, fromDate, toDate)
+	data, err := h.reportsService.GetItemMovement(companyID, locationIDs, fromDate, toDate)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get item movement report", err)
 		return
@@ -202,13 +230,11 @@ func (h *ReportsHandler) GetValuationReport(c *gin.Context) {
 		utils.ForbiddenResponse(c, "Company access required")
 		return
 	}
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
-	data, err := h.reportsService.GetValuationReport(companyID, locationID)
+	data, err := h.reportsService.GetValuationReport(companyID, locationIDs)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get valuation report", err)
 		return
@@ -224,16 +250,14 @@ func (h *ReportsHandler) GetPurchaseVsReturns(c *gin.Context) {
 		return
 	}
 
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetPurchaseVsReturns(companyID, locationID, fromDate, toDate)
+	data, err := h.reportsService.GetPurchaseVsReturns(companyID, locationIDs, fromDate, toDate)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get purchase vs returns report", err)
 		return
@@ -248,16 +272,14 @@ func (h *ReportsHandler) GetSupplierReport(c *gin.Context) {
 		utils.ForbiddenResponse(c, "Company access required")
 		return
 	}
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetSupplierReport(companyID, locationID, fromDate, toDate)
+	data, err := h.reportsService.GetSupplierReport(companyID, locationIDs, fromDate, toDate)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get supplier report", err)
 		return
@@ -265,6 +287,29 @@ func (h *ReportsHandler) GetSupplierReport(c *gin.Context) {
 	h.handleReportResponse(c, "Supplier report retrieved successfully", data)
 }
 
+// GET /reports/invoice-match-variances
+func (h *ReportsHandler) GetInvoiceMatchVarianceReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
+	}
+	fromDate := c.Query("from_date")
+	toDate := c.Query("to_date")
+	status := c.Query("status")
+
+	data, err := h.reportsService.GetInvoiceMatchVarianceReport(companyID, locationIDs, fromDate, toDate, status)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get invoice match variance report", err)
+		return
+	}
+	h.handleReportResponse(c, "Invoice match variance report retrieved successfully", data)
+}
+
 // GET /reports/daily-cash
 func (h *ReportsHandler) GetDailyCashReport(c *gin.Context) {
 	companyID := c.GetInt("company_id")
@@ -272,16 +317,14 @@ func (h *ReportsHandler) GetDailyCashReport(c *gin.Context) {
 		utils.ForbiddenResponse(c, "Company access required")
 		return
 	}
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetDailyCashReport(companyID, locationID, fromDate, toDate)
+	data, err := h.reportsService.GetDailyCashReport(companyID, locationIDs, fromDate, toDate)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get daily cash report", err)
 		return
@@ -359,16 +402,14 @@ func (h *ReportsHandler) GetIncomeExpenseReport(c *gin.Context) {
 		utils.ForbiddenResponse(c, "Company access required")
 		return
 	}
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetIncomeExpenseReport(companyID, locationID, fromDate, toDate)
+	data, err := h.reportsService.GetIncomeExpenseReport(companyID, locationIDs, fromDate, toDate)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get income vs expense report", err)
 		return
@@ -377,6 +418,8 @@ func (h *ReportsHandler) GetIncomeExpenseReport(c *gin.Context) {
 }
 
 // GET /reports/general-ledger
+// dimension_value_id (repeatable) filters and group_by_dimension orders by an
+// analytic dimension; the same applies to trial balance and profit and loss.
 func (h *ReportsHandler) GetGeneralLedger(c *gin.Context) {
 	companyID := c.GetInt("company_id")
 	if companyID == 0 {
@@ -392,7 +435,12 @@ func (h *ReportsHandler) GetGeneralLedger(c *gin.Context) {
 		}
 	}
 
-	data, err := h.reportsService.GetGeneralLedger(companyID, fromDate, toDate, limit)
+	dims, ok := ledgerDimensionFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.reportsService.GetGeneralLedger(companyID, fromDate, toDate, limit, dims)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get general ledger report", err)
 		return
@@ -410,7 +458,12 @@ func (h *ReportsHandler) GetTrialBalance(c *gin.Context) {
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetTrialBalance(companyID, fromDate, toDate)
+	dims, ok := ledgerDimensionFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.reportsService.GetTrialBalance(companyID, fromDate, toDate, dims)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get trial balance report", err)
 		return
@@ -428,7 +481,12 @@ func (h *ReportsHandler) GetProfitLoss(c *gin.Context) {
 	fromDate := c.Query("from_date")
 	toDate := c.Query("to_date")
 
-	data, err := h.reportsService.GetProfitLoss(companyID, fromDate, toDate)
+	dims, ok := ledgerDimensionFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.reportsService.GetProfitLoss(companyID, fromDate, toDate, dims)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get profit and loss report", err)
 		return
@@ -463,14 +521,12 @@ func (h *ReportsHandler) GetOutstandingReport(c *gin.Context) {
 		utils.ForbiddenResponse(c, "Company access required")
 		return
 	}
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
 
-	data, err := h.reportsService.GetOutstandingReport(companyID, locationID)
+	data, err := h.reportsService.GetOutstandingReport(companyID, locationIDs)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get outstanding report", err)
 		return
@@ -540,13 +596,11 @@ func (h *ReportsHandler) GetTopPerformers(c *gin.Context) {
 
 func (h *ReportsHandler) GetAssetRegisterReport(c *gin.Context) {
 	companyID := c.GetInt("company_id")
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
-	data, err := h.reportsService.GetAssetRegisterReport(companyID, locationID, c.Query("from_date"), c.Query("to_date"))
+	data, err := h.reportsService.GetAssetRegisterReport(companyID, locationIDs, c.Query("from_date"), c.Query("to_date"))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get asset register report", err)
 		return
@@ -556,13 +610,11 @@ func (h *ReportsHandler) GetAssetRegisterReport(c *gin.Context) {
 
 func (h *ReportsHandler) GetAssetValueSummaryReport(c *gin.Context) {
 	companyID := c.GetInt("company_id")
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
-	data, err := h.reportsService.GetAssetValueSummary(companyID, locationID)
+	data, err := h.reportsService.GetAssetValueSummary(companyID, locationIDs)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get asset value summary report", err)
 		return
@@ -572,13 +624,11 @@ func (h *ReportsHandler) GetAssetValueSummaryReport(c *gin.Context) {
 
 func (h *ReportsHandler) GetConsumableConsumptionReport(c *gin.Context) {
 	companyID := c.GetInt("company_id")
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
-	data, err := h.reportsService.GetConsumableConsumptionReport(companyID, locationID, c.Query("from_date"), c.Query("to_date"))
+	data, err := h.reportsService.GetConsumableConsumptionReport(companyID, locationIDs, c.Query("from_date"), c.Query("to_date"))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consumable consumption report", err)
 		return
@@ -588,16 +638,43 @@ func (h *ReportsHandler) GetConsumableConsumptionReport(c *gin.Context) {
 
 func (h *ReportsHandler) GetConsumableBalanceReport(c *gin.Context) {
 	companyID := c.GetInt("company_id")
-	var locationID *int
-	if val := c.Query("location_id"); val != "" {
-		if id, err := strconv.Atoi(val); err == nil {
-			locationID = &id
-		}
+	locationIDs, ok := h.reportLocations(c)
+	if !ok {
+		return
 	}
-	data, err := h.reportsService.GetConsumableBalanceReport(companyID, locationID)
+	data, err := h.reportsService.GetConsumableBalanceReport(companyID, locationIDs)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get consumable balance report", err)
 		return
 	}
 	h.handleReportResponse(c, "Consumable balance report retrieved successfully", data)
 }
+
+// ledgerDimensionFilterFromQuery reads the analytic dimension filter of the
+// ledger reports. dimension_value_id may repeat or be comma separated.
+func ledgerDimensionFilterFromQuery(c *gin.Context) (models.LedgerDimensionFilter, bool) {
+	var f models.LedgerDimensionFilter
+	for _, raw := range c.QueryArray("dimension_value_id") {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			id, err := strconv.Atoi(part)
+			if err != nil || id <= 0 {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid dimension_value_id", err)
+				return f, false
+			}
+			f.ValueIDs = append(f.ValueIDs, id)
+		}
+	}
+	if val := c.Query("group_by_dimension"); val != "" {
+		id, err := strconv.Atoi(val)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group_by_dimension", err)
+			return f, false
+		}
+		f.GroupByDimensionID = id
+	}
+	return f, true
+}
@@ -23,7 +23,7 @@ func NewRoleHandler() *RoleHandler {
 
 // GET /roles
 func (h *RoleHandler) GetRoles(c *gin.Context) {
-	roles, err := h.roleService.GetRoles()
+	roles, err := h.roleService.GetRoles(c.GetInt("company_id"))
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get roles", err)
 		return
@@ -47,7 +47,7 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
-	role, err := h.roleService.CreateRole(&req)
+	role, err := h.roleService.CreateRole(c.GetInt("company_id"), &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to create role", err)
 		return
@@ -77,7 +77,7 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 		return
 	}
 
-	err = h.roleService.UpdateRole(roleID, &req)
+	err = h.roleService.UpdateRole(c.GetInt("company_id"), roleID, &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update role", err)
 		return
@@ -94,7 +94,7 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
-	err = h.roleService.DeleteRole(roleID)
+	err = h.roleService.DeleteRole(c.GetInt("company_id"), roleID)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to delete role", err)
 		return
@@ -122,8 +122,12 @@ func (h *RoleHandler) GetRolePermissions(c *gin.Context) {
 		return
 	}
 
-	roleWithPermissions, err := h.roleService.GetRolePermissions(roleID)
+	roleWithPermissions, err := h.roleService.GetRolePermissions(c.GetInt("company_id"), roleID)
 	if err != nil {
+		if err.Error() == "role not found" {
+			utils.NotFoundResponse(c, "Role not found")
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get role permissions", err)
 		return
 	}
@@ -152,7 +156,7 @@ func (h *RoleHandler) AssignPermissions(c *gin.Context) {
 		return
 	}
 
-	err = h.roleService.AssignPermissions(roleID, &req)
+	err = h.roleService.AssignPermissions(c.GetInt("company_id"), roleID, &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to assign permissions", err)
 		return
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SalesOrderHandler exposes sales orders, delivery notes and backorders
+type SalesOrderHandler struct {
+	service *services.SalesOrderService
+}
+
+func NewSalesOrderHandler() *SalesOrderHandler {
+	return &SalesOrderHandler{service: services.NewSalesOrderService()}
+}
+
+// respondSalesOrderError maps service errors: missing records are 404,
+// storage failures 500 and everything else a rejected request.
+func respondSalesOrderError(c *gin.Context, message string, err error) {
+	var stockApprovalErr *services.NegativeStockApprovalRequiredError
+	if errors.As(err, &stockApprovalErr) {
+		utils.JSONResponse(c, http.StatusForbidden, false, stockApprovalErr.Error(), gin.H{
+			"code": "NEGATIVE_STOCK_APPROVAL_REQUIRED",
+		}, nil)
+		return
+	}
+	var profitApprovalErr *services.NegativeProfitApprovalRequiredError
+	if errors.As(err, &profitApprovalErr) {
+		utils.JSONResponse(c, http.StatusForbidden, false, profitApprovalErr.Error(), gin.H{
+			"code":    "NEGATIVE_PROFIT_APPROVAL_REQUIRED",
+			"details": profitApprovalErr.Details,
+		}, nil)
+		return
+	}
+	var profitBlockedErr *services.NegativeProfitNotAllowedError
+	if errors.As(err, &profitBlockedErr) {
+		utils.JSONResponse(c, http.StatusBadRequest, false, profitBlockedErr.Error(), gin.H{
+			"code":    "NEGATIVE_PROFIT_NOT_ALLOWED",
+			"details": profitBlockedErr.Details,
+		}, nil)
+		return
+	}
+
+	msg := err.Error()
+	switch {
+	case msg == "sales order not found", msg == "delivery not found", msg == "quote not found":
+		utils.NotFoundResponse(c, strings.ToUpper(msg[:1])+msg[1:])
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
+
+func salesOrderIDParam(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sales order ID", err)
+		return 0, false
+	}
+	return id, true
+}
+
+// GET /sales-orders
+func (h *SalesOrderHandler) GetSalesOrders(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	filters := map[string]string{}
+	for _, key := range []string{"status", "customer_id", "location_id"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+
+	orders, err := h.service.ListSalesOrders(companyID, filters)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid filter", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get sales orders", err)
+		return
+	}
+	utils.SuccessResponse(c, "Sales orders retrieved successfully", orders)
+}
+
+// GET /sales-orders/backorders
+func (h *SalesOrderHandler) GetBackorders(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var locationID *int
+	if v := c.Query("location_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &id
+	}
+
+	backorders, err := h.service.ListBackorders(companyID, locationID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get backorders", err)
+		return
+	}
+	utils.SuccessResponse(c, "Backorders retrieved successfully", backorders)
+}
+
+// GET /sales-orders/:id
+func (h *SalesOrderHandler) GetSalesOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	orderID, ok := salesOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.GetSalesOrder(companyID, orderID)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to get sales order", err)
+		return
+	}
+	utils.SuccessResponse(c, "Sales order retrieved successfully", order)
+}
+
+// POST /sales-orders
+func (h *SalesOrderHandler) CreateSalesOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.CreateSalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	order, err := h.service.CreateSalesOrder(companyID, locationID, userID, &req)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to create sales order", err)
+		return
+	}
+	utils.CreatedResponse(c, "Sales order created successfully", order)
+}
+
+// POST /sales/quotes/:id/convert-to-order
+func (h *SalesOrderHandler) ConvertQuoteToSalesOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	quoteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid quote ID", err)
+		return
+	}
+
+	var req models.ConvertQuoteToSalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	order, err := h.service.ConvertQuoteToSalesOrder(companyID, quoteID, userID, &req)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to convert quote to sales order", err)
+		return
+	}
+	utils.CreatedResponse(c, "Sales order created from quote successfully", order)
+}
+
+// POST /sales-orders/:id/deliveries
+func (h *SalesOrderHandler) CreateDelivery(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	orderID, ok := salesOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSalesOrderDeliveryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	delivery, err := h.service.CreateDelivery(companyID, orderID, userID, &req)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to create delivery", err)
+		return
+	}
+	utils.CreatedResponse(c, "Delivery created successfully", delivery)
+}
+
+// POST /sales-orders/:id/invoice
+func (h *SalesOrderHandler) InvoiceSalesOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	orderID, ok := salesOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.InvoiceSalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	result, err := h.service.InvoiceDeliveries(companyID, orderID, userID, &req)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to invoice sales order", err)
+		return
+	}
+	utils.CreatedResponse(c, "Sales order invoiced successfully", result)
+}
+
+// POST /sales-orders/:id/close
+func (h *SalesOrderHandler) CloseSalesOrder(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	orderID, ok := salesOrderIDParam(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.CloseSalesOrder(companyID, orderID, userID)
+	if err != nil {
+		respondSalesOrderError(c, "Failed to close sales order", err)
+		return
+	}
+	utils.SuccessResponse(c, "Sales order closed successfully", order)
+}
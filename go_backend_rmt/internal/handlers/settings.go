@@ -197,6 +197,80 @@ func (h *SettingsHandler) UpdateTaxSettings(c *gin.Context) {
 	utils.SuccessResponse(c, "Tax settings updated successfully", nil)
 }
 
+// Three-way match settings
+func (h *SettingsHandler) GetThreeWayMatchSettings(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	settings, err := h.service.GetThreeWayMatchSettings(companyID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get three-way match settings", err)
+		return
+	}
+	utils.SuccessResponse(c, "Three-way match settings retrieved successfully", settings)
+}
+
+func (h *SettingsHandler) UpdateThreeWayMatchSettings(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	var req models.ThreeWayMatchSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+	if err := h.service.UpdateThreeWayMatchSettings(companyID, req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update three-way match settings", err)
+		return
+	}
+	utils.SuccessResponse(c, "Three-way match settings updated successfully", nil)
+}
+
+// Dunning settings
+func (h *SettingsHandler) GetDunningSettings(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	settings, err := h.service.GetDunningSettings(companyID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get dunning settings", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dunning settings retrieved successfully", settings)
+}
+
+func (h *SettingsHandler) UpdateDunningSettings(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	var req models.DunningSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+	if err := h.service.UpdateDunningSettings(companyID, req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update dunning settings", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dunning settings updated successfully", nil)
+}
+
 // Device control settings
 func (h *SettingsHandler) GetDeviceControlSettings(c *gin.Context) {
 	companyID := c.GetInt("company_id")
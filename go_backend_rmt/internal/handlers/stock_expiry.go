@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StockExpiryHandler exposes the near-expiry report and expired stock write-offs
+type StockExpiryHandler struct {
+	service *services.StockExpiryService
+}
+
+func NewStockExpiryHandler() *StockExpiryHandler {
+	return &StockExpiryHandler{service: services.NewStockExpiryService()}
+}
+
+// GET /inventory/expiry-report
+func (h *StockExpiryHandler) GetExpiryReport(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var locationID *int
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		id, err := strconv.Atoi(locationParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &id
+	}
+
+	var horizons []int
+	if raw := strings.TrimSpace(c.Query("horizons")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			days, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid horizons", err)
+				return
+			}
+			horizons = append(horizons, days)
+		}
+	}
+
+	report, err := h.service.GetExpiryReport(companyID, locationID, horizons)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "horizons must be") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get expiry report", err)
+		return
+	}
+	utils.SuccessResponse(c, "Expiry report retrieved successfully", report)
+}
+
+// POST /inventory/expired-stock/quarantine
+func (h *StockExpiryHandler) QuarantineExpiredStock(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	locationID := c.GetInt("location_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		if id, err := strconv.Atoi(locationParam); err == nil {
+			locationID = id
+		}
+	}
+	if locationID == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location ID required", nil)
+		return
+	}
+
+	var req models.QuarantineExpiredStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	writeOff, err := h.service.QuarantineExpiredStock(companyID, locationID, userID, &req)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to quarantine expired stock", err)
+		return
+	}
+	utils.CreatedResponse(c, "Expired stock quarantined successfully", writeOff)
+}
+
+// GET /inventory/expiry-write-offs
+func (h *StockExpiryHandler) GetExpiryWriteOffs(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	var locationID *int
+	if locationParam := c.Query("location_id"); locationParam != "" {
+		id, err := strconv.Atoi(locationParam)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid location ID", err)
+			return
+		}
+		locationID = &id
+	}
+
+	writeOffs, err := h.service.GetExpiryWriteOffs(companyID, locationID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get expiry write-offs", err)
+		return
+	}
+	utils.SuccessResponse(c, "Expiry write-offs retrieved successfully", writeOffs)
+}
+
+// GET /inventory/expiry-write-offs/:id
+func (h *StockExpiryHandler) GetExpiryWriteOff(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	writeOffID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid write-off ID", err)
+		return
+	}
+
+	writeOff, err := h.service.GetExpiryWriteOff(companyID, writeOffID)
+	if err != nil {
+		if err.Error() == "expiry write-off not found" {
+			utils.NotFoundResponse(c, "Expiry write-off not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get expiry write-off", err)
+		return
+	}
+	utils.SuccessResponse(c, "Expiry write-off retrieved successfully", writeOff)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"erp-backend/internal/models"
 	"erp-backend/internal/services"
@@ -107,7 +108,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.UpdateUser(userID, &req)
+	err = h.userService.UpdateUser(c.GetInt("company_id"), userID, &req)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to update user", err)
 		return
@@ -139,3 +140,60 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	utils.SuccessResponse(c, "User deleted successfully", nil)
 }
+
+// GET /users/:id/locations
+func (h *UserHandler) GetUserLocations(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	locations, err := h.userService.GetUserLocations(c.GetInt("company_id"), userID)
+	if err != nil {
+		respondUserLocationError(c, "Failed to get user locations", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "User locations retrieved successfully", locations)
+}
+
+// PUT /users/:id/locations
+func (h *UserHandler) SetUserLocations(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	var req models.SetUserLocationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		validationErrors := utils.GetValidationErrors(err)
+		utils.ValidationErrorResponse(c, validationErrors)
+		return
+	}
+
+	locations, err := h.userService.SetUserLocations(c.GetInt("company_id"), c.GetInt("user_id"), userID, &req)
+	if err != nil {
+		respondUserLocationError(c, "Failed to update user locations", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "User locations updated successfully", locations)
+}
+
+func respondUserLocationError(c *gin.Context, message string, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		utils.NotFoundResponse(c, err.Error())
+	case strings.HasPrefix(err.Error(), "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
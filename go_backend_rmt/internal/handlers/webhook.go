@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/services"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler manages outbound webhook subscriptions and their delivery
+// log. Deliveries are sent by the background webhook runner.
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{service: services.NewWebhookService()}
+}
+
+// GET /webhook-subscriptions/event-types
+func (h *WebhookHandler) ListEventTypes(c *gin.Context) {
+	utils.SuccessResponse(c, "Webhook event types retrieved successfully", models.WebhookEventTypes)
+}
+
+// GET /webhook-subscriptions
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+
+	subs, err := h.service.ListSubscriptions(companyID)
+	if err != nil {
+		respondWebhookError(c, "Failed to list webhook subscriptions", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook subscriptions retrieved successfully", subs)
+}
+
+// POST /webhook-subscriptions
+// The response carries the signing secret, which is not shown again.
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	userID := c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return
+	}
+
+	var req models.CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sub, err := h.service.CreateSubscription(companyID, userID, &req)
+	if err != nil {
+		respondWebhookError(c, "Failed to create webhook subscription", err)
+		return
+	}
+	utils.CreatedResponse(c, "Webhook subscription created successfully", sub)
+}
+
+// GET /webhook-subscriptions/:id
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	companyID, _, id, ok := webhookSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(companyID, id)
+	if err != nil {
+		respondWebhookError(c, "Failed to get webhook subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook subscription retrieved successfully", sub)
+}
+
+// PUT /webhook-subscriptions/:id
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	companyID, userID, id, ok := webhookSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+		return
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sub, err := h.service.UpdateSubscription(companyID, userID, id, &req)
+	if err != nil {
+		respondWebhookError(c, "Failed to update webhook subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook subscription updated successfully", sub)
+}
+
+// DELETE /webhook-subscriptions/:id
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	companyID, _, id, ok := webhookSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(companyID, id); err != nil {
+		respondWebhookError(c, "Failed to delete webhook subscription", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook subscription deleted successfully", nil)
+}
+
+// POST /webhook-subscriptions/:id/rotate-secret
+// The old secret keeps signing next to the new one for grace_hours
+// (default 24).
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	companyID, userID, id, ok := webhookSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.RotateWebhookSecretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body", err)
+			return
+		}
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		utils.ValidationErrorResponse(c, utils.GetValidationErrors(err))
+		return
+	}
+
+	sub, err := h.service.RotateSecret(companyID, userID, id, &req)
+	if err != nil {
+		respondWebhookError(c, "Failed to rotate webhook secret", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook secret rotated successfully", sub)
+}
+
+// POST /webhook-subscriptions/:id/redeliver-dead
+// Requeues every dead-lettered delivery of the subscription.
+func (h *WebhookHandler) RedeliverDead(c *gin.Context) {
+	companyID, _, id, ok := webhookSubscriptionContext(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.service.RedeliverDead(companyID, id)
+	if err != nil {
+		respondWebhookError(c, "Failed to redeliver webhooks", err)
+		return
+	}
+	utils.SuccessResponse(c, "Dead-lettered webhooks requeued successfully", gin.H{"requeued": n})
+}
+
+// GET /webhook-deliveries
+// Delivery log; filter with subscription_id, status (DEAD for the
+// dead-letter queue), event_type and limit.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	companyID := c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return
+	}
+	filter := models.WebhookDeliveryFilter{
+		Status:    c.Query("status"),
+		EventType: c.Query("event_type"),
+	}
+	if raw := c.Query("subscription_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid subscription_id", err)
+			return
+		}
+		filter.SubscriptionID = &id
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit", err)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.service.ListDeliveries(companyID, filter)
+	if err != nil {
+		respondWebhookError(c, "Failed to list webhook deliveries", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook deliveries retrieved successfully", deliveries)
+}
+
+// GET /webhook-deliveries/:id
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	companyID, id, ok := webhookDeliveryContext(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.GetDelivery(companyID, id)
+	if err != nil {
+		respondWebhookError(c, "Failed to get webhook delivery", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook delivery retrieved successfully", d)
+}
+
+// POST /webhook-deliveries/:id/redeliver
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	companyID, id, ok := webhookDeliveryContext(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.service.Redeliver(companyID, id)
+	if err != nil {
+		respondWebhookError(c, "Failed to redeliver webhook", err)
+		return
+	}
+	utils.SuccessResponse(c, "Webhook delivery requeued successfully", d)
+}
+
+func webhookSubscriptionContext(c *gin.Context) (companyID, userID, id int, ok bool) {
+	companyID = c.GetInt("company_id")
+	userID = c.GetInt("user_id")
+	if companyID == 0 || userID == 0 {
+		utils.ForbiddenResponse(c, "Company and user access required")
+		return 0, 0, 0, false
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook subscription ID", err)
+		return 0, 0, 0, false
+	}
+	return companyID, userID, id, true
+}
+
+func webhookDeliveryContext(c *gin.Context) (companyID int, id int64, ok bool) {
+	companyID = c.GetInt("company_id")
+	if companyID == 0 {
+		utils.ForbiddenResponse(c, "Company access required")
+		return 0, 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid webhook delivery ID", err)
+		return 0, 0, false
+	}
+	return companyID, id, true
+}
+
+func respondWebhookError(c *gin.Context, message string, err error) {
+	msg := err.Error()
+	switch {
+	case strings.HasSuffix(msg, "not found"):
+		utils.NotFoundResponse(c, msg)
+	case strings.HasPrefix(msg, "failed to"):
+		utils.ErrorResponse(c, http.StatusInternalServerError, message, err)
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, message, err)
+	}
+}
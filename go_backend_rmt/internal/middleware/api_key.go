@@ -0,0 +1,323 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// apiKeyFromRequest returns the API key sent as X-API-Key or as a bearer
+// token, or "" when the request carries a user JWT or nothing.
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := strings.TrimSpace(c.GetHeader("X-API-Key")); key != "" {
+		return key
+	}
+	if token := utils.ExtractTokenFromHeader(c.GetHeader("Authorization")); strings.HasPrefix(token, utils.APIKeyScheme) {
+		return token
+	}
+	return ""
+}
+
+type apiKeyRecord struct {
+	APIKeyID              int
+	CompanyID             int
+	UserID                int
+	KeyHash               string
+	PreviousKeyHash       sql.NullString
+	PreviousKeyExpiresAt  *time.Time
+	Permissions           []string
+	LocationID            *int
+	IPAllowlist           []string
+	RateLimitPerMinute    *int
+	ExpiresAt             *time.Time
+	RevokedAt             *time.Time
+	ServiceAccountActive  bool
+	ServiceAccountDeleted bool
+}
+
+func getAPIKeyByPrefix(prefix string) (*apiKeyRecord, error) {
+	db := database.GetDB()
+	var k apiKeyRecord
+	var permissions, allowlist pq.StringArray
+	err := db.QueryRow(`
+		SELECT k.api_key_id, k.company_id, k.user_id, k.key_hash, k.previous_key_hash, k.previous_key_expires_at,
+		       k.permissions, k.location_id, k.ip_allowlist, k.rate_limit_per_minute, k.expires_at, k.revoked_at,
+		       COALESCE(u.is_active, FALSE), COALESCE(u.is_deleted, TRUE)
+		FROM api_keys k
+		LEFT JOIN users u ON u.user_id = k.user_id
+		WHERE k.key_prefix = $1
+	`, prefix).Scan(&k.APIKeyID, &k.CompanyID, &k.UserID, &k.KeyHash, &k.PreviousKeyHash, &k.PreviousKeyExpiresAt,
+		&permissions, &k.LocationID, &allowlist, &k.RateLimitPerMinute, &k.ExpiresAt, &k.RevokedAt,
+		&k.ServiceAccountActive, &k.ServiceAccountDeleted)
+	if err != nil {
+		return nil, err
+	}
+	k.Permissions = []string(permissions)
+	k.IPAllowlist = []string(allowlist)
+	return &k, nil
+}
+
+// matches reports whether raw is the key's current secret, or its previous
+// one while the rotation grace period lasts.
+func (k *apiKeyRecord) matches(raw string, now time.Time) bool {
+	hash := utils.HashAPIKey(raw)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(k.KeyHash)) == 1 {
+		return true
+	}
+	return k.PreviousKeyHash.Valid && k.PreviousKeyExpiresAt != nil && now.Before(*k.PreviousKeyExpiresAt) &&
+		subtle.ConstantTimeCompare([]byte(hash), []byte(k.PreviousKeyHash.String)) == 1
+}
+
+// apiKeyIPAllowed reports whether ip is covered by the allowlist; an empty
+// allowlist allows every address. Entries are single addresses or CIDRs.
+func apiKeyIPAllowed(allowlist []string, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowlist {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateAPIKey sets the request context for a valid key: its service
+// account as user_id, the key's company and location, and the permissions
+// RequirePermission checks against. A key restricted to a location rejects
+// requests naming any other one. It writes the error response and returns
+// false otherwise.
+func authenticateAPIKey(c *gin.Context, raw string) bool {
+	prefix, ok := utils.APIKeyPrefix(raw)
+	if !ok {
+		utils.UnauthorizedResponse(c, "Invalid API key")
+		c.Abort()
+		return false
+	}
+	key, err := getAPIKeyByPrefix(prefix)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Failed to load API key %s: %v", prefix, err)
+		}
+		utils.UnauthorizedResponse(c, "Invalid API key")
+		c.Abort()
+		return false
+	}
+	now := time.Now()
+	if !key.matches(raw, now) {
+		utils.UnauthorizedResponse(c, "Invalid API key")
+		c.Abort()
+		return false
+	}
+	if key.RevokedAt != nil || !key.ServiceAccountActive || key.ServiceAccountDeleted {
+		utils.UnauthorizedResponse(c, "API key has been revoked")
+		c.Abort()
+		return false
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		utils.UnauthorizedResponse(c, "API key has expired")
+		c.Abort()
+		return false
+	}
+	ip := c.ClientIP()
+	if !apiKeyIPAllowed(key.IPAllowlist, ip) {
+		utils.ForbiddenResponse(c, "IP address not allowed for this API key")
+		c.Abort()
+		return false
+	}
+	if key.LocationID != nil {
+		requested, ok := requestedLocationIDs(c)
+		if !ok {
+			utils.ForbiddenResponse(c, "API key is restricted to another location")
+			c.Abort()
+			return false
+		}
+		for _, id := range requested {
+			if id != *key.LocationID {
+				utils.ForbiddenResponse(c, "API key is restricted to another location")
+				c.Abort()
+				return false
+			}
+		}
+	}
+	c.Set(apiKeyAuthenticatedKey, true)
+	if !allowAPIKeyRequest(c, key.APIKeyID, key.RateLimitPerMinute) {
+		return false
+	}
+
+	user, err := getUserByID(key.UserID)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "API key has been revoked")
+		c.Abort()
+		return false
+	}
+
+	if key.LocationID != nil {
+		user.LocationID = key.LocationID
+		user.LocationIDs = []int{*key.LocationID}
+	} else {
+		// Company-wide keys act from the service account's locations, like
+		// its own sessions would, or from every company location when the
+		// account has none.
+		grants, err := getUserLocations(key.UserID, &key.CompanyID)
+		if err == nil && len(grants) == 0 && user.LocationID == nil {
+			grants, err = getCompanyLocations(key.CompanyID)
+		}
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to load location access", err)
+			c.Abort()
+			return false
+		}
+		user.LocationID, _ = utils.ResolveActiveLocation(grants, user.LocationID, nil, nil)
+		user.LocationIDs = utils.UserLocationIDs(grants)
+	}
+
+	c.Set("user_id", key.UserID)
+	c.Set("company_id", key.CompanyID)
+	if user.LocationID != nil {
+		c.Set("location_id", *user.LocationID)
+	}
+	c.Set("location_ids", user.LocationIDs)
+	c.Set("user", user)
+	c.Set("api_key_id", key.APIKeyID)
+	c.Set("api_key_permissions", key.Permissions)
+
+	touchAPIKey(c, key.APIKeyID, ip)
+	return true
+}
+
+// apiKeyBodyLocationFields are the JSON body fields naming the location a
+// request acts on. Destinations such as to_location_id are left out: a
+// store's key may still send stock elsewhere.
+var apiKeyBodyLocationFields = map[string]bool{
+	"location_id":        true,
+	"location_ids":       true,
+	"from_location_id":   true,
+	"source_location_id": true,
+}
+
+// requestedLocationIDs collects every location the request names: the
+// location_id query parameter, a location path parameter and location
+// fields anywhere in a JSON body. ok is false when one of them is not a
+// location ID.
+func requestedLocationIDs(c *gin.Context) (ids []int, ok bool) {
+	add := func(raw string) bool {
+		id, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return false
+		}
+		ids = append(ids, id)
+		return true
+	}
+
+	if raw := c.Query("location_id"); raw != "" && !add(raw) {
+		return nil, false
+	}
+	if param := locationPathParam(c.FullPath()); param != "" {
+		if !add(c.Param(param)) {
+			return nil, false
+		}
+	}
+
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ids, true
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return ids, true
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		// The handler rejects the malformed body itself.
+		return ids, true
+	}
+	if !collectBodyLocationIDs(payload, false, add) {
+		return nil, false
+	}
+	return ids, true
+}
+
+// collectBodyLocationIDs walks a decoded JSON value and passes each value of
+// a location field to add. Nulls are skipped.
+func collectBodyLocationIDs(value interface{}, isLocation bool, add func(string) bool) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, child := range v {
+			if !collectBodyLocationIDs(child, apiKeyBodyLocationFields[field], add) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if !collectBodyLocationIDs(child, isLocation, add) {
+				return false
+			}
+		}
+	case json.Number:
+		if isLocation {
+			return add(v.String())
+		}
+	case string:
+		if isLocation {
+			return add(v)
+		}
+	}
+	return true
+}
+
+// locationPathParam returns the path parameter holding a location ID in
+// routes such as /locations/:id, or "" when the route has none.
+func locationPathParam(fullPath string) string {
+	segments := strings.Split(strings.Trim(fullPath, "/"), "/")
+	for i := 1; i < len(segments); i++ {
+		if strings.HasPrefix(segments[i], ":") && strings.HasSuffix(segments[i-1], "locations") {
+			return strings.TrimPrefix(segments[i], ":")
+		}
+	}
+	return ""
+}
+
+// touchAPIKey records last use, throttled like device session activity.
+func touchAPIKey(c *gin.Context, apiKeyID int, ip string) {
+	limiter := getSessionLastSeenLimiter()
+	if limiter != nil {
+		allowed, err := limiter.Allow(c.Request.Context(), fmt.Sprintf("api_key:%d", apiKeyID))
+		if err != nil || !allowed {
+			return
+		}
+	}
+	db := database.GetDB()
+	go func() {
+		if _, err := db.Exec(`UPDATE api_keys SET last_used_at = NOW(), last_used_ip = NULLIF($2, '') WHERE api_key_id = $1`, apiKeyID, ip); err != nil {
+			log.Printf("Failed to update API key %d last use: %v", apiKeyID, err)
+		}
+	}()
+}
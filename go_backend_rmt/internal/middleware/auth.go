@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,6 +19,13 @@ import (
 
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			if authenticateAPIKey(c, key) {
+				c.Next()
+			}
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.UnauthorizedResponse(c, "Authorization header required")
@@ -58,14 +66,27 @@ func RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		var sessionLocationID *int
 		if claims.SessionID != "" {
-			if err := validateSessionState(claims.SessionID, user.UserID, user.CompanyID); err != nil {
+			sessionLocationID, err = validateSessionState(claims.SessionID, user.UserID, user.CompanyID)
+			if err != nil {
 				utils.UnauthorizedResponse(c, err.Error())
 				c.Abort()
 				return
 			}
 		}
 
+		// The session's active location decides location_id and, when the
+		// user has a role for that location, role_id.
+		grants, err := getUserLocations(user.UserID, user.CompanyID)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, "Failed to load location access", err)
+			c.Abort()
+			return
+		}
+		user.LocationID, user.RoleID = utils.ResolveActiveLocation(grants, user.LocationID, user.RoleID, sessionLocationID)
+		user.LocationIDs = utils.UserLocationIDs(grants)
+
 		// Set user context from DB (authoritative; avoids stale JWT role/company/location IDs)
 		c.Set("user_id", user.UserID)
 		if user.CompanyID != nil {
@@ -76,6 +97,7 @@ func RequireAuth() gin.HandlerFunc {
 		if user.LocationID != nil {
 			c.Set("location_id", *user.LocationID)
 		}
+		c.Set("location_ids", user.LocationIDs)
 		if user.RoleID != nil {
 			c.Set("role_id", *user.RoleID)
 		}
@@ -133,7 +155,17 @@ func RequirePermission(permission string) gin.HandlerFunc {
 			return
 		}
 
-		hasPermission, err := checkUserPermission(userID, permission)
+		if granted, isAPIKey := utils.APIKeyPermission(c, permission); isAPIKey {
+			if !granted {
+				utils.ForbiddenResponse(c, "Insufficient permissions")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		hasPermission, err := checkUserPermission(userID, c.GetInt("role_id"), permission)
 		if err != nil {
 			utils.InternalServerErrorResponse(c, "Failed to check permissions", err)
 			c.Abort()
@@ -241,7 +273,9 @@ func RequireCompanyAccess() gin.HandlerFunc {
 	}
 }
 
-// RequireLocationAccess middleware ensures user can only access their location data
+// RequireLocationAccess middleware ensures user can only access their location data.
+// A location_id query parameter must be one of the user's locations and
+// replaces the active location as the filter.
 func RequireLocationAccess() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userLocationID := c.GetInt("location_id")
@@ -251,6 +285,16 @@ func RequireLocationAccess() gin.HandlerFunc {
 			return
 		}
 
+		if raw := c.Query("location_id"); raw != "" {
+			requested, err := strconv.Atoi(raw)
+			if err != nil || !utils.LocationAllowed(utils.ContextLocationIDs(c), requested) {
+				utils.ForbiddenResponse(c, "Location access denied")
+				c.Abort()
+				return
+			}
+			userLocationID = requested
+		}
+
 		// Set location filter for queries
 		c.Set("location_filter", userLocationID)
 		c.Next()
@@ -287,7 +331,9 @@ func getUserByID(userID int) (*models.User, error) {
 	return &user, err
 }
 
-func checkUserPermission(userID int, permission string) (bool, error) {
+// checkUserPermission checks the role in effect for the request, which is
+// the user's role for the active location when one is assigned.
+func checkUserPermission(userID, roleID int, permission string) (bool, error) {
 	db := database.GetDB()
 
 	query := `
@@ -295,7 +341,7 @@ func checkUserPermission(userID int, permission string) (bool, error) {
 			EXISTS (
 				SELECT 1
 				FROM users u
-				JOIN roles r ON u.role_id = r.role_id
+				JOIN roles r ON r.role_id = $2
 				WHERE u.user_id = $1
 					AND u.is_active = TRUE
 					AND u.is_deleted = FALSE
@@ -304,21 +350,76 @@ func checkUserPermission(userID int, permission string) (bool, error) {
 			OR EXISTS (
 				SELECT 1
 				FROM users u
-				JOIN role_permissions rp ON u.role_id = rp.role_id
+				JOIN role_permissions rp ON rp.role_id = $2
 				JOIN permissions p ON rp.permission_id = p.permission_id
 				WHERE u.user_id = $1
 					AND u.is_active = TRUE
 					AND u.is_deleted = FALSE
-					AND p.name = $2
+					AND p.name = $3
 			)
 		) AS has_permission
 	`
 
 	var has bool
-	err := db.QueryRow(query, userID, permission).Scan(&has)
+	err := db.QueryRow(query, userID, roleID, permission).Scan(&has)
 	return has, err
 }
 
+// getUserLocations loads the active locations a user may work in.
+func getUserLocations(userID int, companyID *int) ([]models.UserLocation, error) {
+	if companyID == nil {
+		return nil, nil
+	}
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT ul.location_id, ul.role_id
+		FROM user_locations ul
+		JOIN locations l ON l.location_id = ul.location_id
+		WHERE ul.user_id = $1 AND l.company_id = $2 AND COALESCE(l.is_active, TRUE) = TRUE
+		ORDER BY ul.location_id
+	`, userID, *companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []models.UserLocation
+	for rows.Next() {
+		g := models.UserLocation{UserID: userID}
+		if err := rows.Scan(&g.LocationID, &g.RoleID); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// getCompanyLocations lists the company's active locations as grants
+// without a location role.
+func getCompanyLocations(companyID int) ([]models.UserLocation, error) {
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT location_id
+		FROM locations
+		WHERE company_id = $1 AND COALESCE(is_active, TRUE) = TRUE
+		ORDER BY location_id
+	`, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []models.UserLocation
+	for rows.Next() {
+		var g models.UserLocation
+		if err := rows.Scan(&g.LocationID); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
 func checkUserRole(roleID int, roleName string) (bool, error) {
 	db := database.GetDB()
 
@@ -336,24 +437,27 @@ func getRoleNameByID(roleID int) (string, error) {
 	return name, err
 }
 
-func validateSessionState(sessionID string, userID int, companyID *int) error {
+// validateSessionState checks the session is usable and returns the
+// location it has switched to, if any.
+func validateSessionState(sessionID string, userID int, companyID *int) (*int, error) {
 	db := database.GetDB()
 
 	var isActive bool
 	var lastSeen time.Time
+	var activeLocationID *int
 	err := db.QueryRow(`
-		SELECT is_active, COALESCE(last_seen, created_at)
+		SELECT is_active, COALESCE(last_seen, created_at), active_location_id
 		FROM device_sessions
 		WHERE session_id = $1 AND user_id = $2
-	`, sessionID, userID).Scan(&isActive, &lastSeen)
+	`, sessionID, userID).Scan(&isActive, &lastSeen, &activeLocationID)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("session not found")
+		return nil, fmt.Errorf("session not found")
 	}
 	if err != nil {
-		return fmt.Errorf("failed to validate session")
+		return nil, fmt.Errorf("failed to validate session")
 	}
 	if !isActive {
-		return fmt.Errorf("session is inactive")
+		return nil, fmt.Errorf("session is inactive")
 	}
 
 	idleTimeout := time.Duration(utils.DefaultPasswordPolicy().SessionIdleTimeoutMins) * time.Minute
@@ -362,9 +466,9 @@ func validateSessionState(sessionID string, userID int, companyID *int) error {
 	}
 	if idleTimeout > 0 && time.Since(lastSeen.UTC()) > idleTimeout {
 		_, _ = db.Exec(`UPDATE device_sessions SET is_active = FALSE WHERE session_id = $1`, sessionID)
-		return fmt.Errorf("session expired due to inactivity")
+		return nil, fmt.Errorf("session expired due to inactivity")
 	}
-	return nil
+	return activeLocationID, nil
 }
 
 func resolveCompanySessionIdleTimeout(db *sql.DB, companyID int) time.Duration {
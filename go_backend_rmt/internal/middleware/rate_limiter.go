@@ -15,12 +15,21 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// apiKeyAuthenticatedKey is set by RequireAuth once an API key passes
+// authentication, before the key's own limit is applied.
+const apiKeyAuthenticatedKey = "api_key_authenticated"
+
 const (
 	rateLimitKeyByIP     = "ip"
 	rateLimitKeyByUser   = "user"
 	rateLimitKeyByIPUser = "ip_user"
 )
 
+// apiKeyRateLimiter enforces each API key's own per-minute limit once the key
+// is authenticated. RateLimiter sets it up; it stays nil, leaving keys
+// unlimited, whenever the shared limiter is disabled.
+var apiKeyRateLimiter *redisRateLimiter
+
 var rateLimitScript = redis.NewScript(`
 local current = redis.call("INCR", KEYS[1])
 if current == 1 then
@@ -60,12 +69,23 @@ func RateLimiter(cfg *config.Config) gin.HandlerFunc {
 		keyBy:     cfg.RateLimitKeyBy,
 		failOpen:  cfg.RateLimitFailOpen,
 	}
+	apiKeyRateLimiter = &redisRateLimiter{
+		client:    client,
+		limit:     cfg.APIKeyRateLimitPerMinute,
+		window:    time.Minute,
+		keyPrefix: limiter.prefix() + ":api_key",
+		failOpen:  cfg.RateLimitFailOpen,
+	}
 
 	return func(c *gin.Context) {
 		if c.Request.Method == http.MethodOptions {
 			c.Next()
 			return
 		}
+		if apiKeyFromRequest(c) != "" {
+			limiter.limitAPIKeyRequest(c)
+			return
+		}
 
 		allowed, remaining, resetAt, err := limiter.Allow(c)
 		if err != nil {
@@ -115,25 +135,106 @@ func (l *redisRateLimiter) Allow(c *gin.Context) (bool, int, time.Time, error) {
 	if key == "" {
 		return true, -1, time.Time{}, nil
 	}
+	return l.allowKey(c.Request.Context(), key, l.limit)
+}
 
-	ctx := c.Request.Context()
+func (l *redisRateLimiter) allowKey(ctx context.Context, key string, limit int) (bool, int, time.Time, error) {
 	count, err := rateLimitScript.Run(ctx, l.client, []string{key}, l.window.Milliseconds()).Int64()
 	if err != nil {
 		return true, -1, time.Time{}, err
 	}
 
-	remaining := l.limit - int(count)
+	remaining := limit - int(count)
 	if remaining < 0 {
 		remaining = 0
 	}
 
 	ttl, err := l.client.PTTL(ctx, key).Result()
 	if err != nil {
-		return count <= int64(l.limit), remaining, time.Time{}, nil
+		return count <= int64(limit), remaining, time.Time{}, nil
 	}
 
 	resetAt := time.Now().Add(ttl)
-	return count <= int64(l.limit), remaining, resetAt, nil
+	return count <= int64(limit), remaining, resetAt, nil
+}
+
+// limitAPIKeyRequest holds API key requests to the client's limit until the
+// key authenticates. The request goes through only while the client is under
+// its limit, and is counted against it afterwards unless RequireAuth
+// accepted the key, which then switches it to the key's own limit. Invalid,
+// revoked and made-up keys therefore use up the client's allowance.
+func (l *redisRateLimiter) limitAPIKeyRequest(c *gin.Context) {
+	key := l.buildKey(c)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	count, err := l.client.Get(c.Request.Context(), key).Int()
+	if err != nil && err != redis.Nil {
+		if !l.failOpen {
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded", nil)
+			c.Abort()
+			return
+		}
+		log.Printf("rate_limiter: allow on error: %v", err)
+	} else if count >= l.limit {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(l.limit))
+		c.Header("X-RateLimit-Remaining", "0")
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded", nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+
+	if c.GetBool(apiKeyAuthenticatedKey) {
+		return
+	}
+	if _, _, _, err := l.allowKey(c.Request.Context(), key, l.limit); err != nil {
+		log.Printf("rate_limiter: failed to count api key request: %v", err)
+	}
+}
+
+// allowAPIKeyRequest counts the request against the key's per-minute limit,
+// or the configured default when the key has none. It writes the 429 and
+// returns false once the limit is used up.
+func allowAPIKeyRequest(c *gin.Context, apiKeyID int, limit *int) bool {
+	l := apiKeyRateLimiter
+	if l == nil || l.client == nil {
+		return true
+	}
+	max := l.limit
+	if limit != nil && *limit > 0 {
+		max = *limit
+	}
+	if max <= 0 {
+		return true
+	}
+
+	key := fmt.Sprintf("%s:%d", l.keyPrefix, apiKeyID)
+	allowed, remaining, resetAt, err := l.allowKey(c.Request.Context(), key, max)
+	if err != nil {
+		if l.failOpen {
+			log.Printf("rate_limiter: allow api key %d on error: %v", apiKeyID, err)
+			return true
+		}
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded", nil)
+		c.Abort()
+		return false
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(max))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if !resetAt.IsZero() {
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	}
+	if !allowed {
+		utils.ErrorResponse(c, http.StatusTooManyRequests, "Rate limit exceeded", nil)
+		c.Abort()
+		return false
+	}
+	return true
 }
 
 func (l *redisRateLimiter) buildKey(c *gin.Context) string {
@@ -170,11 +271,14 @@ func (l *redisRateLimiter) buildKey(c *gin.Context) string {
 		return ""
 	}
 
-	prefix := l.keyPrefix
-	if prefix == "" {
-		prefix = "rate_limit"
+	return fmt.Sprintf("%s:%s", l.prefix(), identifier)
+}
+
+func (l *redisRateLimiter) prefix() string {
+	if l.keyPrefix == "" {
+		return "rate_limit"
 	}
-	return fmt.Sprintf("%s:%s", prefix, identifier)
+	return l.keyPrefix
 }
 
 func newRedisClient(redisURL string) (*redis.Client, error) {
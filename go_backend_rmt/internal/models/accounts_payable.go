@@ -0,0 +1,133 @@
+package models
+
+import "time"
+
+const (
+	APAgingBucketCurrent = "CURRENT"
+	APAgingBucket1To30   = "1_30"
+	APAgingBucket31To60  = "31_60"
+	APAgingBucket61To90  = "61_90"
+	APAgingBucketOver90  = "OVER_90"
+
+	PaymentProposalReasonOverdue  = "OVERDUE"
+	PaymentProposalReasonDiscount = "DISCOUNT"
+	PaymentProposalReasonDue      = "DUE"
+)
+
+type APAgingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days_1_30"`
+	Days31To60 float64 `json:"days_31_60"`
+	Days61To90 float64 `json:"days_61_90"`
+	Over90     float64 `json:"over_90"`
+	Total      float64 `json:"total"`
+}
+
+type APAgingInvoice struct {
+	PurchaseID     int       `json:"purchase_id"`
+	PurchaseNumber string    `json:"purchase_number"`
+	LocationID     int       `json:"location_id"`
+	PurchaseDate   time.Time `json:"purchase_date"`
+	DueDate        time.Time `json:"due_date"`
+	TotalAmount    float64   `json:"total_amount"`
+	PaidAmount     float64   `json:"paid_amount"`
+	Outstanding    float64   `json:"outstanding"`
+	DaysOverdue    int       `json:"days_overdue"`
+	Bucket         string    `json:"bucket"`
+}
+
+type APAgingSupplier struct {
+	SupplierID     int                      `json:"supplier_id"`
+	SupplierName   string                   `json:"supplier_name"`
+	Buckets        APAgingBuckets           `json:"buckets"`
+	DebitNoteTotal float64                  `json:"debit_note_total"`
+	Invoices       []APAgingInvoice         `json:"invoices"`
+	DebitNotes     []PurchaseCostAdjustment `json:"debit_notes,omitempty"`
+}
+
+// APAgingReport buckets open supplier balances by days past due. Debit notes
+// are listed for reference; their amounts are already netted off the
+// purchases they were raised against.
+type APAgingReport struct {
+	AsOf      time.Time         `json:"as_of"`
+	Totals    APAgingBuckets    `json:"totals"`
+	Suppliers []APAgingSupplier `json:"suppliers"`
+}
+
+type PaymentProposalRequest struct {
+	CashBudget    float64 `json:"cash_budget" validate:"required,gt=0"`
+	PaymentDate   *string `json:"payment_date,omitempty"`
+	DueWithinDays *int    `json:"due_within_days,omitempty" validate:"omitempty,gte=0,lte=365"`
+	LocationID    *int    `json:"location_id,omitempty"`
+	SupplierIDs   []int   `json:"supplier_ids,omitempty"`
+}
+
+type PaymentProposalLine struct {
+	PurchaseID        int        `json:"purchase_id"`
+	PurchaseNumber    string     `json:"purchase_number"`
+	SupplierID        int        `json:"supplier_id"`
+	SupplierName      string     `json:"supplier_name"`
+	LocationID        int        `json:"location_id"`
+	DueDate           time.Time  `json:"due_date"`
+	DaysOverdue       int        `json:"days_overdue"`
+	Outstanding       float64    `json:"outstanding"`
+	DiscountPercent   float64    `json:"discount_percent"`
+	DiscountAvailable float64    `json:"discount_available"`
+	DiscountDeadline  *time.Time `json:"discount_deadline,omitempty"`
+	TakeDiscount      bool       `json:"take_discount"`
+	PaymentAmount     float64    `json:"payment_amount"`
+	Reason            string     `json:"reason"`
+}
+
+type PaymentProposal struct {
+	PaymentDate     time.Time             `json:"payment_date"`
+	CashBudget      float64               `json:"cash_budget"`
+	TotalPayment    float64               `json:"total_payment"`
+	TotalDiscount   float64               `json:"total_discount"`
+	RemainingBudget float64               `json:"remaining_budget"`
+	Lines           []PaymentProposalLine `json:"lines"`
+	Deferred        []PaymentProposalLine `json:"deferred"`
+}
+
+type SupplierPaymentRunItemInput struct {
+	PurchaseID   int     `json:"purchase_id" validate:"required"`
+	Amount       float64 `json:"amount" validate:"required,gt=0"`
+	TakeDiscount bool    `json:"take_discount"`
+}
+
+type CreateSupplierPaymentRunRequest struct {
+	PaymentDate     *string                       `json:"payment_date,omitempty"`
+	PaymentMethodID *int                          `json:"payment_method_id,omitempty"`
+	ReferenceNumber *string                       `json:"reference_number,omitempty"`
+	Notes           *string                       `json:"notes,omitempty"`
+	Items           []SupplierPaymentRunItemInput `json:"items" validate:"required,min=1,dive"`
+}
+
+type SupplierPaymentRunLine struct {
+	PaymentID            int     `json:"payment_id"`
+	PaymentNumber        string  `json:"payment_number"`
+	SupplierID           int     `json:"supplier_id"`
+	SupplierName         string  `json:"supplier_name,omitempty"`
+	PurchaseID           int     `json:"purchase_id"`
+	PurchaseNumber       string  `json:"purchase_number,omitempty"`
+	LocationID           int     `json:"location_id"`
+	Amount               float64 `json:"amount"`
+	DiscountAmount       float64 `json:"discount_amount"`
+	DiscountAdjustmentID *int    `json:"discount_adjustment_id,omitempty"`
+}
+
+type SupplierPaymentRun struct {
+	RunID           int                      `json:"run_id"`
+	RunNumber       string                   `json:"run_number"`
+	CompanyID       int                      `json:"company_id"`
+	PaymentDate     time.Time                `json:"payment_date"`
+	PaymentMethodID *int                     `json:"payment_method_id,omitempty"`
+	ReferenceNumber *string                  `json:"reference_number,omitempty"`
+	Notes           *string                  `json:"notes,omitempty"`
+	PaymentCount    int                      `json:"payment_count"`
+	TotalAmount     float64                  `json:"total_amount"`
+	TotalDiscount   float64                  `json:"total_discount"`
+	CreatedBy       int                      `json:"created_by"`
+	CreatedAt       time.Time                `json:"created_at"`
+	Payments        []SupplierPaymentRunLine `json:"payments,omitempty"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// Sources an analytic dimension value can be defaulted from, in the order
+// they win when several apply to one document.
+const (
+	AnalyticSourceExpenseCategory = "EXPENSE_CATEGORY"
+	AnalyticSourceEmployee        = "EMPLOYEE"
+	AnalyticSourceDepartment      = "DEPARTMENT"
+	AnalyticSourceLocation        = "LOCATION"
+)
+
+// AnalyticDimension is a company-defined axis ledger entries can be tagged
+// on, such as cost center or project.
+type AnalyticDimension struct {
+	DimensionID int                      `json:"dimension_id" db:"dimension_id"`
+	CompanyID   int                      `json:"company_id" db:"company_id"`
+	Code        string                   `json:"code" db:"code"`
+	Name        string                   `json:"name" db:"name"`
+	IsActive    bool                     `json:"is_active" db:"is_active"`
+	Values      []AnalyticDimensionValue `json:"values"`
+	CreatedBy   int                      `json:"created_by" db:"created_by"`
+	UpdatedBy   *int                     `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt   time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+type AnalyticDimensionValue struct {
+	ValueID     int    `json:"value_id" db:"value_id"`
+	DimensionID int    `json:"dimension_id" db:"dimension_id"`
+	Code        string `json:"code" db:"code"`
+	Name        string `json:"name" db:"name"`
+	IsActive    bool   `json:"is_active" db:"is_active"`
+}
+
+type CreateAnalyticDimensionRequest struct {
+	Code string `json:"code" validate:"required,max=30"`
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type UpdateAnalyticDimensionRequest struct {
+	Name     *string `json:"name" validate:"omitempty,max=100"`
+	IsActive *bool   `json:"is_active"`
+}
+
+type CreateAnalyticDimensionValueRequest struct {
+	Code string `json:"code" validate:"required,max=30"`
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type UpdateAnalyticDimensionValueRequest struct {
+	Name     *string `json:"name" validate:"omitempty,max=100"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// AnalyticDimensionDefault picks the value used for a dimension when a
+// document from the source (a location, department, employee or expense
+// category) does not set one itself.
+type AnalyticDimensionDefault struct {
+	DimensionID   int    `json:"dimension_id" db:"dimension_id"`
+	DimensionName string `json:"dimension_name"`
+	SourceType    string `json:"source_type" db:"source_type"`
+	SourceID      int    `json:"source_id" db:"source_id"`
+	ValueID       int    `json:"value_id" db:"value_id"`
+	ValueCode     string `json:"value_code"`
+	ValueName     string `json:"value_name"`
+}
+
+// SetAnalyticDimensionDefaultRequest sets or, with no value_id, clears a default.
+type SetAnalyticDimensionDefaultRequest struct {
+	DimensionID int    `json:"dimension_id" validate:"required"`
+	SourceType  string `json:"source_type" validate:"required,oneof=LOCATION DEPARTMENT EMPLOYEE EXPENSE_CATEGORY"`
+	SourceID    int    `json:"source_id" validate:"required"`
+	ValueID     *int   `json:"value_id"`
+}
+
+// AnalyticTag assigns a dimension value to a document or ledger entry.
+type AnalyticTag struct {
+	DimensionID int `json:"dimension_id" validate:"required"`
+	ValueID     int `json:"value_id" validate:"required"`
+}
+
+// LedgerDimensionFilter narrows ledger reports to entries tagged with the
+// given values (any value within a dimension, all dimensions listed) and can
+// split the report by one dimension.
+type LedgerDimensionFilter struct {
+	ValueIDs           []int
+	GroupByDimensionID int
+}
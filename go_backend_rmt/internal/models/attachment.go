@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Record types that can carry attachments.
+const (
+	AttachmentEntitySale         = "sale"
+	AttachmentEntityPurchase     = "purchase"
+	AttachmentEntityExpense      = "expense"
+	AttachmentEntityVoucher      = "voucher"
+	AttachmentEntityEmployee     = "employee"
+	AttachmentEntityAsset        = "asset"
+	AttachmentEntitySupportIssue = "support_issue"
+)
+
+type Attachment struct {
+	AttachmentID   int        `json:"attachment_id" db:"attachment_id"`
+	EntityType     string     `json:"entity_type" db:"entity_type"`
+	EntityID       int        `json:"entity_id" db:"entity_id"`
+	ObjectID       int        `json:"object_id" db:"object_id"`
+	FileName       string     `json:"file_name" db:"file_name"`
+	Description    *string    `json:"description,omitempty" db:"description"`
+	ContentType    string     `json:"content_type" db:"content_type"`
+	SizeBytes      int64      `json:"size_bytes" db:"size_bytes"`
+	SHA256         string     `json:"sha256" db:"sha256"`
+	UploadedBy     *int       `json:"uploaded_by,omitempty" db:"uploaded_by"`
+	UploadedByName *string    `json:"uploaded_by_name,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DownloadURL    string     `json:"download_url,omitempty"`
+	URLExpiresAt   *time.Time `json:"url_expires_at,omitempty"`
+	// Deduplicated is set on upload when the bytes were already stored.
+	Deduplicated bool `json:"deduplicated,omitempty"`
+}
+
+type AttachmentFilter struct {
+	EntityType string `form:"entity_type" validate:"required,oneof=sale purchase expense voucher employee asset support_issue"`
+	EntityID   int    `form:"entity_id" validate:"required,gt=0"`
+}
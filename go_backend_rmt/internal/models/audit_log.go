@@ -7,6 +7,7 @@ import "time"
 type AuditLog struct {
 	LogID        int       `json:"log_id" db:"log_id"`
 	UserID       *int      `json:"user_id,omitempty" db:"user_id"`
+	APIKeyID     *int      `json:"api_key_id,omitempty" db:"api_key_id"`
 	Action       string    `json:"action" db:"action"`
 	TableName    string    `json:"table_name" db:"table_name"`
 	RecordID     *int      `json:"record_id,omitempty" db:"record_id"`
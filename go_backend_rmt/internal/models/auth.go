@@ -63,9 +63,12 @@ type JWTClaims struct {
 	UserID     int    `json:"user_id"`
 	CompanyID  *int   `json:"company_id,omitempty"` // CHANGE: int -> *int
 	LocationID *int   `json:"location_id,omitempty"`
-	RoleID     *int   `json:"role_id,omitempty"` // CHANGE: int -> *int
-	Email      string `json:"email"`
-	Type       string `json:"type"` // "access" or "refresh"
+	// LocationIDs are the locations the user may switch to; empty means the
+	// user is not restricted to particular locations.
+	LocationIDs []int  `json:"location_ids,omitempty"`
+	RoleID      *int   `json:"role_id,omitempty"` // CHANGE: int -> *int
+	Email       string `json:"email"`
+	Type        string `json:"type"` // "access" or "refresh"
 }
 
 // Language represents available system languages
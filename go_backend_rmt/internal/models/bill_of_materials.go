@@ -0,0 +1,141 @@
+package models
+
+import "time"
+
+const (
+	AssemblyOrderTypeAssembly    = "ASSEMBLY"
+	AssemblyOrderTypeDisassembly = "DISASSEMBLY"
+
+	AssemblyOrderStatusDraft     = "DRAFT"
+	AssemblyOrderStatusCompleted = "COMPLETED"
+	AssemblyOrderStatusCancelled = "CANCELLED"
+)
+
+// BillOfMaterials lists the components that make OutputQuantity units of a
+// finished good, plus the labor and overhead added per OutputQuantity.
+type BillOfMaterials struct {
+	BOMID          int            `json:"bom_id" db:"bom_id"`
+	CompanyID      int            `json:"company_id" db:"company_id"`
+	ProductID      int            `json:"product_id" db:"product_id"`
+	ProductName    string         `json:"product_name,omitempty"`
+	BarcodeID      *int           `json:"barcode_id,omitempty" db:"barcode_id"`
+	Version        int            `json:"version" db:"version"`
+	Name           *string        `json:"name,omitempty" db:"name"`
+	OutputQuantity float64        `json:"output_quantity" db:"output_quantity"`
+	LaborCost      float64        `json:"labor_cost" db:"labor_cost"`
+	OverheadCost   float64        `json:"overhead_cost" db:"overhead_cost"`
+	IsActive       bool           `json:"is_active" db:"is_active"`
+	Notes          *string        `json:"notes,omitempty" db:"notes"`
+	CreatedBy      int            `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+	Components     []BOMComponent `json:"components,omitempty"`
+}
+
+type BOMComponent struct {
+	BOMComponentID int     `json:"bom_component_id" db:"bom_component_id"`
+	BOMID          int     `json:"bom_id" db:"bom_id"`
+	ProductID      int     `json:"product_id" db:"product_id"`
+	ProductName    string  `json:"product_name,omitempty"`
+	BarcodeID      *int    `json:"barcode_id,omitempty" db:"barcode_id"`
+	Quantity       float64 `json:"quantity" db:"quantity"`
+	UnitID         *int    `json:"unit_id,omitempty" db:"unit_id"`
+	UnitSymbol     *string `json:"unit_symbol,omitempty"`
+	StockFactor    float64 `json:"stock_factor" db:"stock_factor"`
+	ScrapPercent   float64 `json:"scrap_percent" db:"scrap_percent"`
+	Position       int     `json:"position" db:"position"`
+	Notes          *string `json:"notes,omitempty" db:"notes"`
+}
+
+// CreateBillOfMaterialsRequest adds the next BOM version for a product.
+type CreateBillOfMaterialsRequest struct {
+	ProductID      int                 `json:"product_id" validate:"required,gt=0"`
+	BarcodeID      *int                `json:"barcode_id,omitempty" validate:"omitempty,gt=0"`
+	Name           *string             `json:"name,omitempty" validate:"omitempty,max=255"`
+	OutputQuantity *float64            `json:"output_quantity,omitempty" validate:"omitempty,gt=0"`
+	LaborCost      float64             `json:"labor_cost" validate:"gte=0"`
+	OverheadCost   float64             `json:"overhead_cost" validate:"gte=0"`
+	Activate       *bool               `json:"activate,omitempty"`
+	Notes          *string             `json:"notes,omitempty"`
+	Components     []BOMComponentInput `json:"components" validate:"required,min=1,dive"`
+}
+
+type BOMComponentInput struct {
+	ProductID    int     `json:"product_id" validate:"required,gt=0"`
+	BarcodeID    *int    `json:"barcode_id,omitempty" validate:"omitempty,gt=0"`
+	Quantity     float64 `json:"quantity" validate:"required,gt=0"`
+	UnitID       *int    `json:"unit_id,omitempty" validate:"omitempty,gt=0"`
+	ScrapPercent float64 `json:"scrap_percent" validate:"gte=0,lt=100"`
+	Notes        *string `json:"notes,omitempty"`
+}
+
+// AssemblyOrder builds (ASSEMBLY) or breaks down (DISASSEMBLY) Quantity units
+// of a finished good. Lines carry the component side in stock units.
+type AssemblyOrder struct {
+	AssemblyOrderID int                 `json:"assembly_order_id" db:"assembly_order_id"`
+	CompanyID       int                 `json:"company_id" db:"company_id"`
+	LocationID      int                 `json:"location_id" db:"location_id"`
+	OrderNumber     string              `json:"order_number" db:"order_number"`
+	OrderType       string              `json:"order_type" db:"order_type"`
+	Status          string              `json:"status" db:"status"`
+	BOMID           int                 `json:"bom_id" db:"bom_id"`
+	BOMVersion      int                 `json:"bom_version"`
+	ProductID       int                 `json:"product_id" db:"product_id"`
+	ProductName     string              `json:"product_name,omitempty"`
+	BarcodeID       *int                `json:"barcode_id,omitempty" db:"barcode_id"`
+	Quantity        float64             `json:"quantity" db:"quantity"`
+	LaborCost       float64             `json:"labor_cost" db:"labor_cost"`
+	OverheadCost    float64             `json:"overhead_cost" db:"overhead_cost"`
+	InputCost       float64             `json:"input_cost" db:"input_cost"`
+	OutputCost      float64             `json:"output_cost" db:"output_cost"`
+	BatchNumber     *string             `json:"batch_number,omitempty" db:"batch_number"`
+	ExpiryDate      *time.Time          `json:"expiry_date,omitempty" db:"expiry_date"`
+	SerialNumbers   []string            `json:"serial_numbers,omitempty" db:"serial_numbers"`
+	Notes           *string             `json:"notes,omitempty" db:"notes"`
+	CreatedBy       int                 `json:"created_by" db:"created_by"`
+	CompletedBy     *int                `json:"completed_by,omitempty" db:"completed_by"`
+	CompletedAt     *time.Time          `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt       time.Time           `json:"created_at" db:"created_at"`
+	Lines           []AssemblyOrderLine `json:"lines,omitempty"`
+}
+
+type AssemblyOrderLine struct {
+	AssemblyOrderLineID int      `json:"assembly_order_line_id" db:"assembly_order_line_id"`
+	BOMComponentID      *int     `json:"bom_component_id,omitempty" db:"bom_component_id"`
+	ProductID           int      `json:"product_id" db:"product_id"`
+	ProductName         string   `json:"product_name,omitempty"`
+	BarcodeID           *int     `json:"barcode_id,omitempty" db:"barcode_id"`
+	Quantity            float64  `json:"quantity" db:"quantity"`
+	ScrapQuantity       float64  `json:"scrap_quantity" db:"scrap_quantity"`
+	UnitCost            float64  `json:"unit_cost" db:"unit_cost"`
+	TotalCost           float64  `json:"total_cost" db:"total_cost"`
+	SerialNumbers       []string `json:"serial_numbers,omitempty" db:"serial_numbers"`
+}
+
+// CreateAssemblyOrderRequest drafts an order from a BOM, the product's
+// active BOM when BOMID is omitted. Labor and overhead default to the BOM
+// rates scaled to Quantity.
+type CreateAssemblyOrderRequest struct {
+	OrderType    string   `json:"order_type" validate:"required,oneof=ASSEMBLY DISASSEMBLY"`
+	BOMID        *int     `json:"bom_id,omitempty" validate:"omitempty,gt=0"`
+	ProductID    *int     `json:"product_id,omitempty" validate:"omitempty,gt=0"`
+	Quantity     float64  `json:"quantity" validate:"required,gt=0"`
+	LaborCost    *float64 `json:"labor_cost,omitempty" validate:"omitempty,gte=0"`
+	OverheadCost *float64 `json:"overhead_cost,omitempty" validate:"omitempty,gte=0"`
+	BatchNumber  *string  `json:"batch_number,omitempty"`
+	ExpiryDate   *string  `json:"expiry_date,omitempty"`
+	Notes        *string  `json:"notes,omitempty"`
+}
+
+// CompleteAssemblyOrderRequest supplies serial numbers for serialized items:
+// SerialNumbers for the finished good, Lines for components.
+type CompleteAssemblyOrderRequest struct {
+	SerialNumbers    []string                        `json:"serial_numbers,omitempty"`
+	Lines            []AssemblyOrderLineSerialsInput `json:"lines,omitempty" validate:"omitempty,dive"`
+	OverridePassword *string                         `json:"override_password,omitempty"`
+}
+
+type AssemblyOrderLineSerialsInput struct {
+	AssemblyOrderLineID int      `json:"assembly_order_line_id" validate:"required,gt=0"`
+	SerialNumbers       []string `json:"serial_numbers" validate:"required,min=1"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+const (
+	BudgetStatusDraft    = "DRAFT"
+	BudgetStatusActive   = "ACTIVE"
+	BudgetStatusArchived = "ARCHIVED"
+
+	BudgetVersionOriginal = "ORIGINAL"
+	BudgetVersionRevised  = "REVISED"
+
+	// Control modes decide what happens when an expense or purchase would
+	// take an account past its remaining annual budget.
+	BudgetControlNone  = "NONE"
+	BudgetControlWarn  = "WARN"
+	BudgetControlBlock = "BLOCK"
+)
+
+// Budget is one version of a fiscal year's plan for the company, or for a
+// single location or department when those are set. Month 1 of the fiscal
+// year is StartMonth of FiscalYear.
+type Budget struct {
+	BudgetID       int          `json:"budget_id" db:"budget_id"`
+	CompanyID      int          `json:"company_id" db:"company_id"`
+	Name           string       `json:"name" db:"name"`
+	FiscalYear     int          `json:"fiscal_year" db:"fiscal_year"`
+	StartMonth     int          `json:"start_month" db:"start_month"`
+	LocationID     *int         `json:"location_id,omitempty" db:"location_id"`
+	LocationName   *string      `json:"location_name,omitempty"`
+	DepartmentID   *int         `json:"department_id,omitempty" db:"department_id"`
+	DepartmentName *string      `json:"department_name,omitempty"`
+	VersionType    string       `json:"version_type" db:"version_type"`
+	VersionNo      int          `json:"version_no" db:"version_no"`
+	RevisedFromID  *int         `json:"revised_from_id,omitempty" db:"revised_from_id"`
+	Status         string       `json:"status" db:"status"`
+	ControlMode    string       `json:"control_mode" db:"control_mode"`
+	Notes          *string      `json:"notes,omitempty" db:"notes"`
+	TotalAmount    float64      `json:"total_amount"`
+	Lines          []BudgetLine `json:"lines,omitempty"`
+	CreatedBy      int          `json:"created_by" db:"created_by"`
+	UpdatedBy      *int         `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// BudgetLine is an account's plan for the twelve months of the fiscal year.
+type BudgetLine struct {
+	AccountID   int         `json:"account_id"`
+	AccountCode *string     `json:"account_code,omitempty"`
+	AccountName string      `json:"account_name"`
+	AccountType string      `json:"account_type"`
+	Months      [12]float64 `json:"months"`
+	Total       float64     `json:"total"`
+}
+
+type BudgetLineInput struct {
+	AccountID int       `json:"account_id" validate:"required"`
+	Months    []float64 `json:"months" validate:"len=12"`
+}
+
+type CreateBudgetRequest struct {
+	Name         string            `json:"name" validate:"required,max=150"`
+	FiscalYear   int               `json:"fiscal_year" validate:"required,min=2000,max=2100"`
+	StartMonth   int               `json:"start_month" validate:"omitempty,min=1,max=12"`
+	LocationID   *int              `json:"location_id"`
+	DepartmentID *int              `json:"department_id"`
+	ControlMode  string            `json:"control_mode" validate:"omitempty,oneof=NONE WARN BLOCK"`
+	Notes        *string           `json:"notes"`
+	Lines        []BudgetLineInput `json:"lines" validate:"dive"`
+}
+
+// UpdateBudgetRequest changes a budget. Lines can only be replaced while the
+// budget is a draft; revise an active budget to change its amounts.
+type UpdateBudgetRequest struct {
+	Name        *string            `json:"name" validate:"omitempty,max=150"`
+	ControlMode *string            `json:"control_mode" validate:"omitempty,oneof=NONE WARN BLOCK"`
+	Notes       *string            `json:"notes"`
+	Lines       *[]BudgetLineInput `json:"lines" validate:"omitempty,dive"`
+}
+
+type ReviseBudgetRequest struct {
+	Name  *string `json:"name" validate:"omitempty,max=150"`
+	Notes *string `json:"notes"`
+}
+
+type BudgetFilter struct {
+	FiscalYear   int    `form:"fiscal_year" validate:"omitempty,min=2000,max=2100"`
+	Status       string `form:"status" validate:"omitempty,oneof=DRAFT ACTIVE ARCHIVED"`
+	LocationID   *int   `form:"location_id"`
+	DepartmentID *int   `form:"department_id"`
+}
+
+// BudgetVsActualFilter selects fiscal months (1-12) of the budget to compare.
+type BudgetVsActualFilter struct {
+	FromMonth int  `form:"from_month" validate:"omitempty,min=1,max=12"`
+	ToMonth   int  `form:"to_month" validate:"omitempty,min=1,max=12"`
+	ByMonth   bool `form:"by_month"`
+}
+
+// BudgetVsActualRow compares plan and ledger actuals for an account, either
+// for the whole selected range or, with by_month, for one month. Amounts use
+// the account's natural sign, so revenue and expense are both positive.
+// Variance is actual minus budget.
+type BudgetVsActualRow struct {
+	AccountID   int      `json:"account_id"`
+	AccountCode *string  `json:"account_code,omitempty"`
+	AccountName string   `json:"account_name"`
+	AccountType string   `json:"account_type"`
+	Month       string   `json:"month,omitempty"`
+	Budget      float64  `json:"budget"`
+	Actual      float64  `json:"actual"`
+	Variance    float64  `json:"variance"`
+	VariancePct *float64 `json:"variance_pct,omitempty"`
+	Favorable   bool     `json:"favorable"`
+}
+
+type BudgetVsActualReport struct {
+	Budget   Budget              `json:"budget"`
+	FromDate string              `json:"from_date"`
+	ToDate   string              `json:"to_date"`
+	Rows     []BudgetVsActualRow `json:"rows"`
+}
@@ -5,6 +5,8 @@ import "time"
 type CashRegister struct {
 	RegisterID      int       `json:"register_id" db:"register_id"`
 	LocationID      int       `json:"location_id" db:"location_id"`
+	TerminalID      string    `json:"terminal_id" db:"terminal_id"`
+	TerminalName    *string   `json:"terminal_name,omitempty" db:"terminal_name"`
 	Date            time.Time `json:"date" db:"date"`
 	OpeningBalance  float64   `json:"opening_balance" db:"opening_balance"`
 	ClosingBalance  *float64  `json:"closing_balance,omitempty" db:"closing_balance"`
@@ -24,16 +26,26 @@ type CashRegister struct {
 
 type OpenCashRegisterRequest struct {
 	OpeningBalance float64 `json:"opening_balance" validate:"required"`
+	// Terminal the register is bound to; defaults to the device of the
+	// caller's session.
+	TerminalID   *string `json:"terminal_id,omitempty"`
+	TerminalName *string `json:"terminal_name,omitempty"`
 }
 
+// CloseCashRegisterRequest is a blind close: the cashier declares what is in
+// the drawer per tender without seeing the expected totals. ClosingBalance is
+// the declared cash when no CASH tender line is sent.
 type CloseCashRegisterRequest struct {
-	ClosingBalance float64 `json:"closing_balance" validate:"required"`
-	Denominations  *JSONB  `json:"denominations,omitempty"`
+	ClosingBalance float64              `json:"closing_balance" validate:"required_without=Tenders"`
+	Denominations  *JSONB               `json:"denominations,omitempty"`
+	Tenders        []CashTenderDeclared `json:"tenders,omitempty" validate:"omitempty,dive"`
 }
 
 type CashTallyRequest struct {
-	Count float64 `json:"count" validate:"required"`
+	Count float64 `json:"count" validate:"required_without=Tenders"`
 	Notes *string `json:"notes,omitempty"`
 	// Optional breakdown captured as a JSON object, e.g. {"100":2,"50":1}
 	Denominations *JSONB `json:"denominations,omitempty"`
+	// Optional per-tender counts; the CASH line replaces Count when present.
+	Tenders []CashTenderDeclared `json:"tenders,omitempty" validate:"omitempty,dive"`
 }
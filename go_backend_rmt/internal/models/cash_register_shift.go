@@ -0,0 +1,167 @@
+package models
+
+import "time"
+
+// Tenders counted at a cash drawer close. Payment method types map onto these
+// buckets (UPI/DIGITAL/ONLINE are wallets, CREDIT is store credit).
+const (
+	CashTenderCash        = "CASH"
+	CashTenderCard        = "CARD"
+	CashTenderWallet      = "WALLET"
+	CashTenderStoreCredit = "STORE_CREDIT"
+	CashTenderOther       = "OTHER"
+)
+
+// Kinds of per-tender counts.
+const (
+	CashCountTally         = "TALLY"
+	CashCountShiftClose    = "SHIFT_CLOSE"
+	CashCountRegisterClose = "REGISTER_CLOSE"
+)
+
+// Cash register report types.
+const (
+	CashRegisterReportX = "X"
+	CashRegisterReportZ = "Z"
+)
+
+type CashRegisterShift struct {
+	ShiftID     int        `json:"shift_id" db:"shift_id"`
+	RegisterID  int        `json:"register_id" db:"register_id"`
+	LocationID  int        `json:"location_id" db:"location_id"`
+	CashierID   int        `json:"cashier_id" db:"cashier_id"`
+	CashierName string     `json:"cashier_name,omitempty" db:"cashier_name"`
+	Status      string     `json:"status" db:"status"`
+	OpeningCash float64    `json:"opening_cash" db:"opening_cash"`
+	StartedAt   time.Time  `json:"started_at" db:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	EndedBy     *int       `json:"ended_by,omitempty" db:"ended_by"`
+	Notes       *string    `json:"notes,omitempty" db:"notes"`
+}
+
+// CashTenderDeclared is one line of a blind count.
+type CashTenderDeclared struct {
+	Tender        string  `json:"tender" validate:"required,oneof=CASH CARD WALLET STORE_CREDIT OTHER"`
+	Amount        float64 `json:"amount" validate:"gte=0"`
+	Denominations *JSONB  `json:"denominations,omitempty"`
+}
+
+type CashTenderCount struct {
+	CountID       int       `json:"count_id" db:"count_id"`
+	RegisterID    int       `json:"register_id" db:"register_id"`
+	ShiftID       *int      `json:"shift_id,omitempty" db:"shift_id"`
+	CountType     string    `json:"count_type" db:"count_type"`
+	Tender        string    `json:"tender" db:"tender"`
+	Declared      float64   `json:"declared" db:"declared"`
+	Expected      float64   `json:"expected" db:"expected"`
+	Variance      float64   `json:"variance" db:"variance"`
+	Denominations *JSONB    `json:"denominations,omitempty" db:"denominations"`
+	CountedBy     int       `json:"counted_by" db:"counted_by"`
+	CountedAt     time.Time `json:"counted_at" db:"counted_at"`
+}
+
+type StartCashShiftRequest struct {
+	Notes *string `json:"notes,omitempty"`
+}
+
+// EndCashShiftRequest closes the caller's shift with a blind per-tender count.
+type EndCashShiftRequest struct {
+	Tenders []CashTenderDeclared `json:"tenders" validate:"required,min=1,dive"`
+	Notes   *string              `json:"notes,omitempty"`
+}
+
+// CashShiftCloseResult is returned once a blind count has been submitted.
+type CashShiftCloseResult struct {
+	Shift  *CashRegisterShift `json:"shift,omitempty"`
+	Counts []CashTenderCount  `json:"counts"`
+}
+
+type CashRegisterCloseResult struct {
+	RegisterID int               `json:"register_id"`
+	Counts     []CashTenderCount `json:"counts"`
+	ZReport    *CashRegisterZRef `json:"z_report,omitempty"`
+}
+
+type CashRegisterZRef struct {
+	ZReportID int `json:"z_report_id"`
+	ZNumber   int `json:"z_number"`
+}
+
+type CashRegisterSalesSummary struct {
+	Count     int     `json:"count"`
+	Subtotal  float64 `json:"subtotal"`
+	Discounts float64 `json:"discounts"`
+	Tax       float64 `json:"tax"`
+	Total     float64 `json:"total"`
+}
+
+type CashRegisterTaxLine struct {
+	TaxName       string  `json:"tax_name"`
+	TaxRate       float64 `json:"tax_rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+}
+
+type CashRegisterTenderLine struct {
+	Tender   string   `json:"tender"`
+	Expected float64  `json:"expected"`
+	Declared *float64 `json:"declared,omitempty"`
+	Variance *float64 `json:"variance,omitempty"`
+}
+
+type CashRegisterMovementLine struct {
+	EventType  string  `json:"event_type"`
+	ReasonCode string  `json:"reason_code"`
+	Direction  string  `json:"direction"`
+	Count      int     `json:"count"`
+	Amount     float64 `json:"amount"`
+}
+
+// CashRegisterReport is the body of an X (mid-shift, recomputed on demand) or
+// Z (end-of-day, numbered and frozen at close) report.
+type CashRegisterReport struct {
+	ReportType     string                     `json:"report_type"`
+	ZReportID      *int                       `json:"z_report_id,omitempty"`
+	ZNumber        *int                       `json:"z_number,omitempty"`
+	RegisterID     int                        `json:"register_id"`
+	ShiftID        *int                       `json:"shift_id,omitempty"`
+	LocationID     int                        `json:"location_id"`
+	TerminalID     string                     `json:"terminal_id"`
+	PeriodStart    time.Time                  `json:"period_start"`
+	PeriodEnd      time.Time                  `json:"period_end"`
+	GeneratedAt    time.Time                  `json:"generated_at"`
+	GeneratedBy    int                        `json:"generated_by"`
+	OpeningBalance float64                    `json:"opening_balance"`
+	CashIn         float64                    `json:"cash_in"`
+	CashOut        float64                    `json:"cash_out"`
+	ExpectedCash   float64                    `json:"expected_cash"`
+	Sales          CashRegisterSalesSummary   `json:"sales"`
+	Returns        CashRegisterSalesSummary   `json:"returns"`
+	Voids          CashRegisterSalesSummary   `json:"voids"`
+	TaxBreakdown   []CashRegisterTaxLine      `json:"tax_breakdown"`
+	Tenders        []CashRegisterTenderLine   `json:"tenders"`
+	Movements      []CashRegisterMovementLine `json:"movements"`
+	Shifts         []CashRegisterShift        `json:"shifts"`
+	Events         []CashRegisterEvent        `json:"events,omitempty"`
+}
+
+// CashRegisterReportLine is the flattened, export-friendly view of a report.
+type CashRegisterReportLine struct {
+	Section string  `json:"section"`
+	Label   string  `json:"label"`
+	Count   *int    `json:"count,omitempty"`
+	Amount  float64 `json:"amount"`
+}
+
+type CashRegisterZReportSummary struct {
+	ZReportID   int       `json:"z_report_id"`
+	ZNumber     int       `json:"z_number"`
+	LocationID  int       `json:"location_id"`
+	RegisterID  int       `json:"register_id"`
+	TerminalID  string    `json:"terminal_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	SalesTotal  float64   `json:"sales_total"`
+	GeneratedBy int       `json:"generated_by"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
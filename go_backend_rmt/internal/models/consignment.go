@@ -0,0 +1,174 @@
+package models
+
+import "time"
+
+// Stock lot ownership. CONSIGNMENT_IN lots belong to the consignor supplier
+// and are billed to us only once sold; CONSIGNMENT_OUT lots sit at a dealer
+// location and remain our stock until the dealer reports the sale.
+const (
+	StockOwnershipOwned          = "OWNED"
+	StockOwnershipConsignmentIn  = "CONSIGNMENT_IN"
+	StockOwnershipConsignmentOut = "CONSIGNMENT_OUT"
+)
+
+// ConsignmentReceipt records vendor-owned stock taken into one of our stores.
+// It raises no payable; the supplier is billed through settlements.
+type ConsignmentReceipt struct {
+	ConsignmentReceiptID int                      `json:"consignment_receipt_id" db:"consignment_receipt_id"`
+	CompanyID            int                      `json:"company_id" db:"company_id"`
+	LocationID           int                      `json:"location_id" db:"location_id"`
+	SupplierID           int                      `json:"supplier_id" db:"supplier_id"`
+	SupplierName         string                   `json:"supplier_name,omitempty"`
+	ReceiptNumber        string                   `json:"receipt_number" db:"receipt_number"`
+	ReceiptDate          time.Time                `json:"receipt_date" db:"receipt_date"`
+	ReferenceNumber      *string                  `json:"reference_number,omitempty" db:"reference_number"`
+	Notes                *string                  `json:"notes,omitempty" db:"notes"`
+	TotalValue           float64                  `json:"total_value" db:"total_value"`
+	CreatedBy            int                      `json:"created_by" db:"created_by"`
+	CreatedAt            time.Time                `json:"created_at" db:"created_at"`
+	Items                []ConsignmentReceiptItem `json:"items,omitempty"`
+}
+
+type ConsignmentReceiptItem struct {
+	ConsignmentReceiptItemID int        `json:"consignment_receipt_item_id" db:"consignment_receipt_item_id"`
+	ConsignmentReceiptID     int        `json:"consignment_receipt_id" db:"consignment_receipt_id"`
+	ProductID                int        `json:"product_id" db:"product_id"`
+	ProductName              string     `json:"product_name,omitempty"`
+	BarcodeID                *int       `json:"barcode_id,omitempty" db:"barcode_id"`
+	Quantity                 float64    `json:"quantity" db:"quantity"`
+	UnitCost                 float64    `json:"unit_cost" db:"unit_cost"`
+	BatchNumber              *string    `json:"batch_number,omitempty" db:"batch_number"`
+	ExpiryDate               *time.Time `json:"expiry_date,omitempty" db:"expiry_date"`
+	SerialNumbers            []string   `json:"serial_numbers,omitempty" db:"serial_numbers"`
+}
+
+// CreateConsignmentReceiptRequest receives consignor stock in stock units at
+// the agreed unit cost the supplier will bill once it sells.
+type CreateConsignmentReceiptRequest struct {
+	SupplierID      int                                   `json:"supplier_id" validate:"required,gt=0"`
+	ReceiptDate     *string                               `json:"receipt_date,omitempty"`
+	ReferenceNumber *string                               `json:"reference_number,omitempty" validate:"omitempty,max=100"`
+	Notes           *string                               `json:"notes,omitempty"`
+	Items           []CreateConsignmentReceiptItemRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+type CreateConsignmentReceiptItemRequest struct {
+	ProductID     int        `json:"product_id" validate:"required,gt=0"`
+	BarcodeID     *int       `json:"barcode_id,omitempty"`
+	Quantity      float64    `json:"quantity" validate:"required,gt=0"`
+	UnitCost      float64    `json:"unit_cost" validate:"gte=0"`
+	BatchNumber   *string    `json:"batch_number,omitempty" validate:"omitempty,max=100"`
+	ExpiryDate    *time.Time `json:"expiry_date,omitempty"`
+	SerialNumbers []string   `json:"serial_numbers,omitempty"`
+}
+
+// ConsignmentStatement summarises a consignor's stock for a period: what was
+// received, what sold, how much of that is already settled and what is
+// still on our shelves.
+type ConsignmentStatement struct {
+	SupplierID        int                        `json:"supplier_id"`
+	SupplierName      string                     `json:"supplier_name"`
+	PeriodStart       *time.Time                 `json:"period_start,omitempty"`
+	PeriodEnd         time.Time                  `json:"period_end"`
+	ReceivedQuantity  float64                    `json:"received_quantity"`
+	SoldQuantity      float64                    `json:"sold_quantity"`
+	SettledQuantity   float64                    `json:"settled_quantity"`
+	UnsettledQuantity float64                    `json:"unsettled_quantity"`
+	UnsettledAmount   float64                    `json:"unsettled_amount"`
+	OnHandQuantity    float64                    `json:"on_hand_quantity"`
+	OnHandValue       float64                    `json:"on_hand_value"`
+	Lines             []ConsignmentStatementLine `json:"lines"`
+}
+
+type ConsignmentStatementLine struct {
+	ProductID         int     `json:"product_id"`
+	ProductName       string  `json:"product_name"`
+	BarcodeID         *int    `json:"barcode_id,omitempty"`
+	ReceivedQuantity  float64 `json:"received_quantity"`
+	SoldQuantity      float64 `json:"sold_quantity"`
+	SettledQuantity   float64 `json:"settled_quantity"`
+	UnsettledQuantity float64 `json:"unsettled_quantity"`
+	UnsettledAmount   float64 `json:"unsettled_amount"`
+	OnHandQuantity    float64 `json:"on_hand_quantity"`
+	OnHandValue       float64 `json:"on_hand_value"`
+}
+
+// ConsignmentSettlement bills a consignor for consignment-in stock sold up
+// to PeriodEnd. PurchaseID is the supplier invoice generated for it.
+type ConsignmentSettlement struct {
+	SettlementID     int                         `json:"settlement_id" db:"settlement_id"`
+	CompanyID        int                         `json:"company_id" db:"company_id"`
+	LocationID       int                         `json:"location_id" db:"location_id"`
+	SupplierID       int                         `json:"supplier_id" db:"supplier_id"`
+	SupplierName     string                      `json:"supplier_name,omitempty"`
+	SettlementNumber string                      `json:"settlement_number" db:"settlement_number"`
+	PeriodStart      *time.Time                  `json:"period_start,omitempty" db:"period_start"`
+	PeriodEnd        time.Time                   `json:"period_end" db:"period_end"`
+	TotalQuantity    float64                     `json:"total_quantity" db:"total_quantity"`
+	TotalAmount      float64                     `json:"total_amount" db:"total_amount"`
+	PurchaseID       *int                        `json:"purchase_id,omitempty" db:"purchase_id"`
+	PurchaseNumber   *string                     `json:"purchase_number,omitempty"`
+	Notes            *string                     `json:"notes,omitempty" db:"notes"`
+	CreatedBy        int                         `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time                   `json:"created_at" db:"created_at"`
+	Lines            []ConsignmentSettlementLine `json:"lines,omitempty"`
+}
+
+type ConsignmentSettlementLine struct {
+	SettlementLineID int       `json:"settlement_line_id" db:"settlement_line_id"`
+	MovementID       int       `json:"movement_id" db:"movement_id"`
+	StockLotID       int       `json:"stock_lot_id" db:"stock_lot_id"`
+	ProductID        int       `json:"product_id" db:"product_id"`
+	ProductName      string    `json:"product_name,omitempty"`
+	BarcodeID        *int      `json:"barcode_id,omitempty" db:"barcode_id"`
+	Quantity         float64   `json:"quantity" db:"quantity"`
+	UnitCost         float64   `json:"unit_cost" db:"unit_cost"`
+	Amount           float64   `json:"amount" db:"amount"`
+	SoldAt           time.Time `json:"sold_at" db:"sold_at"`
+}
+
+// CreateConsignmentSettlementRequest settles every unsettled sale of the
+// supplier's consignment stock up to PeriodEnd (and from PeriodStart when
+// given). Dates are YYYY-MM-DD.
+type CreateConsignmentSettlementRequest struct {
+	SupplierID   int     `json:"supplier_id" validate:"required,gt=0"`
+	PeriodStart  *string `json:"period_start,omitempty"`
+	PeriodEnd    string  `json:"period_end" validate:"required"`
+	PaymentTerms *int    `json:"payment_terms,omitempty" validate:"omitempty,gte=0"`
+	Notes        *string `json:"notes,omitempty"`
+}
+
+// DealerLocation is a location holding consignment-out stock for a dealer
+// customer, with the stock still on our books there.
+type DealerLocation struct {
+	LocationID     int     `json:"location_id"`
+	Name           string  `json:"name"`
+	CustomerID     int     `json:"customer_id"`
+	CustomerName   string  `json:"customer_name"`
+	OnHandQuantity float64 `json:"on_hand_quantity"`
+	OnHandValue    float64 `json:"on_hand_value"`
+}
+
+// SetDealerLocationRequest assigns a dealer customer to a location; a nil
+// CustomerID turns it back into an ordinary location.
+type SetDealerLocationRequest struct {
+	CustomerID *int `json:"customer_id" validate:"omitempty,gt=0"`
+}
+
+// DealerSalesReportRequest records what a dealer sold out of consignment-out
+// stock. It is invoiced to the dealer customer as a credit sale.
+type DealerSalesReportRequest struct {
+	ReferenceNumber *string                 `json:"reference_number,omitempty" validate:"omitempty,max=100"`
+	Notes           *string                 `json:"notes,omitempty"`
+	Items           []DealerSalesReportItem `json:"items" validate:"required,min=1,dive"`
+}
+
+type DealerSalesReportItem struct {
+	ProductID        int                            `json:"product_id" validate:"required,gt=0"`
+	BarcodeID        *int                           `json:"barcode_id,omitempty"`
+	Quantity         float64                        `json:"quantity" validate:"required,gt=0"`
+	UnitPrice        float64                        `json:"unit_price" validate:"required,gt=0"`
+	TaxID            *int                           `json:"tax_id,omitempty"`
+	SerialNumbers    []string                       `json:"serial_numbers,omitempty"`
+	BatchAllocations []InventoryBatchSelectionInput `json:"batch_allocations,omitempty"`
+}
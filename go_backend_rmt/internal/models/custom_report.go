@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// CustomReportDefinition is a user-built query over one semantic model. Every
+// field name refers to a dimension or measure the model declares; nothing here
+// is passed to SQL verbatim.
+type CustomReportDefinition struct {
+	Model      string               `json:"model" validate:"required"`
+	Dimensions []string             `json:"dimensions" validate:"max=5"`
+	Measures   []string             `json:"measures" validate:"required,min=1,max=10"`
+	Filters    []CustomReportFilter `json:"filters" validate:"max=20,dive"`
+	Sort       []CustomReportSort   `json:"sort" validate:"max=5,dive"`
+	// Pivot spreads one of the dimensions across columns.
+	Pivot *CustomReportPivot `json:"pivot,omitempty"`
+	// DateRange (a relative preset) or FromDate/ToDate restrict the model's
+	// date; values supplied when running the report take precedence.
+	DateRange string `json:"date_range,omitempty" validate:"omitempty,oneof=today yesterday last_7_days last_30_days month_to_date last_month year_to_date"`
+	FromDate  string `json:"from_date,omitempty"`
+	ToDate    string `json:"to_date,omitempty"`
+	Limit     int    `json:"limit,omitempty" validate:"omitempty,min=1,max=10000"`
+}
+
+// CustomReportFilter compares a dimension (or its "<dimension>_id" key) with
+// the given values. eq, neq, gte, lte and contains take one value, between
+// takes two and in/not_in take one or more.
+type CustomReportFilter struct {
+	Field  string   `json:"field" validate:"required"`
+	Op     string   `json:"op" validate:"required,oneof=eq neq in not_in gte lte between contains"`
+	Values []string `json:"values" validate:"required,min=1,max=100"`
+}
+
+type CustomReportSort struct {
+	Field     string `json:"field" validate:"required"`
+	Direction string `json:"direction" validate:"omitempty,oneof=asc desc"`
+}
+
+type CustomReportPivot struct {
+	Dimension string `json:"dimension" validate:"required"`
+}
+
+type CustomReport struct {
+	ReportID    int                    `json:"report_id" db:"report_id"`
+	CompanyID   int                    `json:"company_id" db:"company_id"`
+	Name        string                 `json:"name" db:"name"`
+	Description *string                `json:"description,omitempty" db:"description"`
+	Definition  CustomReportDefinition `json:"definition" db:"definition"`
+	IsShared    bool                   `json:"is_shared" db:"is_shared"`
+	CreatedBy   int                    `json:"created_by" db:"created_by"`
+	UpdatedBy   *int                   `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+type CreateCustomReportRequest struct {
+	Name        string                 `json:"name" validate:"required,max=150"`
+	Description *string                `json:"description"`
+	Definition  CustomReportDefinition `json:"definition" validate:"required"`
+	IsShared    bool                   `json:"is_shared"`
+}
+
+type UpdateCustomReportRequest struct {
+	Name        *string                 `json:"name" validate:"omitempty,max=150"`
+	Description *string                 `json:"description"`
+	Definition  *CustomReportDefinition `json:"definition"`
+	IsShared    *bool                   `json:"is_shared"`
+}
+
+// CustomReportRunOptions override the saved date restriction for one run.
+type CustomReportRunOptions struct {
+	DateRange string `form:"date_range" validate:"omitempty,oneof=today yesterday last_7_days last_30_days month_to_date last_month year_to_date"`
+	FromDate  string `form:"from_date"`
+	ToDate    string `form:"to_date"`
+}
+
+type CustomReportColumn struct {
+	Key    string `json:"key"`
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`
+	Format string `json:"format"`
+}
+
+type CustomReportResult struct {
+	Title     string                   `json:"title"`
+	Model     string                   `json:"model"`
+	Columns   []CustomReportColumn     `json:"columns"`
+	Rows      []map[string]interface{} `json:"rows"`
+	Truncated bool                     `json:"truncated"`
+	// LocationIDs is set when results were limited to the user's locations.
+	LocationIDs []int `json:"location_ids,omitempty"`
+}
+
+// SemanticModelInfo describes what a model exposes to the report builder.
+type SemanticModelInfo struct {
+	Key            string              `json:"key"`
+	Label          string              `json:"label"`
+	LocationScoped bool                `json:"location_scoped"`
+	Dimensions     []SemanticFieldInfo `json:"dimensions"`
+	Measures       []SemanticFieldInfo `json:"measures"`
+}
+
+type SemanticFieldInfo struct {
+	Key        string `json:"key"`
+	Label      string `json:"label"`
+	Type       string `json:"type"`
+	Filterable bool   `json:"filterable,omitempty"`
+	HasID      bool   `json:"has_id,omitempty"`
+}
@@ -1,25 +1,29 @@
 package models
 
 type Customer struct {
-	CustomerID      int                        `json:"customer_id" db:"customer_id"`
-	CompanyID       int                        `json:"company_id" db:"company_id"`
-	Name            string                     `json:"name" db:"name" validate:"required,min=2,max=255"`
-	CustomerType    string                     `json:"customer_type" db:"customer_type"`
-	ContactPerson   *string                    `json:"contact_person,omitempty" db:"contact_person"`
-	Phone           *string                    `json:"phone,omitempty" db:"phone"`
-	Email           *string                    `json:"email,omitempty" db:"email" validate:"omitempty,email"`
-	Address         *string                    `json:"address,omitempty" db:"address"`
-	ShippingAddress *string                    `json:"shipping_address,omitempty" db:"shipping_address"`
-	TaxNumber       *string                    `json:"tax_number,omitempty" db:"tax_number"`
-	CreditLimit     float64                    `json:"credit_limit" db:"credit_limit"`
-	PaymentTerms    int                        `json:"payment_terms" db:"payment_terms"` // Days
-	IsLoyalty       bool                       `json:"is_loyalty" db:"is_loyalty"`
-	LoyaltyTierID   *int                       `json:"loyalty_tier_id,omitempty" db:"loyalty_tier_id"`
-	IsActive        bool                       `json:"is_active" db:"is_active"`
-	CreatedBy       int                        `json:"created_by" db:"created_by"`
-	UpdatedBy       *int                       `json:"updated_by,omitempty" db:"updated_by"`
-	CreditBalance   float64                    `json:"credit_balance,omitempty" db:"-"`
-	Invoices        []CustomerInvoiceReference `json:"invoices,omitempty" db:"-"`
+	CustomerID      int     `json:"customer_id" db:"customer_id"`
+	CompanyID       int     `json:"company_id" db:"company_id"`
+	Name            string  `json:"name" db:"name" validate:"required,min=2,max=255"`
+	CustomerType    string  `json:"customer_type" db:"customer_type"`
+	ContactPerson   *string `json:"contact_person,omitempty" db:"contact_person"`
+	Phone           *string `json:"phone,omitempty" db:"phone"`
+	Email           *string `json:"email,omitempty" db:"email" validate:"omitempty,email"`
+	Address         *string `json:"address,omitempty" db:"address"`
+	ShippingAddress *string `json:"shipping_address,omitempty" db:"shipping_address"`
+	TaxNumber       *string `json:"tax_number,omitempty" db:"tax_number"`
+	CreditLimit     float64 `json:"credit_limit" db:"credit_limit"`
+	PaymentTerms    int     `json:"payment_terms" db:"payment_terms"` // Days
+	IsLoyalty       bool    `json:"is_loyalty" db:"is_loyalty"`
+	LoyaltyTierID   *int    `json:"loyalty_tier_id,omitempty" db:"loyalty_tier_id"`
+	IsActive        bool    `json:"is_active" db:"is_active"`
+	// DunningLevel is the highest reminder level reached by the customer's
+	// overdue invoices; DunningCreditBlocked refuses new credit sales.
+	DunningLevel         int                        `json:"dunning_level" db:"dunning_level"`
+	DunningCreditBlocked bool                       `json:"dunning_credit_blocked" db:"dunning_credit_blocked"`
+	CreatedBy            int                        `json:"created_by" db:"created_by"`
+	UpdatedBy            *int                       `json:"updated_by,omitempty" db:"updated_by"`
+	CreditBalance        float64                    `json:"credit_balance,omitempty" db:"-"`
+	Invoices             []CustomerInvoiceReference `json:"invoices,omitempty" db:"-"`
 	SyncModel
 }
 
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+const (
+	StatementEntryInvoice    = "INVOICE"
+	StatementEntryCreditNote = "CREDIT_NOTE"
+	StatementEntryPayment    = "PAYMENT"
+	StatementEntryReturn     = "RETURN"
+	StatementEntryCollection = "COLLECTION"
+
+	StatementDeliverySent   = "SENT"
+	StatementDeliveryFailed = "FAILED"
+
+	DunningChannelNone  = "NONE"
+	DunningChannelEmail = "EMAIL"
+
+	DunningReminderRecorded = "RECORDED"
+	DunningReminderSent     = "SENT"
+	DunningReminderFailed   = "FAILED"
+)
+
+// CustomerStatementEntry is one line of a statement of account. Debits raise
+// the customer's balance, credits lower it.
+type CustomerStatementEntry struct {
+	Date        time.Time `json:"date"`
+	EntryType   string    `json:"entry_type"`
+	Reference   string    `json:"reference"`
+	Description string    `json:"description,omitempty"`
+	SaleID      *int      `json:"sale_id,omitempty"`
+	Debit       float64   `json:"debit"`
+	Credit      float64   `json:"credit"`
+	Balance     float64   `json:"balance"`
+}
+
+type CustomerStatement struct {
+	CustomerID     int                      `json:"customer_id"`
+	CustomerName   string                   `json:"customer_name"`
+	CustomerEmail  *string                  `json:"customer_email,omitempty"`
+	CustomerPhone  *string                  `json:"customer_phone,omitempty"`
+	Address        *string                  `json:"address,omitempty"`
+	PaymentTerms   int                      `json:"payment_terms"`
+	FromDate       time.Time                `json:"from_date"`
+	ToDate         time.Time                `json:"to_date"`
+	OpeningBalance float64                  `json:"opening_balance"`
+	TotalDebits    float64                  `json:"total_debits"`
+	TotalCredits   float64                  `json:"total_credits"`
+	ClosingBalance float64                  `json:"closing_balance"`
+	Entries        []CustomerStatementEntry `json:"entries"`
+	Aging          APAgingBuckets           `json:"aging"`
+}
+
+type EmailCustomerStatementRequest struct {
+	FromDate *string `json:"from_date,omitempty"`
+	ToDate   *string `json:"to_date,omitempty"`
+	Email    *string `json:"email,omitempty" validate:"omitempty,email"`
+	Message  *string `json:"message,omitempty"`
+}
+
+type BulkEmailCustomerStatementsRequest struct {
+	FromDate    *string `json:"from_date,omitempty"`
+	ToDate      *string `json:"to_date,omitempty"`
+	CustomerIDs []int   `json:"customer_ids,omitempty"`
+	Message     *string `json:"message,omitempty"`
+	// OnlyWithBalance skips customers whose closing balance is zero.
+	OnlyWithBalance bool `json:"only_with_balance"`
+}
+
+type CustomerStatementDelivery struct {
+	CustomerID     int     `json:"customer_id"`
+	CustomerName   string  `json:"customer_name"`
+	Email          *string `json:"email,omitempty"`
+	ClosingBalance float64 `json:"closing_balance"`
+	Status         string  `json:"status"`
+	Error          *string `json:"error,omitempty"`
+}
+
+type RunDunningRequest struct {
+	AsOf        *string `json:"as_of,omitempty"`
+	CustomerIDs []int   `json:"customer_ids,omitempty"`
+	SendEmail   bool    `json:"send_email"`
+	DryRun      bool    `json:"dry_run"`
+}
+
+type DunningReminder struct {
+	ReminderID    int       `json:"reminder_id"`
+	CompanyID     int       `json:"company_id"`
+	CustomerID    int       `json:"customer_id"`
+	CustomerName  string    `json:"customer_name,omitempty"`
+	SaleID        int       `json:"sale_id"`
+	SaleNumber    string    `json:"sale_number,omitempty"`
+	Level         int       `json:"level"`
+	LevelName     string    `json:"level_name"`
+	DaysOverdue   int       `json:"days_overdue"`
+	AmountDue     float64   `json:"amount_due"`
+	LateFeeAmount float64   `json:"late_fee_amount"`
+	LateFeeSaleID *int      `json:"late_fee_sale_id,omitempty"`
+	Channel       string    `json:"channel"`
+	Status        string    `json:"status"`
+	ErrorMessage  *string   `json:"error_message,omitempty"`
+	CreatedBy     int       `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type DunningRunResult struct {
+	AsOf             time.Time         `json:"as_of"`
+	DryRun           bool              `json:"dry_run"`
+	Reminders        []DunningReminder `json:"reminders"`
+	LateFeeTotal     float64           `json:"late_fee_total"`
+	CustomersUpdated int               `json:"customers_updated"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// Document types that can be rendered through invoice templates.
+const (
+	DocumentTypeSale       = "SALE"
+	DocumentTypeQuote      = "QUOTE"
+	DocumentTypeSaleReturn = "SALE_RETURN"
+	DocumentTypePurchase   = "PURCHASE"
+	DocumentTypePayslip    = "PAYSLIP"
+	DocumentTypeWarranty   = "WARRANTY"
+
+	DocumentFormatHTML = "html"
+	DocumentFormatPDF  = "pdf"
+)
+
+// RenderedDocument is a server-rendered copy of a document.
+type RenderedDocument struct {
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename"`
+	Content     []byte `json:"-"`
+}
+
+// PreviewInvoiceTemplateRequest renders sample data through a template. Either
+// a saved TemplateID or an unsaved Layout may be supplied; an unsaved layout
+// wins so the editor can preview changes before saving.
+type PreviewInvoiceTemplateRequest struct {
+	TemplateID        *int    `json:"template_id,omitempty"`
+	TemplateType      *string `json:"template_type,omitempty" validate:"omitempty,oneof=INVOICE RECEIPT QUOTE SALE_RETURN PURCHASE PAYSLIP WARRANTY"`
+	Layout            JSONB   `json:"layout,omitempty"`
+	PrimaryLanguage   *string `json:"primary_language,omitempty"`
+	SecondaryLanguage *string `json:"secondary_language,omitempty"`
+	Format            string  `json:"format,omitempty" validate:"omitempty,oneof=html pdf"`
+}
+
+// EmailDocumentRequest sends the rendered PDF to a recipient. Email defaults
+// to the customer, supplier or employee on the document.
+type EmailDocumentRequest struct {
+	Email      *string `json:"email,omitempty" validate:"omitempty,email"`
+	TemplateID *int    `json:"template_id,omitempty"`
+	Message    *string `json:"message,omitempty"`
+}
+
+type EmailDocumentResponse struct {
+	Email    string `json:"email"`
+	Filename string `json:"filename"`
+}
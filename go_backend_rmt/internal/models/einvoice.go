@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+const (
+	EInvoiceTypeInvoice       = "INVOICE"
+	EInvoiceTypeRefundInvoice = "REFUND_INVOICE"
+	EInvoiceTypeCreditNote    = "CREDIT_NOTE"
+
+	EInvoiceSubmissionPending  = "PENDING"
+	EInvoiceSubmissionAccepted = "ACCEPTED"
+	EInvoiceSubmissionRejected = "REJECTED"
+	EInvoiceSubmissionFailed   = "FAILED"
+)
+
+// EInvoice is the compliance artifact issued for a finalized sale, refund
+// invoice or sale return. InvoiceHash and Signature are base64 encoded; the
+// signed UBL document is served separately.
+type EInvoice struct {
+	EInvoiceID          int        `json:"einvoice_id" db:"einvoice_id"`
+	CompanyID           int        `json:"company_id" db:"company_id"`
+	LocationID          int        `json:"location_id" db:"location_id"`
+	SourceType          string     `json:"source_type" db:"source_type"`
+	SourceID            int        `json:"source_id" db:"source_id"`
+	SaleID              int        `json:"sale_id" db:"sale_id"`
+	DocumentType        string     `json:"document_type" db:"document_type"`
+	InvoiceNumber       string     `json:"invoice_number" db:"invoice_number"`
+	UUID                string     `json:"uuid" db:"uuid"`
+	Counter             int64      `json:"counter" db:"counter"`
+	PreviousHash        string     `json:"previous_hash" db:"previous_hash"`
+	InvoiceHash         string     `json:"invoice_hash" db:"invoice_hash"`
+	Signature           string     `json:"signature" db:"signature"`
+	KeyID               int        `json:"key_id" db:"key_id"`
+	QRPayload           string     `json:"qr_payload" db:"qr_payload"`
+	TotalAmount         float64    `json:"total_amount" db:"total_amount"`
+	TaxAmount           float64    `json:"tax_amount" db:"tax_amount"`
+	IssuedAt            time.Time  `json:"issued_at" db:"issued_at"`
+	SubmissionStatus    string     `json:"submission_status" db:"submission_status"`
+	SubmissionReference *string    `json:"submission_reference,omitempty" db:"submission_reference"`
+	SubmissionError     *string    `json:"submission_error,omitempty" db:"submission_error"`
+	SubmittedAt         *time.Time `json:"submitted_at,omitempty" db:"submitted_at"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	XML                 string     `json:"-" db:"xml"`
+}
+
+// EInvoiceSigningKey exposes the public half of a company signing key.
+type EInvoiceSigningKey struct {
+	KeyID        int        `json:"key_id" db:"key_id"`
+	Algorithm    string     `json:"algorithm" db:"algorithm"`
+	PublicKeyPEM string     `json:"public_key_pem" db:"public_key_pem"`
+	IsActive     bool       `json:"is_active" db:"is_active"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RetiredAt    *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+}
+
+type EInvoiceChainProblem struct {
+	EInvoiceID int    `json:"einvoice_id"`
+	Counter    int64  `json:"counter"`
+	Problem    string `json:"problem"`
+}
+
+// EInvoiceChainVerification reports whether every invoice of a location still
+// hashes to its stored value, links to its predecessor and carries a valid
+// signature.
+type EInvoiceChainVerification struct {
+	LocationID int                    `json:"location_id"`
+	Checked    int                    `json:"checked"`
+	Valid      bool                   `json:"valid"`
+	Problems   []EInvoiceChainProblem `json:"problems"`
+}
+
+// IssueEInvoiceRequest issues the e-invoice for a document finalized before
+// e-invoicing was enabled or whose automatic issuance failed.
+type IssueEInvoiceRequest struct {
+	SourceType string `json:"source_type" validate:"required,oneof=sale sale_return"`
+	SourceID   int    `json:"source_id" validate:"required,gt=0"`
+}
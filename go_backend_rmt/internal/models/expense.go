@@ -33,6 +33,10 @@ type CreateExpenseRequest struct {
 	CategoryID int     `json:"category_id" validate:"required"`
 	Amount     float64 `json:"amount" validate:"required,gt=0"`
 	Notes      *string `json:"notes,omitempty"`
+	// DepartmentID attributes the expense to a department budget.
+	DepartmentID *int `json:"department_id,omitempty"`
+	// Dimensions override the analytic defaults for this expense's postings.
+	Dimensions []AnalyticTag `json:"dimensions,omitempty" validate:"omitempty,dive"`
 	// Accept "YYYY-MM-DD" (Flutter default) or RFC3339 via ExpenseDateRaw.
 	// Handler parses and populates ExpenseDate for DB insert.
 	ExpenseDateRaw *string   `json:"expense_date,omitempty"`
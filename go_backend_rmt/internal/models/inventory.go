@@ -255,6 +255,9 @@ type StockTransferWithDetails struct {
 	ApprovedBy       *int                             `json:"approved_by,omitempty" db:"approved_by"`
 	ApprovedAt       *time.Time                       `json:"approved_at,omitempty" db:"approved_at"`
 	ApprovedByName   *string                          `json:"approved_by_name,omitempty"`
+	TransitValue     float64                          `json:"transit_value" db:"transit_value"`
+	ReceivedBy       *int                             `json:"received_by,omitempty" db:"received_by"`
+	ReceivedAt       *time.Time                       `json:"received_at,omitempty" db:"received_at"`
 	Items            []StockTransferDetailWithProduct `json:"items"`
 	TotalItems       int                              `json:"total_items"`
 	TotalQuantity    float64                          `json:"total_quantity"`
@@ -282,6 +285,9 @@ type StockTransferDetailWithProduct struct {
 	TrackingType     string                         `json:"tracking_type,omitempty"`
 	Quantity         float64                        `json:"quantity" db:"quantity"`
 	ReceivedQuantity float64                        `json:"received_quantity" db:"received_quantity"`
+	ShortQuantity    float64                        `json:"short_quantity" db:"short_quantity"`
+	DamagedQuantity  float64                        `json:"damaged_quantity" db:"damaged_quantity"`
+	TransitValue     float64                        `json:"transit_value" db:"transit_value"`
 	BatchAllocations []InventoryBatchSelectionInput `json:"batch_allocations,omitempty" db:"-"`
 	SerialNumbers    []string                       `json:"serial_numbers,omitempty" db:"-"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+const (
+	InvoiceMatchStatusMatched         = "MATCHED"
+	InvoiceMatchStatusVariance        = "VARIANCE"
+	InvoiceMatchStatusOverridePending = "OVERRIDE_PENDING"
+	InvoiceMatchStatusOverridden      = "OVERRIDDEN"
+
+	InvoiceMatchLineMatched               = "MATCHED"
+	InvoiceMatchLineQuantityVariance      = "QUANTITY_VARIANCE"
+	InvoiceMatchLinePriceVariance         = "PRICE_VARIANCE"
+	InvoiceMatchLineQuantityPriceVariance = "QUANTITY_PRICE_VARIANCE"
+)
+
+// SupplierInvoiceMatch is the three-way match of a supplier invoice against
+// its purchase order and the goods received for it.
+type SupplierInvoiceMatch struct {
+	MatchID                   int                        `json:"match_id" db:"match_id"`
+	CompanyID                 int                        `json:"company_id" db:"company_id"`
+	PurchaseID                int                        `json:"purchase_id" db:"purchase_id"`
+	SupplierID                int                        `json:"supplier_id" db:"supplier_id"`
+	SupplierInvoiceNumber     string                     `json:"supplier_invoice_number" db:"supplier_invoice_number"`
+	InvoiceDate               time.Time                  `json:"invoice_date" db:"invoice_date"`
+	InvoiceTotal              float64                    `json:"invoice_total" db:"invoice_total"`
+	Status                    string                     `json:"status" db:"status"`
+	QuantityTolerancePercent  float64                    `json:"quantity_tolerance_percent" db:"quantity_tolerance_percent"`
+	PriceTolerancePercent     float64                    `json:"price_tolerance_percent" db:"price_tolerance_percent"`
+	PriceVarianceTotal        float64                    `json:"price_variance_total" db:"price_variance_total"`
+	PriceVarianceAdjustmentID *int                       `json:"price_variance_adjustment_id,omitempty" db:"price_variance_adjustment_id"`
+	OverrideApprovalID        *int                       `json:"override_approval_id,omitempty" db:"override_approval_id"`
+	OverrideReason            *string                    `json:"override_reason,omitempty" db:"override_reason"`
+	CreatedBy                 int                        `json:"created_by" db:"created_by"`
+	UpdatedBy                 *int                       `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt                 time.Time                  `json:"created_at" db:"created_at"`
+	UpdatedAt                 time.Time                  `json:"updated_at" db:"updated_at"`
+	Lines                     []SupplierInvoiceMatchLine `json:"lines,omitempty"`
+}
+
+type SupplierInvoiceMatchLine struct {
+	MatchLineID             int     `json:"match_line_id" db:"match_line_id"`
+	MatchID                 int     `json:"match_id" db:"match_id"`
+	PurchaseDetailID        int     `json:"purchase_detail_id" db:"purchase_detail_id"`
+	ProductID               int     `json:"product_id" db:"product_id"`
+	ProductName             string  `json:"product_name,omitempty" db:"product_name"`
+	OrderedQuantity         float64 `json:"ordered_quantity" db:"ordered_quantity"`
+	ReceivedQuantity        float64 `json:"received_quantity" db:"received_quantity"`
+	InvoicedQuantity        float64 `json:"invoiced_quantity" db:"invoiced_quantity"`
+	OrderedUnitPrice        float64 `json:"ordered_unit_price" db:"ordered_unit_price"`
+	InvoicedUnitPrice       float64 `json:"invoiced_unit_price" db:"invoiced_unit_price"`
+	QuantityVariance        float64 `json:"quantity_variance" db:"quantity_variance"`
+	QuantityVariancePercent float64 `json:"quantity_variance_percent" db:"quantity_variance_percent"`
+	PriceVariancePercent    float64 `json:"price_variance_percent" db:"price_variance_percent"`
+	PriceVarianceAmount     float64 `json:"price_variance_amount" db:"price_variance_amount"`
+	Status                  string  `json:"status" db:"status"`
+}
+
+type SupplierInvoiceLineInput struct {
+	PurchaseDetailID int     `json:"purchase_detail_id" validate:"required"`
+	Quantity         float64 `json:"quantity" validate:"gte=0"`
+	UnitPrice        float64 `json:"unit_price" validate:"gte=0"`
+}
+
+type RecordSupplierInvoiceRequest struct {
+	SupplierInvoiceNumber string                     `json:"supplier_invoice_number" validate:"required,max=100"`
+	InvoiceDate           *string                    `json:"invoice_date,omitempty"`
+	Lines                 []SupplierInvoiceLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+type RequestInvoiceMatchOverrideRequest struct {
+	Reason string `json:"reason" validate:"required,min=3"`
+}
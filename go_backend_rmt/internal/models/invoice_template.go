@@ -18,7 +18,7 @@ type InvoiceTemplate struct {
 type CreateInvoiceTemplateRequest struct {
 	CompanyID         int     `json:"company_id" validate:"required"`
 	Name              string  `json:"name" validate:"required"`
-	TemplateType      string  `json:"template_type" validate:"required"`
+	TemplateType      string  `json:"template_type" validate:"required,oneof=INVOICE RECEIPT QUOTE SALE_RETURN PURCHASE PAYSLIP WARRANTY"`
 	Layout            JSONB   `json:"layout" validate:"required"`
 	PrimaryLanguage   *string `json:"primary_language,omitempty"`
 	SecondaryLanguage *string `json:"secondary_language,omitempty"`
@@ -29,7 +29,7 @@ type CreateInvoiceTemplateRequest struct {
 // UpdateInvoiceTemplateRequest is the payload for updating invoice templates.
 type UpdateInvoiceTemplateRequest struct {
 	Name              *string `json:"name,omitempty"`
-	TemplateType      *string `json:"template_type,omitempty"`
+	TemplateType      *string `json:"template_type,omitempty" validate:"omitempty,oneof=INVOICE RECEIPT QUOTE SALE_RETURN PURCHASE PAYSLIP WARRANTY"`
 	Layout            *JSONB  `json:"layout,omitempty"`
 	PrimaryLanguage   *string `json:"primary_language,omitempty"`
 	SecondaryLanguage *string `json:"secondary_language,omitempty"`
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+const (
+	LandedCostAllocationByValue    = "VALUE"
+	LandedCostAllocationByQuantity = "QUANTITY"
+	LandedCostAllocationByWeight   = "WEIGHT"
+	LandedCostAllocationByVolume   = "VOLUME"
+)
+
+// LandedCostVoucher allocates third-party charges over the lines of several
+// goods receipts. InventoryAmount was added to lots still on hand and
+// COGSAmount covers quantities sold or consumed before the charges arrived.
+type LandedCostVoucher struct {
+	VoucherID        int                    `json:"voucher_id" db:"voucher_id"`
+	CompanyID        int                    `json:"company_id" db:"company_id"`
+	LocationID       int                    `json:"location_id" db:"location_id"`
+	VoucherNumber    string                 `json:"voucher_number" db:"voucher_number"`
+	VoucherDate      time.Time              `json:"voucher_date" db:"voucher_date"`
+	AllocationMethod string                 `json:"allocation_method" db:"allocation_method"`
+	ReferenceNumber  *string                `json:"reference_number,omitempty" db:"reference_number"`
+	Notes            *string                `json:"notes,omitempty" db:"notes"`
+	TotalAmount      float64                `json:"total_amount" db:"total_amount"`
+	InventoryAmount  float64                `json:"inventory_amount" db:"inventory_amount"`
+	COGSAmount       float64                `json:"cogs_amount" db:"cogs_amount"`
+	CreatedBy        int                    `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time              `json:"created_at" db:"created_at"`
+	GoodsReceiptIDs  []int                  `json:"goods_receipt_ids"`
+	Charges          []LandedCostCharge     `json:"charges,omitempty"`
+	Allocations      []LandedCostAllocation `json:"allocations,omitempty"`
+}
+
+// LandedCostCharge is one supplier invoice on the voucher and the payable it
+// raises against that supplier.
+type LandedCostCharge struct {
+	ChargeID         int     `json:"charge_id" db:"charge_id"`
+	VoucherID        int     `json:"voucher_id" db:"voucher_id"`
+	SupplierID       int     `json:"supplier_id" db:"supplier_id"`
+	SupplierName     string  `json:"supplier_name,omitempty"`
+	Label            string  `json:"label" db:"label"`
+	InvoiceNumber    *string `json:"invoice_number,omitempty" db:"invoice_number"`
+	AllocationMethod string  `json:"allocation_method" db:"allocation_method"`
+	Amount           float64 `json:"amount" db:"amount"`
+}
+
+type LandedCostAllocation struct {
+	AllocationID       int     `json:"allocation_id" db:"allocation_id"`
+	ChargeID           int     `json:"charge_id" db:"charge_id"`
+	GoodsReceiptID     int     `json:"goods_receipt_id"`
+	GoodsReceiptItemID int     `json:"goods_receipt_item_id" db:"goods_receipt_item_id"`
+	PurchaseDetailID   *int    `json:"purchase_detail_id,omitempty" db:"purchase_detail_id"`
+	ProductID          int     `json:"product_id" db:"product_id"`
+	ProductName        string  `json:"product_name,omitempty"`
+	BarcodeID          *int    `json:"barcode_id,omitempty" db:"barcode_id"`
+	StockQuantity      float64 `json:"stock_quantity" db:"stock_quantity"`
+	Basis              float64 `json:"basis" db:"basis"`
+	Amount             float64 `json:"amount" db:"amount"`
+	InventoryAmount    float64 `json:"inventory_amount" db:"inventory_amount"`
+	COGSAmount         float64 `json:"cogs_amount" db:"cogs_amount"`
+}
+
+// CreateLandedCostVoucherRequest posts charges over the given goods receipts.
+// AllocationMethod applies to charges that do not set their own. Lines
+// supplies packing-list weight or volume per receipt line; weight otherwise
+// falls back to the product weight.
+type CreateLandedCostVoucherRequest struct {
+	GoodsReceiptIDs  []int                        `json:"goods_receipt_ids" validate:"required,min=1,dive,gt=0"`
+	AllocationMethod string                       `json:"allocation_method" validate:"required,oneof=VALUE QUANTITY WEIGHT VOLUME"`
+	VoucherDate      *string                      `json:"voucher_date,omitempty"`
+	ReferenceNumber  *string                      `json:"reference_number,omitempty" validate:"omitempty,max=100"`
+	Notes            *string                      `json:"notes,omitempty"`
+	Charges          []LandedCostChargeInput      `json:"charges" validate:"required,min=1,dive"`
+	Lines            []LandedCostLineMeasureInput `json:"lines,omitempty" validate:"omitempty,dive"`
+}
+
+type LandedCostChargeInput struct {
+	SupplierID       int     `json:"supplier_id" validate:"required,gt=0"`
+	Label            string  `json:"label" validate:"required,min=2,max=255"`
+	InvoiceNumber    *string `json:"invoice_number,omitempty" validate:"omitempty,max=100"`
+	Amount           float64 `json:"amount" validate:"required,gt=0"`
+	AllocationMethod *string `json:"allocation_method,omitempty" validate:"omitempty,oneof=VALUE QUANTITY WEIGHT VOLUME"`
+}
+
+type LandedCostLineMeasureInput struct {
+	GoodsReceiptItemID int      `json:"goods_receipt_item_id" validate:"required,gt=0"`
+	Weight             *float64 `json:"weight,omitempty" validate:"omitempty,gt=0"`
+	Volume             *float64 `json:"volume,omitempty" validate:"omitempty,gt=0"`
+}
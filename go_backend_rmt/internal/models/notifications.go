@@ -2,7 +2,29 @@ package models
 
 import "time"
 
+// Notification event types.
+const (
+	NotificationLowStock           = "LOW_STOCK"
+	NotificationApprovalPending    = "APPROVAL_PENDING"
+	NotificationCreditLimit        = "CREDIT_LIMIT_EXCEEDED"
+	NotificationCashVariance       = "CASH_VARIANCE"
+	NotificationTransferInTransit  = "TRANSFER_AWAITING_RECEIPT"
+	NotificationPeriodClosed       = "PERIOD_CLOSED"
+	NotificationFinanceOutboxError = "FINANCE_OUTBOX_FAILED"
+	NotificationBatchExpiry        = "BATCH_NEARING_EXPIRY"
+	NotificationLeaveDecided       = "LEAVE_DECIDED"
+	NotificationRecurringFailed    = "RECURRING_RUN_FAILED"
+)
+
+// Notification email digest frequencies.
+const (
+	NotificationDigestNone   = "NONE"
+	NotificationDigestHourly = "HOURLY"
+	NotificationDigestDaily  = "DAILY"
+)
+
 type NotificationItem struct {
+	ID          int64      `json:"id"`
 	Key         string     `json:"key"`
 	Type        string     `json:"type"`
 	Title       string     `json:"title"`
@@ -22,6 +44,47 @@ type NotificationItem struct {
 	DueAt       *time.Time `json:"due_at,omitempty"`
 }
 
+// NotificationFilter narrows a user's notification list. LocationID keeps
+// notifications for that location and company-wide ones.
+type NotificationFilter struct {
+	LocationID *int
+	UnreadOnly bool
+	Limit      int
+}
+
+// MarkNotificationsReadRequest takes the keys returned in the list
+// ("notification:<id>").
 type MarkNotificationsReadRequest struct {
 	Keys []string `json:"keys" validate:"required,min=1"`
 }
+
+// NotificationEventPreference is a user's channel choice for one event type.
+type NotificationEventPreference struct {
+	EventType string `json:"event_type"`
+	Label     string `json:"label,omitempty"`
+	InApp     bool   `json:"in_app"`
+	Email     bool   `json:"email"`
+	Webhook   bool   `json:"webhook"`
+}
+
+// NotificationPreferences are a user's settings across all event types.
+type NotificationPreferences struct {
+	EmailDigest string                        `json:"email_digest"`
+	WebhookURL  *string                       `json:"webhook_url,omitempty"`
+	Events      []NotificationEventPreference `json:"events"`
+}
+
+type UpdateNotificationEventPreference struct {
+	EventType string `json:"event_type" validate:"required"`
+	InApp     *bool  `json:"in_app"`
+	Email     *bool  `json:"email"`
+	Webhook   *bool  `json:"webhook"`
+}
+
+// UpdateNotificationPreferencesRequest changes only what it sets; an empty
+// webhook_url removes the webhook.
+type UpdateNotificationPreferencesRequest struct {
+	EmailDigest *string                             `json:"email_digest" validate:"omitempty,oneof=NONE HOURLY DAILY"`
+	WebhookURL  *string                             `json:"webhook_url" validate:"omitempty,max=500"`
+	Events      []UpdateNotificationEventPreference `json:"events" validate:"omitempty,dive"`
+}
@@ -0,0 +1,81 @@
+package models
+
+// ProductVariantDimension is a SELECT attribute that splits a parent product
+// into variants. When FollowAttributeOptions is set, options added to the
+// attribute definition join the matrix on the next sync.
+type ProductVariantDimension struct {
+	DimensionID            int                    `json:"dimension_id" db:"dimension_id"`
+	ProductID              int                    `json:"product_id" db:"product_id"`
+	AttributeID            int                    `json:"attribute_id" db:"attribute_id"`
+	AttributeName          string                 `json:"attribute_name"`
+	Position               int                    `json:"position" db:"position"`
+	FollowAttributeOptions bool                   `json:"follow_attribute_options" db:"follow_attribute_options"`
+	Options                []ProductVariantOption `json:"options"`
+}
+
+type ProductVariantOption struct {
+	OptionID   int     `json:"option_id,omitempty" db:"option_id"`
+	Value      string  `json:"value" db:"value"`
+	Code       string  `json:"code" db:"code"`
+	PriceDelta float64 `json:"price_delta" db:"price_delta"`
+	CostDelta  float64 `json:"cost_delta" db:"cost_delta"`
+	Position   int     `json:"position" db:"position"`
+}
+
+// SetProductVariantDimensionsRequest replaces the dimensions of a product and
+// regenerates its variant matrix.
+type SetProductVariantDimensionsRequest struct {
+	Dimensions []ProductVariantDimensionInput `json:"dimensions" validate:"required,min=1,max=3,dive"`
+}
+
+type ProductVariantDimensionInput struct {
+	AttributeID            int                         `json:"attribute_id" validate:"required,gt=0"`
+	FollowAttributeOptions bool                        `json:"follow_attribute_options"`
+	Options                []ProductVariantOptionInput `json:"options,omitempty" validate:"omitempty,dive"`
+}
+
+type ProductVariantOptionInput struct {
+	Value      string  `json:"value" validate:"required"`
+	Code       *string `json:"code,omitempty" validate:"omitempty,max=20"`
+	PriceDelta float64 `json:"price_delta"`
+	CostDelta  float64 `json:"cost_delta"`
+}
+
+// ProductVariantMatrix lists the generated variants of a parent product with
+// stock and sales per variant and rolled up to the parent.
+type ProductVariantMatrix struct {
+	ProductID         int                       `json:"product_id"`
+	ProductName       string                    `json:"product_name"`
+	SKU               *string                   `json:"sku,omitempty"`
+	LocationID        *int                      `json:"location_id,omitempty"`
+	Dimensions        []ProductVariantDimension `json:"dimensions"`
+	Variants          []ProductVariantCell      `json:"variants"`
+	TotalStock        float64                   `json:"total_stock"`
+	TotalSoldQuantity float64                   `json:"total_sold_quantity"`
+	TotalSalesAmount  float64                   `json:"total_sales_amount"`
+	Sync              *ProductVariantSyncResult `json:"sync,omitempty"`
+}
+
+type ProductVariantCell struct {
+	BarcodeID     int               `json:"barcode_id"`
+	SKU           *string           `json:"sku,omitempty"`
+	Barcode       string            `json:"barcode"`
+	VariantName   *string           `json:"variant_name,omitempty"`
+	Options       map[string]string `json:"options"`
+	SellingPrice  float64           `json:"selling_price"`
+	CostPrice     float64           `json:"cost_price"`
+	IsActive      bool              `json:"is_active"`
+	StockQuantity float64           `json:"stock_quantity"`
+	SoldQuantity  float64           `json:"sold_quantity"`
+	SalesAmount   float64           `json:"sales_amount"`
+}
+
+// ProductVariantSyncResult counts the barcode changes made by a matrix sync.
+// Removed variants that are referenced by transactions are deactivated
+// instead of deleted.
+type ProductVariantSyncResult struct {
+	Created     int `json:"created"`
+	Updated     int `json:"updated"`
+	Deactivated int `json:"deactivated"`
+	Removed     int `json:"removed"`
+}
@@ -29,6 +29,9 @@ type Purchase struct {
 	GoodsReceipts   []GoodsReceipt   `json:"goods_receipts,omitempty"`
 	Supplier        *Supplier        `json:"supplier,omitempty"`
 	Location        *Location        `json:"location,omitempty"`
+	// BudgetWarnings lists WARN-mode budgets this purchase overruns. Only set
+	// on the create response.
+	BudgetWarnings []string `json:"budget_warnings,omitempty"`
 	SyncModel
 }
 
@@ -99,6 +102,8 @@ type CreatePurchaseRequest struct {
 	PaymentTerms    *int                          `json:"payment_terms,omitempty"`
 	Notes           *string                       `json:"notes,omitempty"`
 	Items           []CreatePurchaseDetailRequest `json:"items" validate:"required,min=1"`
+	// Dimensions override the analytic defaults for this purchase's postings.
+	Dimensions []AnalyticTag `json:"dimensions,omitempty" validate:"omitempty,dive"`
 }
 
 type CreatePurchaseDetailRequest struct {
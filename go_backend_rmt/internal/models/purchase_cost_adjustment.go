@@ -5,6 +5,8 @@ import "time"
 const (
 	PurchaseCostAdjustmentTypeGRNAddon          = "GRN_ADDON"
 	PurchaseCostAdjustmentTypeSupplierDebitNote = "SUPPLIER_DEBIT_NOTE"
+	PurchaseCostAdjustmentTypeInvoiceVariance   = "INVOICE_PRICE_VARIANCE"
+	PurchaseCostAdjustmentTypeEarlyPayDiscount  = "EARLY_PAYMENT_DISCOUNT"
 
 	PurchaseCostAdjustmentScopeHeader = "HEADER"
 	PurchaseCostAdjustmentScopeItem   = "ITEM"
@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+// Documents a recurring template can create.
+const (
+	RecurringDocumentVoucher = "VOUCHER"
+	RecurringDocumentExpense = "EXPENSE"
+)
+
+// Recurring template frequencies.
+const (
+	RecurringDaily     = "DAILY"
+	RecurringWeekly    = "WEEKLY"
+	RecurringMonthly   = "MONTHLY"
+	RecurringQuarterly = "QUARTERLY"
+	RecurringYearly    = "YEARLY"
+)
+
+// Recurring template amount modes.
+const (
+	RecurringAmountFixed   = "FIXED"
+	RecurringAmountFormula = "FORMULA"
+)
+
+// Recurring run outcomes. Reversals of auto-reversing vouchers move from
+// PENDING to CREATED or FAILED.
+const (
+	RecurringRunRunning = "RUNNING"
+	RecurringRunCreated = "CREATED"
+	RecurringRunSkipped = "SKIPPED"
+	RecurringRunFailed  = "FAILED"
+	RecurringRunPending = "PENDING"
+)
+
+// RecurringTemplate creates a voucher or expense on a schedule. The saved
+// Voucher or Expense request is replayed for each occurrence with the
+// occurrence date, the computed amount and an idempotency key.
+type RecurringTemplate struct {
+	TemplateID     int                   `json:"template_id" db:"template_id"`
+	CompanyID      int                   `json:"company_id" db:"company_id"`
+	Name           string                `json:"name" db:"name"`
+	DocumentType   string                `json:"document_type" db:"document_type"`
+	VoucherType    *string               `json:"voucher_type,omitempty" db:"voucher_type"`
+	LocationID     *int                  `json:"location_id,omitempty" db:"location_id"`
+	Frequency      string                `json:"frequency" db:"frequency"`
+	IntervalCount  int                   `json:"interval_count" db:"interval_count"`
+	StartDate      time.Time             `json:"start_date" db:"start_date"`
+	EndDate        *time.Time            `json:"end_date,omitempty" db:"end_date"`
+	NextRunDate    *time.Time            `json:"next_run_date,omitempty" db:"next_run_date"`
+	NextOccurrence int                   `json:"next_occurrence" db:"next_occurrence"`
+	AmountMode     string                `json:"amount_mode" db:"amount_mode"`
+	AmountFormula  *string               `json:"amount_formula,omitempty" db:"amount_formula"`
+	AutoReverse    bool                  `json:"auto_reverse" db:"auto_reverse"`
+	Voucher        *CreateVoucherRequest `json:"voucher,omitempty"`
+	Expense        *CreateExpenseRequest `json:"expense,omitempty"`
+	IsActive       bool                  `json:"is_active" db:"is_active"`
+	LastRunDate    *time.Time            `json:"last_run_date,omitempty" db:"last_run_date"`
+	LastStatus     *string               `json:"last_status,omitempty" db:"last_status"`
+	CreatedBy      int                   `json:"created_by" db:"created_by"`
+	UpdatedBy      *int                  `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// CreateRecurringTemplateRequest defines a template. With amount_mode FORMULA
+// the formula's result replaces the voucher or expense amount; journal lines
+// are scaled to it. Formulas may use amount (the saved amount), occurrence,
+// days_in_month and days_in_period, + - * / and round, min, max, abs.
+type CreateRecurringTemplateRequest struct {
+	Name          string                `json:"name" validate:"required,max=150"`
+	DocumentType  string                `json:"document_type" validate:"required,oneof=VOUCHER EXPENSE"`
+	VoucherType   *string               `json:"voucher_type" validate:"omitempty,oneof=payment receipt journal"`
+	LocationID    *int                  `json:"location_id"`
+	Frequency     string                `json:"frequency" validate:"required,oneof=DAILY WEEKLY MONTHLY QUARTERLY YEARLY"`
+	IntervalCount int                   `json:"interval_count" validate:"omitempty,min=1,max=365"`
+	StartDate     string                `json:"start_date" validate:"required"`
+	EndDate       *string               `json:"end_date"`
+	AmountMode    string                `json:"amount_mode" validate:"omitempty,oneof=FIXED FORMULA"`
+	AmountFormula *string               `json:"amount_formula" validate:"omitempty,max=500"`
+	AutoReverse   bool                  `json:"auto_reverse"`
+	Voucher       *CreateVoucherRequest `json:"voucher"`
+	Expense       *CreateExpenseRequest `json:"expense"`
+	IsActive      *bool                 `json:"is_active"`
+}
+
+// UpdateRecurringTemplateRequest edits a template. Changing the schedule
+// restarts it from the first occurrence on or after today.
+type UpdateRecurringTemplateRequest struct {
+	Name          *string               `json:"name" validate:"omitempty,max=150"`
+	Frequency     *string               `json:"frequency" validate:"omitempty,oneof=DAILY WEEKLY MONTHLY QUARTERLY YEARLY"`
+	IntervalCount *int                  `json:"interval_count" validate:"omitempty,min=1,max=365"`
+	StartDate     *string               `json:"start_date"`
+	EndDate       *string               `json:"end_date"`
+	AmountMode    *string               `json:"amount_mode" validate:"omitempty,oneof=FIXED FORMULA"`
+	AmountFormula *string               `json:"amount_formula" validate:"omitempty,max=500"`
+	AutoReverse   *bool                 `json:"auto_reverse"`
+	Voucher       *CreateVoucherRequest `json:"voucher"`
+	Expense       *CreateExpenseRequest `json:"expense"`
+	IsActive      *bool                 `json:"is_active"`
+}
+
+// RecurringTemplateRun is one occurrence in a template's run log.
+type RecurringTemplateRun struct {
+	RunID             int        `json:"run_id" db:"run_id"`
+	TemplateID        int        `json:"template_id" db:"template_id"`
+	OccurrenceDate    time.Time  `json:"occurrence_date" db:"occurrence_date"`
+	OccurrenceNo      int        `json:"occurrence_no" db:"occurrence_no"`
+	Status            string     `json:"status" db:"status"`
+	Amount            *float64   `json:"amount,omitempty" db:"amount"`
+	DocumentID        *int       `json:"document_id,omitempty" db:"document_id"`
+	Message           *string    `json:"message,omitempty" db:"message"`
+	ReversalDate      *time.Time `json:"reversal_date,omitempty" db:"reversal_date"`
+	ReversalStatus    *string    `json:"reversal_status,omitempty" db:"reversal_status"`
+	ReversalVoucherID *int       `json:"reversal_voucher_id,omitempty" db:"reversal_voucher_id"`
+	ReversalMessage   *string    `json:"reversal_message,omitempty" db:"reversal_message"`
+	StartedAt         time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+type RecurringTemplateRunFilter struct {
+	Status string
+	Limit  int
+}
+
+// RecurringOccurrencePreview is an upcoming occurrence with its amount.
+type RecurringOccurrencePreview struct {
+	OccurrenceDate string  `json:"occurrence_date"`
+	Amount         float64 `json:"amount"`
+	ReversalDate   *string `json:"reversal_date,omitempty"`
+}
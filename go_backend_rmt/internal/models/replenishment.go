@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+type ReplenishmentRule struct {
+	RuleID              int       `json:"rule_id" db:"rule_id"`
+	CompanyID           int       `json:"company_id" db:"company_id"`
+	LocationID          int       `json:"location_id" db:"location_id"`
+	ProductID           int       `json:"product_id" db:"product_id"`
+	ProductName         string    `json:"product_name,omitempty" db:"product_name"`
+	PreferredSupplierID *int      `json:"preferred_supplier_id,omitempty" db:"preferred_supplier_id"`
+	ReorderLevel        *float64  `json:"reorder_level,omitempty" db:"reorder_level"`
+	MaxStock            *float64  `json:"max_stock,omitempty" db:"max_stock"`
+	LeadTimeDays        *int      `json:"lead_time_days,omitempty" db:"lead_time_days"`
+	IsActive            bool      `json:"is_active" db:"is_active"`
+	CreatedBy           int       `json:"created_by" db:"created_by"`
+	UpdatedBy           *int      `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpsertReplenishmentRuleRequest struct {
+	LocationID          int      `json:"location_id" validate:"required"`
+	ProductID           int      `json:"product_id" validate:"required"`
+	PreferredSupplierID *int     `json:"preferred_supplier_id,omitempty"`
+	ReorderLevel        *float64 `json:"reorder_level,omitempty" validate:"omitempty,gte=0"`
+	MaxStock            *float64 `json:"max_stock,omitempty" validate:"omitempty,gte=0"`
+	LeadTimeDays        *int     `json:"lead_time_days,omitempty" validate:"omitempty,gte=0"`
+	IsActive            *bool    `json:"is_active,omitempty"`
+}
+
+type ReplenishmentSuggestion struct {
+	ProductID         int      `json:"product_id"`
+	ProductName       string   `json:"product_name"`
+	SKU               *string  `json:"sku,omitempty"`
+	LocationID        int      `json:"location_id"`
+	SupplierID        *int     `json:"supplier_id,omitempty"`
+	OnHand            float64  `json:"on_hand"`
+	InTransit         float64  `json:"in_transit"`
+	PendingPurchase   float64  `json:"pending_purchase"`
+	ReorderLevel      float64  `json:"reorder_level"`
+	MaxStock          *float64 `json:"max_stock,omitempty"`
+	AverageDailySales float64  `json:"average_daily_sales"`
+	LeadTimeDays      int      `json:"lead_time_days"`
+	ReorderPoint      float64  `json:"reorder_point"`
+	TargetStock       float64  `json:"target_stock"`
+	TransferQuantity  float64  `json:"transfer_quantity"`
+	SuggestedQuantity float64  `json:"suggested_quantity"`
+	UnitCost          float64  `json:"unit_cost"`
+	EstimatedCost     float64  `json:"estimated_cost"`
+}
+
+type ReplenishmentSupplierGroup struct {
+	SupplierID     int                       `json:"supplier_id"`
+	SupplierName   string                    `json:"supplier_name"`
+	LeadTimeDays   int                       `json:"lead_time_days"`
+	EstimatedTotal float64                   `json:"estimated_total"`
+	Items          []ReplenishmentSuggestion `json:"items"`
+}
+
+type ReplenishmentTransferSuggestion struct {
+	ProductID         int     `json:"product_id"`
+	ProductName       string  `json:"product_name"`
+	FromLocationID    int     `json:"from_location_id"`
+	FromLocationName  string  `json:"from_location_name"`
+	ToLocationID      int     `json:"to_location_id"`
+	AvailableSurplus  float64 `json:"available_surplus"`
+	SuggestedQuantity float64 `json:"suggested_quantity"`
+}
+
+type ReplenishmentPlan struct {
+	LocationID  int                               `json:"location_id"`
+	WindowDays  int                               `json:"window_days"`
+	GeneratedAt time.Time                         `json:"generated_at"`
+	Suppliers   []ReplenishmentSupplierGroup      `json:"suppliers"`
+	Unassigned  []ReplenishmentSuggestion         `json:"unassigned"`
+	Transfers   []ReplenishmentTransferSuggestion `json:"transfers"`
+}
+
+type CreateReplenishmentDraftsRequest struct {
+	LocationID  *int  `json:"location_id,omitempty"`
+	WindowDays  *int  `json:"window_days,omitempty" validate:"omitempty,min=1,max=365"`
+	SupplierIDs []int `json:"supplier_ids,omitempty"`
+	ProductIDs  []int `json:"product_ids,omitempty"`
+}
+
+type ReplenishmentDraftSkip struct {
+	SupplierID *int   `json:"supplier_id,omitempty"`
+	LocationID *int   `json:"location_id,omitempty"`
+	Reason     string `json:"reason"`
+}
+
+type ReplenishmentPurchaseDraftResult struct {
+	Purchases []Purchase               `json:"purchases"`
+	Skipped   []ReplenishmentDraftSkip `json:"skipped,omitempty"`
+}
+
+type ReplenishmentTransferDraftResult struct {
+	Transfers []StockTransfer          `json:"transfers"`
+	Skipped   []ReplenishmentDraftSkip `json:"skipped,omitempty"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Report job lifecycle.
+const (
+	ReportJobQueued    = "QUEUED"
+	ReportJobRunning   = "RUNNING"
+	ReportJobSucceeded = "SUCCEEDED"
+	ReportJobFailed    = "FAILED"
+	ReportJobExpired   = "EXPIRED"
+)
+
+// ReportJobParams is the union of filters accepted by background reports.
+// Fields a report does not use are dropped before hashing.
+type ReportJobParams struct {
+	FromDate      string `json:"from_date,omitempty"`
+	ToDate        string `json:"to_date,omitempty"`
+	AsOfDate      string `json:"as_of_date,omitempty"`
+	LocationID    *int   `json:"location_id,omitempty"`
+	Limit         int    `json:"limit,omitempty"`
+	CostingMethod string `json:"costing_method,omitempty"`
+	// LocationIDs is the submitter's location access, fixed when the job is
+	// queued so workers and cached results respect it.
+	LocationIDs []int `json:"location_ids,omitempty"`
+	// Analytic dimension filter and grouping for ledger reports.
+	DimensionValueIDs []int `json:"dimension_value_ids,omitempty"`
+	GroupByDimension  int   `json:"group_by_dimension,omitempty"`
+}
+
+type CreateReportJobRequest struct {
+	ReportType string          `json:"report_type" validate:"required,oneof=general-ledger trial-balance balance-sheet item-movement valuation"`
+	Params     ReportJobParams `json:"params"`
+}
+
+type ReportJob struct {
+	JobID       int             `json:"job_id" db:"job_id"`
+	CompanyID   int             `json:"company_id" db:"company_id"`
+	RequestedBy int             `json:"requested_by" db:"requested_by"`
+	ReportType  string          `json:"report_type" db:"report_type"`
+	Params      ReportJobParams `json:"params" db:"params"`
+	DataVersion string          `json:"data_version" db:"data_version"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	RowCount    *int            `json:"row_count,omitempty" db:"row_count"`
+	Error       *string         `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty" db:"expires_at"`
+	// CacheHit is set when a submission was answered by an existing job.
+	CacheHit  bool                `json:"cache_hit,omitempty"`
+	Downloads *ReportJobDownloads `json:"downloads,omitempty"`
+}
+
+// ReportJobDownloads holds short-lived signed URLs for a finished job.
+type ReportJobDownloads struct {
+	JSON      string    `json:"json"`
+	Excel     string    `json:"excel"`
+	PDF       string    `json:"pdf"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ReportJobFilter struct {
+	Status     string `form:"status" validate:"omitempty,oneof=QUEUED RUNNING SUCCEEDED FAILED EXPIRED"`
+	ReportType string `form:"report_type"`
+	Limit      int    `form:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+func (p ReportJobParams) LedgerDimensionFilter() LedgerDimensionFilter {
+	return LedgerDimensionFilter{ValueIDs: p.DimensionValueIDs, GroupByDimensionID: p.GroupByDimension}
+}
@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Report subscription delivery outcomes.
+const (
+	ReportDeliveryRunning   = "RUNNING"
+	ReportDeliverySucceeded = "SUCCEEDED"
+	ReportDeliveryFailed    = "FAILED"
+	ReportDeliverySkipped   = "SKIPPED"
+)
+
+// ReportSubscriptionParams are the saved query parameters of a report
+// endpoint. DateRange, when set, is resolved in the subscription's timezone at
+// send time and replaces FromDate/ToDate (and AsOfDate for point-in-time
+// reports).
+type ReportSubscriptionParams struct {
+	DateRange     string `json:"date_range,omitempty" validate:"omitempty,oneof=today yesterday last_7_days last_30_days month_to_date last_month year_to_date"`
+	FromDate      string `json:"from_date,omitempty"`
+	ToDate        string `json:"to_date,omitempty"`
+	AsOfDate      string `json:"as_of_date,omitempty"`
+	GroupBy       string `json:"group_by,omitempty"`
+	LocationID    *int   `json:"location_id,omitempty"`
+	ProductID     *int   `json:"product_id,omitempty"`
+	BankAccountID *int   `json:"bank_account_id,omitempty"`
+	Status        string `json:"status,omitempty"`
+	Limit         int    `json:"limit,omitempty" validate:"omitempty,min=1,max=1000"`
+	// Analytic dimension filter and grouping for ledger reports.
+	DimensionValueIDs []int `json:"dimension_value_ids,omitempty"`
+	GroupByDimension  int   `json:"group_by_dimension,omitempty"`
+	// LocationIDs is resolved from the subscriber's location access at each
+	// delivery and never stored.
+	LocationIDs []int `json:"-"`
+}
+
+type ReportSubscription struct {
+	SubscriptionID int                      `json:"subscription_id" db:"subscription_id"`
+	CompanyID      int                      `json:"company_id" db:"company_id"`
+	UserID         int                      `json:"user_id" db:"user_id"`
+	Name           string                   `json:"name" db:"name"`
+	Endpoint       string                   `json:"endpoint" db:"endpoint"`
+	Params         ReportSubscriptionParams `json:"params" db:"params"`
+	Schedule       string                   `json:"schedule" db:"schedule"`
+	Timezone       string                   `json:"timezone" db:"timezone"`
+	Format         string                   `json:"format" db:"format"`
+	Recipients     []string                 `json:"recipients" db:"recipients"`
+	IsActive       bool                     `json:"is_active" db:"is_active"`
+	NextRunAt      *time.Time               `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastRunAt      *time.Time               `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastStatus     *string                  `json:"last_status,omitempty" db:"last_status"`
+	CreatedAt      time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+type CreateReportSubscriptionRequest struct {
+	Name       string                   `json:"name" validate:"required,max=150"`
+	Endpoint   string                   `json:"endpoint" validate:"required"`
+	Params     ReportSubscriptionParams `json:"params"`
+	Schedule   string                   `json:"schedule" validate:"required,max=100"`
+	Timezone   string                   `json:"timezone" validate:"omitempty,max=64"`
+	Format     string                   `json:"format" validate:"required,oneof=xlsx pdf csv"`
+	Recipients []string                 `json:"recipients" validate:"required,min=1,max=20,dive,email"`
+	IsActive   *bool                    `json:"is_active"`
+}
+
+type UpdateReportSubscriptionRequest struct {
+	Name       *string                   `json:"name" validate:"omitempty,max=150"`
+	Endpoint   *string                   `json:"endpoint"`
+	Params     *ReportSubscriptionParams `json:"params"`
+	Schedule   *string                   `json:"schedule" validate:"omitempty,max=100"`
+	Timezone   *string                   `json:"timezone" validate:"omitempty,max=64"`
+	Format     *string                   `json:"format" validate:"omitempty,oneof=xlsx pdf csv"`
+	Recipients []string                  `json:"recipients" validate:"omitempty,max=20,dive,email"`
+	IsActive   *bool                     `json:"is_active"`
+}
+
+type ReportSubscriptionDelivery struct {
+	DeliveryID       int        `json:"delivery_id" db:"delivery_id"`
+	SubscriptionID   int        `json:"subscription_id" db:"subscription_id"`
+	ScheduledFor     time.Time  `json:"scheduled_for" db:"scheduled_for"`
+	Status           string     `json:"status" db:"status"`
+	Recipients       []string   `json:"recipients" db:"recipients"`
+	FailedRecipients []string   `json:"failed_recipients,omitempty" db:"failed_recipients"`
+	RowCount         *int       `json:"row_count,omitempty" db:"row_count"`
+	FileName         *string    `json:"file_name,omitempty" db:"file_name"`
+	FileSize         *int       `json:"file_size,omitempty" db:"file_size"`
+	Error            *string    `json:"error,omitempty" db:"error"`
+	StartedAt        time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+type ReportSubscriptionDeliveryFilter struct {
+	Status string `form:"status" validate:"omitempty,oneof=RUNNING SUCCEEDED FAILED SKIPPED"`
+	Limit  int    `form:"limit" validate:"omitempty,min=1,max=200"`
+}
+
+func (p ReportSubscriptionParams) LedgerDimensionFilter() LedgerDimensionFilter {
+	return LedgerDimensionFilter{ValueIDs: p.DimensionValueIDs, GroupByDimensionID: p.GroupByDimension}
+}
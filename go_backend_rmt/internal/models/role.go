@@ -1,10 +1,14 @@
 package models
 
+// Role belongs to a company. Roles with no company are templates that are
+// copied into each new company (TemplateRoleID points back at the template).
 type Role struct {
-	RoleID       int    `json:"role_id" db:"role_id"`
-	Name         string `json:"name" db:"name" validate:"required,min=2,max=100"`
-	Description  string `json:"description" db:"description"`
-	IsSystemRole bool   `json:"is_system_role" db:"is_system_role"`
+	RoleID         int    `json:"role_id" db:"role_id"`
+	CompanyID      *int   `json:"company_id,omitempty" db:"company_id"`
+	TemplateRoleID *int   `json:"template_role_id,omitempty" db:"template_role_id"`
+	Name           string `json:"name" db:"name" validate:"required,min=2,max=100"`
+	Description    string `json:"description" db:"description"`
+	IsSystemRole   bool   `json:"is_system_role" db:"is_system_role"`
 	BaseModel
 }
 
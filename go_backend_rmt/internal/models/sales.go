@@ -244,6 +244,7 @@ type POSPrintDataResponse struct {
 	Sale          Sale           `json:"sale"`
 	Company       Company        `json:"company"`
 	RaffleCoupons []RaffleCoupon `json:"raffle_coupons,omitempty"`
+	EInvoice      *EInvoice      `json:"einvoice,omitempty"`
 }
 
 type POSProductResponse struct {
@@ -267,6 +268,7 @@ type POSProductResponse struct {
 	SellingUnitSymbol     *string `json:"selling_unit_symbol,omitempty"`
 	IsLoyaltyGift         bool    `json:"is_loyalty_gift"`
 	LoyaltyPointsRequired float64 `json:"loyalty_points_required"`
+	HasVariantMatrix      bool    `json:"has_variant_matrix"`
 }
 
 // POSPaymentLine represents an individual payment used in POS checkout, which
@@ -0,0 +1,161 @@
+package models
+
+import "time"
+
+const (
+	SalesOrderStatusOpen               = "OPEN"
+	SalesOrderStatusPartiallyDelivered = "PARTIALLY_DELIVERED"
+	SalesOrderStatusDelivered          = "DELIVERED"
+	SalesOrderStatusClosed             = "CLOSED"
+	SalesOrderStatusCancelled          = "CANCELLED"
+
+	SalesOrderInvoicingPerDelivery  = "PER_DELIVERY"
+	SalesOrderInvoicingConsolidated = "CONSOLIDATED"
+)
+
+type SalesOrderLine struct {
+	SalesOrderLineID    int     `json:"sales_order_line_id"`
+	SalesOrderID        int     `json:"sales_order_id"`
+	ProductID           int     `json:"product_id"`
+	BarcodeID           int     `json:"barcode_id"`
+	ProductName         *string `json:"product_name,omitempty"`
+	Quantity            float64 `json:"quantity"`
+	UnitPrice           float64 `json:"unit_price"`
+	DiscountPercent     float64 `json:"discount_percentage"`
+	TaxID               *int    `json:"tax_id,omitempty"`
+	ReservedQuantity    float64 `json:"reserved_quantity"`
+	DeliveredQuantity   float64 `json:"delivered_quantity"`
+	InvoicedQuantity    float64 `json:"invoiced_quantity"`
+	CancelledQuantity   float64 `json:"cancelled_quantity"`
+	BackorderedQuantity float64 `json:"backordered_quantity"`
+	Notes               *string `json:"notes,omitempty"`
+}
+
+type SalesOrder struct {
+	SalesOrderID         int                  `json:"sales_order_id"`
+	OrderNumber          string               `json:"order_number"`
+	LocationID           int                  `json:"location_id"`
+	CustomerID           int                  `json:"customer_id"`
+	CustomerName         string               `json:"customer_name,omitempty"`
+	QuoteID              *int                 `json:"quote_id,omitempty"`
+	OrderDate            time.Time            `json:"order_date"`
+	ExpectedDeliveryDate *time.Time           `json:"expected_delivery_date,omitempty"`
+	Status               string               `json:"status"`
+	InvoicingMode        string               `json:"invoicing_mode"`
+	TransactionType      string               `json:"transaction_type"`
+	Notes                *string              `json:"notes,omitempty"`
+	NetAmount            float64              `json:"net_amount"`
+	CreatedBy            int                  `json:"created_by"`
+	CreatedAt            time.Time            `json:"created_at"`
+	UpdatedAt            time.Time            `json:"updated_at"`
+	Lines                []SalesOrderLine     `json:"lines,omitempty"`
+	Deliveries           []SalesOrderDelivery `json:"deliveries,omitempty"`
+}
+
+type SalesOrderLineInput struct {
+	ProductID       int     `json:"product_id" validate:"required"`
+	BarcodeID       *int    `json:"barcode_id,omitempty"`
+	ProductName     *string `json:"product_name,omitempty"`
+	Quantity        float64 `json:"quantity" validate:"required,gt=0"`
+	UnitPrice       float64 `json:"unit_price" validate:"gte=0"`
+	DiscountPercent float64 `json:"discount_percentage" validate:"gte=0,lte=100"`
+	TaxID           *int    `json:"tax_id,omitempty"`
+	Notes           *string `json:"notes,omitempty"`
+}
+
+type CreateSalesOrderRequest struct {
+	CustomerID           int                   `json:"customer_id" validate:"required"`
+	ExpectedDeliveryDate *string               `json:"expected_delivery_date,omitempty"`
+	InvoicingMode        *string               `json:"invoicing_mode,omitempty" validate:"omitempty,oneof=PER_DELIVERY CONSOLIDATED"`
+	TransactionType      *string               `json:"transaction_type,omitempty"`
+	Notes                *string               `json:"notes,omitempty"`
+	Lines                []SalesOrderLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+type ConvertQuoteToSalesOrderRequest struct {
+	ExpectedDeliveryDate *string `json:"expected_delivery_date,omitempty"`
+	InvoicingMode        *string `json:"invoicing_mode,omitempty" validate:"omitempty,oneof=PER_DELIVERY CONSOLIDATED"`
+}
+
+type SalesOrderDeliveryLine struct {
+	DeliveryLineID   int      `json:"delivery_line_id"`
+	DeliveryID       int      `json:"delivery_id"`
+	SalesOrderLineID int      `json:"sales_order_line_id"`
+	ProductID        int      `json:"product_id"`
+	BarcodeID        int      `json:"barcode_id"`
+	ProductName      *string  `json:"product_name,omitempty"`
+	Quantity         float64  `json:"quantity"`
+	SerialNumbers    []string `json:"serial_numbers,omitempty"`
+	UnitCost         float64  `json:"unit_cost"`
+	TotalCost        float64  `json:"total_cost"`
+}
+
+type SalesOrderDelivery struct {
+	DeliveryID     int                      `json:"delivery_id"`
+	DeliveryNumber string                   `json:"delivery_number"`
+	SalesOrderID   int                      `json:"sales_order_id"`
+	LocationID     int                      `json:"location_id"`
+	DeliveryDate   time.Time                `json:"delivery_date"`
+	InvoiceSaleID  *int                     `json:"invoice_sale_id,omitempty"`
+	Notes          *string                  `json:"notes,omitempty"`
+	CreatedBy      int                      `json:"created_by"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Lines          []SalesOrderDeliveryLine `json:"lines,omitempty"`
+}
+
+type SalesOrderDeliveryLineInput struct {
+	SalesOrderLineID int                            `json:"sales_order_line_id" validate:"required"`
+	Quantity         float64                        `json:"quantity" validate:"required,gt=0"`
+	SerialNumbers    []string                       `json:"serial_numbers,omitempty"`
+	BatchAllocations []InventoryBatchSelectionInput `json:"batch_allocations,omitempty"`
+}
+
+// CreateSalesOrderDeliveryRequest issues stock for part of an order. Invoice
+// overrides the order's invoicing mode for this delivery; per-delivery orders
+// are invoiced immediately by default.
+type CreateSalesOrderDeliveryRequest struct {
+	DeliveryDate     *string                       `json:"delivery_date,omitempty"`
+	Notes            *string                       `json:"notes,omitempty"`
+	Invoice          *bool                         `json:"invoice,omitempty"`
+	OverridePassword *string                       `json:"override_password,omitempty"`
+	Lines            []SalesOrderDeliveryLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+// InvoiceSalesOrderRequest invoices delivered but uninvoiced deliveries. An
+// empty DeliveryIDs selects all of them; Consolidate defaults to the order's
+// invoicing mode.
+type InvoiceSalesOrderRequest struct {
+	DeliveryIDs      []int   `json:"delivery_ids,omitempty"`
+	Consolidate      *bool   `json:"consolidate,omitempty"`
+	OverridePassword *string `json:"override_password,omitempty"`
+}
+
+type SalesOrderInvoiceResult struct {
+	SalesOrderID int    `json:"sales_order_id"`
+	Invoices     []Sale `json:"invoices"`
+}
+
+// BackorderAllocation records stock reserved for a backordered line when
+// goods arrive.
+type BackorderAllocation struct {
+	SalesOrderID     int     `json:"sales_order_id"`
+	SalesOrderLineID int     `json:"sales_order_line_id"`
+	BarcodeID        int     `json:"barcode_id"`
+	Quantity         float64 `json:"quantity"`
+}
+
+type SalesOrderBackorder struct {
+	SalesOrderID         int        `json:"sales_order_id"`
+	OrderNumber          string     `json:"order_number"`
+	OrderDate            time.Time  `json:"order_date"`
+	ExpectedDeliveryDate *time.Time `json:"expected_delivery_date,omitempty"`
+	CustomerID           int        `json:"customer_id"`
+	CustomerName         string     `json:"customer_name"`
+	LocationID           int        `json:"location_id"`
+	SalesOrderLineID     int        `json:"sales_order_line_id"`
+	ProductID            int        `json:"product_id"`
+	BarcodeID            int        `json:"barcode_id"`
+	ProductName          *string    `json:"product_name,omitempty"`
+	Quantity             float64    `json:"quantity"`
+	BackorderedQuantity  float64    `json:"backordered_quantity"`
+}
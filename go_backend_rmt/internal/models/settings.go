@@ -37,12 +37,20 @@ type InventorySettings struct {
 	NegativeStockPolicy              string `json:"negative_stock_policy,omitempty"`
 	NegativeProfitPolicy             string `json:"negative_profit_policy,omitempty"`
 	HasNegativeStockApprovalPassword bool   `json:"has_negative_stock_approval_password"`
+	BatchIssuePolicy                 string `json:"batch_issue_policy"`
+	BlockExpiredSales                bool   `json:"block_expired_sales"`
+	ExpiryAlertDays                  int    `json:"expiry_alert_days"`
 }
 
+// UpdateInventorySettingsRequest changes the inventory controls. The batch
+// expiry fields are optional and keep their current value when omitted.
 type UpdateInventorySettingsRequest struct {
 	NegativeStockPolicy           string  `json:"negative_stock_policy"`
 	NegativeProfitPolicy          string  `json:"negative_profit_policy"`
 	NegativeStockApprovalPassword *string `json:"negative_stock_approval_password,omitempty"`
+	BatchIssuePolicy              *string `json:"batch_issue_policy,omitempty"`
+	BlockExpiredSales             *bool   `json:"block_expired_sales,omitempty"`
+	ExpiryAlertDays               *int    `json:"expiry_alert_days,omitempty"`
 }
 
 // InvoiceSettings holds invoice-related configuration
@@ -57,6 +65,9 @@ type TaxSettings struct {
 	TaxName    *string  `json:"tax_name,omitempty"`
 	TaxPercent *float64 `json:"tax_percent,omitempty"`
 	PriceMode  string   `json:"price_mode,omitempty"`
+	// EInvoicingEnabled issues signed, hash-chained e-invoices for finalized
+	// sales, refund invoices and sale returns.
+	EInvoicingEnabled bool `json:"einvoicing_enabled,omitempty"`
 }
 
 // DeviceControlSettings holds device control related configuration
@@ -64,6 +75,33 @@ type DeviceControlSettings struct {
 	AllowRemote bool `json:"allow_remote"`
 }
 
+// ThreeWayMatchSettings controls supplier invoice matching tolerances and
+// whether unmatched invoices block supplier payments.
+type ThreeWayMatchSettings struct {
+	QuantityTolerancePercent  float64 `json:"quantity_tolerance_percent" validate:"gte=0,lte=100"`
+	PriceTolerancePercent     float64 `json:"price_tolerance_percent" validate:"gte=0,lte=100"`
+	RequireMatchBeforePayment bool    `json:"require_match_before_payment"`
+}
+
+// DunningLevel is one step of the customer reminder ladder, reached once an
+// invoice is at least DaysOverdue days past due.
+type DunningLevel struct {
+	Level          int     `json:"level" validate:"required,gt=0"`
+	Name           string  `json:"name" validate:"required,max=100"`
+	DaysOverdue    int     `json:"days_overdue" validate:"gte=1"`
+	LateFeeAmount  float64 `json:"late_fee_amount" validate:"gte=0"`
+	LateFeePercent float64 `json:"late_fee_percent" validate:"gte=0,lte=100"`
+	BlockCredit    bool    `json:"block_credit"`
+	EmailSubject   string  `json:"email_subject,omitempty"`
+	EmailMessage   string  `json:"email_message,omitempty"`
+}
+
+// DunningSettings configures reminder levels for overdue customer invoices.
+type DunningSettings struct {
+	Enabled bool           `json:"enabled"`
+	Levels  []DunningLevel `json:"levels" validate:"dive"`
+}
+
 // SecurityPolicySettings holds password and session hardening policy for a company.
 type SecurityPolicySettings struct {
 	MinPasswordLength        int  `json:"min_password_length"`
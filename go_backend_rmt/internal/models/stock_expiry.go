@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+const StockExpiryBucketExpired = "EXPIRED"
+
+// StockExpiryReport lists lots with stock left that have expired or expire
+// within the largest requested horizon, bucketed by horizon.
+type StockExpiryReport struct {
+	AsOf     string              `json:"as_of"`
+	Horizons []int               `json:"horizons"`
+	Buckets  []StockExpiryBucket `json:"buckets"`
+	Lots     []StockExpiryLot    `json:"lots"`
+}
+
+type StockExpiryBucket struct {
+	Bucket   string  `json:"bucket"`
+	MaxDays  *int    `json:"max_days,omitempty"`
+	LotCount int     `json:"lot_count"`
+	Quantity float64 `json:"quantity"`
+	Value    float64 `json:"value"`
+}
+
+type StockExpiryLot struct {
+	LotID             int       `json:"lot_id"`
+	LocationID        int       `json:"location_id"`
+	LocationName      string    `json:"location_name"`
+	ProductID         int       `json:"product_id"`
+	ProductName       string    `json:"product_name"`
+	BarcodeID         *int      `json:"barcode_id,omitempty"`
+	Barcode           *string   `json:"barcode,omitempty"`
+	BatchNumber       *string   `json:"batch_number,omitempty"`
+	ExpiryDate        time.Time `json:"expiry_date"`
+	DaysToExpiry      int       `json:"days_to_expiry"`
+	RemainingQuantity float64   `json:"remaining_quantity"`
+	UnitCost          float64   `json:"unit_cost"`
+	Value             float64   `json:"value"`
+	Bucket            string    `json:"bucket"`
+}
+
+// QuarantineExpiredStockRequest takes expired lots out of sellable stock.
+// Without lot IDs every expired lot at the location is quarantined.
+type QuarantineExpiredStockRequest struct {
+	LotIDs []int   `json:"lot_ids,omitempty" validate:"omitempty,dive,gt=0"`
+	Reason *string `json:"reason,omitempty"`
+}
+
+// StockExpiryWriteOff records quarantined lots and the loss posted for them.
+type StockExpiryWriteOff struct {
+	WriteOffID    int                       `json:"write_off_id" db:"write_off_id"`
+	CompanyID     int                       `json:"company_id" db:"company_id"`
+	LocationID    int                       `json:"location_id" db:"location_id"`
+	LocationName  string                    `json:"location_name,omitempty"`
+	Reason        *string                   `json:"reason,omitempty" db:"reason"`
+	TotalQuantity float64                   `json:"total_quantity" db:"total_quantity"`
+	TotalCost     float64                   `json:"total_cost" db:"total_cost"`
+	CreatedBy     int                       `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time                 `json:"created_at" db:"created_at"`
+	Lines         []StockExpiryWriteOffLine `json:"lines,omitempty"`
+}
+
+type StockExpiryWriteOffLine struct {
+	WriteOffLineID int        `json:"write_off_line_id" db:"write_off_line_id"`
+	WriteOffID     int        `json:"write_off_id" db:"write_off_id"`
+	LotID          int        `json:"lot_id" db:"lot_id"`
+	ProductID      int        `json:"product_id" db:"product_id"`
+	ProductName    string     `json:"product_name,omitempty"`
+	BarcodeID      *int       `json:"barcode_id,omitempty" db:"barcode_id"`
+	BatchNumber    *string    `json:"batch_number,omitempty" db:"batch_number"`
+	ExpiryDate     *time.Time `json:"expiry_date,omitempty" db:"expiry_date"`
+	Quantity       float64    `json:"quantity" db:"quantity"`
+	UnitCost       float64    `json:"unit_cost" db:"unit_cost"`
+	TotalCost      float64    `json:"total_cost" db:"total_cost"`
+	SerialNumbers  []string   `json:"serial_numbers,omitempty" db:"serial_numbers"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+const (
+	TransferDiscrepancyStatusPending  = "PENDING"
+	TransferDiscrepancyStatusApproved = "APPROVED"
+	TransferDiscrepancyStatusRejected = "REJECTED"
+)
+
+// ReceiveStockTransferRequest records what arrived at the destination. Lines
+// that are not listed are received in full.
+type ReceiveStockTransferRequest struct {
+	Items []ReceiveStockTransferItem `json:"items,omitempty" validate:"omitempty,dive"`
+	Notes *string                    `json:"notes,omitempty"`
+}
+
+// ReceiveStockTransferItem splits a shipped line into received, short and
+// damaged quantities, which must add up to the shipped quantity. Serialized
+// lines name the received and damaged serials; the rest are short. Batch
+// lines may name the lots received in good condition.
+type ReceiveStockTransferItem struct {
+	TransferDetailID      int                            `json:"transfer_detail_id" validate:"required"`
+	ReceivedQuantity      float64                        `json:"received_quantity" validate:"gte=0"`
+	ShortQuantity         float64                        `json:"short_quantity" validate:"gte=0"`
+	DamagedQuantity       float64                        `json:"damaged_quantity" validate:"gte=0"`
+	ReceivedSerialNumbers []string                       `json:"received_serial_numbers,omitempty"`
+	DamagedSerialNumbers  []string                       `json:"damaged_serial_numbers,omitempty"`
+	ReceivedBatches       []InventoryBatchSelectionInput `json:"received_batches,omitempty" validate:"omitempty,dive"`
+	Reason                *string                        `json:"reason,omitempty"`
+}
+
+// StockTransferReceipt is the outcome of receiving a transfer.
+type StockTransferReceipt struct {
+	TransferID    int                       `json:"transfer_id"`
+	Status        string                    `json:"status"`
+	ReceivedValue float64                   `json:"received_value"`
+	Discrepancy   *StockTransferDiscrepancy `json:"discrepancy,omitempty"`
+}
+
+// StockTransferDiscrepancy documents the short and damaged goods of a
+// transfer receipt while it waits for approval.
+type StockTransferDiscrepancy struct {
+	DiscrepancyID      int                            `json:"discrepancy_id" db:"discrepancy_id"`
+	CompanyID          int                            `json:"company_id" db:"company_id"`
+	TransferID         int                            `json:"transfer_id" db:"transfer_id"`
+	TransferNumber     string                         `json:"transfer_number,omitempty"`
+	FromLocationID     int                            `json:"from_location_id"`
+	ToLocationID       int                            `json:"to_location_id"`
+	Status             string                         `json:"status" db:"status"`
+	ShortValue         float64                        `json:"short_value" db:"short_value"`
+	DamagedValue       float64                        `json:"damaged_value" db:"damaged_value"`
+	ShortageLocationID *int                           `json:"shortage_location_id,omitempty" db:"shortage_location_id"`
+	ApprovalID         *int                           `json:"approval_id,omitempty" db:"approval_id"`
+	Notes              *string                        `json:"notes,omitempty" db:"notes"`
+	ResolvedBy         *int                           `json:"resolved_by,omitempty" db:"resolved_by"`
+	ResolvedAt         *time.Time                     `json:"resolved_at,omitempty" db:"resolved_at"`
+	CreatedBy          int                            `json:"created_by" db:"created_by"`
+	CreatedAt          time.Time                      `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time                      `json:"updated_at" db:"updated_at"`
+	Lines              []StockTransferDiscrepancyLine `json:"lines,omitempty"`
+}
+
+type StockTransferDiscrepancyLine struct {
+	DiscrepancyLineID    int      `json:"discrepancy_line_id" db:"discrepancy_line_id"`
+	DiscrepancyID        int      `json:"discrepancy_id" db:"discrepancy_id"`
+	TransferDetailID     int      `json:"transfer_detail_id" db:"transfer_detail_id"`
+	ProductID            int      `json:"product_id" db:"product_id"`
+	ProductName          string   `json:"product_name,omitempty"`
+	ShortQuantity        float64  `json:"short_quantity" db:"short_quantity"`
+	DamagedQuantity      float64  `json:"damaged_quantity" db:"damaged_quantity"`
+	UnitCost             float64  `json:"unit_cost" db:"unit_cost"`
+	ShortValue           float64  `json:"short_value" db:"short_value"`
+	DamagedValue         float64  `json:"damaged_value" db:"damaged_value"`
+	ShortSerialNumbers   []string `json:"short_serial_numbers,omitempty" db:"short_serial_numbers"`
+	DamagedSerialNumbers []string `json:"damaged_serial_numbers,omitempty" db:"damaged_serial_numbers"`
+	Reason               *string  `json:"reason,omitempty" db:"reason"`
+}
@@ -22,6 +22,8 @@ type User struct {
 	IsLocked                bool       `json:"is_locked" db:"is_locked"`
 	IsActive                bool       `json:"is_active" db:"is_active"`
 	LastLogin               *time.Time `json:"last_login,omitempty" db:"last_login"`
+	// LocationIDs is filled from user_locations when a token is issued.
+	LocationIDs []int `json:"location_ids,omitempty" db:"-"`
 	SyncModel
 }
 
@@ -34,6 +36,7 @@ type UserResponse struct {
 	Phone                  *string           `json:"phone,omitempty"`
 	RoleID                 *int              `json:"role_id,omitempty"` // CHANGE: int -> *int
 	LocationID             *int              `json:"location_id,omitempty"`
+	LocationIDs            []int             `json:"location_ids,omitempty"`
 	CompanyID              *int              `json:"company_id,omitempty"` // CHANGE: int -> *int
 	IsActive               bool              `json:"is_active"`
 	IsLocked               bool              `json:"is_locked"`
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// UserLocation lets a user work in a location. RoleID, when set, replaces the
+// user's own role while that location is active.
+type UserLocation struct {
+	UserID       int       `json:"user_id" db:"user_id"`
+	LocationID   int       `json:"location_id" db:"location_id"`
+	LocationName string    `json:"location_name" db:"location_name"`
+	RoleID       *int      `json:"role_id,omitempty" db:"role_id"`
+	RoleName     *string   `json:"role_name,omitempty" db:"role_name"`
+	IsDefault    bool      `json:"is_default" db:"-"`
+	IsActive     bool      `json:"is_active" db:"-"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type UserLocationAssignment struct {
+	LocationID int  `json:"location_id" validate:"required,min=1"`
+	RoleID     *int `json:"role_id,omitempty" validate:"omitempty,min=1"`
+}
+
+// SetUserLocationsRequest replaces a user's locations. DefaultLocationID
+// becomes users.location_id and must be one of Locations; it defaults to the
+// first entry.
+type SetUserLocationsRequest struct {
+	Locations         []UserLocationAssignment `json:"locations" validate:"required,min=1,dive"`
+	DefaultLocationID *int                     `json:"default_location_id,omitempty"`
+}
+
+type SwitchLocationRequest struct {
+	LocationID int `json:"location_id" validate:"required,min=1"`
+}
+
+// SwitchLocationResponse carries a new access token for the session's
+// active location.
+type SwitchLocationResponse struct {
+	AccessToken string         `json:"access_token"`
+	LocationID  int            `json:"location_id"`
+	RoleID      *int           `json:"role_id,omitempty"`
+	LocationIDs []int          `json:"location_ids"`
+	Locations   []UserLocation `json:"locations"`
+	Permissions []string       `json:"permissions"`
+}
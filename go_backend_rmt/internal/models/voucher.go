@@ -27,6 +27,8 @@ type CreateVoucherRequest struct {
 	Description         *string                    `json:"description,omitempty"`
 	Lines               []CreateVoucherLineRequest `json:"lines,omitempty"`
 	IdempotencyKey      *string                    `json:"idempotency_key,omitempty"`
+	// Dimensions tag every line that does not set its own.
+	Dimensions []AnalyticTag `json:"dimensions,omitempty"`
 }
 
 type VoucherLine struct {
@@ -44,8 +46,9 @@ type VoucherLine struct {
 }
 
 type CreateVoucherLineRequest struct {
-	AccountID   int     `json:"account_id" validate:"required"`
-	Debit       float64 `json:"debit"`
-	Credit      float64 `json:"credit"`
-	Description *string `json:"description,omitempty"`
+	AccountID   int           `json:"account_id" validate:"required"`
+	Debit       float64       `json:"debit"`
+	Credit      float64       `json:"credit"`
+	Description *string       `json:"description,omitempty"`
+	Dimensions  []AnalyticTag `json:"dimensions,omitempty"`
 }
@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+// Outbound webhook event types.
+const (
+	WebhookEventSaleCreated            = "sale.created"
+	WebhookEventSaleRefunded           = "sale.refunded"
+	WebhookEventSaleDeleted            = "sale.deleted"
+	WebhookEventSaleReturnCreated      = "sale_return.created"
+	WebhookEventPaymentReceived        = "payment.received"
+	WebhookEventSupplierPaymentCreated = "supplier_payment.created"
+	WebhookEventStockAdjusted          = "stock.adjusted"
+	WebhookEventStockTransferCompleted = "stock_transfer.completed"
+	WebhookEventCustomerCreated        = "customer.created"
+	WebhookEventCustomerUpdated        = "customer.updated"
+	WebhookEventCustomerDeleted        = "customer.deleted"
+)
+
+// WebhookEventTypes lists every event a subscription can filter on.
+var WebhookEventTypes = []string{
+	WebhookEventSaleCreated,
+	WebhookEventSaleRefunded,
+	WebhookEventSaleDeleted,
+	WebhookEventSaleReturnCreated,
+	WebhookEventPaymentReceived,
+	WebhookEventSupplierPaymentCreated,
+	WebhookEventStockAdjusted,
+	WebhookEventStockTransferCompleted,
+	WebhookEventCustomerCreated,
+	WebhookEventCustomerUpdated,
+	WebhookEventCustomerDeleted,
+}
+
+// Webhook delivery statuses. DEAD deliveries ran out of retries and wait in
+// the dead-letter queue for a manual redelivery.
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryDead      = "DEAD"
+)
+
+// WebhookSubscription sends the company's events to one endpoint. An empty
+// EventTypes receives every event. The secret is only returned on create and
+// rotation.
+type WebhookSubscription struct {
+	SubscriptionID          int        `json:"subscription_id" db:"subscription_id"`
+	CompanyID               int        `json:"company_id" db:"company_id"`
+	Name                    string     `json:"name" db:"name"`
+	URL                     string     `json:"url" db:"url"`
+	EventTypes              []string   `json:"event_types" db:"event_types"`
+	IsActive                bool       `json:"is_active" db:"is_active"`
+	Secret                  string     `json:"secret,omitempty" db:"-"`
+	SecretHint              string     `json:"secret_hint" db:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty" db:"previous_secret_expires_at"`
+	CreatedBy               int        `json:"created_by" db:"created_by"`
+	UpdatedBy               *int       `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt               time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type CreateWebhookSubscriptionRequest struct {
+	Name       string   `json:"name" validate:"required,max=150"`
+	URL        string   `json:"url" validate:"required,url,max=500"`
+	EventTypes []string `json:"event_types"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+type UpdateWebhookSubscriptionRequest struct {
+	Name       *string   `json:"name" validate:"omitempty,max=150"`
+	URL        *string   `json:"url" validate:"omitempty,url,max=500"`
+	EventTypes *[]string `json:"event_types"`
+	IsActive   *bool     `json:"is_active"`
+}
+
+// RotateWebhookSecretRequest sets how long the old secret keeps signing
+// alongside the new one, so receivers can switch without dropping events.
+type RotateWebhookSecretRequest struct {
+	GraceHours *int `json:"grace_hours" validate:"omitempty,min=0,max=168"`
+}
+
+// WebhookDelivery is one event queued for one subscription.
+type WebhookDelivery struct {
+	DeliveryID     int64                    `json:"delivery_id" db:"delivery_id"`
+	EventID        int64                    `json:"event_id" db:"event_id"`
+	SubscriptionID int                      `json:"subscription_id" db:"subscription_id"`
+	EventType      string                   `json:"event_type" db:"event_type"`
+	ResourceType   string                   `json:"resource_type" db:"resource_type"`
+	ResourceID     int                      `json:"resource_id" db:"resource_id"`
+	Status         string                   `json:"status" db:"status"`
+	Attempts       int                      `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time                `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      *string                  `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time                `json:"created_at" db:"created_at"`
+	Payload        JSONB                    `json:"payload,omitempty"`
+	AttemptLog     []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttempt logs one HTTP call of a delivery.
+type WebhookDeliveryAttempt struct {
+	AttemptID    int64     `json:"attempt_id" db:"attempt_id"`
+	StatusCode   *int      `json:"status_code,omitempty" db:"status_code"`
+	Error        *string   `json:"error,omitempty" db:"error"`
+	ResponseBody *string   `json:"response_body,omitempty" db:"response_body"`
+	DurationMs   int       `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+type WebhookDeliveryFilter struct {
+	SubscriptionID *int
+	Status         string
+	EventType      string
+	Limit          int
+}
@@ -7,6 +7,7 @@ import (
 	"erp-backend/internal/database"
 	"erp-backend/internal/handlers"
 	"erp-backend/internal/middleware"
+	"erp-backend/internal/models"
 	"erp-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -38,6 +39,12 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 	returnsHandler := handlers.NewReturnsHandler()
 	purchaseHandler := handlers.NewPurchaseHandler()
 	purchaseOrderHandler := handlers.NewPurchaseOrderHandler()
+	replenishmentHandler := handlers.NewReplenishmentHandler()
+	invoiceMatchHandler := handlers.NewInvoiceMatchHandler()
+	accountsPayableHandler := handlers.NewAccountsPayableHandler()
+	customerStatementHandler := handlers.NewCustomerStatementHandler()
+	dunningHandler := handlers.NewDunningHandler()
+	salesOrderHandler := handlers.NewSalesOrderHandler()
 	goodsReceiptHandler := handlers.NewGoodsReceiptHandler()
 	supplierHandler := handlers.NewSupplierHandler()
 	paymentHandler := handlers.NewPaymentHandler()
@@ -66,12 +73,27 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 	translationHandler := handlers.NewTranslationHandler()
 	numberingSequenceHandler := handlers.NewNumberingSequenceHandler()
 	invoiceTemplateHandler := handlers.NewInvoiceTemplateHandler()
+	documentHandler := handlers.NewDocumentHandler()
+	einvoiceHandler := handlers.NewEInvoiceHandler()
 	currencyHandler := handlers.NewCurrencyHandler()
 	taxHandler := handlers.NewTaxHandler()
 	userPreferencesHandler := handlers.NewUserPreferencesHandler()
 	supportHandler := handlers.NewSupportHandler(cfg)
 	supportIssueHandler := handlers.NewSupportIssueHandler()
+	attachmentHandler := handlers.NewAttachmentHandler()
+	reportJobHandler := handlers.NewReportJobHandler()
+	reportSubscriptionHandler := handlers.NewReportSubscriptionHandler()
+	customReportHandler := handlers.NewCustomReportHandler()
+	budgetHandler := handlers.NewBudgetHandler()
+	analyticDimensionHandler := handlers.NewAnalyticDimensionHandler()
+	recurringTemplateHandler := handlers.NewRecurringTemplateHandler()
 	notificationsHandler := handlers.NewNotificationsHandler()
+	webhookHandler := handlers.NewWebhookHandler()
+	apiKeyHandler := handlers.NewAPIKeyHandler()
+	stockExpiryHandler := handlers.NewStockExpiryHandler()
+	billOfMaterialsHandler := handlers.NewBillOfMaterialsHandler()
+	landedCostHandler := handlers.NewLandedCostHandler()
+	consignmentHandler := handlers.NewConsignmentHandler()
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -133,6 +155,8 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				authProtected.GET("/me", authHandler.GetMe)
 				authProtected.POST("/logout", authHandler.Logout)
 				authProtected.POST("/verify", authHandler.VerifyCredentials)
+				authProtected.GET("/locations", authHandler.GetMyLocations)
+				authProtected.POST("/switch-location", authHandler.SwitchLocation)
 			}
 
 			// Device session routes
@@ -160,6 +184,8 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				users.POST("", middleware.RequirePermission("CREATE_USERS"), userHandler.CreateUser)
 				users.PUT("/:id", middleware.RequirePermission("UPDATE_USERS"), userHandler.UpdateUser)
 				users.DELETE("/:id", middleware.RequirePermission("DELETE_USERS"), userHandler.DeleteUser)
+				users.GET("/:id/locations", middleware.RequirePermission("VIEW_USERS"), userHandler.GetUserLocations)
+				users.PUT("/:id/locations", middleware.RequirePermission("UPDATE_USERS"), userHandler.SetUserLocations)
 			}
 
 			// Company management routes (admin only)
@@ -193,6 +219,7 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 
 			// Role and permission management routes
 			roles := protected.Group("/roles")
+			roles.Use(middleware.RequireCompanyAccess())                 // Roles belong to the company
 			roles.Use(middleware.RequireAnyRole("Admin", "Super Admin")) // Only admins can manage roles
 			{
 				roles.GET("", roleHandler.GetRoles)
@@ -221,6 +248,9 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				products.POST("", middleware.RequirePermission("CREATE_PRODUCTS"), productHandler.CreateProduct)
 				products.PUT("/:id", middleware.RequirePermission("UPDATE_PRODUCTS"), productHandler.UpdateProduct)
 				products.PUT("/:id/storage-assignments", middleware.RequirePermission("UPDATE_PRODUCTS"), productHandler.ReplaceStorageAssignments)
+				products.GET("/:id/variant-matrix", middleware.RequirePermission("VIEW_PRODUCTS"), productHandler.GetVariantMatrix)
+				products.PUT("/:id/variant-dimensions", middleware.RequirePermission("UPDATE_PRODUCTS"), productHandler.SetVariantDimensions)
+				products.POST("/:id/variant-matrix/sync", middleware.RequirePermission("UPDATE_PRODUCTS"), productHandler.SyncVariantMatrix)
 				products.DELETE("/:id", middleware.RequirePermission("DELETE_PRODUCTS"), productHandler.DeleteProduct)
 			}
 
@@ -282,6 +312,15 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				inventory.POST("/combo-products", middleware.RequirePermission("CREATE_PRODUCTS"), comboProductHandler.CreateComboProduct)
 				inventory.PUT("/combo-products/:id", middleware.RequirePermission("UPDATE_PRODUCTS"), comboProductHandler.UpdateComboProduct)
 				inventory.DELETE("/combo-products/:id", middleware.RequirePermission("DELETE_PRODUCTS"), comboProductHandler.DeleteComboProduct)
+				inventory.GET("/boms", middleware.RequirePermission("VIEW_PRODUCTS"), billOfMaterialsHandler.GetBOMs)
+				inventory.GET("/boms/:id", middleware.RequirePermission("VIEW_PRODUCTS"), billOfMaterialsHandler.GetBOM)
+				inventory.POST("/boms", middleware.RequirePermission("CREATE_PRODUCTS"), billOfMaterialsHandler.CreateBOM)
+				inventory.POST("/boms/:id/activate", middleware.RequirePermission("UPDATE_PRODUCTS"), billOfMaterialsHandler.ActivateBOM)
+				inventory.GET("/assembly-orders", middleware.RequirePermission("VIEW_INVENTORY"), billOfMaterialsHandler.GetAssemblyOrders)
+				inventory.GET("/assembly-orders/:id", middleware.RequirePermission("VIEW_INVENTORY"), billOfMaterialsHandler.GetAssemblyOrder)
+				inventory.POST("/assembly-orders", middleware.RequirePermission("ADJUST_STOCK"), billOfMaterialsHandler.CreateAssemblyOrder)
+				inventory.POST("/assembly-orders/:id/complete", middleware.RequirePermission("ADJUST_STOCK"), billOfMaterialsHandler.CompleteAssemblyOrder)
+				inventory.POST("/assembly-orders/:id/cancel", middleware.RequirePermission("ADJUST_STOCK"), billOfMaterialsHandler.CancelAssemblyOrder)
 				inventory.POST("/import", middleware.RequirePermission("ADJUST_STOCK"), inventoryHandler.ImportInventory)
 				inventory.GET("/import-template", middleware.RequirePermission("ADJUST_STOCK"), inventoryHandler.InventoryImportTemplate)
 				inventory.GET("/import-example", middleware.RequirePermission("ADJUST_STOCK"), inventoryHandler.InventoryImportExample)
@@ -292,7 +331,14 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				inventory.POST("/transfers", middleware.RequirePermission("CREATE_TRANSFERS"), inventoryHandler.CreateStockTransfer)
 				inventory.PUT("/transfers/:id/approve", middleware.RequirePermission("APPROVE_TRANSFERS"), inventoryHandler.ApproveStockTransfer)
 				inventory.PUT("/transfers/:id/complete", middleware.RequirePermission("APPROVE_TRANSFERS"), inventoryHandler.CompleteStockTransfer)
+				inventory.POST("/transfers/:id/receive", middleware.RequirePermission("APPROVE_TRANSFERS"), inventoryHandler.ReceiveStockTransfer)
+				inventory.GET("/transfer-discrepancies", middleware.RequirePermission("VIEW_INVENTORY"), inventoryHandler.GetTransferDiscrepancies)
+				inventory.GET("/transfer-discrepancies/:id", middleware.RequirePermission("VIEW_INVENTORY"), inventoryHandler.GetTransferDiscrepancy)
 				inventory.DELETE("/transfers/:id", middleware.RequirePermission("CREATE_TRANSFERS"), inventoryHandler.CancelStockTransfer)
+				inventory.GET("/expiry-report", middleware.RequirePermission("VIEW_INVENTORY"), stockExpiryHandler.GetExpiryReport)
+				inventory.POST("/expired-stock/quarantine", middleware.RequirePermission("ADJUST_STOCK"), stockExpiryHandler.QuarantineExpiredStock)
+				inventory.GET("/expiry-write-offs", middleware.RequirePermission("VIEW_INVENTORY"), stockExpiryHandler.GetExpiryWriteOffs)
+				inventory.GET("/expiry-write-offs/:id", middleware.RequirePermission("VIEW_INVENTORY"), stockExpiryHandler.GetExpiryWriteOff)
 				inventory.GET("/product-transactions", middleware.RequirePermission("VIEW_INVENTORY"), inventoryHandler.GetProductTransactions)
 				inventory.GET("/asset-categories", middleware.RequirePermission("VIEW_PRODUCTS"), assetConsumableHandler.GetAssetCategories)
 				inventory.POST("/asset-categories", middleware.RequirePermission("CREATE_PRODUCTS"), assetConsumableHandler.CreateAssetCategory)
@@ -319,6 +365,7 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				sales.GET("/history/export", middleware.RequirePermission("VIEW_SALES"), salesHandler.ExportInvoices)
 				sales.GET("/:id", middleware.RequirePermission("VIEW_SALES"), salesHandler.GetSale)
 				sales.GET("/:id/refundable-items", middleware.RequirePermission("VIEW_SALES"), salesHandler.GetRefundableItems)
+				sales.GET("/:id/einvoice", middleware.RequirePermission("VIEW_SALES"), einvoiceHandler.GetSaleEInvoice)
 				sales.POST("", middleware.RequirePermission("CREATE_SALES"), salesHandler.CreateSale)
 				sales.PUT("/:id", middleware.RequirePermission("UPDATE_SALES"), salesHandler.UpdateSale)
 				sales.POST("/:id/refund-invoice", middleware.RequirePermission("CREATE_RETURNS"), salesHandler.CreateRefundInvoice)
@@ -339,14 +386,41 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 					quotes.POST("/:id/print-data", middleware.RequirePermission("VIEW_SALES"), salesHandler.GetQuotePrintData)
 					quotes.POST("/:id/share", middleware.RequirePermission("VIEW_SALES"), salesHandler.ShareQuote)
 					quotes.POST("/:id/convert", middleware.RequirePermission("CREATE_SALES"), salesHandler.ConvertQuoteToSale)
+					quotes.POST("/:id/convert-to-order", middleware.RequirePermission("CREATE_SALES"), salesOrderHandler.ConvertQuoteToSalesOrder)
 				}
 			}
 
+			einvoices := protected.Group("/einvoices")
+			einvoices.Use(middleware.RequireCompanyAccess())
+			{
+				einvoices.GET("", middleware.RequirePermission("VIEW_SALES"), einvoiceHandler.GetEInvoices)
+				einvoices.GET("/verify", middleware.RequirePermission("VIEW_SALES"), einvoiceHandler.VerifyChain)
+				einvoices.GET("/signing-keys", middleware.RequirePermission("VIEW_SETTINGS"), einvoiceHandler.GetSigningKeys)
+				einvoices.POST("/signing-keys/rotate", middleware.RequirePermission("MANAGE_SETTINGS"), einvoiceHandler.RotateSigningKey)
+				einvoices.POST("/issue", middleware.RequirePermission("MANAGE_SETTINGS"), einvoiceHandler.IssueEInvoice)
+				einvoices.GET("/:id", middleware.RequirePermission("VIEW_SALES"), einvoiceHandler.GetEInvoice)
+				einvoices.GET("/:id/xml", middleware.RequirePermission("VIEW_SALES"), einvoiceHandler.GetEInvoiceXML)
+				einvoices.POST("/:id/submit", middleware.RequirePermission("MANAGE_SETTINGS"), einvoiceHandler.SubmitEInvoice)
+			}
+
+			salesOrders := protected.Group("/sales-orders")
+			salesOrders.Use(middleware.RequireCompanyAccess())
+			{
+				salesOrders.GET("", middleware.RequirePermission("VIEW_SALES"), salesOrderHandler.GetSalesOrders)
+				salesOrders.GET("/backorders", middleware.RequirePermission("VIEW_SALES"), salesOrderHandler.GetBackorders)
+				salesOrders.GET("/:id", middleware.RequirePermission("VIEW_SALES"), salesOrderHandler.GetSalesOrder)
+				salesOrders.POST("", middleware.RequirePermission("CREATE_SALES"), salesOrderHandler.CreateSalesOrder)
+				salesOrders.POST("/:id/deliveries", middleware.RequirePermission("CREATE_SALES"), salesOrderHandler.CreateDelivery)
+				salesOrders.POST("/:id/invoice", middleware.RequirePermission("CREATE_SALES"), salesOrderHandler.InvoiceSalesOrder)
+				salesOrders.POST("/:id/close", middleware.RequirePermission("UPDATE_SALES"), salesOrderHandler.CloseSalesOrder)
+			}
+
 			// POS specific routes (require company and location)
 			pos := protected.Group("/pos")
 			pos.Use(middleware.RequireCompanyAccess())
 			{
 				pos.GET("/products", middleware.RequirePermission("VIEW_PRODUCTS"), posHandler.GetPOSProducts)
+				pos.GET("/products/:id/variant-grid", middleware.RequirePermission("VIEW_PRODUCTS"), posHandler.GetVariantGrid)
 				pos.GET("/customers", middleware.RequirePermission("VIEW_CUSTOMERS"), posHandler.GetPOSCustomers)
 				pos.POST("/numbering/reserve", middleware.RequirePermission("CREATE_SALES"), posHandler.ReserveNumberBlock)
 				pos.POST("/checkout", middleware.RequirePermission("CREATE_SALES"), posHandler.ProcessCheckout)
@@ -451,6 +525,10 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				purchases.PUT("/:id/receive", middleware.RequirePermission("RECEIVE_PURCHASES"), purchaseHandler.ReceivePurchase)
 				purchases.POST("/:id/invoice", middleware.RequirePermission("UPDATE_PURCHASES"), purchaseHandler.UploadPurchaseInvoice)
 				purchases.DELETE("/:id", middleware.RequirePermission("DELETE_PURCHASES"), purchaseHandler.DeletePurchase)
+
+				purchases.GET("/:id/invoice-match", middleware.RequirePermission("VIEW_PURCHASES"), invoiceMatchHandler.GetInvoiceMatch)
+				purchases.POST("/:id/invoice-match", middleware.RequirePermission("UPDATE_PURCHASES"), invoiceMatchHandler.RecordInvoice)
+				purchases.POST("/:id/invoice-match/override", middleware.RequirePermission("UPDATE_PURCHASES"), invoiceMatchHandler.RequestOverride)
 			}
 
 			supplierDebitNotes := protected.Group("/supplier-debit-notes")
@@ -470,6 +548,27 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				purchaseOrders.PUT("/:id/approve", middleware.RequirePermission("UPDATE_PURCHASES"), purchaseOrderHandler.ApprovePurchaseOrder)
 			}
 
+			replenishment := protected.Group("/replenishment")
+			replenishment.Use(middleware.RequireCompanyAccess())
+			{
+				replenishment.GET("/suggestions", middleware.RequirePermission("VIEW_INVENTORY"), replenishmentHandler.GetSuggestions)
+				replenishment.POST("/purchase-drafts", middleware.RequirePermission("CREATE_PURCHASES"), replenishmentHandler.CreatePurchaseDrafts)
+				replenishment.POST("/transfer-drafts", middleware.RequirePermission("CREATE_TRANSFERS"), replenishmentHandler.CreateTransferDrafts)
+				replenishment.GET("/rules", middleware.RequirePermission("VIEW_INVENTORY"), replenishmentHandler.GetRules)
+				replenishment.PUT("/rules", middleware.RequirePermission("ADJUST_STOCK"), replenishmentHandler.UpsertRule)
+				replenishment.DELETE("/rules/:id", middleware.RequirePermission("ADJUST_STOCK"), replenishmentHandler.DeleteRule)
+			}
+
+			accountsPayable := protected.Group("/accounts-payable")
+			accountsPayable.Use(middleware.RequireCompanyAccess())
+			{
+				accountsPayable.GET("/aging", middleware.RequirePermission("VIEW_REPORTS"), accountsPayableHandler.GetAging)
+				accountsPayable.POST("/payment-proposals", middleware.RequirePermission("VIEW_PURCHASES"), accountsPayableHandler.BuildPaymentProposal)
+				accountsPayable.GET("/payment-runs", middleware.RequirePermission("VIEW_PURCHASES"), accountsPayableHandler.ListPaymentRuns)
+				accountsPayable.GET("/payment-runs/:id", middleware.RequirePermission("VIEW_PURCHASES"), accountsPayableHandler.GetPaymentRun)
+				accountsPayable.POST("/payment-runs", middleware.RequirePermission("CREATE_PURCHASES"), accountsPayableHandler.CreatePaymentRun)
+			}
+
 			goodsReceipts := protected.Group("/goods-receipts")
 			goodsReceipts.Use(middleware.RequireCompanyAccess())
 			{
@@ -480,6 +579,29 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				goodsReceipts.POST("/:id/addons", middleware.RequirePermission("UPDATE_PURCHASES"), goodsReceiptHandler.CreateGoodsReceiptAddons)
 			}
 
+			landedCosts := protected.Group("/landed-cost-vouchers")
+			landedCosts.Use(middleware.RequireCompanyAccess())
+			{
+				landedCosts.GET("", middleware.RequirePermission("VIEW_PURCHASES"), landedCostHandler.GetVouchers)
+				landedCosts.GET("/:id", middleware.RequirePermission("VIEW_PURCHASES"), landedCostHandler.GetVoucher)
+				landedCosts.POST("", middleware.RequirePermission("UPDATE_PURCHASES"), landedCostHandler.CreateVoucher)
+			}
+
+			consignments := protected.Group("/consignments")
+			consignments.Use(middleware.RequireCompanyAccess())
+			{
+				consignments.GET("/receipts", middleware.RequirePermission("VIEW_PURCHASES"), consignmentHandler.GetReceipts)
+				consignments.GET("/receipts/:id", middleware.RequirePermission("VIEW_PURCHASES"), consignmentHandler.GetReceipt)
+				consignments.POST("/receipts", middleware.RequirePermission("RECEIVE_PURCHASES"), consignmentHandler.CreateReceipt)
+				consignments.GET("/statement", middleware.RequirePermission("VIEW_PURCHASES"), consignmentHandler.GetStatement)
+				consignments.GET("/settlements", middleware.RequirePermission("VIEW_PURCHASES"), consignmentHandler.GetSettlements)
+				consignments.GET("/settlements/:id", middleware.RequirePermission("VIEW_PURCHASES"), consignmentHandler.GetSettlement)
+				consignments.POST("/settlements", middleware.RequirePermission("CREATE_PURCHASES"), consignmentHandler.CreateSettlement)
+				consignments.GET("/dealer-locations", middleware.RequirePermission("VIEW_INVENTORY"), consignmentHandler.GetDealerLocations)
+				consignments.PUT("/dealer-locations/:id", middleware.RequirePermission("UPDATE_LOCATIONS"), consignmentHandler.SetDealerLocation)
+				consignments.POST("/dealer-locations/:id/sales-reports", middleware.RequirePermission("CREATE_SALES"), consignmentHandler.ReportDealerSales)
+			}
+
 			// Purchase Returns management routes (require company and location)
 			purchaseReturns := protected.Group("/purchase-returns")
 			purchaseReturns.Use(middleware.RequireCompanyAccess())
@@ -506,6 +628,9 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				customers.GET("/export", middleware.RequirePermission("VIEW_CUSTOMERS"), customerHandler.ExportCustomers)
 				customers.PUT("/:id", middleware.RequirePermission("UPDATE_CUSTOMERS"), customerHandler.UpdateCustomer)
 				customers.DELETE("/:id", middleware.RequirePermission("DELETE_CUSTOMERS"), customerHandler.DeleteCustomer)
+				customers.GET("/:id/statement", middleware.RequirePermission("VIEW_CUSTOMERS"), customerStatementHandler.GetStatement)
+				customers.POST("/:id/statement/email", middleware.RequirePermission("VIEW_CUSTOMERS"), customerStatementHandler.EmailStatement)
+				customers.POST("/statements/email", middleware.RequirePermission("VIEW_CUSTOMERS"), customerStatementHandler.EmailStatements)
 
 				credit := customers.Group("/:id/credit")
 				{
@@ -514,6 +639,13 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				}
 			}
 
+			dunning := protected.Group("/dunning")
+			dunning.Use(middleware.RequireCompanyAccess())
+			{
+				dunning.POST("/runs", middleware.RequirePermission("CREATE_COLLECTIONS"), dunningHandler.RunDunning)
+				dunning.GET("/reminders", middleware.RequirePermission("VIEW_COLLECTIONS"), dunningHandler.GetReminders)
+			}
+
 			warranties := protected.Group("/warranties")
 			warranties.Use(middleware.RequireCompanyAccess())
 			{
@@ -641,6 +773,65 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				chartOfAccounts.PUT("/:id", middleware.RequirePermission("MANAGE_CHART_OF_ACCOUNTS"), chartOfAccountsHandler.Update)
 			}
 
+			// Analytic dimensions tag ledger postings for the dimension filters on
+			// the trial balance, profit and loss and general ledger reports.
+			analyticDimensions := protected.Group("/analytic-dimensions")
+			analyticDimensions.Use(middleware.RequireCompanyAccess())
+			{
+				analyticDimensions.GET("", middleware.RequirePermission("VIEW_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.ListDimensions)
+				analyticDimensions.POST("", middleware.RequirePermission("MANAGE_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.CreateDimension)
+				analyticDimensions.GET("/defaults", middleware.RequirePermission("VIEW_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.ListDefaults)
+				analyticDimensions.PUT("/defaults", middleware.RequirePermission("MANAGE_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.SetDefault)
+				analyticDimensions.GET("/:id", middleware.RequirePermission("VIEW_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.GetDimension)
+				analyticDimensions.PUT("/:id", middleware.RequirePermission("MANAGE_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.UpdateDimension)
+				analyticDimensions.POST("/:id/values", middleware.RequirePermission("MANAGE_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.CreateValue)
+				analyticDimensions.PUT("/:id/values/:value_id", middleware.RequirePermission("MANAGE_ANALYTIC_DIMENSIONS"), analyticDimensionHandler.UpdateValue)
+			}
+
+			recurringTemplates := protected.Group("/recurring-templates")
+			recurringTemplates.Use(middleware.RequireCompanyAccess())
+			{
+				recurringTemplates.GET("", middleware.RequirePermission("VIEW_RECURRING_TEMPLATES"), recurringTemplateHandler.ListTemplates)
+				recurringTemplates.POST("", middleware.RequirePermission("MANAGE_RECURRING_TEMPLATES"), recurringTemplateHandler.CreateTemplate)
+				recurringTemplates.GET("/:id", middleware.RequirePermission("VIEW_RECURRING_TEMPLATES"), recurringTemplateHandler.GetTemplate)
+				recurringTemplates.PUT("/:id", middleware.RequirePermission("MANAGE_RECURRING_TEMPLATES"), recurringTemplateHandler.UpdateTemplate)
+				recurringTemplates.DELETE("/:id", middleware.RequirePermission("MANAGE_RECURRING_TEMPLATES"), recurringTemplateHandler.DeleteTemplate)
+				recurringTemplates.GET("/:id/runs", middleware.RequirePermission("VIEW_RECURRING_TEMPLATES"), recurringTemplateHandler.ListRuns)
+				recurringTemplates.GET("/:id/preview", middleware.RequirePermission("VIEW_RECURRING_TEMPLATES"), recurringTemplateHandler.PreviewTemplate)
+			}
+
+			webhookSubscriptions := protected.Group("/webhook-subscriptions")
+			webhookSubscriptions.Use(middleware.RequireCompanyAccess())
+			{
+				webhookSubscriptions.GET("", middleware.RequirePermission("VIEW_WEBHOOKS"), webhookHandler.ListSubscriptions)
+				webhookSubscriptions.POST("", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.CreateSubscription)
+				webhookSubscriptions.GET("/event-types", middleware.RequirePermission("VIEW_WEBHOOKS"), webhookHandler.ListEventTypes)
+				webhookSubscriptions.GET("/:id", middleware.RequirePermission("VIEW_WEBHOOKS"), webhookHandler.GetSubscription)
+				webhookSubscriptions.PUT("/:id", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.UpdateSubscription)
+				webhookSubscriptions.DELETE("/:id", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.DeleteSubscription)
+				webhookSubscriptions.POST("/:id/rotate-secret", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.RotateSecret)
+				webhookSubscriptions.POST("/:id/redeliver-dead", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.RedeliverDead)
+			}
+
+			webhookDeliveries := protected.Group("/webhook-deliveries")
+			webhookDeliveries.Use(middleware.RequireCompanyAccess())
+			{
+				webhookDeliveries.GET("", middleware.RequirePermission("VIEW_WEBHOOKS"), webhookHandler.ListDeliveries)
+				webhookDeliveries.GET("/:id", middleware.RequirePermission("VIEW_WEBHOOKS"), webhookHandler.GetDelivery)
+				webhookDeliveries.POST("/:id/redeliver", middleware.RequirePermission("MANAGE_WEBHOOKS"), webhookHandler.Redeliver)
+			}
+
+			apiKeys := protected.Group("/api-keys")
+			apiKeys.Use(middleware.RequireCompanyAccess())
+			{
+				apiKeys.GET("", middleware.RequirePermission("VIEW_API_KEYS"), apiKeyHandler.ListAPIKeys)
+				apiKeys.POST("", middleware.RequirePermission("MANAGE_API_KEYS"), apiKeyHandler.CreateAPIKey)
+				apiKeys.GET("/:id", middleware.RequirePermission("VIEW_API_KEYS"), apiKeyHandler.GetAPIKey)
+				apiKeys.PUT("/:id", middleware.RequirePermission("MANAGE_API_KEYS"), apiKeyHandler.UpdateAPIKey)
+				apiKeys.POST("/:id/rotate", middleware.RequirePermission("MANAGE_API_KEYS"), apiKeyHandler.RotateAPIKey)
+				apiKeys.POST("/:id/revoke", middleware.RequirePermission("MANAGE_API_KEYS"), apiKeyHandler.RevokeAPIKey)
+			}
+
 			accountingPeriods := protected.Group("/accounting-periods")
 			accountingPeriods.Use(middleware.RequireCompanyAccess())
 			{
@@ -684,6 +875,12 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				cashRegisters.POST("/movement", middleware.RequirePermission("CASH_REGISTER_MOVEMENT"), cashRegisterHandler.RecordMovement)
 				cashRegisters.POST("/force-close", middleware.RequirePermission("FORCE_CLOSE_CASH_REGISTER"), cashRegisterHandler.ForceClose)
 				cashRegisters.GET("/events", middleware.RequirePermission("VIEW_CASH_REGISTERS"), cashRegisterHandler.GetEvents)
+				cashRegisters.GET("/shifts", middleware.RequirePermission("VIEW_CASH_REGISTERS"), cashRegisterHandler.GetShifts)
+				cashRegisters.POST("/shifts/start", middleware.RequirePermission("OPEN_CASH_REGISTER"), cashRegisterHandler.StartShift)
+				cashRegisters.POST("/shifts/end", middleware.RequirePermission("CLOSE_CASH_REGISTER"), cashRegisterHandler.EndShift)
+				cashRegisters.GET("/x-report", middleware.RequirePermission("VIEW_CASH_REGISTERS"), cashRegisterHandler.GetXReport)
+				cashRegisters.GET("/z-reports", middleware.RequirePermission("VIEW_CASH_REGISTERS"), cashRegisterHandler.GetZReports)
+				cashRegisters.GET("/z-reports/:id", middleware.RequirePermission("VIEW_CASH_REGISTERS"), cashRegisterHandler.GetZReport)
 			}
 
 			notifications := protected.Group("/notifications")
@@ -692,6 +889,9 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				notifications.GET("", middleware.RequirePermission("VIEW_NOTIFICATIONS"), notificationsHandler.List)
 				notifications.GET("/unread-count", middleware.RequirePermission("VIEW_NOTIFICATIONS"), notificationsHandler.UnreadCount)
 				notifications.POST("/mark-read", middleware.RequirePermission("MARK_NOTIFICATIONS_READ"), notificationsHandler.MarkRead)
+				notifications.POST("/mark-all-read", middleware.RequirePermission("MARK_NOTIFICATIONS_READ"), notificationsHandler.MarkAllRead)
+				notifications.GET("/preferences", middleware.RequirePermission("VIEW_NOTIFICATIONS"), notificationsHandler.GetPreferences)
+				notifications.PUT("/preferences", middleware.RequirePermission("VIEW_NOTIFICATIONS"), notificationsHandler.UpdatePreferences)
 			}
 
 			reports := protected.Group("/reports")
@@ -706,6 +906,7 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				reports.GET("/valuation", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetValuationReport)
 				reports.GET("/purchase-vs-returns", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetPurchaseVsReturns)
 				reports.GET("/supplier", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetSupplierReport)
+				reports.GET("/invoice-match-variances", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetInvoiceMatchVarianceReport)
 				reports.GET("/daily-cash", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetDailyCashReport)
 				reports.GET("/cash-book", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetCashBookReport)
 				reports.GET("/bank-book", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetBankBookReport)
@@ -725,6 +926,64 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				reports.GET("/consumable-balance", middleware.RequirePermission("VIEW_REPORTS"), reportsHandler.GetConsumableBalanceReport)
 			}
 
+			// Heavy reports (general ledger, trial balance, balance sheet, item
+			// movement, valuation) run in the background and are cached per data version.
+			reportJobs := protected.Group("/report-jobs")
+			reportJobs.Use(middleware.RequireCompanyAccess())
+			{
+				reportJobs.POST("", middleware.RequirePermission("VIEW_REPORTS"), reportJobHandler.SubmitReportJob)
+				reportJobs.GET("", middleware.RequirePermission("VIEW_REPORTS"), reportJobHandler.ListReportJobs)
+				reportJobs.GET("/:id", middleware.RequirePermission("VIEW_REPORTS"), reportJobHandler.GetReportJob)
+				reportJobs.GET("/:id/result", middleware.RequirePermission("VIEW_REPORTS"), reportJobHandler.DownloadReportJobResult)
+			}
+
+			// Scheduled report emails. Subscriptions belong to the user who created
+			// them; VIEW_REPORTS is checked again before every delivery.
+			reportSubscriptions := protected.Group("/report-subscriptions")
+			reportSubscriptions.Use(middleware.RequireCompanyAccess())
+			{
+				reportSubscriptions.POST("", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.CreateReportSubscription)
+				reportSubscriptions.GET("", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.ListReportSubscriptions)
+				reportSubscriptions.GET("/:id", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.GetReportSubscription)
+				reportSubscriptions.PUT("/:id", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.UpdateReportSubscription)
+				reportSubscriptions.DELETE("/:id", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.DeleteReportSubscription)
+				reportSubscriptions.POST("/:id/run", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.RunReportSubscription)
+				reportSubscriptions.GET("/:id/deliveries", middleware.RequirePermission("VIEW_REPORTS"), reportSubscriptionHandler.ListReportSubscriptionDeliveries)
+			}
+
+			// Report builder over the semantic layer. Results are limited to the
+			// user's assigned location unless they hold REPORT_ALL_LOCATIONS.
+			customReports := protected.Group("/custom-reports")
+			customReports.Use(middleware.RequireCompanyAccess())
+			{
+				customReports.GET("/model", middleware.RequirePermission("VIEW_REPORTS"), customReportHandler.GetSemanticModels)
+				customReports.POST("/query", middleware.RequirePermission("VIEW_REPORTS"), customReportHandler.QueryCustomReport)
+				customReports.GET("", middleware.RequirePermission("VIEW_REPORTS"), customReportHandler.ListCustomReports)
+				customReports.POST("", middleware.RequirePermission("MANAGE_CUSTOM_REPORTS"), customReportHandler.CreateCustomReport)
+				customReports.GET("/:id", middleware.RequirePermission("VIEW_REPORTS"), customReportHandler.GetCustomReport)
+				customReports.PUT("/:id", middleware.RequirePermission("MANAGE_CUSTOM_REPORTS"), customReportHandler.UpdateCustomReport)
+				customReports.DELETE("/:id", middleware.RequirePermission("MANAGE_CUSTOM_REPORTS"), customReportHandler.DeleteCustomReport)
+				customReports.GET("/:id/run", middleware.RequirePermission("VIEW_REPORTS"), customReportHandler.RunCustomReport)
+			}
+
+			// Budgets are versioned per fiscal year; only one version per scope is
+			// active and its control mode gates expenses and purchases.
+			budgets := protected.Group("/budgets")
+			budgets.Use(middleware.RequireCompanyAccess())
+			{
+				budgets.GET("", middleware.RequirePermission("VIEW_BUDGETS"), budgetHandler.ListBudgets)
+				budgets.POST("", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.CreateBudget)
+				budgets.GET("/:id", middleware.RequirePermission("VIEW_BUDGETS"), budgetHandler.GetBudget)
+				budgets.PUT("/:id", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.UpdateBudget)
+				budgets.DELETE("/:id", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.DeleteBudget)
+				budgets.POST("/:id/revise", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.ReviseBudget)
+				budgets.POST("/:id/activate", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.ActivateBudget)
+				budgets.POST("/:id/archive", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.ArchiveBudget)
+				budgets.GET("/:id/sheet", middleware.RequirePermission("VIEW_BUDGETS"), budgetHandler.ExportBudgetSheet)
+				budgets.POST("/:id/sheet", middleware.RequirePermission("MANAGE_BUDGETS"), budgetHandler.ImportBudgetSheet)
+				budgets.GET("/:id/vs-actual", middleware.RequirePermission("VIEW_BUDGETS"), budgetHandler.GetBudgetVsActual)
+			}
+
 			// Supplier management routes (require company)
 			suppliers := protected.Group("/suppliers")
 			suppliers.Use(middleware.RequireCompanyAccess())
@@ -790,6 +1049,11 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				settings.GET("/tax", middleware.RequirePermission("VIEW_SETTINGS"), settingsHandler.GetTaxSettings)
 				settings.PUT("/tax", middleware.RequirePermission("MANAGE_SETTINGS"), settingsHandler.UpdateTaxSettings)
 
+				settings.GET("/three-way-match", middleware.RequirePermission("VIEW_SETTINGS"), settingsHandler.GetThreeWayMatchSettings)
+				settings.PUT("/three-way-match", middleware.RequirePermission("MANAGE_SETTINGS"), settingsHandler.UpdateThreeWayMatchSettings)
+				settings.GET("/dunning", middleware.RequirePermission("VIEW_SETTINGS"), settingsHandler.GetDunningSettings)
+				settings.PUT("/dunning", middleware.RequirePermission("MANAGE_SETTINGS"), settingsHandler.UpdateDunningSettings)
+
 				settings.GET("/device-control", middleware.RequirePermission("VIEW_SETTINGS"), settingsHandler.GetDeviceControlSettings)
 				settings.PUT("/device-control", middleware.RequirePermission("MANAGE_SETTINGS"), settingsHandler.UpdateDeviceControlSettings)
 				settings.GET("/security-policy", middleware.RequirePermission("VIEW_SETTINGS"), settingsHandler.GetSecurityPolicy)
@@ -863,10 +1127,29 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				invoiceTemplates.GET("", middleware.RequirePermission("VIEW_SETTINGS"), invoiceTemplateHandler.GetInvoiceTemplates)
 				invoiceTemplates.GET("/:id", middleware.RequirePermission("VIEW_SETTINGS"), invoiceTemplateHandler.GetInvoiceTemplate)
 				invoiceTemplates.POST("", middleware.RequirePermission("MANAGE_SETTINGS"), invoiceTemplateHandler.CreateInvoiceTemplate)
+				invoiceTemplates.POST("/preview", middleware.RequirePermission("MANAGE_SETTINGS"), documentHandler.PreviewInvoiceTemplate)
 				invoiceTemplates.PUT("/:id", middleware.RequirePermission("MANAGE_SETTINGS"), invoiceTemplateHandler.UpdateInvoiceTemplate)
 				invoiceTemplates.DELETE("/:id", middleware.RequirePermission("MANAGE_SETTINGS"), invoiceTemplateHandler.DeleteInvoiceTemplate)
 			}
 
+			// Server-rendered documents through invoice templates
+			documents := protected.Group("/documents")
+			documents.Use(middleware.RequireCompanyAccess())
+			{
+				documents.GET("/sales/:id", middleware.RequirePermission("VIEW_SALES"), documentHandler.RenderDocument(models.DocumentTypeSale))
+				documents.POST("/sales/:id/email", middleware.RequirePermission("VIEW_SALES"), documentHandler.EmailDocument(models.DocumentTypeSale))
+				documents.GET("/quotes/:id", middleware.RequirePermission("VIEW_SALES"), documentHandler.RenderDocument(models.DocumentTypeQuote))
+				documents.POST("/quotes/:id/email", middleware.RequirePermission("VIEW_SALES"), documentHandler.EmailDocument(models.DocumentTypeQuote))
+				documents.GET("/sale-returns/:id", middleware.RequirePermission("VIEW_RETURNS"), documentHandler.RenderDocument(models.DocumentTypeSaleReturn))
+				documents.POST("/sale-returns/:id/email", middleware.RequirePermission("VIEW_RETURNS"), documentHandler.EmailDocument(models.DocumentTypeSaleReturn))
+				documents.GET("/purchases/:id", middleware.RequirePermission("VIEW_PURCHASES"), documentHandler.RenderDocument(models.DocumentTypePurchase))
+				documents.POST("/purchases/:id/email", middleware.RequirePermission("VIEW_PURCHASES"), documentHandler.EmailDocument(models.DocumentTypePurchase))
+				documents.GET("/payslips/:id", middleware.RequirePermission("VIEW_PAYROLLS"), documentHandler.RenderDocument(models.DocumentTypePayslip))
+				documents.POST("/payslips/:id/email", middleware.RequirePermission("VIEW_PAYROLLS"), documentHandler.EmailDocument(models.DocumentTypePayslip))
+				documents.GET("/warranties/:id", middleware.RequirePermission("VIEW_CUSTOMERS"), documentHandler.RenderDocument(models.DocumentTypeWarranty))
+				documents.POST("/warranties/:id/email", middleware.RequirePermission("VIEW_CUSTOMERS"), documentHandler.EmailDocument(models.DocumentTypeWarranty))
+			}
+
 			// Printing now handled client-side; server returns print data via /pos/print
 
 			// Workflow & Approvals routes
@@ -889,6 +1172,18 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 				support.GET("/issues", middleware.RequirePermission("VIEW_SETTINGS"), supportIssueHandler.ListIssues)
 				support.GET("/issues/:id", middleware.RequirePermission("VIEW_SETTINGS"), supportIssueHandler.GetIssue)
 			}
+
+			// Attachments on sales, purchases, expenses, vouchers, employees, assets and
+			// support issues. Permissions depend on the entity and are checked in the handler.
+			attachments := protected.Group("/attachments")
+			attachments.Use(middleware.RequireCompanyAccess())
+			{
+				attachments.POST("", attachmentHandler.UploadAttachment)
+				attachments.GET("", attachmentHandler.ListAttachments)
+				attachments.GET("/:id", attachmentHandler.GetAttachment)
+				attachments.GET("/:id/download", attachmentHandler.DownloadAttachment)
+				attachments.DELETE("/:id", attachmentHandler.DeleteAttachment)
+			}
 		}
 	}
 
@@ -904,6 +1199,9 @@ func Initialize(router *gin.Engine, cfg *config.Config) {
 			"message": "Method not allowed",
 		})
 	})
-	// Serve uploaded files
-	router.Static("/uploads", cfg.UploadPath)
+	// Serve uploaded files from the configured object storage; /files serves
+	// signed links issued by the local backend.
+	router.GET("/uploads/*filepath", handlers.ServeUpload)
+	router.HEAD("/uploads/*filepath", handlers.ServeUpload)
+	router.GET("/files/*filepath", handlers.ServeSignedFile)
 }
@@ -339,6 +339,15 @@ func (s *AccountingAdminService) CloseAccountingPeriod(companyID, periodID, user
 	`, rawChecklist, req.Notes, userID, companyID, periodID); err != nil {
 		return nil, fmt.Errorf("failed to close accounting period: %w", err)
 	}
+	notify(s.db, NotificationEvent{
+		CompanyID:   companyID,
+		Type:        models.NotificationPeriodClosed,
+		Title:       fmt.Sprintf("Accounting period %s closed", period.PeriodName),
+		Body:        fmt.Sprintf("Postings dated %s to %s are now blocked", period.StartDate.Format("2006-01-02"), period.EndDate.Format("2006-01-02")),
+		EntityType:  "ACCOUNTING_PERIOD",
+		EntityID:    periodID,
+		ActionLabel: "Open period",
+	})
 	return s.GetAccountingPeriod(companyID, periodID)
 }
 
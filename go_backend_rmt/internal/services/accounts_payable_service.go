@@ -0,0 +1,724 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AccountsPayableService provides supplier aging, payment proposals and batch
+// supplier payment runs.
+type AccountsPayableService struct {
+	db *sql.DB
+}
+
+type openPayable struct {
+	PurchaseID       int
+	PurchaseNumber   string
+	SupplierID       int
+	SupplierName     string
+	LocationID       int
+	PurchaseDate     time.Time
+	DueDate          time.Time
+	DaysOverdue      int
+	TotalAmount      float64
+	PaidAmount       float64
+	Outstanding      float64
+	DiscountPercent  float64
+	DiscountDays     int
+	ReceivedQuantity float64
+	MatchStatus      *string
+}
+
+func NewAccountsPayableService() *AccountsPayableService {
+	return &AccountsPayableService{db: database.GetDB()}
+}
+
+func parseAPDate(value *string) (time.Time, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	parsed, err := time.Parse("2006-01-02", strings.TrimSpace(*value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", *value)
+	}
+	return parsed, nil
+}
+
+func apAgingBucket(daysOverdue int) string {
+	switch {
+	case daysOverdue <= 0:
+		return models.APAgingBucketCurrent
+	case daysOverdue <= 30:
+		return models.APAgingBucket1To30
+	case daysOverdue <= 60:
+		return models.APAgingBucket31To60
+	case daysOverdue <= 90:
+		return models.APAgingBucket61To90
+	default:
+		return models.APAgingBucketOver90
+	}
+}
+
+func addToAgingBuckets(b *models.APAgingBuckets, bucket string, amount float64) {
+	switch bucket {
+	case models.APAgingBucketCurrent:
+		b.Current = round2(b.Current + amount)
+	case models.APAgingBucket1To30:
+		b.Days1To30 = round2(b.Days1To30 + amount)
+	case models.APAgingBucket31To60:
+		b.Days31To60 = round2(b.Days31To60 + amount)
+	case models.APAgingBucket61To90:
+		b.Days61To90 = round2(b.Days61To90 + amount)
+	default:
+		b.Over90 = round2(b.Over90 + amount)
+	}
+	b.Total = round2(b.Total + amount)
+}
+
+// earlyPaymentDiscount returns the discount available when paying on
+// paymentDate, together with the last day the discount can be taken.
+func earlyPaymentDiscount(p openPayable, paymentDate time.Time) (float64, *time.Time) {
+	if p.DiscountPercent <= 0 || p.ReceivedQuantity <= 0 {
+		return 0, nil
+	}
+	deadline := p.PurchaseDate.AddDate(0, 0, p.DiscountDays)
+	if paymentDate.After(deadline) {
+		return 0, nil
+	}
+	return round2(p.Outstanding * p.DiscountPercent / 100), &deadline
+}
+
+// selectPaymentProposal picks invoices to pay within budget. Overdue invoices
+// come first (oldest first), then invoices with an early payment discount
+// (largest discount rate first), then the rest by due date. Discounted
+// invoices are only proposed when they can be settled in full; an overdue or
+// due invoice that does not fit is paid partially with what is left.
+func selectPaymentProposal(candidates []models.PaymentProposalLine, budget float64) ([]models.PaymentProposalLine, []models.PaymentProposalLine, float64) {
+	rank := func(line models.PaymentProposalLine) int {
+		switch line.Reason {
+		case models.PaymentProposalReasonOverdue:
+			return 0
+		case models.PaymentProposalReasonDiscount:
+			return 1
+		default:
+			return 2
+		}
+	}
+	ordered := append([]models.PaymentProposalLine(nil), candidates...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		switch a.Reason {
+		case models.PaymentProposalReasonOverdue:
+			if a.DaysOverdue != b.DaysOverdue {
+				return a.DaysOverdue > b.DaysOverdue
+			}
+		case models.PaymentProposalReasonDiscount:
+			if a.DiscountPercent != b.DiscountPercent {
+				return a.DiscountPercent > b.DiscountPercent
+			}
+		}
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+		return a.PurchaseID < b.PurchaseID
+	})
+
+	remaining := round2(budget)
+	selected := make([]models.PaymentProposalLine, 0)
+	deferred := make([]models.PaymentProposalLine, 0)
+	for _, line := range ordered {
+		if line.DiscountAvailable > 0 {
+			cost := round2(line.Outstanding - line.DiscountAvailable)
+			if cost <= remaining+0.0001 {
+				line.TakeDiscount = true
+				line.PaymentAmount = cost
+				remaining = round2(remaining - cost)
+				selected = append(selected, line)
+				continue
+			}
+		}
+		if line.Reason == models.PaymentProposalReasonDiscount {
+			deferred = append(deferred, line)
+			continue
+		}
+		if line.Outstanding <= remaining+0.0001 {
+			line.PaymentAmount = line.Outstanding
+		} else if remaining > 0 {
+			line.PaymentAmount = remaining
+		} else {
+			deferred = append(deferred, line)
+			continue
+		}
+		line.TakeDiscount = false
+		remaining = round2(remaining - line.PaymentAmount)
+		selected = append(selected, line)
+	}
+	return selected, deferred, remaining
+}
+
+func (s *AccountsPayableService) loadOpenPayables(companyID int, asOf time.Time, locationID *int, supplierIDs []int) ([]openPayable, error) {
+	var locArg interface{}
+	if locationID != nil && *locationID > 0 {
+		locArg = *locationID
+	}
+	if supplierIDs == nil {
+		supplierIDs = []int{}
+	}
+	rows, err := s.db.Query(`
+		WITH open_purchases AS (
+			SELECT p.purchase_id, p.purchase_number, p.supplier_id, s.name AS supplier_name, p.location_id,
+			       p.purchase_date,
+			       COALESCE(p.due_date, p.purchase_date + COALESCE(p.payment_terms, 0)) AS due_date,
+			       p.total_amount::float8 AS total_amount,
+			       p.paid_amount::float8 AS paid_amount,
+			       COALESCE(s.early_payment_discount_percent, 0)::float8 AS discount_percent,
+			       COALESCE(s.early_payment_discount_days, 0) AS discount_days
+			FROM purchases p
+			JOIN suppliers s ON s.supplier_id = p.supplier_id
+			WHERE s.company_id = $1
+			  AND p.is_deleted = FALSE
+			  AND p.status <> 'CANCELLED'
+			  AND (p.total_amount - p.paid_amount) > 0.005
+			  AND ($3::int IS NULL OR p.location_id = $3)
+			  AND (cardinality($4::int[]) = 0 OR p.supplier_id = ANY($4))
+		)
+		SELECT op.purchase_id, op.purchase_number, op.supplier_id, op.supplier_name, op.location_id,
+		       op.purchase_date, op.due_date, ($2::date - op.due_date) AS days_overdue,
+		       op.total_amount, op.paid_amount, op.discount_percent, op.discount_days,
+		       COALESCE((
+		           SELECT SUM(pd.received_quantity)
+		           FROM purchase_details pd
+		           WHERE pd.purchase_id = op.purchase_id
+		       ), 0)::float8 AS received_quantity,
+		       m.status
+		FROM open_purchases op
+		LEFT JOIN supplier_invoice_matches m ON m.purchase_id = op.purchase_id
+		ORDER BY op.supplier_name, op.due_date, op.purchase_id
+	`, companyID, asOf, locArg, pq.Array(supplierIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open payables: %w", err)
+	}
+	defer rows.Close()
+
+	var items []openPayable
+	for rows.Next() {
+		var p openPayable
+		var matchStatus sql.NullString
+		if err := rows.Scan(
+			&p.PurchaseID, &p.PurchaseNumber, &p.SupplierID, &p.SupplierName, &p.LocationID,
+			&p.PurchaseDate, &p.DueDate, &p.DaysOverdue,
+			&p.TotalAmount, &p.PaidAmount, &p.DiscountPercent, &p.DiscountDays,
+			&p.ReceivedQuantity, &matchStatus,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan open payable: %w", err)
+		}
+		p.Outstanding = round2(p.TotalAmount - p.PaidAmount)
+		p.MatchStatus = nullStringPtr(matchStatus)
+		items = append(items, p)
+	}
+	return items, rows.Err()
+}
+
+// GetAging buckets open supplier balances by days past due as of asOf.
+func (s *AccountsPayableService) GetAging(companyID int, locationID *int, asOf *string) (*models.APAgingReport, error) {
+	asOfDate, err := parseAPDate(asOf)
+	if err != nil {
+		return nil, err
+	}
+	payables, err := s.loadOpenPayables(companyID, asOfDate, locationID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.APAgingReport{AsOf: asOfDate, Suppliers: []models.APAgingSupplier{}}
+	index := make(map[int]int)
+	for _, p := range payables {
+		pos, ok := index[p.SupplierID]
+		if !ok {
+			report.Suppliers = append(report.Suppliers, models.APAgingSupplier{
+				SupplierID:   p.SupplierID,
+				SupplierName: p.SupplierName,
+				Invoices:     []models.APAgingInvoice{},
+			})
+			pos = len(report.Suppliers) - 1
+			index[p.SupplierID] = pos
+		}
+		bucket := apAgingBucket(p.DaysOverdue)
+		supplier := &report.Suppliers[pos]
+		supplier.Invoices = append(supplier.Invoices, models.APAgingInvoice{
+			PurchaseID:     p.PurchaseID,
+			PurchaseNumber: p.PurchaseNumber,
+			LocationID:     p.LocationID,
+			PurchaseDate:   p.PurchaseDate,
+			DueDate:        p.DueDate,
+			TotalAmount:    p.TotalAmount,
+			PaidAmount:     p.PaidAmount,
+			Outstanding:    p.Outstanding,
+			DaysOverdue:    max(p.DaysOverdue, 0),
+			Bucket:         bucket,
+		})
+		addToAgingBuckets(&supplier.Buckets, bucket, p.Outstanding)
+		addToAgingBuckets(&report.Totals, bucket, p.Outstanding)
+	}
+
+	debitLocation := 0
+	if locationID != nil {
+		debitLocation = *locationID
+	}
+	debitNotes, err := (&PurchaseCostAdjustmentService{db: s.db}).ListSupplierDebitNotes(companyID, debitLocation, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range debitNotes {
+		if note.AdjustmentDate.After(asOfDate) {
+			continue
+		}
+		pos, ok := index[note.SupplierID]
+		if !ok {
+			continue
+		}
+		supplier := &report.Suppliers[pos]
+		supplier.DebitNotes = append(supplier.DebitNotes, note)
+		supplier.DebitNoteTotal = round2(supplier.DebitNoteTotal + math.Abs(note.TotalAmount))
+	}
+	return report, nil
+}
+
+// BuildPaymentProposal selects due invoices and discount opportunities that
+// fit within the cash budget.
+func (s *AccountsPayableService) BuildPaymentProposal(companyID int, req *models.PaymentProposalRequest) (*models.PaymentProposal, error) {
+	paymentDate, err := parseAPDate(req.PaymentDate)
+	if err != nil {
+		return nil, err
+	}
+	dueWithin := 0
+	if req.DueWithinDays != nil {
+		dueWithin = *req.DueWithinDays
+	}
+	settings, err := (&SettingsService{db: s.db}).GetThreeWayMatchSettings(companyID)
+	if err != nil {
+		return nil, err
+	}
+	payables, err := s.loadOpenPayables(companyID, paymentDate, req.LocationID, req.SupplierIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	horizon := paymentDate.AddDate(0, 0, dueWithin)
+	candidates := make([]models.PaymentProposalLine, 0, len(payables))
+	for _, p := range payables {
+		if p.MatchStatus != nil && (*p.MatchStatus == models.InvoiceMatchStatusVariance || *p.MatchStatus == models.InvoiceMatchStatusOverridePending) {
+			continue
+		}
+		if p.MatchStatus == nil && settings.RequireMatchBeforePayment {
+			continue
+		}
+		discount, deadline := earlyPaymentDiscount(p, paymentDate)
+		reason := ""
+		switch {
+		case p.DaysOverdue > 0:
+			reason = models.PaymentProposalReasonOverdue
+		case discount > 0:
+			reason = models.PaymentProposalReasonDiscount
+		case !p.DueDate.After(horizon):
+			reason = models.PaymentProposalReasonDue
+		default:
+			continue
+		}
+		candidates = append(candidates, models.PaymentProposalLine{
+			PurchaseID:        p.PurchaseID,
+			PurchaseNumber:    p.PurchaseNumber,
+			SupplierID:        p.SupplierID,
+			SupplierName:      p.SupplierName,
+			LocationID:        p.LocationID,
+			DueDate:           p.DueDate,
+			DaysOverdue:       max(p.DaysOverdue, 0),
+			Outstanding:       p.Outstanding,
+			DiscountPercent:   p.DiscountPercent,
+			DiscountAvailable: discount,
+			DiscountDeadline:  deadline,
+			Reason:            reason,
+		})
+	}
+
+	selected, deferred, remaining := selectPaymentProposal(candidates, req.CashBudget)
+	proposal := &models.PaymentProposal{
+		PaymentDate:     paymentDate,
+		CashBudget:      req.CashBudget,
+		RemainingBudget: remaining,
+		Lines:           selected,
+		Deferred:        deferred,
+	}
+	for _, line := range selected {
+		proposal.TotalPayment = round2(proposal.TotalPayment + line.PaymentAmount)
+		if line.TakeDiscount {
+			proposal.TotalDiscount = round2(proposal.TotalDiscount + line.DiscountAvailable)
+		}
+	}
+	return proposal, nil
+}
+
+// CreatePaymentRun pays the given purchases in a single transaction, one
+// supplier payment per purchase, and queues their ledger postings.
+func (s *AccountsPayableService) CreatePaymentRun(companyID, userID int, req *models.CreateSupplierPaymentRunRequest) (*models.SupplierPaymentRun, error) {
+	paymentDate, err := parseAPDate(req.PaymentDate)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(req.Items))
+	for _, item := range req.Items {
+		if seen[item.PurchaseID] {
+			return nil, fmt.Errorf("purchase %d is listed more than once", item.PurchaseID)
+		}
+		seen[item.PurchaseID] = true
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if req.PaymentMethodID != nil {
+		var exists int
+		if err := tx.QueryRow(`
+			SELECT 1 FROM payment_methods WHERE method_id = $1 AND (company_id = $2 OR company_id IS NULL)
+		`, *req.PaymentMethodID, companyID).Scan(&exists); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("payment method not found")
+			}
+			return nil, fmt.Errorf("failed to verify payment method: %w", err)
+		}
+	}
+
+	ns := NewNumberingSequenceService()
+	runNumber, err := ns.NextNumber(tx, "supplier_payment_run", companyID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate payment run number: %w", err)
+	}
+	run := &models.SupplierPaymentRun{
+		RunNumber:       runNumber,
+		CompanyID:       companyID,
+		PaymentDate:     paymentDate,
+		PaymentMethodID: req.PaymentMethodID,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           req.Notes,
+		CreatedBy:       userID,
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO supplier_payment_runs (
+			run_number, company_id, payment_date, payment_method_id, reference_number, notes, created_by
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		RETURNING run_id, created_at
+	`, runNumber, companyID, paymentDate, req.PaymentMethodID, req.ReferenceNumber, req.Notes, userID).Scan(&run.RunID, &run.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create payment run: %w", err)
+	}
+
+	finance := NewFinanceIntegrityServiceWithDB(s.db)
+	paymentSvc := &PaymentService{db: s.db}
+	matchSvc := &InvoiceMatchService{db: s.db}
+	var discountAdjustments []int
+	for _, item := range req.Items {
+		var p openPayable
+		var status string
+		if err := tx.QueryRow(`
+			SELECT p.purchase_number, p.supplier_id, s.name, p.location_id, p.purchase_date, p.status,
+			       p.total_amount::float8, p.paid_amount::float8,
+			       COALESCE(s.early_payment_discount_percent, 0)::float8,
+			       COALESCE(s.early_payment_discount_days, 0),
+			       COALESCE((
+			           SELECT SUM(pd.received_quantity)
+			           FROM purchase_details pd
+			           WHERE pd.purchase_id = p.purchase_id
+			       ), 0)::float8
+			FROM purchases p
+			JOIN suppliers s ON s.supplier_id = p.supplier_id
+			WHERE p.purchase_id = $1 AND s.company_id = $2 AND p.is_deleted = FALSE
+			FOR UPDATE OF p
+		`, item.PurchaseID, companyID).Scan(
+			&p.PurchaseNumber, &p.SupplierID, &p.SupplierName, &p.LocationID, &p.PurchaseDate, &status,
+			&p.TotalAmount, &p.PaidAmount, &p.DiscountPercent, &p.DiscountDays, &p.ReceivedQuantity,
+		); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("purchase %d not found", item.PurchaseID)
+			}
+			return nil, fmt.Errorf("failed to verify purchase: %w", err)
+		}
+		if status == "CANCELLED" {
+			return nil, fmt.Errorf("purchase %s is cancelled", p.PurchaseNumber)
+		}
+		if err := matchSvc.ensurePaymentAllowedTx(tx, companyID, item.PurchaseID); err != nil {
+			return nil, fmt.Errorf("purchase %s: %w", p.PurchaseNumber, err)
+		}
+		p.PurchaseID = item.PurchaseID
+		p.Outstanding = round2(p.TotalAmount - p.PaidAmount)
+
+		line := models.SupplierPaymentRunLine{
+			SupplierID:     p.SupplierID,
+			SupplierName:   p.SupplierName,
+			PurchaseID:     p.PurchaseID,
+			PurchaseNumber: p.PurchaseNumber,
+			LocationID:     p.LocationID,
+			Amount:         round2(item.Amount),
+		}
+		if item.TakeDiscount {
+			discount, _ := earlyPaymentDiscount(p, paymentDate)
+			if discount <= 0 {
+				return nil, fmt.Errorf("early payment discount is not available for purchase %s", p.PurchaseNumber)
+			}
+			if math.Abs(line.Amount-round2(p.Outstanding-discount)) > 0.01 {
+				return nil, fmt.Errorf("discounted payment for purchase %s must settle it in full (%.2f)", p.PurchaseNumber, p.Outstanding-discount)
+			}
+			adjustmentID, err := s.postEarlyPaymentDiscountTx(tx, companyID, userID, p, discount, runNumber)
+			if err != nil {
+				return nil, err
+			}
+			line.DiscountAmount = discount
+			line.DiscountAdjustmentID = &adjustmentID
+			discountAdjustments = append(discountAdjustments, adjustmentID)
+		} else if line.Amount > p.Outstanding+0.0001 {
+			return nil, fmt.Errorf("payment exceeds outstanding amount for purchase %s", p.PurchaseNumber)
+		}
+
+		paymentNumber, err := ns.NextNumber(tx, "payment", companyID, &p.LocationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate payment number: %w", err)
+		}
+		if err := tx.QueryRow(`
+			INSERT INTO payments (
+				payment_number, supplier_id, purchase_id, location_id, payment_date, amount, payment_method_id,
+				reference_number, notes, created_by, updated_by, payment_run_id, discount_amount, discount_adjustment_id
+			)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10,$11,$12,$13)
+			RETURNING payment_id
+		`, paymentNumber, p.SupplierID, p.PurchaseID, p.LocationID, paymentDate, line.Amount, req.PaymentMethodID,
+			req.ReferenceNumber, req.Notes, userID, run.RunID, line.DiscountAmount, line.DiscountAdjustmentID,
+		).Scan(&line.PaymentID); err != nil {
+			return nil, fmt.Errorf("failed to insert payment: %w", err)
+		}
+		line.PaymentNumber = paymentNumber
+
+		if _, err := tx.Exec(`
+			UPDATE purchases SET paid_amount = paid_amount + $1, updated_at = CURRENT_TIMESTAMP, updated_by = $2 WHERE purchase_id = $3
+		`, line.Amount, userID, p.PurchaseID); err != nil {
+			return nil, fmt.Errorf("failed to update purchase paid amount: %w", err)
+		}
+		if err := paymentSvc.enqueueSupplierPaymentEventsTx(tx, finance, companyID, p.LocationID, userID, line.PaymentID, paymentNumber, line.Amount, req.PaymentMethodID); err != nil {
+			return nil, err
+		}
+
+		run.Payments = append(run.Payments, line)
+		run.PaymentCount++
+		run.TotalAmount = round2(run.TotalAmount + line.Amount)
+		run.TotalDiscount = round2(run.TotalDiscount + line.DiscountAmount)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE supplier_payment_runs
+		SET payment_count = $1, total_amount = $2, total_discount = $3
+		WHERE run_id = $4
+	`, run.PaymentCount, run.TotalAmount, run.TotalDiscount, run.RunID); err != nil {
+		return nil, fmt.Errorf("failed to update payment run totals: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit payment run: %w", err)
+	}
+
+	ledger := &LedgerService{db: s.db}
+	for _, adjustmentID := range discountAdjustments {
+		_ = ledger.RecordPurchaseCostAdjustment(companyID, adjustmentID, userID)
+	}
+	for _, line := range run.Payments {
+		if err := finance.ProcessAggregate(companyID, "payment", line.PaymentID); err != nil {
+			log.Printf("accounts_payable_service: failed to process finance outbox for payment %d: %v", line.PaymentID, err)
+		}
+	}
+	return run, nil
+}
+
+// postEarlyPaymentDiscountTx books an early payment discount as a cost-only
+// reduction spread over the received lines of the purchase, lowering both the
+// purchase total and the cost of the received stock.
+func (s *AccountsPayableService) postEarlyPaymentDiscountTx(tx *sql.Tx, companyID, userID int, p openPayable, discount float64, runNumber string) (int, error) {
+	rows, err := tx.Query(`
+		SELECT purchase_detail_id, product_id, (received_quantity * unit_price)::float8
+		FROM purchase_details
+		WHERE purchase_id = $1 AND COALESCE(received_quantity, 0) > 0
+		ORDER BY purchase_detail_id
+	`, p.PurchaseID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load received purchase lines: %w", err)
+	}
+	var detailIDs, productIDs []int
+	var weights []float64
+	for rows.Next() {
+		var detailID, productID int
+		var weight float64
+		if err := rows.Scan(&detailID, &productID, &weight); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan received purchase line: %w", err)
+		}
+		detailIDs = append(detailIDs, detailID)
+		productIDs = append(productIDs, productID)
+		weights = append(weights, math.Max(weight, 0))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load received purchase lines: %w", err)
+	}
+	if len(detailIDs) == 0 {
+		return 0, fmt.Errorf("early payment discount requires received goods on purchase %s", p.PurchaseNumber)
+	}
+
+	costSvc := &PurchaseCostAdjustmentService{db: s.db}
+	trackingSvc := newInventoryTrackingService(s.db)
+	notes := fmt.Sprintf("Early payment discount %.2f%% taken in payment run %s", p.DiscountPercent, runNumber)
+	adjustment, err := costSvc.createAdjustmentDocumentTx(tx, companyID, userID, models.PurchaseCostAdjustmentTypeEarlyPayDiscount, nil, &p.PurchaseID, p.LocationID, p.SupplierID, &runNumber, &notes)
+	if err != nil {
+		return 0, err
+	}
+
+	totalSigned := 0.0
+	allocations := distributeWeightedAmount(discount, weights)
+	for idx, amount := range allocations {
+		if math.Abs(amount) < 0.0001 {
+			continue
+		}
+		ctx, err := costSvc.loadPurchaseDetailContextTx(tx, companyID, detailIDs[idx])
+		if err != nil {
+			return 0, err
+		}
+		detailID := detailIDs[idx]
+		_, result, err := costSvc.applyCostOnlyLineTx(tx, companyID, userID, adjustment.AdjustmentID, costAdjustmentLine{
+			SourceScope:      models.PurchaseCostAdjustmentScopeHeader,
+			PurchaseDetailID: &detailID,
+			ProductID:        productIDs[idx],
+			BarcodeID:        ctx.BarcodeID,
+			Label:            "Early payment discount",
+			StockAction:      models.PurchaseCostAdjustmentStockActionCostOnly,
+			SignedAmount:     -amount,
+		})
+		if err != nil {
+			return 0, err
+		}
+		totalSigned += result.SignedAmount
+		if ctx.BarcodeID != nil && *ctx.BarcodeID > 0 {
+			if err := costSvc.syncVariantAverageCostTx(tx, p.LocationID, *ctx.BarcodeID); err != nil {
+				return 0, err
+			}
+		}
+		if err := trackingSvc.updateProductCostSnapshotTx(tx, companyID, productIDs[idx]); err != nil {
+			return 0, fmt.Errorf("failed to update product cost snapshot: %w", err)
+		}
+	}
+
+	if err := costSvc.finalizeAdjustmentTx(tx, adjustment.AdjustmentID, userID, round2(totalSigned)); err != nil {
+		return 0, err
+	}
+	if err := costSvc.updatePurchaseTotalsTx(tx, p.PurchaseID, companyID, round2(totalSigned)); err != nil {
+		return 0, err
+	}
+	return adjustment.AdjustmentID, nil
+}
+
+func (s *AccountsPayableService) ListPaymentRuns(companyID int) ([]models.SupplierPaymentRun, error) {
+	rows, err := s.db.Query(`
+		SELECT run_id, run_number, company_id, payment_date, payment_method_id, reference_number, notes,
+		       payment_count, total_amount::float8, total_discount::float8, created_by, created_at
+		FROM supplier_payment_runs
+		WHERE company_id = $1
+		ORDER BY payment_date DESC, run_id DESC
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]models.SupplierPaymentRun, 0)
+	for rows.Next() {
+		run, err := scanSupplierPaymentRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+func (s *AccountsPayableService) GetPaymentRun(companyID, runID int) (*models.SupplierPaymentRun, error) {
+	run, err := scanSupplierPaymentRun(s.db.QueryRow(`
+		SELECT run_id, run_number, company_id, payment_date, payment_method_id, reference_number, notes,
+		       payment_count, total_amount::float8, total_discount::float8, created_by, created_at
+		FROM supplier_payment_runs
+		WHERE company_id = $1 AND run_id = $2
+	`, companyID, runID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("payment run not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT pay.payment_id, pay.payment_number, pay.supplier_id, s.name, pay.purchase_id, p.purchase_number,
+		       pay.location_id, pay.amount::float8, pay.discount_amount::float8, pay.discount_adjustment_id
+		FROM payments pay
+		JOIN suppliers s ON s.supplier_id = pay.supplier_id
+		JOIN purchases p ON p.purchase_id = pay.purchase_id
+		WHERE pay.payment_run_id = $1 AND COALESCE(pay.is_deleted, FALSE) = FALSE
+		ORDER BY pay.payment_id
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment run payments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.SupplierPaymentRunLine
+		var adjustmentID sql.NullInt64
+		if err := rows.Scan(
+			&line.PaymentID, &line.PaymentNumber, &line.SupplierID, &line.SupplierName, &line.PurchaseID, &line.PurchaseNumber,
+			&line.LocationID, &line.Amount, &line.DiscountAmount, &adjustmentID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan payment run payment: %w", err)
+		}
+		line.DiscountAdjustmentID = intPtrFromNullInt64(adjustmentID)
+		run.Payments = append(run.Payments, line)
+	}
+	return run, rows.Err()
+}
+
+func scanSupplierPaymentRun(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*models.SupplierPaymentRun, error) {
+	var run models.SupplierPaymentRun
+	var methodID sql.NullInt64
+	var reference, notes sql.NullString
+	if err := scanner.Scan(
+		&run.RunID, &run.RunNumber, &run.CompanyID, &run.PaymentDate, &methodID, &reference, &notes,
+		&run.PaymentCount, &run.TotalAmount, &run.TotalDiscount, &run.CreatedBy, &run.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan payment run: %w", err)
+	}
+	run.PaymentMethodID = intPtrFromNullInt64(methodID)
+	run.ReferenceNumber = nullStringPtr(reference)
+	run.Notes = nullStringPtr(notes)
+	return &run, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+func TestAPAgingBucket(t *testing.T) {
+	cases := map[int]string{
+		-5:  models.APAgingBucketCurrent,
+		0:   models.APAgingBucketCurrent,
+		1:   models.APAgingBucket1To30,
+		30:  models.APAgingBucket1To30,
+		45:  models.APAgingBucket31To60,
+		90:  models.APAgingBucket61To90,
+		120: models.APAgingBucketOver90,
+	}
+	for days, expected := range cases {
+		if got := apAgingBucket(days); got != expected {
+			t.Fatalf("expected %s for %d days, got %s", expected, days, got)
+		}
+	}
+}
+
+func TestSelectPaymentProposal_PrioritisesOverdueThenDiscount(t *testing.T) {
+	due := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	candidates := []models.PaymentProposalLine{
+		{PurchaseID: 1, DueDate: due.AddDate(0, 0, 10), Outstanding: 300, Reason: models.PaymentProposalReasonDue},
+		{PurchaseID: 2, DueDate: due, Outstanding: 200, DaysOverdue: 5, Reason: models.PaymentProposalReasonOverdue},
+		{PurchaseID: 3, DueDate: due.AddDate(0, 0, 30), Outstanding: 500, DiscountPercent: 2, DiscountAvailable: 10, Reason: models.PaymentProposalReasonDiscount},
+	}
+
+	selected, deferred, remaining := selectPaymentProposal(candidates, 800)
+	if len(selected) != 3 || len(deferred) != 0 {
+		t.Fatalf("expected 3 selected lines, got %d selected / %d deferred", len(selected), len(deferred))
+	}
+	if selected[0].PurchaseID != 2 || selected[1].PurchaseID != 3 {
+		t.Fatalf("expected overdue then discount first, got %d, %d", selected[0].PurchaseID, selected[1].PurchaseID)
+	}
+	if !selected[1].TakeDiscount || selected[1].PaymentAmount != 490 {
+		t.Fatalf("expected discounted payment of 490, got %v (take=%v)", selected[1].PaymentAmount, selected[1].TakeDiscount)
+	}
+	// 800 - 200 - 490 leaves 110 for the due invoice, paid partially.
+	if selected[2].PaymentAmount != 110 || remaining != 0 {
+		t.Fatalf("expected partial payment of 110 and no budget left, got %v / %v", selected[2].PaymentAmount, remaining)
+	}
+}
+
+func TestSelectPaymentProposal_DefersDiscountThatDoesNotFit(t *testing.T) {
+	candidates := []models.PaymentProposalLine{
+		{PurchaseID: 1, Outstanding: 1000, DiscountPercent: 3, DiscountAvailable: 30, Reason: models.PaymentProposalReasonDiscount},
+	}
+	selected, deferred, remaining := selectPaymentProposal(candidates, 500)
+	if len(selected) != 0 || len(deferred) != 1 {
+		t.Fatalf("expected discount line to be deferred, got %d selected / %d deferred", len(selected), len(deferred))
+	}
+	if remaining != 500 {
+		t.Fatalf("expected untouched budget, got %v", remaining)
+	}
+}
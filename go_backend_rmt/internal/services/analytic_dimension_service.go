@@ -0,0 +1,487 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AnalyticDimensionService manages the company's analytic dimensions, their
+// values and the defaults that tag ledger postings.
+type AnalyticDimensionService struct {
+	db *sql.DB
+}
+
+func NewAnalyticDimensionService() *AnalyticDimensionService {
+	return &AnalyticDimensionService{db: database.GetDB()}
+}
+
+func (s *AnalyticDimensionService) ListDimensions(companyID int, includeInactive bool) ([]models.AnalyticDimension, error) {
+	rows, err := s.db.Query(`
+		SELECT dimension_id, company_id, code, name, is_active, created_by, updated_by, created_at, updated_at
+		FROM analytic_dimensions
+		WHERE company_id = $1 AND ($2 OR is_active = TRUE)
+		ORDER BY code
+	`, companyID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analytic dimensions: %w", err)
+	}
+	defer rows.Close()
+
+	dims := []models.AnalyticDimension{}
+	index := map[int]int{}
+	for rows.Next() {
+		var d models.AnalyticDimension
+		if err := rows.Scan(&d.DimensionID, &d.CompanyID, &d.Code, &d.Name, &d.IsActive, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan analytic dimension: %w", err)
+		}
+		d.Values = []models.AnalyticDimensionValue{}
+		index[d.DimensionID] = len(dims)
+		dims = append(dims, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytic dimensions: %w", err)
+	}
+
+	valueRows, err := s.db.Query(`
+		SELECT value_id, dimension_id, code, name, is_active
+		FROM analytic_dimension_values
+		WHERE company_id = $1 AND ($2 OR is_active = TRUE)
+		ORDER BY code
+	`, companyID, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analytic dimension values: %w", err)
+	}
+	defer valueRows.Close()
+	for valueRows.Next() {
+		var v models.AnalyticDimensionValue
+		if err := valueRows.Scan(&v.ValueID, &v.DimensionID, &v.Code, &v.Name, &v.IsActive); err != nil {
+			return nil, fmt.Errorf("failed to scan analytic dimension value: %w", err)
+		}
+		if i, ok := index[v.DimensionID]; ok {
+			dims[i].Values = append(dims[i].Values, v)
+		}
+	}
+	if err := valueRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytic dimension values: %w", err)
+	}
+	return dims, nil
+}
+
+func (s *AnalyticDimensionService) GetDimension(companyID, dimensionID int) (*models.AnalyticDimension, error) {
+	dims, err := s.ListDimensions(companyID, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dims {
+		if dims[i].DimensionID == dimensionID {
+			return &dims[i], nil
+		}
+	}
+	return nil, fmt.Errorf("analytic dimension not found")
+}
+
+func (s *AnalyticDimensionService) CreateDimension(companyID, userID int, req *models.CreateAnalyticDimensionRequest) (*models.AnalyticDimension, error) {
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO analytic_dimensions (company_id, code, name, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING dimension_id
+	`, companyID, strings.ToUpper(strings.TrimSpace(req.Code)), strings.TrimSpace(req.Name), userID).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("analytic dimension code already exists")
+		}
+		return nil, fmt.Errorf("failed to create analytic dimension: %w", err)
+	}
+	return s.GetDimension(companyID, id)
+}
+
+func (s *AnalyticDimensionService) UpdateDimension(companyID, userID, dimensionID int, req *models.UpdateAnalyticDimensionRequest) (*models.AnalyticDimension, error) {
+	res, err := s.db.Exec(`
+		UPDATE analytic_dimensions
+		SET name = COALESCE(NULLIF($1, ''), name),
+		    is_active = COALESCE($2, is_active),
+		    updated_by = $3,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE company_id = $4 AND dimension_id = $5
+	`, trimPointer(req.Name), req.IsActive, userID, companyID, dimensionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update analytic dimension: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("analytic dimension not found")
+	}
+	return s.GetDimension(companyID, dimensionID)
+}
+
+func (s *AnalyticDimensionService) CreateValue(companyID, dimensionID int, req *models.CreateAnalyticDimensionValueRequest) (*models.AnalyticDimensionValue, error) {
+	var v models.AnalyticDimensionValue
+	err := s.db.QueryRow(`
+		INSERT INTO analytic_dimension_values (company_id, dimension_id, code, name)
+		SELECT $1, d.dimension_id, $3, $4
+		FROM analytic_dimensions d
+		WHERE d.company_id = $1 AND d.dimension_id = $2
+		RETURNING value_id, dimension_id, code, name, is_active
+	`, companyID, dimensionID, strings.ToUpper(strings.TrimSpace(req.Code)), strings.TrimSpace(req.Name)).
+		Scan(&v.ValueID, &v.DimensionID, &v.Code, &v.Name, &v.IsActive)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("analytic dimension not found")
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("analytic dimension value code already exists")
+		}
+		return nil, fmt.Errorf("failed to create analytic dimension value: %w", err)
+	}
+	return &v, nil
+}
+
+func (s *AnalyticDimensionService) UpdateValue(companyID, dimensionID, valueID int, req *models.UpdateAnalyticDimensionValueRequest) (*models.AnalyticDimensionValue, error) {
+	var v models.AnalyticDimensionValue
+	err := s.db.QueryRow(`
+		UPDATE analytic_dimension_values
+		SET name = COALESCE(NULLIF($1, ''), name),
+		    is_active = COALESCE($2, is_active),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE company_id = $3 AND dimension_id = $4 AND value_id = $5
+		RETURNING value_id, dimension_id, code, name, is_active
+	`, trimPointer(req.Name), req.IsActive, companyID, dimensionID, valueID).
+		Scan(&v.ValueID, &v.DimensionID, &v.Code, &v.Name, &v.IsActive)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("analytic dimension value not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update analytic dimension value: %w", err)
+	}
+	return &v, nil
+}
+
+// ListDefaults returns the configured defaults, optionally for one source type.
+func (s *AnalyticDimensionService) ListDefaults(companyID int, sourceType string) ([]models.AnalyticDimensionDefault, error) {
+	rows, err := s.db.Query(`
+		SELECT d.dimension_id, ad.name, d.source_type, d.source_id, d.value_id, v.code, v.name
+		FROM analytic_dimension_defaults d
+		JOIN analytic_dimensions ad ON ad.dimension_id = d.dimension_id
+		JOIN analytic_dimension_values v ON v.value_id = d.value_id
+		WHERE d.company_id = $1 AND ($2 = '' OR d.source_type = $2)
+		ORDER BY d.source_type, d.source_id, ad.code
+	`, companyID, strings.ToUpper(strings.TrimSpace(sourceType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analytic defaults: %w", err)
+	}
+	defer rows.Close()
+	defaults := []models.AnalyticDimensionDefault{}
+	for rows.Next() {
+		var d models.AnalyticDimensionDefault
+		if err := rows.Scan(&d.DimensionID, &d.DimensionName, &d.SourceType, &d.SourceID, &d.ValueID, &d.ValueCode, &d.ValueName); err != nil {
+			return nil, fmt.Errorf("failed to scan analytic default: %w", err)
+		}
+		defaults = append(defaults, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytic defaults: %w", err)
+	}
+	return defaults, nil
+}
+
+// analyticSourceTables maps a default's source type to the company-scoped
+// table its source_id refers to.
+var analyticSourceTables = map[string]string{
+	models.AnalyticSourceLocation:        "SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)",
+	models.AnalyticSourceDepartment:      "SELECT EXISTS (SELECT 1 FROM departments WHERE department_id = $1 AND company_id = $2)",
+	models.AnalyticSourceEmployee:        "SELECT EXISTS (SELECT 1 FROM employees WHERE employee_id = $1 AND company_id = $2)",
+	models.AnalyticSourceExpenseCategory: "SELECT EXISTS (SELECT 1 FROM expense_categories WHERE category_id = $1 AND company_id = $2)",
+}
+
+func (s *AnalyticDimensionService) SetDefault(companyID int, req *models.SetAnalyticDimensionDefaultRequest) error {
+	check, ok := analyticSourceTables[req.SourceType]
+	if !ok {
+		return fmt.Errorf("invalid source_type")
+	}
+	var exists bool
+	if err := s.db.QueryRow(check, req.SourceID, companyID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to verify default source: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%s not found", strings.ToLower(strings.ReplaceAll(req.SourceType, "_", " ")))
+	}
+
+	if req.ValueID == nil {
+		if _, err := s.db.Exec(`
+			DELETE FROM analytic_dimension_defaults
+			WHERE company_id = $1 AND dimension_id = $2 AND source_type = $3 AND source_id = $4
+		`, companyID, req.DimensionID, req.SourceType, req.SourceID); err != nil {
+			return fmt.Errorf("failed to clear analytic default: %w", err)
+		}
+		return nil
+	}
+	if _, err := validateAnalyticTags(s.db, companyID, []models.AnalyticTag{{DimensionID: req.DimensionID, ValueID: *req.ValueID}}); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`
+		INSERT INTO analytic_dimension_defaults (company_id, dimension_id, source_type, source_id, value_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (dimension_id, source_type, source_id) DO UPDATE SET value_id = EXCLUDED.value_id
+	`, companyID, req.DimensionID, req.SourceType, req.SourceID, *req.ValueID); err != nil {
+		return fmt.Errorf("failed to set analytic default: %w", err)
+	}
+	return nil
+}
+
+// validateAnalyticTags checks that each tag names an active value of the
+// given active dimension in the company, with at most one per dimension.
+// Tags are returned sorted by dimension.
+func validateAnalyticTags(q sqlQueryer, companyID int, tags []models.AnalyticTag) ([]models.AnalyticTag, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := map[int]bool{}
+	valueIDs := make([]int64, 0, len(tags))
+	for _, t := range tags {
+		if seen[t.DimensionID] {
+			return nil, fmt.Errorf("only one value per analytic dimension is allowed")
+		}
+		seen[t.DimensionID] = true
+		valueIDs = append(valueIDs, int64(t.ValueID))
+	}
+
+	rows, err := q.Query(`
+		SELECT v.value_id, v.dimension_id
+		FROM analytic_dimension_values v
+		JOIN analytic_dimensions d ON d.dimension_id = v.dimension_id AND d.is_active = TRUE
+		WHERE v.company_id = $1 AND v.is_active = TRUE AND v.value_id = ANY($2)
+	`, companyID, pq.Array(valueIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify analytic dimension values: %w", err)
+	}
+	defer rows.Close()
+	dimensionOf := map[int]int{}
+	for rows.Next() {
+		var valueID, dimensionID int
+		if err := rows.Scan(&valueID, &dimensionID); err != nil {
+			return nil, fmt.Errorf("failed to scan analytic dimension value: %w", err)
+		}
+		dimensionOf[valueID] = dimensionID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytic dimension values: %w", err)
+	}
+	for _, t := range tags {
+		if dimensionOf[t.ValueID] != t.DimensionID {
+			return nil, fmt.Errorf("analytic dimension value %d not found", t.ValueID)
+		}
+	}
+
+	out := append([]models.AnalyticTag(nil), tags...)
+	sort.Slice(out, func(i, j int) bool { return out[i].DimensionID < out[j].DimensionID })
+	return out, nil
+}
+
+// mergeAnalyticTags returns base with any dimension set in override replaced.
+func mergeAnalyticTags(base, override []models.AnalyticTag) []models.AnalyticTag {
+	if len(override) == 0 {
+		return base
+	}
+	byDimension := map[int]int{}
+	for _, t := range base {
+		byDimension[t.DimensionID] = t.ValueID
+	}
+	for _, t := range override {
+		byDimension[t.DimensionID] = t.ValueID
+	}
+	out := make([]models.AnalyticTag, 0, len(byDimension))
+	for d, v := range byDimension {
+		out = append(out, models.AnalyticTag{DimensionID: d, ValueID: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DimensionID < out[j].DimensionID })
+	return out
+}
+
+// analyticSource is a document attribute a default can be keyed on.
+type analyticSource struct {
+	sourceType string
+	sourceID   int
+}
+
+// resolveAnalyticDefaults picks, per dimension, the default of the first
+// source that has one. Sources are given in precedence order.
+func resolveAnalyticDefaults(q sqlQueryer, companyID int, sources []analyticSource) ([]models.AnalyticTag, error) {
+	types := make([]string, 0, len(sources))
+	ids := make([]int64, 0, len(sources))
+	for _, src := range sources {
+		if src.sourceID > 0 {
+			types = append(types, src.sourceType)
+			ids = append(ids, int64(src.sourceID))
+		}
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+	rows, err := q.Query(`
+		SELECT DISTINCT ON (d.dimension_id) d.dimension_id, d.value_id
+		FROM analytic_dimension_defaults d
+		JOIN unnest($2::text[], $3::int[]) WITH ORDINALITY AS src(source_type, source_id, rank)
+		  ON src.source_type = d.source_type AND src.source_id = d.source_id
+		JOIN analytic_dimensions ad ON ad.dimension_id = d.dimension_id AND ad.is_active = TRUE
+		JOIN analytic_dimension_values v ON v.value_id = d.value_id AND v.is_active = TRUE
+		WHERE d.company_id = $1
+		ORDER BY d.dimension_id, src.rank
+	`, companyID, pq.Array(types), pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve analytic defaults: %w", err)
+	}
+	defer rows.Close()
+	var tags []models.AnalyticTag
+	for rows.Next() {
+		var t models.AnalyticTag
+		if err := rows.Scan(&t.DimensionID, &t.ValueID); err != nil {
+			return nil, fmt.Errorf("failed to scan analytic default: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analytic defaults: %w", err)
+	}
+	return tags, nil
+}
+
+func analyticTagArrays(tags []models.AnalyticTag) (interface{}, interface{}) {
+	dims := make([]int64, len(tags))
+	values := make([]int64, len(tags))
+	for i, t := range tags {
+		dims[i] = int64(t.DimensionID)
+		values[i] = int64(t.ValueID)
+	}
+	return pq.Array(dims), pq.Array(values)
+}
+
+// tagLedgerTransaction tags every ledger entry of a posted document. Existing
+// tags are kept, so re-running a posting is harmless.
+func tagLedgerTransaction(exec sqlExecutor, companyID int, transactionType string, transactionID int, tags []models.AnalyticTag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	dims, values := analyticTagArrays(tags)
+	if _, err := exec.Exec(`
+		INSERT INTO ledger_entry_dimensions (entry_id, company_id, dimension_id, value_id)
+		SELECT le.entry_id, $1, t.dimension_id, t.value_id
+		FROM ledger_entries le
+		CROSS JOIN unnest($4::int[], $5::int[]) AS t(dimension_id, value_id)
+		WHERE le.company_id = $1 AND le.transaction_type = $2 AND le.transaction_id = $3
+		ON CONFLICT (entry_id, dimension_id) DO NOTHING
+	`, companyID, transactionType, transactionID, dims, values); err != nil {
+		return fmt.Errorf("failed to tag ledger entries: %w", err)
+	}
+	return nil
+}
+
+// tagLedgerReference tags the ledger entry with the given posting reference.
+func tagLedgerReference(exec sqlExecutor, companyID int, reference string, tags []models.AnalyticTag) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	dims, values := analyticTagArrays(tags)
+	if _, err := exec.Exec(`
+		INSERT INTO ledger_entry_dimensions (entry_id, company_id, dimension_id, value_id)
+		SELECT le.entry_id, $1, t.dimension_id, t.value_id
+		FROM ledger_entries le
+		CROSS JOIN unnest($3::int[], $4::int[]) AS t(dimension_id, value_id)
+		WHERE le.company_id = $1 AND le.reference = $2
+		ON CONFLICT (entry_id, dimension_id) DO NOTHING
+	`, companyID, reference, dims, values); err != nil {
+		return fmt.Errorf("failed to tag ledger entry: %w", err)
+	}
+	return nil
+}
+
+// ledgerAnalyticSources lists the default sources of a posted document in
+// precedence order: expense category, employee, department, then location.
+func ledgerAnalyticSources(q sqlQueryRower, companyID int, transactionType string, transactionID int, locationID *int) ([]analyticSource, error) {
+	var sources []analyticSource
+	switch transactionType {
+	case "expense":
+		var categoryID int
+		var departmentID, expenseLocationID sql.NullInt64
+		err := q.QueryRow(`
+			SELECT e.category_id, e.department_id, e.location_id
+			FROM expenses e
+			JOIN expense_categories c ON c.category_id = e.category_id
+			WHERE e.expense_id = $1 AND c.company_id = $2
+		`, transactionID, companyID).Scan(&categoryID, &departmentID, &expenseLocationID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to load expense analytic sources: %w", err)
+		}
+		sources = append(sources,
+			analyticSource{models.AnalyticSourceExpenseCategory, categoryID},
+			analyticSource{models.AnalyticSourceDepartment, int(departmentID.Int64)},
+			analyticSource{models.AnalyticSourceLocation, int(expenseLocationID.Int64)},
+		)
+	case "payroll":
+		var employeeID int
+		var departmentID, employeeLocationID sql.NullInt64
+		err := q.QueryRow(`
+			SELECT e.employee_id, e.department_id, e.location_id
+			FROM payroll p
+			JOIN employees e ON e.employee_id = p.employee_id
+			WHERE p.payroll_id = $1 AND e.company_id = $2
+		`, transactionID, companyID).Scan(&employeeID, &departmentID, &employeeLocationID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to load payroll analytic sources: %w", err)
+		}
+		sources = append(sources,
+			analyticSource{models.AnalyticSourceEmployee, employeeID},
+			analyticSource{models.AnalyticSourceDepartment, int(departmentID.Int64)},
+			analyticSource{models.AnalyticSourceLocation, int(employeeLocationID.Int64)},
+		)
+	}
+	if locationID != nil {
+		sources = append(sources, analyticSource{models.AnalyticSourceLocation, *locationID})
+	}
+	return sources, nil
+}
+
+// ledgerDimensionSQL holds the pieces a ledger report splices in to filter
+// and group by analytic dimensions. The report's ledger_entries alias is le.
+type ledgerDimensionSQL struct {
+	where   string // extra conditions on le, starting with AND
+	join    string // joins exposing the grouping value as gv
+	columns string // select list entries, starting with a comma
+	groupBy string // group by entries, starting with a comma
+	args    []interface{}
+}
+
+// compileLedgerDimensionFilter builds the SQL for f with placeholders
+// numbered from next. Values of the same dimension are alternatives; values
+// of different dimensions must all match.
+func compileLedgerDimensionFilter(f models.LedgerDimensionFilter, next int) ledgerDimensionSQL {
+	var out ledgerDimensionSQL
+	if len(f.ValueIDs) > 0 {
+		ids := make([]int64, len(f.ValueIDs))
+		for i, id := range f.ValueIDs {
+			ids[i] = int64(id)
+		}
+		out.where = fmt.Sprintf(`
+		  AND (SELECT COUNT(DISTINCT led.dimension_id) FROM ledger_entry_dimensions led
+		       WHERE led.entry_id = le.entry_id AND led.value_id = ANY($%[1]d::int[]))
+		    = (SELECT COUNT(DISTINCT fv.dimension_id) FROM analytic_dimension_values fv
+		       WHERE fv.value_id = ANY($%[1]d::int[]))`, next)
+		out.args = append(out.args, pq.Array(ids))
+		next++
+	}
+	if f.GroupByDimensionID > 0 {
+		out.join = fmt.Sprintf(`
+		LEFT JOIN ledger_entry_dimensions gd ON gd.entry_id = le.entry_id AND gd.dimension_id = $%d
+		LEFT JOIN analytic_dimension_values gv ON gv.value_id = gd.value_id`, next)
+		out.columns = ", gv.code AS dimension_value_code, gv.name AS dimension_value_name"
+		out.groupBy = ", gv.code, gv.name"
+		out.args = append(out.args, f.GroupByDimensionID)
+	}
+	return out
+}
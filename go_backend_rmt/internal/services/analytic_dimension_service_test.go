@@ -0,0 +1,82 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"erp-backend/internal/models"
+)
+
+func TestCompileLedgerDimensionFilterNumbersPlaceholders(t *testing.T) {
+	empty := compileLedgerDimensionFilter(models.LedgerDimensionFilter{}, 5)
+	if empty.where != "" || empty.join != "" || empty.columns != "" || len(empty.args) != 0 {
+		t.Fatalf("expected no SQL for an empty filter, got %+v", empty)
+	}
+
+	sql := compileLedgerDimensionFilter(models.LedgerDimensionFilter{ValueIDs: []int{3, 7}, GroupByDimensionID: 2}, 5)
+	if !strings.Contains(sql.where, "ANY($5::int[])") || strings.Contains(sql.where, "$6") {
+		t.Fatalf("expected filter on $5, got %s", sql.where)
+	}
+	if !strings.Contains(sql.join, "gd.dimension_id = $6") {
+		t.Fatalf("expected grouping on $6, got %s", sql.join)
+	}
+	if len(sql.args) != 2 || sql.args[1] != 2 {
+		t.Fatalf("unexpected args %v", sql.args)
+	}
+
+	grouped := compileLedgerDimensionFilter(models.LedgerDimensionFilter{GroupByDimensionID: 4}, 4)
+	if grouped.where != "" || !strings.Contains(grouped.join, "$4") || len(grouped.args) != 1 {
+		t.Fatalf("unexpected grouping-only SQL %+v", grouped)
+	}
+}
+
+func TestMergeAnalyticTagsOverridesPerDimension(t *testing.T) {
+	base := []models.AnalyticTag{{DimensionID: 2, ValueID: 20}, {DimensionID: 1, ValueID: 10}}
+	override := []models.AnalyticTag{{DimensionID: 2, ValueID: 21}, {DimensionID: 3, ValueID: 30}}
+
+	got := mergeAnalyticTags(base, override)
+	want := []models.AnalyticTag{{DimensionID: 1, ValueID: 10}, {DimensionID: 2, ValueID: 21}, {DimensionID: 3, ValueID: 30}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if same := mergeAnalyticTags(base, nil); len(same) != 2 || same[0] != base[0] {
+		t.Fatalf("expected base unchanged without overrides, got %v", same)
+	}
+}
+
+func TestValidateAnalyticTagsRejectsMismatchedDimension(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := validateAnalyticTags(db, 1, []models.AnalyticTag{{DimensionID: 1, ValueID: 10}, {DimensionID: 1, ValueID: 11}}); err == nil {
+		t.Fatalf("expected two values for one dimension to be rejected")
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM analytic_dimension_values v")).
+		WillReturnRows(sqlmock.NewRows([]string{"value_id", "dimension_id"}).AddRow(10, 1).AddRow(20, 3))
+	_, err = validateAnalyticTags(db, 1, []models.AnalyticTag{{DimensionID: 1, ValueID: 10}, {DimensionID: 2, ValueID: 20}})
+	if err == nil || err.Error() != "analytic dimension value 20 not found" {
+		t.Fatalf("expected value 20 to be rejected for dimension 2, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM analytic_dimension_values v")).
+		WillReturnRows(sqlmock.NewRows([]string{"value_id", "dimension_id"}).AddRow(10, 1).AddRow(20, 2))
+	tags, err := validateAnalyticTags(db, 1, []models.AnalyticTag{{DimensionID: 2, ValueID: 20}, {DimensionID: 1, ValueID: 10}})
+	if err != nil || len(tags) != 2 || tags[0].DimensionID != 1 {
+		t.Fatalf("expected sorted valid tags, got %v %v", tags, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+const defaultAPIKeyGrace = 24 * time.Hour
+
+type APIKeyService struct {
+	db *sql.DB
+}
+
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{db: database.GetDB()}
+}
+
+const apiKeySelect = `
+	SELECT api_key_id, company_id, user_id, name, key_prefix, permissions, location_id, ip_allowlist,
+	       rate_limit_per_minute, expires_at, previous_key_expires_at, last_used_at, last_used_ip,
+	       revoked_at, revoked_by, created_by, updated_by, created_at, updated_at
+	FROM api_keys
+`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var k models.APIKey
+	var permissions, allowlist pq.StringArray
+	if err := row.Scan(&k.APIKeyID, &k.CompanyID, &k.UserID, &k.Name, &k.Prefix, &permissions, &k.LocationID, &allowlist,
+		&k.RateLimitPerMinute, &k.ExpiresAt, &k.PreviousKeyExpiresAt, &k.LastUsedAt, &k.LastUsedIP,
+		&k.RevokedAt, &k.RevokedBy, &k.CreatedBy, &k.UpdatedBy, &k.CreatedAt, &k.UpdatedAt); err != nil {
+		return nil, err
+	}
+	k.Permissions = []string(permissions)
+	k.IPAllowlist = []string(allowlist)
+	if k.Permissions == nil {
+		k.Permissions = []string{}
+	}
+	if k.IPAllowlist == nil {
+		k.IPAllowlist = []string{}
+	}
+	now := time.Now()
+	if k.PreviousKeyExpiresAt != nil && !k.PreviousKeyExpiresAt.After(now) {
+		k.PreviousKeyExpiresAt = nil
+	}
+	switch {
+	case k.RevokedAt != nil:
+		k.Status = models.APIKeyStatusRevoked
+	case k.ExpiresAt != nil && !k.ExpiresAt.After(now):
+		k.Status = models.APIKeyStatusExpired
+	default:
+		k.Status = models.APIKeyStatusActive
+	}
+	return &k, nil
+}
+
+func (s *APIKeyService) ListAPIKeys(companyID int) ([]models.APIKey, error) {
+	rows, err := s.db.Query(apiKeySelect+`
+		WHERE company_id = $1
+		ORDER BY revoked_at IS NOT NULL, name, api_key_id
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *APIKeyService) GetAPIKey(companyID, apiKeyID int) (*models.APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRow(apiKeySelect+`
+		WHERE api_key_id = $1 AND company_id = $2
+	`, apiKeyID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return k, nil
+}
+
+// CreateAPIKey creates the key and its service account. The returned key is
+// the only time the secret is shown. The creator can only grant permissions
+// they hold under roleID, the role of their active location.
+func (s *APIKeyService) CreateAPIKey(companyID, userID, roleID int, req *models.CreateAPIKeyRequest) (*models.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	permissions, err := s.grantablePermissions(userID, roleID, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	allowlist, err := normalizeIPAllowlist(req.IPAllowlist)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+	if req.LocationID != nil {
+		if err := s.validateLocation(companyID, *req.LocationID); err != nil {
+			return nil, err
+		}
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The service account cannot log in: "!" is never a valid password hash.
+	var serviceUserID int
+	if err := tx.QueryRow(`
+		INSERT INTO users (company_id, location_id, username, email, password_hash, first_name, last_name,
+		                   max_allowed_devices, is_active)
+		VALUES ($1, $2, $3, $4, '!', $5, 'API key', 0, TRUE)
+		RETURNING user_id
+	`, companyID, req.LocationID, "api-key:"+prefix, prefix+"@api-keys.invalid", name).Scan(&serviceUserID); err != nil {
+		return nil, fmt.Errorf("failed to create api key service account: %w", err)
+	}
+
+	var id int
+	if err := tx.QueryRow(`
+		INSERT INTO api_keys (company_id, user_id, name, key_prefix, key_hash, permissions, location_id,
+		                      ip_allowlist, rate_limit_per_minute, expires_at, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+		RETURNING api_key_id
+	`, companyID, serviceUserID, name, prefix, utils.HashAPIKey(key), pq.Array(permissions), req.LocationID,
+		pq.Array(allowlist), req.RateLimitPerMinute, req.ExpiresAt, userID).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	changes := models.JSONB{"name": name, "prefix": prefix, "permissions": permissions}
+	if err := LogAudit(tx, "CREATE", "api_keys", &id, &userID, nil, nil, &changes, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	k, err := s.GetAPIKey(companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	k.Key = key
+	return k, nil
+}
+
+func (s *APIKeyService) UpdateAPIKey(companyID, userID, roleID, apiKeyID int, req *models.UpdateAPIKeyRequest) (*models.APIKey, error) {
+	current, err := s.GetAPIKey(companyID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		return nil, fmt.Errorf("api key is revoked")
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	changes := models.JSONB{}
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		add("name", name)
+		changes["name"] = name
+	}
+	if req.Permissions != nil {
+		permissions, err := s.grantablePermissions(userID, roleID, *req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		add("permissions", pq.Array(permissions))
+		changes["permissions"] = permissions
+	}
+	locationChanged := false
+	var location *int
+	if req.ClearLocation {
+		add("location_id", nil)
+		locationChanged = true
+	} else if req.LocationID != nil {
+		if err := s.validateLocation(companyID, *req.LocationID); err != nil {
+			return nil, err
+		}
+		location = req.LocationID
+		add("location_id", *req.LocationID)
+		locationChanged = true
+	}
+	if locationChanged {
+		changes["location_id"] = location
+	}
+	if req.IPAllowlist != nil {
+		allowlist, err := normalizeIPAllowlist(*req.IPAllowlist)
+		if err != nil {
+			return nil, err
+		}
+		add("ip_allowlist", pq.Array(allowlist))
+		changes["ip_allowlist"] = allowlist
+	}
+	if req.ClearRateLimit {
+		add("rate_limit_per_minute", nil)
+		changes["rate_limit_per_minute"] = nil
+	} else if req.RateLimitPerMinute != nil {
+		add("rate_limit_per_minute", *req.RateLimitPerMinute)
+		changes["rate_limit_per_minute"] = *req.RateLimitPerMinute
+	}
+	if req.ClearExpiry {
+		add("expires_at", nil)
+		changes["expires_at"] = nil
+	} else if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("expires_at must be in the future")
+		}
+		add("expires_at", *req.ExpiresAt)
+		changes["expires_at"] = *req.ExpiresAt
+	}
+	if len(sets) == 0 {
+		return current, nil
+	}
+	add("updated_by", userID)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args = append(args, apiKeyID, companyID)
+	if _, err := tx.Exec(fmt.Sprintf(`
+		UPDATE api_keys SET %s, updated_at = CURRENT_TIMESTAMP
+		WHERE api_key_id = $%d AND company_id = $%d
+	`, strings.Join(sets, ", "), len(args)-1, len(args)), args...); err != nil {
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+	if req.Name != nil || locationChanged {
+		if _, err := tx.Exec(`
+			UPDATE users
+			SET first_name = COALESCE($2, first_name),
+			    location_id = CASE WHEN $3 THEN $4 ELSE location_id END,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1
+		`, current.UserID, changes["name"], locationChanged, location); err != nil {
+			return nil, fmt.Errorf("failed to update api key service account: %w", err)
+		}
+	}
+	if err := LogAudit(tx, "UPDATE", "api_keys", &apiKeyID, &userID, nil, nil, &changes, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetAPIKey(companyID, apiKeyID)
+}
+
+// RotateAPIKey issues a new secret under the same prefix and service
+// account. The old secret keeps working for the grace period (24 hours
+// unless set) so the integration can switch without downtime.
+func (s *APIKeyService) RotateAPIKey(companyID, userID, apiKeyID int, req *models.RotateAPIKeyRequest) (*models.APIKey, error) {
+	current, err := s.GetAPIKey(companyID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		return nil, fmt.Errorf("api key is revoked")
+	}
+	grace := defaultAPIKeyGrace
+	if req != nil && req.GraceHours != nil {
+		grace = time.Duration(*req.GraceHours) * time.Hour
+	}
+	var previousExpires *time.Time
+	if grace > 0 {
+		t := time.Now().Add(grace)
+		previousExpires = &t
+	}
+
+	generated, _, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	// Keep the prefix so the integration and its logs still identify the key.
+	key := current.Prefix + generated[strings.LastIndex(generated, "_"):]
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE api_keys
+		SET previous_key_hash = CASE WHEN $3::timestamp IS NULL THEN NULL ELSE key_hash END,
+		    previous_key_expires_at = $3,
+		    key_hash = $4,
+		    updated_by = $5,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE api_key_id = $1 AND company_id = $2
+	`, apiKeyID, companyID, previousExpires, utils.HashAPIKey(key), userID); err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+	changes := models.JSONB{"previous_key_expires_at": previousExpires}
+	if err := LogAudit(tx, "ROTATE", "api_keys", &apiKeyID, &userID, nil, nil, &changes, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	k, err := s.GetAPIKey(companyID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	k.Key = key
+	return k, nil
+}
+
+// RevokeAPIKey stops the key at once and deactivates its service account.
+// The key stays listed so its audit trail keeps a name.
+func (s *APIKeyService) RevokeAPIKey(companyID, userID, apiKeyID int) (*models.APIKey, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var serviceUserID int
+	var revokedAt *time.Time
+	err = tx.QueryRow(`
+		SELECT user_id, revoked_at FROM api_keys
+		WHERE api_key_id = $1 AND company_id = $2
+		FOR UPDATE
+	`, apiKeyID, companyID).Scan(&serviceUserID, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if revokedAt != nil {
+		return nil, fmt.Errorf("api key is already revoked")
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE api_keys
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = $2, previous_key_hash = NULL,
+		    previous_key_expires_at = NULL, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE api_key_id = $1
+	`, apiKeyID, userID); err != nil {
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE users SET is_active = FALSE, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1
+	`, serviceUserID); err != nil {
+		return nil, fmt.Errorf("failed to deactivate api key service account: %w", err)
+	}
+	if err := LogAudit(tx, "REVOKE", "api_keys", &apiKeyID, &userID, nil, nil, nil, nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetAPIKey(companyID, apiKeyID)
+}
+
+// grantablePermissions checks that every requested code exists and that the
+// granting user holds it under roleID (0 resolves the role of their default
+// location, as in UserCan), so a key can never do more than its creator.
+func (s *APIKeyService) grantablePermissions(userID, roleID int, requested []string) ([]string, error) {
+	permissions := []string{}
+	seen := map[string]bool{}
+	for _, p := range requested {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		permissions = append(permissions, p)
+	}
+	if len(permissions) == 0 {
+		return nil, fmt.Errorf("at least one permission is required")
+	}
+
+	rows, err := s.db.Query(`
+		WITH active AS (
+			SELECT COALESCE(NULLIF($3::int, 0), (
+			         SELECT ul.role_id
+			         FROM user_locations ul
+			         JOIN locations l ON l.location_id = ul.location_id
+			         WHERE ul.user_id = u.user_id AND l.company_id = u.company_id
+			           AND COALESCE(l.is_active, TRUE) = TRUE
+			         ORDER BY ul.location_id = u.location_id DESC, ul.location_id
+			         LIMIT 1
+			       ), u.role_id) AS role_id
+			FROM users u
+			WHERE u.user_id = $2 AND u.is_active = TRUE AND u.is_deleted = FALSE
+		)
+		SELECT p.name,
+		       EXISTS (
+		         SELECT 1 FROM active a
+		         JOIN roles r ON r.role_id = a.role_id
+		         WHERE LOWER(r.name) IN ('super admin', 'admin')
+		       ) OR EXISTS (
+		         SELECT 1 FROM active a
+		         JOIN role_permissions rp ON rp.role_id = a.role_id
+		         WHERE rp.permission_id = p.permission_id
+		       )
+		FROM permissions p
+		WHERE p.name = ANY($1)
+	`, pq.Array(permissions), userID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	defer rows.Close()
+	held := map[string]bool{}
+	known := map[string]bool{}
+	for rows.Next() {
+		var name string
+		var has bool
+		if err := rows.Scan(&name, &has); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		known[name] = true
+		held[name] = has
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	for _, p := range permissions {
+		if !known[p] {
+			return nil, fmt.Errorf("unknown permission %s", p)
+		}
+		if !held[p] {
+			return nil, fmt.Errorf("cannot grant permission %s you do not have", p)
+		}
+	}
+	return permissions, nil
+}
+
+func (s *APIKeyService) validateLocation(companyID, locationID int) error {
+	var exists bool
+	if err := s.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)
+	`, locationID, companyID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check location: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("location not found")
+	}
+	return nil
+}
+
+// normalizeIPAllowlist accepts single addresses and CIDR ranges.
+func normalizeIPAllowlist(entries []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var normalized string
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ip_allowlist entry %s", entry)
+			}
+			normalized = network.String()
+		} else {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip_allowlist entry %s", entry)
+			}
+			normalized = ip.String()
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			out = append(out, normalized)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeIPAllowlist(t *testing.T) {
+	list, err := normalizeIPAllowlist([]string{" 10.0.0.7/24 ", "203.0.113.7", "", "10.0.0.0/24"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0] != "10.0.0.0/24" || list[1] != "203.0.113.7" {
+		t.Fatalf("unexpected allowlist %v", list)
+	}
+	if _, err := normalizeIPAllowlist([]string{"10.0.0.300"}); err == nil || err.Error() != "invalid ip_allowlist entry 10.0.0.300" {
+		t.Fatalf("expected invalid entry error, got %v", err)
+	}
+}
+
+func TestAPIKeyService_GrantablePermissions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	svc := &APIKeyService{db: db}
+
+	if _, err := svc.grantablePermissions(2, 5, []string{" ", ""}); err == nil || err.Error() != "at least one permission is required" {
+		t.Fatalf("expected permission required error, got %v", err)
+	}
+
+	mock.ExpectQuery("(?s)WITH active AS.*NULLIF\\(\\$3::int, 0\\).*SELECT p.name,.*FROM permissions p\\s+WHERE p.name = ANY\\(\\$1\\)").
+		WithArgs(`{"VIEW_SALES","CREATE_SALES"}`, 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "has"}).
+			AddRow("VIEW_SALES", true).
+			AddRow("CREATE_SALES", false))
+	if _, err := svc.grantablePermissions(2, 5, []string{"view_sales", "CREATE_SALES", "VIEW_SALES"}); err == nil || err.Error() != "cannot grant permission CREATE_SALES you do not have" {
+		t.Fatalf("expected escalation error, got %v", err)
+	}
+
+	mock.ExpectQuery("FROM permissions p").
+		WithArgs(`{"VIEW_SALES","NOPE"}`, 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "has"}).AddRow("VIEW_SALES", true))
+	if _, err := svc.grantablePermissions(2, 5, []string{"VIEW_SALES", "nope"}); err == nil || err.Error() != "unknown permission NOPE" {
+		t.Fatalf("expected unknown permission error, got %v", err)
+	}
+
+	mock.ExpectQuery("FROM permissions p").
+		WithArgs(`{"VIEW_SALES"}`, 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "has"}).AddRow("VIEW_SALES", true))
+	perms, err := svc.grantablePermissions(2, 5, []string{"VIEW_SALES"})
+	if err != nil || len(perms) != 1 || perms[0] != "VIEW_SALES" {
+		t.Fatalf("unexpected result %v, %v", perms, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
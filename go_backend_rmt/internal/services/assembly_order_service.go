@@ -0,0 +1,460 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AssemblyOrderService turns components into finished goods and back. Stock
+// moves through IssueStockTx/ReceiveStockTx so lots, serials and costing
+// behave as for any other movement; the finished good's lot carries the
+// component cost plus labor and overhead.
+type AssemblyOrderService struct {
+	db         *sql.DB
+	bomService *BillOfMaterialsService
+}
+
+func NewAssemblyOrderService() *AssemblyOrderService {
+	db := database.GetDB()
+	return &AssemblyOrderService{db: db, bomService: &BillOfMaterialsService{db: db}}
+}
+
+func (s *AssemblyOrderService) CreateAssemblyOrder(companyID, locationID, userID int, req *models.CreateAssemblyOrderRequest) (*models.AssemblyOrder, error) {
+	if req.BOMID == nil && req.ProductID == nil {
+		return nil, fmt.Errorf("bom_id or product_id is required")
+	}
+	var expiryDate *time.Time
+	if req.ExpiryDate != nil && strings.TrimSpace(*req.ExpiryDate) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(*req.ExpiryDate))
+		if err != nil {
+			return nil, fmt.Errorf("expiry_date must be YYYY-MM-DD")
+		}
+		expiryDate = &parsed
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locationOK bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)`, locationID, companyID).Scan(&locationOK); err != nil {
+		return nil, fmt.Errorf("failed to verify location: %w", err)
+	}
+	if !locationOK {
+		return nil, fmt.Errorf("location not found")
+	}
+
+	bomID := 0
+	if req.BOMID != nil {
+		bomID = *req.BOMID
+	} else {
+		err := tx.QueryRow(`
+			SELECT bom_id
+			FROM bill_of_materials
+			WHERE company_id = $1 AND product_id = $2 AND is_active = TRUE
+			ORDER BY barcode_id NULLS FIRST
+			LIMIT 1
+		`, companyID, *req.ProductID).Scan(&bomID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("product has no active bill of materials")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find active bill of materials: %w", err)
+		}
+	}
+	bom, err := s.bomService.getBOM(tx, companyID, bomID)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := req.Quantity / bom.OutputQuantity
+	laborCost := round2(bom.LaborCost * ratio)
+	if req.LaborCost != nil {
+		laborCost = round2(*req.LaborCost)
+	}
+	overheadCost := round2(bom.OverheadCost * ratio)
+	if req.OverheadCost != nil {
+		overheadCost = round2(*req.OverheadCost)
+	}
+
+	ns := NewNumberingSequenceService()
+	orderNumber, err := ns.NextNumber(tx, "assembly_order", companyID, &locationID)
+	if err != nil {
+		orderNumber = fmt.Sprintf("ASM-%d", time.Now().Unix())
+	}
+
+	var orderID int
+	if err := tx.QueryRow(`
+		INSERT INTO assembly_orders (
+			company_id, location_id, order_number, order_type, bom_id, product_id, barcode_id,
+			quantity, labor_cost, overhead_cost, batch_number, expiry_date, notes, created_by
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		RETURNING assembly_order_id
+	`, companyID, locationID, orderNumber, req.OrderType, bom.BOMID, bom.ProductID, bom.BarcodeID,
+		req.Quantity, laborCost, overheadCost, req.BatchNumber, expiryDate, req.Notes, userID,
+	).Scan(&orderID); err != nil {
+		return nil, fmt.Errorf("failed to create assembly order: %w", err)
+	}
+
+	for _, component := range bom.Components {
+		quantity, scrap := bomComponentRequirement(component, bom.OutputQuantity, req.Quantity)
+		if req.OrderType == models.AssemblyOrderTypeDisassembly {
+			// Scrap is lost when building, not recovered when breaking down.
+			quantity, scrap = round3(quantity-scrap), 0
+		}
+		if quantity <= 0 {
+			return nil, fmt.Errorf("component %s rounds to zero for this quantity", component.ProductName)
+		}
+		componentID := component.BOMComponentID
+		if _, err := tx.Exec(`
+			INSERT INTO assembly_order_lines (assembly_order_id, bom_component_id, product_id, barcode_id, quantity, scrap_quantity)
+			VALUES ($1,$2,$3,$4,$5,$6)
+		`, orderID, componentID, component.ProductID, component.BarcodeID, quantity, scrap); err != nil {
+			return nil, fmt.Errorf("failed to create assembly order line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit assembly order: %w", err)
+	}
+	return s.GetAssemblyOrder(companyID, orderID)
+}
+
+// CompleteAssemblyOrder moves the stock and fixes the order's costs. For an
+// assembly the finished good is received at component cost plus labor and
+// overhead; for a disassembly that total is spread over the recovered
+// components in proportion to their standard cost.
+func (s *AssemblyOrderService) CompleteAssemblyOrder(companyID, orderID, userID int, req *models.CompleteAssemblyOrderRequest) (*models.AssemblyOrder, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	order, err := s.getAssemblyOrder(tx, companyID, orderID, true)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.AssemblyOrderStatusDraft {
+		return nil, fmt.Errorf("only draft assembly orders can be completed")
+	}
+
+	lineSerials := make(map[int][]string, len(req.Lines))
+	for _, input := range req.Lines {
+		lineSerials[input.AssemblyOrderLineID] = input.SerialNumbers
+	}
+	for lineID := range lineSerials {
+		found := false
+		for _, line := range order.Lines {
+			if line.AssemblyOrderLineID == lineID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("assembly order line %d not found", lineID)
+		}
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	sourceRef := order.OrderNumber
+	conversionCost := round2(order.LaborCost + order.OverheadCost)
+	inputCost := 0.0
+	outputCost := 0.0
+	var outputBarcodeIDs []int
+
+	if order.OrderType == models.AssemblyOrderTypeAssembly {
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			lineID := line.AssemblyOrderLineID
+			issued, err := trackingSvc.IssueStockTx(tx, companyID, order.LocationID, userID, "ASSEMBLY_CONSUME", "assembly_order_line", &lineID, &sourceRef, inventorySelection{
+				ProductID:        line.ProductID,
+				BarcodeID:        line.BarcodeID,
+				Quantity:         line.Quantity,
+				SerialNumbers:    lineSerials[lineID],
+				Notes:            order.Notes,
+				OverridePassword: req.OverridePassword,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to consume %s: %w", line.ProductName, err)
+			}
+			barcodeID := issued.BarcodeID
+			line.BarcodeID = &barcodeID
+			line.UnitCost = round4(issued.UnitCost)
+			line.TotalCost = round2(issued.TotalCost)
+			line.SerialNumbers = lineSerials[lineID]
+			inputCost += line.TotalCost
+		}
+		inputCost = round2(inputCost)
+		outputCost = round2(inputCost + conversionCost)
+
+		output, err := trackingSvc.ReceiveStockTx(tx, companyID, order.LocationID, userID, "ASSEMBLY_OUTPUT", "assembly_order", &order.AssemblyOrderID, &sourceRef, inventorySelection{
+			ProductID:     order.ProductID,
+			BarcodeID:     order.BarcodeID,
+			Quantity:      order.Quantity,
+			SerialNumbers: req.SerialNumbers,
+			BatchNumber:   order.BatchNumber,
+			ExpiryDate:    order.ExpiryDate,
+			UnitCost:      round4(outputCost / order.Quantity),
+			Notes:         order.Notes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to receive finished good: %w", err)
+		}
+		outputBarcodeIDs = append(outputBarcodeIDs, output.BarcodeID)
+	} else {
+		issued, err := trackingSvc.IssueStockTx(tx, companyID, order.LocationID, userID, "DISASSEMBLY_CONSUME", "assembly_order", &order.AssemblyOrderID, &sourceRef, inventorySelection{
+			ProductID:        order.ProductID,
+			BarcodeID:        order.BarcodeID,
+			Quantity:         order.Quantity,
+			SerialNumbers:    req.SerialNumbers,
+			Notes:            order.Notes,
+			OverridePassword: req.OverridePassword,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to consume finished good: %w", err)
+		}
+		inputCost = round2(issued.TotalCost)
+		outputCost = round2(inputCost + conversionCost)
+
+		variants := make([]*resolvedVariant, len(order.Lines))
+		weights := make([]float64, len(order.Lines))
+		for i, line := range order.Lines {
+			variant, err := trackingSvc.resolveVariantTx(tx, companyID, line.ProductID, line.BarcodeID)
+			if err != nil {
+				return nil, err
+			}
+			variants[i] = variant
+			weights[i] = line.Quantity * variant.DefaultCostPrice
+		}
+		allocations := distributeWeightedAmount(outputCost, weights)
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			lineID := line.AssemblyOrderLineID
+			barcodeID := variants[i].BarcodeID
+			line.BarcodeID = &barcodeID
+			line.TotalCost = allocations[i]
+			line.UnitCost = round4(allocations[i] / line.Quantity)
+			line.SerialNumbers = lineSerials[lineID]
+			if _, err := trackingSvc.ReceiveStockTx(tx, companyID, order.LocationID, userID, "DISASSEMBLY_OUTPUT", "assembly_order_line", &lineID, &sourceRef, inventorySelection{
+				ProductID:     line.ProductID,
+				BarcodeID:     line.BarcodeID,
+				Quantity:      line.Quantity,
+				SerialNumbers: line.SerialNumbers,
+				UnitCost:      line.UnitCost,
+				Notes:         order.Notes,
+			}); err != nil {
+				return nil, fmt.Errorf("failed to receive %s: %w", line.ProductName, err)
+			}
+			outputBarcodeIDs = append(outputBarcodeIDs, barcodeID)
+		}
+	}
+
+	// Serve waiting sales order backorders before the output becomes free
+	// stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, order.LocationID, outputBarcodeIDs); err != nil {
+		return nil, err
+	}
+
+	for _, line := range order.Lines {
+		if _, err := tx.Exec(`
+			UPDATE assembly_order_lines
+			SET barcode_id = $1, unit_cost = $2, total_cost = $3, serial_numbers = $4
+			WHERE assembly_order_line_id = $5
+		`, line.BarcodeID, line.UnitCost, line.TotalCost, pq.Array(line.SerialNumbers), line.AssemblyOrderLineID); err != nil {
+			return nil, fmt.Errorf("failed to update assembly order line: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE assembly_orders
+		SET status = $1,
+		    input_cost = $2,
+		    output_cost = $3,
+		    serial_numbers = $4,
+		    completed_by = $5,
+		    completed_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE assembly_order_id = $6
+	`, models.AssemblyOrderStatusCompleted, inputCost, outputCost, pq.Array(req.SerialNumbers), userID, order.AssemblyOrderID); err != nil {
+		return nil, fmt.Errorf("failed to complete assembly order: %w", err)
+	}
+
+	financeSvc := NewFinanceIntegrityServiceWithDB(s.db)
+	if outputCost > 0 {
+		if err := financeSvc.EnqueueTx(tx, &models.FinanceOutboxEntry{
+			CompanyID:     companyID,
+			LocationID:    &order.LocationID,
+			EventType:     financeEventLedgerAssembly,
+			AggregateType: "assembly_order",
+			AggregateID:   order.AssemblyOrderID,
+			Payload:       models.JSONB{},
+			CreatedBy:     &userID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit assembly order: %w", err)
+	}
+
+	if err := financeSvc.ProcessAggregate(companyID, "assembly_order", order.AssemblyOrderID); err != nil {
+		log.Printf("warning: failed to process finance outbox for assembly order %d: %v", order.AssemblyOrderID, err)
+	}
+	return s.GetAssemblyOrder(companyID, order.AssemblyOrderID)
+}
+
+func (s *AssemblyOrderService) CancelAssemblyOrder(companyID, orderID int) error {
+	res, err := s.db.Exec(`
+		UPDATE assembly_orders
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE assembly_order_id = $2 AND company_id = $3 AND status = $4
+	`, models.AssemblyOrderStatusCancelled, orderID, companyID, models.AssemblyOrderStatusDraft)
+	if err != nil {
+		return fmt.Errorf("failed to cancel assembly order: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if _, err := s.GetAssemblyOrder(companyID, orderID); err != nil {
+			return err
+		}
+		return fmt.Errorf("only draft assembly orders can be cancelled")
+	}
+	return nil
+}
+
+func (s *AssemblyOrderService) GetAssemblyOrders(companyID int, locationID *int, status, orderType string) ([]models.AssemblyOrder, error) {
+	args := []interface{}{companyID}
+	query := `
+		SELECT ao.assembly_order_id, ao.company_id, ao.location_id, ao.order_number, ao.order_type,
+		       ao.status, ao.bom_id, b.version, ao.product_id, p.name, ao.barcode_id,
+		       ao.quantity::float8, ao.labor_cost::float8, ao.overhead_cost::float8,
+		       ao.input_cost::float8, ao.output_cost::float8, ao.batch_number, ao.expiry_date,
+		       ao.serial_numbers, ao.notes, ao.created_by, ao.completed_by, ao.completed_at, ao.created_at
+		FROM assembly_orders ao
+		JOIN bill_of_materials b ON b.bom_id = ao.bom_id
+		JOIN products p ON p.product_id = ao.product_id
+		WHERE ao.company_id = $1
+	`
+	if locationID != nil {
+		args = append(args, *locationID)
+		query += fmt.Sprintf(" AND ao.location_id = $%d", len(args))
+	}
+	if status != "" {
+		args = append(args, strings.ToUpper(status))
+		query += fmt.Sprintf(" AND ao.status = $%d", len(args))
+	}
+	if orderType != "" {
+		args = append(args, strings.ToUpper(orderType))
+		query += fmt.Sprintf(" AND ao.order_type = $%d", len(args))
+	}
+	query += " ORDER BY ao.created_at DESC, ao.assembly_order_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assembly orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := make([]models.AssemblyOrder, 0)
+	for rows.Next() {
+		order, err := scanAssemblyOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func (s *AssemblyOrderService) GetAssemblyOrder(companyID, orderID int) (*models.AssemblyOrder, error) {
+	return s.getAssemblyOrder(s.db, companyID, orderID, false)
+}
+
+func (s *AssemblyOrderService) getAssemblyOrder(q rowsQueryer, companyID, orderID int, forUpdate bool) (*models.AssemblyOrder, error) {
+	query := `
+		SELECT ao.assembly_order_id, ao.company_id, ao.location_id, ao.order_number, ao.order_type,
+		       ao.status, ao.bom_id, b.version, ao.product_id, p.name, ao.barcode_id,
+		       ao.quantity::float8, ao.labor_cost::float8, ao.overhead_cost::float8,
+		       ao.input_cost::float8, ao.output_cost::float8, ao.batch_number, ao.expiry_date,
+		       ao.serial_numbers, ao.notes, ao.created_by, ao.completed_by, ao.completed_at, ao.created_at
+		FROM assembly_orders ao
+		JOIN bill_of_materials b ON b.bom_id = ao.bom_id
+		JOIN products p ON p.product_id = ao.product_id
+		WHERE ao.assembly_order_id = $1 AND ao.company_id = $2
+	`
+	if forUpdate {
+		query += " FOR UPDATE OF ao"
+	}
+	order, err := scanAssemblyOrder(q.QueryRow(query, orderID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("assembly order not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT aol.assembly_order_line_id, aol.bom_component_id, aol.product_id, p.name, aol.barcode_id,
+		       aol.quantity::float8, aol.scrap_quantity::float8, aol.unit_cost::float8,
+		       aol.total_cost::float8, aol.serial_numbers
+		FROM assembly_order_lines aol
+		JOIN products p ON p.product_id = aol.product_id
+		WHERE aol.assembly_order_id = $1
+		ORDER BY aol.assembly_order_line_id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assembly order lines: %w", err)
+	}
+	defer rows.Close()
+
+	order.Lines = make([]models.AssemblyOrderLine, 0)
+	for rows.Next() {
+		var line models.AssemblyOrderLine
+		var serials pq.StringArray
+		if err := rows.Scan(
+			&line.AssemblyOrderLineID, &line.BOMComponentID, &line.ProductID, &line.ProductName, &line.BarcodeID,
+			&line.Quantity, &line.ScrapQuantity, &line.UnitCost,
+			&line.TotalCost, &serials,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan assembly order line: %w", err)
+		}
+		line.SerialNumbers = []string(serials)
+		order.Lines = append(order.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read assembly order lines: %w", err)
+	}
+	return order, nil
+}
+
+func scanAssemblyOrder(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.AssemblyOrder, error) {
+	var order models.AssemblyOrder
+	var serials pq.StringArray
+	if err := row.Scan(
+		&order.AssemblyOrderID, &order.CompanyID, &order.LocationID, &order.OrderNumber, &order.OrderType,
+		&order.Status, &order.BOMID, &order.BOMVersion, &order.ProductID, &order.ProductName, &order.BarcodeID,
+		&order.Quantity, &order.LaborCost, &order.OverheadCost,
+		&order.InputCost, &order.OutputCost, &order.BatchNumber, &order.ExpiryDate,
+		&serials, &order.Notes, &order.CreatedBy, &order.CompletedBy, &order.CompletedAt, &order.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan assembly order: %w", err)
+	}
+	order.SerialNumbers = []string(serials)
+	return &order, nil
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+// attachmentEntity describes a record type that can carry attachments: the
+// permissions needed to read and change its files, and how to check that a
+// record belongs to the company ($1 entity id, $2 company id).
+type attachmentEntity struct {
+	viewPermission   string
+	managePermission string
+	ownerQuery       string
+}
+
+var attachmentEntities = map[string]attachmentEntity{
+	models.AttachmentEntitySale: {
+		viewPermission:   "VIEW_SALES",
+		managePermission: "UPDATE_SALES",
+		ownerQuery: `SELECT 1 FROM sales s JOIN locations l ON l.location_id = s.location_id
+			WHERE s.sale_id = $1 AND l.company_id = $2 AND s.is_deleted = FALSE`,
+	},
+	models.AttachmentEntityPurchase: {
+		viewPermission:   "VIEW_PURCHASES",
+		managePermission: "UPDATE_PURCHASES",
+		ownerQuery: `SELECT 1 FROM purchases p JOIN locations l ON l.location_id = p.location_id
+			WHERE p.purchase_id = $1 AND l.company_id = $2 AND p.is_deleted = FALSE`,
+	},
+	models.AttachmentEntityExpense: {
+		viewPermission:   "VIEW_EXPENSES",
+		managePermission: "UPDATE_EXPENSES",
+		ownerQuery: `SELECT 1 FROM expenses e JOIN locations l ON l.location_id = e.location_id
+			WHERE e.expense_id = $1 AND l.company_id = $2 AND e.is_deleted = FALSE`,
+	},
+	models.AttachmentEntityVoucher: {
+		viewPermission:   "VIEW_VOUCHERS",
+		managePermission: "MANAGE_VOUCHERS",
+		ownerQuery:       `SELECT 1 FROM vouchers WHERE voucher_id = $1 AND company_id = $2 AND is_deleted = FALSE`,
+	},
+	models.AttachmentEntityEmployee: {
+		viewPermission:   "VIEW_EMPLOYEES",
+		managePermission: "UPDATE_EMPLOYEES",
+		ownerQuery:       `SELECT 1 FROM employees WHERE employee_id = $1 AND company_id = $2 AND is_deleted = FALSE`,
+	},
+	models.AttachmentEntityAsset: {
+		viewPermission:   "VIEW_INVENTORY",
+		managePermission: "ADJUST_STOCK",
+		ownerQuery:       `SELECT 1 FROM asset_register_entries WHERE asset_entry_id = $1 AND company_id = $2`,
+	},
+	// Any company user may report an issue, so any user may attach evidence to one.
+	models.AttachmentEntitySupportIssue: {
+		viewPermission:   "VIEW_SETTINGS",
+		managePermission: "",
+		ownerQuery:       `SELECT 1 FROM support_issues WHERE issue_id = $1 AND company_id = $2`,
+	},
+}
+
+type AttachmentService struct {
+	db *sql.DB
+}
+
+func NewAttachmentService() *AttachmentService {
+	return &AttachmentService{db: database.GetDB()}
+}
+
+// AttachmentPermission returns the permission needed to view (or, with manage,
+// add and remove) attachments on entityType.
+func AttachmentPermission(entityType string, manage bool) (string, error) {
+	entity, ok := attachmentEntities[entityType]
+	if !ok {
+		return "", fmt.Errorf("unsupported attachment entity type: %s", entityType)
+	}
+	if manage {
+		return entity.managePermission, nil
+	}
+	return entity.viewPermission, nil
+}
+
+func (s *AttachmentService) verifyEntity(companyID int, entityType string, entityID int) error {
+	entity, ok := attachmentEntities[entityType]
+	if !ok {
+		return fmt.Errorf("unsupported attachment entity type: %s", entityType)
+	}
+	var one int
+	err := s.db.QueryRow(entity.ownerQuery, entityID, companyID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%s not found", strings.ReplaceAll(entityType, "_", " "))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", entityType, err)
+	}
+	return nil
+}
+
+// Upload validates fh against the invoice/receipt allowlist, stores it once
+// per company by content hash and attaches it to the record.
+func (s *AttachmentService) Upload(companyID, userID int, entityType string, entityID int,
+	fh *multipart.FileHeader, description *string, ip, ua *string) (*models.Attachment, error) {
+	if err := s.verifyEntity(companyID, entityType, entityID); err != nil {
+		return nil, err
+	}
+	contentType, ext, err := validateUpload(fh, AllowlistInvoiceOrReceipt())
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	content, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	store, err := GetObjectStorage()
+	if err != nil {
+		return nil, err
+	}
+	objectID, deduplicated, err := s.storeObject(store, companyID, userID, hash, attachmentObjectKey(companyID, hash, ext), contentType, content)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fileName := attachmentFileName(fh.Filename, ext)
+	var attachmentID int
+	err = tx.QueryRow(`
+		INSERT INTO attachments (company_id, entity_type, entity_id, object_id, file_name, description, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING attachment_id
+	`, companyID, entityType, entityID, objectID, fileName, description, userID).Scan(&attachmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	fieldChanges := models.JSONB{
+		"entity_type":  entityType,
+		"entity_id":    entityID,
+		"object_id":    objectID,
+		"file_name":    fileName,
+		"sha256":       hash,
+		"deduplicated": deduplicated,
+	}
+	actor := userID
+	if err := LogAudit(tx, "CREATE", "attachments", &attachmentID, &actor, nil, nil, &fieldChanges, ip, ua); err != nil {
+		return nil, fmt.Errorf("failed to log audit: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	att, err := s.GetAttachment(companyID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	att.Deduplicated = deduplicated
+	return att, nil
+}
+
+// storeObject returns the company's existing object for hash, re-uploading the
+// bytes if the backend lost them, or stores a new one.
+func (s *AttachmentService) storeObject(store ObjectStorage, companyID, userID int, hash, key, contentType string, content []byte) (int, bool, error) {
+	var (
+		objectID    int
+		existingKey string
+	)
+	err := s.db.QueryRow(`
+		SELECT object_id, storage_key FROM file_objects
+		WHERE company_id = $1 AND sha256 = $2
+	`, companyID, hash).Scan(&objectID, &existingKey)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to look up stored file: %w", err)
+	}
+	if err == nil {
+		exists, err := store.Exists(existingKey)
+		if err != nil {
+			return 0, false, err
+		}
+		if !exists {
+			if err := store.Put(existingKey, bytes.NewReader(content), int64(len(content)), contentType); err != nil {
+				return 0, false, err
+			}
+		}
+		return objectID, true, nil
+	}
+
+	if err := store.Put(key, bytes.NewReader(content), int64(len(content)), contentType); err != nil {
+		return 0, false, err
+	}
+	// A concurrent upload of the same bytes wrote the same key; keep one row.
+	err = s.db.QueryRow(`
+		INSERT INTO file_objects (company_id, storage_key, sha256, size_bytes, content_type, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (company_id, sha256) DO UPDATE SET sha256 = EXCLUDED.sha256
+		RETURNING object_id
+	`, companyID, key, hash, len(content), contentType, userID).Scan(&objectID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record stored file: %w", err)
+	}
+	return objectID, false, nil
+}
+
+func attachmentObjectKey(companyID int, hash, ext string) string {
+	return fmt.Sprintf("attachments/%d/%s/%s%s", companyID, hash[:2], hash, ext)
+}
+
+// attachmentFileName keeps the client's name for display only; it never
+// reaches the storage key.
+func attachmentFileName(name, ext string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + ext
+	}
+	name = strings.ToValidUTF8(name, "")
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+const attachmentSelect = `
+	SELECT a.attachment_id, a.entity_type, a.entity_id, a.object_id, a.file_name, a.description,
+	       fo.content_type, fo.size_bytes, fo.sha256, fo.storage_key, a.uploaded_by,
+	       NULLIF(TRIM(CONCAT(u.first_name, ' ', u.last_name)), ''), a.created_at
+	FROM attachments a
+	JOIN file_objects fo ON fo.object_id = a.object_id
+	LEFT JOIN users u ON u.user_id = a.uploaded_by
+`
+
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*models.Attachment, string, error) {
+	var (
+		att models.Attachment
+		key string
+	)
+	err := row.Scan(&att.AttachmentID, &att.EntityType, &att.EntityID, &att.ObjectID, &att.FileName, &att.Description,
+		&att.ContentType, &att.SizeBytes, &att.SHA256, &key, &att.UploadedBy, &att.UploadedByName, &att.CreatedAt)
+	return &att, key, err
+}
+
+// ListAttachments returns the record's files with short-lived download URLs.
+func (s *AttachmentService) ListAttachments(companyID int, entityType string, entityID int) ([]models.Attachment, error) {
+	if err := s.verifyEntity(companyID, entityType, entityID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(attachmentSelect+`
+		WHERE a.company_id = $1 AND a.entity_type = $2 AND a.entity_id = $3 AND a.is_deleted = FALSE
+		ORDER BY a.created_at, a.attachment_id
+	`, companyID, entityType, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	defer rows.Close()
+
+	store, err := GetObjectStorage()
+	if err != nil {
+		return nil, err
+	}
+	ttl := StorageSignedURLTTL()
+	out := []models.Attachment{}
+	for rows.Next() {
+		att, key, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		if err := signAttachment(store, att, key, ttl); err != nil {
+			return nil, err
+		}
+		out = append(out, *att)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	return out, nil
+}
+
+func (s *AttachmentService) GetAttachment(companyID, attachmentID int) (*models.Attachment, error) {
+	att, key, err := scanAttachment(s.db.QueryRow(attachmentSelect+`
+		WHERE a.company_id = $1 AND a.attachment_id = $2 AND a.is_deleted = FALSE
+	`, companyID, attachmentID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	store, err := GetObjectStorage()
+	if err != nil {
+		return nil, err
+	}
+	if err := signAttachment(store, att, key, StorageSignedURLTTL()); err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+func signAttachment(store ObjectStorage, att *models.Attachment, key string, ttl time.Duration) error {
+	url, err := store.SignedURL(key, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to sign attachment url: %w", err)
+	}
+	expires := time.Now().Add(ttl).UTC()
+	att.DownloadURL = url
+	att.URLExpiresAt = &expires
+	return nil
+}
+
+// DeleteAttachment detaches the file. The stored object stays because other
+// attachments may share it.
+func (s *AttachmentService) DeleteAttachment(companyID, attachmentID, userID int, ip, ua *string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		entityType string
+		entityID   int
+	)
+	err = tx.QueryRow(`
+		UPDATE attachments
+		SET is_deleted = TRUE, deleted_by = $3, deleted_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1 AND attachment_id = $2 AND is_deleted = FALSE
+		RETURNING entity_type, entity_id
+	`, companyID, attachmentID, userID).Scan(&entityType, &entityID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("attachment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	fieldChanges := models.JSONB{"entity_type": entityType, "entity_id": entityID}
+	actor := userID
+	if err := LogAudit(tx, "DELETE", "attachments", &attachmentID, &actor, nil, nil, &fieldChanges, ip, ua); err != nil {
+		return fmt.Errorf("failed to log audit: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
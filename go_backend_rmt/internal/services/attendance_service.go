@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"erp-backend/internal/database"
@@ -235,7 +236,9 @@ func (s *AttendanceService) DecideLeave(companyID, leaveID, approverUserID int,
 		newStatus = "APPROVED"
 	}
 
-	res, err := s.db.Exec(`
+	var employeeUserID sql.NullInt64
+	var startDate, endDate time.Time
+	err := s.db.QueryRow(`
 		UPDATE leaves l
 		SET status = $1,
 		    approved_by = $2,
@@ -248,13 +251,31 @@ func (s *AttendanceService) DecideLeave(companyID, leaveID, approverUserID int,
 		  AND e.company_id = $5
 		  AND l.is_deleted = FALSE
 		  AND l.status = 'PENDING'
-	`, newStatus, approverUserID, decisionNotes, leaveID, companyID)
+		RETURNING e.user_id, l.start_date, l.end_date
+	`, newStatus, approverUserID, decisionNotes, leaveID, companyID).Scan(&employeeUserID, &startDate, &endDate)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("leave not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update leave decision: %w", err)
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return fmt.Errorf("leave not found")
+
+	// Only employees linked to a user account can be told.
+	if employeeUserID.Valid {
+		body := fmt.Sprintf("%s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		if decisionNotes != nil && strings.TrimSpace(*decisionNotes) != "" {
+			body += ": " + strings.TrimSpace(*decisionNotes)
+		}
+		notify(s.db, NotificationEvent{
+			CompanyID:  companyID,
+			Type:       models.NotificationLeaveDecided,
+			Title:      "Your leave request was " + strings.ToLower(newStatus),
+			Body:       body,
+			EntityType: "LEAVE",
+			EntityID:   leaveID,
+			DedupeKey:  fmt.Sprintf("leave_decided:%d", leaveID),
+			UserIDs:    []int{int(employeeUserID.Int64)},
+		})
 	}
 	return nil
 }
@@ -20,7 +20,7 @@ func NewAuditLogService() *AuditLogService {
 
 // GetAuditLogs retrieves logs based on provided filters
 func (s *AuditLogService) GetAuditLogs(filters map[string]string) ([]models.AuditLog, error) {
-	query := `SELECT log_id, user_id, action, table_name, record_id, old_value, new_value, field_changes, ip_address, user_agent, timestamp FROM audit_log`
+	query := `SELECT log_id, user_id, api_key_id, action, table_name, record_id, old_value, new_value, field_changes, ip_address, user_agent, timestamp FROM audit_log`
 	args := []interface{}{}
 	conditions := []string{}
 	argCount := 1
@@ -30,6 +30,11 @@ func (s *AuditLogService) GetAuditLogs(filters map[string]string) ([]models.Audi
 		args = append(args, v)
 		argCount++
 	}
+	if v, ok := filters["api_key_id"]; ok && v != "" {
+		conditions = append(conditions, fmt.Sprintf("api_key_id = $%d", argCount))
+		args = append(args, v)
+		argCount++
+	}
 	if v, ok := filters["action"]; ok && v != "" {
 		conditions = append(conditions, fmt.Sprintf("action = $%d", argCount))
 		args = append(args, v)
@@ -59,7 +64,7 @@ func (s *AuditLogService) GetAuditLogs(filters map[string]string) ([]models.Audi
 	var logs []models.AuditLog
 	for rows.Next() {
 		var log models.AuditLog
-		if err := rows.Scan(&log.LogID, &log.UserID, &log.Action, &log.TableName, &log.RecordID, &log.OldValue, &log.NewValue, &log.FieldChanges, &log.IPAddress, &log.UserAgent, &log.Timestamp); err != nil {
+		if err := rows.Scan(&log.LogID, &log.UserID, &log.APIKeyID, &log.Action, &log.TableName, &log.RecordID, &log.OldValue, &log.NewValue, &log.FieldChanges, &log.IPAddress, &log.UserAgent, &log.Timestamp); err != nil {
 			return nil, fmt.Errorf("failed to scan audit log: %w", err)
 		}
 		logs = append(logs, log)
@@ -71,7 +76,8 @@ func (s *AuditLogService) GetAuditLogs(filters map[string]string) ([]models.Audi
 // It captures the action performed, the table affected, and optionally the record ID,
 // acting user, and JSON representations of values or field changes along with client
 // metadata such as IP address and user agent. The operation runs inside the given
-// transaction to ensure atomicity with the calling service's changes.
+// transaction to ensure atomicity with the calling service's changes. When the
+// user is an API key's service account the entry is attributed to the key.
 func LogAudit(tx *sql.Tx, action, table string, recordID, userID *int,
 	oldValue, newValue, fieldChanges *models.JSONB, ip, ua *string) error {
 	if tx == nil {
@@ -79,9 +85,9 @@ func LogAudit(tx *sql.Tx, action, table string, recordID, userID *int,
 	}
 
 	query := `INSERT INTO audit_log
-                (user_id, action, table_name, record_id, old_value, new_value,
+                (user_id, api_key_id, action, table_name, record_id, old_value, new_value,
                  field_changes, ip_address, user_agent)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+                VALUES ($1, (SELECT api_key_id FROM api_keys WHERE user_id = $1), $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	if _, err := tx.Exec(query, userID, action, table, recordID, oldValue, newValue, fieldChanges, ip, ua); err != nil {
 		return fmt.Errorf("failed to insert audit log: %w", err)
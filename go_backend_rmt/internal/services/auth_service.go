@@ -115,6 +115,10 @@ func (s *AuthService) Login(req *models.LoginRequest, ipAddress, userAgent strin
 		return nil, fmt.Errorf("%w: empty session id", ErrDeviceSessionCreate)
 	}
 
+	if _, err := s.applyLocationAccess(user, nil); err != nil {
+		return nil, err
+	}
+
 	// Generate tokens
 	accessToken, err := utils.GenerateAccessToken(user, sessionID, s.cfg.JWTExpiry)
 	if err != nil {
@@ -134,7 +138,7 @@ func (s *AuthService) Login(req *models.LoginRequest, ipAddress, userAgent strin
 	}
 
 	// Get user permissions
-	permissions, err := s.getUserPermissions(user.UserID)
+	permissions, err := s.getUserPermissions(user.RoleID)
 	if err != nil {
 		// Log error but don't fail the login
 		log.Printf("auth_service: failed to get user permissions: %v", err)
@@ -159,6 +163,7 @@ func (s *AuthService) Login(req *models.LoginRequest, ipAddress, userAgent strin
 		Phone:                  user.Phone,
 		RoleID:                 user.RoleID,
 		LocationID:             user.LocationID,
+		LocationIDs:            user.LocationIDs,
 		CompanyID:              user.CompanyID,
 		IsActive:               user.IsActive,
 		IsLocked:               user.IsLocked,
@@ -217,6 +222,16 @@ func (s *AuthService) RefreshToken(req *models.RefreshTokenRequest) (*models.Ref
 		return nil, fmt.Errorf("account is inactive or locked")
 	}
 
+	// Keep the location the session switched to.
+	var activeLocationID *int
+	if err := s.db.QueryRow(`SELECT active_location_id FROM device_sessions WHERE session_id = $1 AND user_id = $2`,
+		claims.SessionID, user.UserID).Scan(&activeLocationID); err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if _, err := s.applyLocationAccess(user, activeLocationID); err != nil {
+		return nil, err
+	}
+
 	// Generate new access token
 	accessToken, err := utils.GenerateAccessToken(user, claims.SessionID, s.cfg.JWTExpiry)
 	if err != nil {
@@ -339,13 +354,17 @@ func (s *AuthService) ResetPassword(req *models.ResetPasswordRequest) error {
 	return nil
 }
 
-func (s *AuthService) GetMe(userID int) (*models.AuthMeResponse, error) {
+// GetMe describes the user as seen from the session's active location.
+func (s *AuthService) GetMe(userID int, activeLocationID *int) (*models.AuthMeResponse, error) {
 	user, err := s.getUserByID(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	if _, err := s.applyLocationAccess(user, activeLocationID); err != nil {
+		return nil, err
+	}
 
-	permissions, err := s.getUserPermissions(userID)
+	permissions, err := s.getUserPermissions(user.RoleID)
 	if err != nil {
 		permissions = []string{}
 	}
@@ -366,6 +385,7 @@ func (s *AuthService) GetMe(userID int) (*models.AuthMeResponse, error) {
 		Phone:                  user.Phone,
 		RoleID:                 user.RoleID,
 		LocationID:             user.LocationID,
+		LocationIDs:            user.LocationIDs,
 		CompanyID:              user.CompanyID,
 		IsActive:               user.IsActive,
 		IsLocked:               user.IsLocked,
@@ -432,7 +452,7 @@ func (s *AuthService) VerifyCredentials(companyID int, req *models.VerifyCredent
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	perms, err := s.getUserPermissions(user.UserID)
+	perms, err := s.getUserPermissions(user.RoleID)
 	if err != nil {
 		perms = []string{}
 	}
@@ -599,16 +619,20 @@ func (s *AuthService) updateLastLogin(userID int) error {
 // 	return permissions, nil
 // }
 
-func (s *AuthService) getUserPermissions(userID int) ([]string, error) {
+// getUserPermissions lists the permissions of the role a session acts
+// under, which may be a location role rather than the user's own.
+func (s *AuthService) getUserPermissions(roleID *int) ([]string, error) {
+	if roleID == nil {
+		return []string{}, nil
+	}
 	query := `
-		SELECT p.name 
-		FROM users u
-		JOIN role_permissions rp ON u.role_id = rp.role_id
+		SELECT p.name
+		FROM role_permissions rp
 		JOIN permissions p ON rp.permission_id = p.permission_id
-		WHERE u.user_id = $1 AND u.is_active = TRUE AND u.role_id IS NOT NULL
+		WHERE rp.role_id = $1
 	`
 
-	rows, err := s.db.Query(query, userID)
+	rows, err := s.db.Query(query, *roleID)
 	if err != nil {
 		return nil, err
 	}
@@ -626,6 +650,126 @@ func (s *AuthService) getUserPermissions(userID int) ([]string, error) {
 	return permissions, nil
 }
 
+// applyLocationAccess narrows user to its granted locations and switches to
+// the role of the active one, preferring the given location.
+func (s *AuthService) applyLocationAccess(user *models.User, preferred *int) ([]models.UserLocation, error) {
+	if user.CompanyID == nil {
+		return nil, nil
+	}
+	grants, err := loadUserLocations(s.db, *user.CompanyID, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user.LocationID, user.RoleID = utils.ResolveActiveLocation(grants, user.LocationID, user.RoleID, preferred)
+	user.LocationIDs = utils.UserLocationIDs(grants)
+	return grants, nil
+}
+
+// GetMyLocations lists the locations the user can switch to. Users without
+// explicit grants may work in any active location of their company.
+func (s *AuthService) GetMyLocations(userID int) ([]models.UserLocation, error) {
+	user, err := s.getUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.CompanyID == nil {
+		return []models.UserLocation{}, nil
+	}
+	grants, err := loadUserLocations(s.db, *user.CompanyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(grants) > 0 {
+		return grants, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT location_id, name
+		FROM locations
+		WHERE company_id = $1 AND COALESCE(is_active, TRUE) = TRUE
+		ORDER BY location_id
+	`, *user.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []models.UserLocation{}
+	for rows.Next() {
+		ul := models.UserLocation{UserID: userID, RoleID: user.RoleID}
+		if err := rows.Scan(&ul.LocationID, &ul.LocationName); err != nil {
+			return nil, fmt.Errorf("failed to scan location: %w", err)
+		}
+		ul.IsDefault = user.LocationID != nil && *user.LocationID == ul.LocationID
+		locations = append(locations, ul)
+	}
+	return locations, rows.Err()
+}
+
+// SwitchLocation moves a session to another of the user's locations and
+// issues an access token carrying that location and its role.
+func (s *AuthService) SwitchLocation(userID int, sessionID string, locationID int) (*models.SwitchLocationResponse, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("session ID required")
+	}
+	user, err := s.getUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.CompanyID == nil {
+		return nil, fmt.Errorf("location not found")
+	}
+	locations, err := s.GetMyLocations(userID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := false
+	for _, loc := range locations {
+		if loc.LocationID == locationID {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("location access denied")
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE device_sessions SET active_location_id = $1
+		WHERE session_id = $2 AND user_id = $3 AND is_active = TRUE
+	`, locationID, sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch location: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	if _, err := s.applyLocationAccess(user, &locationID); err != nil {
+		return nil, err
+	}
+	accessToken, err := utils.GenerateAccessToken(user, sessionID, s.cfg.JWTExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	permissions, err := s.getUserPermissions(user.RoleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	for i := range locations {
+		locations[i].IsActive = locations[i].LocationID == locationID
+	}
+
+	return &models.SwitchLocationResponse{
+		AccessToken: accessToken,
+		LocationID:  locationID,
+		RoleID:      user.RoleID,
+		LocationIDs: user.LocationIDs,
+		Locations:   locations,
+		Permissions: permissions,
+	}, nil
+}
+
 // REPLACE the Register method:
 func (s *AuthService) Register(req *models.RegisterRequest) (*models.RegisterResponse, error) {
 	// Check if username or email already exists
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+type BillOfMaterialsService struct {
+	db *sql.DB
+}
+
+func NewBillOfMaterialsService() *BillOfMaterialsService {
+	return &BillOfMaterialsService{db: database.GetDB()}
+}
+
+func round3(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// bomComponentRequirement returns the stock quantity of a component needed
+// for orderQty finished units, and the part of it that is expected scrap.
+func bomComponentRequirement(component models.BOMComponent, outputQty, orderQty float64) (float64, float64) {
+	if outputQty <= 0 {
+		outputQty = 1
+	}
+	factor := component.StockFactor
+	if factor <= 0 {
+		factor = 1
+	}
+	base := component.Quantity * factor * orderQty / outputQty
+	total := round3(base * (1 + component.ScrapPercent/100))
+	return total, round3(total - base)
+}
+
+func (s *BillOfMaterialsService) GetBOMs(companyID int, productID *int) ([]models.BillOfMaterials, error) {
+	args := []interface{}{companyID}
+	query := `
+		SELECT b.bom_id, b.company_id, b.product_id, p.name, b.barcode_id, b.version, b.name,
+		       b.output_quantity::float8, b.labor_cost::float8, b.overhead_cost::float8,
+		       b.is_active, b.notes, b.created_by, b.created_at, b.updated_at
+		FROM bill_of_materials b
+		JOIN products p ON p.product_id = b.product_id
+		WHERE b.company_id = $1
+	`
+	if productID != nil {
+		args = append(args, *productID)
+		query += fmt.Sprintf(" AND b.product_id = $%d", len(args))
+	}
+	query += " ORDER BY p.name, b.product_id, b.version DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bills of materials: %w", err)
+	}
+	defer rows.Close()
+
+	boms := make([]models.BillOfMaterials, 0)
+	for rows.Next() {
+		bom, err := scanBillOfMaterials(rows)
+		if err != nil {
+			return nil, err
+		}
+		boms = append(boms, *bom)
+	}
+	return boms, rows.Err()
+}
+
+func (s *BillOfMaterialsService) GetBOM(companyID, bomID int) (*models.BillOfMaterials, error) {
+	return s.getBOM(s.db, companyID, bomID)
+}
+
+// rowsQueryer is satisfied by both *sql.DB and *sql.Tx.
+type rowsQueryer interface {
+	sqlQueryer
+	queryer
+}
+
+func (s *BillOfMaterialsService) getBOM(q rowsQueryer, companyID, bomID int) (*models.BillOfMaterials, error) {
+	bom, err := scanBillOfMaterials(q.QueryRow(`
+		SELECT b.bom_id, b.company_id, b.product_id, p.name, b.barcode_id, b.version, b.name,
+		       b.output_quantity::float8, b.labor_cost::float8, b.overhead_cost::float8,
+		       b.is_active, b.notes, b.created_by, b.created_at, b.updated_at
+		FROM bill_of_materials b
+		JOIN products p ON p.product_id = b.product_id
+		WHERE b.bom_id = $1 AND b.company_id = $2
+	`, bomID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("bill of materials not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT bc.bom_component_id, bc.bom_id, bc.product_id, p.name, bc.barcode_id,
+		       bc.quantity::float8, bc.unit_id, u.symbol, bc.stock_factor::float8,
+		       bc.scrap_percent::float8, bc.position, bc.notes
+		FROM bom_components bc
+		JOIN products p ON p.product_id = bc.product_id
+		LEFT JOIN units u ON u.unit_id = bc.unit_id
+		WHERE bc.bom_id = $1
+		ORDER BY bc.position, bc.bom_component_id
+	`, bomID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BOM components: %w", err)
+	}
+	defer rows.Close()
+
+	bom.Components = make([]models.BOMComponent, 0)
+	for rows.Next() {
+		var c models.BOMComponent
+		if err := rows.Scan(
+			&c.BOMComponentID, &c.BOMID, &c.ProductID, &c.ProductName, &c.BarcodeID,
+			&c.Quantity, &c.UnitID, &c.UnitSymbol, &c.StockFactor,
+			&c.ScrapPercent, &c.Position, &c.Notes,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan BOM component: %w", err)
+		}
+		bom.Components = append(bom.Components, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BOM components: %w", err)
+	}
+	return bom, nil
+}
+
+// CreateBOM stores the next version of a product's bill of materials. The
+// new version becomes active when asked to, or when the finished good has no
+// active version yet.
+func (s *BillOfMaterialsService) CreateBOM(companyID, userID int, req *models.CreateBillOfMaterialsRequest) (*models.BillOfMaterials, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.validateProductVariantTx(tx, companyID, req.ProductID, req.BarcodeID); err != nil {
+		return nil, err
+	}
+	for _, c := range req.Components {
+		if c.ProductID == req.ProductID {
+			return nil, fmt.Errorf("a bill of materials cannot consume its own finished good")
+		}
+		if err := s.validateProductVariantTx(tx, companyID, c.ProductID, c.BarcodeID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Serialize version numbering per product.
+	if _, err := tx.Exec(`SELECT product_id FROM products WHERE product_id = $1 FOR UPDATE`, req.ProductID); err != nil {
+		return nil, fmt.Errorf("failed to lock product: %w", err)
+	}
+	var version int
+	var hasActive bool
+	if err := tx.QueryRow(`
+		SELECT COALESCE(MAX(version), 0) + 1,
+		       COALESCE(BOOL_OR(is_active AND COALESCE(barcode_id, 0) = COALESCE($3::int, 0)), FALSE)
+		FROM bill_of_materials
+		WHERE company_id = $1 AND product_id = $2
+	`, companyID, req.ProductID, req.BarcodeID).Scan(&version, &hasActive); err != nil {
+		return nil, fmt.Errorf("failed to get next BOM version: %w", err)
+	}
+
+	activate := !hasActive
+	if req.Activate != nil {
+		activate = *req.Activate
+	}
+	if activate && hasActive {
+		if err := s.deactivateVariantBOMsTx(tx, req.ProductID, req.BarcodeID); err != nil {
+			return nil, err
+		}
+	}
+
+	outputQty := 1.0
+	if req.OutputQuantity != nil {
+		outputQty = *req.OutputQuantity
+	}
+	var bomID int
+	if err := tx.QueryRow(`
+		INSERT INTO bill_of_materials (
+			company_id, product_id, barcode_id, version, name, output_quantity,
+			labor_cost, overhead_cost, is_active, notes, created_by
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		RETURNING bom_id
+	`, companyID, req.ProductID, req.BarcodeID, version, req.Name, outputQty,
+		round2(req.LaborCost), round2(req.OverheadCost), activate, req.Notes, userID,
+	).Scan(&bomID); err != nil {
+		return nil, fmt.Errorf("failed to create bill of materials: %w", err)
+	}
+
+	for i, c := range req.Components {
+		factor, err := s.componentStockFactorTx(tx, c.ProductID, c.UnitID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO bom_components (bom_id, product_id, barcode_id, quantity, unit_id, stock_factor, scrap_percent, position, notes)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		`, bomID, c.ProductID, c.BarcodeID, c.Quantity, c.UnitID, factor, c.ScrapPercent, i, c.Notes); err != nil {
+			return nil, fmt.Errorf("failed to create BOM component: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bill of materials: %w", err)
+	}
+	return s.GetBOM(companyID, bomID)
+}
+
+// ActivateBOM makes a version the one used by new assembly orders.
+func (s *BillOfMaterialsService) ActivateBOM(companyID, bomID int) (*models.BillOfMaterials, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var productID int
+	var barcodeID *int
+	err = tx.QueryRow(`
+		SELECT product_id, barcode_id
+		FROM bill_of_materials
+		WHERE bom_id = $1 AND company_id = $2
+		FOR UPDATE
+	`, bomID, companyID).Scan(&productID, &barcodeID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("bill of materials not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bill of materials: %w", err)
+	}
+
+	if err := s.deactivateVariantBOMsTx(tx, productID, barcodeID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE bill_of_materials
+		SET is_active = TRUE, updated_at = CURRENT_TIMESTAMP
+		WHERE bom_id = $1
+	`, bomID); err != nil {
+		return nil, fmt.Errorf("failed to activate bill of materials: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bill of materials: %w", err)
+	}
+	return s.GetBOM(companyID, bomID)
+}
+
+func (s *BillOfMaterialsService) deactivateVariantBOMsTx(tx *sql.Tx, productID int, barcodeID *int) error {
+	if _, err := tx.Exec(`
+		UPDATE bill_of_materials
+		SET is_active = FALSE, updated_at = CURRENT_TIMESTAMP
+		WHERE product_id = $1 AND COALESCE(barcode_id, 0) = COALESCE($2::int, 0) AND is_active = TRUE
+	`, productID, barcodeID); err != nil {
+		return fmt.Errorf("failed to deactivate previous BOM version: %w", err)
+	}
+	return nil
+}
+
+func (s *BillOfMaterialsService) validateProductVariantTx(tx *sql.Tx, companyID, productID int, barcodeID *int) error {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM products p
+			LEFT JOIN product_barcodes pb ON pb.product_id = p.product_id AND pb.barcode_id = $3
+			WHERE p.product_id = $1 AND p.company_id = $2 AND p.is_deleted = FALSE
+			  AND ($3::int IS NULL OR pb.barcode_id IS NOT NULL)
+		)
+	`, productID, companyID, barcodeID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to verify product: %w", err)
+	}
+	if !exists {
+		if barcodeID != nil {
+			return fmt.Errorf("product %d with barcode %d not found", productID, *barcodeID)
+		}
+		return fmt.Errorf("product %d not found", productID)
+	}
+	return nil
+}
+
+// componentStockFactorTx resolves how many stock units one unitID of the
+// product is. The product's purchase and selling units use their configured
+// factors; other units must be derived from the stock unit.
+func (s *BillOfMaterialsService) componentStockFactorTx(tx *sql.Tx, productID int, unitID *int) (float64, error) {
+	if unitID == nil {
+		return 1, nil
+	}
+	var stockUnitID, purchaseUnitID, sellingUnitID sql.NullInt64
+	var purchaseFactor, sellingFactor float64
+	if err := tx.QueryRow(`
+		SELECT unit_id, purchase_unit_id, selling_unit_id,
+		       COALESCE(purchase_to_stock_factor, 1)::float8,
+		       COALESCE(selling_to_stock_factor, 1)::float8
+		FROM products
+		WHERE product_id = $1
+	`, productID).Scan(&stockUnitID, &purchaseUnitID, &sellingUnitID, &purchaseFactor, &sellingFactor); err != nil {
+		return 0, fmt.Errorf("failed to load product units: %w", err)
+	}
+	switch {
+	case !stockUnitID.Valid || int(stockUnitID.Int64) == *unitID:
+		return 1, nil
+	case purchaseUnitID.Valid && int(purchaseUnitID.Int64) == *unitID:
+		return normalizeProductUOMFactor(&purchaseFactor), nil
+	case sellingUnitID.Valid && int(sellingUnitID.Int64) == *unitID:
+		return normalizeProductUOMFactor(&sellingFactor), nil
+	}
+
+	var baseUnitID sql.NullInt64
+	var factor sql.NullFloat64
+	err := tx.QueryRow(`SELECT base_unit_id, conversion_factor::float8 FROM units WHERE unit_id = $1`, *unitID).Scan(&baseUnitID, &factor)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to load unit: %w", err)
+	}
+	if err == nil && baseUnitID.Valid && baseUnitID.Int64 == stockUnitID.Int64 && factor.Valid && factor.Float64 > 0 {
+		return factor.Float64, nil
+	}
+	return 0, fmt.Errorf("unit %d cannot be converted to the stock unit of product %d", *unitID, productID)
+}
+
+func scanBillOfMaterials(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.BillOfMaterials, error) {
+	var bom models.BillOfMaterials
+	if err := row.Scan(
+		&bom.BOMID, &bom.CompanyID, &bom.ProductID, &bom.ProductName, &bom.BarcodeID, &bom.Version, &bom.Name,
+		&bom.OutputQuantity, &bom.LaborCost, &bom.OverheadCost,
+		&bom.IsActive, &bom.Notes, &bom.CreatedBy, &bom.CreatedAt, &bom.UpdatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan bill of materials: %w", err)
+	}
+	if bom.Name != nil && strings.TrimSpace(*bom.Name) == "" {
+		bom.Name = nil
+	}
+	return &bom, nil
+}
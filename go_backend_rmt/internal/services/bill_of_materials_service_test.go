@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestBOMComponentRequirement(t *testing.T) {
+	cases := []struct {
+		name      string
+		component models.BOMComponent
+		outputQty float64
+		orderQty  float64
+		wantTotal float64
+		wantScrap float64
+	}{
+		{"plain", models.BOMComponent{Quantity: 2, StockFactor: 1}, 1, 5, 10, 0},
+		{"scrap", models.BOMComponent{Quantity: 2, StockFactor: 1, ScrapPercent: 10}, 1, 5, 11, 1},
+		{"unit conversion", models.BOMComponent{Quantity: 250, StockFactor: 0.001}, 1, 4, 1, 0},
+		{"batch output", models.BOMComponent{Quantity: 3, StockFactor: 1}, 12, 6, 1.5, 0},
+		{"missing factor", models.BOMComponent{Quantity: 1.5}, 0, 2, 3, 0},
+	}
+	for _, tc := range cases {
+		total, scrap := bomComponentRequirement(tc.component, tc.outputQty, tc.orderQty)
+		if total != tc.wantTotal || scrap != tc.wantScrap {
+			t.Fatalf("%s: got (%v, %v), want (%v, %v)", tc.name, total, scrap, tc.wantTotal, tc.wantScrap)
+		}
+	}
+}
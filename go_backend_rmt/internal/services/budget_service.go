@@ -0,0 +1,831 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+type BudgetService struct {
+	db *sql.DB
+}
+
+func NewBudgetService() *BudgetService {
+	return &BudgetService{db: database.GetDB()}
+}
+
+// budgetPeriod returns the first day of the fiscal year and the first day
+// after it.
+func budgetPeriod(fiscalYear, startMonth int) (time.Time, time.Time) {
+	start := time.Date(fiscalYear, time.Month(startMonth), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// budgetMonthIndex maps a date to its fiscal month (0-11), or -1 when it
+// falls outside the fiscal year.
+func budgetMonthIndex(fiscalYear, startMonth int, d time.Time) int {
+	idx := (d.Year()*12 + int(d.Month()) - 1) - (fiscalYear*12 + startMonth - 1)
+	if idx < 0 || idx > 11 {
+		return -1
+	}
+	return idx
+}
+
+// budgetNaturalAmount gives debit/credit activity the sign the account type
+// is reported in, so revenue and expense budgets are both positive numbers.
+func budgetNaturalAmount(accountType string, debitMinusCredit float64) float64 {
+	switch accountType {
+	case "ASSET", "EXPENSE":
+		return debitMinusCredit
+	default:
+		return -debitMinusCredit
+	}
+}
+
+func roundBudgetAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// budgetActualEntriesSQL lists ledger entries in $2..$3 ($3 exclusive) with
+// the location and department of their source document where one exists.
+// $4 and $5 optionally restrict to a location or department.
+const budgetActualEntriesSQL = `
+	SELECT e.account_id, e.date, e.debit, e.credit
+	FROM (
+		SELECT le.account_id, le.date,
+		       COALESCE(le.debit, 0) AS debit, COALESCE(le.credit, 0) AS credit,
+		       COALESCE(s.location_id, sr.location_id, pu.location_id, pr.location_id, ex.location_id, emp.location_id) AS location_id,
+		       COALESCE(ex.department_id, emp.department_id) AS department_id
+		FROM ledger_entries le
+		LEFT JOIN sales s ON le.transaction_type = 'sale' AND s.sale_id = le.transaction_id
+		LEFT JOIN sale_returns sr ON le.transaction_type = 'sale_return' AND sr.return_id = le.transaction_id
+		LEFT JOIN purchases pu ON le.transaction_type = 'purchase' AND pu.purchase_id = le.transaction_id
+		LEFT JOIN purchase_returns pr ON le.transaction_type = 'purchase_return' AND pr.return_id = le.transaction_id
+		LEFT JOIN expenses ex ON le.transaction_type = 'expense' AND ex.expense_id = le.transaction_id
+		LEFT JOIN payroll pay ON le.transaction_type = 'payroll' AND pay.payroll_id = le.transaction_id
+		LEFT JOIN employees emp ON emp.employee_id = pay.employee_id
+		WHERE le.company_id = $1 AND le.date >= $2::date AND le.date < $3::date
+	) e
+	WHERE ($4::int IS NULL OR e.location_id = $4)
+	  AND ($5::int IS NULL OR e.department_id = $5)
+`
+
+func nullableIntArg(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+const budgetSelect = `
+	SELECT b.budget_id, b.company_id, b.name, b.fiscal_year, b.start_month, b.location_id, l.name,
+	       b.department_id, d.name, b.version_type, b.version_no, b.revised_from_id, b.status,
+	       b.control_mode, b.notes,
+	       COALESCE((SELECT SUM(bl.amount) FROM budget_lines bl WHERE bl.budget_id = b.budget_id), 0)::float8,
+	       b.created_by, b.updated_by, b.created_at, b.updated_at
+	FROM budgets b
+	LEFT JOIN locations l ON l.location_id = b.location_id
+	LEFT JOIN departments d ON d.department_id = b.department_id
+`
+
+func scanBudget(row interface{ Scan(...interface{}) error }) (*models.Budget, error) {
+	var b models.Budget
+	err := row.Scan(&b.BudgetID, &b.CompanyID, &b.Name, &b.FiscalYear, &b.StartMonth, &b.LocationID, &b.LocationName,
+		&b.DepartmentID, &b.DepartmentName, &b.VersionType, &b.VersionNo, &b.RevisedFromID, &b.Status,
+		&b.ControlMode, &b.Notes, &b.TotalAmount, &b.CreatedBy, &b.UpdatedBy, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (s *BudgetService) getBudgetHeader(companyID, budgetID int) (*models.Budget, error) {
+	b, err := scanBudget(s.db.QueryRow(budgetSelect+`
+		WHERE b.budget_id = $1 AND b.company_id = $2 AND b.is_deleted = FALSE
+	`, budgetID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("budget not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	return b, nil
+}
+
+// GetBudget returns a budget with its lines.
+func (s *BudgetService) GetBudget(companyID, budgetID int) (*models.Budget, error) {
+	b, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.loadBudgetLines(budgetID)
+	if err != nil {
+		return nil, err
+	}
+	b.Lines = lines
+	return b, nil
+}
+
+func (s *BudgetService) loadBudgetLines(budgetID int) ([]models.BudgetLine, error) {
+	rows, err := s.db.Query(`
+		SELECT bl.account_id, a.account_code, a.name, a.type, bl.period_month, bl.amount::float8
+		FROM budget_lines bl
+		JOIN chart_of_accounts a ON a.account_id = bl.account_id
+		WHERE bl.budget_id = $1
+		ORDER BY a.account_code NULLS LAST, a.name, bl.account_id, bl.period_month
+	`, budgetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budget lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []models.BudgetLine{}
+	index := map[int]int{}
+	for rows.Next() {
+		var (
+			line   models.BudgetLine
+			month  int
+			amount float64
+		)
+		if err := rows.Scan(&line.AccountID, &line.AccountCode, &line.AccountName, &line.AccountType, &month, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan budget line: %w", err)
+		}
+		i, ok := index[line.AccountID]
+		if !ok {
+			i = len(lines)
+			index[line.AccountID] = i
+			lines = append(lines, line)
+		}
+		if month >= 1 && month <= 12 {
+			lines[i].Months[month-1] = amount
+			lines[i].Total = roundBudgetAmount(lines[i].Total + amount)
+		}
+	}
+	return lines, rows.Err()
+}
+
+func (s *BudgetService) ListBudgets(companyID int, filter models.BudgetFilter) ([]models.Budget, error) {
+	rows, err := s.db.Query(budgetSelect+`
+		WHERE b.company_id = $1 AND b.is_deleted = FALSE
+		  AND ($2::int = 0 OR b.fiscal_year = $2)
+		  AND ($3::text = '' OR b.status = $3)
+		  AND ($4::int IS NULL OR b.location_id = $4)
+		  AND ($5::int IS NULL OR b.department_id = $5)
+		ORDER BY b.fiscal_year DESC, b.name, b.version_no DESC
+	`, companyID, filter.FiscalYear, filter.Status, nullableIntArg(filter.LocationID), nullableIntArg(filter.DepartmentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+func (s *BudgetService) verifyBudgetScope(companyID int, locationID, departmentID *int) error {
+	if locationID != nil {
+		var ok bool
+		if err := s.db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)
+		`, *locationID, companyID).Scan(&ok); err != nil {
+			return fmt.Errorf("failed to verify location: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("location not found")
+		}
+	}
+	if departmentID != nil {
+		var ok bool
+		if err := s.db.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM departments WHERE department_id = $1 AND company_id = $2 AND is_deleted = FALSE)
+		`, *departmentID, companyID).Scan(&ok); err != nil {
+			return fmt.Errorf("failed to verify department: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("department not found")
+		}
+	}
+	return nil
+}
+
+// replaceBudgetLines swaps the budget's lines for lines, storing only
+// non-zero months.
+func replaceBudgetLines(tx *sql.Tx, companyID, budgetID int, lines []models.BudgetLineInput) error {
+	seen := map[int]bool{}
+	for _, line := range lines {
+		if seen[line.AccountID] {
+			return fmt.Errorf("account %d appears more than once", line.AccountID)
+		}
+		seen[line.AccountID] = true
+		if len(line.Months) != 12 {
+			return fmt.Errorf("account %d must have 12 monthly amounts", line.AccountID)
+		}
+	}
+	if len(seen) > 0 {
+		ids := make([]int, 0, len(seen))
+		for id := range seen {
+			ids = append(ids, id)
+		}
+		var found int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM chart_of_accounts WHERE company_id = $1 AND account_id = ANY($2)
+		`, companyID, pq.Array(ids)).Scan(&found); err != nil {
+			return fmt.Errorf("failed to verify accounts: %w", err)
+		}
+		if found != len(ids) {
+			return fmt.Errorf("account not found")
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM budget_lines WHERE budget_id = $1`, budgetID); err != nil {
+		return fmt.Errorf("failed to clear budget lines: %w", err)
+	}
+	for _, line := range lines {
+		for i, amount := range line.Months {
+			amount = roundBudgetAmount(amount)
+			if amount == 0 {
+				continue
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO budget_lines (budget_id, account_id, period_month, amount) VALUES ($1, $2, $3, $4)
+			`, budgetID, line.AccountID, i+1, amount); err != nil {
+				return fmt.Errorf("failed to save budget line: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *BudgetService) CreateBudget(companyID, userID int, req *models.CreateBudgetRequest) (*models.Budget, error) {
+	if err := s.verifyBudgetScope(companyID, req.LocationID, req.DepartmentID); err != nil {
+		return nil, err
+	}
+	startMonth := req.StartMonth
+	if startMonth == 0 {
+		startMonth = 1
+	}
+	control := req.ControlMode
+	if control == "" {
+		control = models.BudgetControlNone
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO budgets (company_id, name, fiscal_year, start_month, location_id, department_id,
+		                     version_type, version_no, status, control_mode, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 1, $8, $9, $10, $11)
+		RETURNING budget_id
+	`, companyID, strings.TrimSpace(req.Name), req.FiscalYear, startMonth, req.LocationID, req.DepartmentID,
+		models.BudgetVersionOriginal, models.BudgetStatusDraft, control, trimPointer(req.Notes), userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create budget: %w", err)
+	}
+	if err := replaceBudgetLines(tx, companyID, id, req.Lines); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget: %w", err)
+	}
+	return s.GetBudget(companyID, id)
+}
+
+func (s *BudgetService) UpdateBudget(companyID, userID, budgetID int, req *models.UpdateBudgetRequest) (*models.Budget, error) {
+	b, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status == models.BudgetStatusArchived {
+		return nil, fmt.Errorf("archived budgets cannot be changed")
+	}
+	if req.Lines != nil && b.Status != models.BudgetStatusDraft {
+		return nil, fmt.Errorf("only draft budgets can change amounts; revise the budget instead")
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		b.Name = name
+	}
+	if req.ControlMode != nil {
+		b.ControlMode = *req.ControlMode
+	}
+	if req.Notes != nil {
+		b.Notes = trimPointer(req.Notes)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE budgets SET name = $1, control_mode = $2, notes = $3, updated_by = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE budget_id = $5 AND company_id = $6
+	`, b.Name, b.ControlMode, b.Notes, userID, budgetID, companyID); err != nil {
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+	if req.Lines != nil {
+		if err := replaceBudgetLines(tx, companyID, budgetID, *req.Lines); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget: %w", err)
+	}
+	return s.GetBudget(companyID, budgetID)
+}
+
+// DeleteBudget removes a draft or archived budget. Active budgets must be
+// archived first so control never silently disappears.
+func (s *BudgetService) DeleteBudget(companyID, userID, budgetID int) error {
+	b, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return err
+	}
+	if b.Status == models.BudgetStatusActive {
+		return fmt.Errorf("active budgets must be archived before deletion")
+	}
+	if _, err := s.db.Exec(`
+		UPDATE budgets SET is_deleted = TRUE, updated_by = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE budget_id = $2 AND company_id = $3
+	`, userID, budgetID, companyID); err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	return nil
+}
+
+// ReviseBudget copies a budget into a new REVISED draft with the next version
+// number in its lineage. The source stays as it is until the revision is
+// activated.
+func (s *BudgetService) ReviseBudget(companyID, userID, budgetID int, req *models.ReviseBudgetRequest) (*models.Budget, error) {
+	src, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	name := src.Name
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		name = strings.TrimSpace(*req.Name)
+	}
+	notes := src.Notes
+	if req.Notes != nil {
+		notes = trimPointer(req.Notes)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO budgets (company_id, name, fiscal_year, start_month, location_id, department_id,
+		                     version_type, version_no, revised_from_id, status, control_mode, notes, created_by)
+		SELECT $1, $2, b.fiscal_year, b.start_month, b.location_id, b.department_id, $3,
+		       (SELECT COALESCE(MAX(o.version_no), 0) + 1 FROM budgets o
+		        WHERE o.company_id = b.company_id AND o.fiscal_year = b.fiscal_year
+		          AND o.location_id IS NOT DISTINCT FROM b.location_id
+		          AND o.department_id IS NOT DISTINCT FROM b.department_id),
+		       b.budget_id, $4, b.control_mode, $5, $6
+		FROM budgets b
+		WHERE b.budget_id = $7 AND b.company_id = $1
+		RETURNING budget_id
+	`, companyID, name, models.BudgetVersionRevised, models.BudgetStatusDraft, notes, userID, budgetID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revise budget: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO budget_lines (budget_id, account_id, period_month, amount)
+		SELECT $1, account_id, period_month, amount FROM budget_lines WHERE budget_id = $2
+	`, id, budgetID); err != nil {
+		return nil, fmt.Errorf("failed to copy budget lines: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget revision: %w", err)
+	}
+	return s.GetBudget(companyID, id)
+}
+
+// ActivateBudget makes a budget the one in force for its year and scope,
+// archiving whichever version was active before.
+func (s *BudgetService) ActivateBudget(companyID, userID, budgetID int) (*models.Budget, error) {
+	b, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status == models.BudgetStatusActive {
+		return s.GetBudget(companyID, budgetID)
+	}
+	if b.Status == models.BudgetStatusArchived {
+		return nil, fmt.Errorf("archived budgets cannot be activated; revise the budget instead")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE budgets SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE company_id = $3 AND fiscal_year = $4 AND status = $5 AND is_deleted = FALSE
+		  AND location_id IS NOT DISTINCT FROM $6::int
+		  AND department_id IS NOT DISTINCT FROM $7::int
+	`, models.BudgetStatusArchived, userID, companyID, b.FiscalYear, models.BudgetStatusActive,
+		nullableIntArg(b.LocationID), nullableIntArg(b.DepartmentID)); err != nil {
+		return nil, fmt.Errorf("failed to archive previous budget: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE budgets SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE budget_id = $3 AND company_id = $4
+	`, models.BudgetStatusActive, userID, budgetID, companyID); err != nil {
+		return nil, fmt.Errorf("failed to activate budget: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget activation: %w", err)
+	}
+	return s.GetBudget(companyID, budgetID)
+}
+
+func (s *BudgetService) ArchiveBudget(companyID, userID, budgetID int) (*models.Budget, error) {
+	if _, err := s.getBudgetHeader(companyID, budgetID); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec(`
+		UPDATE budgets SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE budget_id = $3 AND company_id = $4
+	`, models.BudgetStatusArchived, userID, budgetID, companyID); err != nil {
+		return nil, fmt.Errorf("failed to archive budget: %w", err)
+	}
+	return s.GetBudget(companyID, budgetID)
+}
+
+type budgetActualKey struct {
+	accountID int
+	month     int
+}
+
+type budgetAccountInfo struct {
+	code *string
+	name string
+	typ  string
+}
+
+// BudgetVsActual compares the budget with ledger actuals for the same scope
+// over the selected fiscal months. Revenue and expense accounts with activity
+// but no budget are included so unplanned spending is visible.
+func (s *BudgetService) BudgetVsActual(companyID, budgetID int, filter models.BudgetVsActualFilter) (*models.BudgetVsActualReport, error) {
+	b, err := s.GetBudget(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	fromMonth, toMonth := filter.FromMonth, filter.ToMonth
+	if fromMonth == 0 {
+		fromMonth = 1
+	}
+	if toMonth == 0 {
+		toMonth = 12
+	}
+	if fromMonth > toMonth {
+		return nil, fmt.Errorf("from_month must not be after to_month")
+	}
+	yearStart, _ := budgetPeriod(b.FiscalYear, b.StartMonth)
+	from := yearStart.AddDate(0, fromMonth-1, 0)
+	to := yearStart.AddDate(0, toMonth, 0)
+
+	rows, err := s.db.Query(`
+		SELECT a.account_id, a.account_code, a.name, a.type,
+		       date_trunc('month', x.date)::date AS month,
+		       COALESCE(SUM(x.debit - x.credit), 0)::float8
+		FROM (`+budgetActualEntriesSQL+`) x
+		JOIN chart_of_accounts a ON a.account_id = x.account_id
+		GROUP BY a.account_id, a.account_code, a.name, a.type, date_trunc('month', x.date)
+	`, companyID, from.Format("2006-01-02"), to.Format("2006-01-02"),
+		nullableIntArg(b.LocationID), nullableIntArg(b.DepartmentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load actuals: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := map[int]budgetAccountInfo{}
+	actuals := map[budgetActualKey]float64{}
+	for rows.Next() {
+		var (
+			id    int
+			info  budgetAccountInfo
+			month time.Time
+			net   float64
+		)
+		if err := rows.Scan(&id, &info.code, &info.name, &info.typ, &month, &net); err != nil {
+			return nil, fmt.Errorf("failed to scan actuals: %w", err)
+		}
+		idx := budgetMonthIndex(b.FiscalYear, b.StartMonth, month)
+		if idx < 0 {
+			continue
+		}
+		accounts[id] = info
+		actuals[budgetActualKey{id, idx}] += budgetNaturalAmount(info.typ, net)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read actuals: %w", err)
+	}
+
+	report := &models.BudgetVsActualReport{
+		FromDate: from.Format("2006-01-02"),
+		ToDate:   to.AddDate(0, 0, -1).Format("2006-01-02"),
+		Rows:     buildBudgetVsActualRows(b, accounts, actuals, fromMonth, toMonth, filter.ByMonth),
+	}
+	b.Lines = nil
+	report.Budget = *b
+	return report, nil
+}
+
+func buildBudgetVsActualRows(b *models.Budget, accounts map[int]budgetAccountInfo, actuals map[budgetActualKey]float64, fromMonth, toMonth int, byMonth bool) []models.BudgetVsActualRow {
+	budgeted := map[int]models.BudgetLine{}
+	for _, line := range b.Lines {
+		budgeted[line.AccountID] = line
+		accounts[line.AccountID] = budgetAccountInfo{code: line.AccountCode, name: line.AccountName, typ: line.AccountType}
+	}
+
+	ids := make([]int, 0, len(accounts))
+	for id, info := range accounts {
+		if _, ok := budgeted[id]; !ok && info.typ != "REVENUE" && info.typ != "EXPENSE" {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, c := accounts[ids[i]], accounts[ids[j]]
+		ac, cc := valueOrEmpty(a.code), valueOrEmpty(c.code)
+		if ac != cc {
+			return ac < cc
+		}
+		return ids[i] < ids[j]
+	})
+
+	yearStart, _ := budgetPeriod(b.FiscalYear, b.StartMonth)
+	rows := []models.BudgetVsActualRow{}
+	for _, id := range ids {
+		info := accounts[id]
+		line := budgeted[id]
+		var budget, actual float64
+		flush := func(month string) {
+			row := models.BudgetVsActualRow{
+				AccountID: id, AccountCode: info.code, AccountName: info.name, AccountType: info.typ, Month: month,
+				Budget: roundBudgetAmount(budget), Actual: roundBudgetAmount(actual),
+			}
+			row.Variance = roundBudgetAmount(row.Actual - row.Budget)
+			if row.Budget != 0 {
+				pct := roundBudgetAmount(row.Variance / math.Abs(row.Budget) * 100)
+				row.VariancePct = &pct
+			}
+			if info.typ == "REVENUE" {
+				row.Favorable = row.Variance >= 0
+			} else {
+				row.Favorable = row.Variance <= 0
+			}
+			rows = append(rows, row)
+			budget, actual = 0, 0
+		}
+		for m := fromMonth - 1; m < toMonth; m++ {
+			budget += line.Months[m]
+			actual += actuals[budgetActualKey{id, m}]
+			if byMonth {
+				flush(yearStart.AddDate(0, m, 0).Format("2006-01"))
+			}
+		}
+		if !byMonth {
+			flush("")
+		}
+	}
+	return rows
+}
+
+// BudgetVsActualTable lays the report out for the Excel, PDF and CSV exports.
+func BudgetVsActualTable(report *models.BudgetVsActualReport) *utils.ReportTable {
+	table := &utils.ReportTable{
+		Title: fmt.Sprintf("Budget vs Actual - %s v%d (%s to %s)",
+			report.Budget.Name, report.Budget.VersionNo, report.FromDate, report.ToDate),
+		Columns: []utils.ReportTableColumn{
+			{Key: "account_code", Label: "Account Code", Format: "text"},
+			{Key: "account_name", Label: "Account", Format: "text"},
+		},
+	}
+	byMonth := len(report.Rows) > 0 && report.Rows[0].Month != ""
+	if byMonth {
+		table.Columns = append(table.Columns, utils.ReportTableColumn{Key: "month", Label: "Month", Format: "text"})
+	}
+	table.Columns = append(table.Columns,
+		utils.ReportTableColumn{Key: "budget", Label: "Budget", Format: "money"},
+		utils.ReportTableColumn{Key: "actual", Label: "Actual", Format: "money"},
+		utils.ReportTableColumn{Key: "variance", Label: "Variance", Format: "money"},
+		utils.ReportTableColumn{Key: "variance_pct", Label: "Variance %", Format: "percent"},
+		utils.ReportTableColumn{Key: "favorable", Label: "Favorable", Format: "text"},
+	)
+	for _, r := range report.Rows {
+		row := map[string]interface{}{
+			"account_code": valueOrEmpty(r.AccountCode),
+			"account_name": r.AccountName,
+			"month":        r.Month,
+			"budget":       r.Budget,
+			"actual":       r.Actual,
+			"variance":     r.Variance,
+			"variance_pct": nil,
+			"favorable":    "No",
+		}
+		if r.VariancePct != nil {
+			row["variance_pct"] = *r.VariancePct
+		}
+		if r.Favorable {
+			row["favorable"] = "Yes"
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// budgetQueryer is satisfied by *sql.DB and *sql.Tx.
+type budgetQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// budgetPendingExpensesSQL sums expenses whose ledger posting is still
+// queued in the finance outbox, so spend committed moments ago counts
+// against a budget before the posting lands.
+const budgetPendingExpensesSQL = `
+	SELECT COALESCE(SUM(ex.amount), 0)::float8
+	FROM finance_integrity_outbox fo
+	JOIN expenses ex ON ex.expense_id = fo.aggregate_id AND ex.is_deleted = FALSE
+	WHERE fo.company_id = $1 AND fo.event_type = $2 AND fo.aggregate_type = 'expense'
+	  AND fo.status <> 'COMPLETED'
+	  AND ex.expense_date >= $3::date AND ex.expense_date < $4::date
+	  AND ($5::int IS NULL OR ex.location_id = $5)
+	  AND ($6::int IS NULL OR ex.department_id = $6)
+	  AND NOT EXISTS (
+	    SELECT 1 FROM ledger_entries le
+	    WHERE le.company_id = $1 AND le.transaction_type = 'expense' AND le.transaction_id = ex.expense_id
+	  )
+`
+
+// budgetSpendLockKey is the advisory lock serializing spend checks against
+// one budget line (company, budget and account).
+func budgetSpendLockKey(companyID, budgetID, accountID int) int64 {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "budget_spend\x00%d\x00%d\x00%d", companyID, budgetID, accountID)
+	return int64(h.Sum64())
+}
+
+type budgetControlCandidate struct {
+	id          int
+	name        string
+	fiscalYear  int
+	startMonth  int
+	locationID  *int
+	deptID      *int
+	controlMode string
+	budget      float64
+}
+
+// checkBudgetSpend tests whether posting amount to the account with
+// accountCode on date would exceed any active, controlled budget covering
+// the company, the location or the department. It returns warnings for WARN
+// budgets and an error for BLOCK budgets.
+//
+// Run it in the transaction that records the spend: each BLOCK budget is
+// locked until that transaction ends, so concurrent spends against the same
+// budget are checked one after the other and cannot both slip under it.
+func checkBudgetSpend(q budgetQueryer, companyID, locationID int, departmentID *int, accountCode string, date time.Time, amount float64) ([]string, error) {
+	if amount <= 0 {
+		return nil, nil
+	}
+	var (
+		accountID   int
+		accountName string
+		accountType string
+	)
+	err := q.QueryRow(`
+		SELECT account_id, name, type FROM chart_of_accounts
+		WHERE company_id = $1 AND account_code = $2 AND is_active = TRUE
+		ORDER BY account_id
+		LIMIT 1
+	`, companyID, accountCode).Scan(&accountID, &accountName, &accountType)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budget account: %w", err)
+	}
+
+	day := date.Format("2006-01-02")
+	rows, err := q.Query(`
+		SELECT b.budget_id, b.name, b.fiscal_year, b.start_month, b.location_id, b.department_id, b.control_mode,
+		       COALESCE(SUM(bl.amount), 0)::float8
+		FROM budgets b
+		JOIN budget_lines bl ON bl.budget_id = b.budget_id AND bl.account_id = $2
+		WHERE b.company_id = $1 AND b.status = 'ACTIVE' AND b.is_deleted = FALSE AND b.control_mode <> 'NONE'
+		  AND make_date(b.fiscal_year, b.start_month, 1) <= $3::date
+		  AND (make_date(b.fiscal_year, b.start_month, 1) + INTERVAL '1 year')::date > $3::date
+		  AND (b.location_id IS NULL OR b.location_id = $4)
+		  AND (b.department_id IS NULL OR b.department_id = $5::int)
+		GROUP BY b.budget_id
+		ORDER BY b.budget_id
+	`, companyID, accountID, day, locationID, nullableIntArg(departmentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budgets: %w", err)
+	}
+	var candidates []budgetControlCandidate
+	for rows.Next() {
+		var c budgetControlCandidate
+		if err := rows.Scan(&c.id, &c.name, &c.fiscalYear, &c.startMonth, &c.locationID, &c.deptID, &c.controlMode, &c.budget); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read budgets: %w", err)
+	}
+
+	// Candidates come ordered by budget_id, so concurrent checks take the
+	// locks in the same order.
+	for _, c := range candidates {
+		if c.controlMode != models.BudgetControlBlock {
+			continue
+		}
+		if _, err := q.Exec(`SELECT pg_advisory_xact_lock($1)`, budgetSpendLockKey(companyID, c.id, accountID)); err != nil {
+			return nil, fmt.Errorf("failed to lock budget: %w", err)
+		}
+	}
+
+	var warnings, blocked []string
+	for _, c := range candidates {
+		start, end := budgetPeriod(c.fiscalYear, c.startMonth)
+		var net float64
+		if err := q.QueryRow(`
+			SELECT COALESCE(SUM(x.debit - x.credit), 0)::float8 FROM (`+budgetActualEntriesSQL+`) x
+			WHERE x.account_id = $6
+		`, companyID, start.Format("2006-01-02"), end.Format("2006-01-02"),
+			nullableIntArg(c.locationID), nullableIntArg(c.deptID), accountID).Scan(&net); err != nil {
+			return nil, fmt.Errorf("failed to load budget actuals: %w", err)
+		}
+		if accountCode == accountCodeExpenses {
+			var pending float64
+			if err := q.QueryRow(budgetPendingExpensesSQL, companyID, financeEventLedgerExpense,
+				start.Format("2006-01-02"), end.Format("2006-01-02"),
+				nullableIntArg(c.locationID), nullableIntArg(c.deptID)).Scan(&pending); err != nil {
+				return nil, fmt.Errorf("failed to load pending budget spend: %w", err)
+			}
+			net += pending
+		}
+		remaining := roundBudgetAmount(c.budget - budgetNaturalAmount(accountType, net))
+		if remaining-amount >= 0 {
+			continue
+		}
+		msg := fmt.Sprintf("%s budget %q has %.2f remaining for %s %s; this needs %.2f",
+			describeBudgetYear(c.fiscalYear, c.startMonth), c.name, remaining, accountCode, accountName, amount)
+		if c.controlMode == models.BudgetControlBlock {
+			blocked = append(blocked, msg)
+		} else {
+			warnings = append(warnings, msg)
+		}
+	}
+	if len(blocked) > 0 {
+		return warnings, fmt.Errorf("budget exceeded: %s", strings.Join(blocked, "; "))
+	}
+	return warnings, nil
+}
+
+func describeBudgetYear(fiscalYear, startMonth int) string {
+	if startMonth == 1 {
+		return fmt.Sprintf("FY%d", fiscalYear)
+	}
+	return fmt.Sprintf("FY%d/%02d", fiscalYear, (fiscalYear+1)%100)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"erp-backend/internal/models"
+)
+
+func TestBudgetMonthIndexFollowsFiscalStart(t *testing.T) {
+	cases := []struct {
+		date string
+		want int
+	}{
+		{"2026-06-30", -1},
+		{"2026-07-01", 0},
+		{"2026-12-15", 5},
+		{"2027-01-01", 6},
+		{"2027-06-30", 11},
+		{"2027-07-01", -1},
+	}
+	for _, tc := range cases {
+		d, _ := time.Parse("2006-01-02", tc.date)
+		if got := budgetMonthIndex(2026, 7, d); got != tc.want {
+			t.Fatalf("%s: expected month %d, got %d", tc.date, tc.want, got)
+		}
+	}
+	labels := budgetMonthLabels(2026, 7)
+	if labels[0] != "Jul 2026" || labels[11] != "Jun 2027" {
+		t.Fatalf("unexpected labels %v", labels)
+	}
+}
+
+func TestBuildBudgetVsActualRows(t *testing.T) {
+	revCode, expCode := "4000", "6000"
+	b := &models.Budget{FiscalYear: 2026, StartMonth: 1}
+	revenue := models.BudgetLine{AccountID: 1, AccountCode: &revCode, AccountName: "Sales", AccountType: "REVENUE"}
+	expense := models.BudgetLine{AccountID: 2, AccountCode: &expCode, AccountName: "Expenses", AccountType: "EXPENSE"}
+	revenue.Months[0], revenue.Months[1] = 1000, 1000
+	expense.Months[0], expense.Months[1] = 400, 400
+	b.Lines = []models.BudgetLine{expense, revenue}
+
+	rentCode, cashCode := "6100", "1000"
+	accounts := map[int]budgetAccountInfo{
+		3: {code: &rentCode, name: "Rent", typ: "EXPENSE"},
+		4: {code: &cashCode, name: "Cash", typ: "ASSET"},
+	}
+	actuals := map[budgetActualKey]float64{
+		{1, 0}: 1200, {1, 1}: 700,
+		{2, 0}: 500, {2, 1}: 200,
+		{3, 1}: 150,
+		{4, 0}: 9999,
+	}
+
+	rows := buildBudgetVsActualRows(b, accounts, actuals, 1, 2, false)
+	if len(rows) != 3 {
+		t.Fatalf("expected revenue, expense and unbudgeted rent rows, got %+v", rows)
+	}
+	if rows[0].AccountName != "Sales" || rows[0].Budget != 2000 || rows[0].Actual != 1900 ||
+		rows[0].Variance != -100 || *rows[0].VariancePct != -5 || rows[0].Favorable {
+		t.Fatalf("unexpected revenue row %+v", rows[0])
+	}
+	if rows[1].AccountName != "Expenses" || rows[1].Variance != -100 || !rows[1].Favorable {
+		t.Fatalf("unexpected expense row %+v", rows[1])
+	}
+	if rows[2].AccountName != "Rent" || rows[2].Budget != 0 || rows[2].Actual != 150 || rows[2].VariancePct != nil || rows[2].Favorable {
+		t.Fatalf("unexpected unbudgeted row %+v", rows[2])
+	}
+
+	monthly := buildBudgetVsActualRows(b, accounts, actuals, 2, 2, true)
+	if len(monthly) != 3 || monthly[0].Month != "2026-02" || monthly[0].Actual != 700 {
+		t.Fatalf("unexpected monthly rows %+v", monthly)
+	}
+}
+
+func TestCheckBudgetSpendWarnsAndBlocks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	dept := 5
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT account_id, name, type FROM chart_of_accounts")).
+		WithArgs(1, accountCodeExpenses).
+		WillReturnRows(sqlmock.NewRows([]string{"account_id", "name", "type"}).AddRow(60, "Expenses", "EXPENSE"))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM budgets b")).
+		WithArgs(1, 60, "2026-03-10", 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"budget_id", "name", "fiscal_year", "start_month", "location_id", "department_id", "control_mode", "budget"}).
+			AddRow(10, "Company plan", 2026, 1, nil, nil, models.BudgetControlWarn, 1000.0).
+			AddRow(11, "Marketing", 2026, 1, nil, 5, models.BudgetControlBlock, 300.0).
+			AddRow(12, "Store 2", 2026, 1, 2, nil, models.BudgetControlBlock, 5000.0))
+	// Only the BLOCK budgets are locked, in budget order.
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WithArgs(budgetSpendLockKey(1, 11, 60)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WithArgs(budgetSpendLockKey(1, 12, 60)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Actuals are the ledger plus expenses still waiting to be posted.
+	mock.ExpectQuery(regexp.QuoteMeta("FROM ledger_entries le")).
+		WithArgs(1, "2026-01-01", "2027-01-01", nil, nil, 60).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(900.0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM finance_integrity_outbox fo")).
+		WithArgs(1, financeEventLedgerExpense, "2026-01-01", "2027-01-01", nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM ledger_entries le")).
+		WithArgs(1, "2026-01-01", "2027-01-01", nil, 5, 60).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(200.0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM finance_integrity_outbox fo")).
+		WithArgs(1, financeEventLedgerExpense, "2026-01-01", "2027-01-01", nil, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(50.0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM ledger_entries le")).
+		WithArgs(1, "2026-01-01", "2027-01-01", 2, nil, 60).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(100.0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM finance_integrity_outbox fo")).
+		WithArgs(1, financeEventLedgerExpense, "2026-01-01", "2027-01-01", 2, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+
+	warnings, err := checkBudgetSpend(db, 1, 2, &dept, accountCodeExpenses, date, 150)
+	if err == nil || !strings.HasPrefix(err.Error(), "budget exceeded") || !strings.Contains(err.Error(), `"Marketing" has 50.00 remaining`) {
+		t.Fatalf("expected Marketing budget to block, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"Company plan" has 100.00 remaining`) {
+		t.Fatalf("expected company plan warning, got %v", warnings)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"erp-backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// budgetMonthLabels names the fiscal months as they appear in budget sheets,
+// e.g. "Jul 2026" ... "Jun 2027" for a July start.
+func budgetMonthLabels(fiscalYear, startMonth int) [12]string {
+	var labels [12]string
+	start, _ := budgetPeriod(fiscalYear, startMonth)
+	for i := range labels {
+		labels[i] = start.AddDate(0, i, 0).Format("Jan 2006")
+	}
+	return labels
+}
+
+// ExportBudgetSheetXLSX writes the budget as one row per account with a
+// column per fiscal month. Active revenue and expense accounts without
+// amounts are included so the sheet doubles as an entry template.
+func (s *BudgetService) ExportBudgetSheetXLSX(companyID, budgetID int) ([]byte, string, error) {
+	b, err := s.GetBudget(companyID, budgetID)
+	if err != nil {
+		return nil, "", err
+	}
+	lines := b.Lines
+	seen := map[int]bool{}
+	for _, line := range lines {
+		seen[line.AccountID] = true
+	}
+	rows, err := s.db.Query(`
+		SELECT account_id, account_code, name, type
+		FROM chart_of_accounts
+		WHERE company_id = $1 AND is_active = TRUE AND type IN ('REVENUE', 'EXPENSE')
+		ORDER BY account_code NULLS LAST, name
+	`, companyID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.BudgetLine
+		if err := rows.Scan(&line.AccountID, &line.AccountCode, &line.AccountName, &line.AccountType); err != nil {
+			return nil, "", fmt.Errorf("failed to scan account: %w", err)
+		}
+		if !seen[line.AccountID] {
+			lines = append(lines, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read accounts: %w", err)
+	}
+
+	f := excelize.NewFile()
+	sheet := "Budget"
+	f.SetSheetName("Sheet1", sheet)
+
+	headers := []string{"Account Code", "Account Name"}
+	for _, label := range budgetMonthLabels(b.FiscalYear, b.StartMonth) {
+		headers = append(headers, label)
+	}
+	headers = append(headers, "Total")
+	for i, h := range headers {
+		cellName, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cellName, h)
+	}
+	_ = f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		Split:       true,
+		XSplit:      2,
+		YSplit:      1,
+		TopLeftCell: "C2",
+		ActivePane:  "bottomRight",
+	})
+
+	for idx, line := range lines {
+		r := idx + 2
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", r), valueOrEmpty(line.AccountCode))
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", r), line.AccountName)
+		for m, amount := range line.Months {
+			cellName, _ := excelize.CoordinatesToCellName(m+3, r)
+			f.SetCellValue(sheet, cellName, amount)
+		}
+		_ = f.SetCellFormula(sheet, fmt.Sprintf("O%d", r), fmt.Sprintf("SUM(C%d:N%d)", r, r))
+	}
+	_ = f.SetColWidth(sheet, "B", "B", 32)
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate file: %w", err)
+	}
+	name := fmt.Sprintf("budget_%d_v%d.xlsx", b.FiscalYear, b.VersionNo)
+	return buf.Bytes(), name, nil
+}
+
+// ImportBudgetSheetXLSX replaces a draft budget's lines with the sheet's.
+// Accounts are matched by code, falling back to name. Month columns are
+// matched by the exported labels or "Month 1".."Month 12". Nothing is saved
+// when any row has an error.
+func (s *BudgetService) ImportBudgetSheetXLSX(companyID, userID, budgetID int, data []byte) (*models.ImportResult, error) {
+	b, err := s.getBudgetHeader(companyID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status != models.BudgetStatusDraft {
+		return nil, fmt.Errorf("only draft budgets can be imported into; revise the budget instead")
+	}
+
+	xl, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Excel file: %w", err)
+	}
+	rows, err := xl.GetRows(xl.GetSheetName(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("sheet is empty")
+	}
+
+	hdr := headerIndex(rows[0])
+	codeIdx, hasCode := firstHeaderMatch(hdr, "account code", "account_code", "code")
+	nameIdx, hasName := firstHeaderMatch(hdr, "account name", "account_name", "account")
+	if !hasCode && !hasName {
+		return nil, fmt.Errorf("missing required column: Account Code")
+	}
+	if !hasCode {
+		codeIdx = -1
+	}
+	if !hasName {
+		nameIdx = -1
+	}
+	labels := budgetMonthLabels(b.FiscalYear, b.StartMonth)
+	var monthIdx [12]int
+	for m := range monthIdx {
+		i, ok := firstHeaderMatch(hdr, labels[m], fmt.Sprintf("month %d", m+1), fmt.Sprintf("m%d", m+1))
+		if !ok {
+			return nil, fmt.Errorf("missing month column: %s", labels[m])
+		}
+		monthIdx[m] = i
+	}
+
+	byCode := map[string]int{}
+	byName := map[string]int{}
+	accountRows, err := s.db.Query(`
+		SELECT account_id, account_code, name FROM chart_of_accounts WHERE company_id = $1
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	for accountRows.Next() {
+		var (
+			id   int
+			code *string
+			name string
+		)
+		if err := accountRows.Scan(&id, &code, &name); err != nil {
+			accountRows.Close()
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		if code != nil && *code != "" {
+			byCode[normalizeHeader(*code)] = id
+		}
+		byName[normalizeHeader(name)] = id
+	}
+	accountRows.Close()
+
+	res := &models.ImportResult{Errors: make([]models.ImportRowError, 0)}
+	var lines []models.BudgetLineInput
+	seen := map[int]int{}
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		code, name := cell(row, codeIdx), cell(row, nameIdx)
+		if code == "" && name == "" {
+			res.Skipped++
+			continue
+		}
+		accountID, ok := byCode[normalizeHeader(code)]
+		if code == "" || !ok {
+			accountID, ok = byName[normalizeHeader(name)]
+		}
+		if !ok {
+			res.Errors = append(res.Errors, models.ImportRowError{Row: rowNum, Column: "Account Code", Message: "unknown account"})
+			continue
+		}
+		if prev, dup := seen[accountID]; dup {
+			res.Errors = append(res.Errors, models.ImportRowError{Row: rowNum, Column: "Account Code", Message: fmt.Sprintf("account already listed on row %d", prev)})
+			continue
+		}
+		seen[accountID] = rowNum
+
+		months := make([]float64, 12)
+		var nonZero, bad bool
+		for m, idx := range monthIdx {
+			raw := strings.ReplaceAll(cell(row, idx), ",", "")
+			if raw == "" {
+				continue
+			}
+			v, ok := parseFloatLoose(raw)
+			if !ok {
+				res.Errors = append(res.Errors, models.ImportRowError{Row: rowNum, Column: labels[m], Message: "invalid number"})
+				bad = true
+				break
+			}
+			months[m] = v
+			nonZero = nonZero || v != 0
+		}
+		if bad {
+			continue
+		}
+		if !nonZero {
+			res.Skipped++
+			continue
+		}
+		lines = append(lines, models.BudgetLineInput{AccountID: accountID, Months: months})
+	}
+	if len(res.Errors) > 0 {
+		res.Skipped += len(lines)
+		return res, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := replaceBudgetLines(tx, companyID, budgetID, lines); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE budgets SET updated_by = $1, updated_at = CURRENT_TIMESTAMP WHERE budget_id = $2
+	`, userID, budgetID); err != nil {
+		return nil, fmt.Errorf("failed to update budget: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit budget import: %w", err)
+	}
+	res.Count = len(lines)
+	res.Created = len(lines)
+	return res, nil
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+// cashRegisterWindowsCTE expands a register ($1), optionally narrowed to one
+// shift ($2), into the cashier windows its activity is attributed to. Sales and
+// returns are not stamped with a register, so a document belongs to a register
+// when its creator held a shift there at the time it was created.
+const cashRegisterWindowsCTE = `
+        WITH windows AS (
+            SELECT sh.cashier_id, sh.location_id, sh.started_at,
+                   COALESCE(sh.ended_at, CURRENT_TIMESTAMP) AS ended_at
+            FROM cash_register_shifts sh
+            WHERE sh.register_id = $1 AND ($2::int IS NULL OR sh.shift_id = $2)
+        )`
+
+// cashRegisterInWindow matches rows of the given alias against the windows CTE.
+func cashRegisterInWindow(alias string) string {
+	return fmt.Sprintf(`EXISTS (
+            SELECT 1 FROM windows w
+            WHERE w.location_id = %[1]s.location_id AND w.cashier_id = %[1]s.created_by
+              AND %[1]s.created_at >= w.started_at AND %[1]s.created_at <= w.ended_at
+        )`, alias)
+}
+
+// GetXReport computes a mid-shift report for a register, or for one shift of
+// it. Without a register id the caller's open register is used. X reports are
+// not stored and can be run any number of times.
+func (s *CashRegisterService) GetXReport(companyID, locationID, userID int, sessionID string, registerID, shiftID *int) (*models.CashRegisterReport, error) {
+	id := 0
+	if registerID != nil {
+		id = *registerID
+	}
+	if id <= 0 {
+		resolved, err := s.resolveOpenRegister(s.db, companyID, locationID, userID, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		id = resolved
+	}
+	return s.buildCashRegisterReport(s.db, companyID, id, shiftID, models.CashRegisterReportX, userID)
+}
+
+// issueZReportTx freezes the end-of-day report for a register being closed.
+// Z numbers come from a per-location counter that can only move forward.
+func (s *CashRegisterService) issueZReportTx(tx *sql.Tx, companyID, registerID, userID int) (*models.CashRegisterZRef, error) {
+	report, err := s.buildCashRegisterReport(tx, companyID, registerID, nil, models.CashRegisterReportZ, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var number int
+	if err := tx.QueryRow(`
+        INSERT INTO cash_register_z_counters (location_id, last_number)
+        VALUES ($1, 1)
+        ON CONFLICT (location_id) DO UPDATE SET last_number = cash_register_z_counters.last_number + 1
+        RETURNING last_number
+    `, report.LocationID).Scan(&number); err != nil {
+		return nil, fmt.Errorf("failed to allocate Z number: %w", err)
+	}
+	report.ZNumber = &number
+
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Z report: %w", err)
+	}
+	var zReportID int
+	if err := tx.QueryRow(`
+        INSERT INTO cash_register_z_reports (location_id, register_id, z_number, period_start, period_end, report, generated_by, generated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING z_report_id
+    `, report.LocationID, registerID, number, report.PeriodStart, report.PeriodEnd, string(payload), userID, report.GeneratedAt).Scan(&zReportID); err != nil {
+		return nil, fmt.Errorf("failed to store Z report: %w", err)
+	}
+	return &models.CashRegisterZRef{ZReportID: zReportID, ZNumber: number}, nil
+}
+
+// GetZReport returns a stored Z report exactly as it was frozen at close.
+func (s *CashRegisterService) GetZReport(companyID, zReportID int) (*models.CashRegisterReport, error) {
+	var payload []byte
+	err := s.db.QueryRow(`
+        SELECT z.report
+        FROM cash_register_z_reports z
+        JOIN locations l ON l.location_id = z.location_id
+        WHERE z.z_report_id = $1 AND l.company_id = $2
+    `, zReportID, companyID).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("z report not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Z report: %w", err)
+	}
+	var report models.CashRegisterReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return nil, fmt.Errorf("failed to decode Z report: %w", err)
+	}
+	report.ZReportID = &zReportID
+	return &report, nil
+}
+
+// ListZReports lists Z reports for a location, newest number first.
+func (s *CashRegisterService) ListZReports(companyID, locationID, limit int) ([]models.CashRegisterZReportSummary, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+	rows, err := s.db.Query(`
+        SELECT z.z_report_id, z.z_number, z.location_id, z.register_id, COALESCE(cr.terminal_id, ''),
+               z.period_start, z.period_end,
+               COALESCE((z.report->'sales'->>'total')::float8, 0),
+               z.generated_by, z.generated_at
+        FROM cash_register_z_reports z
+        JOIN locations l ON l.location_id = z.location_id
+        JOIN cash_register cr ON cr.register_id = z.register_id
+        WHERE l.company_id = $1 AND z.location_id = $2
+        ORDER BY z.z_number DESC
+        LIMIT $3
+    `, companyID, locationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Z reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.CashRegisterZReportSummary{}
+	for rows.Next() {
+		var z models.CashRegisterZReportSummary
+		if err := rows.Scan(&z.ZReportID, &z.ZNumber, &z.LocationID, &z.RegisterID, &z.TerminalID,
+			&z.PeriodStart, &z.PeriodEnd, &z.SalesTotal, &z.GeneratedBy, &z.GeneratedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan Z report: %w", err)
+		}
+		reports = append(reports, z)
+	}
+	return reports, rows.Err()
+}
+
+func (s *CashRegisterService) buildCashRegisterReport(
+	q cashRegisterQueryer,
+	companyID, registerID int,
+	shiftID *int,
+	reportType string,
+	userID int,
+) (*models.CashRegisterReport, error) {
+	report := &models.CashRegisterReport{
+		ReportType:  reportType,
+		RegisterID:  registerID,
+		ShiftID:     shiftID,
+		GeneratedAt: time.Now(),
+		GeneratedBy: userID,
+	}
+
+	var closedAt sql.NullTime
+	err := q.QueryRow(`
+        SELECT cr.location_id, COALESCE(cr.terminal_id, ''),
+               COALESCE(cr.opening_balance, 0)::float8, COALESCE(cr.cash_in, 0)::float8,
+               COALESCE(cr.cash_out, 0)::float8, COALESCE(cr.expected_balance, 0)::float8,
+               COALESCE(cr.opened_at, cr.created_at, CURRENT_TIMESTAMP)::timestamp, cr.closed_at
+        FROM cash_register cr
+        JOIN locations l ON l.location_id = cr.location_id
+        WHERE cr.register_id = $1 AND l.company_id = $2
+    `, registerID, companyID).Scan(
+		&report.LocationID, &report.TerminalID,
+		&report.OpeningBalance, &report.CashIn, &report.CashOut, &report.ExpectedCash,
+		&report.PeriodStart, &closedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("cash register not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cash register: %w", err)
+	}
+	report.PeriodEnd = report.GeneratedAt
+	if closedAt.Valid {
+		report.PeriodEnd = closedAt.Time
+	}
+
+	if shiftID != nil {
+		var endedAt sql.NullTime
+		err := q.QueryRow(`
+            SELECT started_at, ended_at FROM cash_register_shifts
+            WHERE shift_id = $1 AND register_id = $2
+        `, *shiftID, registerID).Scan(&report.PeriodStart, &endedAt)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("shift not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shift: %w", err)
+		}
+		report.PeriodEnd = report.GeneratedAt
+		if endedAt.Valid {
+			report.PeriodEnd = endedAt.Time
+		}
+	}
+
+	if err := q.QueryRow(cashRegisterWindowsCTE+`
+        SELECT COUNT(*) FILTER (WHERE s.status = 'COMPLETED'),
+               COALESCE(SUM(s.subtotal) FILTER (WHERE s.status = 'COMPLETED'), 0)::float8,
+               COALESCE(SUM(s.discount_amount) FILTER (WHERE s.status = 'COMPLETED'), 0)::float8,
+               COALESCE(SUM(s.tax_amount) FILTER (WHERE s.status = 'COMPLETED'), 0)::float8,
+               COALESCE(SUM(s.total_amount) FILTER (WHERE s.status = 'COMPLETED'), 0)::float8,
+               COUNT(*) FILTER (WHERE s.status = 'VOID'),
+               COALESCE(SUM(ABS(s.subtotal)) FILTER (WHERE s.status = 'VOID'), 0)::float8,
+               COALESCE(SUM(ABS(s.discount_amount)) FILTER (WHERE s.status = 'VOID'), 0)::float8,
+               COALESCE(SUM(ABS(s.tax_amount)) FILTER (WHERE s.status = 'VOID'), 0)::float8,
+               COALESCE(SUM(ABS(s.total_amount)) FILTER (WHERE s.status = 'VOID'), 0)::float8
+        FROM sales s
+        WHERE s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE
+          AND `+cashRegisterInWindow("s"),
+		registerID, shiftID,
+	).Scan(
+		&report.Sales.Count, &report.Sales.Subtotal, &report.Sales.Discounts, &report.Sales.Tax, &report.Sales.Total,
+		&report.Voids.Count, &report.Voids.Subtotal, &report.Voids.Discounts, &report.Voids.Tax, &report.Voids.Total,
+	); err != nil {
+		return nil, fmt.Errorf("failed to summarize register sales: %w", err)
+	}
+
+	if err := q.QueryRow(cashRegisterWindowsCTE+`
+        SELECT COUNT(*), COALESCE(SUM(sr.total_amount), 0)::float8
+        FROM sale_returns sr
+        WHERE sr.is_deleted = FALSE AND COALESCE(sr.status, 'COMPLETED') = 'COMPLETED'
+          AND `+cashRegisterInWindow("sr"),
+		registerID, shiftID,
+	).Scan(&report.Returns.Count, &report.Returns.Total); err != nil {
+		return nil, fmt.Errorf("failed to summarize register returns: %w", err)
+	}
+	report.Returns.Subtotal = report.Returns.Total
+
+	taxRows, err := q.Query(cashRegisterWindowsCTE+`
+        SELECT COALESCE(t.name, 'No Tax'), COALESCE(t.percentage, 0)::float8,
+               COALESCE(SUM(sd.line_total), 0)::float8, COALESCE(SUM(sd.tax_amount), 0)::float8
+        FROM sale_details sd
+        JOIN sales s ON s.sale_id = sd.sale_id
+        LEFT JOIN taxes t ON t.tax_id = sd.tax_id
+        WHERE s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE AND s.status = 'COMPLETED'
+          AND `+cashRegisterInWindow("s")+`
+        GROUP BY 1, 2
+        ORDER BY 1, 2`,
+		registerID, shiftID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get register tax breakdown: %w", err)
+	}
+	report.TaxBreakdown = []models.CashRegisterTaxLine{}
+	for taxRows.Next() {
+		var line models.CashRegisterTaxLine
+		if err := taxRows.Scan(&line.TaxName, &line.TaxRate, &line.TaxableAmount, &line.TaxAmount); err != nil {
+			taxRows.Close()
+			return nil, fmt.Errorf("failed to scan tax line: %w", err)
+		}
+		report.TaxBreakdown = append(report.TaxBreakdown, line)
+	}
+	taxRows.Close()
+
+	expected, err := s.expectedTendersTx(q, registerID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	declared, err := s.reportDeclaredTenders(q, registerID, shiftID, closedAt.Valid)
+	if err != nil {
+		return nil, err
+	}
+	report.Tenders = cashRegisterTenderLines(expected, declared)
+
+	moveRows, err := q.Query(`
+        SELECT e.event_type, COALESCE(e.reason_code, ''), COALESCE(e.direction, ''),
+               COUNT(*), COALESCE(SUM(e.amount), 0)::float8
+        FROM cash_register_events e
+        LEFT JOIN cash_register_shifts sh ON sh.shift_id = $2
+        WHERE e.register_id = $1
+          AND ($2::int IS NULL OR (e.created_at >= sh.started_at AND e.created_at <= COALESCE(sh.ended_at, CURRENT_TIMESTAMP)))
+        GROUP BY 1, 2, 3
+        ORDER BY 1, 2, 3
+    `, registerID, shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize register movements: %w", err)
+	}
+	report.Movements = []models.CashRegisterMovementLine{}
+	for moveRows.Next() {
+		var line models.CashRegisterMovementLine
+		if err := moveRows.Scan(&line.EventType, &line.ReasonCode, &line.Direction, &line.Count, &line.Amount); err != nil {
+			moveRows.Close()
+			return nil, fmt.Errorf("failed to scan movement: %w", err)
+		}
+		report.Movements = append(report.Movements, line)
+	}
+	moveRows.Close()
+
+	if report.Shifts, err = s.listShifts(q, companyID, report.LocationID, &registerID, 0); err != nil {
+		return nil, err
+	}
+	if report.Events, err = s.listEvents(q, companyID, report.LocationID, &registerID, 0); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// reportDeclaredTenders loads the blind count that belongs in a report: the
+// shift-close count for a shift, the register-close count for a closed
+// register, and nothing while the drawer is still open.
+func (s *CashRegisterService) reportDeclaredTenders(q sqlQueryer, registerID int, shiftID *int, registerClosed bool) (map[string]float64, error) {
+	countType := models.CashCountRegisterClose
+	if shiftID != nil {
+		countType = models.CashCountShiftClose
+	} else if !registerClosed {
+		return nil, nil
+	}
+	rows, err := q.Query(`
+        SELECT tender, declared::float8
+        FROM cash_register_tender_counts
+        WHERE register_id = $1 AND count_type = $2 AND ($3::int IS NULL OR shift_id = $3)
+        ORDER BY counted_at, count_id
+    `, registerID, countType, shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get declared tenders: %w", err)
+	}
+	defer rows.Close()
+	declared := map[string]float64{}
+	for rows.Next() {
+		var tender string
+		var amount float64
+		if err := rows.Scan(&tender, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan declared tender: %w", err)
+		}
+		declared[tender] = amount
+	}
+	return declared, rows.Err()
+}
+
+// cashRegisterTenderLines lists every tender with an expected or declared
+// amount. Declared and variance stay empty until a blind count exists.
+func cashRegisterTenderLines(expected, declared map[string]float64) []models.CashRegisterTenderLine {
+	lines := []models.CashRegisterTenderLine{}
+	for _, tender := range cashTenderOrder {
+		exp := round2(expected[tender])
+		dec, counted := declared[tender]
+		if tender != models.CashTenderCash && exp == 0 && !counted {
+			continue
+		}
+		line := models.CashRegisterTenderLine{Tender: tender, Expected: exp}
+		if declared != nil {
+			d := round2(dec)
+			v := round2(d - exp)
+			line.Declared = &d
+			line.Variance = &v
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// CashRegisterReportLines flattens an X/Z report into section/label/amount
+// rows for the spreadsheet and PDF exporters.
+func CashRegisterReportLines(report *models.CashRegisterReport) []models.CashRegisterReportLine {
+	if report == nil {
+		return nil
+	}
+	count := func(n int) *int { return &n }
+	lines := []models.CashRegisterReportLine{
+		{Section: "DRAWER", Label: "Opening balance", Amount: report.OpeningBalance},
+		{Section: "DRAWER", Label: "Cash in", Amount: report.CashIn},
+		{Section: "DRAWER", Label: "Cash out", Amount: report.CashOut},
+		{Section: "DRAWER", Label: "Expected cash", Amount: report.ExpectedCash},
+		{Section: "SALES", Label: "Gross sales", Count: count(report.Sales.Count), Amount: report.Sales.Subtotal},
+		{Section: "SALES", Label: "Discounts", Amount: report.Sales.Discounts},
+		{Section: "SALES", Label: "Tax", Amount: report.Sales.Tax},
+		{Section: "SALES", Label: "Net sales", Amount: report.Sales.Total},
+		{Section: "RETURNS", Label: "Returns", Count: count(report.Returns.Count), Amount: report.Returns.Total},
+		{Section: "VOIDS", Label: "Voids", Count: count(report.Voids.Count), Amount: report.Voids.Total},
+	}
+	for _, t := range report.TaxBreakdown {
+		lines = append(lines,
+			models.CashRegisterReportLine{Section: "TAX", Label: fmt.Sprintf("%s (%.2f%%) taxable", t.TaxName, t.TaxRate), Amount: t.TaxableAmount},
+			models.CashRegisterReportLine{Section: "TAX", Label: fmt.Sprintf("%s (%.2f%%) tax", t.TaxName, t.TaxRate), Amount: t.TaxAmount},
+		)
+	}
+	for _, t := range report.Tenders {
+		lines = append(lines, models.CashRegisterReportLine{Section: "TENDER", Label: t.Tender + " expected", Amount: t.Expected})
+		if t.Declared != nil {
+			lines = append(lines, models.CashRegisterReportLine{Section: "TENDER", Label: t.Tender + " declared", Amount: *t.Declared})
+		}
+		if t.Variance != nil {
+			lines = append(lines, models.CashRegisterReportLine{Section: "TENDER", Label: t.Tender + " variance", Amount: *t.Variance})
+		}
+	}
+	for _, m := range report.Movements {
+		label := m.EventType
+		if m.ReasonCode != "" && m.ReasonCode != m.EventType {
+			label += " / " + m.ReasonCode
+		}
+		if m.Direction != "" {
+			label += " (" + m.Direction + ")"
+		}
+		lines = append(lines, models.CashRegisterReportLine{Section: "MOVEMENT", Label: label, Count: count(m.Count), Amount: m.Amount})
+	}
+	return lines
+}
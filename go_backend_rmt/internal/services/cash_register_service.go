@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -22,7 +23,8 @@ func NewCashRegisterService() *CashRegisterService {
 
 func (s *CashRegisterService) GetCashRegisters(companyID, locationID int) ([]models.CashRegister, error) {
 	query := `
-        SELECT cr.register_id, cr.location_id, cr.date, cr.opening_balance, cr.closing_balance,
+        SELECT cr.register_id, cr.location_id, COALESCE(cr.terminal_id, ''), cr.terminal_name,
+               cr.date, cr.opening_balance, cr.closing_balance,
                cr.expected_balance, cr.cash_in, cr.cash_out, cr.variance,
                cr.opened_by, cr.closed_by, cr.status,
                COALESCE(cr.training_mode, FALSE) AS training_mode,
@@ -44,7 +46,8 @@ func (s *CashRegisterService) GetCashRegisters(companyID, locationID int) ([]mod
 	for rows.Next() {
 		var cr models.CashRegister
 		err := rows.Scan(
-			&cr.RegisterID, &cr.LocationID, &cr.Date, &cr.OpeningBalance, &cr.ClosingBalance,
+			&cr.RegisterID, &cr.LocationID, &cr.TerminalID, &cr.TerminalName,
+			&cr.Date, &cr.OpeningBalance, &cr.ClosingBalance,
 			&cr.ExpectedBalance, &cr.CashIn, &cr.CashOut, &cr.Variance,
 			&cr.OpenedBy, &cr.ClosedBy, &cr.Status,
 			&cr.TrainingMode, &cr.TrainingModeUpdatedAt, &cr.TrainingModeUpdatedBy,
@@ -72,15 +75,17 @@ func (s *CashRegisterService) SetTrainingMode(
 	}
 	defer tx.Rollback()
 
-	var registerID int
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return err
+	}
 	var current bool
 	err = tx.QueryRow(`
-        SELECT cr.register_id, COALESCE(cr.training_mode, FALSE)
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        SELECT COALESCE(training_mode, FALSE)
+        FROM cash_register
+        WHERE register_id = $1 AND status = 'OPEN'
         FOR UPDATE
-    `, locationID, companyID).Scan(&registerID, &current)
+    `, registerID).Scan(&current)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("no open cash register")
 	}
@@ -133,12 +138,19 @@ func (s *CashRegisterService) SetTrainingMode(
 	return tx.Commit()
 }
 
+// OpenCashRegister opens a register for a terminal and starts the opener's
+// shift. The terminal defaults to the device of the caller's session, so each
+// till at a location keeps its own drawer.
 func (s *CashRegisterService) OpenCashRegister(
 	companyID, locationID, userID int,
-	openingBalance float64,
+	req *models.OpenCashRegisterRequest,
 	sessionID, requestID string,
 	ip, ua *string,
 ) (int, error) {
+	if req == nil {
+		return 0, fmt.Errorf("request is nil")
+	}
+	openingBalance := req.OpeningBalance
 	// Verify location belongs to company (no tx needed).
 	var count int
 	err := s.db.QueryRow(`SELECT COUNT(*) FROM locations WHERE location_id = $1 AND company_id = $2 AND is_active = TRUE`, locationID, companyID).Scan(&count)
@@ -155,9 +167,17 @@ func (s *CashRegisterService) OpenCashRegister(
 	}
 	defer tx.Rollback()
 
-	// Ensure no open register exists
+	terminalID := ""
+	if req.TerminalID != nil {
+		terminalID = strings.TrimSpace(*req.TerminalID)
+	}
+	if terminalID == "" {
+		terminalID = s.sessionTerminalID(tx, sessionID)
+	}
+
+	// Ensure the terminal has no open register
 	var existing int
-	err = tx.QueryRow(`SELECT register_id FROM cash_register WHERE location_id = $1 AND status = 'OPEN'`, locationID).Scan(&existing)
+	err = tx.QueryRow(`SELECT register_id FROM cash_register WHERE location_id = $1 AND terminal_id = $2 AND status = 'OPEN'`, locationID, terminalID).Scan(&existing)
 	if err != sql.ErrNoRows {
 		if err == nil {
 			return 0, fmt.Errorf("cash register already open")
@@ -169,15 +189,19 @@ func (s *CashRegisterService) OpenCashRegister(
 	openedSessionID := uuidOrNil(sessionID)
 	var registerID int
 	err = tx.QueryRow(`
-        INSERT INTO cash_register (location_id, date, opening_balance, closing_balance, expected_balance, cash_in, cash_out, variance, opened_by, status, opened_at, opened_session_id, opened_request_id)
-        VALUES ($1, CURRENT_DATE, $2, NULL, $2, 0, 0, 0, $3, 'OPEN', $4, $5, $6)
+        INSERT INTO cash_register (location_id, terminal_id, terminal_name, date, opening_balance, closing_balance, expected_balance, cash_in, cash_out, variance, opened_by, status, opened_at, opened_session_id, opened_request_id)
+        VALUES ($1, $2, $3, CURRENT_DATE, $4, NULL, $4, 0, 0, 0, $5, 'OPEN', $6, $7, $8)
         RETURNING register_id`,
-		locationID, openingBalance, userID, now, openedSessionID, nullIfEmptyString(requestID),
+		locationID, terminalID, req.TerminalName, openingBalance, userID, now, openedSessionID, nullIfEmptyString(requestID),
 	).Scan(&registerID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to open cash register: %w", err)
 	}
 
+	if _, err := s.startShiftTx(tx, registerID, locationID, userID, openingBalance, sessionID, nil); err != nil {
+		return 0, err
+	}
+
 	if _, err := s.insertCashRegisterEvent(
 		tx,
 		registerID,
@@ -200,6 +224,7 @@ func (s *CashRegisterService) OpenCashRegister(
 		"event_type":  "OPEN",
 		"register_id": registerID,
 		"location_id": locationID,
+		"terminal_id": terminalID,
 		"request_id":  requestID,
 		"session_id":  sessionID,
 	}
@@ -216,38 +241,68 @@ func (s *CashRegisterService) OpenCashRegister(
 	return registerID, nil
 }
 
+// CloseCashRegister performs a blind close of the caller's register: declared
+// per-tender counts are reconciled against expected totals, the open shift is
+// ended and a numbered Z report is frozen in the same transaction.
 func (s *CashRegisterService) CloseCashRegister(
 	companyID, locationID, userID int,
-	closingBalance float64,
-	denominations *models.JSONB,
+	req *models.CloseCashRegisterRequest,
 	sessionID, requestID string,
 	ip, ua *string,
-) error {
+) (*models.CashRegisterCloseResult, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is nil")
+	}
+
 	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	var registerID int
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
 	var openingBalance, cashIn, cashOut float64
 	err = tx.QueryRow(`
-        SELECT cr.register_id, cr.opening_balance, cr.cash_in, cr.cash_out
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        SELECT opening_balance, cash_in, cash_out
+        FROM cash_register
+        WHERE register_id = $1 AND status = 'OPEN'
         FOR UPDATE
-    `, locationID, companyID).Scan(&registerID, &openingBalance, &cashIn, &cashOut)
+    `, registerID).Scan(&openingBalance, &cashIn, &cashOut)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("no open cash register")
+		return nil, fmt.Errorf("no open cash register")
 	}
 	if err != nil {
-		return fmt.Errorf("failed to get open register: %w", err)
+		return nil, fmt.Errorf("failed to get open register: %w", err)
+	}
+
+	declared := declaredTendersWithCash(req.Tenders, req.ClosingBalance, req.Denominations)
+	closingBalance := declaredTenderAmount(declared, models.CashTenderCash)
+	denominations := req.Denominations
+	for _, d := range declared {
+		if d.Tender == models.CashTenderCash && d.Denominations != nil {
+			denominations = d.Denominations
+		}
 	}
 
 	expected := openingBalance + cashIn - cashOut
 	variance := closingBalance - expected
 
+	expectedTenders, err := s.expectedTendersTx(tx, registerID, nil)
+	if err != nil {
+		return nil, err
+	}
+	shiftID, err := s.endOpenShiftTx(tx, registerID, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.recordTenderCountsTx(tx, registerID, shiftID, models.CashCountRegisterClose, declared, expectedTenders, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	closedSessionID := uuidOrNil(sessionID)
 	_, err = tx.Exec(`
@@ -272,11 +327,11 @@ func (s *CashRegisterService) CloseCashRegister(
 		registerID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to close cash register: %w", err)
+		return nil, fmt.Errorf("failed to close cash register: %w", err)
 	}
 
 	if _, err := s.insertCashRegisterEvent(tx, registerID, locationID, nil, userID, sessionID, requestID, "CLOSE", denominations); err != nil {
-		return err
+		return nil, err
 	}
 
 	fieldChanges := models.JSONB{
@@ -292,10 +347,49 @@ func (s *CashRegisterService) CloseCashRegister(
 	rec := registerID
 	actor := userID
 	if err := LogAudit(tx, "CLOSE", "cash_register", &rec, &actor, nil, nil, &fieldChanges, ip, ua); err != nil {
-		return fmt.Errorf("failed to log audit: %w", err)
+		return nil, fmt.Errorf("failed to log audit: %w", err)
 	}
 
-	return tx.Commit()
+	zRef, err := s.issueZReportTx(tx, companyID, registerID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	s.notifyCashVariance(companyID, locationID, registerID, variance, false)
+
+	return &models.CashRegisterCloseResult{RegisterID: registerID, Counts: counts, ZReport: zRef}, nil
+}
+
+// notifyCashVariance tells the location's cash supervisors that a register
+// closed with a cash difference.
+func (s *CashRegisterService) notifyCashVariance(companyID, locationID, registerID int, variance float64, forced bool) {
+	if math.Abs(variance) < 0.005 {
+		return
+	}
+	kind := "Short"
+	if variance > 0 {
+		kind = "Over"
+	}
+	body := fmt.Sprintf("%s by %.2f on close of register %d", kind, math.Abs(variance), registerID)
+	if forced {
+		body += " (force closed)"
+	}
+	loc := locationID
+	notify(s.db, NotificationEvent{
+		CompanyID:   companyID,
+		Type:        models.NotificationCashVariance,
+		Title:       fmt.Sprintf("Cash variance of %.2f", variance),
+		Body:        body,
+		Severity:    "WARNING",
+		EntityType:  "CASH_REGISTER",
+		EntityID:    registerID,
+		LocationID:  &loc,
+		ActionLabel: "Review register",
+		DedupeKey:   fmt.Sprintf("cash_variance:%d", registerID),
+	})
 }
 
 func (s *CashRegisterService) RecordMovement(
@@ -323,15 +417,17 @@ func (s *CashRegisterService) RecordMovement(
 	}
 	defer tx.Rollback()
 
-	var registerID int
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
 	var openingBalance, cashIn, cashOut float64
 	err = tx.QueryRow(`
-        SELECT cr.register_id, cr.opening_balance, cr.cash_in, cr.cash_out
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        SELECT opening_balance, cash_in, cash_out
+        FROM cash_register
+        WHERE register_id = $1 AND status = 'OPEN'
         FOR UPDATE
-    `, locationID, companyID).Scan(&registerID, &openingBalance, &cashIn, &cashOut)
+    `, registerID).Scan(&openingBalance, &cashIn, &cashOut)
 	if err == sql.ErrNoRows {
 		return 0, fmt.Errorf("no open cash register")
 	}
@@ -409,15 +505,17 @@ func (s *CashRegisterService) ForceClose(
 	}
 	defer tx.Rollback()
 
-	var registerID int
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return err
+	}
 	var openingBalance, cashIn, cashOut float64
 	err = tx.QueryRow(`
-        SELECT cr.register_id, cr.opening_balance, cr.cash_in, cr.cash_out
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        SELECT opening_balance, cash_in, cash_out
+        FROM cash_register
+        WHERE register_id = $1 AND status = 'OPEN'
         FOR UPDATE
-    `, locationID, companyID).Scan(&registerID, &openingBalance, &cashIn, &cashOut)
+    `, registerID).Scan(&openingBalance, &cashIn, &cashOut)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("no open cash register")
 	}
@@ -452,6 +550,10 @@ func (s *CashRegisterService) ForceClose(
 		return fmt.Errorf("failed to force close cash register: %w", err)
 	}
 
+	if _, err := s.endOpenShiftTx(tx, registerID, userID, &req.Reason); err != nil {
+		return err
+	}
+
 	notes := req.Reason
 	_, err = s.insertCashRegisterEvent(
 		tx,
@@ -490,35 +592,58 @@ func (s *CashRegisterService) ForceClose(
 		return fmt.Errorf("failed to log audit: %w", err)
 	}
 
-	return tx.Commit()
+	if _, err := s.issueZReportTx(tx, companyID, registerID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.notifyCashVariance(companyID, locationID, registerID, variance, true)
+	return nil
 }
 
+// RecordTally records a mid-shift count. When per-tender lines are supplied
+// they are reconciled against the expected totals of the open shift and the
+// CASH line becomes the tally count.
 func (s *CashRegisterService) RecordTally(
 	companyID, locationID, userID int,
-	count float64,
-	notes *string,
-	denominations *models.JSONB,
+	req *models.CashTallyRequest,
 	sessionID, requestID string,
 	ip, ua *string,
 ) error {
+	if req == nil {
+		return fmt.Errorf("request is nil")
+	}
+	count := req.Count
+	notes := req.Notes
+	denominations := req.Denominations
+
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	var registerID int
-	err = tx.QueryRow(`
-        SELECT cr.register_id
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
-    `, locationID, companyID).Scan(&registerID)
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("no open cash register")
-	}
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to get open register: %w", err)
+		return err
+	}
+
+	if len(req.Tenders) > 0 {
+		declared := declaredTendersWithCash(req.Tenders, req.Count, req.Denominations)
+		count = declaredTenderAmount(declared, models.CashTenderCash)
+		shiftID, err := s.openShiftIDTx(tx, registerID)
+		if err != nil {
+			return err
+		}
+		expectedTenders, err := s.expectedTendersTx(tx, registerID, shiftID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.recordTenderCountsTx(tx, registerID, shiftID, models.CashCountTally, declared, expectedTenders, userID); err != nil {
+			return err
+		}
 	}
 
 	if _, err := tx.Exec(`INSERT INTO cash_register_tally (location_id, count, notes, recorded_by) VALUES ($1,$2,$3,$4)`, locationID, count, notes, userID); err != nil {
@@ -571,6 +696,12 @@ func (s *CashRegisterService) GetEvents(companyID, locationID int, registerID *i
 	if limit <= 0 || limit > 500 {
 		limit = 200
 	}
+	return s.listEvents(s.db, companyID, locationID, registerID, limit)
+}
+
+// listEvents reads register events newest first; a limit of 0 returns all of
+// them (used when snapshotting a register into a report).
+func (s *CashRegisterService) listEvents(q sqlQueryer, companyID, locationID int, registerID *int, limit int) ([]models.CashRegisterEvent, error) {
 	query := `
         SELECT e.event_id, e.register_id, e.location_id, e.event_type, e.direction, e.amount,
                e.reason_code, e.notes, e.denominations, e.created_by, e.session_id, e.request_id, e.created_at
@@ -587,11 +718,13 @@ func (s *CashRegisterService) GetEvents(companyID, locationID int, registerID *i
 		args = append(args, *registerID)
 	}
 	query += " ORDER BY e.created_at DESC, e.event_id DESC"
-	argCount++
-	query += fmt.Sprintf(" LIMIT $%d", argCount)
-	args = append(args, limit)
+	if limit > 0 {
+		argCount++
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, limit)
+	}
 
-	rows, err := s.db.Query(query, args...)
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
 	}
@@ -716,7 +849,8 @@ func (s *CashRegisterService) insertCashRegisterEvent(
 }
 
 // RecordCashTransactionTx records a cash-impacting transaction (sale/collection/expense/etc)
-// against the currently OPEN cash register for the location.
+// against the currently OPEN cash register for the location. With several tills
+// open, the register where the user holds the open shift wins.
 //
 // This is best-effort:
 // - If there is no open cash register, it returns nil (doesn't block operations).
@@ -747,22 +881,20 @@ func (s *CashRegisterService) RecordCashTransactionTx(
 		defer tx.Rollback()
 	}
 
-	var registerID int
-	err := tx.QueryRow(`
-        SELECT cr.register_id
-        FROM cash_register cr
-        JOIN locations l ON cr.location_id = l.location_id
-        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
-        LIMIT 1
-        FOR UPDATE
-    `, locationID, companyID).Scan(&registerID)
-	if err == sql.ErrNoRows {
+	candidates, err := s.openRegisterCandidates(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	// Ambiguity is tolerated here: the best-ranked register takes the cash
+	// rather than failing the sale or the outbox entry.
+	registerID, _ := pickOpenRegister(candidates)
+	if registerID == 0 {
 		if ownTx {
 			return tx.Commit()
 		}
 		return nil
 	}
-	if err != nil {
+	if _, err := tx.Exec(`SELECT 1 FROM cash_register WHERE register_id = $1 FOR UPDATE`, registerID); err != nil {
 		return fmt.Errorf("failed to get open cash register: %w", err)
 	}
 
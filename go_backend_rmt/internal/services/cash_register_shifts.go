@@ -0,0 +1,562 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+type cashRegisterQueryer interface {
+	sqlQueryRower
+	sqlQueryer
+}
+
+// cashTenderOrder fixes the order tenders are listed in counts and reports.
+var cashTenderOrder = []string{
+	models.CashTenderCash,
+	models.CashTenderCard,
+	models.CashTenderWallet,
+	models.CashTenderStoreCredit,
+	models.CashTenderOther,
+}
+
+// cashTenderForMethodType maps a payment_methods.type onto a drawer tender.
+func cashTenderForMethodType(methodType string) string {
+	switch strings.ToUpper(strings.TrimSpace(methodType)) {
+	case "CASH":
+		return models.CashTenderCash
+	case "CARD":
+		return models.CashTenderCard
+	case "UPI", "DIGITAL", "ONLINE":
+		return models.CashTenderWallet
+	case "CREDIT":
+		return models.CashTenderStoreCredit
+	default:
+		return models.CashTenderOther
+	}
+}
+
+type openRegisterCandidate struct {
+	RegisterID    int
+	TerminalMatch bool
+	ShiftMatch    bool
+}
+
+// pickOpenRegister chooses between the OPEN registers of a location. The
+// register bound to the caller's terminal wins, then the one where the caller
+// holds the open shift; a lone open register is always used so single-till
+// locations work without terminal ids. The second result reports that several
+// registers were open and none could be told apart.
+func pickOpenRegister(candidates []openRegisterCandidate) (int, bool) {
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	for _, c := range candidates {
+		if c.TerminalMatch {
+			return c.RegisterID, false
+		}
+	}
+	for _, c := range candidates {
+		if c.ShiftMatch {
+			return c.RegisterID, false
+		}
+	}
+	return candidates[0].RegisterID, len(candidates) > 1
+}
+
+// sessionTerminalID returns the device bound to a login session, or "".
+func (s *CashRegisterService) sessionTerminalID(q sqlQueryRower, sessionID string) string {
+	sid := uuidOrNil(sessionID)
+	if sid == nil {
+		return ""
+	}
+	var deviceID string
+	if err := q.QueryRow(`SELECT device_id FROM device_sessions WHERE session_id = $1`, sid).Scan(&deviceID); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(deviceID)
+}
+
+func (s *CashRegisterService) openRegisterCandidates(q cashRegisterQueryer, companyID, locationID, userID int, sessionID string) ([]openRegisterCandidate, error) {
+	terminalID := s.sessionTerminalID(q, sessionID)
+	rows, err := q.Query(`
+        SELECT cr.register_id,
+               (COALESCE(cr.terminal_id, '') <> '' AND cr.terminal_id = $3) AS terminal_match,
+               EXISTS (
+                   SELECT 1 FROM cash_register_shifts sh
+                   WHERE sh.register_id = cr.register_id AND sh.status = 'OPEN' AND sh.cashier_id = $4
+               ) AS shift_match
+        FROM cash_register cr
+        JOIN locations l ON cr.location_id = l.location_id
+        WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        ORDER BY cr.register_id
+    `, locationID, companyID, terminalID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open registers: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []openRegisterCandidate
+	for rows.Next() {
+		var c openRegisterCandidate
+		if err := rows.Scan(&c.RegisterID, &c.TerminalMatch, &c.ShiftMatch); err != nil {
+			return nil, fmt.Errorf("failed to scan open register: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// resolveOpenRegister returns the OPEN register the caller is working on.
+func (s *CashRegisterService) resolveOpenRegister(q cashRegisterQueryer, companyID, locationID, userID int, sessionID string) (int, error) {
+	candidates, err := s.openRegisterCandidates(q, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	registerID, ambiguous := pickOpenRegister(candidates)
+	if registerID == 0 {
+		return 0, fmt.Errorf("no open cash register")
+	}
+	if ambiguous {
+		return 0, fmt.Errorf("multiple cash registers are open at this location; open a shift on one first")
+	}
+	return registerID, nil
+}
+
+func (s *CashRegisterService) openShiftIDTx(q sqlQueryRower, registerID int) (*int, error) {
+	var shiftID int
+	err := q.QueryRow(`SELECT shift_id FROM cash_register_shifts WHERE register_id = $1 AND status = 'OPEN'`, registerID).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open shift: %w", err)
+	}
+	return &shiftID, nil
+}
+
+func (s *CashRegisterService) startShiftTx(tx *sql.Tx, registerID, locationID, userID int, openingCash float64, sessionID string, notes *string) (int, error) {
+	var shiftID int
+	err := tx.QueryRow(`
+        INSERT INTO cash_register_shifts (register_id, location_id, cashier_id, status, opening_cash, started_at, session_id, notes)
+        VALUES ($1, $2, $3, 'OPEN', $4, CURRENT_TIMESTAMP, $5, $6)
+        RETURNING shift_id
+    `, registerID, locationID, userID, openingCash, uuidOrNil(sessionID), notes).Scan(&shiftID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("another shift is already open on this register")
+		}
+		return 0, fmt.Errorf("failed to start shift: %w", err)
+	}
+	return shiftID, nil
+}
+
+// endOpenShiftTx closes the register's open shift, if any, and returns its id.
+func (s *CashRegisterService) endOpenShiftTx(tx *sql.Tx, registerID, userID int, notes *string) (*int, error) {
+	var shiftID int
+	err := tx.QueryRow(`
+        UPDATE cash_register_shifts
+        SET status = 'CLOSED', ended_at = CURRENT_TIMESTAMP, ended_by = $2,
+            notes = COALESCE($3, notes)
+        WHERE register_id = $1 AND status = 'OPEN'
+        RETURNING shift_id
+    `, registerID, userID, notes).Scan(&shiftID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to end shift: %w", err)
+	}
+	return &shiftID, nil
+}
+
+// StartShift begins the caller's shift on their terminal's open register,
+// e.g. after a hand-over. The previous cashier must have ended their shift.
+func (s *CashRegisterService) StartShift(
+	companyID, locationID, userID int,
+	req *models.StartCashShiftRequest,
+	sessionID, requestID string,
+	ip, ua *string,
+) (*models.CashRegisterShift, error) {
+	var notes *string
+	if req != nil {
+		notes = req.Notes
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var expected float64
+	err = tx.QueryRow(`
+        SELECT expected_balance FROM cash_register
+        WHERE register_id = $1 AND status = 'OPEN'
+        FOR UPDATE
+    `, registerID).Scan(&expected)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no open cash register")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open register: %w", err)
+	}
+
+	shiftID, err := s.startShiftTx(tx, registerID, locationID, userID, expected, sessionID, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.insertCashRegisterEvent(tx, registerID, locationID, &models.CashRegisterMovementRequest{
+		ReasonCode: "SHIFT_START",
+		Notes:      notes,
+	}, userID, sessionID, requestID, "SHIFT_START", nil); err != nil {
+		return nil, err
+	}
+
+	fieldChanges := models.JSONB{
+		"event_type":   "SHIFT_START",
+		"register_id":  registerID,
+		"shift_id":     shiftID,
+		"location_id":  locationID,
+		"opening_cash": expected,
+		"request_id":   requestID,
+		"session_id":   sessionID,
+	}
+	rec := registerID
+	actor := userID
+	if err := LogAudit(tx, "SHIFT_START", "cash_register", &rec, &actor, nil, nil, &fieldChanges, ip, ua); err != nil {
+		return nil, fmt.Errorf("failed to log audit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return s.getShift(companyID, shiftID)
+}
+
+// EndShift ends the caller's shift with a blind per-tender count. The register
+// stays open for the next cashier.
+func (s *CashRegisterService) EndShift(
+	companyID, locationID, userID int,
+	req *models.EndCashShiftRequest,
+	sessionID, requestID string,
+	ip, ua *string,
+) (*models.CashShiftCloseResult, error) {
+	if req == nil || len(req.Tenders) == 0 {
+		return nil, fmt.Errorf("tenders are required")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	registerID, err := s.resolveOpenRegister(tx, companyID, locationID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`SELECT 1 FROM cash_register WHERE register_id = $1 FOR UPDATE`, registerID); err != nil {
+		return nil, fmt.Errorf("failed to get open register: %w", err)
+	}
+
+	var shiftID, cashierID int
+	err = tx.QueryRow(`
+        SELECT shift_id, cashier_id FROM cash_register_shifts
+        WHERE register_id = $1 AND status = 'OPEN'
+        FOR UPDATE
+    `, registerID).Scan(&shiftID, &cashierID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no open shift")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open shift: %w", err)
+	}
+	if cashierID != userID {
+		return nil, fmt.Errorf("shift belongs to another cashier")
+	}
+
+	expectedTenders, err := s.expectedTendersTx(tx, registerID, &shiftID)
+	if err != nil {
+		return nil, err
+	}
+	declared := declaredTendersWithCash(req.Tenders, 0, nil)
+	counts, err := s.recordTenderCountsTx(tx, registerID, &shiftID, models.CashCountShiftClose, declared, expectedTenders, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.endOpenShiftTx(tx, registerID, userID, req.Notes); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.insertCashRegisterEvent(tx, registerID, locationID, &models.CashRegisterMovementRequest{
+		ReasonCode: "SHIFT_END",
+		Notes:      req.Notes,
+	}, userID, sessionID, requestID, "SHIFT_END", nil); err != nil {
+		return nil, err
+	}
+
+	fieldChanges := models.JSONB{
+		"event_type":  "SHIFT_END",
+		"register_id": registerID,
+		"shift_id":    shiftID,
+		"location_id": locationID,
+		"request_id":  requestID,
+		"session_id":  sessionID,
+	}
+	rec := registerID
+	actor := userID
+	if err := LogAudit(tx, "SHIFT_END", "cash_register", &rec, &actor, nil, nil, &fieldChanges, ip, ua); err != nil {
+		return nil, fmt.Errorf("failed to log audit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	shift, err := s.getShift(companyID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CashShiftCloseResult{Shift: shift, Counts: counts}, nil
+}
+
+const cashRegisterShiftColumns = `
+        sh.shift_id, sh.register_id, sh.location_id, sh.cashier_id,
+        COALESCE(NULLIF(TRIM(CONCAT(COALESCE(u.first_name, ''), ' ', COALESCE(u.last_name, ''))), ''), u.username, '') AS cashier_name,
+        sh.status, sh.opening_cash::float8, sh.started_at, sh.ended_at, sh.ended_by, sh.notes`
+
+func scanCashRegisterShift(row interface{ Scan(...any) error }) (*models.CashRegisterShift, error) {
+	var sh models.CashRegisterShift
+	if err := row.Scan(
+		&sh.ShiftID, &sh.RegisterID, &sh.LocationID, &sh.CashierID, &sh.CashierName,
+		&sh.Status, &sh.OpeningCash, &sh.StartedAt, &sh.EndedAt, &sh.EndedBy, &sh.Notes,
+	); err != nil {
+		return nil, err
+	}
+	return &sh, nil
+}
+
+func (s *CashRegisterService) getShift(companyID, shiftID int) (*models.CashRegisterShift, error) {
+	row := s.db.QueryRow(`
+        SELECT `+cashRegisterShiftColumns+`
+        FROM cash_register_shifts sh
+        JOIN locations l ON l.location_id = sh.location_id
+        LEFT JOIN users u ON u.user_id = sh.cashier_id
+        WHERE sh.shift_id = $1 AND l.company_id = $2
+    `, shiftID, companyID)
+	sh, err := scanCashRegisterShift(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("shift not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shift: %w", err)
+	}
+	return sh, nil
+}
+
+// GetShifts lists shifts for a location, newest first.
+func (s *CashRegisterService) GetShifts(companyID, locationID int, registerID *int, limit int) ([]models.CashRegisterShift, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 200
+	}
+	return s.listShifts(s.db, companyID, locationID, registerID, limit)
+}
+
+func (s *CashRegisterService) listShifts(q sqlQueryer, companyID, locationID int, registerID *int, limit int) ([]models.CashRegisterShift, error) {
+	query := `
+        SELECT ` + cashRegisterShiftColumns + `
+        FROM cash_register_shifts sh
+        JOIN locations l ON l.location_id = sh.location_id
+        LEFT JOIN users u ON u.user_id = sh.cashier_id
+        WHERE l.company_id = $1 AND sh.location_id = $2`
+	args := []interface{}{companyID, locationID}
+	if registerID != nil && *registerID > 0 {
+		args = append(args, *registerID)
+		query += fmt.Sprintf(" AND sh.register_id = $%d", len(args))
+	}
+	query += " ORDER BY sh.started_at DESC, sh.shift_id DESC"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shifts: %w", err)
+	}
+	defer rows.Close()
+
+	shifts := []models.CashRegisterShift{}
+	for rows.Next() {
+		sh, err := scanCashRegisterShift(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shift: %w", err)
+		}
+		shifts = append(shifts, *sh)
+	}
+	return shifts, rows.Err()
+}
+
+// declaredTendersWithCash merges duplicate tender lines and makes sure a CASH
+// line exists, falling back to the legacy single closing amount.
+func declaredTendersWithCash(tenders []models.CashTenderDeclared, cash float64, denominations *models.JSONB) []models.CashTenderDeclared {
+	merged := map[string]*models.CashTenderDeclared{}
+	for _, t := range tenders {
+		tender := strings.ToUpper(strings.TrimSpace(t.Tender))
+		if existing, ok := merged[tender]; ok {
+			existing.Amount = round2(existing.Amount + t.Amount)
+			if t.Denominations != nil {
+				existing.Denominations = t.Denominations
+			}
+			continue
+		}
+		line := t
+		line.Tender = tender
+		line.Amount = round2(t.Amount)
+		merged[tender] = &line
+	}
+	if _, ok := merged[models.CashTenderCash]; !ok {
+		merged[models.CashTenderCash] = &models.CashTenderDeclared{
+			Tender:        models.CashTenderCash,
+			Amount:        round2(cash),
+			Denominations: denominations,
+		}
+	}
+
+	out := make([]models.CashTenderDeclared, 0, len(merged))
+	for _, tender := range cashTenderOrder {
+		if line, ok := merged[tender]; ok {
+			out = append(out, *line)
+		}
+	}
+	return out
+}
+
+func declaredTenderAmount(declared []models.CashTenderDeclared, tender string) float64 {
+	for _, d := range declared {
+		if d.Tender == tender {
+			return d.Amount
+		}
+	}
+	return 0
+}
+
+// reconcileTenderCounts lines declared amounts up against expected totals. A
+// tender that was expected but not declared counts as declared zero.
+func reconcileTenderCounts(declared []models.CashTenderDeclared, expected map[string]float64) []models.CashTenderCount {
+	byTender := map[string]models.CashTenderDeclared{}
+	for _, d := range declared {
+		byTender[d.Tender] = d
+	}
+	var counts []models.CashTenderCount
+	for _, tender := range cashTenderOrder {
+		d, declaredOK := byTender[tender]
+		exp := round2(expected[tender])
+		if !declaredOK && exp == 0 {
+			continue
+		}
+		counts = append(counts, models.CashTenderCount{
+			Tender:        tender,
+			Declared:      d.Amount,
+			Expected:      exp,
+			Variance:      round2(d.Amount - exp),
+			Denominations: d.Denominations,
+		})
+	}
+	return counts
+}
+
+func (s *CashRegisterService) recordTenderCountsTx(
+	tx *sql.Tx,
+	registerID int,
+	shiftID *int,
+	countType string,
+	declared []models.CashTenderDeclared,
+	expected map[string]float64,
+	userID int,
+) ([]models.CashTenderCount, error) {
+	counts := reconcileTenderCounts(declared, expected)
+	now := time.Now()
+	for i := range counts {
+		c := &counts[i]
+		c.RegisterID = registerID
+		c.ShiftID = shiftID
+		c.CountType = countType
+		c.CountedBy = userID
+		c.CountedAt = now
+		var denom interface{}
+		if c.Denominations != nil {
+			denom = *c.Denominations
+		}
+		if err := tx.QueryRow(`
+            INSERT INTO cash_register_tender_counts
+                (register_id, shift_id, count_type, tender, declared, expected, variance, denominations, counted_by, counted_at)
+            VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+            RETURNING count_id
+        `, registerID, shiftID, countType, c.Tender, c.Declared, c.Expected, c.Variance, denom, userID, now).Scan(&c.CountID); err != nil {
+			return nil, fmt.Errorf("failed to record tender count: %w", err)
+		}
+	}
+	return counts, nil
+}
+
+// expectedTendersTx returns what should be in the drawer per tender. Cash
+// comes from the register's running drawer balance; other tenders are the
+// payments taken on sales rung up during the register's (or one shift's)
+// cashier windows.
+func (s *CashRegisterService) expectedTendersTx(q cashRegisterQueryer, registerID int, shiftID *int) (map[string]float64, error) {
+	expected := map[string]float64{}
+	var cash float64
+	if err := q.QueryRow(`SELECT expected_balance::float8 FROM cash_register WHERE register_id = $1`, registerID).Scan(&cash); err != nil {
+		return nil, fmt.Errorf("failed to get expected cash: %w", err)
+	}
+	expected[models.CashTenderCash] = round2(cash)
+
+	rows, err := q.Query(cashRegisterWindowsCTE+`,
+        register_sales AS (
+            SELECT DISTINCT s.sale_id, s.payment_method_id, s.paid_amount
+            FROM sales s
+            JOIN windows w ON s.location_id = w.location_id AND s.created_by = w.cashier_id
+             AND s.created_at >= w.started_at AND s.created_at <= w.ended_at
+            WHERE s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE AND s.status = 'COMPLETED'
+        )
+        SELECT pm.type, COALESCE(SUM(x.amount), 0)::float8
+        FROM (
+            SELECT sp.method_id, sp.base_amount AS amount
+            FROM sale_payments sp
+            JOIN register_sales rs ON rs.sale_id = sp.sale_id
+            UNION ALL
+            SELECT rs.payment_method_id, rs.paid_amount
+            FROM register_sales rs
+            WHERE rs.payment_method_id IS NOT NULL
+              AND NOT EXISTS (SELECT 1 FROM sale_payments sp WHERE sp.sale_id = rs.sale_id)
+        ) x
+        JOIN payment_methods pm ON pm.method_id = x.method_id
+        GROUP BY pm.type
+    `, registerID, shiftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expected tenders: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var methodType string
+		var amount float64
+		if err := rows.Scan(&methodType, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan expected tender: %w", err)
+		}
+		tender := cashTenderForMethodType(methodType)
+		if tender == models.CashTenderCash {
+			continue
+		}
+		expected[tender] = round2(expected[tender] + amount)
+	}
+	return expected, rows.Err()
+}
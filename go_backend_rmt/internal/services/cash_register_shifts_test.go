@@ -0,0 +1,152 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestPickOpenRegisterPrefersTerminalThenShift(t *testing.T) {
+	cases := []struct {
+		name       string
+		candidates []openRegisterCandidate
+		want       int
+		ambiguous  bool
+	}{
+		{name: "none", want: 0},
+		{name: "single legacy register", candidates: []openRegisterCandidate{{RegisterID: 4}}, want: 4},
+		{
+			name: "terminal beats shift",
+			candidates: []openRegisterCandidate{
+				{RegisterID: 1, ShiftMatch: true},
+				{RegisterID: 2, TerminalMatch: true},
+			},
+			want: 2,
+		},
+		{
+			name: "shift match",
+			candidates: []openRegisterCandidate{
+				{RegisterID: 1},
+				{RegisterID: 3, ShiftMatch: true},
+			},
+			want: 3,
+		},
+		{
+			name:       "ambiguous",
+			candidates: []openRegisterCandidate{{RegisterID: 1}, {RegisterID: 2}},
+			want:       1,
+			ambiguous:  true,
+		},
+	}
+	for _, tc := range cases {
+		got, ambiguous := pickOpenRegister(tc.candidates)
+		if got != tc.want || ambiguous != tc.ambiguous {
+			t.Fatalf("%s: got (%d, %v), want (%d, %v)", tc.name, got, ambiguous, tc.want, tc.ambiguous)
+		}
+	}
+}
+
+func TestCashTenderForMethodType(t *testing.T) {
+	cases := map[string]string{
+		"CASH":    models.CashTenderCash,
+		"card":    models.CashTenderCard,
+		"UPI":     models.CashTenderWallet,
+		"DIGITAL": models.CashTenderWallet,
+		"CREDIT":  models.CashTenderStoreCredit,
+		"CHEQUE":  models.CashTenderOther,
+	}
+	for methodType, want := range cases {
+		if got := cashTenderForMethodType(methodType); got != want {
+			t.Fatalf("%s: got %s, want %s", methodType, got, want)
+		}
+	}
+}
+
+func TestDeclaredTendersWithCashMergesAndFallsBack(t *testing.T) {
+	declared := declaredTendersWithCash([]models.CashTenderDeclared{
+		{Tender: "card", Amount: 40},
+		{Tender: "CARD", Amount: 10.005},
+		{Tender: "WALLET", Amount: 12},
+	}, 250, nil)
+
+	if len(declared) != 3 {
+		t.Fatalf("expected 3 lines, got %+v", declared)
+	}
+	if declared[0].Tender != models.CashTenderCash || declared[0].Amount != 250 {
+		t.Fatalf("expected CASH fallback first, got %+v", declared[0])
+	}
+	if declared[1].Tender != models.CashTenderCard || declared[1].Amount != 50.01 {
+		t.Fatalf("expected merged CARD 50.01, got %+v", declared[1])
+	}
+
+	explicit := declaredTendersWithCash([]models.CashTenderDeclared{{Tender: "CASH", Amount: 90}}, 250, nil)
+	if declaredTenderAmount(explicit, models.CashTenderCash) != 90 {
+		t.Fatalf("explicit CASH line must win over closing balance, got %+v", explicit)
+	}
+}
+
+func TestReconcileTenderCountsTreatsMissingDeclarationAsZero(t *testing.T) {
+	counts := reconcileTenderCounts(
+		[]models.CashTenderDeclared{
+			{Tender: models.CashTenderCash, Amount: 195},
+			{Tender: models.CashTenderOther, Amount: 5},
+		},
+		map[string]float64{
+			models.CashTenderCash:   200,
+			models.CashTenderCard:   80,
+			models.CashTenderWallet: 0,
+		},
+	)
+	if len(counts) != 3 {
+		t.Fatalf("expected CASH, CARD and OTHER, got %+v", counts)
+	}
+	want := []struct {
+		tender   string
+		variance float64
+	}{
+		{models.CashTenderCash, -5},
+		{models.CashTenderCard, -80},
+		{models.CashTenderOther, 5},
+	}
+	for i, w := range want {
+		if counts[i].Tender != w.tender || counts[i].Variance != w.variance {
+			t.Fatalf("line %d: got %+v, want %s variance %.2f", i, counts[i], w.tender, w.variance)
+		}
+	}
+}
+
+func TestCashRegisterReportLinesStaysBlindUntilCounted(t *testing.T) {
+	open := cashRegisterTenderLines(map[string]float64{models.CashTenderCash: 100, models.CashTenderCard: 30}, nil)
+	if len(open) != 2 || open[0].Declared != nil || open[1].Variance != nil {
+		t.Fatalf("open register lines must not carry declared amounts: %+v", open)
+	}
+
+	closed := cashRegisterTenderLines(
+		map[string]float64{models.CashTenderCash: 100},
+		map[string]float64{models.CashTenderCash: 98, models.CashTenderWallet: 4},
+	)
+	if len(closed) != 2 || *closed[0].Variance != -2 || closed[1].Tender != models.CashTenderWallet || *closed[1].Variance != 4 {
+		t.Fatalf("unexpected closed tender lines: %+v", closed)
+	}
+
+	report := &models.CashRegisterReport{
+		ReportType:   models.CashRegisterReportZ,
+		ExpectedCash: 100,
+		Sales:        models.CashRegisterSalesSummary{Count: 3, Subtotal: 120, Discounts: 5, Tax: 10, Total: 125},
+		TaxBreakdown: []models.CashRegisterTaxLine{{TaxName: "VAT", TaxRate: 15, TaxableAmount: 66.67, TaxAmount: 10}},
+		Tenders:      closed,
+		Movements:    []models.CashRegisterMovementLine{{EventType: "CASH_OUT", ReasonCode: "PAYOUT", Direction: "OUT", Count: 1, Amount: 20}},
+	}
+	lines := CashRegisterReportLines(report)
+	sections := map[string]int{}
+	for _, l := range lines {
+		sections[l.Section]++
+	}
+	if sections["TAX"] != 2 || sections["TENDER"] != 6 || sections["MOVEMENT"] != 1 {
+		t.Fatalf("unexpected sections: %+v", sections)
+	}
+	last := lines[len(lines)-1]
+	if last.Label != "CASH_OUT / PAYOUT (OUT)" || last.Count == nil || *last.Count != 1 {
+		t.Fatalf("unexpected movement line: %+v", last)
+	}
+}
@@ -333,6 +333,17 @@ func (s *CollectionService) CreateCollection(companyID, locationID, userID int,
 		}
 	}
 
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventPaymentReceived, "collection", col.CollectionID, models.JSONB{
+		"collection_id":     col.CollectionID,
+		"collection_number": col.CollectionNumber,
+		"customer_id":       req.CustomerID,
+		"location_id":       locationID,
+		"amount":            req.Amount,
+		"payment_method_id": req.PaymentMethodID,
+	}); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -340,6 +351,10 @@ func (s *CollectionService) CreateCollection(companyID, locationID, userID int,
 	if err := finance.ProcessAggregate(companyID, "collection", col.CollectionID); err != nil {
 		log.Printf("collection_service: failed to process finance outbox for collection %d: %v", col.CollectionID, err)
 	}
+	// Settling overdue invoices may lift a dunning credit block.
+	if err := (&DunningService{db: s.db}).RefreshCustomerStatus(companyID, req.CustomerID); err != nil {
+		log.Printf("collection_service: failed to refresh dunning status for customer %d: %v", req.CustomerID, err)
+	}
 
 	col.CustomerID = req.CustomerID
 	col.LocationID = locationID
@@ -180,17 +180,21 @@ func (s *CompanyService) CreateCompany(req *models.CreateCompanyRequest, userID
 		return nil, fmt.Errorf("failed to check user: %w", err)
 	}
 
+	// Give the company its own roles, copied from the templates.
+	if err := cloneTemplateRolesTx(tx, company.CompanyID); err != nil {
+		return nil, err
+	}
+
 	if userCompanyID == nil {
 		// User doesn't have company - assign this one and make them Super Admin (by name, not ID).
 		var superAdminRoleID int
-		if err := tx.QueryRow(`SELECT role_id FROM roles WHERE name = 'Super Admin'`).Scan(&superAdminRoleID); err != nil {
+		if err := tx.QueryRow(`SELECT role_id FROM roles WHERE company_id = $1 AND name = 'Super Admin'`, company.CompanyID).Scan(&superAdminRoleID); err != nil {
 			if err == sql.ErrNoRows {
 				if err := tx.QueryRow(`
-					INSERT INTO roles (name, description, is_system_role)
-					VALUES ('Super Admin', 'Full system access', TRUE)
-					ON CONFLICT (name) DO UPDATE SET is_system_role = TRUE
+					INSERT INTO roles (company_id, name, description, is_system_role)
+					VALUES ($1, 'Super Admin', 'Full system access', TRUE)
 					RETURNING role_id
-				`).Scan(&superAdminRoleID); err != nil {
+				`, company.CompanyID).Scan(&superAdminRoleID); err != nil {
 					return nil, fmt.Errorf("failed to ensure Super Admin role: %w", err)
 				}
 			} else {
@@ -207,6 +211,13 @@ func (s *CompanyService) CreateCompany(req *models.CreateCompanyRequest, userID
 		if err != nil {
 			return nil, fmt.Errorf("failed to assign company to user: %w", err)
 		}
+		if _, err := tx.Exec(`
+			INSERT INTO user_locations (user_id, location_id, created_by)
+			VALUES ($1, $2, $1)
+			ON CONFLICT DO NOTHING
+		`, userID, defaultLocationID); err != nil {
+			return nil, fmt.Errorf("failed to assign location to user: %w", err)
+		}
 	}
 
 	// Commit transaction
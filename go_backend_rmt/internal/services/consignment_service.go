@@ -0,0 +1,977 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ConsignmentService handles stock we hold but do not own (consignment-in)
+// and stock we own but hold at dealer locations (consignment-out).
+//
+// Consignment-in stock is received into CONSIGNMENT_IN lots without a
+// payable. It sells through the normal sale flows; a settlement later bills
+// the consignor for what sold by raising a supplier invoice at the agreed
+// lot cost, whose inventory debit offsets the COGS credit of the sale. Sold
+// stock that comes back on a return is ours, as it has been billed.
+//
+// Consignment-out stock is placed by transferring it to a dealer location and
+// stays on our books until the dealer reports the sale, which is invoiced to
+// the dealer customer.
+type ConsignmentService struct {
+	db *sql.DB
+}
+
+func NewConsignmentService() *ConsignmentService {
+	return &ConsignmentService{db: database.GetDB()}
+}
+
+type lotOwnership struct {
+	Type                string
+	ConsignorSupplierID *int
+	ConsigneeCustomerID *int
+}
+
+// transferLotOwnership decides who owns a lot created by a transfer. Stock
+// arriving at a dealer location becomes consignment-out stock held by that
+// dealer; consignment-out stock coming back is ours again. Vendor-owned
+// stock cannot be passed on to a dealer.
+func transferLotOwnership(source string, consignorSupplierID, destConsigneeCustomerID *int) (lotOwnership, error) {
+	if source == models.StockOwnershipConsignmentIn {
+		if destConsigneeCustomerID != nil {
+			return lotOwnership{}, fmt.Errorf("vendor-owned consignment stock cannot be transferred to a dealer location")
+		}
+		return lotOwnership{Type: models.StockOwnershipConsignmentIn, ConsignorSupplierID: consignorSupplierID}, nil
+	}
+	if destConsigneeCustomerID != nil {
+		return lotOwnership{Type: models.StockOwnershipConsignmentOut, ConsigneeCustomerID: destConsigneeCustomerID}, nil
+	}
+	return lotOwnership{Type: models.StockOwnershipOwned}, nil
+}
+
+// loadLocationConsigneeTx returns the dealer customer of a location, or nil
+// for an ordinary location.
+func loadLocationConsigneeTx(tx *sql.Tx, companyID, locationID int) (*int, error) {
+	var customerID sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT consignee_customer_id
+		FROM locations
+		WHERE location_id = $1 AND company_id = $2
+	`, locationID, companyID).Scan(&customerID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("location not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location: %w", err)
+	}
+	return intPtrFromNullInt64(customerID), nil
+}
+
+// consignmentSale is one sale movement drawn from a consignment-in lot. Its
+// quantity is in stock units and its cost is the agreed lot cost.
+type consignmentSale struct {
+	MovementID int
+	LotID      int
+	ProductID  int
+	BarcodeID  *int
+	Quantity   float64
+	UnitCost   float64
+	SoldAt     time.Time
+}
+
+type consignmentInvoiceLine struct {
+	ProductID int
+	BarcodeID *int
+	Quantity  float64
+	UnitCost  float64
+	Amount    float64
+}
+
+func consignmentSaleAmount(sale consignmentSale) float64 {
+	return round2(sale.Quantity * sale.UnitCost)
+}
+
+// consignmentInvoiceLines folds settled sales into supplier invoice lines, one
+// per variant and agreed cost, in the order they first sold. Line amounts are
+// the sum of the per-sale amounts so the invoice matches the settlement.
+func consignmentInvoiceLines(sales []consignmentSale) []consignmentInvoiceLine {
+	lines := make([]consignmentInvoiceLine, 0)
+	index := make(map[string]int)
+	for _, sale := range sales {
+		barcodeKey := 0
+		if sale.BarcodeID != nil {
+			barcodeKey = *sale.BarcodeID
+		}
+		key := fmt.Sprintf("%d:%d:%.4f", sale.ProductID, barcodeKey, round4(sale.UnitCost))
+		idx, ok := index[key]
+		if !ok {
+			idx = len(lines)
+			index[key] = idx
+			lines = append(lines, consignmentInvoiceLine{
+				ProductID: sale.ProductID,
+				BarcodeID: sale.BarcodeID,
+				UnitCost:  round4(sale.UnitCost),
+			})
+		}
+		lines[idx].Quantity = round3(lines[idx].Quantity + sale.Quantity)
+		lines[idx].Amount = round2(lines[idx].Amount + consignmentSaleAmount(sale))
+	}
+	return lines
+}
+
+func parseConsignmentDate(field string, value *string) (*time.Time, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse("2006-01-02", strings.TrimSpace(*value))
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD", field)
+	}
+	return &parsed, nil
+}
+
+func (s *ConsignmentService) loadSupplierName(q settingsQueryRower, companyID, supplierID int) (string, error) {
+	var name string
+	err := q.QueryRow(`
+		SELECT name FROM suppliers WHERE supplier_id = $1 AND company_id = $2
+	`, supplierID, companyID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("supplier not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to verify supplier: %w", err)
+	}
+	return name, nil
+}
+
+func (s *ConsignmentService) CreateReceipt(companyID, locationID, userID int, req *models.CreateConsignmentReceiptRequest) (*models.ConsignmentReceipt, error) {
+	receiptDate := time.Now()
+	parsed, err := parseConsignmentDate("receipt_date", req.ReceiptDate)
+	if err != nil {
+		return nil, err
+	}
+	if parsed != nil {
+		receiptDate = *parsed
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	consignee, err := loadLocationConsigneeTx(tx, companyID, locationID)
+	if err != nil {
+		return nil, err
+	}
+	if consignee != nil {
+		return nil, fmt.Errorf("consignment stock cannot be received into a dealer location")
+	}
+	supplierName, err := s.loadSupplierName(tx, companyID, req.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiptNumber, err := NewNumberingSequenceService().NextNumber(tx, "consignment_receipt", companyID, &locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate consignment receipt number: %w", err)
+	}
+
+	receipt := &models.ConsignmentReceipt{
+		CompanyID:       companyID,
+		LocationID:      locationID,
+		SupplierID:      req.SupplierID,
+		SupplierName:    supplierName,
+		ReceiptNumber:   receiptNumber,
+		ReceiptDate:     receiptDate,
+		ReferenceNumber: req.ReferenceNumber,
+		Notes:           req.Notes,
+		CreatedBy:       userID,
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO consignment_receipts (
+			company_id, location_id, supplier_id, receipt_number, receipt_date,
+			reference_number, notes, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING consignment_receipt_id, created_at
+	`, companyID, locationID, req.SupplierID, receiptNumber, receiptDate,
+		req.ReferenceNumber, req.Notes, userID,
+	).Scan(&receipt.ConsignmentReceiptID, &receipt.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create consignment receipt: %w", err)
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	supplierID := req.SupplierID
+	totalValue := 0.0
+	var receivedBarcodeIDs []int
+	for _, item := range req.Items {
+		var itemID int
+		if err := tx.QueryRow(`
+			INSERT INTO consignment_receipt_items (
+				consignment_receipt_id, product_id, barcode_id, quantity, unit_cost,
+				batch_number, expiry_date, serial_numbers
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING consignment_receipt_item_id
+		`, receipt.ConsignmentReceiptID, item.ProductID, item.BarcodeID, item.Quantity, item.UnitCost,
+			item.BatchNumber, item.ExpiryDate, pq.Array(item.SerialNumbers),
+		).Scan(&itemID); err != nil {
+			return nil, fmt.Errorf("failed to create consignment receipt item: %w", err)
+		}
+
+		variant, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "CONSIGNMENT_IN", "consignment_receipt_item", &itemID, &receiptNumber, inventorySelection{
+			ProductID:           item.ProductID,
+			BarcodeID:           item.BarcodeID,
+			SupplierID:          &supplierID,
+			Quantity:            item.Quantity,
+			SerialNumbers:       item.SerialNumbers,
+			BatchNumber:         item.BatchNumber,
+			ExpiryDate:          item.ExpiryDate,
+			UnitCost:            item.UnitCost,
+			Notes:               req.Notes,
+			Ownership:           models.StockOwnershipConsignmentIn,
+			ConsignorSupplierID: &supplierID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if item.BarcodeID == nil {
+			if _, err := tx.Exec(`
+				UPDATE consignment_receipt_items SET barcode_id = $1 WHERE consignment_receipt_item_id = $2
+			`, variant.BarcodeID, itemID); err != nil {
+				return nil, fmt.Errorf("failed to update consignment receipt item: %w", err)
+			}
+		}
+		totalValue += item.Quantity * item.UnitCost
+		receivedBarcodeIDs = append(receivedBarcodeIDs, variant.BarcodeID)
+	}
+
+	// Serve waiting sales order backorders before the goods become free stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, locationID, receivedBarcodeIDs); err != nil {
+		return nil, err
+	}
+
+	receipt.TotalValue = round2(totalValue)
+	if _, err := tx.Exec(`
+		UPDATE consignment_receipts SET total_value = $1 WHERE consignment_receipt_id = $2
+	`, receipt.TotalValue, receipt.ConsignmentReceiptID); err != nil {
+		return nil, fmt.Errorf("failed to update consignment receipt total: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetReceipt(companyID, receipt.ConsignmentReceiptID)
+}
+
+func (s *ConsignmentService) GetReceipts(companyID int, supplierID *int) ([]models.ConsignmentReceipt, error) {
+	args := []interface{}{companyID}
+	query := `
+		SELECT r.consignment_receipt_id, r.company_id, r.location_id, r.supplier_id, s.name,
+		       r.receipt_number, r.receipt_date, r.reference_number, r.notes,
+		       r.total_value::float8, r.created_by, r.created_at
+		FROM consignment_receipts r
+		JOIN suppliers s ON s.supplier_id = r.supplier_id
+		WHERE r.company_id = $1
+	`
+	if supplierID != nil {
+		args = append(args, *supplierID)
+		query += fmt.Sprintf(" AND r.supplier_id = $%d", len(args))
+	}
+	query += " ORDER BY r.receipt_date DESC, r.consignment_receipt_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := make([]models.ConsignmentReceipt, 0)
+	for rows.Next() {
+		receipt, err := scanConsignmentReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, *receipt)
+	}
+	return receipts, rows.Err()
+}
+
+func (s *ConsignmentService) GetReceipt(companyID, receiptID int) (*models.ConsignmentReceipt, error) {
+	receipt, err := scanConsignmentReceipt(s.db.QueryRow(`
+		SELECT r.consignment_receipt_id, r.company_id, r.location_id, r.supplier_id, s.name,
+		       r.receipt_number, r.receipt_date, r.reference_number, r.notes,
+		       r.total_value::float8, r.created_by, r.created_at
+		FROM consignment_receipts r
+		JOIN suppliers s ON s.supplier_id = r.supplier_id
+		WHERE r.consignment_receipt_id = $1 AND r.company_id = $2
+	`, receiptID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("consignment receipt not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT i.consignment_receipt_item_id, i.consignment_receipt_id, i.product_id, p.name, i.barcode_id,
+		       i.quantity::float8, i.unit_cost::float8, i.batch_number, i.expiry_date,
+		       COALESCE(i.serial_numbers, '{}')
+		FROM consignment_receipt_items i
+		JOIN products p ON p.product_id = i.product_id
+		WHERE i.consignment_receipt_id = $1
+		ORDER BY i.consignment_receipt_item_id
+	`, receiptID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment receipt items: %w", err)
+	}
+	defer rows.Close()
+	receipt.Items = make([]models.ConsignmentReceiptItem, 0)
+	for rows.Next() {
+		var item models.ConsignmentReceiptItem
+		var serials pq.StringArray
+		if err := rows.Scan(
+			&item.ConsignmentReceiptItemID, &item.ConsignmentReceiptID, &item.ProductID, &item.ProductName, &item.BarcodeID,
+			&item.Quantity, &item.UnitCost, &item.BatchNumber, &item.ExpiryDate, &serials,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment receipt item: %w", err)
+		}
+		item.SerialNumbers = []string(serials)
+		receipt.Items = append(receipt.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read consignment receipt items: %w", err)
+	}
+	return receipt, nil
+}
+
+func scanConsignmentReceipt(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.ConsignmentReceipt, error) {
+	var receipt models.ConsignmentReceipt
+	if err := row.Scan(
+		&receipt.ConsignmentReceiptID, &receipt.CompanyID, &receipt.LocationID, &receipt.SupplierID, &receipt.SupplierName,
+		&receipt.ReceiptNumber, &receipt.ReceiptDate, &receipt.ReferenceNumber, &receipt.Notes,
+		&receipt.TotalValue, &receipt.CreatedBy, &receipt.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan consignment receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+type consignmentStatementKey struct {
+	ProductID int
+	BarcodeID int
+}
+
+// GetStatement reports a consignor's stock for a period. Received and sold
+// quantities fall within the period; on-hand stock is as of now.
+func (s *ConsignmentService) GetStatement(companyID, supplierID int, periodStart *time.Time, periodEnd time.Time) (*models.ConsignmentStatement, error) {
+	supplierName, err := s.loadSupplierName(s.db, companyID, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	statement := &models.ConsignmentStatement{
+		SupplierID:   supplierID,
+		SupplierName: supplierName,
+		PeriodStart:  periodStart,
+		PeriodEnd:    periodEnd,
+		Lines:        make([]models.ConsignmentStatementLine, 0),
+	}
+	index := make(map[consignmentStatementKey]int)
+	lineFor := func(productID int, productName string, barcodeID *int) *models.ConsignmentStatementLine {
+		key := consignmentStatementKey{ProductID: productID}
+		if barcodeID != nil {
+			key.BarcodeID = *barcodeID
+		}
+		idx, ok := index[key]
+		if !ok {
+			idx = len(statement.Lines)
+			index[key] = idx
+			statement.Lines = append(statement.Lines, models.ConsignmentStatementLine{
+				ProductID:   productID,
+				ProductName: productName,
+				BarcodeID:   barcodeID,
+			})
+		}
+		return &statement.Lines[idx]
+	}
+	periodEndExclusive := periodEnd.AddDate(0, 0, 1)
+
+	receivedRows, err := s.db.Query(`
+		SELECT i.product_id, p.name, i.barcode_id, COALESCE(SUM(i.quantity), 0)::float8
+		FROM consignment_receipts r
+		JOIN consignment_receipt_items i ON i.consignment_receipt_id = r.consignment_receipt_id
+		JOIN products p ON p.product_id = i.product_id
+		WHERE r.company_id = $1 AND r.supplier_id = $2
+		  AND r.receipt_date < $3
+		  AND ($4::date IS NULL OR r.receipt_date >= $4)
+		GROUP BY i.product_id, p.name, i.barcode_id
+		ORDER BY p.name, i.barcode_id
+	`, companyID, supplierID, periodEndExclusive, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment receipts: %w", err)
+	}
+	defer receivedRows.Close()
+	for receivedRows.Next() {
+		var productID int
+		var productName string
+		var barcodeID sql.NullInt64
+		var quantity float64
+		if err := receivedRows.Scan(&productID, &productName, &barcodeID, &quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment receipts: %w", err)
+		}
+		lineFor(productID, productName, intPtrFromNullInt64(barcodeID)).ReceivedQuantity += quantity
+	}
+	if err := receivedRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read consignment receipts: %w", err)
+	}
+
+	soldRows, err := s.db.Query(`
+		SELECT im.product_id, p.name, im.barcode_id,
+		       COALESCE(SUM(-im.quantity), 0)::float8,
+		       COALESCE(SUM(-im.quantity) FILTER (WHERE csl.movement_id IS NOT NULL), 0)::float8,
+		       COALESCE(SUM(ROUND((-im.quantity * sl.cost_price)::numeric, 2)) FILTER (WHERE csl.movement_id IS NULL), 0)::float8
+		FROM inventory_movements im
+		JOIN stock_lots sl ON sl.lot_id = im.stock_lot_id
+		JOIN products p ON p.product_id = im.product_id
+		LEFT JOIN consignment_settlement_lines csl ON csl.movement_id = im.movement_id
+		WHERE im.company_id = $1
+		  AND sl.ownership_type = 'CONSIGNMENT_IN'
+		  AND sl.consignor_supplier_id = $2
+		  AND im.movement_type IN ('SALE', 'SALE_EDIT')
+		  AND im.quantity < 0
+		  AND im.occurred_at < $3
+		  AND ($4::timestamp IS NULL OR im.occurred_at >= $4)
+		GROUP BY im.product_id, p.name, im.barcode_id
+		ORDER BY p.name, im.barcode_id
+	`, companyID, supplierID, periodEndExclusive, periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment sales: %w", err)
+	}
+	defer soldRows.Close()
+	for soldRows.Next() {
+		var productID int
+		var productName string
+		var barcodeID sql.NullInt64
+		var sold, settled, unsettledAmount float64
+		if err := soldRows.Scan(&productID, &productName, &barcodeID, &sold, &settled, &unsettledAmount); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment sales: %w", err)
+		}
+		line := lineFor(productID, productName, intPtrFromNullInt64(barcodeID))
+		line.SoldQuantity += sold
+		line.SettledQuantity += settled
+		line.UnsettledQuantity += sold - settled
+		line.UnsettledAmount += unsettledAmount
+	}
+	if err := soldRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read consignment sales: %w", err)
+	}
+
+	onHandRows, err := s.db.Query(`
+		SELECT sl.product_id, p.name, sl.barcode_id,
+		       COALESCE(SUM(sl.remaining_quantity), 0)::float8,
+		       COALESCE(SUM(sl.remaining_quantity * sl.cost_price), 0)::float8
+		FROM stock_lots sl
+		JOIN products p ON p.product_id = sl.product_id
+		WHERE sl.company_id = $1
+		  AND sl.ownership_type = 'CONSIGNMENT_IN'
+		  AND sl.consignor_supplier_id = $2
+		  AND sl.remaining_quantity > 0
+		GROUP BY sl.product_id, p.name, sl.barcode_id
+		ORDER BY p.name, sl.barcode_id
+	`, companyID, supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment stock on hand: %w", err)
+	}
+	defer onHandRows.Close()
+	for onHandRows.Next() {
+		var productID int
+		var productName string
+		var barcodeID sql.NullInt64
+		var quantity, value float64
+		if err := onHandRows.Scan(&productID, &productName, &barcodeID, &quantity, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment stock on hand: %w", err)
+		}
+		line := lineFor(productID, productName, intPtrFromNullInt64(barcodeID))
+		line.OnHandQuantity += quantity
+		line.OnHandValue += value
+	}
+	if err := onHandRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read consignment stock on hand: %w", err)
+	}
+
+	for i := range statement.Lines {
+		line := &statement.Lines[i]
+		line.UnsettledAmount = round2(line.UnsettledAmount)
+		line.OnHandValue = round2(line.OnHandValue)
+		statement.ReceivedQuantity += line.ReceivedQuantity
+		statement.SoldQuantity += line.SoldQuantity
+		statement.SettledQuantity += line.SettledQuantity
+		statement.UnsettledQuantity += line.UnsettledQuantity
+		statement.UnsettledAmount += line.UnsettledAmount
+		statement.OnHandQuantity += line.OnHandQuantity
+		statement.OnHandValue += line.OnHandValue
+	}
+	statement.UnsettledAmount = round2(statement.UnsettledAmount)
+	statement.OnHandValue = round2(statement.OnHandValue)
+	return statement, nil
+}
+
+// loadUnsettledSalesTx locks the consignor's sale movements in the period
+// that no settlement has billed yet.
+func (s *ConsignmentService) loadUnsettledSalesTx(tx *sql.Tx, companyID, supplierID int, periodStart *time.Time, periodEnd time.Time) ([]consignmentSale, error) {
+	rows, err := tx.Query(`
+		SELECT im.movement_id, im.stock_lot_id, im.product_id, im.barcode_id,
+		       (-im.quantity)::float8, sl.cost_price::float8, im.occurred_at
+		FROM inventory_movements im
+		JOIN stock_lots sl ON sl.lot_id = im.stock_lot_id
+		LEFT JOIN consignment_settlement_lines csl ON csl.movement_id = im.movement_id
+		WHERE im.company_id = $1
+		  AND sl.ownership_type = 'CONSIGNMENT_IN'
+		  AND sl.consignor_supplier_id = $2
+		  AND im.movement_type IN ('SALE', 'SALE_EDIT')
+		  AND im.quantity < 0
+		  AND csl.movement_id IS NULL
+		  AND im.occurred_at < $3
+		  AND ($4::timestamp IS NULL OR im.occurred_at >= $4)
+		ORDER BY im.occurred_at, im.movement_id
+		FOR UPDATE OF im
+	`, companyID, supplierID, periodEnd.AddDate(0, 0, 1), periodStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consignment sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := make([]consignmentSale, 0)
+	for rows.Next() {
+		var sale consignmentSale
+		var barcodeID sql.NullInt64
+		if err := rows.Scan(&sale.MovementID, &sale.LotID, &sale.ProductID, &barcodeID, &sale.Quantity, &sale.UnitCost, &sale.SoldAt); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment sale: %w", err)
+		}
+		sale.BarcodeID = intPtrFromNullInt64(barcodeID)
+		sales = append(sales, sale)
+	}
+	return sales, rows.Err()
+}
+
+// CreateSettlement bills the consignor for unsettled consignment sales in the
+// period. The supplier invoice is a received purchase at the agreed lot cost,
+// so it flows into payables, AP aging and payment runs like any other bill.
+func (s *ConsignmentService) CreateSettlement(companyID, locationID, userID int, req *models.CreateConsignmentSettlementRequest) (*models.ConsignmentSettlement, error) {
+	periodStart, err := parseConsignmentDate("period_start", req.PeriodStart)
+	if err != nil {
+		return nil, err
+	}
+	periodEndPtr, err := parseConsignmentDate("period_end", &req.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if periodEndPtr == nil {
+		return nil, fmt.Errorf("period_end is required")
+	}
+	periodEnd := *periodEndPtr
+	if periodStart != nil && periodStart.After(periodEnd) {
+		return nil, fmt.Errorf("period_start must not be after period_end")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locationOK bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)`, locationID, companyID).Scan(&locationOK); err != nil {
+		return nil, fmt.Errorf("failed to verify location: %w", err)
+	}
+	if !locationOK {
+		return nil, fmt.Errorf("location not found")
+	}
+	if _, err := s.loadSupplierName(tx, companyID, req.SupplierID); err != nil {
+		return nil, err
+	}
+
+	sales, err := s.loadUnsettledSalesTx(tx, companyID, req.SupplierID, periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(sales) == 0 {
+		return nil, fmt.Errorf("no unsettled consignment sales for this supplier in the period")
+	}
+	invoiceLines := consignmentInvoiceLines(sales)
+	totalQuantity, totalAmount := 0.0, 0.0
+	for _, line := range invoiceLines {
+		totalQuantity += line.Quantity
+		totalAmount += line.Amount
+	}
+	totalQuantity = round3(totalQuantity)
+	totalAmount = round2(totalAmount)
+
+	ns := NewNumberingSequenceService()
+	settlementNumber, err := ns.NextNumber(tx, "consignment_settlement", companyID, &locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate consignment settlement number: %w", err)
+	}
+	purchaseNumber, err := ns.NextNumber(tx, "purchase", companyID, &locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate purchase number: %w", err)
+	}
+
+	invoiceDate := time.Now()
+	paymentTerms := 0
+	if req.PaymentTerms != nil {
+		paymentTerms = *req.PaymentTerms
+	}
+	var dueDate *time.Time
+	if paymentTerms > 0 {
+		due := invoiceDate.AddDate(0, 0, paymentTerms)
+		dueDate = &due
+	}
+	invoiceNotes := fmt.Sprintf("Consignment settlement %s", settlementNumber)
+	var purchaseID int
+	if err := tx.QueryRow(`
+		INSERT INTO purchases (purchase_number, location_id, supplier_id, purchase_date,
+		                       subtotal, tax_amount, discount_amount, total_amount, paid_amount,
+		                       payment_terms, due_date, status, reference_number, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, 0, 0, $5, 0, $6, $7, 'RECEIVED', $8, $9, $10)
+		RETURNING purchase_id
+	`, purchaseNumber, locationID, req.SupplierID, invoiceDate, totalAmount,
+		paymentTerms, dueDate, settlementNumber, invoiceNotes, userID,
+	).Scan(&purchaseID); err != nil {
+		return nil, fmt.Errorf("failed to create consignment supplier invoice: %w", err)
+	}
+
+	productIDs := make([]int, 0, len(invoiceLines))
+	for _, line := range invoiceLines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	productMetaByID, err := fetchProductMeta(tx, companyID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range invoiceLines {
+		// Consignment stock is counted in stock units, so the invoice is too.
+		stockUnitID := productMetaByID[line.ProductID].StockUnitID
+		if _, err := tx.Exec(`
+			INSERT INTO purchase_details (purchase_id, product_id, barcode_id, quantity, unit_price,
+			                              discount_percentage, discount_amount, tax_id, tax_amount,
+			                              line_total, received_quantity, stock_unit_id, purchase_unit_id,
+			                              purchase_uom_mode, purchase_to_stock_factor, stock_quantity)
+			VALUES ($1, $2, $3, $4, $5, 0, 0, NULL, 0, $6, $4, $7, $7, 'LOOSE', 1.0, $4)
+		`, purchaseID, line.ProductID, line.BarcodeID, line.Quantity, line.UnitCost, line.Amount, stockUnitID); err != nil {
+			return nil, fmt.Errorf("failed to create consignment supplier invoice line: %w", err)
+		}
+	}
+
+	settlement := &models.ConsignmentSettlement{
+		CompanyID:        companyID,
+		LocationID:       locationID,
+		SupplierID:       req.SupplierID,
+		SettlementNumber: settlementNumber,
+		PeriodStart:      periodStart,
+		PeriodEnd:        periodEnd,
+		TotalQuantity:    totalQuantity,
+		TotalAmount:      totalAmount,
+		PurchaseID:       &purchaseID,
+		Notes:            req.Notes,
+		CreatedBy:        userID,
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO consignment_settlements (
+			company_id, location_id, supplier_id, settlement_number, period_start, period_end,
+			total_quantity, total_amount, purchase_id, notes, created_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING settlement_id
+	`, companyID, locationID, req.SupplierID, settlementNumber, periodStart, periodEnd,
+		totalQuantity, totalAmount, purchaseID, req.Notes, userID,
+	).Scan(&settlement.SettlementID); err != nil {
+		return nil, fmt.Errorf("failed to create consignment settlement: %w", err)
+	}
+	for _, sale := range sales {
+		if _, err := tx.Exec(`
+			INSERT INTO consignment_settlement_lines (
+				settlement_id, movement_id, stock_lot_id, product_id, barcode_id,
+				quantity, unit_cost, amount, sold_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, settlement.SettlementID, sale.MovementID, sale.LotID, sale.ProductID, sale.BarcodeID,
+			sale.Quantity, sale.UnitCost, consignmentSaleAmount(sale), sale.SoldAt); err != nil {
+			if isUniqueViolation(err) {
+				return nil, fmt.Errorf("consignment sale %d is already settled", sale.MovementID)
+			}
+			return nil, fmt.Errorf("failed to create consignment settlement line: %w", err)
+		}
+	}
+
+	finance := NewFinanceIntegrityServiceWithDB(s.db)
+	if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
+		CompanyID:     companyID,
+		LocationID:    &locationID,
+		EventType:     financeEventLedgerPurchase,
+		AggregateType: "purchase",
+		AggregateID:   purchaseID,
+		Payload:       models.JSONB{},
+		CreatedBy:     &userID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue consignment invoice ledger posting: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if err := finance.ProcessAggregate(companyID, "purchase", purchaseID); err != nil {
+		log.Printf("warning: failed to process finance outbox for consignment settlement %d: %v", settlement.SettlementID, err)
+	}
+	return s.GetSettlement(companyID, settlement.SettlementID)
+}
+
+func (s *ConsignmentService) GetSettlements(companyID int, supplierID *int) ([]models.ConsignmentSettlement, error) {
+	args := []interface{}{companyID}
+	query := `
+		SELECT cs.settlement_id, cs.company_id, cs.location_id, cs.supplier_id, s.name,
+		       cs.settlement_number, cs.period_start, cs.period_end, cs.total_quantity::float8,
+		       cs.total_amount::float8, cs.purchase_id, p.purchase_number, cs.notes,
+		       cs.created_by, cs.created_at
+		FROM consignment_settlements cs
+		JOIN suppliers s ON s.supplier_id = cs.supplier_id
+		LEFT JOIN purchases p ON p.purchase_id = cs.purchase_id
+		WHERE cs.company_id = $1
+	`
+	if supplierID != nil {
+		args = append(args, *supplierID)
+		query += fmt.Sprintf(" AND cs.supplier_id = $%d", len(args))
+	}
+	query += " ORDER BY cs.period_end DESC, cs.settlement_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment settlements: %w", err)
+	}
+	defer rows.Close()
+
+	settlements := make([]models.ConsignmentSettlement, 0)
+	for rows.Next() {
+		settlement, err := scanConsignmentSettlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, *settlement)
+	}
+	return settlements, rows.Err()
+}
+
+func (s *ConsignmentService) GetSettlement(companyID, settlementID int) (*models.ConsignmentSettlement, error) {
+	settlement, err := scanConsignmentSettlement(s.db.QueryRow(`
+		SELECT cs.settlement_id, cs.company_id, cs.location_id, cs.supplier_id, s.name,
+		       cs.settlement_number, cs.period_start, cs.period_end, cs.total_quantity::float8,
+		       cs.total_amount::float8, cs.purchase_id, p.purchase_number, cs.notes,
+		       cs.created_by, cs.created_at
+		FROM consignment_settlements cs
+		JOIN suppliers s ON s.supplier_id = cs.supplier_id
+		LEFT JOIN purchases p ON p.purchase_id = cs.purchase_id
+		WHERE cs.settlement_id = $1 AND cs.company_id = $2
+	`, settlementID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("consignment settlement not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT l.settlement_line_id, l.movement_id, l.stock_lot_id, l.product_id, p.name, l.barcode_id,
+		       l.quantity::float8, l.unit_cost::float8, l.amount::float8, l.sold_at
+		FROM consignment_settlement_lines l
+		JOIN products p ON p.product_id = l.product_id
+		WHERE l.settlement_id = $1
+		ORDER BY l.sold_at, l.settlement_line_id
+	`, settlementID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consignment settlement lines: %w", err)
+	}
+	defer rows.Close()
+	settlement.Lines = make([]models.ConsignmentSettlementLine, 0)
+	for rows.Next() {
+		var line models.ConsignmentSettlementLine
+		if err := rows.Scan(
+			&line.SettlementLineID, &line.MovementID, &line.StockLotID, &line.ProductID, &line.ProductName, &line.BarcodeID,
+			&line.Quantity, &line.UnitCost, &line.Amount, &line.SoldAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan consignment settlement line: %w", err)
+		}
+		settlement.Lines = append(settlement.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read consignment settlement lines: %w", err)
+	}
+	return settlement, nil
+}
+
+func scanConsignmentSettlement(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.ConsignmentSettlement, error) {
+	var settlement models.ConsignmentSettlement
+	if err := row.Scan(
+		&settlement.SettlementID, &settlement.CompanyID, &settlement.LocationID, &settlement.SupplierID, &settlement.SupplierName,
+		&settlement.SettlementNumber, &settlement.PeriodStart, &settlement.PeriodEnd, &settlement.TotalQuantity,
+		&settlement.TotalAmount, &settlement.PurchaseID, &settlement.PurchaseNumber, &settlement.Notes,
+		&settlement.CreatedBy, &settlement.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan consignment settlement: %w", err)
+	}
+	return &settlement, nil
+}
+
+// SetDealerLocation assigns or clears the dealer customer of a location. The
+// location must be empty, otherwise lots already there would carry the
+// wrong owner.
+func (s *ConsignmentService) SetDealerLocation(companyID, locationID int, customerID *int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := loadLocationConsigneeTx(tx, companyID, locationID)
+	if err != nil {
+		return err
+	}
+	if customerID != nil {
+		var customerOK bool
+		if err := tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM customers WHERE customer_id = $1 AND company_id = $2)
+		`, *customerID, companyID).Scan(&customerOK); err != nil {
+			return fmt.Errorf("failed to verify customer: %w", err)
+		}
+		if !customerOK {
+			return fmt.Errorf("customer not found")
+		}
+	}
+	if (current == nil && customerID == nil) || (current != nil && customerID != nil && *current == *customerID) {
+		return nil
+	}
+
+	var hasStock bool
+	if err := tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM stock_lots
+			WHERE company_id = $1 AND location_id = $2 AND remaining_quantity > 0
+		)
+	`, companyID, locationID).Scan(&hasStock); err != nil {
+		return fmt.Errorf("failed to check location stock: %w", err)
+	}
+	if hasStock {
+		return fmt.Errorf("location still holds stock; transfer it out before changing the dealer")
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE locations
+		SET consignee_customer_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE location_id = $2 AND company_id = $3
+	`, customerID, locationID, companyID); err != nil {
+		return fmt.Errorf("failed to update location: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *ConsignmentService) GetDealerLocations(companyID int) ([]models.DealerLocation, error) {
+	rows, err := s.db.Query(`
+		SELECT l.location_id, l.name, c.customer_id, c.name,
+		       COALESCE(SUM(sl.remaining_quantity), 0)::float8,
+		       COALESCE(SUM(sl.remaining_quantity * sl.cost_price), 0)::float8
+		FROM locations l
+		JOIN customers c ON c.customer_id = l.consignee_customer_id
+		LEFT JOIN stock_lots sl ON sl.location_id = l.location_id
+		                      AND sl.ownership_type = 'CONSIGNMENT_OUT'
+		                      AND sl.remaining_quantity > 0
+		WHERE l.company_id = $1
+		GROUP BY l.location_id, l.name, c.customer_id, c.name
+		ORDER BY l.name
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dealer locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := make([]models.DealerLocation, 0)
+	for rows.Next() {
+		var loc models.DealerLocation
+		if err := rows.Scan(&loc.LocationID, &loc.Name, &loc.CustomerID, &loc.CustomerName, &loc.OnHandQuantity, &loc.OnHandValue); err != nil {
+			return nil, fmt.Errorf("failed to scan dealer location: %w", err)
+		}
+		loc.OnHandValue = round2(loc.OnHandValue)
+		locations = append(locations, loc)
+	}
+	return locations, rows.Err()
+}
+
+// ReportDealerSales invoices the dealer customer for what it sold out of a
+// dealer location. The sale draws on the consignment-out lots there, which is
+// when the stock leaves our books.
+func (s *ConsignmentService) ReportDealerSales(companyID, locationID, userID int, req *models.DealerSalesReportRequest) (*models.Sale, error) {
+	var customerID sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT consignee_customer_id FROM locations WHERE location_id = $1 AND company_id = $2
+	`, locationID, companyID).Scan(&customerID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("location not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load location: %w", err)
+	}
+	if !customerID.Valid {
+		return nil, fmt.Errorf("location is not a dealer location")
+	}
+	dealerID := int(customerID.Int64)
+
+	notes := "Dealer sales report"
+	if req.ReferenceNumber != nil && strings.TrimSpace(*req.ReferenceNumber) != "" {
+		notes += " " + strings.TrimSpace(*req.ReferenceNumber)
+	}
+	if req.Notes != nil && strings.TrimSpace(*req.Notes) != "" {
+		notes += ": " + strings.TrimSpace(*req.Notes)
+	}
+
+	saleReq := &models.CreateSaleRequest{
+		CustomerID: &dealerID,
+		Items:      make([]models.CreateSaleDetailRequest, 0, len(req.Items)),
+		Notes:      &notes,
+	}
+	for _, item := range req.Items {
+		productID := item.ProductID
+		saleReq.Items = append(saleReq.Items, models.CreateSaleDetailRequest{
+			ProductID:        &productID,
+			BarcodeID:        item.BarcodeID,
+			Quantity:         item.Quantity,
+			UnitPrice:        item.UnitPrice,
+			TaxID:            item.TaxID,
+			SerialNumbers:    item.SerialNumbers,
+			BatchAllocations: item.BatchAllocations,
+		})
+	}
+	return NewSalesService().CreateSaleWithOptions(companyID, locationID, userID, saleReq, nil, CreateSaleOptions{
+		TransactionType:     "B2B",
+		SourceChannel:       "CONSIGNMENT",
+		SkipCustomerRewards: true,
+	})
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestTransferLotOwnership(t *testing.T) {
+	supplierID, dealerID := 4, 9
+
+	got, err := transferLotOwnership(models.StockOwnershipConsignmentIn, &supplierID, nil)
+	if err != nil || got.Type != models.StockOwnershipConsignmentIn || got.ConsignorSupplierID != &supplierID {
+		t.Fatalf("consignment-in between stores: got %+v, %v", got, err)
+	}
+	if _, err := transferLotOwnership(models.StockOwnershipConsignmentIn, &supplierID, &dealerID); err == nil {
+		t.Fatalf("expected an error placing vendor-owned stock at a dealer")
+	}
+
+	got, err = transferLotOwnership(models.StockOwnershipOwned, nil, &dealerID)
+	if err != nil || got.Type != models.StockOwnershipConsignmentOut || got.ConsigneeCustomerID != &dealerID {
+		t.Fatalf("owned stock to dealer: got %+v, %v", got, err)
+	}
+
+	got, err = transferLotOwnership(models.StockOwnershipConsignmentOut, nil, nil)
+	if err != nil || got.Type != models.StockOwnershipOwned || got.ConsigneeCustomerID != nil {
+		t.Fatalf("dealer stock coming back: got %+v, %v", got, err)
+	}
+}
+
+func TestConsignmentInvoiceLines(t *testing.T) {
+	barcodeA, barcodeB := 11, 12
+	sales := []consignmentSale{
+		{MovementID: 1, ProductID: 1, BarcodeID: &barcodeA, Quantity: 2, UnitCost: 1.125},
+		{MovementID: 2, ProductID: 1, BarcodeID: &barcodeB, Quantity: 1, UnitCost: 5},
+		{MovementID: 3, ProductID: 1, BarcodeID: &barcodeA, Quantity: 1, UnitCost: 1.125},
+		{MovementID: 4, ProductID: 1, BarcodeID: &barcodeA, Quantity: 1, UnitCost: 4},
+	}
+
+	lines := consignmentInvoiceLines(sales)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 invoice lines, got %d: %+v", len(lines), lines)
+	}
+	first := lines[0]
+	if *first.BarcodeID != barcodeA || first.Quantity != 3 || first.UnitCost != 1.125 || first.Amount != 3.38 {
+		t.Fatalf("unexpected first line %+v", first)
+	}
+	if *lines[1].BarcodeID != barcodeB || lines[1].Amount != 5 {
+		t.Fatalf("unexpected second line %+v", lines[1])
+	}
+	if lines[2].UnitCost != 4 || lines[2].Amount != 4 {
+		t.Fatalf("unexpected third line %+v", lines[2])
+	}
+}
@@ -14,3 +14,16 @@ func (e *CreditLimitExceededError) Error() string {
 	}
 	return fmt.Sprintf("credit limit exceeded (limit %.2f, outstanding %.2f, new %.2f)", e.CreditLimit, e.CurrentBalance, e.AttemptedDelta)
 }
+
+// CustomerDunningBlockedError is returned when a credit sale is attempted for a
+// customer whose dunning level blocks further credit.
+type CustomerDunningBlockedError struct {
+	DunningLevel int
+}
+
+func (e *CustomerDunningBlockedError) Error() string {
+	if e == nil {
+		return "customer credit blocked by dunning"
+	}
+	return fmt.Sprintf("customer credit blocked by dunning (level %d)", e.DunningLevel)
+}
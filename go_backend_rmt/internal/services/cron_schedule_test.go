@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	utc := time.UTC
+	base := time.Date(2026, 4, 15, 10, 30, 0, 0, utc) // Wednesday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 7 * * *", time.Date(2026, 4, 16, 7, 0, 0, 0, utc)},
+		{"45 10 * * *", time.Date(2026, 4, 15, 10, 45, 0, 0, utc)},
+		{"30 10 * * *", time.Date(2026, 4, 16, 10, 30, 0, 0, utc)},
+		{"*/20 * * * *", time.Date(2026, 4, 15, 10, 40, 0, 0, utc)},
+		{"0 8 * * mon-fri", time.Date(2026, 4, 16, 8, 0, 0, 0, utc)},
+		{"0 8 * * 7", time.Date(2026, 4, 19, 8, 0, 0, 0, utc)},
+		{"0 6 1 * *", time.Date(2026, 5, 1, 6, 0, 0, 0, utc)},
+		{"0 0 1,15 * 5", time.Date(2026, 4, 17, 0, 0, 0, 0, utc)},
+		{"0 9 29 feb *", time.Date(2028, 2, 29, 9, 0, 0, 0, utc)},
+		{"@monthly", time.Date(2026, 5, 1, 0, 0, 0, 0, utc)},
+	}
+	for _, tc := range cases {
+		s, err := parseCronSchedule(tc.expr)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.expr, err)
+		}
+		if got := s.Next(base, utc); !got.Equal(tc.want) {
+			t.Fatalf("%q: got %s want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestCronScheduleNextUsesTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Riyadh")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	s, err := parseCronSchedule("0 7 * * *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := s.Next(time.Date(2026, 4, 15, 5, 0, 0, 0, time.UTC), loc)
+	want := time.Date(2026, 4, 16, 4, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("got %s want %s", got.UTC(), want)
+	}
+}
+
+func TestCronScheduleNeverFires(t *testing.T) {
+	s, err := parseCronSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Next(time.Now(), time.UTC); !got.IsZero() {
+		t.Fatalf("expected no firing, got %s", got)
+	}
+}
+
+func TestParseCronScheduleRejectsBadExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "*/0 * * * *", "5-1 * * * *", "0 0 * * funday"} {
+		if _, err := parseCronSchedule(expr); err == nil {
+			t.Fatalf("%q: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// customReportAllLocationsPermission lets a user with an assigned location
+// report across the whole company.
+const customReportAllLocationsPermission = "REPORT_ALL_LOCATIONS"
+
+// CustomReportService saves user-built report definitions and runs them
+// through the semantic layer.
+type CustomReportService struct {
+	db *sql.DB
+}
+
+func NewCustomReportService() *CustomReportService {
+	return &CustomReportService{db: database.GetDB()}
+}
+
+// Models returns the semantic models the report builder may use.
+func (s *CustomReportService) Models() []models.SemanticModelInfo {
+	return semanticCatalog()
+}
+
+// reportScope restricts users bound to locations to those locations unless
+// they hold REPORT_ALL_LOCATIONS.
+func (s *CustomReportService) reportScope(companyID, userID, roleID int) (customReportScope, error) {
+	locationIDs, err := userReportLocations(s.db, companyID, userID, roleID)
+	if err != nil {
+		return customReportScope{CompanyID: companyID}, err
+	}
+	return customReportScope{CompanyID: companyID, LocationIDs: locationIDs}, nil
+}
+
+// userReportLocations lists the locations a user's reports are limited to:
+// their granted locations, or their own location when they have no grants.
+// nil means every location, either because the user is not bound to one or
+// because their active role (see UserCan) holds REPORT_ALL_LOCATIONS.
+func userReportLocations(db *sql.DB, companyID, userID, roleID int) ([]int, error) {
+	var locationIDs pq.Int64Array
+	err := db.QueryRow(`
+		SELECT COALESCE(
+		         NULLIF(ARRAY(
+		           SELECT ul.location_id FROM user_locations ul
+		           JOIN locations l ON l.location_id = ul.location_id
+		           WHERE ul.user_id = u.user_id AND l.company_id = u.company_id
+		           ORDER BY ul.location_id = u.location_id DESC, ul.location_id
+		         ), '{}'),
+		         CASE WHEN u.location_id IS NULL THEN '{}'::int[] ELSE ARRAY[u.location_id] END
+		       )
+		FROM users u WHERE u.user_id = $1 AND u.company_id = $2 AND u.is_deleted = FALSE
+	`, userID, companyID).Scan(&locationIDs)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user location: %w", err)
+	}
+	if len(locationIDs) == 0 {
+		return nil, nil
+	}
+	all, err := (&PermissionService{db: db}).UserCan(userID, roleID, customReportAllLocationsPermission)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check location access: %w", err)
+	}
+	if all {
+		return nil, nil
+	}
+	ids := make([]int, len(locationIDs))
+	for i, id := range locationIDs {
+		ids[i] = int(id)
+	}
+	return ids, nil
+}
+
+// scopeReportLocations applies allowed (see userReportLocations) to a
+// requested location filter: a restricted user may narrow to one of their
+// locations and otherwise covers all of them.
+func scopeReportLocations(allowed []int, requested *int) ([]int, error) {
+	if requested != nil {
+		if !utils.LocationAllowed(allowed, *requested) {
+			return nil, fmt.Errorf("location access denied")
+		}
+		return []int{*requested}, nil
+	}
+	return allowed, nil
+}
+
+// applyCustomReportRunOptions lets a run replace the saved date restriction.
+func applyCustomReportRunOptions(def models.CustomReportDefinition, opts models.CustomReportRunOptions) models.CustomReportDefinition {
+	switch {
+	case opts.DateRange != "":
+		def.DateRange, def.FromDate, def.ToDate = opts.DateRange, "", ""
+	case opts.FromDate != "" || opts.ToDate != "":
+		def.DateRange, def.FromDate, def.ToDate = "", opts.FromDate, opts.ToDate
+	}
+	return def
+}
+
+// Query runs an unsaved definition.
+func (s *CustomReportService) Query(companyID, userID, roleID int, def models.CustomReportDefinition, opts models.CustomReportRunOptions) (*models.CustomReportResult, error) {
+	scope, err := s.reportScope(companyID, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return runCustomReport(s.db, "", applyCustomReportRunOptions(def, opts), scope, time.Now())
+}
+
+// RunReport runs a saved definition visible to the user.
+func (s *CustomReportService) RunReport(companyID, userID, roleID, reportID int, opts models.CustomReportRunOptions) (*models.CustomReportResult, error) {
+	report, err := s.GetReport(companyID, userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := s.reportScope(companyID, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return runCustomReport(s.db, report.Name, applyCustomReportRunOptions(report.Definition, opts), scope, time.Now())
+}
+
+func runCustomReport(db *sql.DB, title string, def models.CustomReportDefinition, scope customReportScope, now time.Time) (*models.CustomReportResult, error) {
+	compiled, err := compileCustomReport(def, scope, now)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(compiled.sql, compiled.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run custom report: %w", err)
+	}
+	defer rows.Close()
+
+	if title == "" {
+		title = compiled.model.label + " report"
+	}
+	result := &models.CustomReportResult{
+		Title:       title,
+		Model:       compiled.model.key,
+		Rows:        []map[string]interface{}{},
+		LocationIDs: scope.LocationIDs,
+	}
+	for _, d := range compiled.dimensions {
+		format := "text"
+		if d.filterType == "date" {
+			format = "date"
+		}
+		result.Columns = append(result.Columns, models.CustomReportColumn{Key: d.key, Label: d.label, Kind: "dimension", Format: format})
+	}
+	for _, ms := range compiled.measures {
+		result.Columns = append(result.Columns, models.CustomReportColumn{Key: ms.key, Label: ms.label, Kind: "measure", Format: ms.format})
+	}
+
+	for rows.Next() {
+		dims := make([]sql.NullString, len(compiled.dimensions))
+		measures := make([]sql.NullFloat64, len(compiled.measures))
+		dest := make([]interface{}, 0, len(dims)+len(measures))
+		for i := range dims {
+			dest = append(dest, &dims[i])
+		}
+		for i := range measures {
+			dest = append(dest, &measures[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan custom report row: %w", err)
+		}
+		if len(result.Rows) == compiled.limit {
+			result.Truncated = true
+			break
+		}
+		row := make(map[string]interface{}, len(dest))
+		for i, d := range compiled.dimensions {
+			row[d.key] = nil
+			if dims[i].Valid {
+				row[d.key] = dims[i].String
+			}
+		}
+		for i, ms := range compiled.measures {
+			row[ms.key] = measures[i].Float64
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read custom report rows: %w", err)
+	}
+
+	if def.Pivot != nil {
+		if err := pivotCustomReport(result, def.Pivot.Dimension); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// pivotCustomReport turns the values of one dimension into column groups,
+// one column per measure, keeping the order in which values first appear.
+func pivotCustomReport(result *models.CustomReportResult, pivotKey string) error {
+	var (
+		rowCols, measureCols []models.CustomReportColumn
+		pivotLabel           string
+	)
+	for _, col := range result.Columns {
+		switch {
+		case col.Key == pivotKey:
+			pivotLabel = col.Label
+		case col.Kind == "measure":
+			measureCols = append(measureCols, col)
+		default:
+			rowCols = append(rowCols, col)
+		}
+	}
+	if pivotLabel == "" {
+		return fmt.Errorf("pivot dimension must be one of the selected dimensions")
+	}
+
+	valueIndex := map[string]int{}
+	var values []string
+	rowIndex := map[string]int{}
+	var pivoted []map[string]interface{}
+	for _, row := range result.Rows {
+		value := fmt.Sprint(row[pivotKey])
+		if row[pivotKey] == nil {
+			value = "(none)"
+		}
+		vi, ok := valueIndex[value]
+		if !ok {
+			if len(values) == customReportMaxPivotValues {
+				return fmt.Errorf("pivot on %s produces more than %d columns; add a filter", pivotLabel, customReportMaxPivotValues)
+			}
+			vi = len(values)
+			valueIndex[value] = vi
+			values = append(values, value)
+		}
+
+		keyParts := make([]string, len(rowCols))
+		for i, col := range rowCols {
+			keyParts[i] = fmt.Sprint(row[col.Key])
+		}
+		rowKey := strings.Join(keyParts, "\x00")
+		ri, ok := rowIndex[rowKey]
+		if !ok {
+			ri = len(pivoted)
+			rowIndex[rowKey] = ri
+			out := map[string]interface{}{}
+			for _, col := range rowCols {
+				out[col.Key] = row[col.Key]
+			}
+			pivoted = append(pivoted, out)
+		}
+		for _, col := range measureCols {
+			pivoted[ri][fmt.Sprintf("%s_%d", col.Key, vi)] = row[col.Key]
+		}
+	}
+
+	columns := append([]models.CustomReportColumn{}, rowCols...)
+	for vi, value := range values {
+		for _, col := range measureCols {
+			label := value
+			if len(measureCols) > 1 {
+				label = value + " " + col.Label
+			}
+			columns = append(columns, models.CustomReportColumn{
+				Key: fmt.Sprintf("%s_%d", col.Key, vi), Label: label, Kind: "measure", Format: col.Format,
+			})
+		}
+	}
+	// Cells with no underlying rows are zero rather than missing.
+	for _, row := range pivoted {
+		for _, col := range columns[len(rowCols):] {
+			if _, ok := row[col.Key]; !ok {
+				row[col.Key] = float64(0)
+			}
+		}
+	}
+	if pivoted == nil {
+		pivoted = []map[string]interface{}{}
+	}
+	result.Columns = columns
+	result.Rows = pivoted
+	return nil
+}
+
+// CustomReportTable adapts a result for utils.GenerateExcel, GeneratePDF and
+// GenerateCSV.
+func CustomReportTable(result *models.CustomReportResult) *utils.ReportTable {
+	table := &utils.ReportTable{Title: result.Title, Rows: result.Rows}
+	for _, col := range result.Columns {
+		table.Columns = append(table.Columns, utils.ReportTableColumn{Key: col.Key, Label: col.Label, Format: col.Format})
+	}
+	return table
+}
+
+// validateCustomReportDefinition compiles def so saved reports only ever
+// reference fields the semantic layer declares.
+func validateCustomReportDefinition(companyID int, def models.CustomReportDefinition) error {
+	_, err := compileCustomReport(def, customReportScope{CompanyID: companyID}, time.Now())
+	return err
+}
+
+func (s *CustomReportService) CreateReport(companyID, userID int, req *models.CreateCustomReportRequest) (*models.CustomReport, error) {
+	if err := validateCustomReportDefinition(companyID, req.Definition); err != nil {
+		return nil, err
+	}
+	definition, err := json.Marshal(req.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report definition: %w", err)
+	}
+
+	var id int
+	err = s.db.QueryRow(`
+		INSERT INTO custom_reports (company_id, name, description, definition, is_shared, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING report_id
+	`, companyID, strings.TrimSpace(req.Name), req.Description, definition, req.IsShared, userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create custom report: %w", err)
+	}
+	return s.GetReport(companyID, userID, id)
+}
+
+func (s *CustomReportService) UpdateReport(companyID, userID, reportID int, req *models.UpdateCustomReportRequest) (*models.CustomReport, error) {
+	report, err := s.GetReport(companyID, userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if report.CreatedBy != userID {
+		return nil, fmt.Errorf("only the owner can change a shared report")
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		report.Name = name
+	}
+	if req.Description != nil {
+		report.Description = req.Description
+	}
+	if req.Definition != nil {
+		if err := utils.ValidateStruct(req.Definition); err != nil {
+			return nil, fmt.Errorf("invalid definition: %v", err)
+		}
+		if err := validateCustomReportDefinition(companyID, *req.Definition); err != nil {
+			return nil, err
+		}
+		report.Definition = *req.Definition
+	}
+	if req.IsShared != nil {
+		report.IsShared = *req.IsShared
+	}
+	definition, err := json.Marshal(report.Definition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report definition: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE custom_reports
+		SET name = $1, description = $2, definition = $3, is_shared = $4,
+		    updated_by = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE report_id = $6 AND company_id = $7 AND is_deleted = FALSE
+	`, report.Name, report.Description, definition, report.IsShared, userID, reportID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update custom report: %w", err)
+	}
+	return s.GetReport(companyID, userID, reportID)
+}
+
+func (s *CustomReportService) DeleteReport(companyID, userID, reportID int) error {
+	report, err := s.GetReport(companyID, userID, reportID)
+	if err != nil {
+		return err
+	}
+	if report.CreatedBy != userID {
+		return fmt.Errorf("only the owner can delete a shared report")
+	}
+	_, err = s.db.Exec(`
+		UPDATE custom_reports SET is_deleted = TRUE, updated_by = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE report_id = $2 AND company_id = $3
+	`, userID, reportID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom report: %w", err)
+	}
+	return nil
+}
+
+const customReportSelect = `
+	SELECT report_id, company_id, name, description, definition, is_shared,
+	       created_by, updated_by, created_at, updated_at
+	FROM custom_reports
+`
+
+func scanCustomReport(row interface{ Scan(...interface{}) error }) (*models.CustomReport, error) {
+	var (
+		report     models.CustomReport
+		definition []byte
+	)
+	err := row.Scan(&report.ReportID, &report.CompanyID, &report.Name, &report.Description, &definition,
+		&report.IsShared, &report.CreatedBy, &report.UpdatedBy, &report.CreatedAt, &report.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(definition, &report.Definition); err != nil {
+		return nil, fmt.Errorf("failed to decode report definition: %w", err)
+	}
+	return &report, nil
+}
+
+// GetReport returns a report the user owns or that was shared with the company.
+func (s *CustomReportService) GetReport(companyID, userID, reportID int) (*models.CustomReport, error) {
+	report, err := scanCustomReport(s.db.QueryRow(customReportSelect+`
+		WHERE report_id = $1 AND company_id = $2 AND is_deleted = FALSE
+		  AND (created_by = $3 OR is_shared = TRUE)
+	`, reportID, companyID, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("custom report not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom report: %w", err)
+	}
+	return report, nil
+}
+
+func (s *CustomReportService) ListReports(companyID, userID int) ([]models.CustomReport, error) {
+	rows, err := s.db.Query(customReportSelect+`
+		WHERE company_id = $1 AND is_deleted = FALSE AND (created_by = $2 OR is_shared = TRUE)
+		ORDER BY name, report_id
+	`, companyID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list custom reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.CustomReport{}
+	for rows.Next() {
+		report, err := scanCustomReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom report: %w", err)
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"erp-backend/internal/models"
+)
+
+func TestCompileCustomReportParameterizesEverything(t *testing.T) {
+	loc := 7
+	def := models.CustomReportDefinition{
+		Model:      "sales",
+		Dimensions: []string{"brand", "weekday"},
+		Measures:   []string{"revenue", "margin"},
+		Filters: []models.CustomReportFilter{
+			{Field: "category", Op: "in", Values: []string{"Drinks", "Snacks'; DROP TABLE sales; --"}},
+			{Field: "customer", Op: "contains", Values: []string{"50%_off"}},
+			{Field: "product_id", Op: "eq", Values: []string{"42"}},
+		},
+		Sort:     []models.CustomReportSort{{Field: "revenue", Direction: "desc"}},
+		FromDate: "2026-01-01",
+		ToDate:   "2026-01-31",
+	}
+	c, err := compileCustomReport(def, customReportScope{CompanyID: 3, LocationIDs: []int{loc}}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(c.sql, "DROP TABLE") || strings.Contains(c.sql, "50%") {
+		t.Fatalf("user values leaked into SQL:\n%s", c.sql)
+	}
+	for _, want := range []string{
+		"l.company_id = $1",
+		"s.location_id = ANY($2)",
+		"s.sale_date >= $3::date",
+		"s.sale_date <= $4::date",
+		"COALESCE(c.name, 'Uncategorized') = ANY($5::text[])",
+		"ILIKE '%' || $6 || '%'",
+		"sd.product_id = $7::int",
+		"LEFT JOIN products p",
+		"LEFT JOIN categories c",
+		"LEFT JOIN brands b",
+		"LEFT JOIN customers cu",
+		"ORDER BY COALESCE(SUM(sd.line_total), 0)::float8 DESC",
+		"LIMIT $8",
+	} {
+		if !strings.Contains(c.sql, want) {
+			t.Fatalf("expected %q in SQL:\n%s", want, c.sql)
+		}
+	}
+	if strings.Contains(c.sql, "LEFT JOIN users u") {
+		t.Fatalf("unused join included:\n%s", c.sql)
+	}
+	if len(c.args) != 8 || c.args[0] != 3 || !reflect.DeepEqual(c.args[1], pq.Array([]int{loc})) || c.args[5] != `50\%\_off` || c.args[7] != customReportDefaultLimit+1 {
+		t.Fatalf("unexpected args %#v", c.args)
+	}
+	if _, ok := c.args[4].(*pq.StringArray); !ok {
+		t.Fatalf("expected array argument, got %T", c.args[4])
+	}
+}
+
+func TestCompileCustomReportRejectsUnknownFields(t *testing.T) {
+	cases := []models.CustomReportDefinition{
+		{Model: "users", Measures: []string{"qty"}},
+		{Model: "sales", Measures: []string{"SUM(1); --"}},
+		{Model: "sales", Dimensions: []string{"s.sale_id"}, Measures: []string{"qty"}},
+		{Model: "sales", Measures: []string{"qty"}, Filters: []models.CustomReportFilter{{Field: "1=1 OR x", Op: "eq", Values: []string{"a"}}}},
+		{Model: "sales", Measures: []string{"qty"}, Filters: []models.CustomReportFilter{{Field: "date", Op: "eq", Values: []string{"yesterday"}}}},
+		{Model: "sales", Measures: []string{"qty"}, Filters: []models.CustomReportFilter{{Field: "product_id", Op: "eq", Values: []string{"1 OR 1=1"}}}},
+		{Model: "sales", Measures: []string{"qty"}, Sort: []models.CustomReportSort{{Field: "brand"}}},
+		{Model: "sales", Dimensions: []string{"brand"}, Measures: []string{"qty"}, Pivot: &models.CustomReportPivot{Dimension: "brand"}},
+		{Model: "purchases", Dimensions: []string{"cashier"}, Measures: []string{"cost"}},
+	}
+	for i, def := range cases {
+		if _, err := compileCustomReport(def, customReportScope{CompanyID: 1}, time.Now()); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestCompileCustomReportDeniesLedgerToLocationUsers(t *testing.T) {
+	loc := 2
+	def := models.CustomReportDefinition{Model: "ledger", Dimensions: []string{"account"}, Measures: []string{"net"}}
+	if _, err := compileCustomReport(def, customReportScope{CompanyID: 1, LocationIDs: []int{loc}}, time.Now()); err == nil {
+		t.Fatalf("expected location-restricted ledger query to fail")
+	}
+	if _, err := compileCustomReport(def, customReportScope{CompanyID: 1}, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunCustomReportPivotsAndTruncates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	def := models.CustomReportDefinition{
+		Model:      "sales",
+		Dimensions: []string{"brand", "weekday"},
+		Measures:   []string{"revenue"},
+		Pivot:      &models.CustomReportPivot{Dimension: "weekday"},
+		Limit:      3,
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sale_details sd")).
+		WillReturnRows(sqlmock.NewRows([]string{"brand", "weekday", "revenue"}).
+			AddRow("Acme", "1 Monday", 10.0).
+			AddRow("Acme", "2 Tuesday", 5.0).
+			AddRow("Zen", "2 Tuesday", 7.5).
+			AddRow("Zen", "3 Wednesday", 1.0))
+
+	result, err := runCustomReport(db, "By weekday", def, customReportScope{CompanyID: 1}, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Truncated {
+		t.Fatalf("expected truncation at limit")
+	}
+	var labels []string
+	for _, col := range result.Columns {
+		labels = append(labels, col.Label)
+	}
+	if strings.Join(labels, ",") != "Brand,1 Monday,2 Tuesday" {
+		t.Fatalf("unexpected columns %v", labels)
+	}
+	if len(result.Rows) != 2 {
+		t.Fatalf("expected 2 pivoted rows, got %d", len(result.Rows))
+	}
+	zen := result.Rows[1]
+	if zen["brand"] != "Zen" || zen["revenue_0"] != float64(0) || zen["revenue_1"] != 7.5 {
+		t.Fatalf("unexpected pivoted row %v", zen)
+	}
+
+	table := CustomReportTable(result)
+	if table.Title != "By weekday" || len(table.Columns) != 3 || table.Columns[1].Format != "money" {
+		t.Fatalf("unexpected table %+v", table)
+	}
+}
+
+func TestCustomReportScopeRestrictsLocationUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM user_locations ul")).
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"location_ids"}).AddRow("{4,6}"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+		WithArgs(9, 5, customReportAllLocationsPermission).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	scope, err := (&CustomReportService{db: db}).reportScope(1, 9, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scope.LocationIDs) != 2 || scope.LocationIDs[0] != 4 || scope.LocationIDs[1] != 6 {
+		t.Fatalf("expected scope limited to locations 4 and 6, got %+v", scope)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestScopeReportLocations(t *testing.T) {
+	nine := 9
+	if got, err := scopeReportLocations(nil, &nine); err != nil || !reflect.DeepEqual(got, []int{9}) {
+		t.Fatalf("unrestricted users keep their filter, got %v %v", got, err)
+	}
+	if got, err := scopeReportLocations(nil, nil); err != nil || got != nil {
+		t.Fatalf("unrestricted users see every location, got %v %v", got, err)
+	}
+	if _, err := scopeReportLocations([]int{4, 6}, &nine); err == nil || err.Error() != "location access denied" {
+		t.Fatalf("expected location access denied, got %v", err)
+	}
+	if got, _ := scopeReportLocations([]int{4, 6}, nil); !reflect.DeepEqual(got, []int{4, 6}) {
+		t.Fatalf("expected all permitted locations, got %v", got)
+	}
+	six := 6
+	if got, _ := scopeReportLocations([]int{4, 6}, &six); !reflect.DeepEqual(got, []int{6}) {
+		t.Fatalf("expected requested location 6, got %v", got)
+	}
+}
@@ -37,7 +37,7 @@ func normalizeCustomerType(raw *string) string {
 func (s *CustomerService) GetCustomers(companyID int, filters map[string]string) ([]models.Customer, error) {
 	query := `
                 SELECT c.customer_id, c.company_id, c.name, c.customer_type, c.contact_person, c.phone, c.email, c.address, c.shipping_address, c.tax_number,
-                       c.credit_limit, c.payment_terms, c.is_loyalty, c.loyalty_tier_id, c.is_active, c.created_by, c.updated_by,
+                       c.credit_limit, c.payment_terms, c.is_loyalty, c.loyalty_tier_id, c.is_active, c.dunning_level, c.dunning_credit_blocked, c.created_by, c.updated_by,
                        c.sync_status, c.created_at, c.updated_at, c.is_deleted,
                        COALESCE(SUM(s.total_amount - s.paid_amount),0) AS credit_balance
                 FROM customers c
@@ -125,7 +125,7 @@ func (s *CustomerService) GetCustomers(companyID int, filters map[string]string)
 		var c models.Customer
 		if err := rows.Scan(
 			&c.CustomerID, &c.CompanyID, &c.Name, &c.CustomerType, &c.ContactPerson, &c.Phone, &c.Email, &c.Address, &c.ShippingAddress,
-			&c.TaxNumber, &c.CreditLimit, &c.PaymentTerms, &c.IsLoyalty, &c.LoyaltyTierID, &c.IsActive,
+			&c.TaxNumber, &c.CreditLimit, &c.PaymentTerms, &c.IsLoyalty, &c.LoyaltyTierID, &c.IsActive, &c.DunningLevel, &c.DunningCreditBlocked,
 			&c.CreatedBy, &c.UpdatedBy, &c.SyncStatus, &c.CreatedAt, &c.UpdatedAt, &c.IsDeleted,
 			&c.CreditBalance,
 		); err != nil {
@@ -141,7 +141,7 @@ func (s *CustomerService) GetCustomers(companyID int, filters map[string]string)
 func (s *CustomerService) GetCustomerByID(customerID, companyID int) (*models.Customer, error) {
 	query := `
                SELECT c.customer_id, c.company_id, c.name, c.customer_type, c.contact_person, c.phone, c.email, c.address, c.shipping_address, c.tax_number,
-                      c.credit_limit, c.payment_terms, c.is_loyalty, c.loyalty_tier_id, c.is_active, c.created_by, c.updated_by,
+                      c.credit_limit, c.payment_terms, c.is_loyalty, c.loyalty_tier_id, c.is_active, c.dunning_level, c.dunning_credit_blocked, c.created_by, c.updated_by,
                       c.sync_status, c.created_at, c.updated_at, c.is_deleted,
                       COALESCE(SUM(s.total_amount - s.paid_amount),0) AS credit_balance
                FROM customers c
@@ -152,7 +152,7 @@ func (s *CustomerService) GetCustomerByID(customerID, companyID int) (*models.Cu
 	var c models.Customer
 	err := s.db.QueryRow(query, customerID, companyID).Scan(
 		&c.CustomerID, &c.CompanyID, &c.Name, &c.CustomerType, &c.ContactPerson, &c.Phone, &c.Email, &c.Address, &c.ShippingAddress,
-		&c.TaxNumber, &c.CreditLimit, &c.PaymentTerms, &c.IsLoyalty, &c.LoyaltyTierID, &c.IsActive,
+		&c.TaxNumber, &c.CreditLimit, &c.PaymentTerms, &c.IsLoyalty, &c.LoyaltyTierID, &c.IsActive, &c.DunningLevel, &c.DunningCreditBlocked,
 		&c.CreatedBy, &c.UpdatedBy, &c.SyncStatus, &c.CreatedAt, &c.UpdatedAt, &c.IsDeleted,
 		&c.CreditBalance,
 	)
@@ -179,8 +179,14 @@ func (s *CustomerService) CreateCustomer(companyID, userID int, req *models.Crea
                 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$14)
                 RETURNING customer_id, created_at, updated_at`
 
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	var c models.Customer
-	err := s.db.QueryRow(query,
+	err = tx.QueryRow(query,
 		companyID, req.Name, customerType, req.ContactPerson, req.Phone, req.Email, req.Address, req.ShippingAddress, req.TaxNumber,
 		req.CreditLimit, req.PaymentTerms, req.IsLoyalty, req.LoyaltyTierID, userID,
 	).Scan(&c.CustomerID, &c.CreatedAt, &c.UpdatedAt)
@@ -207,6 +213,21 @@ func (s *CustomerService) CreateCustomer(companyID, userID int, req *models.Crea
 	c.SyncStatus = "synced"
 	c.IsDeleted = false
 
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventCustomerCreated, "customer", c.CustomerID, models.JSONB{
+		"customer_id":   c.CustomerID,
+		"name":          c.Name,
+		"customer_type": c.CustomerType,
+		"phone":         c.Phone,
+		"email":         c.Email,
+		"tax_number":    c.TaxNumber,
+		"credit_limit":  c.CreditLimit,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &c, nil
 }
 
@@ -292,6 +313,11 @@ func (s *CustomerService) UpdateCustomer(customerID, companyID, userID int, req
 		return s.GetCustomerByID(customerID, companyID)
 	}
 
+	changedFields := make([]string, 0, len(updates))
+	for _, u := range updates {
+		changedFields = append(changedFields, strings.SplitN(u, " ", 2)[0])
+	}
+
 	updates = append(updates, fmt.Sprintf("updated_at = $%d", argCount))
 	args = append(args, time.Now())
 	argCount++
@@ -299,21 +325,34 @@ func (s *CustomerService) UpdateCustomer(customerID, companyID, userID int, req
 	args = append(args, userID)
 	argCount++
 
-	query := fmt.Sprintf("UPDATE customers SET %s WHERE customer_id = $%d AND company_id = $%d AND is_deleted = FALSE",
+	query := fmt.Sprintf("UPDATE customers SET %s WHERE customer_id = $%d AND company_id = $%d AND is_deleted = FALSE RETURNING name",
 		strings.Join(updates, ", "), argCount, argCount+1)
 	args = append(args, customerID, companyID)
 
-	result, err := s.db.Exec(query, args...)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("failed to update customer: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var name string
+	err = tx.QueryRow(query, args...).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("customer not found")
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("failed to update customer: %w", err)
 	}
-	if rows == 0 {
-		return nil, fmt.Errorf("customer not found")
+
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventCustomerUpdated, "customer", customerID, models.JSONB{
+		"customer_id":    customerID,
+		"name":           name,
+		"changed_fields": changedFields,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return s.GetCustomerByID(customerID, companyID)
@@ -321,23 +360,33 @@ func (s *CustomerService) UpdateCustomer(customerID, companyID, userID int, req
 
 // DeleteCustomer marks customer as deleted
 func (s *CustomerService) DeleteCustomer(customerID, companyID, userID int) error {
-	result, err := s.db.Exec(
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRow(
 		`UPDATE customers SET is_deleted = TRUE, updated_at = CURRENT_TIMESTAMP, updated_by = $3
-                  WHERE customer_id = $1 AND company_id = $2 AND is_deleted = FALSE`,
+                  WHERE customer_id = $1 AND company_id = $2 AND is_deleted = FALSE
+                  RETURNING name`,
 		customerID, companyID, userID,
-	)
+	).Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("customer not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete customer: %w", err)
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rows == 0 {
-		return fmt.Errorf("customer not found")
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventCustomerDeleted, "customer", customerID, models.JSONB{
+		"customer_id": customerID,
+		"name":        name,
+	}); err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
 // GetCustomerSummary aggregates sales, payments, returns and loyalty for a customer
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// CustomerStatementService builds statements of account for customers and
+// delivers them as PDF by email.
+type CustomerStatementService struct {
+	db *sql.DB
+}
+
+type statementMovement struct {
+	Date        time.Time
+	EntryType   string
+	Reference   string
+	Description string
+	SaleID      *int
+	Amount      float64
+}
+
+func NewCustomerStatementService() *CustomerStatementService {
+	return &CustomerStatementService{db: database.GetDB()}
+}
+
+// statementPeriod defaults to the month to date when no dates are given.
+func statementPeriod(from, to *string) (time.Time, time.Time, error) {
+	toDate, err := parseAPDate(to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	fromDate := time.Date(toDate.Year(), toDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if from != nil && strings.TrimSpace(*from) != "" {
+		if fromDate, err = parseAPDate(from); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if fromDate.After(toDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from_date must not be after to_date")
+	}
+	return fromDate, toDate, nil
+}
+
+// buildStatementEntries folds movements before fromDate into the opening
+// balance and lists the rest with a running balance. Movements must be sorted
+// by date.
+func buildStatementEntries(movements []statementMovement, fromDate time.Time) (float64, []models.CustomerStatementEntry, float64, float64) {
+	opening := 0.0
+	entries := make([]models.CustomerStatementEntry, 0, len(movements))
+	var debits, credits float64
+	balance := 0.0
+	for _, m := range movements {
+		if m.Date.Before(fromDate) {
+			opening = round2(opening + m.Amount)
+			balance = opening
+			continue
+		}
+		entry := models.CustomerStatementEntry{
+			Date:        m.Date,
+			EntryType:   m.EntryType,
+			Reference:   m.Reference,
+			Description: m.Description,
+			SaleID:      m.SaleID,
+		}
+		if m.Amount >= 0 {
+			entry.Debit = round2(m.Amount)
+			debits = round2(debits + entry.Debit)
+		} else {
+			entry.Credit = round2(-m.Amount)
+			credits = round2(credits + entry.Credit)
+		}
+		balance = round2(balance + m.Amount)
+		entry.Balance = balance
+		entries = append(entries, entry)
+	}
+	return opening, entries, debits, credits
+}
+
+// GetStatement returns the statement of account for a customer over the
+// given period.
+func (s *CustomerStatementService) GetStatement(companyID, customerID int, from, to *string) (*models.CustomerStatement, error) {
+	fromDate, toDate, err := statementPeriod(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.CustomerStatement{CustomerID: customerID, FromDate: fromDate, ToDate: toDate}
+	var email, phone, address sql.NullString
+	if err := s.db.QueryRow(`
+		SELECT name, email, phone, address, COALESCE(payment_terms, 0)
+		FROM customers
+		WHERE customer_id = $1 AND company_id = $2 AND is_deleted = FALSE
+	`, customerID, companyID).Scan(&statement.CustomerName, &email, &phone, &address, &statement.PaymentTerms); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("customer not found")
+		}
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	statement.CustomerEmail = nullStringPtr(email)
+	statement.CustomerPhone = nullStringPtr(phone)
+	statement.Address = nullStringPtr(address)
+
+	movements, err := s.loadMovements(companyID, customerID, toDate)
+	if err != nil {
+		return nil, err
+	}
+	statement.OpeningBalance, statement.Entries, statement.TotalDebits, statement.TotalCredits = buildStatementEntries(movements, fromDate)
+	statement.ClosingBalance = round2(statement.OpeningBalance + statement.TotalDebits - statement.TotalCredits)
+
+	if err := s.loadAging(companyID, customerID, toDate, &statement.Aging); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+func (s *CustomerStatementService) loadMovements(companyID, customerID int, toDate time.Time) ([]statementMovement, error) {
+	rows, err := s.db.Query(`
+		WITH customer_sales AS (
+			SELECT s.sale_id, s.sale_number, s.sale_date, s.notes, s.total_amount, s.paid_amount
+			FROM sales s
+			JOIN locations l ON l.location_id = s.location_id
+			WHERE s.customer_id = $1 AND l.company_id = $2
+			  AND s.is_deleted = FALSE
+			  AND COALESCE(s.is_training, FALSE) = FALSE
+			  AND s.status IN ('COMPLETED', 'RETURNED')
+			  AND COALESCE(s.pos_status, 'COMPLETED') = 'COMPLETED'
+		)
+		SELECT entry_date, entry_type, reference, description, sale_id, amount
+		FROM (
+			SELECT cs.sale_date AS entry_date,
+			       CASE WHEN cs.total_amount < 0 THEN 'CREDIT_NOTE' ELSE 'INVOICE' END AS entry_type,
+			       cs.sale_number AS reference, COALESCE(cs.notes, '') AS description, cs.sale_id,
+			       cs.total_amount::float8 AS amount, 1 AS sort_order, cs.sale_id AS sort_id
+			FROM customer_sales cs
+
+			UNION ALL
+
+			-- Settled at the till rather than through a collection.
+			SELECT cs.sale_date, 'PAYMENT', cs.sale_number, 'Paid at sale', cs.sale_id,
+			       -(cs.paid_amount - COALESCE(ci.collected, 0))::float8, 2, cs.sale_id
+			FROM customer_sales cs
+			LEFT JOIN (
+				SELECT sale_id, SUM(amount) AS collected FROM collection_invoices GROUP BY sale_id
+			) ci ON ci.sale_id = cs.sale_id
+			WHERE ABS(cs.paid_amount - COALESCE(ci.collected, 0)) > 0.005
+
+			UNION ALL
+
+			SELECT sr.return_date, 'RETURN', sr.return_number, COALESCE(sr.reason, ''), sr.sale_id,
+			       -sr.total_amount::float8, 3, sr.return_id
+			FROM sale_returns sr
+			JOIN sales s ON s.sale_id = sr.sale_id
+			JOIN locations l ON l.location_id = sr.location_id
+			WHERE COALESCE(sr.customer_id, s.customer_id) = $1 AND l.company_id = $2
+			  AND sr.is_deleted = FALSE
+			  AND COALESCE(sr.status, 'COMPLETED') = 'COMPLETED'
+
+			UNION ALL
+
+			SELECT col.collection_date, 'COLLECTION', col.collection_number, COALESCE(col.reference_number, ''), NULL,
+			       -col.amount::float8, 4, col.collection_id
+			FROM collections col
+			JOIN locations l ON l.location_id = col.location_id
+			WHERE col.customer_id = $1 AND l.company_id = $2
+		) movements
+		WHERE entry_date <= $3
+		ORDER BY entry_date, sort_order, sort_id
+	`, customerID, companyID, toDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement movements: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []statementMovement
+	for rows.Next() {
+		var m statementMovement
+		var saleID sql.NullInt64
+		if err := rows.Scan(&m.Date, &m.EntryType, &m.Reference, &m.Description, &saleID, &m.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan statement movement: %w", err)
+		}
+		m.SaleID = intPtrFromNullInt64(saleID)
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+// loadAging buckets the customer's open invoices by days past due, where the
+// due date is the sale date plus the customer's payment terms.
+func (s *CustomerStatementService) loadAging(companyID, customerID int, asOf time.Time, buckets *models.APAgingBuckets) error {
+	rows, err := s.db.Query(`
+		SELECT ($3::date - (s.sale_date + COALESCE(c.payment_terms, 0))) AS days_overdue,
+		       (s.total_amount - s.paid_amount)::float8 AS outstanding
+		FROM sales s
+		JOIN customers c ON c.customer_id = s.customer_id
+		WHERE s.customer_id = $1 AND c.company_id = $2
+		  AND s.is_deleted = FALSE
+		  AND COALESCE(s.is_training, FALSE) = FALSE
+		  AND s.status = 'COMPLETED'
+		  AND s.sale_date <= $3
+		  AND (s.total_amount - s.paid_amount) > 0.005
+	`, customerID, companyID, asOf)
+	if err != nil {
+		return fmt.Errorf("failed to get customer aging: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var days int
+		var outstanding float64
+		if err := rows.Scan(&days, &outstanding); err != nil {
+			return fmt.Errorf("failed to scan customer aging: %w", err)
+		}
+		addToAgingBuckets(buckets, apAgingBucket(days), outstanding)
+	}
+	return rows.Err()
+}
+
+// RenderStatementPDF lays the statement out as a printable PDF.
+func (s *CustomerStatementService) RenderStatementPDF(companyID int, statement *models.CustomerStatement) ([]byte, error) {
+	companyName := ""
+	if err := s.db.QueryRow(`SELECT name FROM companies WHERE company_id = $1`, companyID).Scan(&companyName); err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get company: %w", err)
+	}
+	return utils.GenerateDocumentPDF(statementLines(companyName, statement)), nil
+}
+
+func statementLines(companyName string, st *models.CustomerStatement) []string {
+	const dateFmt = "2006-01-02"
+	lines := []string{}
+	if companyName != "" {
+		lines = append(lines, companyName)
+	}
+	lines = append(lines,
+		"STATEMENT OF ACCOUNT",
+		"",
+		"Customer: "+st.CustomerName,
+	)
+	if st.Address != nil && *st.Address != "" {
+		lines = append(lines, "Address:  "+*st.Address)
+	}
+	lines = append(lines,
+		fmt.Sprintf("Period:   %s to %s", st.FromDate.Format(dateFmt), st.ToDate.Format(dateFmt)),
+		"",
+		fmt.Sprintf("%-10s  %-11s  %-18s  %12s  %12s  %12s", "Date", "Type", "Reference", "Debit", "Credit", "Balance"),
+		strings.Repeat("-", 84),
+		fmt.Sprintf("%-10s  %-11s  %-18s  %12s  %12s  %12.2f", st.FromDate.Format(dateFmt), "", "Opening balance", "", "", st.OpeningBalance),
+	)
+	amount := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", v)
+	}
+	for _, e := range st.Entries {
+		reference := e.Reference
+		if len(reference) > 18 {
+			reference = reference[:18]
+		}
+		lines = append(lines, fmt.Sprintf("%-10s  %-11s  %-18s  %12s  %12s  %12.2f",
+			e.Date.Format(dateFmt), e.EntryType, reference, amount(e.Debit), amount(e.Credit), e.Balance))
+	}
+	lines = append(lines,
+		strings.Repeat("-", 84),
+		fmt.Sprintf("%-43s  %12.2f  %12.2f  %12.2f", "Totals", st.TotalDebits, st.TotalCredits, st.ClosingBalance),
+		"",
+		"Aging of open invoices",
+		fmt.Sprintf("%12s  %12s  %12s  %12s  %12s  %12s", "Current", "1-30", "31-60", "61-90", "Over 90", "Total"),
+		fmt.Sprintf("%12.2f  %12.2f  %12.2f  %12.2f  %12.2f  %12.2f",
+			st.Aging.Current, st.Aging.Days1To30, st.Aging.Days31To60, st.Aging.Days61To90, st.Aging.Over90, st.Aging.Total),
+	)
+	return lines
+}
+
+// EmailStatement sends the statement PDF to the customer's email address, or
+// to the override address when one is given.
+func (s *CustomerStatementService) EmailStatement(companyID, customerID int, req *models.EmailCustomerStatementRequest) (*models.CustomerStatementDelivery, error) {
+	statement, err := s.GetStatement(companyID, customerID, req.FromDate, req.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	to := statement.CustomerEmail
+	if req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+		to = req.Email
+	}
+	delivery := s.deliverStatement(companyID, statement, to, req.Message)
+	if delivery.Status != models.StatementDeliverySent {
+		return delivery, fmt.Errorf("%s", *delivery.Error)
+	}
+	return delivery, nil
+}
+
+// EmailStatements sends statements to many customers; failures are reported
+// per customer rather than aborting the batch.
+func (s *CustomerStatementService) EmailStatements(companyID int, req *models.BulkEmailCustomerStatementsRequest) ([]models.CustomerStatementDelivery, error) {
+	customerIDs := req.CustomerIDs
+	if customerIDs == nil {
+		customerIDs = []int{}
+	}
+	rows, err := s.db.Query(`
+		SELECT customer_id
+		FROM customers
+		WHERE company_id = $1 AND is_deleted = FALSE AND is_active = TRUE
+		  AND (cardinality($2::int[]) = 0 OR customer_id = ANY($2))
+		ORDER BY name
+	`, companyID, pq.Array(customerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customers: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan customer: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	deliveries := make([]models.CustomerStatementDelivery, 0, len(ids))
+	for _, id := range ids {
+		statement, err := s.GetStatement(companyID, id, req.FromDate, req.ToDate)
+		if err != nil {
+			return nil, err
+		}
+		if req.OnlyWithBalance && statement.ClosingBalance == 0 {
+			continue
+		}
+		deliveries = append(deliveries, *s.deliverStatement(companyID, statement, statement.CustomerEmail, req.Message))
+	}
+	return deliveries, nil
+}
+
+func (s *CustomerStatementService) deliverStatement(companyID int, statement *models.CustomerStatement, to, message *string) *models.CustomerStatementDelivery {
+	delivery := &models.CustomerStatementDelivery{
+		CustomerID:     statement.CustomerID,
+		CustomerName:   statement.CustomerName,
+		Email:          to,
+		ClosingBalance: statement.ClosingBalance,
+		Status:         models.StatementDeliverySent,
+	}
+	fail := func(err error) *models.CustomerStatementDelivery {
+		msg := err.Error()
+		delivery.Status = models.StatementDeliveryFailed
+		delivery.Error = &msg
+		return delivery
+	}
+	if to == nil || strings.TrimSpace(*to) == "" {
+		return fail(fmt.Errorf("customer has no email address"))
+	}
+	pdf, err := s.RenderStatementPDF(companyID, statement)
+	if err != nil {
+		return fail(err)
+	}
+
+	body := fmt.Sprintf("Dear %s,\n\nPlease find attached your statement of account for %s to %s.\nBalance due: %.2f\n",
+		statement.CustomerName, statement.FromDate.Format("2006-01-02"), statement.ToDate.Format("2006-01-02"), statement.ClosingBalance)
+	if message != nil && strings.TrimSpace(*message) != "" {
+		body += "\n" + strings.TrimSpace(*message) + "\n"
+	}
+	filename := fmt.Sprintf("statement-%d-%s.pdf", statement.CustomerID, statement.ToDate.Format("20060102"))
+	if err := utils.SendEmailWithAttachments(strings.TrimSpace(*to), "Statement of account", body, []utils.EmailAttachment{{
+		Filename:    filename,
+		ContentType: "application/pdf",
+		Content:     pdf,
+	}}); err != nil {
+		return fail(err)
+	}
+	return delivery
+}
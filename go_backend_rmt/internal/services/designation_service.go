@@ -73,7 +73,7 @@ func (s *DesignationService) Create(companyID, userID int, req *models.CreateDes
 
 	if req.DefaultAppRoleID != nil {
 		var roleOk bool
-		if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE role_id = $1)`, *req.DefaultAppRoleID).Scan(&roleOk); err != nil {
+		if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE role_id = $1 AND company_id = $2)`, *req.DefaultAppRoleID, companyID).Scan(&roleOk); err != nil {
 			return nil, fmt.Errorf("failed to verify default app role: %w", err)
 		}
 		if !roleOk {
@@ -129,7 +129,7 @@ func (s *DesignationService) Update(companyID, designationID, userID int, req *m
 			set = append(set, "default_app_role_id = NULL")
 		} else {
 			var roleOk bool
-			if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE role_id = $1)`, *req.DefaultAppRoleID).Scan(&roleOk); err != nil {
+			if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE role_id = $1 AND company_id = $2)`, *req.DefaultAppRoleID, companyID).Scan(&roleOk); err != nil {
 				return fmt.Errorf("failed to verify default app role: %w", err)
 			}
 			if !roleOk {
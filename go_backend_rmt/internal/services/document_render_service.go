@@ -0,0 +1,755 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// DocumentRenderService renders sales, quotes, returns, purchases, payslips
+// and warranty cards through the company's invoice templates so every client
+// and every email gets the same copy.
+type DocumentRenderService struct {
+	db *sql.DB
+}
+
+func NewDocumentRenderService() *DocumentRenderService {
+	return &DocumentRenderService{db: database.GetDB()}
+}
+
+// documentTemplateTypes lists the template types tried for a document, in
+// order of preference.
+var documentTemplateTypes = map[string][]string{
+	models.DocumentTypeSale:       {"INVOICE", "RECEIPT"},
+	models.DocumentTypeQuote:      {"QUOTE"},
+	models.DocumentTypeSaleReturn: {"SALE_RETURN"},
+	models.DocumentTypePurchase:   {"PURCHASE"},
+	models.DocumentTypePayslip:    {"PAYSLIP"},
+	models.DocumentTypeWarranty:   {"WARRANTY"},
+}
+
+// templateDocumentTypes maps a template type back to the document it prints.
+var templateDocumentTypes = map[string]string{
+	"INVOICE":     models.DocumentTypeSale,
+	"RECEIPT":     models.DocumentTypeSale,
+	"QUOTE":       models.DocumentTypeQuote,
+	"SALE_RETURN": models.DocumentTypeSaleReturn,
+	"PURCHASE":    models.DocumentTypePurchase,
+	"PAYSLIP":     models.DocumentTypePayslip,
+	"WARRANTY":    models.DocumentTypeWarranty,
+}
+
+var documentFilenamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// RenderDocument renders one document. Without a template ID the company's
+// default active template for the document type is used, falling back to the
+// built-in layout.
+func (s *DocumentRenderService) RenderDocument(companyID int, docType string, docID int, templateID *int, format string) (*models.RenderedDocument, error) {
+	data, templateTypes, err := s.loadDocument(companyID, docType, docID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.resolveTemplate(companyID, templateTypes, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(companyID, template, data, format)
+}
+
+// PreviewTemplate renders sample data through a saved or unsaved template.
+func (s *DocumentRenderService) PreviewTemplate(companyID int, req *models.PreviewInvoiceTemplateRequest) (*models.RenderedDocument, error) {
+	template := &models.InvoiceTemplate{TemplateType: "INVOICE"}
+	if req.TemplateID != nil {
+		saved, err := (&InvoiceTemplateService{db: s.db}).GetInvoiceTemplateByID(*req.TemplateID, companyID)
+		if err != nil {
+			return nil, err
+		}
+		template = saved
+	}
+	if req.TemplateType != nil {
+		template.TemplateType = *req.TemplateType
+	}
+	if req.Layout != nil {
+		template.Layout = req.Layout
+	}
+	if req.PrimaryLanguage != nil {
+		template.PrimaryLanguage = req.PrimaryLanguage
+	}
+	if req.SecondaryLanguage != nil {
+		template.SecondaryLanguage = req.SecondaryLanguage
+	}
+	docType, ok := templateDocumentTypes[template.TemplateType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported template type %s", template.TemplateType)
+	}
+	return s.render(companyID, template, sampleDocumentData(docType), req.Format)
+}
+
+// EmailDocument sends the rendered PDF to the given address or to the
+// customer, supplier or employee on the document.
+func (s *DocumentRenderService) EmailDocument(companyID int, docType string, docID int, req *models.EmailDocumentRequest) (*models.EmailDocumentResponse, error) {
+	data, templateTypes, err := s.loadDocument(companyID, docType, docID)
+	if err != nil {
+		return nil, err
+	}
+	to := ""
+	if data.Party != nil {
+		to = data.Party.Email
+	}
+	if req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+		to = strings.TrimSpace(*req.Email)
+	}
+	if to == "" {
+		return nil, fmt.Errorf("no email address available for this document")
+	}
+	template, err := s.resolveTemplate(companyID, templateTypes, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := s.render(companyID, template, data, models.DocumentFormatPDF)
+	if err != nil {
+		return nil, err
+	}
+
+	title := firstNonEmpty(defaultDocumentLabels[documentTitleKey(docType)], "Document")
+	recipient := "customer"
+	if data.Party != nil && data.Party.Name != "" {
+		recipient = data.Party.Name
+	}
+	body := fmt.Sprintf("Dear %s,\n\nPlease find attached %s %s from %s.\n", recipient, strings.ToLower(title), data.Number, data.Company.Name)
+	if req.Message != nil && strings.TrimSpace(*req.Message) != "" {
+		body += "\n" + strings.TrimSpace(*req.Message) + "\n"
+	}
+	subject := fmt.Sprintf("%s %s - %s", title, data.Number, data.Company.Name)
+	if err := utils.SendEmailWithAttachments(to, subject, body, []utils.EmailAttachment{{
+		Filename:    rendered.Filename,
+		ContentType: rendered.ContentType,
+		Content:     rendered.Content,
+	}}); err != nil {
+		return nil, fmt.Errorf("failed to send email: %w", err)
+	}
+	return &models.EmailDocumentResponse{Email: to, Filename: rendered.Filename}, nil
+}
+
+// resolveTemplate returns the requested template, the best default for the
+// document, or nil for the built-in layout.
+func (s *DocumentRenderService) resolveTemplate(companyID int, templateTypes []string, templateID *int) (*models.InvoiceTemplate, error) {
+	if templateID != nil {
+		return (&InvoiceTemplateService{db: s.db}).GetInvoiceTemplateByID(*templateID, companyID)
+	}
+	var t models.InvoiceTemplate
+	err := s.db.QueryRow(`
+		SELECT template_id, company_id, name, template_type, layout, primary_language, secondary_language, is_default, is_active, created_at
+		FROM invoice_templates
+		WHERE company_id = $1 AND is_active = TRUE AND template_type = ANY($2)
+		ORDER BY array_position($2::varchar[], template_type::varchar), is_default DESC, template_id
+		LIMIT 1
+	`, companyID, pq.Array(templateTypes)).Scan(&t.TemplateID, &t.CompanyID, &t.Name, &t.TemplateType, &t.Layout, &t.PrimaryLanguage, &t.SecondaryLanguage, &t.IsDefault, &t.IsActive, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice template: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *DocumentRenderService) render(companyID int, template *models.InvoiceTemplate, data *documentData, format string) (*models.RenderedDocument, error) {
+	layout := &documentLayout{}
+	primaryLanguage, secondaryLanguage := "en", ""
+	if template != nil {
+		parsed, err := parseDocumentLayout(template.Layout)
+		if err != nil {
+			return nil, err
+		}
+		layout = parsed
+		if template.PrimaryLanguage != nil && *template.PrimaryLanguage != "" {
+			primaryLanguage = *template.PrimaryLanguage
+		}
+		if template.SecondaryLanguage != nil && *template.SecondaryLanguage != primaryLanguage {
+			secondaryLanguage = *template.SecondaryLanguage
+		}
+	}
+
+	translations := &TranslationService{db: s.db}
+	labels := documentLabels{overrides: layout.Labels, showSecondary: secondaryLanguage != ""}
+	if layout.ShowSecondaryLanguage != nil {
+		labels.showSecondary = labels.showSecondary && *layout.ShowSecondaryLanguage
+	}
+	var err error
+	if labels.primary, err = translations.GetTranslations(primaryLanguage); err != nil {
+		return nil, err
+	}
+	if labels.showSecondary {
+		if labels.secondary, err = translations.GetTranslations(secondaryLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.applyCompany(companyID, data); err != nil {
+		return nil, err
+	}
+
+	base := documentFilenamePattern.ReplaceAllString(strings.ToLower(strings.ReplaceAll(data.Type, "_", "-"))+"-"+data.Number, "_")
+	switch strings.ToLower(format) {
+	case "", models.DocumentFormatHTML:
+		return &models.RenderedDocument{
+			ContentType: "text/html; charset=utf-8",
+			Filename:    base + ".html",
+			Content:     renderDocumentHTML(layout, data, labels, primaryLanguage),
+		}, nil
+	case models.DocumentFormatPDF:
+		return &models.RenderedDocument{
+			ContentType: "application/pdf",
+			Filename:    base + ".pdf",
+			Content:     renderDocumentPDF(layout, data, labels),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+// applyCompany fills the letterhead: company details, currency and logo.
+func (s *DocumentRenderService) applyCompany(companyID int, data *documentData) error {
+	company, err := (&CompanyService{db: s.db}).GetCompanyByID(companyID)
+	if err != nil {
+		return err
+	}
+	data.Company = documentParty{
+		Name:      company.Name,
+		Address:   ptrString(company.Address),
+		Phone:     ptrString(company.Phone),
+		Email:     ptrString(company.Email),
+		TaxNumber: ptrString(company.TaxNumber),
+	}
+
+	err = s.db.QueryRow(`
+		SELECT code, name FROM currencies
+		WHERE currency_id = COALESCE($1, (SELECT currency_id FROM currencies WHERE is_base_currency = TRUE ORDER BY currency_id LIMIT 1))
+	`, company.CurrencyID).Scan(&data.CurrencyCode, &data.CurrencyName)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get company currency: %w", err)
+	}
+
+	if company.Logo != nil && *company.Logo != "" {
+		data.LogoDataURI, data.Logo = loadDocumentLogo(*company.Logo)
+	}
+	return nil
+}
+
+// loadDocumentLogo inlines uploaded logos for HTML and decodes them for PDF.
+// Remote logos are linked from HTML only.
+func loadDocumentLogo(logo string) (string, image.Image) {
+	key, ok := UploadObjectKey(logo)
+	if !ok {
+		if strings.HasPrefix(logo, "http://") || strings.HasPrefix(logo, "https://") {
+			return logo, nil
+		}
+		return "", nil
+	}
+	content, err := readStoredObject(key)
+	if err != nil {
+		log.Printf("document_render_service: failed to read logo %s: %v", logo, err)
+		return "", nil
+	}
+	dataURI := "data:" + http.DetectContentType(content) + ";base64," + base64.StdEncoding.EncodeToString(content)
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		// SVG and other formats still show in HTML.
+		return dataURI, nil
+	}
+	return dataURI, img
+}
+
+func readStoredObject(key string) ([]byte, error) {
+	store, err := GetObjectStorage()
+	if err != nil {
+		return nil, err
+	}
+	rc, _, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (s *DocumentRenderService) loadDocument(companyID int, docType string, docID int) (*documentData, []string, error) {
+	var (
+		data *documentData
+		err  error
+	)
+	templateTypes := documentTemplateTypes[docType]
+	switch docType {
+	case models.DocumentTypeSale:
+		var sale *models.Sale
+		sale, err = (&SalesService{db: s.db}).GetSaleByID(docID, companyID)
+		if err == nil {
+			data, err = s.saleDocument(companyID, sale)
+			if sale.SourceChannel != nil && *sale.SourceChannel == "POS" {
+				templateTypes = []string{"RECEIPT", "INVOICE"}
+			}
+		}
+	case models.DocumentTypeQuote:
+		var quote *models.Quote
+		quote, err = (&SalesService{db: s.db}).GetQuoteByID(docID, companyID)
+		if err == nil {
+			data, err = s.quoteDocument(companyID, quote)
+		}
+	case models.DocumentTypeSaleReturn:
+		var ret *models.SaleReturn
+		ret, err = (&ReturnsService{db: s.db}).GetSaleReturnByID(docID, companyID)
+		if err == nil {
+			data, err = s.saleReturnDocument(companyID, ret)
+		}
+	case models.DocumentTypePurchase:
+		var purchase *models.PurchaseWithDetails
+		purchase, err = (&PurchaseService{db: s.db}).GetPurchaseByID(docID, companyID)
+		if err == nil {
+			data, err = s.purchaseDocument(companyID, purchase)
+		}
+	case models.DocumentTypePayslip:
+		var payslip *models.Payslip
+		payslip, err = (&PayrollService{db: s.db}).GeneratePayslip(docID, companyID)
+		if err == nil {
+			data, err = s.payslipDocument(companyID, payslip)
+		}
+	case models.DocumentTypeWarranty:
+		var warranty *models.WarrantyRegistration
+		warranty, err = (&WarrantyService{db: s.db}).GetWarrantyByID(companyID, docID)
+		if err == nil {
+			data = warrantyDocument(warranty)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported document type %s", docType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, templateTypes, nil
+}
+
+func (s *DocumentRenderService) customerParty(companyID int, customerID *int) (*documentParty, error) {
+	if customerID == nil {
+		return nil, nil
+	}
+	var name string
+	var address, phone, email, taxNumber sql.NullString
+	err := s.db.QueryRow(`
+		SELECT name, address, phone, email, tax_number FROM customers
+		WHERE customer_id = $1 AND company_id = $2
+	`, *customerID, companyID).Scan(&name, &address, &phone, &email, &taxNumber)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+	return &documentParty{Name: name, Address: address.String, Phone: phone.String, Email: email.String, TaxNumber: taxNumber.String}, nil
+}
+
+// taxLinesByTax groups item tax amounts by tax for documents that do not
+// store a breakdown of their own.
+func (s *DocumentRenderService) taxLinesByTax(companyID int, amounts map[int]float64) ([]documentTaxLine, error) {
+	if len(amounts) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, 0, len(amounts))
+	for id := range amounts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	rows, err := s.db.Query(`SELECT tax_id, name, percentage FROM taxes WHERE company_id = $1 AND tax_id = ANY($2) ORDER BY tax_id`, companyID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get taxes: %w", err)
+	}
+	defer rows.Close()
+	var lines []documentTaxLine
+	for rows.Next() {
+		var id int
+		var line documentTaxLine
+		if err := rows.Scan(&id, &line.Name, &line.Rate); err != nil {
+			return nil, fmt.Errorf("failed to scan tax: %w", err)
+		}
+		line.Amount = round2(amounts[id])
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func salesTotals(subtotal, discount, tax, total, paid float64, showPaid bool) []documentTotal {
+	totals := []documentTotal{{Key: "subtotal", Amount: subtotal}}
+	if discount > 0 {
+		totals = append(totals, documentTotal{Key: "discount_total", Amount: discount})
+	}
+	totals = append(totals,
+		documentTotal{Key: "tax_total", Amount: tax},
+		documentTotal{Key: "grand_total", Amount: total, Emphasis: true},
+	)
+	if showPaid {
+		totals = append(totals, documentTotal{Key: "paid", Amount: paid})
+		if balance := round2(total - paid); balance > 0 {
+			totals = append(totals, documentTotal{Key: "balance_due", Amount: balance})
+		}
+	}
+	return totals
+}
+
+func itemDescription(name, variant *string) string {
+	description := ptrString(name)
+	if v := ptrString(variant); v != "" {
+		description = strings.TrimSpace(description + " - " + v)
+	}
+	return description
+}
+
+func (s *DocumentRenderService) saleDocument(companyID int, sale *models.Sale) (*documentData, error) {
+	party, err := s.customerParty(companyID, sale.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	data := &documentData{
+		Type:       models.DocumentTypeSale,
+		Number:     sale.SaleNumber,
+		Info:       []documentField{{Key: "date", Value: formatDocumentDate(sale.SaleDate)}},
+		PartyKey:   "bill_to",
+		Party:      party,
+		GrandTotal: sale.TotalAmount,
+		Notes:      ptrString(sale.Notes),
+		Totals:     salesTotals(sale.Subtotal, sale.DiscountAmount, sale.TaxAmount, sale.TotalAmount, sale.PaidAmount, true),
+	}
+	taxAmounts := map[int]float64{}
+	for _, item := range sale.Items {
+		data.Items = append(data.Items, documentItem{
+			Description: itemDescription(item.ProductName, item.VariantName),
+			Details:     []documentField{{Key: "serial_number", Value: strings.Join(item.SerialNumbers, ", ")}},
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    item.DiscountAmount,
+			Tax:         item.TaxAmount,
+			Total:       item.LineTotal,
+		})
+		if item.TaxID != nil && item.TaxAmount != 0 {
+			taxAmounts[*item.TaxID] += item.TaxAmount
+		}
+	}
+	if len(sale.TaxBreakdown) > 0 {
+		for _, line := range sale.TaxBreakdown {
+			name := line.TaxName
+			if line.ComponentName != "" && line.ComponentName != line.TaxName {
+				name += " - " + line.ComponentName
+			}
+			data.TaxLines = append(data.TaxLines, documentTaxLine{Name: name, Rate: line.Percentage, Amount: line.Amount})
+		}
+	} else if data.TaxLines, err = s.taxLinesByTax(companyID, taxAmounts); err != nil {
+		return nil, err
+	}
+	einvoice, err := (&EInvoiceService{db: s.db}).GetEInvoiceForSale(companyID, sale.SaleID)
+	if err != nil {
+		return nil, err
+	}
+	if einvoice != nil {
+		data.EInvoiceQR = einvoice.QRPayload
+	}
+	return data, nil
+}
+
+func (s *DocumentRenderService) quoteDocument(companyID int, quote *models.Quote) (*documentData, error) {
+	party, err := s.customerParty(companyID, quote.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	data := &documentData{
+		Type:       models.DocumentTypeQuote,
+		Number:     quote.QuoteNumber,
+		Info:       []documentField{{Key: "date", Value: formatDocumentDate(quote.QuoteDate)}},
+		PartyKey:   "bill_to",
+		Party:      party,
+		GrandTotal: quote.TotalAmount,
+		Notes:      ptrString(quote.Notes),
+		Totals:     salesTotals(quote.Subtotal, quote.DiscountAmount, quote.TaxAmount, quote.TotalAmount, 0, false),
+	}
+	if quote.ValidUntil != nil {
+		data.Info = append(data.Info, documentField{Key: "valid_until", Value: formatDocumentDate(*quote.ValidUntil)})
+	}
+	taxAmounts := map[int]float64{}
+	for _, item := range quote.Items {
+		data.Items = append(data.Items, documentItem{
+			Description: ptrString(item.ProductName),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    item.DiscountAmount,
+			Tax:         item.TaxAmount,
+			Total:       item.LineTotal,
+		})
+		if item.TaxID != nil && item.TaxAmount != 0 {
+			taxAmounts[*item.TaxID] += item.TaxAmount
+		}
+	}
+	if data.TaxLines, err = s.taxLinesByTax(companyID, taxAmounts); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *DocumentRenderService) saleReturnDocument(companyID int, ret *models.SaleReturn) (*documentData, error) {
+	party, err := s.customerParty(companyID, ret.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	data := &documentData{
+		Type:       models.DocumentTypeSaleReturn,
+		Number:     ret.ReturnNumber,
+		Info:       []documentField{{Key: "date", Value: formatDocumentDate(ret.ReturnDate)}},
+		PartyKey:   "bill_to",
+		Party:      party,
+		GrandTotal: ret.TotalAmount,
+		Notes:      ptrString(ret.Reason),
+		Totals:     []documentTotal{{Key: "refund_total", Amount: ret.TotalAmount, Emphasis: true}},
+	}
+	var saleNumber string
+	err = s.db.QueryRow(`SELECT sale_number FROM sales WHERE sale_id = $1`, ret.SaleID).Scan(&saleNumber)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get original sale: %w", err)
+	}
+	if saleNumber != "" {
+		data.Info = append(data.Info, documentField{Key: "original_invoice", Value: saleNumber})
+	}
+	for _, item := range ret.Items {
+		data.Items = append(data.Items, documentItem{
+			Description: ptrString(item.ProductName),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.LineTotal,
+		})
+	}
+	einvoice, err := (&EInvoiceService{db: s.db}).GetEInvoiceForSaleReturn(companyID, ret.ReturnID)
+	if err != nil {
+		return nil, err
+	}
+	if einvoice != nil {
+		data.EInvoiceQR = einvoice.QRPayload
+	}
+	return data, nil
+}
+
+func (s *DocumentRenderService) purchaseDocument(companyID int, purchase *models.PurchaseWithDetails) (*documentData, error) {
+	var party documentParty
+	var address, phone, email, taxNumber sql.NullString
+	err := s.db.QueryRow(`
+		SELECT name, address, phone, email, tax_number FROM suppliers
+		WHERE supplier_id = $1 AND company_id = $2
+	`, purchase.SupplierID, companyID).Scan(&party.Name, &address, &phone, &email, &taxNumber)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+	party.Address, party.Phone, party.Email, party.TaxNumber = address.String, phone.String, email.String, taxNumber.String
+
+	data := &documentData{
+		Type:       models.DocumentTypePurchase,
+		Number:     purchase.PurchaseNumber,
+		Info:       []documentField{{Key: "date", Value: formatDocumentDate(purchase.PurchaseDate)}},
+		PartyKey:   "supplier",
+		Party:      &party,
+		GrandTotal: purchase.TotalAmount,
+		Notes:      ptrString(purchase.Notes),
+		Totals:     salesTotals(purchase.Subtotal, purchase.DiscountAmount, purchase.TaxAmount, purchase.TotalAmount, purchase.PaidAmount, true),
+	}
+	if purchase.DueDate != nil {
+		data.Info = append(data.Info, documentField{Key: "due_date", Value: formatDocumentDate(*purchase.DueDate)})
+	}
+	if ref := ptrString(purchase.ReferenceNumber); ref != "" {
+		data.Info = append(data.Info, documentField{Key: "reference", Value: ref})
+	}
+	taxAmounts := map[int]float64{}
+	for _, item := range purchase.Items {
+		description := ""
+		if item.Product != nil {
+			description = item.Product.Name
+		}
+		data.Items = append(data.Items, documentItem{
+			Description: description,
+			Details:     []documentField{{Key: "serial_number", Value: strings.Join(item.SerialNumbers, ", ")}},
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    item.DiscountAmount,
+			Tax:         item.TaxAmount,
+			Total:       item.LineTotal,
+		})
+		if item.TaxID != nil && item.TaxAmount != 0 {
+			taxAmounts[*item.TaxID] += item.TaxAmount
+		}
+	}
+	if data.TaxLines, err = s.taxLinesByTax(companyID, taxAmounts); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *DocumentRenderService) payslipDocument(companyID int, payslip *models.Payslip) (*documentData, error) {
+	p := payslip.Payroll
+	var party documentParty
+	var code, address, phone, email sql.NullString
+	err := s.db.QueryRow(`
+		SELECT name, employee_code, address, phone, email FROM employees
+		WHERE employee_id = $1 AND company_id = $2
+	`, p.EmployeeID, companyID).Scan(&party.Name, &code, &address, &phone, &email)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get employee: %w", err)
+	}
+	if code.String != "" {
+		party.Name += " (" + code.String + ")"
+	}
+	party.Address, party.Phone, party.Email = address.String, phone.String, email.String
+
+	data := &documentData{
+		Type:   models.DocumentTypePayslip,
+		Number: fmt.Sprintf("PS-%06d", p.PayrollID),
+		Info: []documentField{
+			{Key: "pay_period", Value: formatDocumentDate(p.PayPeriodStart) + " - " + formatDocumentDate(p.PayPeriodEnd)},
+			{Key: "status", Value: p.Status},
+		},
+		PartyKey:   "employee",
+		Party:      &party,
+		GrandTotal: payslip.NetPay,
+	}
+	var allowances, deductions, advances float64
+	for _, c := range payslip.Components {
+		data.Items = append(data.Items, documentItem{Description: c.Type, Total: c.Amount})
+		allowances += c.Amount
+	}
+	for _, d := range payslip.Deductions {
+		data.Items = append(data.Items, documentItem{Description: d.Type, Total: -d.Amount})
+		deductions += d.Amount
+	}
+	for _, a := range payslip.Advances {
+		data.Items = append(data.Items, documentItem{
+			Description: "Advance",
+			Details:     []documentField{{Key: "date", Value: formatDocumentDate(a.Date)}},
+			Total:       -a.Amount,
+		})
+		advances += a.Amount
+	}
+	data.Totals = []documentTotal{
+		{Key: "basic_salary", Amount: p.BasicSalary},
+		{Key: "allowances", Amount: round2(allowances)},
+		{Key: "deductions", Amount: round2(deductions)},
+		{Key: "advances", Amount: round2(advances)},
+		{Key: "net_pay", Amount: payslip.NetPay, Emphasis: true},
+	}
+	return data, nil
+}
+
+func warrantyDocument(w *models.WarrantyRegistration) *documentData {
+	data := &documentData{
+		Type:   models.DocumentTypeWarranty,
+		Number: fmt.Sprintf("WR-%06d", w.WarrantyID),
+		Info: []documentField{
+			{Key: "date", Value: formatDocumentDate(w.RegisteredAt)},
+			{Key: "original_invoice", Value: w.SaleNumber},
+		},
+		PartyKey: "customer",
+		Party: &documentParty{
+			Name:    w.CustomerName,
+			Address: ptrString(w.CustomerAddress),
+			Phone:   ptrString(w.CustomerPhone),
+			Email:   ptrString(w.CustomerEmail),
+		},
+		Notes: ptrString(w.Notes),
+	}
+	for _, item := range w.Items {
+		data.Items = append(data.Items, documentItem{
+			Description: itemDescription(&item.ProductName, item.VariantName),
+			Details: []documentField{
+				{Key: "serial_number", Value: ptrString(item.SerialNumber)},
+				{Key: "warranty_until", Value: formatDocumentDate(item.WarrantyEndDate)},
+			},
+			Quantity: item.Quantity,
+		})
+	}
+	return data
+}
+
+// sampleDocumentData is the fixed document shown while a template is edited.
+func sampleDocumentData(docType string) *documentData {
+	date := time.Now().UTC()
+	data := &documentData{
+		Type:     docType,
+		Number:   "SAMPLE-0001",
+		Info:     []documentField{{Key: "date", Value: formatDocumentDate(date)}},
+		PartyKey: "bill_to",
+		Party: &documentParty{
+			Name:      "Sample Customer",
+			Address:   "12 Example Street\nSample City",
+			Phone:     "+1 555 0100",
+			Email:     "customer@example.com",
+			TaxNumber: "TAX-000123",
+		},
+		Items: []documentItem{
+			{Description: "Sample product", Details: []documentField{{Key: "serial_number", Value: "SN-0001"}}, Quantity: 2, UnitPrice: 50, Tax: 15, Total: 115},
+			{Description: "Sample service", Quantity: 1, UnitPrice: 40, Discount: 4, Tax: 5.4, Total: 41.4},
+		},
+		TaxLines:   []documentTaxLine{{Name: "VAT", Rate: 15, Amount: 20.4}},
+		Totals:     salesTotals(140, 4, 20.4, 156.4, 100, true),
+		GrandTotal: 156.4,
+		Notes:      "Thank you for your business.",
+	}
+	switch docType {
+	case models.DocumentTypeQuote:
+		data.Info = append(data.Info, documentField{Key: "valid_until", Value: formatDocumentDate(date.AddDate(0, 0, 30))})
+		data.Totals = salesTotals(140, 4, 20.4, 156.4, 0, false)
+	case models.DocumentTypeSaleReturn:
+		data.Info = append(data.Info, documentField{Key: "original_invoice", Value: "SAMPLE-0000"})
+		data.Totals = []documentTotal{{Key: "refund_total", Amount: 156.4, Emphasis: true}}
+	case models.DocumentTypePurchase:
+		data.PartyKey = "supplier"
+		data.Party.Name = "Sample Supplier"
+	case models.DocumentTypePayslip:
+		data.PartyKey = "employee"
+		data.Party = &documentParty{Name: "Sample Employee (EMP-001)", Email: "employee@example.com"}
+		data.Info = []documentField{{Key: "pay_period", Value: formatDocumentDate(date.AddDate(0, -1, 0)) + " - " + formatDocumentDate(date)}}
+		data.Items = []documentItem{{Description: "Overtime", Total: 150}, {Description: "Loan", Total: -50}}
+		data.TaxLines = nil
+		data.Totals = []documentTotal{
+			{Key: "basic_salary", Amount: 1000},
+			{Key: "allowances", Amount: 150},
+			{Key: "deductions", Amount: 50},
+			{Key: "advances", Amount: 0},
+			{Key: "net_pay", Amount: 1100, Emphasis: true},
+		}
+		data.GrandTotal = 1100
+		data.Notes = ""
+	case models.DocumentTypeWarranty:
+		data.PartyKey = "customer"
+		data.Info = append(data.Info, documentField{Key: "original_invoice", Value: "SAMPLE-0000"})
+		data.Items = []documentItem{{
+			Description: "Sample product",
+			Details: []documentField{
+				{Key: "serial_number", Value: "SN-0001"},
+				{Key: "warranty_until", Value: formatDocumentDate(date.AddDate(1, 0, 0))},
+			},
+			Quantity: 1,
+		}}
+		data.TaxLines = nil
+		data.Totals = nil
+		data.GrandTotal = 0
+	}
+	return data
+}
@@ -0,0 +1,1007 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+)
+
+// The renderer turns an invoice template layout and a normalised document
+// into HTML or PDF. Layouts are JSON objects of the form
+//
+//	{
+//	  "page": {"size": "A4", "margin": 36},
+//	  "style": {"font_size": 9, "accent_color": "#1f2937"},
+//	  "show_secondary_language": true,
+//	  "labels": {"title_sale": "Tax Invoice"},
+//	  "sections": [{"type": "header"}, {"type": "items", "columns": ["description", "total"]}]
+//	}
+//
+// Every key is optional; a layout without sections prints the default
+// sections for the document type.
+
+const (
+	documentSectionHeader        = "header"
+	documentSectionTitle         = "title"
+	documentSectionDocumentInfo  = "document_info"
+	documentSectionParty         = "party"
+	documentSectionItems         = "items"
+	documentSectionTaxBreakdown  = "tax_breakdown"
+	documentSectionTotals        = "totals"
+	documentSectionAmountInWords = "amount_in_words"
+	documentSectionNotes         = "notes"
+	documentSectionQR            = "qr"
+	documentSectionBarcode       = "barcode"
+	documentSectionText          = "text"
+	documentSectionSignature     = "signature"
+	documentSectionFooter        = "footer"
+)
+
+var documentSectionTypes = map[string]bool{
+	documentSectionHeader: true, documentSectionTitle: true, documentSectionDocumentInfo: true,
+	documentSectionParty: true, documentSectionItems: true, documentSectionTaxBreakdown: true,
+	documentSectionTotals: true, documentSectionAmountInWords: true, documentSectionNotes: true,
+	documentSectionQR: true, documentSectionBarcode: true, documentSectionText: true,
+	documentSectionSignature: true, documentSectionFooter: true,
+}
+
+var documentItemColumns = map[string]bool{
+	"index": true, "description": true, "quantity": true, "unit_price": true,
+	"discount": true, "tax": true, "total": true,
+}
+
+var documentColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// defaultDocumentLabels are the English labels used when neither the layout
+// nor the translations table provides one. Translations use the key with a
+// "print." prefix.
+var defaultDocumentLabels = map[string]string{
+	"title_sale":        "Invoice",
+	"title_quote":       "Quotation",
+	"title_sale_return": "Credit Note",
+	"title_purchase":    "Purchase Invoice",
+	"title_payslip":     "Payslip",
+	"title_warranty":    "Warranty Card",
+	"number":            "No.",
+	"date":              "Date",
+	"due_date":          "Due Date",
+	"valid_until":       "Valid Until",
+	"reference":         "Reference",
+	"original_invoice":  "Original Invoice",
+	"pay_period":        "Pay Period",
+	"status":            "Status",
+	"bill_to":           "Bill To",
+	"supplier":          "Supplier",
+	"employee":          "Employee",
+	"customer":          "Customer",
+	"phone":             "Phone",
+	"email":             "Email",
+	"tax_number":        "Tax No.",
+	"index":             "#",
+	"description":       "Description",
+	"quantity":          "Qty",
+	"unit_price":        "Unit Price",
+	"discount":          "Discount",
+	"tax":               "Tax",
+	"total":             "Total",
+	"subtotal":          "Subtotal",
+	"discount_total":    "Discount",
+	"tax_total":         "Tax",
+	"grand_total":       "Grand Total",
+	"paid":              "Paid",
+	"balance_due":       "Balance Due",
+	"refund_total":      "Refund Total",
+	"basic_salary":      "Basic Salary",
+	"allowances":        "Allowances",
+	"deductions":        "Deductions",
+	"advances":          "Advances",
+	"net_pay":           "Net Pay",
+	"tax_breakdown":     "Tax Breakdown",
+	"tax_rate":          "Rate",
+	"amount":            "Amount",
+	"amount_in_words":   "Amount in Words",
+	"notes":             "Notes",
+	"serial_number":     "Serial No.",
+	"warranty_until":    "Warranty Until",
+	"signature":         "Authorised Signature",
+}
+
+type documentLayout struct {
+	Page struct {
+		Size   string  `json:"size"`
+		Margin float64 `json:"margin"`
+	} `json:"page"`
+	Style struct {
+		FontSize    float64 `json:"font_size"`
+		AccentColor string  `json:"accent_color"`
+	} `json:"style"`
+	ShowSecondaryLanguage *bool             `json:"show_secondary_language"`
+	Labels                map[string]string `json:"labels"`
+	Sections              []documentSection `json:"sections"`
+}
+
+type documentSection struct {
+	Type     string   `json:"type"`
+	Text     string   `json:"text,omitempty"`
+	Align    string   `json:"align,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Columns  []string `json:"columns,omitempty"`
+	ShowLogo *bool    `json:"show_logo,omitempty"`
+}
+
+// parseDocumentLayout decodes and validates a stored template layout.
+func parseDocumentLayout(raw models.JSONB) (*documentLayout, error) {
+	layout := &documentLayout{}
+	if len(raw) > 0 {
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid layout: %w", err)
+		}
+		if err := json.Unmarshal(encoded, layout); err != nil {
+			return nil, fmt.Errorf("invalid layout: %w", err)
+		}
+	}
+
+	switch strings.ToUpper(layout.Page.Size) {
+	case "", "A4", "LETTER":
+	default:
+		return nil, fmt.Errorf("invalid layout: unsupported page size %q", layout.Page.Size)
+	}
+	if layout.Page.Margin < 0 || layout.Page.Margin > 144 {
+		return nil, fmt.Errorf("invalid layout: page margin must be between 0 and 144")
+	}
+	if layout.Style.FontSize != 0 && (layout.Style.FontSize < 6 || layout.Style.FontSize > 16) {
+		return nil, fmt.Errorf("invalid layout: font size must be between 6 and 16")
+	}
+	if layout.Style.AccentColor != "" && !documentColorPattern.MatchString(layout.Style.AccentColor) {
+		return nil, fmt.Errorf("invalid layout: accent color must look like #RRGGBB")
+	}
+	for i, section := range layout.Sections {
+		if !documentSectionTypes[section.Type] {
+			return nil, fmt.Errorf("invalid layout: unknown section type %q at position %d", section.Type, i)
+		}
+		switch section.Align {
+		case "", "left", "center", "right":
+		default:
+			return nil, fmt.Errorf("invalid layout: unsupported alignment %q", section.Align)
+		}
+		for _, col := range section.Columns {
+			if !documentItemColumns[col] {
+				return nil, fmt.Errorf("invalid layout: unknown item column %q", col)
+			}
+		}
+	}
+	return layout, nil
+}
+
+// defaultDocumentSections is the layout printed when a template defines none.
+func defaultDocumentSections(docType string) []documentSection {
+	names := []string{documentSectionHeader, documentSectionTitle, documentSectionDocumentInfo, documentSectionParty, documentSectionItems}
+	switch docType {
+	case models.DocumentTypePayslip:
+		names = append(names, documentSectionTotals, documentSectionAmountInWords, documentSectionSignature)
+	case models.DocumentTypeWarranty:
+		names = append(names, documentSectionNotes, documentSectionBarcode, documentSectionSignature)
+	default:
+		names = append(names, documentSectionTaxBreakdown, documentSectionTotals, documentSectionAmountInWords, documentSectionNotes, documentSectionQR)
+	}
+	sections := make([]documentSection, len(names))
+	for i, name := range names {
+		sections[i] = documentSection{Type: name}
+	}
+	return sections
+}
+
+func defaultDocumentColumns(docType string) []string {
+	switch docType {
+	case models.DocumentTypePayslip:
+		return []string{"index", "description", "total"}
+	case models.DocumentTypeWarranty:
+		return []string{"index", "description", "quantity"}
+	case models.DocumentTypeSaleReturn:
+		return []string{"index", "description", "quantity", "unit_price", "total"}
+	default:
+		return []string{"index", "description", "quantity", "unit_price", "discount", "tax", "total"}
+	}
+}
+
+// documentData is the document-type-neutral view every renderer works from.
+type documentData struct {
+	Type         string
+	Number       string
+	Info         []documentField
+	Company      documentParty
+	LogoDataURI  string
+	Logo         image.Image
+	PartyKey     string
+	Party        *documentParty
+	Items        []documentItem
+	TaxLines     []documentTaxLine
+	Totals       []documentTotal
+	GrandTotal   float64
+	CurrencyCode string
+	CurrencyName string
+	Notes        string
+	// EInvoiceQR is the TLV payload of the issued e-invoice, if any.
+	EInvoiceQR string
+}
+
+type documentField struct {
+	Key   string
+	Value string
+}
+
+type documentParty struct {
+	Name      string
+	Address   string
+	Phone     string
+	Email     string
+	TaxNumber string
+}
+
+type documentItem struct {
+	Description string
+	Details     []documentField
+	Quantity    float64
+	UnitPrice   float64
+	Discount    float64
+	Tax         float64
+	Total       float64
+}
+
+type documentTaxLine struct {
+	Name   string
+	Rate   float64
+	Amount float64
+}
+
+type documentTotal struct {
+	Key      string
+	Amount   float64
+	Emphasis bool
+}
+
+// documentLabels resolves bilingual labels: layout overrides win, then the
+// translations of each language, then the English defaults for the primary
+// language only.
+type documentLabels struct {
+	overrides     map[string]string
+	primary       map[string]string
+	secondary     map[string]string
+	showSecondary bool
+}
+
+func (l documentLabels) get(key string) (string, string) {
+	primary := l.overrides[key]
+	if primary == "" {
+		primary = l.primary["print."+key]
+	}
+	if primary == "" {
+		primary = defaultDocumentLabels[key]
+	}
+	if primary == "" {
+		primary = key
+	}
+	secondary := ""
+	if l.showSecondary {
+		secondary = l.secondary["print."+key]
+		if secondary == primary {
+			secondary = ""
+		}
+	}
+	return primary, secondary
+}
+
+func documentTitleKey(docType string) string {
+	return "title_" + strings.ToLower(docType)
+}
+
+func formatDocumentAmount(v float64) string {
+	negative := v < 0
+	cents := int64(math.Round(math.Abs(v) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	out := fmt.Sprintf("%s.%02d", grouped.String(), cents%100)
+	if negative && cents > 0 {
+		out = "-" + out
+	}
+	return out
+}
+
+func formatDocumentQuantity(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func formatDocumentDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// documentCodeValue resolves what a QR or barcode section encodes: the
+// document number, a plain-text summary, the e-invoice payload, or literal
+// text with placeholders. QR codes default to the e-invoice payload once one
+// has been issued.
+func documentCodeValue(section documentSection, data *documentData) string {
+	value := section.Value
+	if value == "" {
+		switch {
+		case section.Type == documentSectionQR && data.EInvoiceQR != "":
+			value = "einvoice"
+		case section.Type == documentSectionQR:
+			value = "summary"
+		default:
+			value = "document_number"
+		}
+	}
+	switch value {
+	case "einvoice":
+		return data.EInvoiceQR
+	case "document_number":
+		return data.Number
+	case "summary":
+		lines := []string{data.Company.Name}
+		if data.Company.TaxNumber != "" {
+			lines = append(lines, "Tax No: "+data.Company.TaxNumber)
+		}
+		lines = append(lines, "No: "+data.Number)
+		for _, f := range data.Info {
+			if f.Key == "date" {
+				lines = append(lines, "Date: "+f.Value)
+			}
+		}
+		taxTotal := 0.0
+		for _, t := range data.TaxLines {
+			taxTotal += t.Amount
+		}
+		total := formatDocumentAmount(data.GrandTotal)
+		if data.CurrencyCode != "" {
+			total = data.CurrencyCode + " " + total
+		}
+		lines = append(lines, "Total: "+total)
+		if taxTotal != 0 {
+			lines = append(lines, "Tax: "+formatDocumentAmount(taxTotal))
+		}
+		return strings.Join(lines, "\n")
+	}
+	replacer := strings.NewReplacer(
+		"{{document_number}}", data.Number,
+		"{{total}}", formatDocumentAmount(data.GrandTotal),
+		"{{company}}", data.Company.Name,
+	)
+	return replacer.Replace(value)
+}
+
+// documentItemDetail renders the secondary line under an item description,
+// such as serial numbers or warranty end dates.
+func documentItemDetail(item documentItem, label func(string) string) string {
+	parts := make([]string, 0, len(item.Details))
+	for _, f := range item.Details {
+		if f.Value != "" {
+			parts = append(parts, label(f.Key)+": "+f.Value)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+func documentItemColumnsFor(section documentSection, docType string) []string {
+	if len(section.Columns) > 0 {
+		return section.Columns
+	}
+	return defaultDocumentColumns(docType)
+}
+
+func documentItemCell(item documentItem, index int, column string) string {
+	switch column {
+	case "index":
+		return strconv.Itoa(index + 1)
+	case "description":
+		return item.Description
+	case "quantity":
+		return formatDocumentQuantity(item.Quantity)
+	case "unit_price":
+		return formatDocumentAmount(item.UnitPrice)
+	case "discount":
+		return formatDocumentAmount(item.Discount)
+	case "tax":
+		return formatDocumentAmount(item.Tax)
+	case "total":
+		return formatDocumentAmount(item.Total)
+	}
+	return ""
+}
+
+func documentAccent(layout *documentLayout) string {
+	if layout.Style.AccentColor != "" {
+		return layout.Style.AccentColor
+	}
+	return "#1f2937"
+}
+
+// renderDocumentHTML produces a self-contained HTML page; the logo and codes
+// are inlined so the page can be emailed or archived as is.
+func renderDocumentHTML(layout *documentLayout, data *documentData, labels documentLabels, language string) []byte {
+	sections := layout.Sections
+	if len(sections) == 0 {
+		sections = defaultDocumentSections(data.Type)
+	}
+	fontSize := layout.Style.FontSize
+	if fontSize == 0 {
+		fontSize = 9
+	}
+	esc := html.EscapeString
+	label := func(key string) string {
+		primary, secondary := labels.get(key)
+		out := esc(primary)
+		if secondary != "" {
+			out += ` <span class="secondary" dir="auto">` + esc(secondary) + `</span>`
+		}
+		return out
+	}
+
+	plainLabel := func(key string) string {
+		primary, _ := labels.get(key)
+		return primary
+	}
+
+	var b strings.Builder
+	title, _ := labels.get(documentTitleKey(data.Type))
+	fmt.Fprintf(&b, `<!DOCTYPE html><html lang="%s"><head><meta charset="utf-8"><title>%s %s</title>`, esc(language), esc(title), esc(data.Number))
+	fmt.Fprintf(&b, `<style>body{font-family:Helvetica,Arial,sans-serif;font-size:%.1fpt;color:#111;margin:0}`+
+		`.document{max-width:780px;margin:0 auto;padding:24px}`+
+		`.secondary{color:#555;font-size:0.9em}`+
+		`.header{display:flex;justify-content:space-between;align-items:flex-start;gap:16px}`+
+		`.header img{max-height:72px;max-width:200px}`+
+		`.company-name{font-size:1.5em;font-weight:bold}`+
+		`h1{color:%s;font-size:1.6em;margin:16px 0 8px}`+
+		`table{width:100%%;border-collapse:collapse;margin:8px 0}`+
+		`th{background:%s;color:#fff;text-align:left;padding:4px 6px}`+
+		`td{border-bottom:1px solid #ddd;padding:4px 6px;vertical-align:top}`+
+		`.num{text-align:right}.detail{color:#555;font-size:0.9em}`+
+		`.totals{width:auto;margin-left:auto}.totals td{border:none}.emphasis td{font-weight:bold;border-top:2px solid #111}`+
+		`.info td{border:none;padding:2px 6px}.section{margin:8px 0}`+
+		`.signature{margin-top:48px;border-top:1px solid #111;width:220px;text-align:center;padding-top:4px}`+
+		`.code{margin:8px 0}</style></head><body>`, fontSize, esc(documentAccent(layout)), esc(documentAccent(layout)))
+	fmt.Fprintf(&b, `<div class="document doc-%s">`, esc(strings.ToLower(data.Type)))
+
+	for _, section := range sections {
+		align := ""
+		if section.Align != "" {
+			align = ` style="text-align:` + section.Align + `"`
+		}
+		switch section.Type {
+		case documentSectionHeader:
+			b.WriteString(`<div class="header"><div>`)
+			fmt.Fprintf(&b, `<div class="company-name">%s</div>`, esc(data.Company.Name))
+			writePartyHTML(&b, data.Company, label)
+			b.WriteString(`</div>`)
+			if data.LogoDataURI != "" && (section.ShowLogo == nil || *section.ShowLogo) {
+				fmt.Fprintf(&b, `<img src="%s" alt="%s">`, esc(data.LogoDataURI), esc(data.Company.Name))
+			}
+			b.WriteString(`</div>`)
+		case documentSectionTitle:
+			fmt.Fprintf(&b, `<h1%s>%s</h1>`, align, label(documentTitleKey(data.Type)))
+		case documentSectionDocumentInfo:
+			b.WriteString(`<table class="info">`)
+			fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td></tr>`, label("number"), esc(data.Number))
+			for _, f := range data.Info {
+				fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td></tr>`, label(f.Key), esc(f.Value))
+			}
+			b.WriteString(`</table>`)
+		case documentSectionParty:
+			if data.Party == nil {
+				continue
+			}
+			fmt.Fprintf(&b, `<div class="section party"><strong>%s</strong><div>%s</div>`, label(data.PartyKey), esc(data.Party.Name))
+			writePartyHTML(&b, *data.Party, label)
+			b.WriteString(`</div>`)
+		case documentSectionItems:
+			columns := documentItemColumnsFor(section, data.Type)
+			b.WriteString(`<table class="items"><thead><tr>`)
+			for _, col := range columns {
+				class := ""
+				if col != "description" && col != "index" {
+					class = ` class="num"`
+				}
+				fmt.Fprintf(&b, `<th%s>%s</th>`, class, label(col))
+			}
+			b.WriteString(`</tr></thead><tbody>`)
+			for i, item := range data.Items {
+				b.WriteString(`<tr>`)
+				for _, col := range columns {
+					cell := esc(documentItemCell(item, i, col))
+					if detail := documentItemDetail(item, plainLabel); col == "description" && detail != "" {
+						cell += `<div class="detail">` + esc(detail) + `</div>`
+					}
+					if col != "description" && col != "index" {
+						fmt.Fprintf(&b, `<td class="num">%s</td>`, cell)
+					} else {
+						fmt.Fprintf(&b, `<td>%s</td>`, cell)
+					}
+				}
+				b.WriteString(`</tr>`)
+			}
+			b.WriteString(`</tbody></table>`)
+		case documentSectionTaxBreakdown:
+			if len(data.TaxLines) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, `<div class="section"><strong>%s</strong><table class="tax-breakdown"><thead><tr><th>%s</th><th class="num">%s</th><th class="num">%s</th></tr></thead><tbody>`,
+				label("tax_breakdown"), label("tax"), label("tax_rate"), label("amount"))
+			for _, t := range data.TaxLines {
+				fmt.Fprintf(&b, `<tr><td>%s</td><td class="num">%s%%</td><td class="num">%s</td></tr>`, esc(t.Name), formatDocumentQuantity(t.Rate), formatDocumentAmount(t.Amount))
+			}
+			b.WriteString(`</tbody></table></div>`)
+		case documentSectionTotals:
+			b.WriteString(`<table class="totals">`)
+			for _, t := range data.Totals {
+				class := ""
+				text := label(t.Key)
+				if t.Emphasis {
+					class = ` class="emphasis"`
+					if data.CurrencyCode != "" {
+						text += " (" + esc(data.CurrencyCode) + ")"
+					}
+				}
+				fmt.Fprintf(&b, `<tr%s><td>%s</td><td class="num">%s</td></tr>`, class, text, formatDocumentAmount(t.Amount))
+			}
+			b.WriteString(`</table>`)
+		case documentSectionAmountInWords:
+			fmt.Fprintf(&b, `<div class="section amount-words"%s><strong>%s:</strong> %s</div>`, align, label("amount_in_words"), esc(utils.AmountInWords(data.GrandTotal, data.CurrencyName)))
+		case documentSectionNotes:
+			text := data.Notes
+			if section.Text != "" {
+				text = section.Text
+			}
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			fmt.Fprintf(&b, `<div class="section notes"%s><strong>%s</strong><div>%s</div></div>`, align, label("notes"), strings.ReplaceAll(esc(text), "\n", "<br>"))
+		case documentSectionQR:
+			qr, err := utils.EncodeQR(documentCodeValue(section, data), utils.QRErrorCorrectionMedium)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, `<div class="code qr"%s>%s</div>`, align, qr.SVG(3))
+		case documentSectionBarcode:
+			svg, err := utils.Code128SVG(documentCodeValue(section, data), 2, 48)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(&b, `<div class="code barcode"%s>%s</div>`, align, svg)
+		case documentSectionText:
+			fmt.Fprintf(&b, `<div class="section text"%s>%s</div>`, align, strings.ReplaceAll(esc(section.Text), "\n", "<br>"))
+		case documentSectionSignature:
+			fmt.Fprintf(&b, `<div class="signature">%s</div>`, label("signature"))
+		case documentSectionFooter:
+			fmt.Fprintf(&b, `<div class="section footer" style="text-align:%s;color:#555">%s</div>`, firstNonEmpty(section.Align, "center"), esc(section.Text))
+		}
+	}
+	b.WriteString(`</div></body></html>`)
+	return []byte(b.String())
+}
+
+func writePartyHTML(b *strings.Builder, party documentParty, label func(string) string) {
+	esc := html.EscapeString
+	if party.Address != "" {
+		fmt.Fprintf(b, `<div>%s</div>`, strings.ReplaceAll(esc(party.Address), "\n", "<br>"))
+	}
+	if party.Phone != "" {
+		fmt.Fprintf(b, `<div>%s: %s</div>`, label("phone"), esc(party.Phone))
+	}
+	if party.Email != "" {
+		fmt.Fprintf(b, `<div>%s: %s</div>`, label("email"), esc(party.Email))
+	}
+	if party.TaxNumber != "" {
+		fmt.Fprintf(b, `<div>%s: %s</div>`, label("tax_number"), esc(party.TaxNumber))
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// documentPDF lays sections out top to bottom, starting new pages as needed.
+type documentPDF struct {
+	canvas   *utils.PDFCanvas
+	labels   documentLabels
+	margin   float64
+	fontSize float64
+	accent   [3]float64
+	y        float64
+}
+
+// renderDocumentPDF produces the PDF copy with the standard Helvetica fonts.
+// Secondary-language labels are only printed when the script is one those
+// fonts can show; the HTML rendering carries every language.
+func renderDocumentPDF(layout *documentLayout, data *documentData, labels documentLabels) []byte {
+	sections := layout.Sections
+	if len(sections) == 0 {
+		sections = defaultDocumentSections(data.Type)
+	}
+	width, height := utils.PDFPageA4Width, utils.PDFPageA4Height
+	if strings.EqualFold(layout.Page.Size, "LETTER") {
+		width, height = utils.PDFPageLetterWidth, utils.PDFPageLetterHeight
+	}
+	p := &documentPDF{
+		canvas:   utils.NewPDFCanvas(width, height),
+		labels:   labels,
+		margin:   layout.Page.Margin,
+		fontSize: layout.Style.FontSize,
+	}
+	if p.margin == 0 {
+		p.margin = 36
+	}
+	if p.fontSize == 0 {
+		p.fontSize = 9
+	}
+	accent := documentAccent(layout)
+	for i := 0; i < 3; i++ {
+		v, _ := strconv.ParseUint(accent[1+i*2:3+i*2], 16, 8)
+		p.accent[i] = float64(v) / 255
+	}
+	p.y = height - p.margin
+
+	for _, section := range sections {
+		p.section(section, data)
+	}
+	return p.canvas.Bytes()
+}
+
+func (p *documentPDF) contentWidth() float64 {
+	return p.canvas.Width - 2*p.margin
+}
+
+func (p *documentPDF) lineHeight(size float64) float64 {
+	return size * 1.35
+}
+
+// ensure starts a new page when fewer than h points remain.
+func (p *documentPDF) ensure(h float64) bool {
+	if p.y-h >= p.margin {
+		return false
+	}
+	p.canvas.AddPage()
+	p.y = p.canvas.Height - p.margin
+	return true
+}
+
+// primaryLabel falls back to English when the primary language cannot be
+// drawn with the standard fonts.
+func (p *documentPDF) primaryLabel(key string) string {
+	primary, _ := p.labels.get(key)
+	if !utils.PDFRepresentable(primary) {
+		primary = firstNonEmpty(defaultDocumentLabels[key], key)
+	}
+	return primary
+}
+
+func (p *documentPDF) label(key string) string {
+	primary := p.primaryLabel(key)
+	if _, secondary := p.labels.get(key); secondary != "" && utils.PDFRepresentable(secondary) {
+		return primary + " / " + secondary
+	}
+	return primary
+}
+
+// fit shortens text with an ellipsis so it fits into width.
+func (p *documentPDF) fit(text string, size, width float64, bold bool) string {
+	if utils.PDFTextWidth(text, size, bold) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && utils.PDFTextWidth(string(runes)+"...", size, bold) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// wrap breaks text into lines no wider than width.
+func (p *documentPDF) wrap(text string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := strings.TrimSpace(line + " " + word)
+			if line != "" && utils.PDFTextWidth(candidate, size, false) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, p.fit(line, size, width, false))
+	}
+	return lines
+}
+
+func (p *documentPDF) text(text string, size float64, bold bool, align string) {
+	p.ensure(p.lineHeight(size))
+	p.y -= p.lineHeight(size)
+	text = p.fit(text, size, p.contentWidth(), bold)
+	x := p.margin
+	switch align {
+	case "center":
+		x = p.margin + (p.contentWidth()-utils.PDFTextWidth(text, size, bold))/2
+	case "right":
+		x = p.canvas.Width - p.margin - utils.PDFTextWidth(text, size, bold)
+	}
+	p.canvas.Text(x, p.y+size*0.25, size, bold, text)
+}
+
+func (p *documentPDF) gap(h float64) {
+	p.y -= h
+}
+
+func (p *documentPDF) partyLines(party documentParty) []string {
+	var lines []string
+	if party.Address != "" {
+		lines = append(lines, strings.Split(party.Address, "\n")...)
+	}
+	if party.Phone != "" {
+		lines = append(lines, p.label("phone")+": "+party.Phone)
+	}
+	if party.Email != "" {
+		lines = append(lines, p.label("email")+": "+party.Email)
+	}
+	if party.TaxNumber != "" {
+		lines = append(lines, p.label("tax_number")+": "+party.TaxNumber)
+	}
+	return lines
+}
+
+func (p *documentPDF) section(section documentSection, data *documentData) {
+	size := p.fontSize
+	switch section.Type {
+	case documentSectionHeader:
+		top := p.y
+		p.text(data.Company.Name, size+6, true, "left")
+		for _, line := range p.partyLines(data.Company) {
+			p.text(line, size, false, "left")
+		}
+		if data.Logo != nil && (section.ShowLogo == nil || *section.ShowLogo) {
+			bounds := data.Logo.Bounds()
+			h := 60.0
+			w := h * float64(bounds.Dx()) / float64(bounds.Dy())
+			if w > 180 {
+				w, h = 180, 180*float64(bounds.Dy())/float64(bounds.Dx())
+			}
+			p.canvas.Image(data.Logo, p.canvas.Width-p.margin-w, top-h, w, h)
+			if top-h < p.y {
+				p.y = top - h
+			}
+		}
+		p.gap(size)
+	case documentSectionTitle:
+		p.canvas.SetColor(p.accent[0], p.accent[1], p.accent[2])
+		p.text(p.label(documentTitleKey(data.Type)), size+8, true, firstNonEmpty(section.Align, "left"))
+		p.canvas.SetColor(0, 0, 0)
+		p.gap(size / 2)
+	case documentSectionDocumentInfo:
+		fields := append([]documentField{{Key: "number", Value: data.Number}}, data.Info...)
+		for _, f := range fields {
+			p.ensure(p.lineHeight(size))
+			p.y -= p.lineHeight(size)
+			p.canvas.Text(p.margin, p.y+size*0.25, size, true, p.fit(p.label(f.Key), size, 150, true))
+			p.canvas.Text(p.margin+160, p.y+size*0.25, size, false, p.fit(f.Value, size, p.contentWidth()-160, false))
+		}
+		p.gap(size)
+	case documentSectionParty:
+		if data.Party == nil {
+			return
+		}
+		p.text(p.label(data.PartyKey), size, true, "left")
+		p.text(data.Party.Name, size, false, "left")
+		for _, line := range p.partyLines(*data.Party) {
+			p.text(line, size, false, "left")
+		}
+		p.gap(size)
+	case documentSectionItems:
+		p.items(documentItemColumnsFor(section, data.Type), data)
+		p.gap(size / 2)
+	case documentSectionTaxBreakdown:
+		if len(data.TaxLines) == 0 {
+			return
+		}
+		p.text(p.label("tax_breakdown"), size, true, "left")
+		right := p.canvas.Width - p.margin
+		for _, t := range data.TaxLines {
+			p.ensure(p.lineHeight(size))
+			p.y -= p.lineHeight(size)
+			p.canvas.Text(p.margin, p.y+size*0.25, size, false, p.fit(t.Name, size, p.contentWidth()-200, false))
+			rate := formatDocumentQuantity(t.Rate) + "%"
+			p.canvas.Text(right-110-utils.PDFTextWidth(rate, size, false), p.y+size*0.25, size, false, rate)
+			amount := formatDocumentAmount(t.Amount)
+			p.canvas.Text(right-utils.PDFTextWidth(amount, size, false), p.y+size*0.25, size, false, amount)
+		}
+		p.gap(size)
+	case documentSectionTotals:
+		right := p.canvas.Width - p.margin
+		for _, t := range data.Totals {
+			text := p.label(t.Key)
+			if t.Emphasis && data.CurrencyCode != "" {
+				text += " (" + data.CurrencyCode + ")"
+			}
+			p.ensure(p.lineHeight(size))
+			p.y -= p.lineHeight(size)
+			if t.Emphasis {
+				p.canvas.Line(right-240, p.y+p.lineHeight(size), right, p.y+p.lineHeight(size), 1)
+			}
+			p.canvas.Text(right-240, p.y+size*0.25, size, t.Emphasis, p.fit(text, size, 150, t.Emphasis))
+			amount := formatDocumentAmount(t.Amount)
+			p.canvas.Text(right-utils.PDFTextWidth(amount, size, t.Emphasis), p.y+size*0.25, size, t.Emphasis, amount)
+		}
+		p.gap(size)
+	case documentSectionAmountInWords:
+		words := p.label("amount_in_words") + ": " + utils.AmountInWords(data.GrandTotal, data.CurrencyName)
+		for _, line := range p.wrap(words, size, p.contentWidth()) {
+			p.text(line, size, false, section.Align)
+		}
+		p.gap(size / 2)
+	case documentSectionNotes:
+		text := data.Notes
+		if section.Text != "" {
+			text = section.Text
+		}
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		p.text(p.label("notes"), size, true, section.Align)
+		for _, line := range p.wrap(text, size, p.contentWidth()) {
+			p.text(line, size, false, section.Align)
+		}
+		p.gap(size / 2)
+	case documentSectionQR:
+		qr, err := utils.EncodeQR(documentCodeValue(section, data), utils.QRErrorCorrectionMedium)
+		if err != nil {
+			return
+		}
+		module := 2.0
+		dim := float64(qr.Size) * module
+		p.ensure(dim + size)
+		x := p.alignedX(section.Align, dim)
+		top := p.y - size/2
+		for y := 0; y < qr.Size; y++ {
+			for x2 := 0; x2 < qr.Size; x2++ {
+				if qr.Dark(x2, y) {
+					p.canvas.Rect(x+float64(x2)*module, top-float64(y+1)*module, module, module, true)
+				}
+			}
+		}
+		p.y = top - dim - size/2
+	case documentSectionBarcode:
+		widths, err := utils.EncodeCode128(documentCodeValue(section, data))
+		if err != nil {
+			return
+		}
+		module := 1.0
+		total := 0
+		for _, w := range widths {
+			total += w
+		}
+		barHeight := 36.0
+		p.ensure(barHeight + size*2)
+		x := p.alignedX(section.Align, float64(total)*module)
+		top := p.y - size/2
+		for i, w := range widths {
+			if i%2 == 0 {
+				p.canvas.Rect(x, top-barHeight, float64(w)*module, barHeight, true)
+			}
+			x += float64(w) * module
+		}
+		p.y = top - barHeight
+		p.text(documentCodeValue(section, data), size-1, false, firstNonEmpty(section.Align, "left"))
+		p.gap(size / 2)
+	case documentSectionText:
+		for _, line := range p.wrap(section.Text, size, p.contentWidth()) {
+			p.text(line, size, false, section.Align)
+		}
+	case documentSectionSignature:
+		p.ensure(size * 6)
+		p.gap(size * 4)
+		p.canvas.Line(p.margin, p.y, p.margin+180, p.y, 0.8)
+		p.text(p.label("signature"), size, false, "left")
+	case documentSectionFooter:
+		p.gap(size)
+		for _, line := range p.wrap(section.Text, size-1, p.contentWidth()) {
+			p.text(line, size-1, false, firstNonEmpty(section.Align, "center"))
+		}
+	}
+}
+
+func (p *documentPDF) alignedX(align string, width float64) float64 {
+	switch align {
+	case "center":
+		return p.margin + (p.contentWidth()-width)/2
+	case "right":
+		return p.canvas.Width - p.margin - width
+	}
+	return p.margin
+}
+
+// items draws the line table; numeric columns get fixed widths and the
+// description takes the rest. The header row repeats on every page.
+func (p *documentPDF) items(columns []string, data *documentData) {
+	size := p.fontSize
+	fixed := map[string]float64{"index": 24, "quantity": 48, "unit_price": 72, "discount": 64, "tax": 64, "total": 80}
+	widths := make([]float64, len(columns))
+	used := 0.0
+	for i, col := range columns {
+		widths[i] = fixed[col]
+		used += widths[i]
+	}
+	for i, col := range columns {
+		if col == "description" {
+			widths[i] = math.Max(p.contentWidth()-used, 80)
+		}
+	}
+	rowHeight := p.lineHeight(size) + 2
+
+	header := func() {
+		p.ensure(rowHeight * 2)
+		p.y -= rowHeight
+		p.canvas.SetColor(p.accent[0], p.accent[1], p.accent[2])
+		p.canvas.Rect(p.margin, p.y, p.contentWidth(), rowHeight, true)
+		p.canvas.SetColor(1, 1, 1)
+		x := p.margin
+		for i, col := range columns {
+			p.cell(p.label(col), x, widths[i], col, true)
+			x += widths[i]
+		}
+		p.canvas.SetColor(0, 0, 0)
+	}
+	header()
+	for idx, item := range data.Items {
+		detail := documentItemDetail(item, p.primaryLabel)
+		h := rowHeight
+		if detail != "" {
+			h += p.lineHeight(size - 1)
+		}
+		if p.ensure(h) {
+			header()
+		}
+		p.y -= rowHeight
+		x := p.margin
+		for i, col := range columns {
+			p.cell(documentItemCell(item, idx, col), x, widths[i], col, false)
+			if col == "description" && detail != "" {
+				p.canvas.Text(x+3, p.y+size*0.3-p.lineHeight(size-1), size-1, false, p.fit(detail, size-1, widths[i]-6, false))
+			}
+			x += widths[i]
+		}
+		if detail != "" {
+			p.y -= p.lineHeight(size - 1)
+		}
+		p.canvas.SetColor(0.8, 0.8, 0.8)
+		p.canvas.Line(p.margin, p.y, p.margin+p.contentWidth(), p.y, 0.5)
+		p.canvas.SetColor(0, 0, 0)
+	}
+}
+
+func (p *documentPDF) cell(text string, x, width float64, column string, bold bool) {
+	size := p.fontSize
+	text = p.fit(text, size, width-6, bold)
+	if column == "description" || column == "index" {
+		p.canvas.Text(x+3, p.y+size*0.3, size, bold, text)
+		return
+	}
+	p.canvas.Text(x+width-3-utils.PDFTextWidth(text, size, bold), p.y+size*0.3, size, bold, text)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestParseDocumentLayoutValidatesSections(t *testing.T) {
+	layout, err := parseDocumentLayout(models.JSONB{})
+	if err != nil || len(layout.Sections) != 0 {
+		t.Fatalf("expected empty layout to parse, got %v %v", layout, err)
+	}
+
+	bad := []models.JSONB{
+		{"sections": []interface{}{map[string]interface{}{"type": "watermark"}}},
+		{"sections": []interface{}{map[string]interface{}{"type": "items", "columns": []interface{}{"cost"}}}},
+		{"page": map[string]interface{}{"size": "A3"}},
+		{"style": map[string]interface{}{"accent_color": "red"}},
+		{"sections": "header"},
+	}
+	for _, raw := range bad {
+		if _, err := parseDocumentLayout(raw); err == nil {
+			t.Fatalf("expected layout %v to be rejected", raw)
+		}
+	}
+}
+
+func TestRenderDocumentHTMLUsesBilingualLabelsAndOverrides(t *testing.T) {
+	layout, err := parseDocumentLayout(models.JSONB{
+		"labels": map[string]interface{}{"title_sale": "Tax Invoice"},
+		"sections": []interface{}{
+			map[string]interface{}{"type": "title"},
+			map[string]interface{}{"type": "items", "columns": []interface{}{"description", "total"}},
+			map[string]interface{}{"type": "tax_breakdown"},
+			map[string]interface{}{"type": "amount_in_words"},
+			map[string]interface{}{"type": "qr"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("parseDocumentLayout returned error: %v", err)
+	}
+	data := sampleDocumentData(models.DocumentTypeSale)
+	data.Company = documentParty{Name: "Acme <Trading>"}
+	data.CurrencyName = "US Dollar"
+	labels := documentLabels{
+		overrides:     layout.Labels,
+		secondary:     map[string]string{"print.title_sale": "فاتورة ضريبية", "print.total": "المجموع"},
+		showSecondary: true,
+	}
+
+	out := string(renderDocumentHTML(layout, data, labels, "en"))
+	for _, want := range []string{
+		"Tax Invoice", "فاتورة ضريبية", "المجموع", "Sample product", "SN-0001",
+		"VAT", "One Hundred Fifty-Six and 40/100", "<svg",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected rendered HTML to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "Unit Price") {
+		t.Fatalf("expected only the configured item columns")
+	}
+}
+
+func TestRenderDocumentPDFPaginatesLongDocuments(t *testing.T) {
+	data := sampleDocumentData(models.DocumentTypeQuote)
+	data.Company = documentParty{Name: "Acme"}
+	for i := 0; i < 120; i++ {
+		data.Items = append(data.Items, documentItem{Description: "Line item", Quantity: 1, UnitPrice: 1, Total: 1})
+	}
+	labels := documentLabels{secondary: map[string]string{"print.total": "المجموع", "print.description": "Articulo"}, showSecondary: true}
+
+	pdf := renderDocumentPDF(&documentLayout{}, data, labels)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%EOF\n")) {
+		t.Fatalf("expected a complete PDF document")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 3")) {
+		t.Fatalf("expected the item table to run over three pages")
+	}
+	if !bytes.Contains(pdf, []byte("Description / Articulo")) {
+		t.Fatalf("expected Latin secondary labels in the PDF")
+	}
+	if bytes.Contains(pdf, []byte("Total / ")) {
+		t.Fatalf("expected labels the standard fonts cannot draw to be left out")
+	}
+}
+
+func TestFormatDocumentAmount(t *testing.T) {
+	cases := map[float64]string{1234567.891: "1,234,567.89", 0: "0.00", -12.5: "-12.50", 999.995: "1,000.00"}
+	for v, want := range cases {
+		if got := formatDocumentAmount(v); got != want {
+			t.Fatalf("formatDocumentAmount(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// DunningService issues reminders for overdue customer invoices according to
+// the company's dunning levels and keeps the customer dunning flag current.
+type DunningService struct {
+	db *sql.DB
+}
+
+type overdueInvoice struct {
+	SaleID       int
+	SaleNumber   string
+	CustomerID   int
+	CustomerName string
+	Email        *string
+	LocationID   int
+	SaleDate     time.Time
+	DaysOverdue  int
+	AmountDue    float64
+	LastLevel    int
+}
+
+func NewDunningService() *DunningService {
+	return &DunningService{db: database.GetDB()}
+}
+
+// dunningLevelFor returns the highest level reached after daysOverdue days.
+// Levels must be sorted by level number.
+func dunningLevelFor(levels []models.DunningLevel, daysOverdue int) *models.DunningLevel {
+	var reached *models.DunningLevel
+	for i := range levels {
+		if daysOverdue >= levels[i].DaysOverdue {
+			reached = &levels[i]
+		}
+	}
+	return reached
+}
+
+func dunningLateFee(level models.DunningLevel, amountDue float64) float64 {
+	return round2(level.LateFeeAmount + amountDue*level.LateFeePercent/100)
+}
+
+// dunningCreditBlockLevel returns the lowest level that blocks credit sales,
+// or 0 when no level does.
+func dunningCreditBlockLevel(levels []models.DunningLevel) int {
+	for _, level := range levels {
+		if level.BlockCredit {
+			return level.Level
+		}
+	}
+	return 0
+}
+
+// RunDunning raises the next reminder for every overdue invoice that has
+// crossed a new level, optionally emailing the customer and billing a late fee.
+func (s *DunningService) RunDunning(companyID, userID int, req *models.RunDunningRequest) (*models.DunningRunResult, error) {
+	settings, err := (&SettingsService{db: s.db}).GetDunningSettings(companyID)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled || len(settings.Levels) == 0 {
+		return nil, fmt.Errorf("dunning is not enabled")
+	}
+	asOf, err := parseAPDate(req.AsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.loadOverdueInvoices(companyID, asOf, req.CustomerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DunningRunResult{AsOf: asOf, DryRun: req.DryRun, Reminders: []models.DunningReminder{}}
+	for _, inv := range invoices {
+		level := dunningLevelFor(settings.Levels, inv.DaysOverdue)
+		if level == nil || level.Level <= inv.LastLevel {
+			continue
+		}
+		reminder := models.DunningReminder{
+			CompanyID:     companyID,
+			CustomerID:    inv.CustomerID,
+			CustomerName:  inv.CustomerName,
+			SaleID:        inv.SaleID,
+			SaleNumber:    inv.SaleNumber,
+			Level:         level.Level,
+			LevelName:     level.Name,
+			DaysOverdue:   inv.DaysOverdue,
+			AmountDue:     inv.AmountDue,
+			LateFeeAmount: dunningLateFee(*level, inv.AmountDue),
+			Channel:       models.DunningChannelNone,
+			Status:        models.DunningReminderRecorded,
+			CreatedBy:     userID,
+		}
+		if req.SendEmail {
+			reminder.Channel = models.DunningChannelEmail
+		}
+		if req.DryRun {
+			reminder.CreatedAt = time.Now()
+			result.Reminders = append(result.Reminders, reminder)
+			result.LateFeeTotal = round2(result.LateFeeTotal + reminder.LateFeeAmount)
+			continue
+		}
+
+		err := s.db.QueryRow(`
+			INSERT INTO dunning_reminders (
+				company_id, customer_id, sale_id, level, level_name, days_overdue, amount_due,
+				late_fee_amount, channel, status, created_by
+			)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+			ON CONFLICT (sale_id, level) DO NOTHING
+			RETURNING reminder_id, created_at
+		`, companyID, inv.CustomerID, inv.SaleID, level.Level, level.Name, inv.DaysOverdue, inv.AmountDue,
+			reminder.LateFeeAmount, reminder.Channel, reminder.Status, userID,
+		).Scan(&reminder.ReminderID, &reminder.CreatedAt)
+		if err == sql.ErrNoRows {
+			// Another run already issued this level.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to record dunning reminder: %w", err)
+		}
+
+		var failures []string
+		if reminder.LateFeeAmount > 0 {
+			feeSaleID, err := s.createLateFeeInvoice(companyID, userID, inv, *level, reminder.LateFeeAmount)
+			if err != nil {
+				failures = append(failures, "late fee: "+err.Error())
+			} else {
+				reminder.LateFeeSaleID = &feeSaleID
+				result.LateFeeTotal = round2(result.LateFeeTotal + reminder.LateFeeAmount)
+			}
+		}
+		if req.SendEmail {
+			if err := sendDunningEmail(inv, *level, reminder.LateFeeAmount); err != nil {
+				failures = append(failures, "email: "+err.Error())
+			} else {
+				reminder.Status = models.DunningReminderSent
+			}
+		}
+		if len(failures) > 0 {
+			msg := strings.Join(failures, "; ")
+			reminder.Status = models.DunningReminderFailed
+			reminder.ErrorMessage = &msg
+		}
+		if _, err := s.db.Exec(`
+			UPDATE dunning_reminders
+			SET late_fee_sale_id = $1, status = $2, error_message = $3
+			WHERE reminder_id = $4
+		`, reminder.LateFeeSaleID, reminder.Status, reminder.ErrorMessage, reminder.ReminderID); err != nil {
+			return nil, fmt.Errorf("failed to update dunning reminder: %w", err)
+		}
+		result.Reminders = append(result.Reminders, reminder)
+	}
+
+	if !req.DryRun {
+		updated, err := s.refreshCustomerStatus(companyID, settings.Levels, req.CustomerIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.CustomersUpdated = updated
+	}
+	return result, nil
+}
+
+func (s *DunningService) loadOverdueInvoices(companyID int, asOf time.Time, customerIDs []int) ([]overdueInvoice, error) {
+	if customerIDs == nil {
+		customerIDs = []int{}
+	}
+	rows, err := s.db.Query(`
+		SELECT s.sale_id, s.sale_number, c.customer_id, c.name, c.email, s.location_id, s.sale_date,
+		       ($2::date - (s.sale_date + COALESCE(c.payment_terms, 0))) AS days_overdue,
+		       (s.total_amount - s.paid_amount)::float8 AS amount_due,
+		       COALESCE((SELECT MAX(r.level) FROM dunning_reminders r WHERE r.sale_id = s.sale_id), 0) AS last_level
+		FROM sales s
+		JOIN customers c ON c.customer_id = s.customer_id
+		WHERE c.company_id = $1 AND c.is_deleted = FALSE
+		  AND s.is_deleted = FALSE
+		  AND COALESCE(s.is_training, FALSE) = FALSE
+		  AND s.status = 'COMPLETED'
+		  AND (s.total_amount - s.paid_amount) > 0.005
+		  AND (s.sale_date + COALESCE(c.payment_terms, 0)) < $2::date
+		  AND (cardinality($3::int[]) = 0 OR c.customer_id = ANY($3))
+		  AND NOT EXISTS (SELECT 1 FROM dunning_reminders f WHERE f.late_fee_sale_id = s.sale_id)
+		ORDER BY c.name, s.sale_date, s.sale_id
+	`, companyID, asOf, pq.Array(customerIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []overdueInvoice
+	for rows.Next() {
+		var inv overdueInvoice
+		var email sql.NullString
+		if err := rows.Scan(&inv.SaleID, &inv.SaleNumber, &inv.CustomerID, &inv.CustomerName, &email, &inv.LocationID,
+			&inv.SaleDate, &inv.DaysOverdue, &inv.AmountDue, &inv.LastLevel); err != nil {
+			return nil, fmt.Errorf("failed to scan overdue invoice: %w", err)
+		}
+		inv.Email = nullStringPtr(email)
+		invoices = append(invoices, inv)
+	}
+	return invoices, rows.Err()
+}
+
+// createLateFeeInvoice bills the late fee as an unpaid service line on a new
+// invoice, posted through the regular sales flow.
+func (s *DunningService) createLateFeeInvoice(companyID, userID int, inv overdueInvoice, level models.DunningLevel, fee float64) (int, error) {
+	label := fmt.Sprintf("Late fee - %s (%s)", inv.SaleNumber, level.Name)
+	notes := fmt.Sprintf("Dunning level %d late fee for invoice %s", level.Level, inv.SaleNumber)
+	idempotencyKey := fmt.Sprintf("dunning-fee-%d-%d", inv.SaleID, level.Level)
+	customerID := inv.CustomerID
+	sale, err := (&SalesService{db: s.db}).CreateSaleWithOptions(companyID, inv.LocationID, userID, &models.CreateSaleRequest{
+		CustomerID: &customerID,
+		Items: []models.CreateSaleDetailRequest{{
+			ProductName: &label,
+			Quantity:    1,
+			UnitPrice:   fee,
+		}},
+		Notes: &notes,
+	}, &idempotencyKey, CreateSaleOptions{SourceChannel: "INVOICE", SkipCustomerRewards: true})
+	if err != nil {
+		return 0, err
+	}
+	return sale.SaleID, nil
+}
+
+func sendDunningEmail(inv overdueInvoice, level models.DunningLevel, fee float64) error {
+	if inv.Email == nil || strings.TrimSpace(*inv.Email) == "" {
+		return fmt.Errorf("customer has no email address")
+	}
+	subject := strings.TrimSpace(level.EmailSubject)
+	if subject == "" {
+		subject = fmt.Sprintf("Payment reminder: invoice %s", inv.SaleNumber)
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "Dear %s,\n\n", inv.CustomerName)
+	if msg := strings.TrimSpace(level.EmailMessage); msg != "" {
+		body.WriteString(msg + "\n\n")
+	}
+	fmt.Fprintf(&body, "Invoice: %s\nInvoice date: %s\nAmount due: %.2f\nDays overdue: %d\n",
+		inv.SaleNumber, inv.SaleDate.Format("2006-01-02"), inv.AmountDue, inv.DaysOverdue)
+	if fee > 0 {
+		fmt.Fprintf(&body, "Late fee charged: %.2f\n", fee)
+	}
+	return utils.SendEmail(strings.TrimSpace(*inv.Email), subject, body.String())
+}
+
+// refreshCustomerStatus sets each customer's dunning level to the highest
+// reminder level still open on an unpaid invoice and derives the credit block
+// from it.
+func (s *DunningService) refreshCustomerStatus(companyID int, levels []models.DunningLevel, customerIDs []int) (int, error) {
+	if customerIDs == nil {
+		customerIDs = []int{}
+	}
+	res, err := s.db.Exec(`
+		WITH current_levels AS (
+			SELECT c.customer_id,
+			       COALESCE(MAX(r.level) FILTER (WHERE s.sale_id IS NOT NULL), 0) AS level
+			FROM customers c
+			LEFT JOIN dunning_reminders r ON r.customer_id = c.customer_id AND r.company_id = c.company_id
+			LEFT JOIN sales s ON s.sale_id = r.sale_id
+			                 AND s.is_deleted = FALSE
+			                 AND (s.total_amount - s.paid_amount) > 0.005
+			WHERE c.company_id = $1
+			  AND (cardinality($2::int[]) = 0 OR c.customer_id = ANY($2))
+			GROUP BY c.customer_id
+		)
+		UPDATE customers c
+		SET dunning_level = cl.level,
+		    dunning_credit_blocked = ($3 > 0 AND cl.level >= $3),
+		    dunning_updated_at = CURRENT_TIMESTAMP
+		FROM current_levels cl
+		WHERE c.customer_id = cl.customer_id
+		  AND (c.dunning_level <> cl.level OR c.dunning_credit_blocked <> ($3 > 0 AND cl.level >= $3))
+	`, companyID, pq.Array(customerIDs), dunningCreditBlockLevel(levels))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update customer dunning status: %w", err)
+	}
+	updated, _ := res.RowsAffected()
+	return int(updated), nil
+}
+
+// RefreshCustomerStatus recomputes a single customer's dunning flag, e.g.
+// after a collection settles overdue invoices.
+func (s *DunningService) RefreshCustomerStatus(companyID, customerID int) error {
+	settings, err := (&SettingsService{db: s.db}).GetDunningSettings(companyID)
+	if err != nil {
+		return err
+	}
+	_, err = s.refreshCustomerStatus(companyID, settings.Levels, []int{customerID})
+	return err
+}
+
+// ListReminders returns reminder history, optionally for one customer or invoice.
+func (s *DunningService) ListReminders(companyID int, filters map[string]string) ([]models.DunningReminder, error) {
+	query := `
+		SELECT r.reminder_id, r.company_id, r.customer_id, c.name, r.sale_id, s.sale_number, r.level, r.level_name,
+		       r.days_overdue, r.amount_due::float8, r.late_fee_amount::float8, r.late_fee_sale_id,
+		       r.channel, r.status, r.error_message, r.created_by, r.created_at
+		FROM dunning_reminders r
+		JOIN customers c ON c.customer_id = r.customer_id
+		JOIN sales s ON s.sale_id = r.sale_id
+		WHERE r.company_id = $1`
+	args := []interface{}{companyID}
+	for _, key := range []string{"customer_id", "sale_id"} {
+		value, ok := filters[key]
+		if !ok || value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s", key)
+		}
+		args = append(args, id)
+		query += fmt.Sprintf(" AND r.%s = $%d", key, len(args))
+	}
+	query += " ORDER BY r.created_at DESC, r.reminder_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dunning reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := make([]models.DunningReminder, 0)
+	for rows.Next() {
+		var r models.DunningReminder
+		var feeSaleID sql.NullInt64
+		var errorMessage sql.NullString
+		if err := rows.Scan(&r.ReminderID, &r.CompanyID, &r.CustomerID, &r.CustomerName, &r.SaleID, &r.SaleNumber, &r.Level, &r.LevelName,
+			&r.DaysOverdue, &r.AmountDue, &r.LateFeeAmount, &feeSaleID,
+			&r.Channel, &r.Status, &errorMessage, &r.CreatedBy, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dunning reminder: %w", err)
+		}
+		r.LateFeeSaleID = intPtrFromNullInt64(feeSaleID)
+		r.ErrorMessage = nullStringPtr(errorMessage)
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
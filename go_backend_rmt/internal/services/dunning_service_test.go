@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+var testDunningLevels = []models.DunningLevel{
+	{Level: 1, Name: "Friendly reminder", DaysOverdue: 7},
+	{Level: 2, Name: "Second notice", DaysOverdue: 30, LateFeeAmount: 5, LateFeePercent: 2},
+	{Level: 3, Name: "Final notice", DaysOverdue: 60, LateFeeAmount: 10, BlockCredit: true},
+}
+
+func TestDunningLevelFor(t *testing.T) {
+	if level := dunningLevelFor(testDunningLevels, 3); level != nil {
+		t.Fatalf("expected no level before the first threshold, got %d", level.Level)
+	}
+	if level := dunningLevelFor(testDunningLevels, 45); level == nil || level.Level != 2 {
+		t.Fatalf("expected level 2 at 45 days, got %+v", level)
+	}
+	if level := dunningLevelFor(testDunningLevels, 200); level == nil || level.Level != 3 {
+		t.Fatalf("expected level 3 at 200 days, got %+v", level)
+	}
+}
+
+func TestDunningLateFeeAndCreditBlock(t *testing.T) {
+	if fee := dunningLateFee(testDunningLevels[1], 250); fee != 10 {
+		t.Fatalf("expected fixed plus percentage fee of 10, got %v", fee)
+	}
+	if fee := dunningLateFee(testDunningLevels[0], 250); fee != 0 {
+		t.Fatalf("expected no fee, got %v", fee)
+	}
+	if level := dunningCreditBlockLevel(testDunningLevels); level != 3 {
+		t.Fatalf("expected credit block from level 3, got %d", level)
+	}
+}
+
+func TestBuildStatementEntries_OpeningAndRunningBalance(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	movements := []statementMovement{
+		{Date: day(1), EntryType: models.StatementEntryInvoice, Amount: 500},
+		{Date: day(5), EntryType: models.StatementEntryCollection, Amount: -200},
+		{Date: day(12), EntryType: models.StatementEntryInvoice, Amount: 150},
+		{Date: day(14), EntryType: models.StatementEntryReturn, Amount: -50},
+		{Date: day(20), EntryType: models.StatementEntryCollection, Amount: -100},
+	}
+
+	opening, entries, debits, credits := buildStatementEntries(movements, day(10))
+	if opening != 300 {
+		t.Fatalf("expected opening balance 300, got %v", opening)
+	}
+	if len(entries) != 3 || debits != 150 || credits != 150 {
+		t.Fatalf("expected 3 entries with 150/150, got %d entries %v/%v", len(entries), debits, credits)
+	}
+	if entries[0].Balance != 450 || entries[2].Balance != 300 {
+		t.Fatalf("unexpected running balance %v .. %v", entries[0].Balance, entries[2].Balance)
+	}
+}
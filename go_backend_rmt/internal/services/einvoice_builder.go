@@ -0,0 +1,380 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+// E-invoices follow the UBL 2.1 Invoice schema in the shape used by Gulf
+// e-invoicing regimes: credit notes are Invoice documents with type code 381
+// and a billing reference, every document carries its per-location counter
+// (ICV) and the hash of its predecessor (PIH), and a TLV QR code summarises
+// the seller, totals, hash and signature.
+//
+// The invoice hash is the SHA-256 of the document before it is stamped, i.e.
+// without the UBLExtensions signature block, the QR reference and the
+// cac:Signature element. The ECDSA signature is taken over that hash.
+
+const (
+	einvoiceTypeCodeInvoice    = "388"
+	einvoiceTypeCodeCreditNote = "381"
+
+	einvoiceStampedMarker = "<cac:AccountingSupplierParty>"
+	einvoiceQRReference   = "<cac:AdditionalDocumentReference><cbc:ID>QR</cbc:ID>"
+)
+
+// einvoiceGenesisHash is the previous-invoice hash of the first invoice in a
+// chain: the base64 of the hex SHA-256 of "0".
+var einvoiceGenesisHash = func() string {
+	sum := sha256.Sum256([]byte("0"))
+	return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(sum[:])))
+}()
+
+type einvoiceParty struct {
+	Name      string
+	TaxNumber string
+	Address   string
+}
+
+type einvoiceLine struct {
+	Name       string
+	Quantity   float64
+	Net        float64
+	TaxPercent float64
+	Tax        float64
+}
+
+// einvoiceDocument holds the amounts of an e-invoice as positive values;
+// refund invoices and credit notes are expressed by their type code.
+type einvoiceDocument struct {
+	DocumentType     string
+	Number           string
+	UUID             string
+	IssuedAt         time.Time
+	Counter          int64
+	PreviousHash     string
+	BillingReference string
+	Note             string
+	CurrencyCode     string
+	Seller           einvoiceParty
+	Buyer            *einvoiceParty
+	Lines            []einvoiceLine
+	LineExtension    float64
+	Allowance        float64
+	Tax              float64
+	Total            float64
+}
+
+func (d *einvoiceDocument) typeCode() string {
+	if d.DocumentType == models.EInvoiceTypeInvoice {
+		return einvoiceTypeCodeInvoice
+	}
+	return einvoiceTypeCodeCreditNote
+}
+
+// transactionCode marks standard (B2B) invoices, which name a buyer with a
+// tax number, apart from simplified ones.
+func (d *einvoiceDocument) transactionCode() string {
+	if d.Buyer != nil && d.Buyer.TaxNumber != "" {
+		return "0100000"
+	}
+	return "0200000"
+}
+
+func einvoiceText(v string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(v))
+	return sb.String()
+}
+
+func einvoiceAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', 2, 64)
+}
+
+func einvoiceTaxCategory(percent float64) string {
+	if percent > 0 {
+		return "S"
+	}
+	return "Z"
+}
+
+// buildEInvoiceXML renders the unsigned document the invoice hash is taken
+// over.
+func buildEInvoiceXML(doc *einvoiceDocument) string {
+	cur := einvoiceText(doc.CurrencyCode)
+	amount := func(tag string, v float64) string {
+		return fmt.Sprintf(`<cbc:%s currencyID="%s">%s</cbc:%s>`, tag, cur, einvoiceAmount(v), tag)
+	}
+	issued := doc.IssuedAt.UTC()
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"` +
+		` xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"` +
+		` xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"` +
+		` xmlns:ext="urn:oasis:names:specification:ubl:schema:xsd:CommonExtensionComponents-2">`)
+	b.WriteString(`<cbc:ProfileID>reporting:1.0</cbc:ProfileID>`)
+	fmt.Fprintf(&b, `<cbc:ID>%s</cbc:ID>`, einvoiceText(doc.Number))
+	fmt.Fprintf(&b, `<cbc:UUID>%s</cbc:UUID>`, doc.UUID)
+	fmt.Fprintf(&b, `<cbc:IssueDate>%s</cbc:IssueDate>`, issued.Format("2006-01-02"))
+	fmt.Fprintf(&b, `<cbc:IssueTime>%s</cbc:IssueTime>`, issued.Format("15:04:05"))
+	fmt.Fprintf(&b, `<cbc:InvoiceTypeCode name="%s">%s</cbc:InvoiceTypeCode>`, doc.transactionCode(), doc.typeCode())
+	if doc.Note != "" {
+		fmt.Fprintf(&b, `<cbc:Note>%s</cbc:Note>`, einvoiceText(doc.Note))
+	}
+	fmt.Fprintf(&b, `<cbc:DocumentCurrencyCode>%s</cbc:DocumentCurrencyCode>`, cur)
+	fmt.Fprintf(&b, `<cbc:TaxCurrencyCode>%s</cbc:TaxCurrencyCode>`, cur)
+	if doc.BillingReference != "" {
+		fmt.Fprintf(&b, `<cac:BillingReference><cac:InvoiceDocumentReference><cbc:ID>%s</cbc:ID></cac:InvoiceDocumentReference></cac:BillingReference>`, einvoiceText(doc.BillingReference))
+	}
+	fmt.Fprintf(&b, `<cac:AdditionalDocumentReference><cbc:ID>ICV</cbc:ID><cbc:UUID>%d</cbc:UUID></cac:AdditionalDocumentReference>`, doc.Counter)
+	fmt.Fprintf(&b, `<cac:AdditionalDocumentReference><cbc:ID>PIH</cbc:ID><cac:Attachment><cbc:EmbeddedDocumentBinaryObject mimeCode="text/plain">%s</cbc:EmbeddedDocumentBinaryObject></cac:Attachment></cac:AdditionalDocumentReference>`, doc.PreviousHash)
+
+	b.WriteString(einvoiceStampedMarker)
+	writeEInvoiceParty(&b, doc.Seller)
+	b.WriteString(`</cac:AccountingSupplierParty>`)
+	b.WriteString(`<cac:AccountingCustomerParty>`)
+	if doc.Buyer != nil {
+		writeEInvoiceParty(&b, *doc.Buyer)
+	}
+	b.WriteString(`</cac:AccountingCustomerParty>`)
+
+	if doc.Allowance != 0 {
+		b.WriteString(`<cac:AllowanceCharge><cbc:ChargeIndicator>false</cbc:ChargeIndicator><cbc:AllowanceChargeReason>discount</cbc:AllowanceChargeReason>`)
+		b.WriteString(amount("Amount", doc.Allowance))
+		b.WriteString(`</cac:AllowanceCharge>`)
+	}
+
+	// Tax subtotals group the lines by rate; the total comes from the source
+	// document so that it matches the books to the cent.
+	type taxGroup struct{ taxable, tax float64 }
+	groups := map[float64]*taxGroup{}
+	for _, line := range doc.Lines {
+		g := groups[line.TaxPercent]
+		if g == nil {
+			g = &taxGroup{}
+			groups[line.TaxPercent] = g
+		}
+		g.taxable += line.Net
+		g.tax += line.Tax
+	}
+	rates := make([]float64, 0, len(groups))
+	for rate := range groups {
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+	b.WriteString(`<cac:TaxTotal>` + amount("TaxAmount", doc.Tax))
+	for _, rate := range rates {
+		b.WriteString(`<cac:TaxSubtotal>`)
+		b.WriteString(amount("TaxableAmount", groups[rate].taxable))
+		b.WriteString(amount("TaxAmount", groups[rate].tax))
+		fmt.Fprintf(&b, `<cac:TaxCategory><cbc:ID>%s</cbc:ID><cbc:Percent>%s</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:TaxCategory>`, einvoiceTaxCategory(rate), einvoiceAmount(rate))
+		b.WriteString(`</cac:TaxSubtotal>`)
+	}
+	b.WriteString(`</cac:TaxTotal>`)
+
+	b.WriteString(`<cac:LegalMonetaryTotal>`)
+	b.WriteString(amount("LineExtensionAmount", doc.LineExtension))
+	b.WriteString(amount("TaxExclusiveAmount", doc.Total-doc.Tax))
+	b.WriteString(amount("TaxInclusiveAmount", doc.Total))
+	b.WriteString(amount("AllowanceTotalAmount", doc.Allowance))
+	b.WriteString(amount("PayableAmount", doc.Total))
+	b.WriteString(`</cac:LegalMonetaryTotal>`)
+
+	for i, line := range doc.Lines {
+		price := line.Net
+		if line.Quantity != 0 {
+			price = line.Net / line.Quantity
+		}
+		b.WriteString(`<cac:InvoiceLine>`)
+		fmt.Fprintf(&b, `<cbc:ID>%d</cbc:ID>`, i+1)
+		fmt.Fprintf(&b, `<cbc:InvoicedQuantity unitCode="PCE">%s</cbc:InvoicedQuantity>`, strconv.FormatFloat(line.Quantity, 'f', -1, 64))
+		b.WriteString(amount("LineExtensionAmount", line.Net))
+		b.WriteString(`<cac:TaxTotal>` + amount("TaxAmount", line.Tax) + amount("RoundingAmount", line.Net+line.Tax) + `</cac:TaxTotal>`)
+		fmt.Fprintf(&b, `<cac:Item><cbc:Name>%s</cbc:Name><cac:ClassifiedTaxCategory><cbc:ID>%s</cbc:ID><cbc:Percent>%s</cbc:Percent><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:ClassifiedTaxCategory></cac:Item>`,
+			einvoiceText(line.Name), einvoiceTaxCategory(line.TaxPercent), einvoiceAmount(line.TaxPercent))
+		b.WriteString(`<cac:Price>` + amount("PriceAmount", price) + `</cac:Price>`)
+		b.WriteString(`</cac:InvoiceLine>`)
+	}
+	b.WriteString(`</Invoice>`)
+	return b.String()
+}
+
+func writeEInvoiceParty(b *strings.Builder, p einvoiceParty) {
+	b.WriteString(`<cac:Party>`)
+	if p.Address != "" {
+		fmt.Fprintf(b, `<cac:PostalAddress><cbc:StreetName>%s</cbc:StreetName></cac:PostalAddress>`, einvoiceText(p.Address))
+	}
+	if p.TaxNumber != "" {
+		fmt.Fprintf(b, `<cac:PartyTaxScheme><cbc:CompanyID>%s</cbc:CompanyID><cac:TaxScheme><cbc:ID>VAT</cbc:ID></cac:TaxScheme></cac:PartyTaxScheme>`, einvoiceText(p.TaxNumber))
+	}
+	fmt.Fprintf(b, `<cac:PartyLegalEntity><cbc:RegistrationName>%s</cbc:RegistrationName></cac:PartyLegalEntity>`, einvoiceText(p.Name))
+	b.WriteString(`</cac:Party>`)
+}
+
+func einvoiceHash(unsigned string) string {
+	sum := sha256.Sum256([]byte(unsigned))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// stampEInvoiceXML adds the signature extension, the QR reference and the
+// cac:Signature element to an unsigned document.
+func stampEInvoiceXML(unsigned, hash, signature, qr string, keyID int) string {
+	extensions := `<ext:UBLExtensions><ext:UBLExtension>` +
+		`<ext:ExtensionURI>urn:oasis:names:specification:ubl:dsig:enveloped:xades</ext:ExtensionURI>` +
+		`<ext:ExtensionContent><ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Id="signature">` +
+		`<ds:SignedInfo><ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"/>` +
+		`<ds:Reference Id="invoiceSignedData" URI=""><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		`<ds:DigestValue>` + hash + `</ds:DigestValue></ds:Reference></ds:SignedInfo>` +
+		`<ds:SignatureValue>` + signature + `</ds:SignatureValue>` +
+		`<ds:KeyInfo><ds:KeyName>` + strconv.Itoa(keyID) + `</ds:KeyName></ds:KeyInfo>` +
+		`</ds:Signature></ext:ExtensionContent></ext:UBLExtension></ext:UBLExtensions>`
+	references := einvoiceQRReference +
+		`<cac:Attachment><cbc:EmbeddedDocumentBinaryObject mimeCode="text/plain">` + qr + `</cbc:EmbeddedDocumentBinaryObject></cac:Attachment></cac:AdditionalDocumentReference>` +
+		`<cac:Signature><cbc:ID>urn:oasis:names:specification:ubl:signature:Invoice</cbc:ID>` +
+		`<cbc:SignatureMethod>urn:oasis:names:specification:ubl:dsig:enveloped:xades</cbc:SignatureMethod></cac:Signature>`
+
+	rootEnd := strings.Index(unsigned, "<cbc:ProfileID>")
+	marker := strings.Index(unsigned, einvoiceStampedMarker)
+	return unsigned[:rootEnd] + extensions + unsigned[rootEnd:marker] + references + unsigned[marker:]
+}
+
+// unstampEInvoiceXML reverses stampEInvoiceXML so that a stored document can
+// be re-hashed.
+func unstampEInvoiceXML(stamped string) (string, error) {
+	extStart := strings.Index(stamped, "<ext:UBLExtensions>")
+	extEnd := strings.Index(stamped, "</ext:UBLExtensions>")
+	refStart := strings.Index(stamped, einvoiceQRReference)
+	refEnd := strings.Index(stamped, "</cac:Signature>")
+	if extStart < 0 || extEnd < extStart || refStart < extEnd || refEnd < refStart {
+		return "", fmt.Errorf("e-invoice document is not stamped")
+	}
+	extEnd += len("</ext:UBLExtensions>")
+	refEnd += len("</cac:Signature>")
+	return stamped[:extStart] + stamped[extEnd:refStart] + stamped[refEnd:], nil
+}
+
+// einvoiceTLV encodes tag-length-value fields with one-byte tags and lengths,
+// as the QR payload requires.
+func einvoiceTLV(fields [][]byte) ([]byte, error) {
+	var out []byte
+	for i, value := range fields {
+		if len(value) > 255 {
+			return nil, fmt.Errorf("e-invoice QR field %d is too long", i+1)
+		}
+		out = append(out, byte(i+1), byte(len(value)))
+		out = append(out, value...)
+	}
+	return out, nil
+}
+
+// buildEInvoiceQR returns the base64 TLV payload: seller name, seller tax
+// number, timestamp, total with tax, tax total, invoice hash, signature and
+// public key.
+func buildEInvoiceQR(doc *einvoiceDocument, hash, signature string, publicKeyDER []byte) (string, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return "", fmt.Errorf("invalid e-invoice signature: %w", err)
+	}
+	tlv, err := einvoiceTLV([][]byte{
+		[]byte(doc.Seller.Name),
+		[]byte(doc.Seller.TaxNumber),
+		[]byte(doc.IssuedAt.UTC().Format(time.RFC3339)),
+		[]byte(einvoiceAmount(doc.Total)),
+		[]byte(einvoiceAmount(doc.Tax)),
+		[]byte(hash),
+		sig,
+		publicKeyDER,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(tlv), nil
+}
+
+func generateEInvoiceKey() (privatePEM, publicPEM string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate e-invoice key: %w", err)
+	}
+	privDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode e-invoice key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode e-invoice public key: %w", err)
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privDER}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	return privatePEM, publicPEM, nil
+}
+
+func parseEInvoicePrivateKey(privatePEM string) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid e-invoice private key")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid e-invoice private key: %w", err)
+	}
+	return key, nil
+}
+
+func parseEInvoicePublicKey(publicPEM string) (*ecdsa.PublicKey, []byte, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid e-invoice public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid e-invoice public key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("e-invoice public key is not an ECDSA key")
+	}
+	return key, block.Bytes, nil
+}
+
+// signEInvoiceHash signs the raw SHA-256 digest behind a base64 invoice hash
+// and returns the ASN.1 signature in base64.
+func signEInvoiceHash(key *ecdsa.PrivateKey, hash string) (string, error) {
+	digest, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return "", fmt.Errorf("invalid e-invoice hash: %w", err)
+	}
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign e-invoice: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+func verifyEInvoiceSignature(key *ecdsa.PublicKey, hash, signature string) bool {
+	digest, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ecdsa.VerifyASN1(key, digest, sig)
+}
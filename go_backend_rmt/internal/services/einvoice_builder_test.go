@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+func sampleEInvoiceDocument() *einvoiceDocument {
+	return &einvoiceDocument{
+		DocumentType:  models.EInvoiceTypeInvoice,
+		Number:        "INV-0001",
+		UUID:          "3cf5ee18-ee25-44ea-a444-2c37ba7f28be",
+		IssuedAt:      time.Date(2026, 4, 16, 9, 30, 0, 0, time.UTC),
+		Counter:       1,
+		PreviousHash:  einvoiceGenesisHash,
+		CurrencyCode:  "SAR",
+		Seller:        einvoiceParty{Name: "Acme & Sons", TaxNumber: "300000000000003", Address: "King Fahd Rd"},
+		Lines:         []einvoiceLine{{Name: "Widget <large>", Quantity: 2, Net: 100, TaxPercent: 15, Tax: 15}, {Name: "Bread", Quantity: 1, Net: 5}},
+		LineExtension: 105,
+		Allowance:     5,
+		Tax:           15,
+		Total:         115,
+	}
+}
+
+func decodeEInvoiceTLV(t *testing.T, payload string) map[byte][]byte {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatalf("qr payload is not base64: %v", err)
+	}
+	fields := map[byte][]byte{}
+	for len(raw) > 0 {
+		if len(raw) < 2 || len(raw) < 2+int(raw[1]) {
+			t.Fatalf("truncated TLV field")
+		}
+		fields[raw[0]] = raw[2 : 2+int(raw[1])]
+		raw = raw[2+int(raw[1]):]
+	}
+	return fields
+}
+
+func TestEInvoiceGenesisHash(t *testing.T) {
+	want := "NWZlY2ViNjZmZmM4NmYzOGQ5NTI3ODZjNmQ2OTZjNzljMmRiYzIzOWRkNGU5MWI0NjcyOWQ3M2EyN2ZiNTdlOQ=="
+	if einvoiceGenesisHash != want {
+		t.Fatalf("genesis hash = %s, want %s", einvoiceGenesisHash, want)
+	}
+}
+
+func TestEInvoiceSignStampAndVerify(t *testing.T) {
+	privatePEM, publicPEM, err := generateEInvoiceKey()
+	if err != nil {
+		t.Fatalf("generateEInvoiceKey returned error: %v", err)
+	}
+	privateKey, err := parseEInvoicePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("parseEInvoicePrivateKey returned error: %v", err)
+	}
+	publicKey, publicDER, err := parseEInvoicePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("parseEInvoicePublicKey returned error: %v", err)
+	}
+
+	doc := sampleEInvoiceDocument()
+	unsigned := buildEInvoiceXML(doc)
+	hash := einvoiceHash(unsigned)
+	signature, err := signEInvoiceHash(privateKey, hash)
+	if err != nil {
+		t.Fatalf("signEInvoiceHash returned error: %v", err)
+	}
+	qr, err := buildEInvoiceQR(doc, hash, signature, publicDER)
+	if err != nil {
+		t.Fatalf("buildEInvoiceQR returned error: %v", err)
+	}
+	stamped := stampEInvoiceXML(unsigned, hash, signature, qr, 7)
+
+	decoder := xml.NewDecoder(strings.NewReader(stamped))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("stamped document is not well-formed: %v", err)
+		}
+	}
+	for _, want := range []string{
+		`<cbc:InvoiceTypeCode name="0200000">388</cbc:InvoiceTypeCode>`,
+		`Acme &amp; Sons`, `Widget &lt;large&gt;`,
+		`<cbc:TaxExclusiveAmount currencyID="SAR">100.00</cbc:TaxExclusiveAmount>`,
+		`<cbc:ID>ICV</cbc:ID><cbc:UUID>1</cbc:UUID>`,
+		`<cbc:Percent>15.00</cbc:Percent>`, `<cbc:ID>Z</cbc:ID>`,
+		"<ds:DigestValue>" + hash + "</ds:DigestValue>",
+	} {
+		if !strings.Contains(stamped, want) {
+			t.Fatalf("expected stamped document to contain %q", want)
+		}
+	}
+
+	restored, err := unstampEInvoiceXML(stamped)
+	if err != nil || restored != unsigned {
+		t.Fatalf("unstamping did not restore the hashed document: %v", err)
+	}
+
+	fields := decodeEInvoiceTLV(t, qr)
+	if string(fields[1]) != "Acme & Sons" || string(fields[2]) != "300000000000003" ||
+		string(fields[3]) != "2026-04-16T09:30:00Z" || string(fields[4]) != "115.00" ||
+		string(fields[5]) != "15.00" || string(fields[6]) != hash {
+		t.Fatalf("unexpected QR fields: %q", fields)
+	}
+	if base64.StdEncoding.EncodeToString(fields[7]) != signature || string(fields[8]) != string(publicDER) {
+		t.Fatalf("expected QR to carry the signature and public key")
+	}
+
+	inv := &models.EInvoice{Counter: 1, PreviousHash: einvoiceGenesisHash, InvoiceHash: hash, Signature: signature, XML: stamped}
+	if problems := verifyEInvoice(inv, 1, einvoiceGenesisHash, publicPEM); len(problems) != 0 {
+		t.Fatalf("expected a valid invoice, got %v", problems)
+	}
+	if !verifyEInvoiceSignature(publicKey, hash, signature) {
+		t.Fatalf("expected signature to verify")
+	}
+
+	inv.XML = strings.Replace(stamped, "115.00", "15.00", 1)
+	if problems := verifyEInvoice(inv, 2, "other", publicPEM); len(problems) != 3 {
+		t.Fatalf("expected counter, link and hash problems, got %v", problems)
+	}
+}
+
+func TestEInvoiceCreditNoteReferencesOriginal(t *testing.T) {
+	doc := sampleEInvoiceDocument()
+	doc.DocumentType = models.EInvoiceTypeCreditNote
+	doc.BillingReference = "INV-0001"
+	doc.Note = "Damaged"
+	doc.Buyer = &einvoiceParty{Name: "Buyer LLC", TaxNumber: "310000000000003"}
+
+	out := buildEInvoiceXML(doc)
+	for _, want := range []string{
+		`<cbc:InvoiceTypeCode name="0100000">381</cbc:InvoiceTypeCode>`,
+		`<cac:BillingReference><cac:InvoiceDocumentReference><cbc:ID>INV-0001</cbc:ID>`,
+		`<cbc:Note>Damaged</cbc:Note>`,
+		`<cbc:RegistrationName>Buyer LLC</cbc:RegistrationName>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected credit note to contain %q", want)
+		}
+	}
+}
+
+func TestEInvoiceTLVRejectsLongFields(t *testing.T) {
+	if _, err := einvoiceTLV([][]byte{make([]byte, 256)}); err == nil {
+		t.Fatalf("expected a field over 255 bytes to be rejected")
+	}
+}
@@ -0,0 +1,740 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/config"
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	einvoiceSourceSale       = "sale"
+	einvoiceSourceSaleReturn = "sale_return"
+)
+
+// EInvoiceSubmissionResult is a tax authority's answer to a submission.
+type EInvoiceSubmissionResult struct {
+	Status    string
+	Reference string
+	Message   string
+}
+
+// EInvoiceSubmitter hands signed e-invoices to a tax authority. Clearance or
+// reporting APIs plug in here; generation and chaining do not depend on it.
+type EInvoiceSubmitter interface {
+	Submit(inv *models.EInvoice) (*EInvoiceSubmissionResult, error)
+}
+
+// localEInvoiceSubmitter accepts every well-formed document without
+// contacting an authority.
+type localEInvoiceSubmitter struct{}
+
+func (localEInvoiceSubmitter) Submit(inv *models.EInvoice) (*EInvoiceSubmissionResult, error) {
+	decoder := xml.NewDecoder(strings.NewReader(inv.XML))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			return &EInvoiceSubmissionResult{Status: models.EInvoiceSubmissionRejected, Message: fmt.Sprintf("malformed document: %v", err)}, nil
+		}
+	}
+	return &EInvoiceSubmissionResult{Status: models.EInvoiceSubmissionAccepted, Reference: "LOCAL-" + inv.UUID}, nil
+}
+
+type EInvoiceService struct {
+	db        *sql.DB
+	submitter EInvoiceSubmitter
+}
+
+func NewEInvoiceService() *EInvoiceService {
+	return &EInvoiceService{db: database.GetDB(), submitter: localEInvoiceSubmitter{}}
+}
+
+// enqueueEInvoiceTx schedules e-invoice issuance for a finalized document
+// when the company has e-invoicing enabled. It runs through the finance
+// outbox so that failed issuance is retried with the ledger postings.
+func enqueueEInvoiceTx(tx *sql.Tx, finance *FinanceIntegrityService, companyID, locationID int, sourceType string, sourceID, userID int) error {
+	cfg, err := loadCompanyTaxSettings(tx, companyID)
+	if err != nil {
+		return err
+	}
+	if !cfg.EInvoicingEnabled {
+		return nil
+	}
+	if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
+		CompanyID:     companyID,
+		LocationID:    &locationID,
+		EventType:     financeEventEInvoiceIssue,
+		AggregateType: sourceType,
+		AggregateID:   sourceID,
+		Payload:       models.JSONB{},
+		CreatedBy:     &userID,
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue e-invoice: %w", err)
+	}
+	return nil
+}
+
+// IssueEInvoice generates, chains and signs the e-invoice for a sale or sale
+// return. Issuing twice returns the existing invoice.
+func (s *EInvoiceService) IssueEInvoice(companyID int, sourceType string, sourceID int) (*models.EInvoice, error) {
+	if existing, err := s.getEInvoiceBySource(companyID, sourceType, sourceID); err != nil || existing != nil {
+		return existing, err
+	}
+
+	var doc *einvoiceDocument
+	var locationID, saleID int
+	var err error
+	switch sourceType {
+	case einvoiceSourceSale:
+		doc, locationID, err = s.saleEInvoiceDocument(companyID, sourceID)
+		saleID = sourceID
+	case einvoiceSourceSaleReturn:
+		doc, locationID, saleID, err = s.saleReturnEInvoiceDocument(companyID, sourceID)
+	default:
+		return nil, fmt.Errorf("unsupported e-invoice source %s", sourceType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applySeller(companyID, doc); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start e-invoice transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO einvoice_chain_heads (location_id, company_id) VALUES ($1, $2)
+		ON CONFLICT (location_id) DO NOTHING
+	`, locationID, companyID); err != nil {
+		return nil, fmt.Errorf("failed to initialise e-invoice chain: %w", err)
+	}
+	var lastCounter int64
+	var lastHash sql.NullString
+	if err := tx.QueryRow(`
+		SELECT last_counter, last_hash FROM einvoice_chain_heads WHERE location_id = $1 FOR UPDATE
+	`, locationID).Scan(&lastCounter, &lastHash); err != nil {
+		return nil, fmt.Errorf("failed to lock e-invoice chain: %w", err)
+	}
+	// A concurrent worker may have issued the invoice while we waited.
+	var issuedID int
+	err = tx.QueryRow(`SELECT einvoice_id FROM einvoices WHERE source_type = $1 AND source_id = $2`, sourceType, sourceID).Scan(&issuedID)
+	if err == nil {
+		tx.Rollback()
+		return s.GetEInvoice(companyID, issuedID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check e-invoice: %w", err)
+	}
+
+	keyID, privateKey, publicPEM, err := s.activeSigningKeyTx(tx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	_, publicDER, err := parseEInvoicePublicKey(publicPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.UUID = uuid.NewString()
+	doc.IssuedAt = time.Now().UTC().Truncate(time.Second)
+	doc.Counter = lastCounter + 1
+	doc.PreviousHash = einvoiceGenesisHash
+	if lastHash.Valid && lastHash.String != "" {
+		doc.PreviousHash = lastHash.String
+	}
+
+	unsigned := buildEInvoiceXML(doc)
+	hash := einvoiceHash(unsigned)
+	signature, err := signEInvoiceHash(privateKey, hash)
+	if err != nil {
+		return nil, err
+	}
+	qr, err := buildEInvoiceQR(doc, hash, signature, publicDER)
+	if err != nil {
+		return nil, err
+	}
+	stamped := stampEInvoiceXML(unsigned, hash, signature, qr, keyID)
+
+	var einvoiceID int
+	err = tx.QueryRow(`
+		INSERT INTO einvoices (
+			company_id, location_id, source_type, source_id, sale_id, document_type, invoice_number,
+			uuid, counter, previous_hash, invoice_hash, signature, key_id, qr_payload, xml,
+			total_amount, tax_amount, issued_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING einvoice_id
+	`, companyID, locationID, sourceType, sourceID, saleID, doc.DocumentType, doc.Number,
+		doc.UUID, doc.Counter, doc.PreviousHash, hash, signature, keyID, qr, stamped,
+		round2(doc.Total), round2(doc.Tax), doc.IssuedAt).Scan(&einvoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store e-invoice: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE einvoice_chain_heads
+		SET last_counter = $2, last_hash = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE location_id = $1
+	`, locationID, doc.Counter, hash); err != nil {
+		return nil, fmt.Errorf("failed to advance e-invoice chain: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit e-invoice: %w", err)
+	}
+
+	inv, err := s.SubmitEInvoice(companyID, einvoiceID)
+	if err != nil {
+		log.Printf("einvoice_service: failed to submit e-invoice %d: %v", einvoiceID, err)
+		return s.GetEInvoice(companyID, einvoiceID)
+	}
+	return inv, nil
+}
+
+// IssueEInvoiceOnRequest issues an e-invoice outside the finance outbox,
+// provided e-invoicing is enabled for the company.
+func (s *EInvoiceService) IssueEInvoiceOnRequest(companyID int, req *models.IssueEInvoiceRequest) (*models.EInvoice, error) {
+	cfg, err := loadCompanyTaxSettings(s.db, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.EInvoicingEnabled {
+		return nil, fmt.Errorf("e-invoicing is not enabled")
+	}
+	return s.IssueEInvoice(companyID, req.SourceType, req.SourceID)
+}
+
+// SubmitEInvoice sends an invoice to the configured submitter and records
+// the outcome. Accepted invoices are not sent again.
+func (s *EInvoiceService) SubmitEInvoice(companyID, einvoiceID int) (*models.EInvoice, error) {
+	inv, err := s.GetEInvoice(companyID, einvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if inv.SubmissionStatus == models.EInvoiceSubmissionAccepted {
+		return inv, nil
+	}
+	submitter := s.submitter
+	if submitter == nil {
+		submitter = localEInvoiceSubmitter{}
+	}
+
+	status, reference, message := models.EInvoiceSubmissionFailed, "", ""
+	result, submitErr := submitter.Submit(inv)
+	if submitErr != nil {
+		message = submitErr.Error()
+	} else {
+		status, reference, message = result.Status, result.Reference, result.Message
+	}
+	if _, err := s.db.Exec(`
+		UPDATE einvoices
+		SET submission_status = $3,
+		    submission_reference = NULLIF($4, ''),
+		    submission_error = NULLIF($5, ''),
+		    submitted_at = CURRENT_TIMESTAMP
+		WHERE einvoice_id = $1 AND company_id = $2
+	`, einvoiceID, companyID, status, reference, message); err != nil {
+		return nil, fmt.Errorf("failed to record e-invoice submission: %w", err)
+	}
+	return s.GetEInvoice(companyID, einvoiceID)
+}
+
+// saleEInvoiceDocument builds the document for a completed sale; sales with
+// a refund source or a negative total become refund invoices.
+func (s *EInvoiceService) saleEInvoiceDocument(companyID, saleID int) (*einvoiceDocument, int, error) {
+	var doc einvoiceDocument
+	var locationID int
+	var customerID sql.NullInt64
+	var sourceNumber, notes sql.NullString
+	var refundSourceID sql.NullInt64
+	var status string
+	var isTraining bool
+	err := s.db.QueryRow(`
+		SELECT s.sale_number, s.location_id, s.customer_id, s.refund_source_sale_id, rs.sale_number,
+		       s.subtotal::float8, s.tax_amount::float8, s.discount_amount::float8, s.total_amount::float8,
+		       s.notes, s.status, COALESCE(s.is_training, FALSE)
+		FROM sales s
+		JOIN locations l ON l.location_id = s.location_id
+		LEFT JOIN sales rs ON rs.sale_id = s.refund_source_sale_id
+		WHERE s.sale_id = $1 AND l.company_id = $2 AND s.is_deleted = FALSE
+	`, saleID, companyID).Scan(&doc.Number, &locationID, &customerID, &refundSourceID, &sourceNumber,
+		&doc.LineExtension, &doc.Tax, &doc.Allowance, &doc.Total, &notes, &status, &isTraining)
+	if err == sql.ErrNoRows {
+		return nil, 0, fmt.Errorf("sale not found")
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get sale: %w", err)
+	}
+	if status != "COMPLETED" || isTraining {
+		return nil, 0, fmt.Errorf("only completed sales can be e-invoiced")
+	}
+
+	doc.DocumentType = models.EInvoiceTypeInvoice
+	if refundSourceID.Valid || doc.Total < 0 {
+		doc.DocumentType = models.EInvoiceTypeRefundInvoice
+		doc.BillingReference = sourceNumber.String
+		doc.Note = notes.String
+	}
+	doc.LineExtension, doc.Tax = math.Abs(doc.LineExtension), math.Abs(doc.Tax)
+	doc.Allowance, doc.Total = math.Abs(doc.Allowance), math.Abs(doc.Total)
+
+	rows, err := s.db.Query(`
+		SELECT COALESCE(sd.product_name, p.name, cp.name, ''), sd.quantity::float8, sd.line_total::float8,
+		       COALESCE(sd.tax_amount, 0)::float8, COALESCE(t.percentage, 0)::float8
+		FROM sale_details sd
+		LEFT JOIN products p ON p.product_id = sd.product_id
+		LEFT JOIN combo_products cp ON cp.combo_product_id = sd.combo_product_id
+		LEFT JOIN taxes t ON t.tax_id = sd.tax_id
+		WHERE sd.sale_id = $1
+		ORDER BY sd.sale_detail_id
+	`, saleID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get sale items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line einvoiceLine
+		if err := rows.Scan(&line.Name, &line.Quantity, &line.Net, &line.Tax, &line.TaxPercent); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan sale item: %w", err)
+		}
+		line.Quantity, line.Net, line.Tax = math.Abs(line.Quantity), math.Abs(line.Net), math.Abs(line.Tax)
+		doc.Lines = append(doc.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get sale items: %w", err)
+	}
+
+	if err := s.applyBuyer(companyID, customerID, &doc); err != nil {
+		return nil, 0, err
+	}
+	return &doc, locationID, nil
+}
+
+// saleReturnEInvoiceDocument builds the credit note for a completed sale
+// return. Amounts follow the ledger: the return total includes the tax
+// recorded on its lines.
+func (s *EInvoiceService) saleReturnEInvoiceDocument(companyID, returnID int) (*einvoiceDocument, int, int, error) {
+	doc := einvoiceDocument{DocumentType: models.EInvoiceTypeCreditNote}
+	var locationID, saleID int
+	var customerID sql.NullInt64
+	var reason sql.NullString
+	var status string
+	var isTraining bool
+	err := s.db.QueryRow(`
+		SELECT sr.return_number, sr.location_id, sr.sale_id, s.sale_number, sr.customer_id,
+		       sr.total_amount::float8, sr.reason, COALESCE(sr.status, 'COMPLETED'), COALESCE(s.is_training, FALSE)
+		FROM sale_returns sr
+		JOIN locations l ON l.location_id = sr.location_id
+		JOIN sales s ON s.sale_id = sr.sale_id
+		WHERE sr.return_id = $1 AND l.company_id = $2 AND sr.is_deleted = FALSE
+	`, returnID, companyID).Scan(&doc.Number, &locationID, &saleID, &doc.BillingReference, &customerID,
+		&doc.Total, &reason, &status, &isTraining)
+	if err == sql.ErrNoRows {
+		return nil, 0, 0, fmt.Errorf("sale return not found")
+	}
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get sale return: %w", err)
+	}
+	if status != "COMPLETED" || isTraining {
+		return nil, 0, 0, fmt.Errorf("only completed sale returns can be e-invoiced")
+	}
+	doc.Note = reason.String
+
+	rows, err := s.db.Query(`
+		SELECT COALESCE(sd.product_name, p.name, ''), srd.quantity::float8,
+		       (srd.line_total - COALESCE(srd.tax_amount, 0))::float8,
+		       COALESCE(srd.tax_amount, 0)::float8, COALESCE(t.percentage, 0)::float8
+		FROM sale_return_details srd
+		LEFT JOIN sale_details sd ON sd.sale_detail_id = srd.sale_detail_id
+		LEFT JOIN products p ON p.product_id = srd.product_id
+		LEFT JOIN taxes t ON t.tax_id = sd.tax_id
+		WHERE srd.return_id = $1
+		ORDER BY srd.return_detail_id
+	`, returnID)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get sale return items: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line einvoiceLine
+		if err := rows.Scan(&line.Name, &line.Quantity, &line.Net, &line.Tax, &line.TaxPercent); err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to scan sale return item: %w", err)
+		}
+		doc.Tax += line.Tax
+		doc.Lines = append(doc.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to get sale return items: %w", err)
+	}
+	doc.LineExtension = doc.Total - doc.Tax
+
+	if err := s.applyBuyer(companyID, customerID, &doc); err != nil {
+		return nil, 0, 0, err
+	}
+	return &doc, locationID, saleID, nil
+}
+
+func (s *EInvoiceService) applySeller(companyID int, doc *einvoiceDocument) error {
+	company, err := (&CompanyService{db: s.db}).GetCompanyByID(companyID)
+	if err != nil {
+		return err
+	}
+	doc.Seller = einvoiceParty{Name: company.Name, TaxNumber: ptrString(company.TaxNumber), Address: ptrString(company.Address)}
+	err = s.db.QueryRow(`
+		SELECT code FROM currencies
+		WHERE currency_id = COALESCE($1, (SELECT currency_id FROM currencies WHERE is_base_currency = TRUE ORDER BY currency_id LIMIT 1))
+	`, company.CurrencyID).Scan(&doc.CurrencyCode)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get company currency: %w", err)
+	}
+	if doc.CurrencyCode == "" {
+		return fmt.Errorf("company currency is required for e-invoicing")
+	}
+	return nil
+}
+
+func (s *EInvoiceService) applyBuyer(companyID int, customerID sql.NullInt64, doc *einvoiceDocument) error {
+	if !customerID.Valid {
+		return nil
+	}
+	id := int(customerID.Int64)
+	party, err := (&DocumentRenderService{db: s.db}).customerParty(companyID, &id)
+	if err != nil || party == nil {
+		return err
+	}
+	doc.Buyer = &einvoiceParty{Name: party.Name, TaxNumber: party.TaxNumber, Address: party.Address}
+	return nil
+}
+
+// einvoiceKeySecret seals signing keys at rest. Like storageSigningKey it
+// falls back to the JWT secret; production requires its own secret.
+func einvoiceKeySecret() string {
+	cfg := config.Load()
+	if secret := strings.TrimSpace(cfg.EInvoiceKeySecret); secret != "" {
+		return secret
+	}
+	return cfg.JWTSecret
+}
+
+// activeSigningKeyTx returns the company's active signing key, generating
+// the first one on demand. It is the only reader of the sealed private key;
+// keys stored in clear before sealing are sealed here on first use.
+func (s *EInvoiceService) activeSigningKeyTx(tx *sql.Tx, companyID int) (int, *ecdsa.PrivateKey, string, error) {
+	var keyID int
+	var stored, publicPEM string
+	err := tx.QueryRow(`
+		SELECT key_id, private_key_sealed, public_key_pem FROM einvoice_signing_keys
+		WHERE company_id = $1 AND is_active = TRUE
+	`, companyID).Scan(&keyID, &stored, &publicPEM)
+	if err == sql.ErrNoRows {
+		var privatePEM string
+		if keyID, privatePEM, publicPEM, err = s.createSigningKeyTx(tx, companyID, nil); err != nil {
+			return 0, nil, "", err
+		}
+		key, err := parseEInvoicePrivateKey(privatePEM)
+		return keyID, key, publicPEM, err
+	}
+	if err != nil {
+		return 0, nil, "", fmt.Errorf("failed to get e-invoice signing key: %w", err)
+	}
+
+	secret := einvoiceKeySecret()
+	privatePEM := stored
+	if utils.IsSealedSecret(stored) {
+		if privatePEM, err = utils.OpenSecret(secret, stored); err != nil {
+			return 0, nil, "", fmt.Errorf("failed to unseal e-invoice signing key: %w", err)
+		}
+	} else {
+		sealed, err := utils.SealSecret(secret, privatePEM)
+		if err != nil {
+			return 0, nil, "", fmt.Errorf("failed to seal e-invoice signing key: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE einvoice_signing_keys SET private_key_sealed = $1 WHERE key_id = $2`, sealed, keyID); err != nil {
+			return 0, nil, "", fmt.Errorf("failed to seal e-invoice signing key: %w", err)
+		}
+	}
+	key, err := parseEInvoicePrivateKey(privatePEM)
+	if err != nil {
+		return 0, nil, "", err
+	}
+	return keyID, key, publicPEM, nil
+}
+
+// createSigningKeyTx generates a key and stores it sealed as the company's
+// active one.
+func (s *EInvoiceService) createSigningKeyTx(tx *sql.Tx, companyID int, userID *int) (int, string, string, error) {
+	privatePEM, publicPEM, err := generateEInvoiceKey()
+	if err != nil {
+		return 0, "", "", err
+	}
+	sealed, err := utils.SealSecret(einvoiceKeySecret(), privatePEM)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to seal e-invoice signing key: %w", err)
+	}
+	var keyID int
+	if err := tx.QueryRow(`
+		INSERT INTO einvoice_signing_keys (company_id, private_key_sealed, public_key_pem, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING key_id
+	`, companyID, sealed, publicPEM, userID).Scan(&keyID); err != nil {
+		return 0, "", "", fmt.Errorf("failed to store e-invoice signing key: %w", err)
+	}
+	return keyID, privatePEM, publicPEM, nil
+}
+
+// RotateSigningKey retires the active key and generates a new one. Issued
+// invoices keep pointing at the key that signed them.
+func (s *EInvoiceService) RotateSigningKey(companyID, userID int) (*models.EInvoiceSigningKey, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start key rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE einvoice_signing_keys SET is_active = FALSE, retired_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1 AND is_active = TRUE
+	`, companyID); err != nil {
+		return nil, fmt.Errorf("failed to retire e-invoice signing key: %w", err)
+	}
+	keyID, _, _, err := s.createSigningKeyTx(tx, companyID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit key rotation: %w", err)
+	}
+
+	keys, err := s.ListSigningKeys(companyID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if keys[i].KeyID == keyID {
+			return &keys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("e-invoice signing key not found")
+}
+
+func (s *EInvoiceService) ListSigningKeys(companyID int) ([]models.EInvoiceSigningKey, error) {
+	rows, err := s.db.Query(`
+		SELECT key_id, algorithm, public_key_pem, is_active, created_at, retired_at
+		FROM einvoice_signing_keys
+		WHERE company_id = $1
+		ORDER BY key_id DESC
+	`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoice signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.EInvoiceSigningKey{}
+	for rows.Next() {
+		var key models.EInvoiceSigningKey
+		var retired sql.NullTime
+		if err := rows.Scan(&key.KeyID, &key.Algorithm, &key.PublicKeyPEM, &key.IsActive, &key.CreatedAt, &retired); err != nil {
+			return nil, fmt.Errorf("failed to scan e-invoice signing key: %w", err)
+		}
+		if retired.Valid {
+			key.RetiredAt = &retired.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+const einvoiceSelect = `
+	SELECT einvoice_id, company_id, location_id, source_type, source_id, sale_id, document_type,
+	       invoice_number, uuid, counter, previous_hash, invoice_hash, signature, key_id, qr_payload,
+	       total_amount::float8, tax_amount::float8, issued_at, submission_status, submission_reference,
+	       submission_error, submitted_at, created_at, xml
+	FROM einvoices
+`
+
+func scanEInvoice(scanner interface{ Scan(...interface{}) error }) (*models.EInvoice, error) {
+	var inv models.EInvoice
+	var reference, submissionError sql.NullString
+	var submittedAt sql.NullTime
+	if err := scanner.Scan(&inv.EInvoiceID, &inv.CompanyID, &inv.LocationID, &inv.SourceType, &inv.SourceID, &inv.SaleID,
+		&inv.DocumentType, &inv.InvoiceNumber, &inv.UUID, &inv.Counter, &inv.PreviousHash, &inv.InvoiceHash,
+		&inv.Signature, &inv.KeyID, &inv.QRPayload, &inv.TotalAmount, &inv.TaxAmount, &inv.IssuedAt,
+		&inv.SubmissionStatus, &reference, &submissionError, &submittedAt, &inv.CreatedAt, &inv.XML); err != nil {
+		return nil, err
+	}
+	if reference.Valid {
+		inv.SubmissionReference = &reference.String
+	}
+	if submissionError.Valid {
+		inv.SubmissionError = &submissionError.String
+	}
+	if submittedAt.Valid {
+		inv.SubmittedAt = &submittedAt.Time
+	}
+	return &inv, nil
+}
+
+func (s *EInvoiceService) GetEInvoice(companyID, einvoiceID int) (*models.EInvoice, error) {
+	inv, err := scanEInvoice(s.db.QueryRow(einvoiceSelect+` WHERE einvoice_id = $1 AND company_id = $2`, einvoiceID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("e-invoice not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoice: %w", err)
+	}
+	return inv, nil
+}
+
+func (s *EInvoiceService) getEInvoiceBySource(companyID int, sourceType string, sourceID int) (*models.EInvoice, error) {
+	inv, err := scanEInvoice(s.db.QueryRow(einvoiceSelect+` WHERE source_type = $1 AND source_id = $2 AND company_id = $3`, sourceType, sourceID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// GetEInvoiceForSale returns the e-invoice issued for a sale or refund
+// invoice, or nil when none has been issued.
+func (s *EInvoiceService) GetEInvoiceForSale(companyID, saleID int) (*models.EInvoice, error) {
+	return s.getEInvoiceBySource(companyID, einvoiceSourceSale, saleID)
+}
+
+// GetEInvoiceForSaleReturn returns the credit note issued for a sale return,
+// or nil when none has been issued.
+func (s *EInvoiceService) GetEInvoiceForSaleReturn(companyID, returnID int) (*models.EInvoice, error) {
+	return s.getEInvoiceBySource(companyID, einvoiceSourceSaleReturn, returnID)
+}
+
+func (s *EInvoiceService) ListEInvoices(companyID int, filters map[string]string) ([]models.EInvoice, error) {
+	query := einvoiceSelect + ` WHERE company_id = $1`
+	args := []interface{}{companyID}
+	for _, key := range []string{"document_type", "submission_status"} {
+		if v := filters[key]; v != "" {
+			args = append(args, strings.ToUpper(v))
+			query += fmt.Sprintf(" AND %s = $%d", key, len(args))
+		}
+	}
+	for _, key := range []string{"location_id", "sale_id"} {
+		if v := filters[key]; v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", key)
+			}
+			args = append(args, id)
+			query += fmt.Sprintf(" AND %s = $%d", key, len(args))
+		}
+	}
+	if v := filters["from_date"]; v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND issued_at::date >= $%d::date", len(args))
+	}
+	if v := filters["to_date"]; v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND issued_at::date <= $%d::date", len(args))
+	}
+	query += " ORDER BY issued_at DESC, einvoice_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.EInvoice{}
+	for rows.Next() {
+		inv, err := scanEInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan e-invoice: %w", err)
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+// VerifyChain re-hashes every invoice of a location in counter order and
+// checks the previous-hash links and signatures.
+func (s *EInvoiceService) VerifyChain(companyID, locationID int) (*models.EInvoiceChainVerification, error) {
+	publicKeys := map[int]string{}
+	keyRows, err := s.db.Query(`SELECT key_id, public_key_pem FROM einvoice_signing_keys WHERE company_id = $1`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoice signing keys: %w", err)
+	}
+	for keyRows.Next() {
+		var id int
+		var pemText string
+		if err := keyRows.Scan(&id, &pemText); err != nil {
+			keyRows.Close()
+			return nil, fmt.Errorf("failed to scan e-invoice signing key: %w", err)
+		}
+		publicKeys[id] = pemText
+	}
+	keyRows.Close()
+
+	rows, err := s.db.Query(einvoiceSelect+` WHERE company_id = $1 AND location_id = $2 ORDER BY counter`, companyID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get e-invoices: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.EInvoiceChainVerification{LocationID: locationID, Valid: true, Problems: []models.EInvoiceChainProblem{}}
+	expectedCounter := int64(1)
+	expectedPrevious := einvoiceGenesisHash
+	for rows.Next() {
+		inv, err := scanEInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan e-invoice: %w", err)
+		}
+		result.Checked++
+		for _, problem := range verifyEInvoice(inv, expectedCounter, expectedPrevious, publicKeys[inv.KeyID]) {
+			result.Problems = append(result.Problems, models.EInvoiceChainProblem{EInvoiceID: inv.EInvoiceID, Counter: inv.Counter, Problem: problem})
+		}
+		expectedCounter = inv.Counter + 1
+		expectedPrevious = inv.InvoiceHash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get e-invoices: %w", err)
+	}
+	result.Valid = len(result.Problems) == 0
+	return result, nil
+}
+
+func verifyEInvoice(inv *models.EInvoice, expectedCounter int64, expectedPrevious, publicPEM string) []string {
+	var problems []string
+	if inv.Counter != expectedCounter {
+		problems = append(problems, fmt.Sprintf("counter gap: expected %d", expectedCounter))
+	}
+	if inv.PreviousHash != expectedPrevious {
+		problems = append(problems, "previous hash does not match the preceding invoice")
+	}
+	unsigned, err := unstampEInvoiceXML(inv.XML)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if einvoiceHash(unsigned) != inv.InvoiceHash {
+		problems = append(problems, "document does not match its hash")
+	}
+	publicKey, _, err := parseEInvoicePublicKey(publicPEM)
+	if err != nil {
+		problems = append(problems, "signing key not found")
+	} else if !verifyEInvoiceSignature(publicKey, inv.InvoiceHash, inv.Signature) {
+		problems = append(problems, "signature is invalid")
+	}
+	return problems
+}
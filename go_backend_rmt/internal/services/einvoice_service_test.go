@@ -0,0 +1,98 @@
+package services
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"erp-backend/internal/utils"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+// sealedWith matches a value sealed under secret that opens to plaintext,
+// or to any private key when plaintext is empty.
+type sealedWith struct {
+	secret    string
+	plaintext string
+}
+
+func (m sealedWith) Match(v driver.Value) bool {
+	sealed, ok := v.(string)
+	if !ok {
+		return false
+	}
+	opened, err := utils.OpenSecret(m.secret, sealed)
+	if err != nil {
+		return false
+	}
+	if m.plaintext == "" {
+		_, err := parseEInvoicePrivateKey(opened)
+		return err == nil
+	}
+	return opened == m.plaintext
+}
+
+func TestEInvoiceSigningKeysAreSealedAtRest(t *testing.T) {
+	const secret = "einvoice-test-secret"
+	t.Setenv("EINVOICE_KEY_SECRET", secret)
+	privatePEM, publicPEM, err := generateEInvoiceKey()
+	if err != nil {
+		t.Fatalf("generateEInvoiceKey returned error: %v", err)
+	}
+	sealed, err := utils.SealSecret(secret, privatePEM)
+	if err != nil {
+		t.Fatalf("SealSecret returned error: %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	svc := &EInvoiceService{db: db}
+	selectKey := regexp.QuoteMeta("SELECT key_id, private_key_sealed, public_key_pem FROM einvoice_signing_keys")
+
+	mock.ExpectBegin()
+	// A sealed key is opened for signing and left as it is.
+	mock.ExpectQuery(selectKey).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "private_key_sealed", "public_key_pem"}).AddRow(4, sealed, publicPEM))
+	// A key stored in clear before sealing is sealed on first use.
+	mock.ExpectQuery(selectKey).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "private_key_sealed", "public_key_pem"}).AddRow(5, privatePEM, publicPEM))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE einvoice_signing_keys SET private_key_sealed = $1 WHERE key_id = $2")).
+		WithArgs(sealedWith{secret, privatePEM}, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Under another secret the key cannot be opened.
+	mock.ExpectQuery(selectKey).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id", "private_key_sealed", "public_key_pem"}).AddRow(6, sealed, publicPEM))
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	for _, companyID := range []int{1, 2} {
+		_, key, _, err := svc.activeSigningKeyTx(tx, companyID)
+		if err != nil || key == nil {
+			t.Fatalf("company %d: unexpected result %v, %v", companyID, key, err)
+		}
+	}
+	t.Setenv("EINVOICE_KEY_SECRET", "another-secret")
+	if _, _, _, err := svc.activeSigningKeyTx(tx, 3); err == nil {
+		t.Fatal("expected a key sealed under another secret to fail")
+	}
+
+	// New keys are stored sealed.
+	t.Setenv("EINVOICE_KEY_SECRET", secret)
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO einvoice_signing_keys (company_id, private_key_sealed, public_key_pem, created_by)")).
+		WithArgs(1, sealedWith{secret, ""}, sqlmock.AnyArg(), 9).
+		WillReturnRows(sqlmock.NewRows([]string{"key_id"}).AddRow(7))
+	userID := 9
+	keyID, newPrivatePEM, _, err := svc.createSigningKeyTx(tx, 1, &userID)
+	if err != nil || keyID != 7 || newPrivatePEM == "" {
+		t.Fatalf("unexpected create result %d, %v", keyID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
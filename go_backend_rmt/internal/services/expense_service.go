@@ -22,6 +22,13 @@ func NewExpenseService() *ExpenseService {
 }
 
 func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *models.CreateExpenseRequest, idempotencyKey string) (int, error) {
+	id, _, err := s.CreateExpenseWithBudgetWarnings(companyID, locationID, userID, req, idempotencyKey)
+	return id, err
+}
+
+// CreateExpenseWithBudgetWarnings creates the expense and also returns the
+// WARN-mode budgets it overruns. BLOCK-mode budgets fail the create instead.
+func (s *ExpenseService) CreateExpenseWithBudgetWarnings(companyID, locationID, userID int, req *models.CreateExpenseRequest, idempotencyKey string) (int, []string, error) {
 	idemKey := strings.TrimSpace(idempotencyKey)
 
 	// Default document date to the OPEN cash register's business date when not provided.
@@ -45,16 +52,16 @@ func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *m
 		existingID, err := s.lookupExpenseIdempotencyKey(companyID, locationID, idemKey)
 		if err == nil {
 			_ = NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "expense", existingID)
-			return existingID, nil
+			return existingID, nil, nil
 		}
 		if err != sql.ErrNoRows {
-			return 0, fmt.Errorf("failed to lookup expense idempotency key: %w", err)
+			return 0, nil, fmt.Errorf("failed to lookup expense idempotency key: %w", err)
 		}
 	}
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		return 0, fmt.Errorf("failed to start transaction: %w", err)
+		return 0, nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	rolledBack := false
 	rollback := func() {
@@ -66,19 +73,39 @@ func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *m
 	}
 	defer rollback()
 
+	if req.DepartmentID != nil {
+		var ok bool
+		if err := tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM departments WHERE department_id = $1 AND company_id = $2 AND is_deleted = FALSE)
+		`, *req.DepartmentID, companyID).Scan(&ok); err != nil {
+			return 0, nil, fmt.Errorf("failed to verify department: %w", err)
+		}
+		if !ok {
+			return 0, nil, fmt.Errorf("department not found")
+		}
+	}
+	budgetWarnings, err := checkBudgetSpend(tx, companyID, locationID, req.DepartmentID, accountCodeExpenses, req.ExpenseDate, req.Amount)
+	if err != nil {
+		return 0, nil, err
+	}
+	dimensions, err := validateAnalyticTags(tx, companyID, req.Dimensions)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	// Generate expense number using numbering sequence.
 	ns := &NumberingSequenceService{db: s.db}
 	expenseNumber, err := ns.NextNumber(tx, "expense", companyID, &locationID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to generate expense number: %w", err)
+		return 0, nil, fmt.Errorf("failed to generate expense number: %w", err)
 	}
 
 	var id int
 	err = tx.QueryRow(`
-		INSERT INTO expenses (expense_number, category_id, location_id, amount, notes, expense_date, created_by, updated_by, idempotency_key)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8, NULLIF($9,''))
+		INSERT INTO expenses (expense_number, category_id, location_id, amount, notes, expense_date, created_by, updated_by, idempotency_key, department_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8, NULLIF($9,''), $10)
 		RETURNING expense_id`,
-		expenseNumber, req.CategoryID, locationID, req.Amount, req.Notes, req.ExpenseDate, userID, userID, idemKey,
+		expenseNumber, req.CategoryID, locationID, req.Amount, req.Notes, req.ExpenseDate, userID, userID, idemKey, req.DepartmentID,
 	).Scan(&id)
 	if err != nil {
 		if idemKey != "" {
@@ -88,11 +115,11 @@ func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *m
 				existingID, lerr := s.lookupExpenseIdempotencyKey(companyID, locationID, idemKey)
 				if lerr == nil {
 					_ = NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "expense", existingID)
-					return existingID, nil
+					return existingID, nil, nil
 				}
 			}
 		}
-		return 0, fmt.Errorf("failed to create expense: %w", err)
+		return 0, nil, fmt.Errorf("failed to create expense: %w", err)
 	}
 
 	finance := NewFinanceIntegrityServiceWithDB(s.db)
@@ -103,10 +130,10 @@ func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *m
 		EventType:     financeEventLedgerExpense,
 		AggregateType: "expense",
 		AggregateID:   id,
-		Payload:       models.JSONB{},
+		Payload:       ledgerPostingPayloadJSON(dimensions),
 		CreatedBy:     &userID,
 	}); err != nil {
-		return 0, fmt.Errorf("failed to enqueue expense ledger posting: %w", err)
+		return 0, nil, fmt.Errorf("failed to enqueue expense ledger posting: %w", err)
 	}
 	if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
 		CompanyID:     companyID,
@@ -123,17 +150,17 @@ func (s *ExpenseService) CreateExpense(companyID, locationID, userID int, req *m
 		},
 		CreatedBy: &userID,
 	}); err != nil {
-		return 0, fmt.Errorf("failed to enqueue expense cash register event: %w", err)
+		return 0, nil, fmt.Errorf("failed to enqueue expense cash register event: %w", err)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("failed to commit expense create: %w", err)
+		return 0, nil, fmt.Errorf("failed to commit expense create: %w", err)
 	}
 	rolledBack = true
 	if err := finance.ProcessAggregate(companyID, "expense", id); err != nil {
 		log.Printf("expense_service: failed to process finance outbox for expense %d: %v", id, err)
 	}
-	return id, nil
+	return id, budgetWarnings, nil
 }
 
 func (s *ExpenseService) lookupExpenseIdempotencyKey(companyID, locationID int, idemKey string) (int, error) {
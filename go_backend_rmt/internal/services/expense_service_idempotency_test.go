@@ -87,6 +87,10 @@ func TestExpenseService_CreateExpense_IdempotencyUniqueViolationReturnsExisting(
 
 	mock.ExpectBegin()
 
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT account_id, name, type FROM chart_of_accounts")).
+		WithArgs(companyID, accountCodeExpenses).
+		WillReturnError(sql.ErrNoRows)
+
 	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
 		WithArgs(sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(0, 1))
@@ -101,7 +105,7 @@ func TestExpenseService_CreateExpense_IdempotencyUniqueViolationReturnsExisting(
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.ExpectQuery("(?s)INSERT INTO expenses .*RETURNING expense_id").
-		WithArgs("EXP-000042", req.CategoryID, locationID, req.Amount, req.Notes, req.ExpenseDate, userID, userID, idemKey).
+		WithArgs("EXP-000042", req.CategoryID, locationID, req.Amount, req.Notes, req.ExpenseDate, userID, userID, idemKey, nil).
 		WillReturnError(&pq.Error{Code: "23505"})
 
 	mock.ExpectRollback()
@@ -24,6 +24,13 @@ const (
 	financeEventLedgerSupplierPay    = "ledger.supplier_payment.record"
 	financeEventLedgerSaleReturn     = "ledger.sale_return.record"
 	financeEventLedgerPurchaseReturn = "ledger.purchase_return.record"
+	financeEventLedgerTransferOut    = "ledger.stock_transfer_dispatch.record"
+	financeEventLedgerTransferIn     = "ledger.stock_transfer_receipt.record"
+	financeEventLedgerTransferShort  = "ledger.transfer_shortage.record"
+	financeEventLedgerTransferDamage = "ledger.transfer_damage.record"
+	financeEventLedgerExpiryWriteOff = "ledger.expiry_write_off.record"
+	financeEventLedgerAssembly       = "ledger.assembly_order.record"
+	financeEventLedgerLandedCost     = "ledger.landed_cost.record"
 
 	financeEventCashSale        = "cash.sale.record"
 	financeEventCashPurchase    = "cash.purchase.record"
@@ -35,6 +42,8 @@ const (
 	financeEventLoyaltyRedeem = "loyalty.sale.redeem_points"
 	financeEventCouponRedeem  = "promotion.sale.redeem_coupon"
 	financeEventRaffleIssue   = "promotion.sale.issue_raffle"
+
+	financeEventEInvoiceIssue = "einvoice.issue"
 )
 
 type FinanceIntegrityService struct {
@@ -291,6 +300,18 @@ func (s *FinanceIntegrityService) processEntry(companyID, outboxID int) (*models
 		`, outboxID, companyID, msg); updateErr != nil {
 			return nil, fmt.Errorf("failed to mark finance outbox entry failed: %w", updateErr)
 		}
+		// Retries keep failing the same entry; alert on the first failure only.
+		notify(s.db, NotificationEvent{
+			CompanyID:   companyID,
+			Type:        models.NotificationFinanceOutboxError,
+			Title:       fmt.Sprintf("Ledger posting failed for %s %d", entry.AggregateType, entry.AggregateID),
+			Body:        msg,
+			Severity:    "CRITICAL",
+			EntityType:  "FINANCE_OUTBOX",
+			EntityID:    outboxID,
+			ActionLabel: "Open finance integrity",
+			DedupeKey:   fmt.Sprintf("finance_outbox:%d", outboxID),
+		})
 		updatedEntry, loadErr := s.loadEntry(companyID, outboxID)
 		if loadErr != nil {
 			return nil, err
@@ -313,7 +334,73 @@ func (s *FinanceIntegrityService) processEntry(companyID, outboxID int) (*models
 	return s.loadEntry(companyID, outboxID)
 }
 
+// ledgerEventTransactionTypes maps ledger posting events to the
+// ledger_entries.transaction_type they write.
+var ledgerEventTransactionTypes = map[string]string{
+	financeEventLedgerSale:           "sale",
+	financeEventLedgerPurchase:       "purchase",
+	financeEventLedgerCollection:     "collection",
+	financeEventLedgerExpense:        "expense",
+	financeEventLedgerSupplierPay:    "payment",
+	financeEventLedgerSaleReturn:     "sale_return",
+	financeEventLedgerPurchaseReturn: "purchase_return",
+	financeEventLedgerTransferOut:    "transfer_dispatch",
+	financeEventLedgerTransferIn:     "transfer_receipt",
+	financeEventLedgerTransferShort:  "transfer_shortage",
+	financeEventLedgerTransferDamage: "transfer_damage",
+	financeEventLedgerExpiryWriteOff: "expiry_write_off",
+	financeEventLedgerAssembly:       "assembly_order",
+	financeEventLedgerLandedCost:     "landed_cost_voucher",
+}
+
+// ledgerPostingPayload carries the analytic dimensions chosen on the source
+// document; dimensions it leaves out fall back to the configured defaults.
+type ledgerPostingPayload struct {
+	Dimensions []models.AnalyticTag `json:"dimensions,omitempty"`
+}
+
+// ledgerPostingPayloadJSON builds the payload of a ledger posting event.
+func ledgerPostingPayloadJSON(dimensions []models.AnalyticTag) models.JSONB {
+	if len(dimensions) == 0 {
+		return models.JSONB{}
+	}
+	tags := make([]interface{}, len(dimensions))
+	for i, t := range dimensions {
+		tags[i] = map[string]interface{}{"dimension_id": t.DimensionID, "value_id": t.ValueID}
+	}
+	return models.JSONB{"dimensions": tags}
+}
+
 func (s *FinanceIntegrityService) handleEntry(entry *models.FinanceOutboxEntry) error {
+	if err := s.postEntry(entry); err != nil {
+		return err
+	}
+	if transactionType, ok := ledgerEventTransactionTypes[entry.EventType]; ok {
+		return s.tagLedgerPosting(entry, transactionType)
+	}
+	return nil
+}
+
+// tagLedgerPosting applies the document's analytic dimensions to the ledger
+// entries its posting wrote.
+func (s *FinanceIntegrityService) tagLedgerPosting(entry *models.FinanceOutboxEntry, transactionType string) error {
+	payload := ledgerPostingPayload{}
+	if err := decodeFinancePayload(entry.Payload, &payload); err != nil {
+		return err
+	}
+	sources, err := ledgerAnalyticSources(s.db, entry.CompanyID, transactionType, entry.AggregateID, entry.LocationID)
+	if err != nil {
+		return err
+	}
+	defaults, err := resolveAnalyticDefaults(s.db, entry.CompanyID, sources)
+	if err != nil {
+		return err
+	}
+	tags := mergeAnalyticTags(defaults, payload.Dimensions)
+	return tagLedgerTransaction(s.db, entry.CompanyID, transactionType, entry.AggregateID, tags)
+}
+
+func (s *FinanceIntegrityService) postEntry(entry *models.FinanceOutboxEntry) error {
 	switch entry.EventType {
 	case financeEventLedgerSale:
 		return (&LedgerService{db: s.db}).RecordSale(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
@@ -329,6 +416,23 @@ func (s *FinanceIntegrityService) handleEntry(entry *models.FinanceOutboxEntry)
 		return (&LedgerService{db: s.db}).RecordSaleReturn(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
 	case financeEventLedgerPurchaseReturn:
 		return (&LedgerService{db: s.db}).RecordPurchaseReturn(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerTransferOut:
+		return (&LedgerService{db: s.db}).RecordStockTransferDispatch(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerTransferIn:
+		return (&LedgerService{db: s.db}).RecordStockTransferReceipt(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerTransferShort:
+		return (&LedgerService{db: s.db}).RecordTransferShortage(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerTransferDamage:
+		return (&LedgerService{db: s.db}).RecordTransferDamage(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerExpiryWriteOff:
+		return (&LedgerService{db: s.db}).RecordExpiryWriteOff(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerAssembly:
+		return (&LedgerService{db: s.db}).RecordAssemblyOrder(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventLedgerLandedCost:
+		return (&LedgerService{db: s.db}).RecordLandedCostVoucher(entry.CompanyID, entry.AggregateID, createdByOrZero(entry.CreatedBy))
+	case financeEventEInvoiceIssue:
+		_, err := (&EInvoiceService{db: s.db}).IssueEInvoice(entry.CompanyID, entry.AggregateType, entry.AggregateID)
+		return err
 	case financeEventCashSale, financeEventCashPurchase, financeEventCashCollection, financeEventCashExpense, financeEventCashSupplierPay:
 		return s.handleCashEntry(entry)
 	case financeEventLoyaltyAward:
@@ -23,6 +23,8 @@ func loadCompanyInventoryPolicy(querier settingsQueryRower, companyID int) (*com
 		CostingMethod:        costingMethodFIFO,
 		NegativeStockPolicy:  negativeStockPolicyDisallow,
 		NegativeProfitPolicy: negativeStockPolicyDisallow,
+		BatchIssuePolicy:     batchIssuePolicyFIFO,
+		ExpiryAlertDays:      defaultExpiryAlertDays,
 	}
 	if err == nil {
 		if value, ok := raw["inventory_costing_method"].(string); ok {
@@ -40,6 +42,15 @@ func loadCompanyInventoryPolicy(querier settingsQueryRower, companyID int) (*com
 		if value, ok := raw["approval_password_hash"].(string); ok {
 			policy.ApprovalPasswordHash = strings.TrimSpace(value)
 		}
+		if value, ok := raw["batch_issue_policy"].(string); ok {
+			policy.BatchIssuePolicy = normalizeBatchIssuePolicy(value)
+		}
+		if value, ok := raw["block_expired_sales"].(bool); ok {
+			policy.BlockExpiredSales = value
+		}
+		if value, ok := raw["expiry_alert_days"].(float64); ok && value > 0 {
+			policy.ExpiryAlertDays = int(value)
+		}
 	}
 	if policy.ApprovalPasswordHash == "" {
 		policy.ApprovalPasswordHash = policy.NegativeStockApprovalPasswordHash
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -32,6 +33,8 @@ type transferLotSnapshot struct {
 	ExpiryDate        *time.Time
 	RemainingQuantity float64
 	CostPrice         float64
+	Ownership         string
+	ConsignorSupplier *int
 }
 
 type transferIssueSnapshot struct {
@@ -76,12 +79,14 @@ func (s *InventoryService) validateProductInCompanyTx(tx *sql.Tx, companyID, pro
 func (s *InventoryService) loadTransferLotSnapshotTx(tx *sql.Tx, companyID, fromLocationID int, lotID int) (*transferLotSnapshot, error) {
 	var snap transferLotSnapshot
 	err := tx.QueryRow(`
-		SELECT lot_id, batch_number, expiry_date, remaining_quantity::float8, cost_price::float8
+		SELECT lot_id, batch_number, expiry_date, remaining_quantity::float8, cost_price::float8,
+		       ownership_type, consignor_supplier_id
 		FROM stock_lots
 		WHERE company_id = $1 AND location_id = $2 AND lot_id = $3
 		FOR UPDATE
 	`, companyID, fromLocationID, lotID).Scan(
 		&snap.LotID, &snap.BatchNumber, &snap.ExpiryDate, &snap.RemainingQuantity, &snap.CostPrice,
+		&snap.Ownership, &snap.ConsignorSupplier,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("selected batch not found")
@@ -384,13 +389,28 @@ func (s *InventoryService) AdjustStock(companyID, locationID, userID int, req *m
 		}
 	}
 
-	if _, err := tx.Exec(`
+	var adjustmentID int
+	if err := tx.QueryRow(`
 		INSERT INTO stock_adjustments (location_id, product_id, adjustment, reason, created_by)
 		VALUES ($1, $2, $3, $4, $5)
-	`, locationID, req.ProductID, req.Adjustment, req.Reason, userID); err != nil {
+		RETURNING adjustment_id
+	`, locationID, req.ProductID, req.Adjustment, req.Reason, userID).Scan(&adjustmentID); err != nil {
 		return fmt.Errorf("failed to record adjustment: %w", err)
 	}
 
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventStockAdjusted, "stock_adjustment", adjustmentID, models.JSONB{
+		"adjustment_id": adjustmentID,
+		"location_id":   locationID,
+		"reason":        req.Reason,
+		"items": []models.JSONB{{
+			"product_id": req.ProductID,
+			"barcode_id": req.BarcodeID,
+			"adjustment": req.Adjustment,
+		}},
+	}); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -458,6 +478,7 @@ func (s *InventoryService) CreateStockAdjustmentDocument(companyID, locationID,
 		return nil, fmt.Errorf("failed to create document: %w", err)
 	}
 
+	webhookItems := make([]models.JSONB, 0, len(req.Items))
 	for _, it := range req.Items {
 		if err := s.validateProductInCompanyTx(tx, companyID, it.ProductID); err != nil {
 			return nil, err
@@ -507,6 +528,21 @@ func (s *InventoryService) CreateStockAdjustmentDocument(companyID, locationID,
         `, locationID, it.ProductID, it.Adjustment, movementReason, userID); err != nil {
 			return nil, fmt.Errorf("failed to record adjustment: %w", err)
 		}
+		webhookItems = append(webhookItems, models.JSONB{
+			"product_id": it.ProductID,
+			"barcode_id": it.BarcodeID,
+			"adjustment": it.Adjustment,
+		})
+	}
+
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventStockAdjusted, "stock_adjustment_document", docID, models.JSONB{
+		"document_id":     docID,
+		"document_number": docNumber,
+		"location_id":     locationID,
+		"reason":          req.Reason,
+		"items":           webhookItems,
+	}); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -620,7 +656,8 @@ func (s *InventoryService) transferTrackedStockTx(tx *sql.Tx, companyID, fromLoc
 	if len(issue.BatchAllocations) > 0 {
 		selection.BatchAllocations = issue.BatchAllocations
 	}
-	return s.receiveTransferTrackedStockTx(tx, companyID, fromLocationID, toLocationID, userID, sourceLineID, sourceRef, selection)
+	_, err = s.receiveTransferTrackedStockTx(tx, companyID, fromLocationID, toLocationID, userID, sourceLineID, sourceRef, selection)
+	return err
 }
 
 func (s *InventoryService) issueTransferTrackedStockTx(tx *sql.Tx, companyID, fromLocationID, userID int, sourceLineID *int, sourceRef *string, selection inventorySelection) (*transferIssueSnapshot, error) {
@@ -632,6 +669,9 @@ func (s *InventoryService) issueTransferTrackedStockTx(tx *sql.Tx, companyID, fr
 	if selection.Quantity <= 0 {
 		return nil, fmt.Errorf("quantity must be greater than zero")
 	}
+	if err := trackingSvc.checkUnreservedStockTx(tx, fromLocationID, variant, selection.Quantity); err != nil {
+		return nil, err
+	}
 
 	snapshot := &transferIssueSnapshot{Variant: variant}
 	if variant.IsSerialized {
@@ -663,10 +703,12 @@ func (s *InventoryService) issueTransferTrackedStockTx(tx *sql.Tx, companyID, fr
 		if err != nil {
 			return nil, err
 		}
-		method, err := trackingSvc.getCompanyCostingMethodTx(tx, companyID)
+		policy, err := trackingSvc.getCompanyInventoryPolicyTx(tx, companyID)
 		if err != nil {
 			return nil, err
 		}
+		method := policy.CostingMethod
+		orderLotsForIssue(lots, policy.BatchIssuePolicy, false, time.Now())
 		avgCost := variant.DefaultCostPrice
 		if err := tx.QueryRow(`
 			SELECT COALESCE(average_cost, 0)::float8
@@ -764,51 +806,66 @@ func (s *InventoryService) issueTransferTrackedStockTx(tx *sql.Tx, companyID, fr
 	return snapshot, nil
 }
 
-func (s *InventoryService) receiveTransferTrackedStockTx(tx *sql.Tx, companyID, fromLocationID, toLocationID, userID int, sourceLineID *int, sourceRef *string, selection inventorySelection) error {
+func (s *InventoryService) receiveTransferTrackedStockTx(tx *sql.Tx, companyID, fromLocationID, toLocationID, userID int, sourceLineID *int, sourceRef *string, selection inventorySelection) (*resolvedVariant, error) {
 	trackingSvc := newInventoryTrackingService(s.db)
 	variant, err := trackingSvc.resolveVariantTx(tx, companyID, selection.ProductID, selection.BarcodeID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if selection.Quantity <= 0 {
-		return fmt.Errorf("quantity must be greater than zero")
+		return nil, fmt.Errorf("quantity must be greater than zero")
+	}
+	consigneeCustomerID, err := loadLocationConsigneeTx(tx, companyID, toLocationID)
+	if err != nil {
+		return nil, err
 	}
 
 	totalCost := 0.0
 	if variant.IsSerialized {
 		if selection.Quantity != float64(int(selection.Quantity)) {
-			return fmt.Errorf("serialized quantities must be whole numbers")
+			return nil, fmt.Errorf("serialized quantities must be whole numbers")
 		}
 		if len(selection.SerialNumbers) != int(selection.Quantity) {
-			return fmt.Errorf("serial numbers count must equal quantity")
+			return nil, fmt.Errorf("serial numbers count must equal quantity")
 		}
 		records, err := trackingSvc.loadSerialsForTransferReceiveTx(tx, companyID, variant, selection.SerialNumbers)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for _, rec := range records {
 			var batchNumber *string
 			var expiryDate *time.Time
+			sourceOwnership := models.StockOwnershipOwned
+			var consignorID *int
 			if rec.StockLotID != nil {
 				snap, err := s.loadTransferLotSnapshotTx(tx, companyID, fromLocationID, *rec.StockLotID)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				batchNumber = snap.BatchNumber
 				expiryDate = snap.ExpiryDate
+				sourceOwnership = snap.Ownership
+				consignorID = snap.ConsignorSupplier
+			}
+			ownership, err := transferLotOwnership(sourceOwnership, consignorID, consigneeCustomerID)
+			if err != nil {
+				return nil, err
 			}
 			destLotID, err := trackingSvc.createLotTx(tx, companyID, toLocationID, variant, inventorySelection{
-				ProductID:     variant.ProductID,
-				BarcodeID:     &variant.BarcodeID,
-				Quantity:      1,
-				BatchNumber:   batchNumber,
-				ExpiryDate:    expiryDate,
-				UnitCost:      rec.CostPrice,
-				SerialNumbers: []string{rec.SerialNumber},
-				Notes:         selection.Notes,
+				ProductID:           variant.ProductID,
+				BarcodeID:           &variant.BarcodeID,
+				Quantity:            1,
+				BatchNumber:         batchNumber,
+				ExpiryDate:          expiryDate,
+				UnitCost:            rec.CostPrice,
+				SerialNumbers:       []string{rec.SerialNumber},
+				Notes:               selection.Notes,
+				Ownership:           ownership.Type,
+				ConsignorSupplierID: ownership.ConsignorSupplierID,
+				ConsigneeCustomerID: ownership.ConsigneeCustomerID,
 			})
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if _, err := tx.Exec(`
 				UPDATE product_serials
@@ -819,16 +876,16 @@ func (s *InventoryService) receiveTransferTrackedStockTx(tx *sql.Tx, companyID,
 				    last_movement_at = CURRENT_TIMESTAMP
 				WHERE product_serial_id = $4
 			`, destLotID, toLocationID, rec.CostPrice, rec.ProductSerialID); err != nil {
-				return fmt.Errorf("failed to relocate serial: %w", err)
+				return nil, fmt.Errorf("failed to relocate serial: %w", err)
 			}
 			if err := trackingSvc.createMovementTx(tx, companyID, toLocationID, variant, "TRANSFER_IN", "stock_transfer_detail", sourceLineID, sourceRef, nil, &destLotID, &rec.ProductSerialID, 1, rec.CostPrice, userID, selection.Notes); err != nil {
-				return err
+				return nil, err
 			}
 			totalCost += rec.CostPrice
 		}
 	} else {
 		if len(selection.BatchAllocations) == 0 {
-			return fmt.Errorf("approved transfer is missing issued batch allocations")
+			return nil, fmt.Errorf("approved transfer is missing issued batch allocations")
 		}
 		coveredQty := 0.0
 		for _, alloc := range selection.BatchAllocations {
@@ -836,40 +893,51 @@ func (s *InventoryService) receiveTransferTrackedStockTx(tx *sql.Tx, companyID,
 			var batchNumber *string
 			var expiryDate *time.Time
 			unitCost := variant.DefaultCostPrice
+			sourceOwnership := models.StockOwnershipOwned
+			var consignorID *int
 			if alloc.LotID > 0 {
 				snap, err := s.loadTransferLotSnapshotTx(tx, companyID, fromLocationID, alloc.LotID)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				batchNumber = snap.BatchNumber
 				expiryDate = snap.ExpiryDate
 				unitCost = snap.CostPrice
+				sourceOwnership = snap.Ownership
+				consignorID = snap.ConsignorSupplier
+			}
+			ownership, err := transferLotOwnership(sourceOwnership, consignorID, consigneeCustomerID)
+			if err != nil {
+				return nil, err
 			}
 			if sourceLineID != nil {
 				unitCost, err = s.loadTransferIssueUnitCostTx(tx, companyID, fromLocationID, *sourceLineID, alloc.LotID)
 				if err != nil {
-					return err
+					return nil, err
 				}
 			}
 			destLotID, err := trackingSvc.createLotTx(tx, companyID, toLocationID, variant, inventorySelection{
-				ProductID:   variant.ProductID,
-				BarcodeID:   &variant.BarcodeID,
-				Quantity:    alloc.Quantity,
-				BatchNumber: batchNumber,
-				ExpiryDate:  expiryDate,
-				UnitCost:    unitCost,
-				Notes:       selection.Notes,
+				ProductID:           variant.ProductID,
+				BarcodeID:           &variant.BarcodeID,
+				Quantity:            alloc.Quantity,
+				BatchNumber:         batchNumber,
+				ExpiryDate:          expiryDate,
+				UnitCost:            unitCost,
+				Notes:               selection.Notes,
+				Ownership:           ownership.Type,
+				ConsignorSupplierID: ownership.ConsignorSupplierID,
+				ConsigneeCustomerID: ownership.ConsigneeCustomerID,
 			})
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if err := trackingSvc.createMovementTx(tx, companyID, toLocationID, variant, "TRANSFER_IN", "stock_transfer_detail", sourceLineID, sourceRef, nil, &destLotID, nil, alloc.Quantity, unitCost, userID, selection.Notes); err != nil {
-				return err
+				return nil, err
 			}
 			totalCost += alloc.Quantity * unitCost
 		}
 		if coveredQty+1e-9 < selection.Quantity {
-			return fmt.Errorf("batch allocations do not cover requested quantity")
+			return nil, fmt.Errorf("batch allocations do not cover requested quantity")
 		}
 	}
 
@@ -878,12 +946,12 @@ func (s *InventoryService) receiveTransferTrackedStockTx(tx *sql.Tx, companyID,
 		inboundUnitCost = totalCost / selection.Quantity
 	}
 	if _, _, err := trackingSvc.adjustVariantBalanceTx(tx, companyID, toLocationID, variant, selection.Quantity, inboundUnitCost, nil); err != nil {
-		return err
+		return nil, err
 	}
 	if err := trackingSvc.updateProductCostSnapshotTx(tx, companyID, variant.ProductID); err != nil {
-		return fmt.Errorf("failed to update product cost snapshot: %w", err)
+		return nil, fmt.Errorf("failed to update product cost snapshot: %w", err)
 	}
-	return nil
+	return variant, nil
 }
 
 func (s *InventoryService) CreateStockTransfer(companyID, fromLocationID, userID int, req *models.CreateStockTransferRequest) (*models.StockTransfer, error) {
@@ -1011,15 +1079,15 @@ func (s *InventoryService) ApproveStockTransfer(transferID, companyID, actingLoc
 	defer tx.Rollback()
 
 	var status string
-	var fromLocationID int
-	var transferNumber string
+	var fromLocationID, toLocationID int
+	var transferNumber, fromLocationName string
 	err = tx.QueryRow(`
-                SELECT st.status, st.from_location_id, st.transfer_number
+                SELECT st.status, st.from_location_id, st.to_location_id, st.transfer_number, fl.name
                 FROM stock_transfers st
                 JOIN locations fl ON st.from_location_id = fl.location_id
                 JOIN locations tl ON st.to_location_id = tl.location_id
                 WHERE st.transfer_id = $1 AND (fl.company_id = $2 OR tl.company_id = $2)
-        `, transferID, companyID).Scan(&status, &fromLocationID, &transferNumber)
+        `, transferID, companyID).Scan(&status, &fromLocationID, &toLocationID, &transferNumber, &fromLocationName)
 
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("transfer not found")
@@ -1094,131 +1162,69 @@ func (s *InventoryService) ApproveStockTransfer(transferID, companyID, actingLoc
 		if _, err := tx.Exec(`
 			UPDATE stock_transfer_details
 			SET barcode_id = $2,
-			    batch_allocations = $3
+			    batch_allocations = $3,
+			    transit_value = (
+			        SELECT COALESCE(SUM(ABS(total_cost)), 0)
+			        FROM inventory_movements
+			        WHERE company_id = $4
+			          AND movement_type = 'TRANSFER_OUT'
+			          AND source_type = 'stock_transfer_detail'
+			          AND source_line_id = $1
+			    )
 			WHERE transfer_detail_id = $1
-		`, it.transferDetailID, issue.Variant.BarcodeID, encodeBatchAllocations(issue.BatchAllocations)); err != nil {
+		`, it.transferDetailID, issue.Variant.BarcodeID, encodeBatchAllocations(issue.BatchAllocations), companyID); err != nil {
 			return fmt.Errorf("failed to persist transfer issue details: %w", err)
 		}
 	}
 
 	_, err = tx.Exec(`
                 UPDATE stock_transfers
-                SET status = 'IN_TRANSIT', approved_by = $2, approved_at = CURRENT_TIMESTAMP, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+                SET status = 'IN_TRANSIT', approved_by = $2, approved_at = CURRENT_TIMESTAMP, updated_by = $2, updated_at = CURRENT_TIMESTAMP,
+                    transit_value = (SELECT COALESCE(SUM(transit_value), 0) FROM stock_transfer_details WHERE transfer_id = $1)
                 WHERE transfer_id = $1
         `, transferID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to approve transfer: %w", err)
 	}
 
-	return tx.Commit()
-}
-
-func (s *InventoryService) CompleteStockTransfer(transferID, companyID, actingLocationID, userID int) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+	// Goods on the road stay on the balance sheet as inventory in transit.
+	if err := NewFinanceIntegrityServiceWithDB(s.db).EnqueueTx(tx, &models.FinanceOutboxEntry{
+		CompanyID:     companyID,
+		LocationID:    &fromLocationID,
+		EventType:     financeEventLedgerTransferOut,
+		AggregateType: "stock_transfer",
+		AggregateID:   transferID,
+		Payload:       models.JSONB{},
+		CreatedBy:     &userID,
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue transfer dispatch ledger posting: %w", err)
 	}
-	defer tx.Rollback()
 
-	var fromLocationID, toLocationID int
-	var status, transferNumber string
-	err = tx.QueryRow(`
-		SELECT st.from_location_id, st.to_location_id, st.status, st.transfer_number
-		FROM stock_transfers st
-		JOIN locations fl ON fl.location_id = st.from_location_id
-		JOIN locations tl ON tl.location_id = st.to_location_id
-		WHERE st.transfer_id = $1
-		  AND (fl.company_id = $2 OR tl.company_id = $2)
-	`, transferID, companyID).Scan(&fromLocationID, &toLocationID, &status, &transferNumber)
-
-	if err == sql.ErrNoRows {
-		return fmt.Errorf("transfer not found")
-	}
-	if err != nil {
-		return fmt.Errorf("failed to get transfer: %w", err)
-	}
-
-	if status != "IN_TRANSIT" {
-		return fmt.Errorf("transfer is not in transit")
-	}
-
-	// Ensure completion is performed at destination location
-	if actingLocationID != toLocationID {
-		return fmt.Errorf("completion must be done at destination location")
-	}
-
-	rows, err := tx.Query(`
-        SELECT transfer_detail_id, product_id, barcode_id, quantity, serial_numbers, COALESCE(batch_allocations, '[]'::jsonb)
-        FROM stock_transfer_details
-        WHERE transfer_id = $1
-        ORDER BY transfer_detail_id
-    `, transferID)
-	if err != nil {
-		return fmt.Errorf("failed to get transfer items: %w", err)
-	}
-	var items []struct {
-		transferDetailID int
-		productID        int
-		barcodeID        *int
-		quantity         float64
-		serials          []string
-		batches          []models.InventoryBatchSelectionInput
-	}
-	for rows.Next() {
-		var item struct {
-			transferDetailID int
-			productID        int
-			barcodeID        *int
-			quantity         float64
-			serials          []string
-			batches          []models.InventoryBatchSelectionInput
-		}
-		var serials pq.StringArray
-		var batchAllocRaw []byte
-		if err := rows.Scan(&item.transferDetailID, &item.productID, &item.barcodeID, &item.quantity, &serials, &batchAllocRaw); err != nil {
-			rows.Close()
-			return fmt.Errorf("failed to scan transfer item: %w", err)
-		}
-		item.serials = []string(serials)
-		item.batches = decodeBatchAllocations(batchAllocRaw)
-		items = append(items, item)
-	}
-	if err := rows.Close(); err != nil {
-		return fmt.Errorf("failed to close items cursor: %w", err)
-	}
-
-	for _, it := range items {
-		sourceRef := transferNumber
-		if err := s.receiveTransferTrackedStockTx(tx, companyID, fromLocationID, toLocationID, userID, &it.transferDetailID, &sourceRef, inventorySelection{
-			ProductID:        it.productID,
-			BarcodeID:        it.barcodeID,
-			Quantity:         it.quantity,
-			SerialNumbers:    it.serials,
-			BatchAllocations: it.batches,
-			Notes:            nil,
-		}); err != nil {
-			return fmt.Errorf("failed to transfer stock: %w", err)
-		}
-		if _, err := tx.Exec(`
-			UPDATE stock_transfer_details
-			SET received_quantity = quantity
-			WHERE transfer_detail_id = $1
-		`, it.transferDetailID); err != nil {
-			return fmt.Errorf("failed to update received quantity: %w", err)
-		}
-	}
-
-	// Mark transfer as completed
-	_, err = tx.Exec(`
-                UPDATE stock_transfers
-                SET status = 'COMPLETED', updated_by = $1, updated_at = CURRENT_TIMESTAMP
-                WHERE transfer_id = $2
-        `, userID, transferID)
-	if err != nil {
-		return fmt.Errorf("failed to complete transfer: %w", err)
+	if err := tx.Commit(); err != nil {
+		return err
 	}
+	if err := NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "stock_transfer", transferID); err != nil {
+		log.Printf("inventory_service: failed to process finance outbox for stock_transfer %d: %v", transferID, err)
+	}
+	// The receiving location is told the goods are on their way.
+	notify(s.db, NotificationEvent{
+		CompanyID:   companyID,
+		Type:        models.NotificationTransferInTransit,
+		Title:       fmt.Sprintf("Transfer %s awaiting receipt", transferNumber),
+		Body:        fmt.Sprintf("Dispatched from %s", fromLocationName),
+		EntityType:  "STOCK_TRANSFER",
+		EntityID:    transferID,
+		LocationID:  &toLocationID,
+		ActionLabel: "Receive transfer",
+		DedupeKey:   fmt.Sprintf("transfer_in_transit:%d", transferID),
+	})
+	return nil
+}
 
-	return tx.Commit()
+// CompleteStockTransfer receives every line of an in-transit transfer in full.
+func (s *InventoryService) CompleteStockTransfer(transferID, companyID, actingLocationID, userID int) error {
+	_, err := s.ReceiveStockTransfer(transferID, companyID, actingLocationID, userID, nil)
+	return err
 }
 
 // ADD THESE METHODS TO YOUR EXISTING inventory_service.go FILE
@@ -1232,7 +1238,8 @@ func (s *InventoryService) GetStockTransfer(transferID, companyID int) (*models.
                            st.transfer_date, st.status, st.notes, st.created_by, st.approved_by, st.approved_at,
                            st.sync_status, st.created_at, st.updated_at,
                            fl.name as from_location_name, tl.name as to_location_name,
-                           cu.username as created_by_name, au.username as approved_by_name
+                           cu.username as created_by_name, au.username as approved_by_name,
+                           st.transit_value::float8, st.received_by, st.received_at
                 FROM stock_transfers st
 		JOIN locations fl ON st.from_location_id = fl.location_id
 		JOIN locations tl ON st.to_location_id = tl.location_id
@@ -1249,6 +1256,7 @@ func (s *InventoryService) GetStockTransfer(transferID, companyID int) (*models.
 		&transfer.SyncStatus, &transfer.CreatedAt, &transfer.UpdatedAt,
 		&transfer.FromLocationName, &transfer.ToLocationName,
 		&transfer.CreatedByName, &transfer.ApprovedByName,
+		&transfer.TransitValue, &transfer.ReceivedBy, &transfer.ReceivedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -1262,6 +1270,7 @@ func (s *InventoryService) GetStockTransfer(transferID, companyID int) (*models.
 	itemsQuery := `
 		SELECT
 			std.transfer_detail_id, std.product_id, std.barcode_id, std.quantity, std.received_quantity,
+			std.short_quantity, std.damaged_quantity, std.transit_value::float8,
 			p.name as product_name, p.sku as product_sku, u.symbol as unit_symbol,
 			pb.barcode, pb.variant_name,
 			COALESCE(p.tracking_type, CASE WHEN COALESCE(p.is_serialized, FALSE) THEN 'SERIAL' ELSE 'VARIANT' END) AS tracking_type,
@@ -1287,6 +1296,7 @@ func (s *InventoryService) GetStockTransfer(transferID, companyID int) (*models.
 		var batchAllocRaw []byte
 		err := rows.Scan(
 			&item.TransferDetailID, &item.ProductID, &item.BarcodeID, &item.Quantity, &item.ReceivedQuantity,
+			&item.ShortQuantity, &item.DamagedQuantity, &item.TransitValue,
 			&item.ProductName, &item.ProductSKU, &item.UnitSymbol, &item.Barcode, &item.VariantName,
 			&item.TrackingType, &serials, &batchAllocRaw,
 		)
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"time"
@@ -24,6 +25,11 @@ const (
 	negativeStockPolicyAllow    = "ALLOW"
 	negativeStockPolicyDisallow = "DONT_ALLOW"
 	negativeStockPolicyApproval = "ALLOW_WITH_APPROVAL"
+	batchIssuePolicyFIFO        = "FIFO"
+	batchIssuePolicyFEFO        = "FEFO"
+	defaultExpiryAlertDays      = 30
+
+	permissionSellExpiredStock = "SELL_EXPIRED_STOCK"
 )
 
 type inventorySelection struct {
@@ -41,6 +47,12 @@ type inventorySelection struct {
 	UnitCost         float64
 	Notes            *string
 	OverridePassword *string
+	// AllowExpired lets a sale draw on expired lots after a manager override.
+	AllowExpired bool
+	// Ownership marks received lots as consignment stock; empty means OWNED.
+	Ownership           string
+	ConsignorSupplierID *int
+	ConsigneeCustomerID *int
 }
 
 type companyInventoryPolicy struct {
@@ -49,6 +61,9 @@ type companyInventoryPolicy struct {
 	NegativeProfitPolicy              string `json:"negative_profit_policy,omitempty"`
 	NegativeStockApprovalPasswordHash string `json:"negative_stock_approval_password_hash,omitempty"`
 	ApprovalPasswordHash              string `json:"approval_password_hash,omitempty"`
+	BatchIssuePolicy                  string `json:"batch_issue_policy,omitempty"`
+	BlockExpiredSales                 bool   `json:"block_expired_sales,omitempty"`
+	ExpiryAlertDays                   int    `json:"expiry_alert_days,omitempty"`
 }
 
 type NegativeStockApprovalRequiredError struct {
@@ -121,6 +136,35 @@ func normalizeNegativeStockPolicy(value string) string {
 	}
 }
 
+func normalizeBatchIssuePolicy(value string) string {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case batchIssuePolicyFEFO:
+		return batchIssuePolicyFEFO
+	default:
+		return batchIssuePolicyFIFO
+	}
+}
+
+// expiredStockOverrideError asks the cashier for a manager override before an
+// expired lot is sold.
+func expiredStockOverrideError() error {
+	return &OverrideRequiredError{
+		Message:             "Expired stock requires manager override",
+		RequiredPermissions: []string{permissionSellExpiredStock},
+		ReasonRequired:      true,
+	}
+}
+
+// lotExpired reports whether a lot is past its expiry date. The expiry date
+// itself is still sellable.
+func lotExpired(expiryDate *time.Time, today time.Time) bool {
+	if expiryDate == nil {
+		return false
+	}
+	y, m, d := today.Date()
+	return expiryDate.Before(time.Date(y, m, d, 0, 0, 0, 0, expiryDate.Location()))
+}
+
 func firstNonNilInt(values ...*int) *int {
 	for _, value := range values {
 		if value != nil && *value > 0 {
@@ -242,13 +286,14 @@ func (s *inventoryTrackingService) adjustVariantBalanceTx(tx *sql.Tx, companyID,
 	}
 
 	var currentQty float64
+	var reservedQty float64
 	var currentAvg float64
 	err := tx.QueryRow(`
-		SELECT quantity::float8, average_cost::float8
+		SELECT quantity::float8, reserved_quantity::float8, average_cost::float8
 		FROM stock_variants
 		WHERE location_id = $1 AND barcode_id = $2
 		FOR UPDATE
-	`, locationID, variant.BarcodeID).Scan(&currentQty, &currentAvg)
+	`, locationID, variant.BarcodeID).Scan(&currentQty, &reservedQty, &currentAvg)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to lock stock balance: %w", err)
 	}
@@ -302,9 +347,48 @@ func (s *inventoryTrackingService) adjustVariantBalanceTx(tx *sql.Tx, companyID,
 		return 0, 0, fmt.Errorf("failed to sync aggregate stock: %w", err)
 	}
 
+	// Write-downs can leave less on hand than sales orders hold; the excess
+	// goes back to backorder.
+	if excess := reservedQty - math.Max(newQty, 0); quantityDelta < 0 && excess > 1e-9 {
+		if err := releaseExcessReservationsTx(tx, companyID, locationID, variant.ProductID, variant.BarcodeID, excess); err != nil {
+			return 0, 0, err
+		}
+	}
+
 	return newQty, newAvg, nil
 }
 
+// reservationGuardedMovements are the IssueStockTx movements that may not
+// draw on stock reserved for sales orders; transfers check it themselves.
+// Sales order deliveries are SALE movements too; they release their own
+// reservation before issuing.
+var reservationGuardedMovements = map[string]bool{
+	"SALE":      true,
+	"SALE_EDIT": true,
+}
+
+// checkUnreservedStockTx rejects an issue that would dip into stock reserved
+// for sales orders, whatever the negative stock policy allows.
+func (s *inventoryTrackingService) checkUnreservedStockTx(tx *sql.Tx, locationID int, variant *resolvedVariant, quantity float64) error {
+	var onHand, reserved float64
+	err := tx.QueryRow(`
+		SELECT quantity::float8, reserved_quantity::float8
+		FROM stock_variants
+		WHERE location_id = $1 AND barcode_id = $2
+		FOR UPDATE
+	`, locationID, variant.BarcodeID).Scan(&onHand, &reserved)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock stock balance: %w", err)
+	}
+	if reserved > 1e-9 && onHand-quantity < reserved-1e-9 {
+		return fmt.Errorf("insufficient unreserved stock for variation %d", variant.BarcodeID)
+	}
+	return nil
+}
+
 func (s *inventoryTrackingService) updateProductCostSnapshotTx(tx *sql.Tx, companyID, productID int) error {
 	method, err := s.getCompanyCostingMethodTx(tx, companyID)
 	if err != nil {
@@ -388,18 +472,24 @@ func (s *inventoryTrackingService) updateProductCostSnapshotTx(tx *sql.Tx, compa
 
 func (s *inventoryTrackingService) createLotTx(tx *sql.Tx, companyID, locationID int, variant *resolvedVariant, selection inventorySelection) (int, error) {
 	receivedDate := time.Now().UTC().Format("2006-01-02")
+	ownership := selection.Ownership
+	if ownership == "" {
+		ownership = models.StockOwnershipOwned
+	}
 	var lotID int
 	err := tx.QueryRow(`
 		INSERT INTO stock_lots (
 			product_id, location_id, supplier_id, purchase_id, goods_receipt_id,
 			quantity, remaining_quantity, cost_price, received_date,
-			expiry_date, batch_number, serial_numbers, company_id, barcode_id
+			expiry_date, batch_number, serial_numbers, company_id, barcode_id,
+			ownership_type, consignor_supplier_id, consignee_customer_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12, $13)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING lot_id
 	`, variant.ProductID, locationID, selection.SupplierID, selection.PurchaseID, selection.GoodsReceiptID,
 		selection.Quantity, selection.UnitCost, receivedDate, selection.ExpiryDate,
 		selection.BatchNumber, pq.Array(selection.SerialNumbers), companyID, variant.BarcodeID,
+		ownership, selection.ConsignorSupplierID, selection.ConsigneeCustomerID,
 	).Scan(&lotID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create stock lot: %w", err)
@@ -510,11 +600,54 @@ type availableLot struct {
 	RemainingQuantity float64
 	CostPrice         float64
 	ReceivedDate      time.Time
+	ExpiryDate        *time.Time
+}
+
+// orderLotsForIssue sorts lots in the order they are consumed: by receipt
+// under FIFO, by earliest expiry under FEFO. With demoteExpired, expired lots
+// go last so they are only reached when nothing else is left.
+func orderLotsForIssue(lots []availableLot, issuePolicy string, demoteExpired bool, today time.Time) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		a, b := lots[i], lots[j]
+		if demoteExpired {
+			if ea, eb := lotExpired(a.ExpiryDate, today), lotExpired(b.ExpiryDate, today); ea != eb {
+				return eb
+			}
+		}
+		byExpiry := compareLotExpiry(a.ExpiryDate, b.ExpiryDate)
+		if issuePolicy == batchIssuePolicyFEFO && byExpiry != 0 {
+			return byExpiry < 0
+		}
+		if !a.ReceivedDate.Equal(b.ReceivedDate) {
+			return a.ReceivedDate.Before(b.ReceivedDate)
+		}
+		if byExpiry != 0 {
+			return byExpiry < 0
+		}
+		return a.LotID < b.LotID
+	})
+}
+
+// compareLotExpiry orders expiry dates ascending with undated lots last.
+func compareLotExpiry(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case a.Before(*b):
+		return -1
+	case b.Before(*a):
+		return 1
+	}
+	return 0
 }
 
 func (s *inventoryTrackingService) loadAvailableLotsTx(tx *sql.Tx, companyID, locationID int, variant *resolvedVariant) ([]availableLot, error) {
 	rows, err := tx.Query(`
-		SELECT lot_id, remaining_quantity::float8, cost_price::float8, received_date
+		SELECT lot_id, remaining_quantity::float8, cost_price::float8, received_date, expiry_date
 		FROM stock_lots
 		WHERE company_id = $1
 		  AND location_id = $2
@@ -532,7 +665,7 @@ func (s *inventoryTrackingService) loadAvailableLotsTx(tx *sql.Tx, companyID, lo
 	lots := make([]availableLot, 0)
 	for rows.Next() {
 		var lot availableLot
-		if err := rows.Scan(&lot.LotID, &lot.RemainingQuantity, &lot.CostPrice, &lot.ReceivedDate); err != nil {
+		if err := rows.Scan(&lot.LotID, &lot.RemainingQuantity, &lot.CostPrice, &lot.ReceivedDate, &lot.ExpiryDate); err != nil {
 			return nil, fmt.Errorf("failed to scan stock lot: %w", err)
 		}
 		lots = append(lots, lot)
@@ -565,19 +698,21 @@ type serialRecord struct {
 	StockLotID      *int
 	CostPrice       float64
 	SerialNumber    string
+	ExpiryDate      *time.Time
 }
 
 func (s *inventoryTrackingService) loadSerialsForIssueTx(tx *sql.Tx, companyID, locationID int, variant *resolvedVariant, serialNumbers []string) ([]serialRecord, error) {
 	rows, err := tx.Query(`
-		SELECT product_serial_id, stock_lot_id, cost_price::float8, serial_number
-		FROM product_serials
-		WHERE company_id = $1
-		  AND location_id = $2
-		  AND product_id = $3
-		  AND barcode_id = $4
-		  AND status = 'IN_STOCK'
-		  AND serial_number = ANY($5)
-		FOR UPDATE
+		SELECT ps.product_serial_id, ps.stock_lot_id, ps.cost_price::float8, ps.serial_number, sl.expiry_date
+		FROM product_serials ps
+		LEFT JOIN stock_lots sl ON sl.lot_id = ps.stock_lot_id
+		WHERE ps.company_id = $1
+		  AND ps.location_id = $2
+		  AND ps.product_id = $3
+		  AND ps.barcode_id = $4
+		  AND ps.status = 'IN_STOCK'
+		  AND ps.serial_number = ANY($5)
+		FOR UPDATE OF ps
 	`, companyID, locationID, variant.ProductID, variant.BarcodeID, pq.Array(serialNumbers))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load serial numbers: %w", err)
@@ -587,7 +722,7 @@ func (s *inventoryTrackingService) loadSerialsForIssueTx(tx *sql.Tx, companyID,
 	records := make([]serialRecord, 0, len(serialNumbers))
 	for rows.Next() {
 		var rec serialRecord
-		if err := rows.Scan(&rec.ProductSerialID, &rec.StockLotID, &rec.CostPrice, &rec.SerialNumber); err != nil {
+		if err := rows.Scan(&rec.ProductSerialID, &rec.StockLotID, &rec.CostPrice, &rec.SerialNumber, &rec.ExpiryDate); err != nil {
 			return nil, fmt.Errorf("failed to scan serial: %w", err)
 		}
 		records = append(records, rec)
@@ -648,14 +783,22 @@ func (s *inventoryTrackingService) IssueStockTx(tx *sql.Tx, companyID, locationI
 		return nil, err
 	}
 
-	method, err := s.getCompanyCostingMethodTx(tx, companyID)
+	policy, err := s.getCompanyInventoryPolicyTx(tx, companyID)
 	if err != nil {
 		return nil, err
 	}
+	method := policy.CostingMethod
+	today := time.Now()
+	blockExpired := policy.BlockExpiredSales && movementType == "SALE" && !selection.AllowExpired
 
 	if selection.Quantity <= 0 {
 		return nil, fmt.Errorf("quantity must be greater than zero")
 	}
+	if reservationGuardedMovements[movementType] {
+		if err := s.checkUnreservedStockTx(tx, locationID, variant, selection.Quantity); err != nil {
+			return nil, err
+		}
+	}
 
 	totalCost := 0.0
 	totalQty := selection.Quantity
@@ -672,6 +815,9 @@ func (s *inventoryTrackingService) IssueStockTx(tx *sql.Tx, companyID, locationI
 			return nil, err
 		}
 		for _, rec := range records {
+			if blockExpired && lotExpired(rec.ExpiryDate, today) {
+				return nil, expiredStockOverrideError()
+			}
 			if rec.StockLotID != nil {
 				if err := s.consumeLotTx(tx, *rec.StockLotID, 1); err != nil {
 					return nil, err
@@ -690,6 +836,7 @@ func (s *inventoryTrackingService) IssueStockTx(tx *sql.Tx, companyID, locationI
 		if err != nil {
 			return nil, err
 		}
+		orderLotsForIssue(lots, policy.BatchIssuePolicy, policy.BlockExpiredSales && movementType == "SALE", today)
 
 		avgCost := variant.DefaultCostPrice
 		if err := tx.QueryRow(`
@@ -710,6 +857,9 @@ func (s *inventoryTrackingService) IssueStockTx(tx *sql.Tx, companyID, locationI
 				found := false
 				for _, lot := range lots {
 					if lot.LotID == alloc.LotID {
+						if blockExpired && lotExpired(lot.ExpiryDate, today) {
+							return nil, expiredStockOverrideError()
+						}
 						lotCost = lot.CostPrice
 						found = true
 						break
@@ -749,6 +899,9 @@ func (s *inventoryTrackingService) IssueStockTx(tx *sql.Tx, companyID, locationI
 				if consumeQty <= 0 {
 					continue
 				}
+				if blockExpired && lotExpired(lot.ExpiryDate, today) {
+					return nil, expiredStockOverrideError()
+				}
 				if err := s.consumeLotTx(tx, lot.LotID, consumeQty); err != nil {
 					return nil, err
 				}
@@ -809,8 +962,10 @@ func serialStatusForMovement(movementType string) string {
 	switch strings.ToUpper(strings.TrimSpace(movementType)) {
 	case "TRANSFER_OUT":
 		return "TRANSFER_IN_TRANSIT"
-	case "PURCHASE_RETURN", "ADJUSTMENT_OUT", "SUPPLIER_DEBIT_NOTE":
+	case "PURCHASE_RETURN", "ADJUSTMENT_OUT", "SUPPLIER_DEBIT_NOTE", "ASSEMBLY_CONSUME", "DISASSEMBLY_CONSUME":
 		return "ADJUSTED_OUT"
+	case "EXPIRY_QUARANTINE":
+		return "QUARANTINED"
 	default:
 		return "SOLD"
 	}
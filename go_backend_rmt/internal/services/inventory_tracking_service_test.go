@@ -0,0 +1,96 @@
+package services
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckUnreservedStock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	variant := &resolvedVariant{ProductID: 5, BarcodeID: 9}
+	balance := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"quantity", "reserved_quantity"}).AddRow(10.0, 8.0)
+	}
+	lockQuery := regexp.QuoteMeta("SELECT quantity::float8, reserved_quantity::float8")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WithArgs(3, 9).WillReturnRows(balance())
+	mock.ExpectQuery(lockQuery).WithArgs(3, 9).WillReturnRows(balance())
+	mock.ExpectQuery(lockQuery).WithArgs(4, 9).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	svc := &inventoryTrackingService{}
+	// 10 on hand with 8 reserved leaves 2 to sell.
+	if err := svc.checkUnreservedStockTx(tx, 3, variant, 5); err == nil || err.Error() != "insufficient unreserved stock for variation 9" {
+		t.Fatalf("expected selling reserved stock to fail, got %v", err)
+	}
+	if err := svc.checkUnreservedStockTx(tx, 3, variant, 2); err != nil {
+		t.Fatalf("expected the unreserved 2 to sell, got %v", err)
+	}
+	if err := svc.checkUnreservedStockTx(tx, 4, variant, 2); err != nil {
+		t.Fatalf("expected a location without a balance to pass, got %v", err)
+	}
+	_ = tx.Rollback()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAdjustVariantBalanceWriteDownReleasesReservations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	variant := &resolvedVariant{ProductID: 5, BarcodeID: 9}
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO stock_variants")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT quantity::float8, reserved_quantity::float8, average_cost::float8")).
+		WithArgs(3, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved_quantity", "average_cost"}).AddRow(10.0, 8.0, 2.0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE stock_variants")).WithArgs(5.0, 2.0, 3, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO stock (location_id, product_id, quantity, last_updated)")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Newest order first: line 31 gives up both units, line 30 one of six.
+	mock.ExpectQuery(regexp.QuoteMeta("FROM sales_order_lines l")).WithArgs(1, 3, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"sales_order_line_id", "reserved_quantity", "selling_to_stock"}).
+			AddRow(31, 2.0, 1.0).
+			AddRow(30, 6.0, 1.0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sales_order_lines")).WithArgs(2.0, 31).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sales_order_lines")).WithArgs(1.0, 30).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SET reserved_quantity = GREATEST(reserved_quantity + $1, 0)")).WithArgs(-3.0, 3, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO stock (location_id, product_id, quantity, reserved_quantity, last_updated)")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	// A stock count finds 5 missing while sales orders hold 8.
+	if qty, _, err := (&inventoryTrackingService{}).adjustVariantBalanceTx(tx, 1, 3, variant, -5, 0, nil); err != nil || qty != 5 {
+		t.Fatalf("expected the write-down to succeed, got %v, %v", qty, err)
+	}
+	_ = tx.Rollback()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+// InvoiceMatchService performs three-way matching of supplier invoices against
+// purchase orders and goods receipts.
+type InvoiceMatchService struct {
+	db *sql.DB
+}
+
+type invoiceMatchLineInput struct {
+	OrderedQuantity   float64
+	ReceivedQuantity  float64
+	InvoicedQuantity  float64
+	OrderedUnitPrice  float64
+	InvoicedUnitPrice float64
+}
+
+func NewInvoiceMatchService() *InvoiceMatchService {
+	return &InvoiceMatchService{db: database.GetDB()}
+}
+
+// variancePercent returns the deviation of actual from expected as a
+// percentage of expected. Any non-zero actual against a zero expectation is
+// treated as a full (100%) variance.
+func variancePercent(actual, expected float64) float64 {
+	if math.Abs(expected) < 0.0001 {
+		if math.Abs(actual) < 0.0001 {
+			return 0
+		}
+		return 100
+	}
+	return round2((actual - expected) / expected * 100)
+}
+
+// evaluateInvoiceMatchLine compares one invoice line with what was ordered and
+// received. Quantities are matched against the received quantity and prices
+// against the purchase order price; the price variance amount only covers
+// quantities that were both invoiced and received.
+func evaluateInvoiceMatchLine(in invoiceMatchLineInput, quantityTolerance, priceTolerance float64) models.SupplierInvoiceMatchLine {
+	line := models.SupplierInvoiceMatchLine{
+		OrderedQuantity:   in.OrderedQuantity,
+		ReceivedQuantity:  in.ReceivedQuantity,
+		InvoicedQuantity:  in.InvoicedQuantity,
+		OrderedUnitPrice:  in.OrderedUnitPrice,
+		InvoicedUnitPrice: in.InvoicedUnitPrice,
+	}
+	line.QuantityVariance = round4(in.InvoicedQuantity - in.ReceivedQuantity)
+	line.QuantityVariancePercent = variancePercent(in.InvoicedQuantity, in.ReceivedQuantity)
+	line.PriceVariancePercent = variancePercent(in.InvoicedUnitPrice, in.OrderedUnitPrice)
+	line.PriceVarianceAmount = round2((in.InvoicedUnitPrice - in.OrderedUnitPrice) * math.Min(in.InvoicedQuantity, in.ReceivedQuantity))
+
+	quantityOut := math.Abs(line.QuantityVariancePercent) > quantityTolerance+0.0001
+	priceOut := math.Abs(line.PriceVariancePercent) > priceTolerance+0.0001
+	switch {
+	case quantityOut && priceOut:
+		line.Status = models.InvoiceMatchLineQuantityPriceVariance
+	case quantityOut:
+		line.Status = models.InvoiceMatchLineQuantityVariance
+	case priceOut:
+		line.Status = models.InvoiceMatchLinePriceVariance
+	default:
+		line.Status = models.InvoiceMatchLineMatched
+	}
+	return line
+}
+
+func (s *InvoiceMatchService) GetMatch(companyID, purchaseID int) (*models.SupplierInvoiceMatch, error) {
+	var m models.SupplierInvoiceMatch
+	var adjustmentID, approvalID, updatedBy sql.NullInt64
+	var overrideReason sql.NullString
+	err := s.db.QueryRow(`
+		SELECT match_id, company_id, purchase_id, supplier_id, supplier_invoice_number, invoice_date,
+		       invoice_total::float8, status, quantity_tolerance_percent::float8, price_tolerance_percent::float8,
+		       price_variance_total::float8, price_variance_adjustment_id, override_approval_id, override_reason,
+		       created_by, updated_by, created_at, updated_at
+		FROM supplier_invoice_matches
+		WHERE company_id = $1 AND purchase_id = $2
+	`, companyID, purchaseID).Scan(
+		&m.MatchID, &m.CompanyID, &m.PurchaseID, &m.SupplierID, &m.SupplierInvoiceNumber, &m.InvoiceDate,
+		&m.InvoiceTotal, &m.Status, &m.QuantityTolerancePercent, &m.PriceTolerancePercent,
+		&m.PriceVarianceTotal, &adjustmentID, &approvalID, &overrideReason,
+		&m.CreatedBy, &updatedBy, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invoice match not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice match: %w", err)
+	}
+	m.PriceVarianceAdjustmentID = intPtrFromNullInt64(adjustmentID)
+	m.OverrideApprovalID = intPtrFromNullInt64(approvalID)
+	m.OverrideReason = nullStringPtr(overrideReason)
+	m.UpdatedBy = intPtrFromNullInt64(updatedBy)
+
+	rows, err := s.db.Query(`
+		SELECT l.match_line_id, l.match_id, l.purchase_detail_id, l.product_id, p.name,
+		       l.ordered_quantity::float8, l.received_quantity::float8, l.invoiced_quantity::float8,
+		       l.ordered_unit_price::float8, l.invoiced_unit_price::float8, l.quantity_variance::float8,
+		       l.quantity_variance_percent::float8, l.price_variance_percent::float8,
+		       l.price_variance_amount::float8, l.status
+		FROM supplier_invoice_match_lines l
+		JOIN products p ON p.product_id = l.product_id
+		WHERE l.match_id = $1
+		ORDER BY l.purchase_detail_id
+	`, m.MatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice match lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.SupplierInvoiceMatchLine
+		if err := rows.Scan(
+			&line.MatchLineID, &line.MatchID, &line.PurchaseDetailID, &line.ProductID, &line.ProductName,
+			&line.OrderedQuantity, &line.ReceivedQuantity, &line.InvoicedQuantity,
+			&line.OrderedUnitPrice, &line.InvoicedUnitPrice, &line.QuantityVariance,
+			&line.QuantityVariancePercent, &line.PriceVariancePercent,
+			&line.PriceVarianceAmount, &line.Status,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan invoice match line: %w", err)
+		}
+		m.Lines = append(m.Lines, line)
+	}
+	return &m, rows.Err()
+}
+
+// RecordInvoice matches a supplier invoice against its purchase order. A clean
+// match is finalized immediately and its price variances are posted; otherwise
+// the match stays in VARIANCE until it is re-entered or overridden.
+func (s *InvoiceMatchService) RecordInvoice(companyID, userID, purchaseID int, req *models.RecordSupplierInvoiceRequest) (*models.SupplierInvoiceMatch, error) {
+	settings, err := (&SettingsService{db: s.db}).GetThreeWayMatchSettings(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoiceDate := time.Now()
+	if req.InvoiceDate != nil && strings.TrimSpace(*req.InvoiceDate) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(*req.InvoiceDate))
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice_date")
+		}
+		invoiceDate = parsed
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var supplierID int
+	var purchaseStatus string
+	if err := tx.QueryRow(`
+		SELECT p.supplier_id, p.status
+		FROM purchases p
+		JOIN suppliers s ON s.supplier_id = p.supplier_id
+		WHERE p.purchase_id = $1 AND s.company_id = $2 AND p.is_deleted = FALSE
+		FOR UPDATE OF p
+	`, purchaseID, companyID).Scan(&supplierID, &purchaseStatus); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("purchase not found")
+		}
+		return nil, fmt.Errorf("failed to verify purchase: %w", err)
+	}
+	if purchaseStatus == "CANCELLED" {
+		return nil, fmt.Errorf("cannot match an invoice against a cancelled purchase")
+	}
+
+	var matchID int
+	var matchStatus string
+	err = tx.QueryRow(`
+		SELECT match_id, status FROM supplier_invoice_matches WHERE purchase_id = $1 FOR UPDATE
+	`, purchaseID).Scan(&matchID, &matchStatus)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load invoice match: %w", err)
+	}
+	switch matchStatus {
+	case models.InvoiceMatchStatusMatched, models.InvoiceMatchStatusOverridden:
+		return nil, fmt.Errorf("invoice match is already finalized")
+	case models.InvoiceMatchStatusOverridePending:
+		return nil, fmt.Errorf("invoice match override is pending approval")
+	}
+
+	inputs := make(map[int]models.SupplierInvoiceLineInput, len(req.Lines))
+	for _, line := range req.Lines {
+		if _, dup := inputs[line.PurchaseDetailID]; dup {
+			return nil, fmt.Errorf("purchase detail %d is listed more than once", line.PurchaseDetailID)
+		}
+		inputs[line.PurchaseDetailID] = line
+	}
+
+	rows, err := tx.Query(`
+		SELECT purchase_detail_id, product_id, quantity::float8,
+		       COALESCE(received_quantity, 0)::float8, unit_price::float8
+		FROM purchase_details
+		WHERE purchase_id = $1
+		ORDER BY purchase_detail_id
+	`, purchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load purchase details: %w", err)
+	}
+	var lines []models.SupplierInvoiceMatchLine
+	for rows.Next() {
+		var detailID, productID int
+		var in invoiceMatchLineInput
+		if err := rows.Scan(&detailID, &productID, &in.OrderedQuantity, &in.ReceivedQuantity, &in.OrderedUnitPrice); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan purchase detail: %w", err)
+		}
+		in.InvoicedUnitPrice = in.OrderedUnitPrice
+		if invoiced, ok := inputs[detailID]; ok {
+			in.InvoicedQuantity = invoiced.Quantity
+			in.InvoicedUnitPrice = invoiced.UnitPrice
+			delete(inputs, detailID)
+		}
+		line := evaluateInvoiceMatchLine(in, settings.QuantityTolerancePercent, settings.PriceTolerancePercent)
+		line.PurchaseDetailID = detailID
+		line.ProductID = productID
+		lines = append(lines, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load purchase details: %w", err)
+	}
+	for _, line := range req.Lines {
+		if _, unmatched := inputs[line.PurchaseDetailID]; unmatched {
+			return nil, fmt.Errorf("purchase detail %d does not belong to purchase", line.PurchaseDetailID)
+		}
+	}
+
+	status := models.InvoiceMatchStatusMatched
+	invoiceTotal := 0.0
+	for _, line := range lines {
+		invoiceTotal += line.InvoicedQuantity * line.InvoicedUnitPrice
+		if line.Status != models.InvoiceMatchLineMatched {
+			status = models.InvoiceMatchStatusVariance
+		}
+	}
+
+	invoiceNumber := strings.TrimSpace(req.SupplierInvoiceNumber)
+	if matchID == 0 {
+		if err := tx.QueryRow(`
+			INSERT INTO supplier_invoice_matches (
+				company_id, purchase_id, supplier_id, supplier_invoice_number, invoice_date, invoice_total,
+				status, quantity_tolerance_percent, price_tolerance_percent, created_by, updated_by
+			)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$10)
+			RETURNING match_id
+		`, companyID, purchaseID, supplierID, invoiceNumber, invoiceDate, round2(invoiceTotal),
+			status, settings.QuantityTolerancePercent, settings.PriceTolerancePercent, userID).Scan(&matchID); err != nil {
+			return nil, fmt.Errorf("failed to create invoice match: %w", err)
+		}
+	} else {
+		if _, err := tx.Exec(`
+			UPDATE supplier_invoice_matches
+			SET supplier_invoice_number = $1, invoice_date = $2, invoice_total = $3, status = $4,
+			    quantity_tolerance_percent = $5, price_tolerance_percent = $6,
+			    updated_by = $7, updated_at = CURRENT_TIMESTAMP
+			WHERE match_id = $8
+		`, invoiceNumber, invoiceDate, round2(invoiceTotal), status,
+			settings.QuantityTolerancePercent, settings.PriceTolerancePercent, userID, matchID); err != nil {
+			return nil, fmt.Errorf("failed to update invoice match: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM supplier_invoice_match_lines WHERE match_id = $1`, matchID); err != nil {
+			return nil, fmt.Errorf("failed to reset invoice match lines: %w", err)
+		}
+	}
+
+	for _, line := range lines {
+		if _, err := tx.Exec(`
+			INSERT INTO supplier_invoice_match_lines (
+				match_id, purchase_detail_id, product_id, ordered_quantity, received_quantity, invoiced_quantity,
+				ordered_unit_price, invoiced_unit_price, quantity_variance, quantity_variance_percent,
+				price_variance_percent, price_variance_amount, status
+			)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		`, matchID, line.PurchaseDetailID, line.ProductID, line.OrderedQuantity, line.ReceivedQuantity, line.InvoicedQuantity,
+			line.OrderedUnitPrice, line.InvoicedUnitPrice, line.QuantityVariance, line.QuantityVariancePercent,
+			line.PriceVariancePercent, line.PriceVarianceAmount, line.Status); err != nil {
+			return nil, fmt.Errorf("failed to insert invoice match line: %w", err)
+		}
+	}
+
+	var adjustmentID *int
+	if status == models.InvoiceMatchStatusMatched {
+		adjustmentID, err = s.postPriceVariancesTx(tx, companyID, userID, matchID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice match: %w", err)
+	}
+	if adjustmentID != nil {
+		_ = (&LedgerService{db: s.db}).RecordPurchaseCostAdjustment(companyID, *adjustmentID, userID)
+	}
+	return s.GetMatch(companyID, purchaseID)
+}
+
+// postPriceVariancesTx revalues received stock for the accepted invoice
+// prices and raises the purchase total accordingly. The returned adjustment
+// must be posted to the ledger once the transaction commits.
+func (s *InvoiceMatchService) postPriceVariancesTx(tx *sql.Tx, companyID, userID, matchID int) (*int, error) {
+	var purchaseID, supplierID, locationID int
+	var invoiceNumber string
+	if err := tx.QueryRow(`
+		SELECT m.purchase_id, m.supplier_id, p.location_id, m.supplier_invoice_number
+		FROM supplier_invoice_matches m
+		JOIN purchases p ON p.purchase_id = m.purchase_id
+		WHERE m.match_id = $1 AND m.company_id = $2
+	`, matchID, companyID).Scan(&purchaseID, &supplierID, &locationID, &invoiceNumber); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invoice match not found")
+		}
+		return nil, fmt.Errorf("failed to load invoice match: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT purchase_detail_id, product_id, price_variance_amount::float8
+		FROM supplier_invoice_match_lines
+		WHERE match_id = $1 AND price_variance_amount <> 0 AND received_quantity > 0
+		ORDER BY purchase_detail_id
+	`, matchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price variances: %w", err)
+	}
+	type varianceLine struct {
+		PurchaseDetailID int
+		ProductID        int
+		Amount           float64
+	}
+	var variances []varianceLine
+	for rows.Next() {
+		var v varianceLine
+		if err := rows.Scan(&v.PurchaseDetailID, &v.ProductID, &v.Amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan price variance: %w", err)
+		}
+		variances = append(variances, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load price variances: %w", err)
+	}
+	if len(variances) == 0 {
+		return nil, nil
+	}
+
+	costSvc := &PurchaseCostAdjustmentService{db: s.db}
+	trackingSvc := newInventoryTrackingService(s.db)
+	notes := fmt.Sprintf("Invoice price variance for supplier invoice %s", invoiceNumber)
+	adjustment, err := costSvc.createAdjustmentDocumentTx(tx, companyID, userID, models.PurchaseCostAdjustmentTypeInvoiceVariance, nil, &purchaseID, locationID, supplierID, &invoiceNumber, &notes)
+	if err != nil {
+		return nil, err
+	}
+
+	totalSigned := 0.0
+	for _, v := range variances {
+		ctx, err := costSvc.loadPurchaseDetailContextTx(tx, companyID, v.PurchaseDetailID)
+		if err != nil {
+			return nil, err
+		}
+		detailID := v.PurchaseDetailID
+		_, result, err := costSvc.applyCostOnlyLineTx(tx, companyID, userID, adjustment.AdjustmentID, costAdjustmentLine{
+			SourceScope:      models.PurchaseCostAdjustmentScopeItem,
+			PurchaseDetailID: &detailID,
+			ProductID:        v.ProductID,
+			BarcodeID:        ctx.BarcodeID,
+			Label:            "Invoice price variance",
+			StockAction:      models.PurchaseCostAdjustmentStockActionCostOnly,
+			SignedAmount:     v.Amount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		totalSigned += result.SignedAmount
+		if ctx.BarcodeID != nil && *ctx.BarcodeID > 0 {
+			if err := costSvc.syncVariantAverageCostTx(tx, locationID, *ctx.BarcodeID); err != nil {
+				return nil, err
+			}
+		}
+		if err := trackingSvc.updateProductCostSnapshotTx(tx, companyID, v.ProductID); err != nil {
+			return nil, fmt.Errorf("failed to update product cost snapshot: %w", err)
+		}
+	}
+
+	if err := costSvc.finalizeAdjustmentTx(tx, adjustment.AdjustmentID, userID, round2(totalSigned)); err != nil {
+		return nil, err
+	}
+	if err := costSvc.updatePurchaseTotalsTx(tx, purchaseID, companyID, round2(totalSigned)); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE supplier_invoice_matches
+		SET price_variance_total = $1, price_variance_adjustment_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE match_id = $3
+	`, round2(totalSigned), adjustment.AdjustmentID, matchID); err != nil {
+		return nil, fmt.Errorf("failed to link price variance adjustment: %w", err)
+	}
+	return &adjustment.AdjustmentID, nil
+}
+
+// RequestOverride raises a workflow approval to accept a match whose variances
+// exceed tolerance.
+func (s *InvoiceMatchService) RequestOverride(companyID, userID, purchaseID int, reason string) (*models.WorkflowRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var matchID, locationID int
+	var status, invoiceNumber, purchaseNumber string
+	if err := tx.QueryRow(`
+		SELECT m.match_id, m.status, m.supplier_invoice_number, p.purchase_number, p.location_id
+		FROM supplier_invoice_matches m
+		JOIN purchases p ON p.purchase_id = m.purchase_id
+		WHERE m.company_id = $1 AND m.purchase_id = $2
+		FOR UPDATE OF m
+	`, companyID, purchaseID).Scan(&matchID, &status, &invoiceNumber, &purchaseNumber, &locationID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("invoice match not found")
+		}
+		return nil, fmt.Errorf("failed to load invoice match: %w", err)
+	}
+	if status != models.InvoiceMatchStatusVariance {
+		return nil, fmt.Errorf("only invoice matches with variances can be overridden")
+	}
+
+	workflow := &WorkflowService{db: s.db}
+	approverRoleID, err := workflow.findApproverRoleTx(tx, companyID, "Purchase Manager", "Manager", "Admin", "Super Admin")
+	if err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	title := fmt.Sprintf("Override invoice match for purchase %s", purchaseNumber)
+	summary := fmt.Sprintf("Supplier invoice %s has variances beyond tolerance", invoiceNumber)
+	dueAt := time.Now().Add(24 * time.Hour)
+	created, err := workflow.createRequestTx(tx, companyID, userID, workflowCreateInput{
+		LocationID:     &locationID,
+		Module:         workflowModulePurchases,
+		EntityType:     workflowEntityInvoiceMatch,
+		EntityID:       &matchID,
+		ActionType:     workflowActionOverrideInvoiceMatch,
+		Title:          title,
+		Summary:        &summary,
+		RequestReason:  &reason,
+		Priority:       workflowPriorityHigh,
+		ApproverRoleID: approverRoleID,
+		Payload: models.JSONB{
+			"match_id":                matchID,
+			"purchase_id":             purchaseID,
+			"purchase_number":         purchaseNumber,
+			"supplier_invoice_number": invoiceNumber,
+		},
+		DueAt: &dueAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE supplier_invoice_matches
+		SET status = $1, override_approval_id = $2, override_reason = $3,
+		    updated_by = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE match_id = $5
+	`, models.InvoiceMatchStatusOverridePending, created.ApprovalID, reason, userID, matchID); err != nil {
+		return nil, fmt.Errorf("failed to mark invoice match override pending: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice match override request: %w", err)
+	}
+	return created, nil
+}
+
+// applyOverrideTx accepts an overridden match and posts its price variances.
+func (s *InvoiceMatchService) applyOverrideTx(tx *sql.Tx, companyID, matchID, userID int) (*int, error) {
+	result, err := tx.Exec(`
+		UPDATE supplier_invoice_matches
+		SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE match_id = $3 AND company_id = $4 AND status = $5
+	`, models.InvoiceMatchStatusOverridden, userID, matchID, companyID, models.InvoiceMatchStatusOverridePending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to override invoice match: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to inspect invoice match override result: %w", err)
+	} else if rows == 0 {
+		return nil, fmt.Errorf("invoice match is not awaiting override")
+	}
+	return s.postPriceVariancesTx(tx, companyID, userID, matchID)
+}
+
+// releaseOverrideTx returns a match to VARIANCE after its override was rejected.
+func (s *InvoiceMatchService) releaseOverrideTx(tx *sql.Tx, companyID, matchID, userID int) error {
+	if _, err := tx.Exec(`
+		UPDATE supplier_invoice_matches
+		SET status = $1, override_approval_id = NULL, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE match_id = $3 AND company_id = $4 AND status = $5
+	`, models.InvoiceMatchStatusVariance, userID, matchID, companyID, models.InvoiceMatchStatusOverridePending); err != nil {
+		return fmt.Errorf("failed to release invoice match override: %w", err)
+	}
+	return nil
+}
+
+// ensurePaymentAllowedTx blocks supplier payments against purchases whose
+// invoice has unresolved variances, or that have no matched invoice at all
+// when the company requires one.
+func (s *InvoiceMatchService) ensurePaymentAllowedTx(tx *sql.Tx, companyID, purchaseID int) error {
+	var status string
+	err := tx.QueryRow(`
+		SELECT status FROM supplier_invoice_matches WHERE company_id = $1 AND purchase_id = $2
+	`, companyID, purchaseID).Scan(&status)
+	if err == sql.ErrNoRows {
+		settings, err := (&SettingsService{db: s.db}).GetThreeWayMatchSettings(companyID)
+		if err != nil {
+			return err
+		}
+		if settings.RequireMatchBeforePayment {
+			return fmt.Errorf("supplier invoice must be matched before payment")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check invoice match: %w", err)
+	}
+	switch status {
+	case models.InvoiceMatchStatusVariance:
+		return fmt.Errorf("supplier invoice has unresolved match variances")
+	case models.InvoiceMatchStatusOverridePending:
+		return fmt.Errorf("supplier invoice match override is pending approval")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestEvaluateInvoiceMatchLine_WithinTolerance(t *testing.T) {
+	line := evaluateInvoiceMatchLine(invoiceMatchLineInput{
+		OrderedQuantity:   10,
+		ReceivedQuantity:  10,
+		InvoicedQuantity:  10,
+		OrderedUnitPrice:  100,
+		InvoicedUnitPrice: 101,
+	}, 0, 2)
+	if line.Status != models.InvoiceMatchLineMatched {
+		t.Fatalf("expected matched line, got %s", line.Status)
+	}
+	if line.PriceVariancePercent != 1 || line.PriceVarianceAmount != 10 {
+		t.Fatalf("expected 1%% / 10.00 price variance, got %v / %v", line.PriceVariancePercent, line.PriceVarianceAmount)
+	}
+}
+
+func TestEvaluateInvoiceMatchLine_FlagsQuantityAndPrice(t *testing.T) {
+	line := evaluateInvoiceMatchLine(invoiceMatchLineInput{
+		OrderedQuantity:   10,
+		ReceivedQuantity:  8,
+		InvoicedQuantity:  10,
+		OrderedUnitPrice:  50,
+		InvoicedUnitPrice: 55,
+	}, 5, 5)
+	if line.Status != models.InvoiceMatchLineQuantityPriceVariance {
+		t.Fatalf("expected quantity and price variance, got %s", line.Status)
+	}
+	if line.QuantityVariance != 2 || line.QuantityVariancePercent != 25 {
+		t.Fatalf("expected +2 units (25%%), got %v (%v%%)", line.QuantityVariance, line.QuantityVariancePercent)
+	}
+	// Only the 8 received units carry a price variance.
+	if line.PriceVarianceAmount != 40 {
+		t.Fatalf("expected price variance amount 40, got %v", line.PriceVarianceAmount)
+	}
+}
+
+func TestEvaluateInvoiceMatchLine_InvoicedWithoutReceipt(t *testing.T) {
+	line := evaluateInvoiceMatchLine(invoiceMatchLineInput{
+		OrderedQuantity:   5,
+		InvoicedQuantity:  5,
+		OrderedUnitPrice:  20,
+		InvoicedUnitPrice: 20,
+	}, 10, 10)
+	if line.Status != models.InvoiceMatchLineQuantityVariance {
+		t.Fatalf("expected quantity variance for unreceived goods, got %s", line.Status)
+	}
+	if line.PriceVarianceAmount != 0 {
+		t.Fatalf("expected no price variance amount, got %v", line.PriceVarianceAmount)
+	}
+}
@@ -48,6 +48,9 @@ func (s *InvoiceTemplateService) GetInvoiceTemplateByID(id, companyID int) (*mod
 }
 
 func (s *InvoiceTemplateService) CreateInvoiceTemplate(req *models.CreateInvoiceTemplateRequest) (*models.InvoiceTemplate, error) {
+	if _, err := parseDocumentLayout(req.Layout); err != nil {
+		return nil, err
+	}
 	exists, err := s.checkCompanyExists(req.CompanyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check company existence: %w", err)
@@ -86,6 +89,9 @@ func (s *InvoiceTemplateService) UpdateInvoiceTemplate(id, companyID int, req *m
 		args = append(args, *req.TemplateType)
 	}
 	if req.Layout != nil {
+		if _, err := parseDocumentLayout(*req.Layout); err != nil {
+			return err
+		}
 		setParts = append(setParts, fmt.Sprintf("layout = $%d", len(args)+1))
 		args = append(args, *req.Layout)
 	}
@@ -0,0 +1,531 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// LandedCostService spreads third-party charges (freight, duty, clearing)
+// over the lines of several goods receipts. Lots still on hand are revalued;
+// the share belonging to quantities already consumed goes to COGS. Each
+// charge is payable to its own supplier, so the goods purchases keep their
+// totals.
+type LandedCostService struct {
+	db          *sql.DB
+	costService *PurchaseCostAdjustmentService
+}
+
+type landedCostLine struct {
+	GoodsReceiptItemID int
+	GoodsReceiptID     int
+	LocationID         int
+	PurchaseDetailID   int
+	ProductID          int
+	ProductName        string
+	BarcodeID          *int
+	StockQuantity      float64
+	LineTotal          float64
+	Weight             float64
+	Volume             float64
+}
+
+func NewLandedCostService() *LandedCostService {
+	db := database.GetDB()
+	return &LandedCostService{db: db, costService: &PurchaseCostAdjustmentService{db: db}}
+}
+
+func landedCostBasis(method string, line landedCostLine) float64 {
+	switch method {
+	case models.LandedCostAllocationByQuantity:
+		return line.StockQuantity
+	case models.LandedCostAllocationByWeight:
+		return line.Weight
+	case models.LandedCostAllocationByVolume:
+		return line.Volume
+	default:
+		return math.Max(line.LineTotal, 0)
+	}
+}
+
+// allocateLandedCharge splits amount over lines by method and returns the
+// basis used for each line alongside its share. Weight and volume must be
+// known for every line, otherwise the split would silently fall back to equal
+// shares.
+func allocateLandedCharge(method string, amount float64, lines []landedCostLine) ([]float64, []float64, error) {
+	basis := make([]float64, len(lines))
+	for i, line := range lines {
+		basis[i] = landedCostBasis(method, line)
+		if basis[i] <= 0 && (method == models.LandedCostAllocationByWeight || method == models.LandedCostAllocationByVolume) {
+			return nil, nil, fmt.Errorf("%s of %s on goods receipt %d is unknown; supply it on the receipt line", strings.ToLower(method), line.ProductName, line.GoodsReceiptID)
+		}
+	}
+	return basis, distributeWeightedAmount(amount, basis), nil
+}
+
+// splitLandedCost divides a line's charge between stock still on hand and
+// stock already consumed.
+func splitLandedCost(amount, remainingQty, stockQty float64) (float64, float64) {
+	if stockQty <= 0 {
+		return 0, round2(amount)
+	}
+	remainingQty = math.Min(math.Max(remainingQty, 0), stockQty)
+	inventory := round2(amount * remainingQty / stockQty)
+	return inventory, round2(amount - inventory)
+}
+
+func (s *LandedCostService) CreateVoucher(companyID, locationID, userID int, req *models.CreateLandedCostVoucherRequest) (*models.LandedCostVoucher, error) {
+	voucherDate := time.Now()
+	if req.VoucherDate != nil && strings.TrimSpace(*req.VoucherDate) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(*req.VoucherDate))
+		if err != nil {
+			return nil, fmt.Errorf("voucher_date must be YYYY-MM-DD")
+		}
+		voucherDate = parsed
+	}
+	receiptIDs := make([]int, 0, len(req.GoodsReceiptIDs))
+	seenReceipts := make(map[int]bool, len(req.GoodsReceiptIDs))
+	for _, id := range req.GoodsReceiptIDs {
+		if !seenReceipts[id] {
+			seenReceipts[id] = true
+			receiptIDs = append(receiptIDs, id)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locationOK bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2)`, locationID, companyID).Scan(&locationOK); err != nil {
+		return nil, fmt.Errorf("failed to verify location: %w", err)
+	}
+	if !locationOK {
+		return nil, fmt.Errorf("location not found")
+	}
+
+	supplierIDs := make([]int, 0, len(req.Charges))
+	for _, charge := range req.Charges {
+		supplierIDs = append(supplierIDs, charge.SupplierID)
+	}
+	suppliers, err := s.loadSupplierNamesTx(tx, companyID, supplierIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range supplierIDs {
+		if _, ok := suppliers[id]; !ok {
+			return nil, fmt.Errorf("supplier %d not found", id)
+		}
+	}
+
+	lines, err := s.loadReceiptLinesTx(tx, companyID, receiptIDs)
+	if err != nil {
+		return nil, err
+	}
+	byItem := make(map[int]int, len(lines))
+	for i, line := range lines {
+		byItem[line.GoodsReceiptItemID] = i
+	}
+	for _, measure := range req.Lines {
+		idx, ok := byItem[measure.GoodsReceiptItemID]
+		if !ok {
+			return nil, fmt.Errorf("goods receipt item %d is not on the selected receipts", measure.GoodsReceiptItemID)
+		}
+		if measure.Weight != nil {
+			lines[idx].Weight = *measure.Weight
+		}
+		if measure.Volume != nil {
+			lines[idx].Volume = *measure.Volume
+		}
+	}
+
+	ns := NewNumberingSequenceService()
+	voucherNumber, err := ns.NextNumber(tx, "landed_cost_voucher", companyID, &locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate voucher number: %w", err)
+	}
+
+	var voucherID int
+	if err := tx.QueryRow(`
+		INSERT INTO landed_cost_vouchers (
+			company_id, location_id, voucher_number, voucher_date, allocation_method,
+			reference_number, notes, created_by
+		)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		RETURNING voucher_id
+	`, companyID, locationID, voucherNumber, voucherDate, req.AllocationMethod,
+		req.ReferenceNumber, req.Notes, userID,
+	).Scan(&voucherID); err != nil {
+		return nil, fmt.Errorf("failed to create landed cost voucher: %w", err)
+	}
+	for _, receiptID := range receiptIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO landed_cost_voucher_receipts (voucher_id, goods_receipt_id)
+			VALUES ($1, $2)
+		`, voucherID, receiptID); err != nil {
+			return nil, fmt.Errorf("failed to link goods receipt: %w", err)
+		}
+	}
+
+	type pendingAllocation struct {
+		ChargeID int
+		Basis    float64
+		Amount   float64
+	}
+	pending := make([][]pendingAllocation, len(lines))
+	lineTotals := make([]float64, len(lines))
+	totalAmount := 0.0
+	for _, charge := range req.Charges {
+		method := req.AllocationMethod
+		if charge.AllocationMethod != nil && *charge.AllocationMethod != "" {
+			method = *charge.AllocationMethod
+		}
+		amount := round2(charge.Amount)
+		basis, shares, err := allocateLandedCharge(method, amount, lines)
+		if err != nil {
+			return nil, err
+		}
+		var chargeID int
+		if err := tx.QueryRow(`
+			INSERT INTO landed_cost_charges (voucher_id, supplier_id, label, invoice_number, allocation_method, amount)
+			VALUES ($1,$2,$3,$4,$5,$6)
+			RETURNING charge_id
+		`, voucherID, charge.SupplierID, strings.TrimSpace(charge.Label), charge.InvoiceNumber, method, amount).Scan(&chargeID); err != nil {
+			return nil, fmt.Errorf("failed to create landed cost charge: %w", err)
+		}
+		for i := range lines {
+			if math.Abs(shares[i]) < 0.005 {
+				continue
+			}
+			pending[i] = append(pending[i], pendingAllocation{ChargeID: chargeID, Basis: basis[i], Amount: shares[i]})
+			lineTotals[i] += shares[i]
+		}
+		totalAmount += amount
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	inventoryAmount := 0.0
+	cogsAmount := 0.0
+	touchedVariants := make(map[[2]int]bool)
+	touchedProducts := make(map[int]bool)
+	for i, line := range lines {
+		if len(pending[i]) == 0 {
+			continue
+		}
+		lots, err := s.costService.loadLotsForRevaluationTx(tx, line.LocationID, line.PurchaseDetailID, line.BarcodeID, line.GoodsReceiptItemID)
+		if err != nil {
+			return nil, err
+		}
+		unitDelta := round2(lineTotals[i]) / line.StockQuantity
+		remainingQty := 0.0
+		for _, lot := range lots {
+			if _, err := tx.Exec(`UPDATE stock_lots SET cost_price = $1 WHERE lot_id = $2`, round2(lot.CostPrice+unitDelta), lot.LotID); err != nil {
+				return nil, fmt.Errorf("failed to update stock lot cost: %w", err)
+			}
+			if _, err := tx.Exec(`
+				UPDATE product_serials
+				SET cost_price = $1
+				WHERE stock_lot_id = $2 AND status = 'IN_STOCK'
+			`, round4(lot.CostPrice+unitDelta), lot.LotID); err != nil {
+				return nil, fmt.Errorf("failed to update serial cost: %w", err)
+			}
+			remainingQty += lot.RemainingQuantity
+			touchedVariants[[2]int{line.LocationID, lot.BarcodeID}] = true
+		}
+		touchedProducts[line.ProductID] = true
+
+		for _, alloc := range pending[i] {
+			inventory, cogs := splitLandedCost(alloc.Amount, remainingQty, line.StockQuantity)
+			if _, err := tx.Exec(`
+				INSERT INTO landed_cost_allocations (
+					voucher_id, charge_id, goods_receipt_item_id, purchase_detail_id, product_id, barcode_id,
+					stock_quantity, basis, amount, inventory_amount, cogs_amount
+				)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+			`, voucherID, alloc.ChargeID, line.GoodsReceiptItemID, line.PurchaseDetailID, line.ProductID, line.BarcodeID,
+				line.StockQuantity, round4(alloc.Basis), alloc.Amount, inventory, cogs); err != nil {
+				return nil, fmt.Errorf("failed to record landed cost allocation: %w", err)
+			}
+			inventoryAmount += inventory
+			cogsAmount += cogs
+		}
+	}
+	for key := range touchedVariants {
+		if err := s.costService.syncVariantAverageCostTx(tx, key[0], key[1]); err != nil {
+			return nil, err
+		}
+	}
+	for productID := range touchedProducts {
+		if err := trackingSvc.updateProductCostSnapshotTx(tx, companyID, productID); err != nil {
+			return nil, fmt.Errorf("failed to update product cost snapshot: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE landed_cost_vouchers
+		SET total_amount = $1, inventory_amount = $2, cogs_amount = $3
+		WHERE voucher_id = $4
+	`, round2(totalAmount), round2(inventoryAmount), round2(cogsAmount), voucherID); err != nil {
+		return nil, fmt.Errorf("failed to update landed cost voucher totals: %w", err)
+	}
+
+	financeSvc := NewFinanceIntegrityServiceWithDB(s.db)
+	if err := financeSvc.EnqueueTx(tx, &models.FinanceOutboxEntry{
+		CompanyID:     companyID,
+		LocationID:    &locationID,
+		EventType:     financeEventLedgerLandedCost,
+		AggregateType: "landed_cost_voucher",
+		AggregateID:   voucherID,
+		Payload:       models.JSONB{},
+		CreatedBy:     &userID,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit landed cost voucher: %w", err)
+	}
+
+	if err := financeSvc.ProcessAggregate(companyID, "landed_cost_voucher", voucherID); err != nil {
+		log.Printf("warning: failed to process finance outbox for landed cost voucher %d: %v", voucherID, err)
+	}
+	return s.GetVoucher(companyID, voucherID)
+}
+
+func (s *LandedCostService) loadSupplierNamesTx(tx *sql.Tx, companyID int, supplierIDs []int) (map[int]string, error) {
+	rows, err := tx.Query(`
+		SELECT supplier_id, name
+		FROM suppliers
+		WHERE company_id = $1 AND supplier_id = ANY($2)
+	`, companyID, pq.Array(supplierIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[int]string, len(supplierIDs))
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// loadReceiptLinesTx returns the receipt lines in stock units. Lines that put
+// nothing into stock cannot carry a charge and are left out.
+func (s *LandedCostService) loadReceiptLinesTx(tx *sql.Tx, companyID int, receiptIDs []int) ([]landedCostLine, error) {
+	rows, err := tx.Query(`
+		SELECT gri.goods_receipt_item_id, gr.goods_receipt_id, gr.location_id, gri.purchase_detail_id,
+		       gri.product_id, p.name, gri.barcode_id,
+		       (gri.received_quantity * COALESCE(pd.purchase_to_stock_factor, 1.0))::float8,
+		       COALESCE(gri.line_total, 0)::float8,
+		       COALESCE(p.weight, 0)::float8
+		FROM goods_receipts gr
+		JOIN suppliers s ON s.supplier_id = gr.supplier_id
+		JOIN goods_receipt_items gri ON gri.goods_receipt_id = gr.goods_receipt_id
+		JOIN products p ON p.product_id = gri.product_id
+		LEFT JOIN purchase_details pd ON pd.purchase_detail_id = gri.purchase_detail_id
+		WHERE gr.goods_receipt_id = ANY($1) AND s.company_id = $2 AND gr.is_deleted = FALSE
+		ORDER BY gr.goods_receipt_id, gri.goods_receipt_item_id
+	`, pq.Array(receiptIDs), companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load goods receipt items: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(receiptIDs))
+	lines := make([]landedCostLine, 0)
+	for rows.Next() {
+		var line landedCostLine
+		var purchaseDetailID, barcodeID sql.NullInt64
+		var unitWeight float64
+		if err := rows.Scan(
+			&line.GoodsReceiptItemID, &line.GoodsReceiptID, &line.LocationID, &purchaseDetailID,
+			&line.ProductID, &line.ProductName, &barcodeID,
+			&line.StockQuantity, &line.LineTotal, &unitWeight,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan goods receipt item: %w", err)
+		}
+		found[line.GoodsReceiptID] = true
+		if line.StockQuantity <= 0 {
+			continue
+		}
+		if !purchaseDetailID.Valid {
+			// Lots are traced back to their receipt through the purchase line.
+			return nil, fmt.Errorf("goods receipt %d has lines without a purchase line and cannot take landed costs", line.GoodsReceiptID)
+		}
+		line.PurchaseDetailID = int(purchaseDetailID.Int64)
+		line.BarcodeID = intPtrFromNullInt64(barcodeID)
+		line.Weight = unitWeight * line.StockQuantity
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read goods receipt items: %w", err)
+	}
+	for _, id := range receiptIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("goods receipt %d not found", id)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("selected goods receipts have no received stock")
+	}
+	return lines, nil
+}
+
+func (s *LandedCostService) GetVouchers(companyID int, supplierID, goodsReceiptID *int) ([]models.LandedCostVoucher, error) {
+	args := []interface{}{companyID}
+	query := `
+		SELECT v.voucher_id, v.company_id, v.location_id, v.voucher_number, v.voucher_date,
+		       v.allocation_method, v.reference_number, v.notes, v.total_amount::float8,
+		       v.inventory_amount::float8, v.cogs_amount::float8, v.created_by, v.created_at,
+		       COALESCE((
+		           SELECT array_agg(r.goods_receipt_id ORDER BY r.goods_receipt_id)
+		           FROM landed_cost_voucher_receipts r
+		           WHERE r.voucher_id = v.voucher_id
+		       ), '{}')
+		FROM landed_cost_vouchers v
+		WHERE v.company_id = $1
+	`
+	if supplierID != nil {
+		args = append(args, *supplierID)
+		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM landed_cost_charges c WHERE c.voucher_id = v.voucher_id AND c.supplier_id = $%d)", len(args))
+	}
+	if goodsReceiptID != nil {
+		args = append(args, *goodsReceiptID)
+		query += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM landed_cost_voucher_receipts r WHERE r.voucher_id = v.voucher_id AND r.goods_receipt_id = $%d)", len(args))
+	}
+	query += " ORDER BY v.voucher_date DESC, v.voucher_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost vouchers: %w", err)
+	}
+	defer rows.Close()
+
+	vouchers := make([]models.LandedCostVoucher, 0)
+	for rows.Next() {
+		voucher, err := scanLandedCostVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, *voucher)
+	}
+	return vouchers, rows.Err()
+}
+
+func (s *LandedCostService) GetVoucher(companyID, voucherID int) (*models.LandedCostVoucher, error) {
+	voucher, err := scanLandedCostVoucher(s.db.QueryRow(`
+		SELECT v.voucher_id, v.company_id, v.location_id, v.voucher_number, v.voucher_date,
+		       v.allocation_method, v.reference_number, v.notes, v.total_amount::float8,
+		       v.inventory_amount::float8, v.cogs_amount::float8, v.created_by, v.created_at,
+		       COALESCE((
+		           SELECT array_agg(r.goods_receipt_id ORDER BY r.goods_receipt_id)
+		           FROM landed_cost_voucher_receipts r
+		           WHERE r.voucher_id = v.voucher_id
+		       ), '{}')
+		FROM landed_cost_vouchers v
+		WHERE v.voucher_id = $1 AND v.company_id = $2
+	`, voucherID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("landed cost voucher not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	chargeRows, err := s.db.Query(`
+		SELECT c.charge_id, c.voucher_id, c.supplier_id, s.name, c.label, c.invoice_number,
+		       c.allocation_method, c.amount::float8
+		FROM landed_cost_charges c
+		JOIN suppliers s ON s.supplier_id = c.supplier_id
+		WHERE c.voucher_id = $1
+		ORDER BY c.charge_id
+	`, voucherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost charges: %w", err)
+	}
+	defer chargeRows.Close()
+	voucher.Charges = make([]models.LandedCostCharge, 0)
+	for chargeRows.Next() {
+		var charge models.LandedCostCharge
+		if err := chargeRows.Scan(
+			&charge.ChargeID, &charge.VoucherID, &charge.SupplierID, &charge.SupplierName, &charge.Label,
+			&charge.InvoiceNumber, &charge.AllocationMethod, &charge.Amount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan landed cost charge: %w", err)
+		}
+		voucher.Charges = append(voucher.Charges, charge)
+	}
+	if err := chargeRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read landed cost charges: %w", err)
+	}
+
+	allocRows, err := s.db.Query(`
+		SELECT a.allocation_id, a.charge_id, gri.goods_receipt_id, a.goods_receipt_item_id,
+		       a.purchase_detail_id, a.product_id, p.name, a.barcode_id, a.stock_quantity::float8,
+		       a.basis::float8, a.amount::float8, a.inventory_amount::float8, a.cogs_amount::float8
+		FROM landed_cost_allocations a
+		JOIN goods_receipt_items gri ON gri.goods_receipt_item_id = a.goods_receipt_item_id
+		JOIN products p ON p.product_id = a.product_id
+		WHERE a.voucher_id = $1
+		ORDER BY a.charge_id, a.allocation_id
+	`, voucherID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get landed cost allocations: %w", err)
+	}
+	defer allocRows.Close()
+	voucher.Allocations = make([]models.LandedCostAllocation, 0)
+	for allocRows.Next() {
+		var alloc models.LandedCostAllocation
+		if err := allocRows.Scan(
+			&alloc.AllocationID, &alloc.ChargeID, &alloc.GoodsReceiptID, &alloc.GoodsReceiptItemID,
+			&alloc.PurchaseDetailID, &alloc.ProductID, &alloc.ProductName, &alloc.BarcodeID, &alloc.StockQuantity,
+			&alloc.Basis, &alloc.Amount, &alloc.InventoryAmount, &alloc.COGSAmount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan landed cost allocation: %w", err)
+		}
+		voucher.Allocations = append(voucher.Allocations, alloc)
+	}
+	if err := allocRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read landed cost allocations: %w", err)
+	}
+	return voucher, nil
+}
+
+func scanLandedCostVoucher(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.LandedCostVoucher, error) {
+	var voucher models.LandedCostVoucher
+	var receiptIDs pq.Int64Array
+	if err := row.Scan(
+		&voucher.VoucherID, &voucher.CompanyID, &voucher.LocationID, &voucher.VoucherNumber, &voucher.VoucherDate,
+		&voucher.AllocationMethod, &voucher.ReferenceNumber, &voucher.Notes, &voucher.TotalAmount,
+		&voucher.InventoryAmount, &voucher.COGSAmount, &voucher.CreatedBy, &voucher.CreatedAt,
+		&receiptIDs,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan landed cost voucher: %w", err)
+	}
+	voucher.GoodsReceiptIDs = make([]int, len(receiptIDs))
+	for i, id := range receiptIDs {
+		voucher.GoodsReceiptIDs[i] = int(id)
+	}
+	return &voucher, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestAllocateLandedCharge(t *testing.T) {
+	lines := []landedCostLine{
+		{ProductName: "Chairs", StockQuantity: 10, LineTotal: 300, Weight: 50, Volume: 2},
+		{ProductName: "Tables", StockQuantity: 5, LineTotal: 700, Weight: 150},
+	}
+
+	cases := map[string][]float64{
+		models.LandedCostAllocationByValue:    {30, 70},
+		models.LandedCostAllocationByQuantity: {66.67, 33.33},
+		models.LandedCostAllocationByWeight:   {25, 75},
+	}
+	for method, want := range cases {
+		_, shares, err := allocateLandedCharge(method, 100, lines)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", method, err)
+		}
+		if !reflect.DeepEqual(shares, want) {
+			t.Fatalf("%s: got %v, want %v", method, shares, want)
+		}
+	}
+
+	if _, _, err := allocateLandedCharge(models.LandedCostAllocationByVolume, 100, lines); err == nil {
+		t.Fatalf("expected an error when a line has no volume")
+	}
+}
+
+func TestSplitLandedCost(t *testing.T) {
+	cases := []struct {
+		amount, remaining, stock float64
+		inventory, cogs          float64
+	}{
+		{100, 10, 10, 100, 0},
+		{100, 4, 10, 40, 60},
+		{100, 0, 10, 0, 100},
+		{100, 12, 10, 100, 0},
+		{33.33, 1, 3, 11.11, 22.22},
+	}
+	for _, tc := range cases {
+		inventory, cogs := splitLandedCost(tc.amount, tc.remaining, tc.stock)
+		if inventory != tc.inventory || cogs != tc.cogs {
+			t.Fatalf("splitLandedCost(%v, %v, %v) = (%v, %v), want (%v, %v)", tc.amount, tc.remaining, tc.stock, inventory, cogs, tc.inventory, tc.cogs)
+		}
+	}
+}
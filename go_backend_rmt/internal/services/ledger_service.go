@@ -25,11 +25,15 @@ const (
 	accountCodeAR            = "1100"
 	accountCodeInventory     = "1200"
 	accountCodeFixedAssets   = "1210"
+	accountCodeInTransit     = "1220"
+	accountCodeWIP           = "1230"
 	accountCodeAP            = "2000"
 	accountCodeTaxPayable    = "2100"
 	accountCodeTaxReceivable = "2200"
 	accountCodeSalesRevenue  = "4000"
 	accountCodeCOGS          = "5000"
+	accountCodeShrinkage     = "5010"
+	accountCodeAppliedOH     = "5020"
 	accountCodeExpenses      = "6000"
 	accountCodeConsumables   = "6010"
 )
@@ -414,7 +418,18 @@ func (s *LedgerService) RecordPayrollPayment(companyID, payrollID, userID int, p
 	if err := s.insertEntryIfMissing(companyID, ref2, cashID, paymentDate, 0, amount, "payroll", payrollID, &desc, nil, userID); err != nil {
 		return err
 	}
-	return nil
+
+	// Payroll posts directly rather than through the finance outbox, so tag
+	// it with the employee's analytic defaults here.
+	sources, err := ledgerAnalyticSources(s.db, companyID, "payroll", payrollID, nil)
+	if err != nil {
+		return err
+	}
+	tags, err := resolveAnalyticDefaults(s.db, companyID, sources)
+	if err != nil {
+		return err
+	}
+	return tagLedgerTransaction(s.db, companyID, "payroll", payrollID, tags)
 }
 
 // RecordCollection posts minimal double-entry ledger lines for a collection.
@@ -851,6 +866,94 @@ func (s *LedgerService) RecordPurchaseCostAdjustment(companyID, adjustmentID, us
 	return nil
 }
 
+// RecordLandedCostVoucher credits accounts payable once per charge, so each
+// freight or duty supplier carries its own payable, and debits inventory for
+// the share on lots still on hand and COGS for the share already consumed.
+func (s *LedgerService) RecordLandedCostVoucher(companyID, voucherID, userID int) error {
+	var voucherNumber string
+	var voucherDate time.Time
+	var inventoryAmount, cogsAmount float64
+	if err := s.db.QueryRow(`
+		SELECT voucher_number, voucher_date, inventory_amount::float8, cogs_amount::float8
+		FROM landed_cost_vouchers
+		WHERE voucher_id = $1 AND company_id = $2
+	`, voucherID, companyID).Scan(&voucherNumber, &voucherDate, &inventoryAmount, &cogsAmount); err != nil {
+		return fmt.Errorf("failed to load landed cost voucher for ledger posting: %w", err)
+	}
+
+	type chargePosting struct {
+		chargeID int
+		amount   float64
+		desc     string
+	}
+	rows, err := s.db.Query(`
+		SELECT c.charge_id, c.amount::float8, s.name, c.label, c.invoice_number
+		FROM landed_cost_charges c
+		JOIN suppliers s ON s.supplier_id = c.supplier_id
+		WHERE c.voucher_id = $1
+		ORDER BY c.charge_id
+	`, voucherID)
+	if err != nil {
+		return fmt.Errorf("failed to load landed cost charges for ledger posting: %w", err)
+	}
+	defer rows.Close()
+	charges := make([]chargePosting, 0)
+	for rows.Next() {
+		var charge chargePosting
+		var supplierName, label string
+		var invoiceNumber sql.NullString
+		if err := rows.Scan(&charge.chargeID, &charge.amount, &supplierName, &label, &invoiceNumber); err != nil {
+			return fmt.Errorf("failed to scan landed cost charge: %w", err)
+		}
+		charge.desc = fmt.Sprintf("Landed cost %s: %s - %s", voucherNumber, label, supplierName)
+		if invoiceNumber.Valid && invoiceNumber.String != "" {
+			charge.desc += fmt.Sprintf(" (%s)", invoiceNumber.String)
+		}
+		charges = append(charges, charge)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read landed cost charges: %w", err)
+	}
+
+	apID, err := s.ensureDefaultAccountID(companyID, accountCodeAP)
+	if err != nil {
+		return err
+	}
+	for _, charge := range charges {
+		if charge.amount <= 0 {
+			continue
+		}
+		desc := charge.desc
+		ref := fmt.Sprintf("landed_cost:%d:charge:%d:%s", voucherID, charge.chargeID, accountCodeAP)
+		if err := s.insertEntryIfMissing(companyID, ref, apID, voucherDate, 0, round2(charge.amount), "landed_cost_voucher", voucherID, &desc, nil, userID); err != nil {
+			return err
+		}
+	}
+
+	desc := fmt.Sprintf("Landed cost %s", voucherNumber)
+	debits := []struct {
+		code   string
+		amount float64
+	}{
+		{accountCodeInventory, inventoryAmount},
+		{accountCodeCOGS, cogsAmount},
+	}
+	for _, debit := range debits {
+		if debit.amount <= 0 {
+			continue
+		}
+		accountID, err := s.ensureDefaultAccountID(companyID, debit.code)
+		if err != nil {
+			return err
+		}
+		ref := fmt.Sprintf("landed_cost:%d:%s", voucherID, debit.code)
+		if err := s.insertEntryIfMissing(companyID, ref, accountID, voucherDate, round2(debit.amount), 0, "landed_cost_voucher", voucherID, &desc, nil, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // RecordSaleReturn posts a credit-note style reversal for a completed sale return.
 // Debit: Sales revenue + tax payable + inventory
 // Credit: Accounts receivable + cost of goods sold reversal
@@ -1119,3 +1222,173 @@ func (s *LedgerService) RecordConsumableUsage(companyID, consumptionID, userID i
 	}
 	return nil
 }
+
+// RecordStockTransferDispatch moves the value of a dispatched transfer from
+// inventory into the in-transit account.
+func (s *LedgerService) RecordStockTransferDispatch(companyID, transferID, userID int) error {
+	var amount float64
+	var number string
+	var approvedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT st.transit_value::float8, st.transfer_number, st.approved_at
+		FROM stock_transfers st
+		JOIN locations fl ON fl.location_id = st.from_location_id
+		WHERE st.transfer_id = $1 AND fl.company_id = $2
+	`, transferID, companyID).Scan(&amount, &number, &approvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to load stock transfer for ledger posting: %w", err)
+	}
+	date := time.Now()
+	if approvedAt.Valid {
+		date = approvedAt.Time
+	}
+	desc := fmt.Sprintf("Stock transfer %s dispatched", number)
+	return s.recordTransferPosting(companyID, "transfer_dispatch", transferID, accountCodeInTransit, accountCodeInventory, date, amount, &desc, userID)
+}
+
+// RecordStockTransferReceipt clears what was received in good condition from
+// the in-transit account back into inventory. Short and damaged goods stay in
+// transit until their discrepancy is resolved.
+func (s *LedgerService) RecordStockTransferReceipt(companyID, transferID, userID int) error {
+	var amount float64
+	var number string
+	var receivedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT (st.transit_value - COALESCE(d.short_value + d.damaged_value, 0))::float8,
+		       st.transfer_number, st.received_at
+		FROM stock_transfers st
+		JOIN locations tl ON tl.location_id = st.to_location_id
+		LEFT JOIN stock_transfer_discrepancies d ON d.transfer_id = st.transfer_id
+		WHERE st.transfer_id = $1 AND tl.company_id = $2
+	`, transferID, companyID).Scan(&amount, &number, &receivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to load stock transfer for ledger posting: %w", err)
+	}
+	date := time.Now()
+	if receivedAt.Valid {
+		date = receivedAt.Time
+	}
+	desc := fmt.Sprintf("Stock transfer %s received", number)
+	return s.recordTransferPosting(companyID, "transfer_receipt", transferID, accountCodeInventory, accountCodeInTransit, date, amount, &desc, userID)
+}
+
+// RecordTransferShortage writes off the goods that never arrived.
+func (s *LedgerService) RecordTransferShortage(companyID, discrepancyID, userID int) error {
+	return s.recordTransferDiscrepancy(companyID, discrepancyID, userID, "transfer_shortage", "short_value", "shortage")
+}
+
+// RecordTransferDamage writes off the goods that arrived damaged.
+func (s *LedgerService) RecordTransferDamage(companyID, discrepancyID, userID int) error {
+	return s.recordTransferDiscrepancy(companyID, discrepancyID, userID, "transfer_damage", "damaged_value", "damage")
+}
+
+func (s *LedgerService) recordTransferDiscrepancy(companyID, discrepancyID, userID int, transactionType, valueColumn, label string) error {
+	var amount float64
+	var number string
+	var resolvedAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT d.`+valueColumn+`::float8, st.transfer_number, d.resolved_at
+		FROM stock_transfer_discrepancies d
+		JOIN stock_transfers st ON st.transfer_id = d.transfer_id
+		WHERE d.discrepancy_id = $1 AND d.company_id = $2
+	`, discrepancyID, companyID).Scan(&amount, &number, &resolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to load transfer discrepancy for ledger posting: %w", err)
+	}
+	date := time.Now()
+	if resolvedAt.Valid {
+		date = resolvedAt.Time
+	}
+	desc := fmt.Sprintf("Stock transfer %s %s", number, label)
+	return s.recordTransferPosting(companyID, transactionType, discrepancyID, accountCodeShrinkage, accountCodeInTransit, date, amount, &desc, userID)
+}
+
+// RecordExpiryWriteOff books the cost of quarantined expired lots as
+// inventory shrinkage.
+func (s *LedgerService) RecordExpiryWriteOff(companyID, writeOffID, userID int) error {
+	var amount float64
+	var createdAt time.Time
+	err := s.db.QueryRow(`
+		SELECT total_cost::float8, created_at
+		FROM stock_expiry_write_offs
+		WHERE write_off_id = $1 AND company_id = $2
+	`, writeOffID, companyID).Scan(&amount, &createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to load expiry write-off for ledger posting: %w", err)
+	}
+	desc := fmt.Sprintf("Expired stock write-off EXP-WO-%d", writeOffID)
+	return s.recordTransferPosting(companyID, "expiry_write_off", writeOffID, accountCodeShrinkage, accountCodeInventory, createdAt, amount, &desc, userID)
+}
+
+// RecordAssemblyOrder posts a completed assembly or disassembly through work
+// in progress: the consumed stock moves from inventory into WIP, labor and
+// overhead are absorbed into WIP, and the produced stock leaves WIP back into
+// inventory.
+func (s *LedgerService) RecordAssemblyOrder(companyID, orderID, userID int) error {
+	var orderNumber, orderType string
+	var inputCost, outputCost float64
+	var completedAt time.Time
+	err := s.db.QueryRow(`
+		SELECT order_number, order_type, input_cost::float8, output_cost::float8, COALESCE(completed_at, created_at)
+		FROM assembly_orders
+		WHERE assembly_order_id = $1 AND company_id = $2 AND status = 'COMPLETED'
+	`, orderID, companyID).Scan(&orderNumber, &orderType, &inputCost, &outputCost, &completedAt)
+	if err != nil {
+		return fmt.Errorf("failed to load assembly order for ledger posting: %w", err)
+	}
+	desc := fmt.Sprintf("Assembly order %s", orderNumber)
+	if orderType == "DISASSEMBLY" {
+		desc = fmt.Sprintf("Disassembly order %s", orderNumber)
+	}
+	postings := []struct {
+		stage      string
+		debitCode  string
+		creditCode string
+		amount     float64
+	}{
+		{"consume", accountCodeWIP, accountCodeInventory, inputCost},
+		{"conversion", accountCodeWIP, accountCodeAppliedOH, round2(outputCost - inputCost)},
+		{"output", accountCodeInventory, accountCodeWIP, outputCost},
+	}
+	for _, p := range postings {
+		if p.amount <= 0 {
+			continue
+		}
+		debitID, err := s.ensureDefaultAccountID(companyID, p.debitCode)
+		if err != nil {
+			return err
+		}
+		creditID, err := s.ensureDefaultAccountID(companyID, p.creditCode)
+		if err != nil {
+			return err
+		}
+		ref := fmt.Sprintf("assembly_order:%d:%s", orderID, p.stage)
+		if err := s.insertEntryIfMissing(companyID, ref+":"+p.debitCode, debitID, completedAt, p.amount, 0, "assembly_order", orderID, &desc, nil, userID); err != nil {
+			return err
+		}
+		if err := s.insertEntryIfMissing(companyID, ref+":"+p.creditCode, creditID, completedAt, 0, p.amount, "assembly_order", orderID, &desc, nil, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LedgerService) recordTransferPosting(companyID int, transactionType string, transactionID int, debitCode, creditCode string, date time.Time, amount float64, desc *string, userID int) error {
+	if amount <= 0 {
+		return nil
+	}
+	debitID, err := s.ensureDefaultAccountID(companyID, debitCode)
+	if err != nil {
+		return err
+	}
+	creditID, err := s.ensureDefaultAccountID(companyID, creditCode)
+	if err != nil {
+		return err
+	}
+	ref1 := fmt.Sprintf("%s:%d:%s", transactionType, transactionID, debitCode)
+	if err := s.insertEntryIfMissing(companyID, ref1, debitID, date, amount, 0, transactionType, transactionID, desc, nil, userID); err != nil {
+		return err
+	}
+	ref2 := fmt.Sprintf("%s:%d:%s", transactionType, transactionID, creditCode)
+	return s.insertEntryIfMissing(companyID, ref2, creditID, date, 0, amount, transactionType, transactionID, desc, nil, userID)
+}
@@ -201,3 +201,15 @@ func (s *LocationService) checkCompanyExists(companyID int) (bool, error) {
 
 	return count > 0, nil
 }
+
+// ensureCompanyLocation rejects location IDs outside the company.
+func ensureCompanyLocation(q queryer, companyID, locationID int) error {
+	var ok bool
+	if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2 AND is_active = TRUE)`, locationID, companyID).Scan(&ok); err != nil {
+		return fmt.Errorf("failed to check location: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("location not found")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// notificationEventDef describes an event type: who receives it by default
+// and which channels are on when the user has not chosen.
+type notificationEventDef struct {
+	Label      string
+	Permission string
+	Email      bool
+}
+
+// notificationEvents is the catalog of event types. Events addressed to
+// explicit users or an approver role (leave decisions, approvals) carry no
+// permission.
+var notificationEvents = map[string]notificationEventDef{
+	models.NotificationLowStock:           {Label: "Low stock", Permission: "VIEW_INVENTORY"},
+	models.NotificationApprovalPending:    {Label: "Approval pending", Email: true},
+	models.NotificationCreditLimit:        {Label: "Credit limit exceeded", Permission: "VIEW_CUSTOMERS"},
+	models.NotificationCashVariance:       {Label: "Cash variance on close", Permission: "VIEW_CASH_REGISTERS", Email: true},
+	models.NotificationTransferInTransit:  {Label: "Transfer awaiting receipt", Permission: "APPROVE_TRANSFERS"},
+	models.NotificationPeriodClosed:       {Label: "Accounting period closed", Permission: "VIEW_ACCOUNTING_PERIODS"},
+	models.NotificationFinanceOutboxError: {Label: "Finance posting failed", Permission: "VIEW_LEDGER", Email: true},
+	models.NotificationBatchExpiry:        {Label: "Batch nearing expiry", Permission: "VIEW_INVENTORY"},
+	models.NotificationLeaveDecided:       {Label: "Leave decided", Email: true},
+	models.NotificationRecurringFailed:    {Label: "Recurring run failed", Permission: "MANAGE_RECURRING_TEMPLATES", Email: true},
+}
+
+// notificationEventTypes returns the catalog's event types in a stable order.
+func notificationEventTypes() []string {
+	types := make([]string, 0, len(notificationEvents))
+	for t := range notificationEvents {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NotificationEvent is a domain event fanned out to the users it concerns.
+// Recipients are UserIDs when set, else the users of RoleID, else everyone
+// holding the event type's permission (admins included). Roles are the ones
+// users hold at the event's location (their default location without one),
+// so a location's role grant counts there. With a LocationID only users of
+// that location or without one receive it. DedupeKey makes the event fire
+// once per user.
+type NotificationEvent struct {
+	CompanyID   int
+	Type        string
+	Title       string
+	Body        string
+	Severity    string
+	EntityType  string
+	EntityID    int
+	LocationID  *int
+	ActionLabel string
+	DueAt       *time.Time
+	DedupeKey   string
+	UserIDs     []int
+	RoleID      *int
+}
+
+// publishNotification stores the event for each recipient and queues its
+// email and webhook deliveries in one statement. Users who turned every
+// channel off get nothing; a dedupe conflict skips the user's deliveries too.
+func publishNotification(db sqlExecutor, ev NotificationEvent) error {
+	def, ok := notificationEvents[ev.Type]
+	if !ok {
+		return fmt.Errorf("unknown notification event type %s", ev.Type)
+	}
+	severity := ev.Severity
+	if severity == "" {
+		severity = "INFO"
+	}
+	var userIDs pq.Int64Array
+	for _, id := range ev.UserIDs {
+		userIDs = append(userIDs, int64(id))
+	}
+	if ev.UserIDs != nil && userIDs == nil {
+		return nil
+	}
+
+	_, err := db.Exec(`
+		WITH recipients AS (
+			SELECT u.user_id,
+			       COALESCE(np.in_app, TRUE) AS in_app,
+			       COALESCE(np.email, $13) AND COALESCE(u.email, '') <> '' AS email,
+			       COALESCE(np.webhook, FALSE) AND COALESCE(us.webhook_url, '') <> '' AS webhook,
+			       COALESCE(us.email_digest, 'NONE') AS email_digest
+			FROM users u
+			LEFT JOIN user_locations ul
+			       ON ul.user_id = u.user_id AND ul.location_id = COALESCE($8, u.location_id)
+			LEFT JOIN roles r ON r.role_id = COALESCE(ul.role_id, u.role_id)
+			LEFT JOIN notification_preferences np
+			       ON np.company_id = u.company_id AND np.user_id = u.user_id AND np.event_type = $2
+			LEFT JOIN notification_user_settings us
+			       ON us.company_id = u.company_id AND us.user_id = u.user_id
+			WHERE u.company_id = $1 AND u.is_active = TRUE AND u.is_deleted = FALSE
+			  AND ($8::int IS NULL OR u.location_id IS NULL OR u.location_id = $8 OR ul.user_id IS NOT NULL)
+			  AND CASE
+			        WHEN $11::bigint[] IS NOT NULL THEN u.user_id = ANY($11)
+			        WHEN $12::int IS NOT NULL THEN COALESCE(ul.role_id, u.role_id) = $12
+			          OR ($8::int IS NULL AND EXISTS (
+			               SELECT 1 FROM user_locations ula WHERE ula.user_id = u.user_id AND ula.role_id = $12))
+			        ELSE LOWER(COALESCE(r.name, '')) IN ('super admin', 'admin')
+			          OR EXISTS (
+			               SELECT 1 FROM role_permissions rp
+			               JOIN permissions p ON p.permission_id = rp.permission_id
+			               WHERE rp.role_id = r.role_id AND p.name = $14
+			             )
+			      END
+		), inserted AS (
+			INSERT INTO notifications (company_id, user_id, event_type, title, body, severity,
+			                           entity_type, entity_id, location_id, action_label, due_at,
+			                           dedupe_key, in_app)
+			SELECT $1, user_id, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, 0), $8, NULLIF($9, ''), $10,
+			       NULLIF($15, ''), in_app
+			FROM recipients
+			WHERE in_app OR email OR webhook
+			ON CONFLICT (company_id, user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+			RETURNING notification_id, user_id
+		)
+		INSERT INTO notification_deliveries (notification_id, company_id, user_id, channel, send_after)
+		SELECT i.notification_id, $1, i.user_id, c.channel,
+		       CASE
+		         WHEN c.channel = 'EMAIL' AND r.email_digest = 'HOURLY' THEN date_trunc('hour', CURRENT_TIMESTAMP) + INTERVAL '1 hour'
+		         WHEN c.channel = 'EMAIL' AND r.email_digest = 'DAILY' THEN date_trunc('day', CURRENT_TIMESTAMP) + INTERVAL '1 day'
+		         ELSE CURRENT_TIMESTAMP
+		       END
+		FROM inserted i
+		JOIN recipients r ON r.user_id = i.user_id
+		CROSS JOIN LATERAL (VALUES ('EMAIL', r.email), ('WEBHOOK', r.webhook)) AS c(channel, enabled)
+		WHERE c.enabled
+	`, ev.CompanyID, ev.Type, ev.Title, ev.Body, severity,
+		ev.EntityType, ev.EntityID, ev.LocationID, ev.ActionLabel, ev.DueAt,
+		userIDs, ev.RoleID, def.Email, def.Permission, ev.DedupeKey)
+	if err != nil {
+		return fmt.Errorf("failed to publish notification: %w", err)
+	}
+	wakeNotificationRunner()
+	return nil
+}
+
+// notify publishes ev after the change that raised it has committed. A
+// failure only loses the notification, so it is logged rather than returned.
+func notify(db *sql.DB, ev NotificationEvent) {
+	if db == nil {
+		return
+	}
+	if err := publishNotification(db, ev); err != nil {
+		log.Printf("warning: %s notification for company %d: %v", ev.Type, ev.CompanyID, err)
+	}
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+const (
+	notificationPollInterval   = 30 * time.Second
+	notificationSweepInterval  = 15 * time.Minute
+	notificationDeliveryBatch  = 100
+	notificationMaxAttempts    = 5
+	notificationWebhookTimeout = 10 * time.Second
+)
+
+var notificationWake = make(chan struct{}, 1)
+
+// wakeNotificationRunner makes the runner send immediate deliveries now
+// instead of at the next poll.
+func wakeNotificationRunner() {
+	select {
+	case notificationWake <- struct{}{}:
+	default:
+	}
+}
+
+// NotificationRunner sends queued email and webhook deliveries, raises the
+// notifications that come from standing conditions (low stock, expiring
+// batches, pending approvals) and purges notifications past retention.
+// Deliveries due for a user on one channel go out together, which is what
+// turns held digest emails into one message. Failed sends are retried with
+// backoff and given up after notificationMaxAttempts.
+type NotificationRunner struct {
+	db                *sql.DB
+	client            *http.Client
+	retentionDays     int
+	expiryWarningDays int
+	lastSweep         time.Time
+	wg                sync.WaitGroup
+}
+
+func NewNotificationRunner(retentionDays, expiryWarningDays int) *NotificationRunner {
+	return &NotificationRunner{
+		db:                database.GetDB(),
+		client:            &http.Client{Timeout: notificationWebhookTimeout},
+		retentionDays:     retentionDays,
+		expiryWarningDays: expiryWarningDays,
+	}
+}
+
+// Start launches the delivery loop. It returns at once; cancel ctx and call
+// Shutdown to stop.
+func (r *NotificationRunner) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+}
+
+// Shutdown waits up to timeout for an in-flight delivery to finish.
+func (r *NotificationRunner) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (r *NotificationRunner) loop(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		if time.Since(r.lastSweep) >= notificationSweepInterval {
+			r.sweep()
+			r.lastSweep = time.Now()
+		}
+		for ctx.Err() == nil {
+			worked, err := r.deliverOne()
+			if err != nil {
+				log.Printf("warning: notification delivery: %v", err)
+				break
+			}
+			if !worked {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-notificationWake:
+		case <-time.After(notificationPollInterval):
+		}
+	}
+}
+
+// deliverOne sends every due delivery of the user and channel owning the
+// oldest due delivery. It reports whether there was anything to send.
+func (r *NotificationRunner) deliverOne() (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var channel string
+	err = tx.QueryRow(`
+		SELECT user_id, channel FROM notification_deliveries
+		WHERE status = 'PENDING' AND send_after <= CURRENT_TIMESTAMP
+		ORDER BY send_after
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`).Scan(&userID, &channel)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load due delivery: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT d.delivery_id, n.notification_id, n.event_type, n.title, n.body, n.severity,
+		       n.entity_type, n.entity_id, n.location_id, n.created_at,
+		       COALESCE(u.email, ''), COALESCE(us.webhook_url, '')
+		FROM notification_deliveries d
+		JOIN notifications n ON n.notification_id = d.notification_id
+		JOIN users u ON u.user_id = d.user_id
+		LEFT JOIN notification_user_settings us ON us.company_id = d.company_id AND us.user_id = d.user_id
+		WHERE d.user_id = $1 AND d.channel = $2
+		  AND d.status = 'PENDING' AND d.send_after <= CURRENT_TIMESTAMP
+		ORDER BY n.created_at
+		FOR UPDATE OF d SKIP LOCKED
+		LIMIT $3
+	`, userID, channel, notificationDeliveryBatch)
+	if err != nil {
+		return false, fmt.Errorf("failed to load deliveries: %w", err)
+	}
+	var deliveryIDs pq.Int64Array
+	var items []models.NotificationItem
+	var email, webhookURL string
+	for rows.Next() {
+		var deliveryID int64
+		var it models.NotificationItem
+		var entityType sql.NullString
+		var entityID, locationID sql.NullInt64
+		if err := rows.Scan(&deliveryID, &it.ID, &it.Type, &it.Title, &it.Body, &it.Severity,
+			&entityType, &entityID, &locationID, &it.CreatedAt, &email, &webhookURL); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		it.Key = notificationKey(it.ID)
+		if entityType.Valid {
+			it.EntityType = &entityType.String
+		}
+		if entityID.Valid {
+			v := int(entityID.Int64)
+			it.EntityID = &v
+		}
+		if locationID.Valid {
+			v := int(locationID.Int64)
+			it.LocationID = &v
+		}
+		deliveryIDs = append(deliveryIDs, deliveryID)
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to load deliveries: %w", err)
+	}
+	if len(items) == 0 {
+		return true, tx.Commit()
+	}
+
+	var sendErr error
+	if channel == "EMAIL" {
+		sendErr = sendNotificationEmail(email, items)
+	} else {
+		sendErr = r.postNotificationWebhook(webhookURL, userID, items)
+	}
+	if sendErr == nil {
+		_, err = tx.Exec(`
+			UPDATE notification_deliveries
+			SET status = 'SENT', attempts = attempts + 1, sent_at = CURRENT_TIMESTAMP, last_error = NULL
+			WHERE delivery_id = ANY($1)
+		`, deliveryIDs)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE notification_deliveries
+			SET attempts = attempts + 1,
+			    last_error = $2,
+			    status = CASE WHEN attempts + 1 >= $3 THEN 'FAILED' ELSE 'PENDING' END,
+			    send_after = CURRENT_TIMESTAMP + make_interval(mins => (attempts + 1) * (attempts + 1))
+			WHERE delivery_id = ANY($1)
+		`, deliveryIDs, sendErr.Error(), notificationMaxAttempts)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record delivery: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// notificationEmail renders one notification as is and several as a digest.
+func notificationEmail(items []models.NotificationItem) (subject, body string) {
+	if len(items) == 1 {
+		return items[0].Title, items[0].Body
+	}
+	var b strings.Builder
+	for _, it := range items {
+		fmt.Fprintf(&b, "[%s] %s\n", it.Severity, it.Title)
+		if it.Body != "" {
+			fmt.Fprintf(&b, "  %s\n", it.Body)
+		}
+		fmt.Fprintf(&b, "  %s\n\n", it.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%d new notifications", len(items)), b.String()
+}
+
+func sendNotificationEmail(to string, items []models.NotificationItem) error {
+	if strings.TrimSpace(to) == "" {
+		return fmt.Errorf("user has no email address")
+	}
+	subject, body := notificationEmail(items)
+	return utils.SendEmail(strings.TrimSpace(to), subject, body)
+}
+
+func (r *NotificationRunner) postNotificationWebhook(url string, userID int, items []models.NotificationItem) error {
+	if strings.TrimSpace(url) == "" {
+		return fmt.Errorf("user has no webhook url")
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"user_id":       userID,
+		"notifications": items,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	resp, err := r.client.Post(strings.TrimSpace(url), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sweep raises condition notifications and applies retention. Each step logs
+// its own failure so one broken query does not stop the others.
+func (r *NotificationRunner) sweep() {
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"low stock", r.sweepLowStock},
+		{"batch expiry", r.sweepBatchExpiry},
+		{"pending approvals", r.sweepPendingApprovals},
+		{"retention", r.purgeExpired},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			log.Printf("warning: notification sweep (%s): %v", step.name, err)
+		}
+	}
+}
+
+// sweepLowStock alerts once a day per product and location while stock is at
+// or below the reorder level.
+func (r *NotificationRunner) sweepLowStock() error {
+	rows, err := r.db.Query(`
+		SELECT l.company_id, st.location_id, COALESCE(l.name, ''), st.product_id, COALESCE(p.name, ''),
+		       COALESCE(st.quantity, 0), COALESCE(p.reorder_level, 0)
+		FROM stock st
+		JOIN locations l ON st.location_id = l.location_id
+		JOIN products p ON st.product_id = p.product_id
+		WHERE l.is_active = TRUE
+		  AND p.is_deleted = FALSE
+		  AND p.is_active = TRUE
+		  AND COALESCE(p.reorder_level, 0) > 0
+		  AND COALESCE(st.quantity, 0) <= COALESCE(p.reorder_level, 0)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query low stock: %w", err)
+	}
+	var events []NotificationEvent
+	day := time.Now().Format("2006-01-02")
+	for rows.Next() {
+		var companyID, locationID, productID, reorder int
+		var locationName, productName string
+		var qty float64
+		if err := rows.Scan(&companyID, &locationID, &locationName, &productID, &productName, &qty, &reorder); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan low stock row: %w", err)
+		}
+		severity, title := "WARNING", "Low stock: "+productName
+		if qty <= 0 {
+			severity, title = "CRITICAL", "Stockout: "+productName
+		}
+		loc := locationID
+		events = append(events, NotificationEvent{
+			CompanyID:   companyID,
+			Type:        models.NotificationLowStock,
+			Title:       title,
+			Body:        fmt.Sprintf("Qty %.2f <= reorder %d at %s", qty, reorder, locationName),
+			Severity:    severity,
+			EntityType:  "PRODUCT",
+			EntityID:    productID,
+			LocationID:  &loc,
+			ActionLabel: "Open inventory",
+			DedupeKey:   fmt.Sprintf("low_stock:%d:%d:%s", locationID, productID, day),
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query low stock: %w", err)
+	}
+	return r.publishAll(events)
+}
+
+// sweepBatchExpiry alerts once per lot with stock left that expires within
+// the warning window, and again once the lot has expired. Companies may set
+// their own window in the inventory settings.
+func (r *NotificationRunner) sweepBatchExpiry() error {
+	rows, err := r.db.Query(`
+		SELECT l.company_id, sl.lot_id, sl.location_id, COALESCE(l.name, ''), sl.product_id,
+		       COALESCE(p.name, ''), COALESCE(sl.batch_number, ''), sl.remaining_quantity, sl.expiry_date
+		FROM stock_lots sl
+		JOIN locations l ON l.location_id = sl.location_id
+		JOIN products p ON p.product_id = sl.product_id
+		LEFT JOIN settings st ON st.company_id = l.company_id AND st.key = 'inventory'
+		WHERE sl.remaining_quantity > 0
+		  AND sl.expiry_date IS NOT NULL
+		  AND sl.expiry_date <= CURRENT_DATE + COALESCE(NULLIF(st.value->>'expiry_alert_days', '')::int, $1::int)
+		  AND l.is_active = TRUE
+		  AND p.is_deleted = FALSE
+	`, r.expiryWarningDays)
+	if err != nil {
+		return fmt.Errorf("failed to query expiring batches: %w", err)
+	}
+	var events []NotificationEvent
+	today := recurringDate(time.Now())
+	for rows.Next() {
+		var companyID, lotID, locationID, productID int
+		var locationName, productName, batch string
+		var qty float64
+		var expiry time.Time
+		if err := rows.Scan(&companyID, &lotID, &locationID, &locationName, &productID,
+			&productName, &batch, &qty, &expiry); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan expiring batch: %w", err)
+		}
+		label := batch
+		if label == "" {
+			label = fmt.Sprintf("lot %d", lotID)
+		}
+		severity := "WARNING"
+		if !recurringDate(expiry).After(today) {
+			severity = "CRITICAL"
+		}
+		title := fmt.Sprintf("Batch %s of %s expires %s", label, productName, expiry.Format("2006-01-02"))
+		dedupe := fmt.Sprintf("batch_expiry:%d", lotID)
+		if recurringDate(expiry).Before(today) {
+			title = fmt.Sprintf("Batch %s of %s expired on %s", label, productName, expiry.Format("2006-01-02"))
+			dedupe = fmt.Sprintf("batch_expired:%d", lotID)
+		}
+		loc := locationID
+		due := expiry
+		events = append(events, NotificationEvent{
+			CompanyID:   companyID,
+			Type:        models.NotificationBatchExpiry,
+			Title:       title,
+			Body:        fmt.Sprintf("%.2f remaining at %s", qty, locationName),
+			Severity:    severity,
+			EntityType:  "PRODUCT",
+			EntityID:    productID,
+			LocationID:  &loc,
+			ActionLabel: "Open inventory",
+			DueAt:       &due,
+			DedupeKey:   dedupe,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query expiring batches: %w", err)
+	}
+	return r.publishAll(events)
+}
+
+// sweepPendingApprovals notifies the approver role once per pending workflow
+// request.
+func (r *NotificationRunner) sweepPendingApprovals() error {
+	rows, err := r.db.Query(`
+		SELECT company_id, approval_id, location_id, entity_type, COALESCE(entity_id, 0), title,
+		       COALESCE(summary, ''), COALESCE(priority, 'NORMAL'), approver_role_id, due_at
+		FROM workflow_requests
+		WHERE status = 'PENDING'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query pending approvals: %w", err)
+	}
+	var events []NotificationEvent
+	for rows.Next() {
+		var companyID, approvalID, entityID, roleID int
+		var locationID sql.NullInt64
+		var entityType, title, summary, priority string
+		var dueAt sql.NullTime
+		if err := rows.Scan(&companyID, &approvalID, &locationID, &entityType, &entityID, &title,
+			&summary, &priority, &roleID, &dueAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pending approval: %w", err)
+		}
+		severity := "INFO"
+		if strings.EqualFold(strings.TrimSpace(priority), "HIGH") {
+			severity = "CRITICAL"
+		}
+		role := roleID
+		ev := NotificationEvent{
+			CompanyID:   companyID,
+			Type:        models.NotificationApprovalPending,
+			Title:       title,
+			Body:        strings.TrimSpace(summary),
+			Severity:    severity,
+			EntityType:  "WORKFLOW_REQUEST",
+			EntityID:    approvalID,
+			ActionLabel: "Review approval",
+			DedupeKey:   fmt.Sprintf("approval:%d", approvalID),
+			RoleID:      &role,
+		}
+		if locationID.Valid {
+			loc := int(locationID.Int64)
+			ev.LocationID = &loc
+		}
+		if dueAt.Valid {
+			due := dueAt.Time
+			ev.DueAt = &due
+		}
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query pending approvals: %w", err)
+	}
+	return r.publishAll(events)
+}
+
+func (r *NotificationRunner) publishAll(events []NotificationEvent) error {
+	for _, ev := range events {
+		if err := publishNotification(r.db, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeExpired deletes notifications older than the retention window; their
+// deliveries go with them.
+func (r *NotificationRunner) purgeExpired() error {
+	if r.retentionDays <= 0 {
+		return nil
+	}
+	if _, err := r.db.Exec(`
+		DELETE FROM notifications WHERE created_at < CURRENT_TIMESTAMP - make_interval(days => $1)
+	`, r.retentionDays); err != nil {
+		return fmt.Errorf("failed to purge notifications: %w", err)
+	}
+	return nil
+}
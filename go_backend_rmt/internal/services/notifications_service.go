@@ -3,14 +3,24 @@ package services
 import (
 	"database/sql"
 	"fmt"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"erp-backend/internal/database"
 	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
 )
 
+// NotificationsService reads a user's stored notifications and manages their
+// channel preferences. Notifications are written by publishNotification when
+// domain events happen.
 type NotificationsService struct {
 	db *sql.DB
 }
@@ -19,302 +29,270 @@ func NewNotificationsService() *NotificationsService {
 	return &NotificationsService{db: database.GetDB()}
 }
 
-func (s *NotificationsService) ListNotifications(companyID, userID int, locationID *int) ([]models.NotificationItem, error) {
-	readKeys, err := s.getReadKeys(companyID, userID)
-	if err != nil {
-		return nil, err
-	}
+func notificationKey(id int64) string {
+	return fmt.Sprintf("notification:%d", id)
+}
 
-	userRoleID, err := s.getUserRoleID(userID)
-	if err != nil {
-		return nil, err
+// parseNotificationKey returns the notification ID of a list key.
+func parseNotificationKey(key string) (int64, bool) {
+	raw, ok := strings.CutPrefix(strings.TrimSpace(key), "notification:")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
 	}
+	return id, true
+}
 
-	var items []models.NotificationItem
+// notificationScope builds the WHERE clause shared by the list and count.
+func notificationScope(companyID, userID int, filter models.NotificationFilter) (string, []interface{}) {
+	where := "WHERE company_id = $1 AND user_id = $2 AND in_app = TRUE"
+	args := []interface{}{companyID, userID}
+	if filter.LocationID != nil && *filter.LocationID != 0 {
+		args = append(args, *filter.LocationID)
+		where += fmt.Sprintf(" AND (location_id IS NULL OR location_id = $%d)", len(args))
+	}
+	if filter.UnreadOnly {
+		where += " AND read_at IS NULL"
+	}
+	return where, args
+}
 
-	lowStock, err := s.lowStockNotifications(companyID, locationID, readKeys)
-	if err != nil {
-		return nil, err
+// ListNotifications returns the user's notifications, unread and overdue
+// first, then newest first.
+func (s *NotificationsService) ListNotifications(companyID, userID int, filter models.NotificationFilter) ([]models.NotificationItem, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
 	}
-	items = append(items, lowStock...)
+	where, args := notificationScope(companyID, userID, filter)
+	args = append(args, limit)
 
-	workflows, err := s.workflowPendingNotifications(companyID, userRoleID, readKeys)
+	rows, err := s.db.Query(`
+		SELECT notification_id, event_type, title, body, severity, entity_type, entity_id,
+		       location_id, action_label, due_at, read_at, created_at
+		FROM notifications
+		`+where+`
+		ORDER BY (read_at IS NULL) DESC, (due_at IS NOT NULL AND due_at < CURRENT_TIMESTAMP) DESC, created_at DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
 	}
-	items = append(items, workflows...)
+	defer rows.Close()
 
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].IsOverdue != items[j].IsOverdue {
-			return items[i].IsOverdue
+	now := time.Now()
+	list := []models.NotificationItem{}
+	for rows.Next() {
+		var it models.NotificationItem
+		var entityType, actionLabel sql.NullString
+		var entityID, locationID sql.NullInt64
+		var dueAt, readAt sql.NullTime
+		if err := rows.Scan(&it.ID, &it.Type, &it.Title, &it.Body, &it.Severity, &entityType, &entityID,
+			&locationID, &actionLabel, &dueAt, &readAt, &it.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
 		}
-		return items[i].CreatedAt.After(items[j].CreatedAt)
-	})
-
-	return items, nil
+		it.Key = notificationKey(it.ID)
+		it.IsRead = readAt.Valid
+		it.Status = "PENDING"
+		if dueAt.Valid {
+			due := dueAt.Time
+			it.DueAt = &due
+			if due.Before(now) {
+				it.IsOverdue = true
+				it.Status = "OVERDUE"
+			}
+		}
+		if entityType.Valid {
+			it.EntityType = &entityType.String
+		}
+		if entityID.Valid {
+			id := int(entityID.Int64)
+			it.EntityID = &id
+			switch entityType.String {
+			case "PRODUCT":
+				it.ProductID = &id
+			case "WORKFLOW_REQUEST":
+				it.ApprovalID = &id
+			}
+		}
+		if locationID.Valid {
+			id := int(locationID.Int64)
+			it.LocationID = &id
+		}
+		if actionLabel.Valid {
+			it.ActionLabel = &actionLabel.String
+		}
+		if def, ok := notificationEvents[it.Type]; ok {
+			badge := def.Label
+			it.BadgeLabel = &badge
+		}
+		list = append(list, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	return list, nil
 }
 
 func (s *NotificationsService) UnreadCount(companyID, userID int, locationID *int) (int, error) {
-	items, err := s.ListNotifications(companyID, userID, locationID)
-	if err != nil {
-		return 0, err
-	}
-	n := 0
-	for _, it := range items {
-		if !it.IsRead {
-			n++
-		}
+	where, args := notificationScope(companyID, userID, models.NotificationFilter{LocationID: locationID, UnreadOnly: true})
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM notifications `+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
 	}
 	return n, nil
 }
 
 func (s *NotificationsService) MarkRead(companyID, userID int, keys []string) error {
-	if len(keys) == 0 {
-		return fmt.Errorf("keys required")
-	}
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.Prepare(`
-        INSERT INTO notification_reads (company_id, user_id, notification_key, read_at)
-        VALUES ($1,$2,$3,CURRENT_TIMESTAMP)
-        ON CONFLICT (company_id, user_id, notification_key) DO NOTHING
-    `)
-	if err != nil {
-		return fmt.Errorf("failed to prepare: %w", err)
-	}
-	defer stmt.Close()
-
+	var ids pq.Int64Array
 	for _, k := range keys {
-		key := strings.TrimSpace(k)
-		if key == "" {
+		if strings.TrimSpace(k) == "" {
 			continue
 		}
-		if _, err := stmt.Exec(companyID, userID, key); err != nil {
-			return fmt.Errorf("failed to mark read: %w", err)
+		id, ok := parseNotificationKey(k)
+		if !ok {
+			return fmt.Errorf("invalid notification key %q", strings.TrimSpace(k))
 		}
+		ids = append(ids, id)
 	}
-
-	return tx.Commit()
-}
-
-func (s *NotificationsService) getUserRoleID(userID int) (int, error) {
-	var roleID int
-	if err := s.db.QueryRow(`SELECT COALESCE(role_id, 0) FROM users WHERE user_id = $1`, userID).Scan(&roleID); err != nil {
-		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("user not found")
-		}
-		return 0, fmt.Errorf("failed to get user role: %w", err)
+	if len(ids) == 0 {
+		return fmt.Errorf("keys required")
+	}
+	if _, err := s.db.Exec(`
+		UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1 AND user_id = $2 AND notification_id = ANY($3) AND read_at IS NULL
+	`, companyID, userID, ids); err != nil {
+		return fmt.Errorf("failed to mark read: %w", err)
 	}
-	return roleID, nil
+	return nil
 }
 
-func (s *NotificationsService) getReadKeys(companyID, userID int) (map[string]struct{}, error) {
-	rows, err := s.db.Query(`SELECT notification_key FROM notification_reads WHERE company_id=$1 AND user_id=$2`, companyID, userID)
+// MarkAllRead marks every unread notification of the user read and returns
+// how many changed.
+func (s *NotificationsService) MarkAllRead(companyID, userID int) (int, error) {
+	res, err := s.db.Exec(`
+		UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1 AND user_id = $2 AND read_at IS NULL
+	`, companyID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read notification keys: %w", err)
+		return 0, fmt.Errorf("failed to mark read: %w", err)
 	}
-	defer rows.Close()
-
-	m := make(map[string]struct{})
-	for rows.Next() {
-		var key string
-		if err := rows.Scan(&key); err != nil {
-			return nil, fmt.Errorf("failed to scan read key: %w", err)
-		}
-		if key != "" {
-			m[key] = struct{}{}
-		}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark read: %w", err)
 	}
-	return m, nil
+	return int(n), nil
 }
 
-func (s *NotificationsService) lowStockNotifications(companyID int, locationID *int, readKeys map[string]struct{}) ([]models.NotificationItem, error) {
-	query := `
-        SELECT st.location_id,
-               COALESCE(l.name,'') as location_name,
-               st.product_id,
-               COALESCE(p.name,'') as product_name,
-               COALESCE(pb.barcode, '') as barcode,
-               COALESCE(st.quantity,0) as quantity,
-               COALESCE(p.reorder_level,0) as reorder_level,
-               COALESCE(st.last_updated, CURRENT_TIMESTAMP) as last_updated
-        FROM stock st
-        JOIN locations l ON st.location_id = l.location_id
-        JOIN products p ON st.product_id = p.product_id
-        LEFT JOIN LATERAL (
-            SELECT barcode
-            FROM product_barcodes
-            WHERE product_id = p.product_id
-              AND COALESCE(is_active, TRUE) = TRUE
-            ORDER BY is_primary DESC, barcode_id
-            LIMIT 1
-        ) pb ON TRUE
-        WHERE l.company_id = $1
-          AND l.is_active = TRUE
-          AND p.is_deleted = FALSE
-          AND p.is_active = TRUE
-          AND COALESCE(p.reorder_level,0) > 0
-          AND COALESCE(st.quantity,0) <= COALESCE(p.reorder_level,0)
-    `
-
-	args := []interface{}{companyID}
-	if locationID != nil && *locationID != 0 {
-		query += " AND st.location_id = $2"
-		args = append(args, *locationID)
+// GetPreferences returns the user's settings with one entry per event type;
+// event types the user never changed show their defaults.
+func (s *NotificationsService) GetPreferences(companyID, userID int) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{EmailDigest: models.NotificationDigestNone}
+	var webhookURL sql.NullString
+	err := s.db.QueryRow(`
+		SELECT email_digest, webhook_url FROM notification_user_settings
+		WHERE company_id = $1 AND user_id = $2
+	`, companyID, userID).Scan(&prefs.EmailDigest, &webhookURL)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get notification settings: %w", err)
+	}
+	if webhookURL.Valid {
+		prefs.WebhookURL = &webhookURL.String
 	}
 
-	query += " ORDER BY COALESCE(st.quantity,0) ASC, p.name ASC LIMIT 50"
-
-	rows, err := s.db.Query(query, args...)
+	rows, err := s.db.Query(`
+		SELECT event_type, in_app, email, webhook FROM notification_preferences
+		WHERE company_id = $1 AND user_id = $2
+	`, companyID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query low stock: %w", err)
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
 	}
 	defer rows.Close()
-
-	var list []models.NotificationItem
+	saved := map[string]models.NotificationEventPreference{}
 	for rows.Next() {
-		var locID, productID, reorder int
-		var locName, productName, barcode string
-		var qty float64
-		var updated time.Time
-		if err := rows.Scan(&locID, &locName, &productID, &productName, &barcode, &qty, &reorder, &updated); err != nil {
-			return nil, fmt.Errorf("failed to scan low stock row: %w", err)
-		}
-		key := fmt.Sprintf("low_stock:loc:%d:product:%d", locID, productID)
-		_, isRead := readKeys[key]
-
-		status := "PENDING"
-		severity := "WARNING"
-		badge := "Low stock"
-		if qty <= 0 {
-			status = "OVERDUE"
-			severity = "CRITICAL"
-			badge = "Stockout"
+		var p models.NotificationEventPreference
+		if err := rows.Scan(&p.EventType, &p.InApp, &p.Email, &p.Webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
 		}
+		saved[p.EventType] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
 
-		title := fmt.Sprintf("Low stock: %s", productName)
-		bodyParts := []string{fmt.Sprintf("Qty %.2f <= reorder %d at %s", qty, reorder, locName)}
-		if strings.TrimSpace(barcode) != "" {
-			bodyParts = append(bodyParts, fmt.Sprintf("Barcode %s", strings.TrimSpace(barcode)))
+	for _, eventType := range notificationEventTypes() {
+		def := notificationEvents[eventType]
+		p, ok := saved[eventType]
+		if !ok {
+			p = models.NotificationEventPreference{EventType: eventType, InApp: true, Email: def.Email}
 		}
-
-		entityType := "PRODUCT"
-		actionLabel := "Open inventory"
-		badgeLabel := badge
-		list = append(list, models.NotificationItem{
-			Key:         key,
-			Type:        "LOW_STOCK",
-			Title:       title,
-			Body:        strings.Join(bodyParts, " • "),
-			Status:      status,
-			Severity:    severity,
-			CreatedAt:   updated,
-			IsRead:      isRead,
-			IsOverdue:   status == "OVERDUE",
-			EntityType:  &entityType,
-			EntityID:    &productID,
-			LocationID:  &locID,
-			ProductID:   &productID,
-			ActionLabel: &actionLabel,
-			BadgeLabel:  &badgeLabel,
-		})
+		p.Label = def.Label
+		prefs.Events = append(prefs.Events, p)
 	}
-	return list, nil
+	return prefs, nil
 }
 
-func (s *NotificationsService) workflowPendingNotifications(companyID, userRoleID int, readKeys map[string]struct{}) ([]models.NotificationItem, error) {
-	rows, err := s.db.Query(`
-        SELECT approval_id,
-               entity_type,
-               entity_id,
-               title,
-               COALESCE(summary, '') AS summary,
-               COALESCE(priority, 'NORMAL') AS priority,
-               due_at,
-               created_at
-        FROM workflow_requests
-        WHERE company_id = $1
-          AND approver_role_id = $2
-          AND status = 'PENDING'
-        ORDER BY COALESCE(due_at, created_at) ASC, created_at DESC
-        LIMIT 50
-    `, companyID, userRoleID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query workflow pending: %w", err)
+// UpdatePreferences applies the fields set in req. A new event preference
+// starts from the event type's defaults.
+func (s *NotificationsService) UpdatePreferences(companyID, userID int, req *models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	for _, e := range req.Events {
+		if _, ok := notificationEvents[e.EventType]; !ok {
+			return nil, fmt.Errorf("unknown notification event type %s", e.EventType)
+		}
 	}
-	defer rows.Close()
-
-	now := time.Now()
-	var list []models.NotificationItem
-	for rows.Next() {
-		var approvalID int
-		var entityType string
-		var entityID sql.NullInt64
-		var title, summary, priority string
-		var dueAt, createdAt sql.NullTime
-		if err := rows.Scan(&approvalID, &entityType, &entityID, &title, &summary, &priority, &dueAt, &createdAt); err != nil {
-			return nil, fmt.Errorf("failed to scan workflow row: %w", err)
+	if req.WebhookURL != nil {
+		url := strings.TrimSpace(*req.WebhookURL)
+		if url != "" && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			return nil, fmt.Errorf("webhook_url must be an http or https URL")
 		}
+	}
 
-		key := fmt.Sprintf("workflow_request:%d", approvalID)
-		_, isRead := readKeys[key]
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-		status := "PENDING"
-		severity := "INFO"
-		isOverdue := false
-		escalationBadge := "Pending approval"
-		var duePtr *time.Time
-		if dueAt.Valid {
-			value := dueAt.Time
-			duePtr = &value
-			if dueAt.Time.Before(now) {
-				status = "OVERDUE"
-				severity = "WARNING"
-				isOverdue = true
-				escalationBadge = "Overdue approval"
-			}
+	if req.EmailDigest != nil || req.WebhookURL != nil {
+		var webhookURL *string
+		if req.WebhookURL != nil {
+			url := strings.TrimSpace(*req.WebhookURL)
+			webhookURL = &url
 		}
-		if strings.EqualFold(strings.TrimSpace(priority), "HIGH") {
-			severity = "CRITICAL"
+		if _, err := tx.Exec(`
+			INSERT INTO notification_user_settings (company_id, user_id, email_digest, webhook_url)
+			VALUES ($1, $2, COALESCE($3, 'NONE'), NULLIF($4, ''))
+			ON CONFLICT (company_id, user_id) DO UPDATE SET
+			  email_digest = COALESCE($3, notification_user_settings.email_digest),
+			  webhook_url = CASE WHEN $4::text IS NULL THEN notification_user_settings.webhook_url ELSE NULLIF($4, '') END,
+			  updated_at = CURRENT_TIMESTAMP
+		`, companyID, userID, req.EmailDigest, webhookURL); err != nil {
+			return nil, fmt.Errorf("failed to save notification settings: %w", err)
 		}
+	}
 
-		bodyParts := []string{}
-		if strings.TrimSpace(summary) != "" {
-			bodyParts = append(bodyParts, strings.TrimSpace(summary))
-		}
-		if dueAt.Valid {
-			bodyParts = append(bodyParts, fmt.Sprintf("Due %s", dueAt.Time.Local().Format("2006-01-02 15:04")))
+	for _, e := range req.Events {
+		def := notificationEvents[e.EventType]
+		if _, err := tx.Exec(`
+			INSERT INTO notification_preferences (company_id, user_id, event_type, in_app, email, webhook)
+			VALUES ($1, $2, $3, COALESCE($4, TRUE), COALESCE($5, $7), COALESCE($6, FALSE))
+			ON CONFLICT (company_id, user_id, event_type) DO UPDATE SET
+			  in_app = COALESCE($4, notification_preferences.in_app),
+			  email = COALESCE($5, notification_preferences.email),
+			  webhook = COALESCE($6, notification_preferences.webhook),
+			  updated_at = CURRENT_TIMESTAMP
+		`, companyID, userID, e.EventType, e.InApp, e.Email, e.Webhook, def.Email); err != nil {
+			return nil, fmt.Errorf("failed to save notification preference: %w", err)
 		}
+	}
 
-		actionLabel := "Review approval"
-		item := models.NotificationItem{
-			Key:         key,
-			Type:        "APPROVAL_PENDING",
-			Title:       title,
-			Body:        strings.Join(bodyParts, " • "),
-			Status:      status,
-			Severity:    severity,
-			CreatedAt:   now,
-			IsRead:      isRead,
-			IsOverdue:   isOverdue,
-			ApprovalID:  &approvalID,
-			EntityType:  &entityType,
-			ActionLabel: &actionLabel,
-			BadgeLabel:  &escalationBadge,
-			DueAt:       duePtr,
-		}
-		if entityID.Valid {
-			entityIDValue := int(entityID.Int64)
-			item.EntityID = &entityIDValue
-		}
-		if createdAt.Valid {
-			item.CreatedAt = createdAt.Time
-		}
-		list = append(list, item)
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return list, nil
+	return s.GetPreferences(companyID, userID)
 }
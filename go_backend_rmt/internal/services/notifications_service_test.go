@@ -2,13 +2,16 @@ package services
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"erp-backend/internal/models"
+
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 )
 
-func TestNotificationsService_ListNotifications_ReadStateApplied(t *testing.T) {
+func TestNotificationsService_ListNotifications_MapsStoredRows(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to create sqlmock: %v", err)
@@ -16,79 +19,102 @@ func TestNotificationsService_ListNotifications_ReadStateApplied(t *testing.T) {
 	defer db.Close()
 
 	svc := &NotificationsService{db: db}
+	location := 1
+	created := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	overdue := time.Now().Add(-2 * time.Hour)
 
-	const (
-		companyID = 1
-		userID    = 2
-		location  = 1
-	)
+	mock.ExpectQuery("(?s)FROM notifications\\s+WHERE company_id = \\$1 AND user_id = \\$2 AND in_app = TRUE AND \\(location_id IS NULL OR location_id = \\$3\\) AND read_at IS NULL.*LIMIT \\$4").
+		WithArgs(1, 2, location, 20).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"notification_id", "event_type", "title", "body", "severity", "entity_type", "entity_id",
+			"location_id", "action_label", "due_at", "read_at", "created_at",
+		}).
+			AddRow(7, models.NotificationApprovalPending, "Approve PO-0001", "Supplier ACME", "CRITICAL", "WORKFLOW_REQUEST", 10, nil, "Review approval", overdue, nil, created).
+			AddRow(8, models.NotificationLowStock, "Low stock: Item A", "Qty 3.00 <= reorder 5 at Main", "WARNING", "PRODUCT", 2, 1, nil, nil, created, created))
 
-	lowStockKey := "low_stock:loc:1:product:2"
-	workflowKey := "workflow_request:10"
+	items, err := svc.ListNotifications(1, 2, models.NotificationFilter{LocationID: &location, UnreadOnly: true, Limit: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(items))
+	}
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT notification_key FROM notification_reads WHERE company_id=$1 AND user_id=$2")).
-		WithArgs(companyID, userID).
-		WillReturnRows(sqlmock.NewRows([]string{"notification_key"}).
-			AddRow(lowStockKey).
-			AddRow(workflowKey))
+	approval := items[0]
+	if approval.Key != "notification:7" || approval.IsRead || !approval.IsOverdue || approval.Status != "OVERDUE" {
+		t.Fatalf("unexpected approval notification: %+v", approval)
+	}
+	if approval.ApprovalID == nil || *approval.ApprovalID != 10 || approval.LocationID != nil {
+		t.Fatalf("expected approval 10 without location, got %+v", approval)
+	}
+	if approval.BadgeLabel == nil || *approval.BadgeLabel != "Approval pending" {
+		t.Fatalf("expected catalog badge label, got %v", approval.BadgeLabel)
+	}
 
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(role_id, 0) FROM users WHERE user_id = $1")).
-		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(3))
+	stock := items[1]
+	if !stock.IsRead || stock.IsOverdue || stock.ProductID == nil || *stock.ProductID != 2 || stock.ActionLabel != nil {
+		t.Fatalf("unexpected low stock notification: %+v", stock)
+	}
 
-	updated := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
-	mock.ExpectQuery("(?s)FROM stock st.*WHERE l\\.company_id = \\$1.*st\\.location_id = \\$2.*LIMIT 50").
-		WithArgs(companyID, location).
-		WillReturnRows(sqlmock.NewRows([]string{
-			"location_id",
-			"location_name",
-			"product_id",
-			"product_name",
-			"barcode",
-			"quantity",
-			"reorder_level",
-			"last_updated",
-		}).AddRow(1, "Main", 2, "Item A", "ABC123", 3.0, 5, updated))
-
-	dueAt := updated.Add(-2 * time.Hour)
-	mock.ExpectQuery("(?s)FROM workflow_requests.*WHERE company_id = \\$1.*approver_role_id = \\$2.*status = 'PENDING'.*LIMIT 50").
-		WithArgs(companyID, 3).
-		WillReturnRows(sqlmock.NewRows([]string{
-			"approval_id",
-			"entity_type",
-			"entity_id",
-			"title",
-			"summary",
-			"priority",
-			"due_at",
-			"created_at",
-		}).AddRow(10, "PURCHASE_ORDER", 44, "Approve purchase order PO-0001", "Supplier ACME", "HIGH", dueAt, updated))
-
-	items, err := svc.ListNotifications(companyID, userID, func() *int { v := location; return &v }())
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestNotificationsService_UnreadCount_CountsStoredRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	svc := &NotificationsService{db: db}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM notifications WHERE company_id = $1 AND user_id = $2 AND in_app = TRUE AND read_at IS NULL")).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	n, err := svc.UnreadCount(1, 2, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(items) != 2 {
-		t.Fatalf("expected 2 notifications, got %d", len(items))
+	if n != 4 {
+		t.Fatalf("expected 4 unread, got %d", n)
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
 
-	seen := map[string]bool{}
-	for _, it := range items {
-		seen[it.Key] = it.IsRead
+func TestNotificationsService_MarkRead_UpdatesByKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
 	}
-	if seen[lowStockKey] != true {
-		t.Fatalf("expected low stock notification to be read")
+	defer db.Close()
+
+	svc := &NotificationsService{db: db}
+
+	if err := svc.MarkRead(1, 2, []string{"workflow_request:10"}); err == nil || !strings.Contains(err.Error(), "invalid notification key") {
+		t.Fatalf("expected invalid key error, got %v", err)
 	}
-	if seen[workflowKey] != true {
-		t.Fatalf("expected workflow notification to be read")
+	if err := svc.MarkRead(1, 2, []string{" ", ""}); err == nil || err.Error() != "keys required" {
+		t.Fatalf("expected keys required, got %v", err)
 	}
 
+	mock.ExpectExec("UPDATE notifications SET read_at = CURRENT_TIMESTAMP\\s+WHERE company_id = \\$1 AND user_id = \\$2 AND notification_id = ANY\\(\\$3\\)").
+		WithArgs(1, 2, "{10,12}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := svc.MarkRead(1, 2, []string{" notification:10 ", "", "notification:12"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
 
-func TestNotificationsService_MarkRead_InsertsKeys(t *testing.T) {
+func TestNotificationsService_GetPreferences_FillsDefaults(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to create sqlmock: %v", err)
@@ -97,33 +123,96 @@ func TestNotificationsService_MarkRead_InsertsKeys(t *testing.T) {
 
 	svc := &NotificationsService{db: db}
 
-	mock.ExpectBegin()
-	mock.ExpectPrepare(regexp.QuoteMeta(`
-        INSERT INTO notification_reads (company_id, user_id, notification_key, read_at)
-        VALUES ($1,$2,$3,CURRENT_TIMESTAMP)
-        ON CONFLICT (company_id, user_id, notification_key) DO NOTHING
-    `))
-	mock.ExpectExec(regexp.QuoteMeta(`
-        INSERT INTO notification_reads (company_id, user_id, notification_key, read_at)
-        VALUES ($1,$2,$3,CURRENT_TIMESTAMP)
-        ON CONFLICT (company_id, user_id, notification_key) DO NOTHING
-    `)).
-		WithArgs(1, 2, "workflow_request:10").
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectExec(regexp.QuoteMeta(`
-        INSERT INTO notification_reads (company_id, user_id, notification_key, read_at)
-        VALUES ($1,$2,$3,CURRENT_TIMESTAMP)
-        ON CONFLICT (company_id, user_id, notification_key) DO NOTHING
-    `)).
-		WithArgs(1, 2, "low_stock:loc:1:product:2").
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit()
-
-	if err := svc.MarkRead(1, 2, []string{" workflow_request:10 ", "", "low_stock:loc:1:product:2"}); err != nil {
+	mock.ExpectQuery("FROM notification_user_settings").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"email_digest", "webhook_url"}).AddRow("DAILY", "https://hooks.example.com/erp"))
+	mock.ExpectQuery("FROM notification_preferences").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"event_type", "in_app", "email", "webhook"}).
+			AddRow(models.NotificationLowStock, false, true, true))
+
+	prefs, err := svc.GetPreferences(1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prefs.EmailDigest != "DAILY" || prefs.WebhookURL == nil {
+		t.Fatalf("unexpected settings: %+v", prefs)
+	}
+	if len(prefs.Events) != len(notificationEvents) {
+		t.Fatalf("expected one entry per event type, got %d", len(prefs.Events))
+	}
+	byType := map[string]models.NotificationEventPreference{}
+	for _, p := range prefs.Events {
+		byType[p.EventType] = p
+	}
+	if p := byType[models.NotificationLowStock]; p.InApp || !p.Email || !p.Webhook || p.Label != "Low stock" {
+		t.Fatalf("expected saved low stock preference, got %+v", p)
+	}
+	if p := byType[models.NotificationCashVariance]; !p.InApp || !p.Email || p.Webhook {
+		t.Fatalf("expected cash variance defaults, got %+v", p)
+	}
+	if p := byType[models.NotificationPeriodClosed]; !p.InApp || p.Email {
+		t.Fatalf("expected period closed defaults, got %+v", p)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPublishNotification_FansOutInOneStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	if err := publishNotification(db, NotificationEvent{CompanyID: 1, Type: "UNKNOWN"}); err == nil {
+		t.Fatalf("expected unknown event type error")
+	}
+
+	location := 3
+	mock.ExpectExec(`(?s)WITH recipients AS.*LEFT JOIN roles r ON r.role_id = COALESCE\(ul.role_id, u.role_id\).*INSERT INTO notifications.*ON CONFLICT.*INSERT INTO notification_deliveries`).
+		WithArgs(1, models.NotificationTransferInTransit, "Transfer TR-1 awaiting receipt", "", "INFO",
+			"STOCK_TRANSFER", 9, location, "", nil, nil, nil, false, "APPROVE_TRANSFERS", "transfer_in_transit:9").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = publishNotification(db, NotificationEvent{
+		CompanyID:  1,
+		Type:       models.NotificationTransferInTransit,
+		Title:      "Transfer TR-1 awaiting receipt",
+		EntityType: "STOCK_TRANSFER",
+		EntityID:   9,
+		LocationID: &location,
+		DedupeKey:  "transfer_in_transit:9",
+	})
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	// An explicit empty recipient list reaches nobody.
+	if err := publishNotification(db, NotificationEvent{CompanyID: 1, Type: models.NotificationLeaveDecided, UserIDs: []int{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestNotificationEmail_DigestsSeveral(t *testing.T) {
+	created := time.Date(2026, 4, 1, 9, 30, 0, 0, time.UTC)
+	one := []models.NotificationItem{{Title: "Cash variance of -5.00", Body: "Short by 5.00", Severity: "WARNING", CreatedAt: created}}
+	subject, body := notificationEmail(one)
+	if subject != "Cash variance of -5.00" || body != "Short by 5.00" {
+		t.Fatalf("unexpected single email: %q %q", subject, body)
+	}
+
+	two := append(one, models.NotificationItem{Title: "Accounting period Mar closed", Severity: "INFO", CreatedAt: created})
+	subject, body = notificationEmail(two)
+	if subject != "2 new notifications" {
+		t.Fatalf("unexpected digest subject %q", subject)
+	}
+	if !strings.Contains(body, "[WARNING] Cash variance of -5.00\n  Short by 5.00\n") ||
+		!strings.Contains(body, "[INFO] Accounting period Mar closed\n  2026-04-01 09:30") {
+		t.Fatalf("unexpected digest body:\n%s", body)
+	}
+}
@@ -403,6 +403,14 @@ func defaultPrefixFor(name string) *string {
 		p = "ADJ-"
 	case "stock_transfer":
 		p = "ST-"
+	case "assembly_order":
+		p = "ASM-"
+	case "landed_cost_voucher":
+		p = "LCV-"
+	case "consignment_receipt":
+		p = "CNR-"
+	case "consignment_settlement":
+		p = "CNS-"
 	default:
 		// Use first 3 letters uppercased as a generic prefix
 		up := strings.ToUpper(n)
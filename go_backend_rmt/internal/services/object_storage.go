@@ -0,0 +1,272 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/config"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrObjectNotFound        = errors.New("object not found")
+	ErrInvalidObjectKey      = errors.New("invalid object key")
+	ErrInvalidSignedURL      = errors.New("invalid signed url")
+	ErrExpiredSignedURL      = errors.New("signed url expired")
+	ErrUnknownStorageBackend = errors.New("unknown storage backend")
+)
+
+// Storage backends selectable through STORAGE_BACKEND.
+const (
+	StorageBackendLocal = "local"
+	StorageBackendS3    = "s3"
+)
+
+// signedFilesPrefix is where the API serves objects behind an HMAC signature.
+const signedFilesPrefix = "/files/"
+
+// ObjectStorage stores uploaded files under slash-separated keys such as
+// "logos/<uuid>.png". Every API replica must see the same objects, so
+// multi-replica deployments use the S3 backend.
+type ObjectStorage interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, *ObjectInfo, error)
+	Exists(key string) (bool, error)
+	Delete(key string) error
+	// SignedURL returns a time-limited download URL for key.
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// GetObjectStorage returns the storage backend configured for this process.
+func GetObjectStorage() (ObjectStorage, error) {
+	return NewObjectStorage(config.Load())
+}
+
+func NewObjectStorage(cfg *config.Config) (ObjectStorage, error) {
+	switch strings.ToLower(strings.TrimSpace(cfg.StorageBackend)) {
+	case "", StorageBackendLocal:
+		return NewLocalObjectStorage(cfg.UploadPath, storageSigningKey(cfg)), nil
+	case StorageBackendS3:
+		return NewS3ObjectStorage(S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStorageBackend, cfg.StorageBackend)
+	}
+}
+
+// storageSigningKey falls back to the JWT secret so local installs get signed
+// links without extra configuration.
+func storageSigningKey(cfg *config.Config) string {
+	if key := strings.TrimSpace(cfg.StorageSigningKey); key != "" {
+		return key
+	}
+	return cfg.JWTSecret
+}
+
+// StorageSignedURLTTL is the lifetime of download links handed to clients.
+func StorageSignedURLTTL() time.Duration {
+	if ttl := config.Load().StorageSignedURLTTL; ttl > 0 {
+		return ttl
+	}
+	return 15 * time.Minute
+}
+
+// cleanObjectKey rejects absolute keys, traversal and empty segments.
+func cleanObjectKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidObjectKey, key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("%w: %q", ErrInvalidObjectKey, key)
+		}
+	}
+	return key, nil
+}
+
+func contentTypeForKey(key string) string {
+	if ct := mime.TypeByExtension(strings.ToLower(path.Ext(key))); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// LocalObjectStorage keeps objects on the local UPLOAD_PATH disk. Signed URLs
+// point at the API's /files route and are verified with an HMAC.
+type LocalObjectStorage struct {
+	basePath   string
+	signingKey string
+	now        func() time.Time
+}
+
+func NewLocalObjectStorage(basePath, signingKey string) *LocalObjectStorage {
+	return &LocalObjectStorage{basePath: basePath, signingKey: signingKey, now: time.Now}
+}
+
+func (s *LocalObjectStorage) pathFor(key string) (string, error) {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return "", err
+	}
+	return safeJoinUnderBase(s.basePath, "", filepath.FromSlash(key))
+}
+
+func (s *LocalObjectStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	dst, err := s.pathFor(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial object.
+	tmp := dst + ".tmp-" + uuid.NewString()
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create dest file: %w", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStorage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	src, err := s.pathFor(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, ErrObjectNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open object: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, nil, ErrObjectNotFound
+	}
+	return f, &ObjectInfo{Key: key, Size: st.Size(), ContentType: contentTypeForKey(key)}, nil
+}
+
+func (s *LocalObjectStorage) Exists(key string) (bool, error) {
+	src, err := s.pathFor(key)
+	if err != nil {
+		return false, err
+	}
+	st, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return !st.IsDir(), nil
+}
+
+func (s *LocalObjectStorage) Delete(key string) error {
+	src, err := s.pathFor(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalObjectStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return "", err
+	}
+	if s.signingKey == "" {
+		return "", fmt.Errorf("storage signing key is not configured")
+	}
+	expires := s.now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", signStorageKey(s.signingKey, key, expires))
+	return signedFilesPrefix + escapeObjectKey(key) + "?" + q.Encode(), nil
+}
+
+// VerifyStorageSignature checks a /files link produced by LocalObjectStorage.
+func VerifyStorageSignature(key, expires, signature string, now time.Time) error {
+	return verifyStorageSignature(storageSigningKey(config.Load()), key, expires, signature, now)
+}
+
+func verifyStorageSignature(signingKey, key, expires, signature string, now time.Time) error {
+	if signingKey == "" || signature == "" {
+		return ErrInvalidSignedURL
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignedURL
+	}
+	want := signStorageKey(signingKey, key, exp)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignedURL
+	}
+	if now.Unix() > exp {
+		return ErrExpiredSignedURL
+	}
+	return nil
+}
+
+func signStorageKey(signingKey, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// escapeObjectKey percent-encodes each key segment, keeping the separators.
+func escapeObjectKey(key string) string {
+	segs := strings.Split(key, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	s3AmzDateFormat    = "20060102T150405Z"
+	s3MaxPresignTTL    = 7 * 24 * time.Hour
+)
+
+// S3Config addresses an S3-compatible bucket. MinIO and most self-hosted
+// implementations need UsePathStyle.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UsePathStyle    bool
+}
+
+// S3ObjectStorage talks to S3 over plain HTTP with Signature Version 4.
+type S3ObjectStorage struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3ObjectStorage(cfg S3Config) (*S3ObjectStorage, error) {
+	cfg.Region = strings.TrimSpace(cfg.Region)
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if strings.TrimSpace(cfg.Bucket) == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required for s3 storage")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required for s3 storage")
+	}
+	raw := strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/")
+	if raw == "" {
+		raw = "https://s3." + cfg.Region + ".amazonaws.com"
+		if cfg.Region == "us-east-1" {
+			raw = "https://s3.amazonaws.com"
+		}
+	}
+	endpoint, err := url.Parse(raw)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+	return &S3ObjectStorage{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3ObjectStorage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.UsePathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	}
+	u.RawPath = s3CanonicalURI(u.Path)
+	u.RawQuery = ""
+	return &u
+}
+
+func (s *S3ObjectStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read object body: %w", err)
+	}
+	if contentType == "" {
+		contentType = contentTypeForKey(key)
+	}
+	resp, err := s.do(http.MethodPut, key, body, map[string]string{"Content-Type": contentType})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return s3ResponseError("put", key, resp)
+	}
+	return nil
+}
+
+func (s *S3ObjectStorage) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrObjectNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, nil, s3ResponseError("get", key, resp)
+	}
+	info := &ObjectInfo{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	if info.ContentType == "" {
+		info.ContentType = contentTypeForKey(key)
+	}
+	return resp.Body, info, nil
+}
+
+func (s *S3ObjectStorage) Exists(key string) (bool, error) {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode/100 == 2:
+		return true, nil
+	default:
+		return false, s3ResponseError("head", key, resp)
+	}
+}
+
+func (s *S3ObjectStorage) Delete(key string) error {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return s3ResponseError("delete", key, resp)
+	}
+	return nil
+}
+
+// SignedURL returns a presigned GET URL that the client fetches directly from
+// the bucket.
+func (s *S3ObjectStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > s3MaxPresignTTL {
+		return "", fmt.Errorf("presigned url ttl must be between 1s and %s", s3MaxPresignTTL)
+	}
+	return s.presign(http.MethodGet, key, ttl, s.now().UTC()), nil
+}
+
+func (s *S3ObjectStorage) presign(method, key string, ttl time.Duration, now time.Time) string {
+	u := s.objectURL(key)
+	amzDate := now.Format(s3AmzDateFormat)
+	scope := s.credentialScope(now)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", s3SigningAlgorithm)
+	q.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(ttl/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalQuery := s3CanonicalQuery(q)
+	canonical := strings.Join([]string{
+		method,
+		s3CanonicalURI(u.Path),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	signature := s.signature(now, amzDate, scope, canonical)
+
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return u.String()
+}
+
+func (s *S3ObjectStorage) do(method, key string, body []byte, headers map[string]string) (*http.Response, error) {
+	u := s.objectURL(key)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build s3 request: %w", err)
+	}
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	s.signRequest(req, body, s.now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach object storage: %w", err)
+	}
+	return resp, nil
+}
+
+// signRequest adds a SigV4 Authorization header covering host, the payload
+// hash and the request date.
+func (s *S3ObjectStorage) signRequest(req *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.Format(s3AmzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		signed["content-type"] = ct
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signed[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	scope := s.credentialScope(now)
+	canonical := strings.Join([]string{
+		req.Method,
+		s3CanonicalURI(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	signature := s.signature(now, amzDate, scope, canonical)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func (s *S3ObjectStorage) credentialScope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3ObjectStorage) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalURI encodes every byte outside the RFC 3986 unreserved set,
+// leaving the path separators alone.
+func s3CanonicalURI(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = s3URIEncode(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3URIEncode(k)+"="+s3URIEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3URIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func s3ResponseError(op, key string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("failed to %s object %s: s3 returned %d: %s", op, key, resp.StatusCode, strings.TrimSpace(string(msg)))
+}
@@ -117,6 +117,9 @@ func (s *PaymentService) CreatePayment(companyID, locationID, userID int, req *m
 		if purCompanyID != companyID {
 			return nil, fmt.Errorf("purchase does not belong to company")
 		}
+		if err := (&InvoiceMatchService{db: s.db}).ensurePaymentAllowedTx(tx, companyID, *req.PurchaseID); err != nil {
+			return nil, err
+		}
 		// enforce not overpaying a specific purchase
 		if req.Amount > (totalAmt-paidAmt)+0.0001 { // small epsilon
 			return nil, fmt.Errorf("payment exceeds outstanding amount for purchase")
@@ -192,64 +195,79 @@ func (s *PaymentService) CreatePayment(companyID, locationID, userID int, req *m
 	}
 
 	finance := NewFinanceIntegrityServiceWithDB(s.db)
+	if err := s.enqueueSupplierPaymentEventsTx(tx, finance, companyID, locationID, userID, p.PaymentID, p.PaymentNumber, req.Amount, req.PaymentMethodID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if err := finance.ProcessAggregate(companyID, "payment", p.PaymentID); err != nil {
+		log.Printf("payment_service: failed to process finance outbox for payment %d: %v", p.PaymentID, err)
+	}
+
+	p.SupplierID = supplierID
+	p.PurchaseID = req.PurchaseID
+	p.LocationID = &locationID
+	p.Amount = req.Amount
+	p.PaymentMethodID = req.PaymentMethodID
+	p.ReferenceNumber = req.ReferenceNumber
+	p.Notes = req.Notes
+	p.IdempotencyKey = nullIfEmpty(idemKey)
+	p.CreatedBy = userID
+	p.SyncStatus = "synced"
+	return &p, nil
+}
+
+// enqueueSupplierPaymentEventsTx queues the ledger posting for a supplier
+// payment and, for cash payments, the matching cash register movement.
+func (s *PaymentService) enqueueSupplierPaymentEventsTx(tx *sql.Tx, finance *FinanceIntegrityService, companyID, locationID, userID, paymentID int, paymentNumber string, amount float64, paymentMethodID *int) error {
 	if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
 		CompanyID:     companyID,
 		LocationID:    &locationID,
 		EventType:     financeEventLedgerSupplierPay,
 		AggregateType: "payment",
-		AggregateID:   p.PaymentID,
+		AggregateID:   paymentID,
 		Payload:       models.JSONB{},
 		CreatedBy:     &userID,
 	}); err != nil {
-		return nil, fmt.Errorf("failed to enqueue supplier payment ledger posting: %w", err)
+		return fmt.Errorf("failed to enqueue supplier payment ledger posting: %w", err)
 	}
 
 	isCash := true
-	if req.PaymentMethodID != nil {
+	if paymentMethodID != nil {
 		var paymentType string
-		if err := tx.QueryRow(`SELECT type FROM payment_methods WHERE method_id = $1`, *req.PaymentMethodID).Scan(&paymentType); err == nil {
+		if err := tx.QueryRow(`SELECT type FROM payment_methods WHERE method_id = $1`, *paymentMethodID).Scan(&paymentType); err == nil {
 			isCash = strings.EqualFold(strings.TrimSpace(paymentType), "CASH")
 		}
 	}
-	if isCash && req.Amount > 0 {
-		note := fmt.Sprintf("payment_id=%d payment_number=%s", p.PaymentID, p.PaymentNumber)
+	if isCash && amount > 0 {
+		note := fmt.Sprintf("payment_id=%d payment_number=%s", paymentID, paymentNumber)
 		if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
 			CompanyID:     companyID,
 			LocationID:    &locationID,
 			EventType:     financeEventCashSupplierPay,
 			AggregateType: "payment",
-			AggregateID:   p.PaymentID,
+			AggregateID:   paymentID,
 			Payload: models.JSONB{
-				"amount":      req.Amount,
+				"amount":      amount,
 				"direction":   "OUT",
 				"event_type":  "SUPPLIER_PAYMENT",
-				"reason_code": fmt.Sprintf("payment:%d", p.PaymentID),
+				"reason_code": fmt.Sprintf("payment:%d", paymentID),
 				"notes":       note,
 			},
 			CreatedBy: &userID,
 		}); err != nil {
-			return nil, fmt.Errorf("failed to enqueue supplier payment cash register event: %w", err)
+			return fmt.Errorf("failed to enqueue supplier payment cash register event: %w", err)
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	if err := finance.ProcessAggregate(companyID, "payment", p.PaymentID); err != nil {
-		log.Printf("payment_service: failed to process finance outbox for payment %d: %v", p.PaymentID, err)
-	}
-
-	p.SupplierID = supplierID
-	p.PurchaseID = req.PurchaseID
-	p.LocationID = &locationID
-	p.Amount = req.Amount
-	p.PaymentMethodID = req.PaymentMethodID
-	p.ReferenceNumber = req.ReferenceNumber
-	p.Notes = req.Notes
-	p.IdempotencyKey = nullIfEmpty(idemKey)
-	p.CreatedBy = userID
-	p.SyncStatus = "synced"
-	return &p, nil
+	return enqueueWebhookEventTx(tx, companyID, models.WebhookEventSupplierPaymentCreated, "payment", paymentID, models.JSONB{
+		"payment_id":        paymentID,
+		"payment_number":    paymentNumber,
+		"location_id":       locationID,
+		"amount":            amount,
+		"payment_method_id": paymentMethodID,
+	})
 }
 
 func (s *PaymentService) getPaymentByIdempotencyKey(key string, companyID, locationID int) (*models.Payment, error) {
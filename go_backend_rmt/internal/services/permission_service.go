@@ -33,6 +33,53 @@ func (s *PermissionService) UserHasPermission(userID int, permission string) (bo
 	return count > 0, nil
 }
 
+// UserCan checks a permission against the user's active role, as
+// RequirePermission does: roleID is the request's role_id, which RequireAuth
+// resolves from the active location. Without a request (roleID 0) the role
+// of the user's default location is used, falling back to their base role.
+// Admin roles pass every check. Handlers use it when the permission depends
+// on the request payload.
+func (s *PermissionService) UserCan(userID, roleID int, permission string) (bool, error) {
+	if userID == 0 {
+		return false, fmt.Errorf("invalid permission query")
+	}
+	if permission == "" {
+		return true, nil
+	}
+	var has bool
+	err := s.db.QueryRow(`
+		WITH active AS (
+			SELECT COALESCE(NULLIF($2::int, 0), (
+			         SELECT ul.role_id
+			         FROM user_locations ul
+			         JOIN locations l ON l.location_id = ul.location_id
+			         WHERE ul.user_id = u.user_id AND l.company_id = u.company_id
+			           AND COALESCE(l.is_active, TRUE) = TRUE
+			         ORDER BY ul.location_id = u.location_id DESC, ul.location_id
+			         LIMIT 1
+			       ), u.role_id) AS role_id
+			FROM users u
+			WHERE u.user_id = $1 AND u.is_active = TRUE AND u.is_deleted = FALSE
+		)
+		SELECT EXISTS (
+			SELECT 1
+			FROM active a
+			JOIN roles r ON r.role_id = a.role_id
+			WHERE LOWER(r.name) IN ('super admin', 'admin')
+		) OR EXISTS (
+			SELECT 1
+			FROM active a
+			JOIN role_permissions rp ON rp.role_id = a.role_id
+			JOIN permissions p ON rp.permission_id = p.permission_id
+			WHERE p.name = $3
+		)
+	`, userID, roleID, permission).Scan(&has)
+	if err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	return has, nil
+}
+
 func (s *PermissionService) GetUserRoleID(userID int) (int, error) {
 	if userID == 0 {
 		return 0, fmt.Errorf("invalid user id")
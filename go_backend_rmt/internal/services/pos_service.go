@@ -64,7 +64,8 @@ func (s *POSService) GetPOSProducts(companyID, locationID int, includeCombos boo
                            su.name as selling_unit_name,
                            su.symbol as selling_unit_symbol,
                            COALESCE((pb.variant_attributes->>'loyalty_gift_enabled')::boolean, FALSE) as is_loyalty_gift,
-                           COALESCE((pb.variant_attributes->>'loyalty_points_required')::float8, 0) as loyalty_points_required
+                           COALESCE((pb.variant_attributes->>'loyalty_points_required')::float8, 0) as loyalty_points_required,
+                           EXISTS (SELECT 1 FROM product_variant_dimensions pvd WHERE pvd.product_id = p.product_id) as has_variant_matrix
                 FROM products p
                 LEFT JOIN product_barcodes pb ON p.product_id = pb.product_id AND pb.is_primary = TRUE
                 LEFT JOIN stock st ON p.product_id = st.product_id AND st.location_id = $2
@@ -99,7 +100,8 @@ func (s *POSService) GetPOSProducts(companyID, locationID int, includeCombos boo
                            NULL::varchar as selling_unit_name,
                            NULL::varchar as selling_unit_symbol,
                            FALSE as is_loyalty_gift,
-                           0::float8 as loyalty_points_required
+                           0::float8 as loyalty_points_required,
+                           FALSE as has_variant_matrix
                 FROM combo_products cp
                 LEFT JOIN LATERAL (
                   SELECT CASE
@@ -131,7 +133,7 @@ func (s *POSService) GetPOSProducts(companyID, locationID int, includeCombos boo
 			&product.Barcode, &product.VariantName, &product.CategoryName, &product.PrimaryStorage, &product.IsVirtualCombo,
 			&product.IsWeighable, &product.TrackingType, &product.IsSerialized, &product.SellingUOMMode,
 			&product.SellingUnitID, &product.SellingUnitName, &product.SellingUnitSymbol,
-			&product.IsLoyaltyGift, &product.LoyaltyPointsRequired,
+			&product.IsLoyaltyGift, &product.LoyaltyPointsRequired, &product.HasVariantMatrix,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan POS product: %w", err)
@@ -201,7 +203,7 @@ func (s *POSService) ProcessCheckout(companyID, locationID, userID int, req *mod
 		return nil, fmt.Errorf("b2b transactions require customer_id")
 	}
 
-	trainingEnabled, err := s.isTrainingModeEnabled(companyID, locationID)
+	trainingEnabled, err := s.isTrainingModeEnabled(companyID, locationID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -250,6 +252,10 @@ func (s *POSService) ProcessCheckout(companyID, locationID, userID int, req *mod
 	if err != nil {
 		return nil, err
 	}
+	expiredApproverID, allowExpired, err := s.resolveExpiredStockOverride(companyID, req.ManagerOverrideToken, req.OverrideReason)
+	if err != nil {
+		return nil, err
+	}
 
 	// Normal flow: create a fresh sale
 	saleReq := &models.CreateSaleRequest{
@@ -355,6 +361,7 @@ func (s *POSService) ProcessCheckout(companyID, locationID, userID int, req *mod
 			CouponCode:                 strings.TrimSpace(ptrString(req.CouponCode)),
 			AutoFillRaffleCustomerData: req.AutoFillRaffleCustomerData,
 			SourceChannel:              "POS",
+			AllowExpiredStock:          allowExpired,
 		},
 	)
 	if err != nil {
@@ -390,6 +397,9 @@ func (s *POSService) ProcessCheckout(companyID, locationID, userID int, req *mod
 			_ = tx.Commit()
 		}
 	}
+	if allowExpired && sale != nil {
+		s.logExpiredStockOverride(sale.SaleID, userID, expiredApproverID, req.OverrideReason)
+	}
 
 	return sale, nil
 }
@@ -555,7 +565,7 @@ func (s *POSService) GetHeldSales(companyID, locationID int) ([]models.Sale, err
 func (s *POSService) SearchProducts(companyID, locationID int, searchTerm string, includeCombos bool) ([]models.POSProductResponse, error) {
 	// Enrich POS search to match:
 	// - name (ILIKE)
-	// - sku (ILIKE)
+	// - sku (ILIKE), including generated variant SKUs
 	// - barcode (exact OR LIKE)
 	// - category name (ILIKE)
 	// - attribute values (ILIKE)
@@ -576,7 +586,8 @@ func (s *POSService) SearchProducts(companyID, locationID int, searchTerm string
                            su.name as selling_unit_name,
                            su.symbol as selling_unit_symbol,
                            COALESCE((pb.variant_attributes->>'loyalty_gift_enabled')::boolean, FALSE) as is_loyalty_gift,
-                           COALESCE((pb.variant_attributes->>'loyalty_points_required')::float8, 0) as loyalty_points_required
+                           COALESCE((pb.variant_attributes->>'loyalty_points_required')::float8, 0) as loyalty_points_required,
+                           EXISTS (SELECT 1 FROM product_variant_dimensions pvd WHERE pvd.product_id = p.product_id) as has_variant_matrix
                 FROM products p
                 JOIN product_barcodes pb ON p.product_id = pb.product_id AND COALESCE(pb.is_active, TRUE) = TRUE
                 LEFT JOIN stock_variants sv ON pb.barcode_id = sv.barcode_id AND sv.location_id = $2
@@ -595,6 +606,7 @@ func (s *POSService) SearchProducts(companyID, locationID int, searchTerm string
                         LOWER(p.name) LIKE LOWER($3) OR
                         LOWER(COALESCE(pb.variant_name, '')) LIKE LOWER($3) OR
                         LOWER(COALESCE(p.sku, '')) LIKE LOWER($3) OR
+                        LOWER(COALESCE(pb.sku, '')) LIKE LOWER($3) OR
                         pb.barcode = $4 OR
                         pb.barcode ILIKE $3 OR
                         (c.name IS NOT NULL AND LOWER(c.name) LIKE LOWER($3)) OR
@@ -624,7 +636,8 @@ func (s *POSService) SearchProducts(companyID, locationID int, searchTerm string
                            NULL::varchar as selling_unit_name,
                            NULL::varchar as selling_unit_symbol,
                            FALSE as is_loyalty_gift,
-                           0::float8 as loyalty_points_required
+                           0::float8 as loyalty_points_required,
+                           FALSE as has_variant_matrix
                 FROM combo_products cp
                 LEFT JOIN LATERAL (
                     SELECT CASE
@@ -663,7 +676,7 @@ func (s *POSService) SearchProducts(companyID, locationID int, searchTerm string
 			&product.ProductID, &product.ComboProductID, &product.BarcodeID, &product.Name, &product.Price, &product.Stock,
 			&product.Barcode, &product.VariantName, &product.CategoryName, &product.PrimaryStorage, &product.IsVirtualCombo, &product.IsWeighable, &product.TrackingType, &product.IsSerialized, &product.SellingUOMMode,
 			&product.SellingUnitID, &product.SellingUnitName, &product.SellingUnitSymbol,
-			&product.IsLoyaltyGift, &product.LoyaltyPointsRequired,
+			&product.IsLoyaltyGift, &product.LoyaltyPointsRequired, &product.HasVariantMatrix,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan search result: %w", err)
@@ -956,6 +969,10 @@ func (s *POSService) finalizeHeldSale(companyID, locationID, userID, saleID int,
 	if err != nil {
 		return nil, err
 	}
+	expiredApproverID, allowExpired, err := s.resolveExpiredStockOverride(companyID, req.ManagerOverrideToken, req.OverrideReason)
+	if err != nil {
+		return nil, err
+	}
 	if !isTraining && req.CustomerID != nil {
 		outstandingDelta := total - req.PaidAmount
 		if outstandingDelta > 0.0001 {
@@ -1013,6 +1030,7 @@ func (s *POSService) finalizeHeldSale(companyID, locationID, userID, saleID int,
 			BatchAllocations: line.BatchAllocations,
 			Notes:            line.Notes,
 			OverridePassword: req.OverridePassword,
+			AllowExpired:     allowExpired,
 		}
 
 		issue, err := trackingSvc.IssueStockTx(tx, companyID, locationID, userID, "SALE", "sale_detail", &saleDetailID, nil, selection)
@@ -1058,10 +1076,22 @@ func (s *POSService) finalizeHeldSale(companyID, locationID, userID, saleID int,
 		}
 	}
 
+	if !isTraining {
+		if err := enqueueEInvoiceTx(tx, NewFinanceIntegrityServiceWithDB(s.db), companyID, locationID, einvoiceSourceSale, saleID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit finalize: %w", err)
 	}
 
+	if !isTraining {
+		if err := NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "sale", saleID); err != nil {
+			log.Printf("warning: failed to process finance outbox for sale %d: %v", saleID, err)
+		}
+	}
+
 	// Best-effort audit log when a manager override was used (do not fail the sale).
 	if overrideUsed {
 		tx2, err := s.db.Begin()
@@ -1078,6 +1108,9 @@ func (s *POSService) finalizeHeldSale(companyID, locationID, userID, saleID int,
 			_ = tx2.Commit()
 		}
 	}
+	if allowExpired {
+		s.logExpiredStockOverride(saleID, userID, expiredApproverID, req.OverrideReason)
+	}
 
 	return s.salesService.GetSaleByID(saleID, companyID)
 }
@@ -1292,7 +1325,7 @@ func (s *POSService) CreateHeldSale(companyID, locationID, userID int, req *mode
 		return nil, err
 	}
 
-	isTraining, err := s.isTrainingModeEnabled(companyID, locationID)
+	isTraining, err := s.isTrainingModeEnabled(companyID, locationID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -1536,15 +1569,21 @@ func (s *POSService) CreateHeldSale(companyID, locationID, userID int, req *mode
 	return s.salesService.GetSaleByID(saleID, companyID)
 }
 
-func (s *POSService) isTrainingModeEnabled(companyID, locationID int) (bool, error) {
+// isTrainingModeEnabled reports the training flag of the register the user is
+// working on, preferring the one where they hold the open shift.
+func (s *POSService) isTrainingModeEnabled(companyID, locationID, userID int) (bool, error) {
 	var enabled bool
 	err := s.db.QueryRow(`
         SELECT COALESCE(cr.training_mode, FALSE)
         FROM cash_register cr
         JOIN locations l ON cr.location_id = l.location_id
         WHERE cr.location_id = $1 AND l.company_id = $2 AND cr.status = 'OPEN'
+        ORDER BY EXISTS (
+            SELECT 1 FROM cash_register_shifts sh
+            WHERE sh.register_id = cr.register_id AND sh.status = 'OPEN' AND sh.cashier_id = $3
+        ) DESC, cr.register_id
         LIMIT 1
-    `, locationID, companyID).Scan(&enabled)
+    `, locationID, companyID, userID).Scan(&enabled)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -1623,21 +1662,64 @@ func (s *POSService) enforceDiscountLimits(
 	return ctx.ApproverUserID, true, nil
 }
 
+// resolveExpiredStockOverride reports whether the checkout carries a manager
+// override for selling expired lots. A token without that permission is left
+// to the other override checks.
+func (s *POSService) resolveExpiredStockOverride(companyID int, overrideToken, overrideReason *string) (approverUserID int, allowed bool, err error) {
+	token := ""
+	if overrideToken != nil {
+		token = strings.TrimSpace(*overrideToken)
+	}
+	if token == "" {
+		return 0, false, nil
+	}
+	ctx, err := ValidateOverrideToken(token, companyID, []string{permissionSellExpiredStock})
+	if err != nil {
+		return 0, false, nil
+	}
+	if overrideReason == nil || strings.TrimSpace(*overrideReason) == "" {
+		return 0, false, fmt.Errorf("override_reason is required")
+	}
+	return ctx.ApproverUserID, true, nil
+}
+
+// logExpiredStockOverride is a best-effort audit of an expired stock override.
+func (s *POSService) logExpiredStockOverride(saleID, userID, approverUserID int, reason *string) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return
+	}
+	recordID := saleID
+	actorID := userID
+	changes := models.JSONB{
+		"override":             true,
+		"override_type":        "expired_stock",
+		"override_approver_id": approverUserID,
+		"override_reason":      strings.TrimSpace(ptrString(reason)),
+	}
+	_ = LogAudit(tx, "OVERRIDE", "sales", &recordID, &actorID, nil, nil, &changes, nil, nil)
+	_ = tx.Commit()
+}
+
 func (s *POSService) enforceCustomerCreditLimit(companyID, customerID int, additionalOutstanding float64) error {
 	if additionalOutstanding <= 0 {
 		return nil
 	}
 
+	var name string
 	var limit float64
 	var current float64
+	var dunningLevel int
+	var dunningBlocked bool
 	err := s.db.QueryRow(`
-		SELECT c.credit_limit,
-			   COALESCE(SUM(s.total_amount - s.paid_amount),0) AS credit_balance
+		SELECT c.name, c.credit_limit,
+			   COALESCE(SUM(s.total_amount - s.paid_amount),0) AS credit_balance,
+			   c.dunning_level, c.dunning_credit_blocked
 		FROM customers c
 		LEFT JOIN sales s ON c.customer_id = s.customer_id AND s.is_deleted = FALSE
 		WHERE c.customer_id = $1 AND c.company_id = $2 AND c.is_deleted = FALSE
-		GROUP BY c.credit_limit
-	`, customerID, companyID).Scan(&limit, &current)
+		GROUP BY c.customer_id, c.name, c.credit_limit, c.dunning_level, c.dunning_credit_blocked
+	`, customerID, companyID).Scan(&name, &limit, &current, &dunningLevel, &dunningBlocked)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("customer not found")
@@ -1645,12 +1727,23 @@ func (s *POSService) enforceCustomerCreditLimit(companyID, customerID int, addit
 		return fmt.Errorf("failed to check credit limit: %w", err)
 	}
 
-	// A limit of 0 (or negative) means no credit allowed.
-	if limit <= 0 {
-		return &CreditLimitExceededError{CreditLimit: limit, CurrentBalance: current, AttemptedDelta: additionalOutstanding}
+	if dunningBlocked {
+		return &CustomerDunningBlockedError{DunningLevel: dunningLevel}
 	}
 
-	if current+additionalOutstanding > limit+0.0001 {
+	// A limit of 0 (or negative) means no credit allowed.
+	if limit <= 0 || current+additionalOutstanding > limit+0.0001 {
+		notify(s.db, NotificationEvent{
+			CompanyID:   companyID,
+			Type:        models.NotificationCreditLimit,
+			Title:       "Credit limit exceeded: " + name,
+			Body:        fmt.Sprintf("A credit sale of %.2f was blocked (limit %.2f, outstanding %.2f)", additionalOutstanding, limit, current),
+			Severity:    "WARNING",
+			EntityType:  "CUSTOMER",
+			EntityID:    customerID,
+			ActionLabel: "Open customer",
+			DedupeKey:   fmt.Sprintf("credit_limit:%d:%s", customerID, time.Now().Format("2006-01-02")),
+		})
 		return &CreditLimitExceededError{CreditLimit: limit, CurrentBalance: current, AttemptedDelta: additionalOutstanding}
 	}
 
@@ -0,0 +1,706 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+// maxVariantMatrixCells caps how many barcodes one parent product can
+// generate so a mistyped option list cannot flood the catalogue.
+const maxVariantMatrixCells = 500
+
+// ProductVariantService generates child barcodes for a parent product from
+// its variant dimensions. Variants stay barcodes of the parent, so stock,
+// sales and product reports roll up to the parent through product_id.
+type ProductVariantService struct {
+	db             *sql.DB
+	productService *ProductService
+}
+
+func NewProductVariantService() *ProductVariantService {
+	return &ProductVariantService{
+		db:             database.GetDB(),
+		productService: NewProductService(),
+	}
+}
+
+type variantMatrixDimension struct {
+	AttributeID int
+	Name        string
+	Options     []models.ProductVariantOption
+}
+
+type variantMatrixCell struct {
+	Key        string
+	SKU        string
+	Name       string
+	Options    map[string]string
+	PriceDelta float64
+	CostDelta  float64
+}
+
+type matrixBarcodeRow struct {
+	BarcodeID int
+	Barcode   string
+	MatrixKey *string
+	IsActive  bool
+}
+
+// variantOptionCode derives the SKU suffix for an option value from its
+// letters and digits, e.g. "X-Large" becomes "XLARGE".
+func variantOptionCode(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(value) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+		if b.Len() >= 20 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// resolveDimensionOptions merges the configured options of a dimension with
+// the options of its attribute definition. Configured values must exist on
+// the attribute and codes must be unique within the dimension.
+func resolveDimensionOptions(attributeName string, attributeOptions []string, configured []models.ProductVariantOption, follow bool) ([]models.ProductVariantOption, error) {
+	allowed := make(map[string]struct{}, len(attributeOptions))
+	for _, opt := range attributeOptions {
+		allowed[opt] = struct{}{}
+	}
+	byValue := make(map[string]models.ProductVariantOption, len(configured))
+	for _, opt := range configured {
+		if _, ok := allowed[opt.Value]; !ok {
+			return nil, fmt.Errorf("value %q is not an option of attribute %s", opt.Value, attributeName)
+		}
+		byValue[opt.Value] = opt
+	}
+
+	resolved := make([]models.ProductVariantOption, 0, len(attributeOptions))
+	appendOption := func(opt models.ProductVariantOption) {
+		if strings.TrimSpace(opt.Code) == "" {
+			opt.Code = variantOptionCode(opt.Value)
+		}
+		opt.Code = strings.ToUpper(strings.TrimSpace(opt.Code))
+		opt.Position = len(resolved)
+		resolved = append(resolved, opt)
+	}
+	if follow {
+		for _, value := range attributeOptions {
+			opt, ok := byValue[value]
+			if !ok {
+				opt = models.ProductVariantOption{Value: value}
+			}
+			appendOption(opt)
+		}
+	} else {
+		for _, opt := range configured {
+			appendOption(opt)
+		}
+	}
+
+	codes := make(map[string]string, len(resolved))
+	for _, opt := range resolved {
+		if opt.Code == "" {
+			return nil, fmt.Errorf("option %q of attribute %s needs a code", opt.Value, attributeName)
+		}
+		if other, ok := codes[opt.Code]; ok {
+			return nil, fmt.Errorf("options %q and %q of attribute %s share code %s", other, opt.Value, attributeName, opt.Code)
+		}
+		codes[opt.Code] = opt.Value
+	}
+	return resolved, nil
+}
+
+// buildVariantMatrix expands the dimensions into every option combination,
+// first dimension outermost. SKUs append the option codes to baseSKU.
+func buildVariantMatrix(baseSKU string, dims []variantMatrixDimension) ([]variantMatrixCell, error) {
+	if len(dims) == 0 {
+		return nil, fmt.Errorf("product has no variant dimensions")
+	}
+	total := 1
+	for _, dim := range dims {
+		if len(dim.Options) == 0 {
+			return nil, fmt.Errorf("attribute %s has no options for the variant matrix", dim.Name)
+		}
+		total *= len(dim.Options)
+		if total > maxVariantMatrixCells {
+			return nil, fmt.Errorf("variant matrix exceeds %d variants", maxVariantMatrixCells)
+		}
+	}
+
+	cells := make([]variantMatrixCell, 0, total)
+	indexes := make([]int, len(dims))
+	for {
+		keys := make([]string, len(dims))
+		codes := make([]string, len(dims))
+		names := make([]string, len(dims))
+		cell := variantMatrixCell{Options: make(map[string]string, len(dims))}
+		for i, dim := range dims {
+			opt := dim.Options[indexes[i]]
+			keys[i] = fmt.Sprintf("%d:%s", dim.AttributeID, opt.Value)
+			codes[i] = opt.Code
+			names[i] = opt.Value
+			cell.Options[dim.Name] = opt.Value
+			cell.PriceDelta += opt.PriceDelta
+			cell.CostDelta += opt.CostDelta
+		}
+		cell.Key = strings.Join(keys, "|")
+		cell.SKU = baseSKU + "-" + strings.Join(codes, "-")
+		cell.Name = strings.Join(names, " / ")
+		cell.PriceDelta = round2(cell.PriceDelta)
+		cell.CostDelta = round2(cell.CostDelta)
+		cells = append(cells, cell)
+
+		i := len(dims) - 1
+		for i >= 0 {
+			indexes[i]++
+			if indexes[i] < len(dims[i].Options) {
+				break
+			}
+			indexes[i] = 0
+			i--
+		}
+		if i < 0 {
+			break
+		}
+	}
+	return cells, nil
+}
+
+func parseSelectOptions(def models.ProductAttributeDefinition) ([]string, error) {
+	if def.Type != "SELECT" {
+		return nil, fmt.Errorf("attribute %s must be a SELECT attribute to be a variant dimension", def.Name)
+	}
+	if def.Options == nil {
+		return nil, fmt.Errorf("attribute %s has no options", def.Name)
+	}
+	var opts []string
+	if err := json.Unmarshal([]byte(*def.Options), &opts); err != nil {
+		return nil, fmt.Errorf("invalid options for attribute %s", def.Name)
+	}
+	return opts, nil
+}
+
+// GetVariantMatrix returns the dimensions and generated variants of a product.
+// Stock is for locationID when given, otherwise across locations; sales can
+// be limited to a date range.
+func (s *ProductVariantService) GetVariantMatrix(companyID, productID int, locationID *int, fromDate, toDate string, activeOnly bool) (*models.ProductVariantMatrix, error) {
+	matrix := &models.ProductVariantMatrix{ProductID: productID, LocationID: locationID}
+	err := s.db.QueryRow(`
+		SELECT name, sku
+		FROM products
+		WHERE product_id = $1 AND company_id = $2 AND is_deleted = FALSE
+	`, productID, companyID).Scan(&matrix.ProductName, &matrix.SKU)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	dims, err := s.loadDimensions(s.db, companyID, productID)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Dimensions = dims
+
+	var location interface{}
+	if locationID != nil && *locationID > 0 {
+		location = *locationID
+	}
+	var from, to interface{}
+	if fromDate != "" {
+		from = fromDate
+	}
+	if toDate != "" {
+		to = toDate
+	}
+	rows, err := s.db.Query(`
+		SELECT pb.barcode_id, pb.sku, pb.barcode, pb.variant_name,
+		       COALESCE(pb.variant_attributes, '{}'::jsonb),
+		       COALESCE(pb.selling_price, p.selling_price, 0)::float8,
+		       COALESCE(pb.cost_price, p.cost_price, 0)::float8,
+		       COALESCE(pb.is_active, TRUE),
+		       COALESCE(st.quantity, 0)::float8,
+		       COALESCE(sold.quantity, 0)::float8,
+		       COALESCE(sold.amount, 0)::float8
+		FROM product_barcodes pb
+		JOIN products p ON p.product_id = pb.product_id
+		LEFT JOIN LATERAL (
+			SELECT SUM(sv.quantity) AS quantity
+			FROM stock_variants sv
+			WHERE sv.barcode_id = pb.barcode_id
+			  AND ($2::int IS NULL OR sv.location_id = $2)
+		) st ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT SUM(sd.quantity) AS quantity, SUM(sd.line_total) AS amount
+			FROM sale_details sd
+			JOIN sales s ON s.sale_id = sd.sale_id
+			WHERE sd.barcode_id = pb.barcode_id
+			  AND s.is_deleted = FALSE
+			  AND COALESCE(s.is_training, FALSE) = FALSE
+			  AND ($2::int IS NULL OR s.location_id = $2)
+			  AND ($3::date IS NULL OR s.sale_date >= $3)
+			  AND ($4::date IS NULL OR s.sale_date <= $4)
+		) sold ON TRUE
+		WHERE pb.product_id = $1
+		  AND pb.matrix_key IS NOT NULL
+		  AND ($5 = FALSE OR COALESCE(pb.is_active, TRUE) = TRUE)
+		ORDER BY pb.barcode_id
+	`, productID, location, from, to, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product variants: %w", err)
+	}
+	defer rows.Close()
+
+	matrix.Variants = make([]models.ProductVariantCell, 0)
+	for rows.Next() {
+		var cell models.ProductVariantCell
+		var attrs models.JSONB
+		if err := rows.Scan(
+			&cell.BarcodeID, &cell.SKU, &cell.Barcode, &cell.VariantName, &attrs,
+			&cell.SellingPrice, &cell.CostPrice, &cell.IsActive,
+			&cell.StockQuantity, &cell.SoldQuantity, &cell.SalesAmount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan product variant: %w", err)
+		}
+		cell.Options = make(map[string]string, len(dims))
+		for _, dim := range dims {
+			if value, ok := attrs[dim.AttributeName].(string); ok {
+				cell.Options[dim.AttributeName] = value
+			}
+		}
+		matrix.Variants = append(matrix.Variants, cell)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read product variants: %w", err)
+	}
+
+	// Parent totals cover every barcode of the product, not only the
+	// generated ones, so they match product-level stock and sales reports.
+	err = s.db.QueryRow(`
+		SELECT
+			COALESCE((
+				SELECT SUM(st.quantity)
+				FROM stock st
+				WHERE st.product_id = $1
+				  AND ($2::int IS NULL OR st.location_id = $2)
+			), 0)::float8,
+			COALESCE(SUM(sd.quantity), 0)::float8,
+			COALESCE(SUM(sd.line_total), 0)::float8
+		FROM sale_details sd
+		JOIN sales s ON s.sale_id = sd.sale_id
+		WHERE sd.product_id = $1
+		  AND s.is_deleted = FALSE
+		  AND COALESCE(s.is_training, FALSE) = FALSE
+		  AND ($2::int IS NULL OR s.location_id = $2)
+		  AND ($3::date IS NULL OR s.sale_date >= $3)
+		  AND ($4::date IS NULL OR s.sale_date <= $4)
+	`, productID, location, from, to).Scan(&matrix.TotalStock, &matrix.TotalSoldQuantity, &matrix.TotalSalesAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product variant totals: %w", err)
+	}
+	return matrix, nil
+}
+
+// SetDimensions replaces the variant dimensions of a product and syncs its
+// matrix. Existing variants keep their barcodes as long as their option
+// combination survives.
+func (s *ProductVariantService) SetDimensions(companyID, productID int, req *models.SetProductVariantDimensionsRequest) (*models.ProductVariantMatrix, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.lockProductTx(tx, companyID, productID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]struct{}, len(req.Dimensions))
+	for _, dim := range req.Dimensions {
+		if _, dup := seen[dim.AttributeID]; dup {
+			return nil, fmt.Errorf("attribute %d is used by more than one dimension", dim.AttributeID)
+		}
+		seen[dim.AttributeID] = struct{}{}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM product_variant_dimensions WHERE product_id = $1`, productID); err != nil {
+		return nil, fmt.Errorf("failed to clear variant dimensions: %w", err)
+	}
+	for position, dim := range req.Dimensions {
+		follow := dim.FollowAttributeOptions || len(dim.Options) == 0
+		var dimensionID int
+		if err := tx.QueryRow(`
+			INSERT INTO product_variant_dimensions (product_id, attribute_id, position, follow_attribute_options)
+			SELECT $1, attribute_id, $3, $4
+			FROM product_attributes
+			WHERE attribute_id = $2 AND company_id = $5 AND is_deleted = FALSE
+			RETURNING dimension_id
+		`, productID, dim.AttributeID, position, follow, companyID).Scan(&dimensionID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("attribute %d not found", dim.AttributeID)
+			}
+			return nil, fmt.Errorf("failed to save variant dimension: %w", err)
+		}
+		values := make(map[string]struct{}, len(dim.Options))
+		for optPosition, opt := range dim.Options {
+			if _, dup := values[opt.Value]; dup {
+				return nil, fmt.Errorf("option %q is listed more than once", opt.Value)
+			}
+			values[opt.Value] = struct{}{}
+			code := variantOptionCode(opt.Value)
+			if opt.Code != nil && strings.TrimSpace(*opt.Code) != "" {
+				code = strings.ToUpper(strings.TrimSpace(*opt.Code))
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO product_variant_dimension_options (dimension_id, value, code, price_delta, cost_delta, position)
+				VALUES ($1,$2,$3,$4,$5,$6)
+			`, dimensionID, opt.Value, code, round2(opt.PriceDelta), round2(opt.CostDelta), optPosition); err != nil {
+				return nil, fmt.Errorf("failed to save variant option: %w", err)
+			}
+		}
+	}
+
+	result, err := s.syncMatrixTx(tx, companyID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit variant dimensions: %w", err)
+	}
+
+	matrix, err := s.GetVariantMatrix(companyID, productID, nil, "", "", false)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Sync = result
+	return matrix, nil
+}
+
+// SyncMatrix regenerates the variants of a product from its current
+// dimensions, picking up options added to the attribute definitions.
+func (s *ProductVariantService) SyncMatrix(companyID, productID int) (*models.ProductVariantMatrix, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.lockProductTx(tx, companyID, productID); err != nil {
+		return nil, err
+	}
+	result, err := s.syncMatrixTx(tx, companyID, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit variant matrix: %w", err)
+	}
+
+	matrix, err := s.GetVariantMatrix(companyID, productID, nil, "", "", false)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Sync = result
+	return matrix, nil
+}
+
+// SyncMatricesForAttribute resyncs every product whose dimensions follow the
+// attribute, after its option list changed. Failures are logged per product
+// so one bad matrix does not block the attribute update.
+func (s *ProductVariantService) SyncMatricesForAttribute(companyID, attributeID int) error {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT d.product_id
+		FROM product_variant_dimensions d
+		JOIN products p ON p.product_id = d.product_id
+		WHERE d.attribute_id = $1
+		  AND d.follow_attribute_options = TRUE
+		  AND p.company_id = $2
+		  AND p.is_deleted = FALSE
+	`, attributeID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to find products using attribute: %w", err)
+	}
+	productIDs := make([]int, 0)
+	for rows.Next() {
+		var productID int
+		if err := rows.Scan(&productID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan product: %w", err)
+		}
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+
+	for _, productID := range productIDs {
+		if _, err := s.SyncMatrix(companyID, productID); err != nil {
+			log.Printf("variant matrix sync failed for product %d: %v", productID, err)
+		}
+	}
+	return nil
+}
+
+func (s *ProductVariantService) lockProductTx(tx *sql.Tx, companyID, productID int) error {
+	var exists int
+	err := tx.QueryRow(`
+		SELECT product_id
+		FROM products
+		WHERE product_id = $1 AND company_id = $2 AND is_deleted = FALSE
+		FOR UPDATE
+	`, productID, companyID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+	return nil
+}
+
+// loadDimensions returns the dimensions of a product with options resolved
+// against the current attribute definitions.
+func (s *ProductVariantService) loadDimensions(q sqlQueryer, companyID, productID int) ([]models.ProductVariantDimension, error) {
+	rows, err := q.Query(`
+		SELECT d.dimension_id, d.attribute_id, d.position, d.follow_attribute_options,
+		       pa.name, pa.type, pa.options,
+		       o.option_id, o.value, o.code, o.price_delta::float8, o.cost_delta::float8
+		FROM product_variant_dimensions d
+		JOIN product_attributes pa ON pa.attribute_id = d.attribute_id AND pa.company_id = $2
+		LEFT JOIN product_variant_dimension_options o ON o.dimension_id = d.dimension_id
+		WHERE d.product_id = $1
+		ORDER BY d.position, d.dimension_id, o.position, o.option_id
+	`, productID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load variant dimensions: %w", err)
+	}
+	defer rows.Close()
+
+	dims := make([]models.ProductVariantDimension, 0)
+	definitions := make([]models.ProductAttributeDefinition, 0)
+	configured := make([][]models.ProductVariantOption, 0)
+	for rows.Next() {
+		var (
+			dim        models.ProductVariantDimension
+			def        models.ProductAttributeDefinition
+			optionID   sql.NullInt64
+			value      sql.NullString
+			code       sql.NullString
+			priceDelta sql.NullFloat64
+			costDelta  sql.NullFloat64
+		)
+		if err := rows.Scan(
+			&dim.DimensionID, &dim.AttributeID, &dim.Position, &dim.FollowAttributeOptions,
+			&def.Name, &def.Type, &def.Options,
+			&optionID, &value, &code, &priceDelta, &costDelta,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan variant dimension: %w", err)
+		}
+		if len(dims) == 0 || dims[len(dims)-1].DimensionID != dim.DimensionID {
+			dim.ProductID = productID
+			dim.AttributeName = def.Name
+			def.AttributeID = dim.AttributeID
+			dims = append(dims, dim)
+			definitions = append(definitions, def)
+			configured = append(configured, nil)
+		}
+		if optionID.Valid {
+			last := len(configured) - 1
+			configured[last] = append(configured[last], models.ProductVariantOption{
+				OptionID:   int(optionID.Int64),
+				Value:      value.String,
+				Code:       code.String,
+				PriceDelta: priceDelta.Float64,
+				CostDelta:  costDelta.Float64,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read variant dimensions: %w", err)
+	}
+
+	for i := range dims {
+		attributeOptions, err := parseSelectOptions(definitions[i])
+		if err != nil {
+			return nil, err
+		}
+		options, err := resolveDimensionOptions(dims[i].AttributeName, attributeOptions, configured[i], dims[i].FollowAttributeOptions)
+		if err != nil {
+			return nil, err
+		}
+		dims[i].Options = options
+	}
+	return dims, nil
+}
+
+func (s *ProductVariantService) syncMatrixTx(tx *sql.Tx, companyID, productID int) (*models.ProductVariantSyncResult, error) {
+	var (
+		sku          *string
+		sellingPrice float64
+		costPrice    float64
+	)
+	if err := tx.QueryRow(`
+		SELECT sku, COALESCE(selling_price, 0)::float8, COALESCE(cost_price, 0)::float8
+		FROM products
+		WHERE product_id = $1
+	`, productID).Scan(&sku, &sellingPrice, &costPrice); err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	baseSKU := fmt.Sprintf("P%d", productID)
+	if sku != nil && strings.TrimSpace(*sku) != "" {
+		baseSKU = strings.TrimSpace(*sku)
+	}
+
+	dims, err := s.loadDimensions(tx, companyID, productID)
+	if err != nil {
+		return nil, err
+	}
+	matrixDims := make([]variantMatrixDimension, 0, len(dims))
+	for _, dim := range dims {
+		matrixDims = append(matrixDims, variantMatrixDimension{AttributeID: dim.AttributeID, Name: dim.AttributeName, Options: dim.Options})
+	}
+	cells, err := buildVariantMatrix(baseSKU, matrixDims)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.loadMatrixBarcodesTx(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]matrixBarcodeRow, len(existing))
+	byCode := make(map[string]matrixBarcodeRow, len(existing))
+	for _, row := range existing {
+		if row.MatrixKey != nil {
+			byKey[*row.MatrixKey] = row
+		} else {
+			byCode[barcodeLookupKey(row.Barcode)] = row
+		}
+	}
+
+	result := &models.ProductVariantSyncResult{}
+	kept := make(map[int]struct{}, len(cells))
+	for _, cell := range cells {
+		attrs := make(models.JSONB, len(cell.Options))
+		for name, value := range cell.Options {
+			attrs[name] = value
+		}
+		price := round2(sellingPrice + cell.PriceDelta)
+		cost := round2(costPrice + cell.CostDelta)
+
+		row, ok := byKey[cell.Key]
+		if !ok {
+			// A barcode already entered by hand with the generated code is
+			// adopted instead of duplicated.
+			row, ok = byCode[barcodeLookupKey(cell.SKU)]
+		}
+		if ok {
+			kept[row.BarcodeID] = struct{}{}
+			if _, err := tx.Exec(`
+				UPDATE product_barcodes
+				SET sku = $1,
+				    matrix_key = $2,
+				    variant_name = $3,
+				    variant_attributes = COALESCE(variant_attributes, '{}'::jsonb) || $4::jsonb,
+				    selling_price = $5,
+				    cost_price = $6,
+				    is_active = TRUE
+				WHERE barcode_id = $7 AND product_id = $8
+			`, cell.SKU, cell.Key, cell.Name, attrs, price, cost, row.BarcodeID, productID); err != nil {
+				return nil, fmt.Errorf("failed to update product variant: %w", err)
+			}
+			result.Updated++
+			continue
+		}
+
+		var inUse bool
+		if err := tx.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1
+				FROM product_barcodes pb
+				JOIN products p ON p.product_id = pb.product_id
+				WHERE p.company_id = $1 AND p.is_deleted = FALSE AND pb.barcode = $2
+			)
+		`, companyID, cell.SKU).Scan(&inUse); err != nil {
+			return nil, fmt.Errorf("failed to check variant barcode: %w", err)
+		}
+		if inUse {
+			return nil, fmt.Errorf("barcode %s is already in use", cell.SKU)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO product_barcodes (product_id, barcode, pack_size, cost_price, selling_price, is_primary, variant_name, variant_attributes, is_active, sku, matrix_key)
+			VALUES ($1,$2,1,$3,$4,FALSE,$5,$6,TRUE,$7,$8)
+		`, productID, cell.SKU, cost, price, cell.Name, attrs, cell.SKU, cell.Key); err != nil {
+			return nil, fmt.Errorf("failed to insert product variant: %w", err)
+		}
+		result.Created++
+	}
+
+	for _, row := range existing {
+		if row.MatrixKey == nil {
+			continue
+		}
+		if _, ok := kept[row.BarcodeID]; ok {
+			continue
+		}
+		referenced, err := s.productService.barcodeReferencedTx(tx, row.BarcodeID)
+		if err != nil {
+			return nil, err
+		}
+		if referenced {
+			if row.IsActive {
+				if _, err := tx.Exec(`
+					UPDATE product_barcodes
+					SET is_primary = FALSE,
+					    is_active = FALSE
+					WHERE barcode_id = $1 AND product_id = $2
+				`, row.BarcodeID, productID); err != nil {
+					return nil, fmt.Errorf("failed to deactivate product variant: %w", err)
+				}
+				result.Deactivated++
+			}
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM product_barcodes WHERE barcode_id = $1 AND product_id = $2`, row.BarcodeID, productID); err != nil {
+			return nil, fmt.Errorf("failed to delete product variant: %w", err)
+		}
+		result.Removed++
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET updated_at = CURRENT_TIMESTAMP WHERE product_id = $1`, productID); err != nil {
+		return nil, fmt.Errorf("failed to touch product: %w", err)
+	}
+	return result, nil
+}
+
+func (s *ProductVariantService) loadMatrixBarcodesTx(tx *sql.Tx, productID int) ([]matrixBarcodeRow, error) {
+	rows, err := tx.Query(`
+		SELECT barcode_id, barcode, matrix_key, COALESCE(is_active, TRUE)
+		FROM product_barcodes
+		WHERE product_id = $1
+		ORDER BY barcode_id
+		FOR UPDATE
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load product barcodes: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]matrixBarcodeRow, 0)
+	for rows.Next() {
+		var item matrixBarcodeRow
+		if err := rows.Scan(&item.BarcodeID, &item.Barcode, &item.MatrixKey, &item.IsActive); err != nil {
+			return nil, fmt.Errorf("failed to scan product barcode: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestVariantOptionCode(t *testing.T) {
+	cases := map[string]string{
+		"Red":                     "RED",
+		"X-Large":                 "XLARGE",
+		"42 EU":                   "42EU",
+		"--":                      "",
+		"A very long colour name": "AVERYLONGCOLOURNAME",
+	}
+	for value, want := range cases {
+		if got := variantOptionCode(value); got != want {
+			t.Fatalf("variantOptionCode(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestResolveDimensionOptions(t *testing.T) {
+	attribute := []string{"S", "M", "L"}
+	configured := []models.ProductVariantOption{{Value: "L", Code: "lg", PriceDelta: 2}}
+
+	following, err := resolveDimensionOptions("Size", attribute, configured, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	codes := []string{}
+	for _, opt := range following {
+		codes = append(codes, opt.Code)
+	}
+	if !reflect.DeepEqual(codes, []string{"S", "M", "LG"}) || following[2].PriceDelta != 2 || following[2].Position != 2 {
+		t.Fatalf("unexpected following options %+v", following)
+	}
+
+	fixed, err := resolveDimensionOptions("Size", attribute, configured, false)
+	if err != nil || len(fixed) != 1 || fixed[0].Value != "L" {
+		t.Fatalf("unexpected fixed options %+v (%v)", fixed, err)
+	}
+
+	if _, err := resolveDimensionOptions("Size", attribute, []models.ProductVariantOption{{Value: "XL"}}, false); err == nil {
+		t.Fatalf("expected an error for a value missing from the attribute")
+	}
+	if _, err := resolveDimensionOptions("Colour", []string{"Blue", "BLUE"}, nil, true); err == nil {
+		t.Fatalf("expected an error for clashing option codes")
+	}
+}
+
+func TestBuildVariantMatrix(t *testing.T) {
+	dims := []variantMatrixDimension{
+		{AttributeID: 3, Name: "Colour", Options: []models.ProductVariantOption{
+			{Value: "Red", Code: "RED", PriceDelta: 1},
+			{Value: "Blue", Code: "BLU"},
+		}},
+		{AttributeID: 7, Name: "Size", Options: []models.ProductVariantOption{
+			{Value: "S", Code: "S"},
+			{Value: "M", Code: "M", PriceDelta: 0.5, CostDelta: 0.25},
+		}},
+	}
+	cells, err := buildVariantMatrix("TSHIRT", dims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	skus := []string{}
+	for _, cell := range cells {
+		skus = append(skus, cell.SKU)
+	}
+	if !reflect.DeepEqual(skus, []string{"TSHIRT-RED-S", "TSHIRT-RED-M", "TSHIRT-BLU-S", "TSHIRT-BLU-M"}) {
+		t.Fatalf("unexpected skus %v", skus)
+	}
+	redM := cells[1]
+	if redM.Key != "3:Red|7:M" || redM.Name != "Red / M" || redM.PriceDelta != 1.5 || redM.CostDelta != 0.25 {
+		t.Fatalf("unexpected cell %+v", redM)
+	}
+	if redM.Options["Colour"] != "Red" || redM.Options["Size"] != "M" {
+		t.Fatalf("unexpected cell options %v", redM.Options)
+	}
+
+	if _, err := buildVariantMatrix("TSHIRT", []variantMatrixDimension{{AttributeID: 1, Name: "Size"}}); err == nil {
+		t.Fatalf("expected an error for a dimension without options")
+	}
+	if _, err := buildVariantMatrix("TSHIRT", nil); err == nil {
+		t.Fatalf("expected an error without dimensions")
+	}
+}
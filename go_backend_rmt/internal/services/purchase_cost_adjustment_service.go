@@ -202,7 +202,9 @@ func (s *PurchaseCostAdjustmentService) ListSupplierDebitNotes(companyID, locati
 		filters = map[string]string{}
 	}
 	filters["adjustment_type"] = models.PurchaseCostAdjustmentTypeSupplierDebitNote
-	filters["location_id"] = fmt.Sprintf("%d", locationID)
+	if locationID > 0 {
+		filters["location_id"] = fmt.Sprintf("%d", locationID)
+	}
 	return s.listAdjustments(companyID, filters, false)
 }
 
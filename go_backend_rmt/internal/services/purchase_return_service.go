@@ -361,7 +361,7 @@ func (s *PurchaseReturnService) CreatePurchaseReturn(companyID, locationID, user
 		return nil, fmt.Errorf("failed to enqueue purchase return ledger posting: %w", err)
 	}
 
-	approverRoleID, err := NewWorkflowService().findApproverRoleTx(tx, "Admin", "Manager", "Purchase Manager", "Super Admin")
+	approverRoleID, err := NewWorkflowService().findApproverRoleTx(tx, companyID, "Admin", "Manager", "Purchase Manager", "Super Admin")
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve purchase return approver role: %w", err)
 	}
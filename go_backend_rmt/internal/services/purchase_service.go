@@ -347,6 +347,15 @@ func (s *PurchaseService) CreatePurchase(companyID, locationID, userID int, req
 		}
 	}
 
+	budgetWarnings, err := checkBudgetSpend(tx, companyID, locationID, nil, accountCodeInventory, purchaseDate, totalAmount-totalTax)
+	if err != nil {
+		return nil, err
+	}
+	dimensions, err := validateAnalyticTags(tx, companyID, req.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+
 	// Set due date based on payment terms
 	var dueDate *time.Time
 	paymentTerms := 0
@@ -472,7 +481,7 @@ func (s *PurchaseService) CreatePurchase(companyID, locationID, userID int, req
 		EventType:     financeEventLedgerPurchase,
 		AggregateType: "purchase",
 		AggregateID:   purchase.PurchaseID,
-		Payload:       models.JSONB{},
+		Payload:       ledgerPostingPayloadJSON(dimensions),
 		CreatedBy:     &userID,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to enqueue purchase ledger posting: %w", err)
@@ -526,6 +535,7 @@ func (s *PurchaseService) CreatePurchase(companyID, locationID, userID int, req
 	purchase.ReferenceNumber = req.ReferenceNumber
 	purchase.Notes = req.Notes
 	purchase.CreatedBy = userID
+	purchase.BudgetWarnings = budgetWarnings
 
 	return &purchase, nil
 }
@@ -647,6 +657,7 @@ func (s *PurchaseService) ReceivePurchase(purchaseID, companyID, userID int, req
 	for _, item := range req.Items {
 		detailIDs = append(detailIDs, item.PurchaseDetailID)
 	}
+	receivedBarcodeIDs := make([]int, 0, len(req.Items))
 	detailSnapshots, err := fetchPurchaseDetailSnapshots(tx, companyID, detailIDs)
 	if err != nil {
 		return nil, err
@@ -788,6 +799,7 @@ func (s *PurchaseService) ReceivePurchase(purchaseID, companyID, userID int, req
 			if err != nil {
 				return nil, fmt.Errorf("failed to receive inventory: %w", err)
 			}
+			receivedBarcodeIDs = append(receivedBarcodeIDs, variant.BarcodeID)
 			if _, err := tx.Exec(`
 				UPDATE purchase_details
 				SET barcode_id = $1
@@ -834,6 +846,11 @@ func (s *PurchaseService) ReceivePurchase(purchaseID, companyID, userID int, req
 		return nil, fmt.Errorf("failed to update purchase status: %w", err)
 	}
 
+	// Serve waiting sales order backorders before the goods become free stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, locationID, receivedBarcodeIDs); err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -79,6 +79,10 @@ func TestPurchaseService_CreatePurchase_IdempotencyUniqueViolationReturnsExistin
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"company_id"}).AddRow(companyID))
 
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT account_id, name, type FROM chart_of_accounts")).
+		WithArgs(companyID, accountCodeInventory).
+		WillReturnError(sql.ErrNoRows)
+
 	mock.ExpectQuery("(?s)INSERT INTO purchases \\(purchase_number, location_id, supplier_id, purchase_date,.*RETURNING purchase_id, created_at").
 		WillReturnError(&pq.Error{Code: "23505"})
 
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// recurringFormulaVars are the names a recurring amount formula may use.
+var recurringFormulaVars = map[string]bool{
+	"amount":         true,
+	"occurrence":     true,
+	"days_in_month":  true,
+	"days_in_period": true,
+}
+
+// recurringFormula is a parsed amount formula: arithmetic over numbers,
+// recurringFormulaVars and the functions round, min, max and abs.
+type recurringFormula struct {
+	root formulaNode
+}
+
+type formulaNode interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type formulaNumber float64
+
+func (n formulaNumber) eval(map[string]float64) (float64, error) { return float64(n), nil }
+
+type formulaVar string
+
+func (v formulaVar) eval(vars map[string]float64) (float64, error) { return vars[string(v)], nil }
+
+type formulaNeg struct{ x formulaNode }
+
+func (n formulaNeg) eval(vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(vars)
+	return -x, err
+}
+
+type formulaBinary struct {
+	op   byte
+	l, r formulaNode
+}
+
+func (b formulaBinary) eval(vars map[string]float64) (float64, error) {
+	l, err := b.l.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.r.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	default:
+		if r == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	}
+}
+
+type formulaCall struct {
+	name string
+	args []formulaNode
+}
+
+func (c formulaCall) eval(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	switch c.name {
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "round":
+		places := 0.0
+		if len(args) == 2 {
+			places = args[1]
+		}
+		p := math.Pow(10, places)
+		return math.Round(args[0]*p) / p, nil
+	case "min":
+		out := args[0]
+		for _, v := range args[1:] {
+			out = math.Min(out, v)
+		}
+		return out, nil
+	default:
+		out := args[0]
+		for _, v := range args[1:] {
+			out = math.Max(out, v)
+		}
+		return out, nil
+	}
+}
+
+// formulaArity is the allowed argument count range per function.
+var formulaArity = map[string][2]int{
+	"abs":   {1, 1},
+	"round": {1, 2},
+	"min":   {2, 10},
+	"max":   {2, 10},
+}
+
+func parseRecurringFormula(src string) (*recurringFormula, error) {
+	p := &formulaParser{src: src}
+	p.next()
+	root, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount formula: %w", err)
+	}
+	if p.tok != "" {
+		return nil, fmt.Errorf("invalid amount formula: unexpected %q", p.tok)
+	}
+	return &recurringFormula{root: root}, nil
+}
+
+func (f *recurringFormula) eval(vars map[string]float64) (float64, error) {
+	v, err := f.root.eval(vars)
+	if err != nil {
+		return 0, fmt.Errorf("amount formula: %w", err)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("amount formula did not produce a number")
+	}
+	return v, nil
+}
+
+// formulaParser is a recursive descent parser; tok is the current token and
+// "" marks the end of input.
+type formulaParser struct {
+	src string
+	pos int
+	tok string
+}
+
+func (p *formulaParser) next() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || unicode.IsLetter(rune(p.src[p.pos])) || unicode.IsDigit(rune(p.src[p.pos]))) {
+			p.pos++
+		}
+	default:
+		p.pos++
+	}
+	p.tok = p.src[start:p.pos]
+}
+
+func (p *formulaParser) expr() (formulaNode, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok[0]
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = formulaBinary{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) term() (formulaNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "*" || p.tok == "/" {
+		op := p.tok[0]
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = formulaBinary{op: op, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) unary() (formulaNode, error) {
+	if p.tok == "-" {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return formulaNeg{x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *formulaParser) primary() (formulaNode, error) {
+	tok := p.tok
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of formula")
+	case tok == "(":
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return x, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		v, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok)
+		}
+		p.next()
+		return formulaNumber(v), nil
+	case tok[0] == '_' || unicode.IsLetter(rune(tok[0])):
+		name := strings.ToLower(tok)
+		p.next()
+		if p.tok != "(" {
+			if !recurringFormulaVars[name] {
+				return nil, fmt.Errorf("unknown variable %q", tok)
+			}
+			return formulaVar(name), nil
+		}
+		arity, ok := formulaArity[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q", tok)
+		}
+		p.next()
+		var args []formulaNode
+		for p.tok != ")" {
+			if len(args) > 0 {
+				if p.tok != "," {
+					return nil, fmt.Errorf("expected , or ) in %s()", name)
+				}
+				p.next()
+			}
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		p.next()
+		if len(args) < arity[0] || len(args) > arity[1] {
+			return nil, fmt.Errorf("%s() takes %d to %d arguments", name, arity[0], arity[1])
+		}
+		return formulaCall{name: name, args: args}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+const (
+	recurringTemplatePollInterval = time.Minute
+	recurringRunStaleAfter        = 30 * time.Minute
+)
+
+// RecurringTemplateRunner creates the vouchers and expenses of due recurring
+// templates through the normal services. Each occurrence is claimed with SKIP
+// LOCKED and logged once per template and date; documents carry an
+// idempotency key per occurrence, so an occurrence interrupted mid-run is
+// safely retried. Occurrences missed while the server was down are all
+// created, oldest first.
+type RecurringTemplateRunner struct {
+	db *sql.DB
+	wg sync.WaitGroup
+}
+
+func NewRecurringTemplateRunner() *RecurringTemplateRunner {
+	return &RecurringTemplateRunner{db: database.GetDB()}
+}
+
+// Start launches the scheduler loop. It returns at once; cancel ctx and call
+// Shutdown to stop.
+func (r *RecurringTemplateRunner) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+}
+
+// Shutdown waits up to timeout for an in-flight occurrence to finish.
+func (r *RecurringTemplateRunner) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (r *RecurringTemplateRunner) loop(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		for ctx.Err() == nil {
+			worked, err := r.runOne()
+			if err != nil {
+				log.Printf("warning: recurring template scheduler: %v", err)
+				break
+			}
+			if !worked {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-recurringTemplateWake:
+		case <-time.After(recurringTemplatePollInterval):
+		}
+	}
+}
+
+// runOne handles one unit of work: a stale occurrence, a due occurrence or a
+// due reversal. It reports whether there was anything to do.
+func (r *RecurringTemplateRunner) runOne() (bool, error) {
+	today := recurringDate(time.Now())
+	if worked, err := r.retryStaleRun(); worked || err != nil {
+		return worked, err
+	}
+	if worked, err := r.runDueOccurrence(today); worked || err != nil {
+		return worked, err
+	}
+	return r.runDueReversal(today)
+}
+
+// runDueOccurrence claims the oldest due occurrence of any template, advances
+// the template past it and opens a RUNNING log row for it.
+func (r *RecurringTemplateRunner) runDueOccurrence(today time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := scanRecurringTemplate(tx.QueryRow(recurringTemplateSelect+`
+		WHERE is_active = TRUE AND next_run_date <= $1
+		ORDER BY next_run_date
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`, today))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load due recurring template: %w", err)
+	}
+	occurrence, n := recurringDate(*t.NextRunDate), t.NextOccurrence
+
+	var next *time.Time
+	if d := recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, n+1); t.EndDate == nil || !d.After(recurringDate(*t.EndDate)) {
+		next = &d
+	}
+	if _, err := tx.Exec(`
+		UPDATE recurring_templates SET next_run_date = $2, next_occurrence = $3 WHERE template_id = $1
+	`, t.TemplateID, next, n+1); err != nil {
+		return false, fmt.Errorf("failed to advance recurring template: %w", err)
+	}
+	var runID int
+	err = tx.QueryRow(`
+		INSERT INTO recurring_template_runs (template_id, company_id, occurrence_date, occurrence_no)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (template_id, occurrence_date) DO NOTHING
+		RETURNING run_id
+	`, t.TemplateID, t.CompanyID, occurrence, n).Scan(&runID)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to record recurring run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	// A rescheduled template can land on a date it already ran for.
+	if runID != 0 {
+		r.execute(t, runID, occurrence, n)
+	}
+	return true, nil
+}
+
+// retryStaleRun re-executes an occurrence left RUNNING by a process that
+// died mid-run. The idempotency key returns the document if it was created.
+func (r *RecurringTemplateRunner) retryStaleRun() (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		runID, templateID, companyID, n int
+		occurrence                      time.Time
+	)
+	err = tx.QueryRow(`
+		SELECT run_id, template_id, company_id, occurrence_date, occurrence_no
+		FROM recurring_template_runs
+		WHERE status = 'RUNNING' AND started_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		ORDER BY started_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`, int64(recurringRunStaleAfter/time.Second)).Scan(&runID, &templateID, &companyID, &occurrence, &n)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load stale recurring run: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE recurring_template_runs SET started_at = CURRENT_TIMESTAMP WHERE run_id = $1
+	`, runID); err != nil {
+		return false, fmt.Errorf("failed to reclaim recurring run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	t, err := (&RecurringTemplateService{db: r.db}).GetTemplate(companyID, templateID)
+	if err != nil {
+		return false, err
+	}
+	r.execute(t, runID, recurringDate(occurrence), n)
+	return true, nil
+}
+
+// runDueReversal creates the reversal of one auto-reversing voucher whose
+// reversal date has come.
+func (r *RecurringTemplateRunner) runDueReversal(today time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		runID, templateID, companyID, voucherID, userID int
+		templateName                                    string
+		reversalDate                                    time.Time
+	)
+	err = tx.QueryRow(`
+		SELECT r.run_id, r.template_id, t.name, r.company_id, r.document_id, COALESCE(t.updated_by, t.created_by), r.reversal_date
+		FROM recurring_template_runs r
+		JOIN recurring_templates t ON t.template_id = r.template_id
+		WHERE r.reversal_status = 'PENDING' AND r.reversal_date <= $1
+		ORDER BY r.reversal_date
+		FOR UPDATE OF r SKIP LOCKED
+		LIMIT 1
+	`, today).Scan(&runID, &templateID, &templateName, &companyID, &voucherID, &userID, &reversalDate)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load due reversal: %w", err)
+	}
+
+	status := models.RecurringRunCreated
+	var reversalID *int
+	var message *string
+	id, revErr := createRecurringReversal(r.db, companyID, userID, voucherID, recurringDate(reversalDate))
+	if revErr != nil {
+		status = models.RecurringRunFailed
+		text := revErr.Error()
+		message = &text
+		log.Printf("recurring run %d reversal failed: %s", runID, text)
+	} else {
+		reversalID = &id
+	}
+	if _, err := tx.Exec(`
+		UPDATE recurring_template_runs
+		SET reversal_status = $2, reversal_voucher_id = $3, reversal_message = $4
+		WHERE run_id = $1
+	`, runID, status, reversalID, message); err != nil {
+		return false, fmt.Errorf("failed to record reversal: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if revErr != nil {
+		notifyRecurringFailure(r.db, companyID, templateID,
+			fmt.Sprintf("Reversal for recurring template %s failed", templateName), revErr.Error(),
+			fmt.Sprintf("recurring_reversal_failed:%d", runID))
+	}
+	return true, nil
+}
+
+type recurringOutcome struct {
+	status       string
+	amount       *float64
+	documentID   *int
+	message      string
+	reversalDate *time.Time
+}
+
+func (r *RecurringTemplateRunner) execute(t *models.RecurringTemplate, runID int, occurrence time.Time, n int) {
+	out := r.executeSafely(t, occurrence, n)
+	if out.status == models.RecurringRunFailed {
+		log.Printf("recurring template %d occurrence %s failed: %s", t.TemplateID, occurrence.Format("2006-01-02"), out.message)
+	}
+	var message, reversalStatus *string
+	if out.message != "" {
+		message = &out.message
+	}
+	if out.reversalDate != nil {
+		pending := models.RecurringRunPending
+		reversalStatus = &pending
+	}
+	if _, err := r.db.Exec(`
+		UPDATE recurring_template_runs
+		SET status = $2, amount = $3, document_id = $4, message = $5, reversal_date = $6,
+		    reversal_status = $7, finished_at = CURRENT_TIMESTAMP
+		WHERE run_id = $1
+	`, runID, out.status, out.amount, out.documentID, message, out.reversalDate, reversalStatus); err != nil {
+		log.Printf("warning: failed to record recurring run %d: %v", runID, err)
+	}
+	if _, err := r.db.Exec(`
+		UPDATE recurring_templates SET last_run_date = $2, last_status = $3 WHERE template_id = $1
+	`, t.TemplateID, occurrence, out.status); err != nil {
+		log.Printf("warning: failed to update recurring template %d: %v", t.TemplateID, err)
+	}
+	if out.status == models.RecurringRunFailed {
+		notifyRecurringFailure(r.db, t.CompanyID, t.TemplateID,
+			fmt.Sprintf("Recurring template %s failed for %s", t.Name, occurrence.Format("2006-01-02")), out.message,
+			fmt.Sprintf("recurring_run_failed:%d", runID))
+	}
+}
+
+func notifyRecurringFailure(db *sql.DB, companyID, templateID int, title, message, dedupeKey string) {
+	notify(db, NotificationEvent{
+		CompanyID:   companyID,
+		Type:        models.NotificationRecurringFailed,
+		Title:       title,
+		Body:        message,
+		Severity:    "WARNING",
+		EntityType:  "RECURRING_TEMPLATE",
+		EntityID:    templateID,
+		ActionLabel: "Open template",
+		DedupeKey:   dedupeKey,
+	})
+}
+
+func (r *RecurringTemplateRunner) executeSafely(t *models.RecurringTemplate, occurrence time.Time, n int) (out recurringOutcome) {
+	defer func() {
+		if p := recover(); p != nil {
+			out = recurringOutcome{status: models.RecurringRunFailed, message: fmt.Sprintf("recurring run panicked: %v", p)}
+		}
+	}()
+	return runRecurringOccurrence(r.db, t, occurrence, n)
+}
+
+// runRecurringOccurrence creates occurrence n of the template. Closed periods
+// and non-positive amounts skip the occurrence; other errors fail it.
+func runRecurringOccurrence(db *sql.DB, t *models.RecurringTemplate, occurrence time.Time, n int) recurringOutcome {
+	failed := func(err error) recurringOutcome {
+		return recurringOutcome{status: models.RecurringRunFailed, message: err.Error()}
+	}
+	amount, err := recurringOccurrenceAmount(t, n, occurrence)
+	if err != nil {
+		return failed(err)
+	}
+	if amount <= 0 {
+		return recurringOutcome{status: models.RecurringRunSkipped, amount: &amount,
+			message: fmt.Sprintf("amount %.2f is not positive", amount)}
+	}
+	if err := (&AccountingAdminService{db: db}).EnsurePeriodOpen(t.CompanyID, occurrence); err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			return failed(err)
+		}
+		return recurringOutcome{status: models.RecurringRunSkipped, amount: &amount, message: err.Error()}
+	}
+
+	userID := t.CreatedBy
+	if t.UpdatedBy != nil {
+		userID = *t.UpdatedBy
+	}
+	out := recurringOutcome{status: models.RecurringRunCreated, amount: &amount}
+	switch t.DocumentType {
+	case models.RecurringDocumentVoucher:
+		req := recurringVoucherRequest(t, occurrence, amount)
+		id, err := (&VoucherService{db: db}).CreateVoucher(t.CompanyID, userID, *t.VoucherType, req)
+		if err != nil {
+			return failed(err)
+		}
+		out.documentID = &id
+		if t.AutoReverse {
+			rev, err := recurringReversalDate(db, t.CompanyID, occurrence)
+			if err != nil {
+				out.message = err.Error()
+				return out
+			}
+			out.reversalDate = &rev
+		}
+	case models.RecurringDocumentExpense:
+		if t.LocationID == nil {
+			return failed(fmt.Errorf("expense template has no location"))
+		}
+		req := recurringExpenseRequest(t, occurrence, amount)
+		id, warnings, err := (&ExpenseService{db: db}).CreateExpenseWithBudgetWarnings(
+			t.CompanyID, *t.LocationID, userID, req, recurringIdempotencyKey(t.TemplateID, occurrence))
+		if err != nil {
+			return failed(err)
+		}
+		out.documentID = &id
+		out.message = strings.Join(warnings, "; ")
+	default:
+		return failed(fmt.Errorf("invalid document_type %q", t.DocumentType))
+	}
+	return out
+}
+
+// createRecurringReversal posts a journal voucher dated date that swaps the
+// debits and credits of voucherID, carrying each line's analytic tags.
+func createRecurringReversal(db *sql.DB, companyID, userID, voucherID int, date time.Time) (int, error) {
+	vs := &VoucherService{db: db}
+	original, err := vs.GetVoucher(companyID, voucherID)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT le.reference, led.dimension_id, led.value_id
+		FROM ledger_entries le
+		JOIN ledger_entry_dimensions led ON led.entry_id = le.entry_id
+		WHERE le.company_id = $1 AND le.transaction_type = 'voucher' AND le.transaction_id = $2
+	`, companyID, voucherID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load voucher dimensions: %w", err)
+	}
+	defer rows.Close()
+	tags := map[string][]models.AnalyticTag{}
+	for rows.Next() {
+		var ref string
+		var tag models.AnalyticTag
+		if err := rows.Scan(&ref, &tag.DimensionID, &tag.ValueID); err != nil {
+			return 0, fmt.Errorf("failed to scan voucher dimension: %w", err)
+		}
+		tags[ref] = append(tags[ref], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read voucher dimensions: %w", err)
+	}
+
+	lines := make([]models.CreateVoucherLineRequest, 0, len(original.Lines))
+	for _, l := range original.Lines {
+		lines = append(lines, models.CreateVoucherLineRequest{
+			AccountID:   l.AccountID,
+			Debit:       l.Credit,
+			Credit:      l.Debit,
+			Description: l.Description,
+			Dimensions:  tags[fmt.Sprintf("voucher:%d:line:%d", voucherID, l.LineNo)],
+		})
+	}
+	dateStr := date.Format("2006-01-02")
+	key := fmt.Sprintf("recurring-reversal:%d", voucherID)
+	description := "Reversal of " + original.Reference
+	reference := "REV " + original.Reference
+	if len(reference) > 100 {
+		reference = reference[:100]
+	}
+	return vs.CreateVoucher(companyID, userID, "journal", &models.CreateVoucherRequest{
+		Reference:      reference,
+		Date:           &dateStr,
+		Description:    &description,
+		Lines:          lines,
+		IdempotencyKey: &key,
+	})
+}
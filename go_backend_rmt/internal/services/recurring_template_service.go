@@ -0,0 +1,749 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type RecurringTemplateService struct {
+	db *sql.DB
+}
+
+func NewRecurringTemplateService() *RecurringTemplateService {
+	return &RecurringTemplateService{db: database.GetDB()}
+}
+
+// recurringPayload is the saved create request stored in
+// recurring_templates.payload.
+type recurringPayload struct {
+	Voucher *models.CreateVoucherRequest `json:"voucher,omitempty"`
+	Expense *models.CreateExpenseRequest `json:"expense,omitempty"`
+}
+
+// recurringDate drops the time of day, keeping the calendar date.
+func recurringDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// addMonthsClamped moves start by n months, keeping its day of month where
+// the target month has it and using the month's last day otherwise, so a
+// schedule anchored on the 31st does not drift after February.
+func addMonthsClamped(start time.Time, n int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	day := start.Day()
+	if last := daysInMonth(first.Year(), first.Month()); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// recurringOccurrenceDate returns occurrence n (from 0) of a schedule.
+// Occurrences are computed from the start date rather than from each other.
+func recurringOccurrenceDate(start time.Time, frequency string, interval, n int) time.Time {
+	start = recurringDate(start)
+	step := n * interval
+	switch frequency {
+	case models.RecurringDaily:
+		return start.AddDate(0, 0, step)
+	case models.RecurringWeekly:
+		return start.AddDate(0, 0, 7*step)
+	case models.RecurringQuarterly:
+		return addMonthsClamped(start, 3*step)
+	case models.RecurringYearly:
+		return addMonthsClamped(start, 12*step)
+	default:
+		return addMonthsClamped(start, step)
+	}
+}
+
+// scheduleRecurringTemplate points the template at its first occurrence on
+// or after from. A schedule past its end date gets no next run.
+func scheduleRecurringTemplate(t *models.RecurringTemplate, from time.Time) {
+	from = recurringDate(from)
+	n := 0
+	next := recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, 0)
+	if next.Before(from) {
+		// Jump close to from, then step; the estimate never overshoots.
+		days := int(from.Sub(next).Hours() / 24)
+		switch t.Frequency {
+		case models.RecurringDaily:
+			n = days / t.IntervalCount
+		case models.RecurringWeekly:
+			n = days / (7 * t.IntervalCount)
+		case models.RecurringQuarterly:
+			n = days / (92 * t.IntervalCount)
+		case models.RecurringYearly:
+			n = days / (366 * t.IntervalCount)
+		default:
+			n = days / (31 * t.IntervalCount)
+		}
+		next = recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, n)
+		for next.Before(from) {
+			n++
+			next = recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, n)
+		}
+	}
+	t.NextOccurrence = n
+	if t.EndDate != nil && next.After(recurringDate(*t.EndDate)) {
+		t.NextRunDate = nil
+		return
+	}
+	t.NextRunDate = &next
+}
+
+// recurringBaseAmount is the amount saved on the template: the voucher or
+// expense amount, or a journal's debit total.
+func recurringBaseAmount(t *models.RecurringTemplate) float64 {
+	if t.Expense != nil {
+		return t.Expense.Amount
+	}
+	if t.Voucher == nil {
+		return 0
+	}
+	if len(t.Voucher.Lines) > 0 {
+		total := 0.0
+		for _, l := range t.Voucher.Lines {
+			total += l.Debit
+		}
+		return total
+	}
+	return t.Voucher.Amount
+}
+
+// recurringOccurrenceAmount computes the amount of occurrence n, which falls
+// on date, rounded to cents.
+func recurringOccurrenceAmount(t *models.RecurringTemplate, n int, date time.Time) (float64, error) {
+	base := recurringBaseAmount(t)
+	if t.AmountMode != models.RecurringAmountFormula || t.AmountFormula == nil {
+		return base, nil
+	}
+	f, err := parseRecurringFormula(*t.AmountFormula)
+	if err != nil {
+		return 0, err
+	}
+	next := recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, n+1)
+	v, err := f.eval(map[string]float64{
+		"amount":         base,
+		"occurrence":     float64(n + 1),
+		"days_in_month":  float64(daysInMonth(date.Year(), date.Month())),
+		"days_in_period": next.Sub(date).Hours() / 24,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(v*100) / 100, nil
+}
+
+// scaleJournalLines scales a journal's lines so both sides total amount. The
+// rounding remainder of each side goes to its last line.
+func scaleJournalLines(lines []models.CreateVoucherLineRequest, amount float64) []models.CreateVoucherLineRequest {
+	debits, credits := 0.0, 0.0
+	lastDebit, lastCredit := -1, -1
+	for i, l := range lines {
+		if l.Debit > 0 {
+			debits += l.Debit
+			lastDebit = i
+		}
+		if l.Credit > 0 {
+			credits += l.Credit
+			lastCredit = i
+		}
+	}
+	out := make([]models.CreateVoucherLineRequest, len(lines))
+	copy(out, lines)
+	if debits <= 0 || credits <= 0 {
+		return out
+	}
+	scaledDebits, scaledCredits := 0.0, 0.0
+	for i := range out {
+		if out[i].Debit > 0 {
+			out[i].Debit = math.Round(out[i].Debit*amount/debits*100) / 100
+			scaledDebits += out[i].Debit
+		}
+		if out[i].Credit > 0 {
+			out[i].Credit = math.Round(out[i].Credit*amount/credits*100) / 100
+			scaledCredits += out[i].Credit
+		}
+	}
+	out[lastDebit].Debit = math.Round((out[lastDebit].Debit+amount-scaledDebits)*100) / 100
+	out[lastCredit].Credit = math.Round((out[lastCredit].Credit+amount-scaledCredits)*100) / 100
+	return out
+}
+
+func recurringIdempotencyKey(templateID int, date time.Time) string {
+	return fmt.Sprintf("recurring:%d:%s", templateID, date.Format("2006-01-02"))
+}
+
+// recurringReference suffixes the saved reference with the occurrence date,
+// trimming it to fit the 100 character reference column.
+func recurringReference(reference string, date time.Time) string {
+	suffix := " " + date.Format("2006-01-02")
+	reference = strings.TrimSpace(reference)
+	if len(reference)+len(suffix) > 100 {
+		reference = reference[:100-len(suffix)]
+	}
+	return reference + suffix
+}
+
+// recurringVoucherRequest builds the create request for one occurrence.
+func recurringVoucherRequest(t *models.RecurringTemplate, date time.Time, amount float64) *models.CreateVoucherRequest {
+	req := *t.Voucher
+	dateStr := date.Format("2006-01-02")
+	key := recurringIdempotencyKey(t.TemplateID, date)
+	req.Date = &dateStr
+	req.IdempotencyKey = &key
+	req.Reference = recurringReference(t.Voucher.Reference, date)
+	if len(t.Voucher.Lines) > 0 {
+		if math.Abs(amount-recurringBaseAmount(t)) > 0.001 {
+			req.Lines = scaleJournalLines(t.Voucher.Lines, amount)
+		} else {
+			req.Lines = append([]models.CreateVoucherLineRequest(nil), t.Voucher.Lines...)
+		}
+	} else {
+		req.Amount = amount
+	}
+	return &req
+}
+
+// recurringExpenseRequest builds the create request for one occurrence.
+func recurringExpenseRequest(t *models.RecurringTemplate, date time.Time, amount float64) *models.CreateExpenseRequest {
+	req := *t.Expense
+	req.Amount = amount
+	req.ExpenseDateRaw = nil
+	req.ExpenseDate = date
+	return &req
+}
+
+// recurringReversalDate is the first day of the accounting period after
+// date, or the first of the next month when no later period is defined.
+func recurringReversalDate(q sqlQueryRower, companyID int, date time.Time) (time.Time, error) {
+	var start sql.NullTime
+	if err := q.QueryRow(`
+		SELECT MIN(start_date) FROM accounting_periods WHERE company_id = $1 AND start_date > $2
+	`, companyID, date.Format("2006-01-02")).Scan(&start); err != nil {
+		return time.Time{}, fmt.Errorf("failed to find next accounting period: %w", err)
+	}
+	if start.Valid {
+		return recurringDate(start.Time), nil
+	}
+	return time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// validateRecurringTemplate checks the template's shape and normalizes its
+// defaults. References to accounts, locations and categories are checked
+// against the database separately.
+func validateRecurringTemplate(t *models.RecurringTemplate) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.IntervalCount <= 0 {
+		t.IntervalCount = 1
+	}
+	if t.AmountMode == "" {
+		t.AmountMode = models.RecurringAmountFixed
+	}
+	if t.EndDate != nil && t.EndDate.Before(t.StartDate) {
+		return fmt.Errorf("end_date must be on or after start_date")
+	}
+
+	switch t.DocumentType {
+	case models.RecurringDocumentVoucher:
+		if t.Voucher == nil || t.Expense != nil {
+			return fmt.Errorf("voucher templates require a voucher and no expense")
+		}
+		if t.VoucherType == nil {
+			return fmt.Errorf("voucher_type is required for voucher templates")
+		}
+		if strings.TrimSpace(t.Voucher.Reference) == "" {
+			return fmt.Errorf("voucher reference is required")
+		}
+		if *t.VoucherType == "journal" {
+			if len(t.Voucher.Lines) < 2 {
+				return fmt.Errorf("journal vouchers require at least two lines")
+			}
+			debits, credits := 0.0, 0.0
+			for _, l := range t.Voucher.Lines {
+				if l.AccountID <= 0 {
+					return fmt.Errorf("each journal line requires account_id")
+				}
+				if (l.Debit > 0) == (l.Credit > 0) || l.Debit < 0 || l.Credit < 0 {
+					return fmt.Errorf("each journal line must have either debit or credit")
+				}
+				debits += l.Debit
+				credits += l.Credit
+			}
+			if math.Abs(debits-credits) > 0.01 {
+				return fmt.Errorf("journal voucher lines must balance")
+			}
+		} else {
+			if t.Voucher.AccountID <= 0 {
+				return fmt.Errorf("account_id is required")
+			}
+			if t.Voucher.Amount <= 0 {
+				return fmt.Errorf("amount must be greater than zero")
+			}
+			t.Voucher.Lines = nil
+		}
+		t.Voucher.Date = nil
+		t.Voucher.IdempotencyKey = nil
+		t.LocationID = nil
+	case models.RecurringDocumentExpense:
+		if t.Expense == nil || t.Voucher != nil {
+			return fmt.Errorf("expense templates require an expense and no voucher")
+		}
+		if t.LocationID == nil {
+			return fmt.Errorf("location_id is required for expense templates")
+		}
+		if t.Expense.CategoryID <= 0 || t.Expense.Amount <= 0 {
+			return fmt.Errorf("expense category_id and a positive amount are required")
+		}
+		t.Expense.ExpenseDateRaw = nil
+		t.VoucherType = nil
+	default:
+		return fmt.Errorf("invalid document_type")
+	}
+
+	if t.AutoReverse && (t.VoucherType == nil || *t.VoucherType != "journal") {
+		return fmt.Errorf("only journal voucher templates can auto-reverse")
+	}
+	if t.AmountMode == models.RecurringAmountFormula {
+		if t.AmountFormula == nil || strings.TrimSpace(*t.AmountFormula) == "" {
+			return fmt.Errorf("amount_formula is required when amount_mode is FORMULA")
+		}
+		formula := strings.TrimSpace(*t.AmountFormula)
+		if _, err := parseRecurringFormula(formula); err != nil {
+			return err
+		}
+		t.AmountFormula = &formula
+	} else {
+		t.AmountFormula = nil
+	}
+	return nil
+}
+
+// verifyRecurringTemplateRefs checks that the accounts, location, expense
+// category and analytic tags a template uses belong to the company.
+func (s *RecurringTemplateService) verifyRecurringTemplateRefs(companyID int, t *models.RecurringTemplate) error {
+	if t.Voucher != nil {
+		ids := []int64{}
+		if t.Voucher.AccountID > 0 {
+			ids = append(ids, int64(t.Voucher.AccountID))
+		}
+		if t.Voucher.SettlementAccountID != nil {
+			ids = append(ids, int64(*t.Voucher.SettlementAccountID))
+		}
+		for _, l := range t.Voucher.Lines {
+			ids = append(ids, int64(l.AccountID))
+		}
+		var missing int
+		if err := s.db.QueryRow(`
+			SELECT COUNT(*) FROM unnest($2::int[]) AS a(id)
+			WHERE NOT EXISTS (SELECT 1 FROM chart_of_accounts WHERE company_id = $1 AND account_id = a.id)
+		`, companyID, pq.Array(ids)).Scan(&missing); err != nil {
+			return fmt.Errorf("failed to verify accounts: %w", err)
+		}
+		if missing > 0 {
+			return fmt.Errorf("account not found")
+		}
+		if t.Voucher.BankAccountID != nil {
+			var ok bool
+			if err := s.db.QueryRow(`
+				SELECT EXISTS (SELECT 1 FROM bank_accounts WHERE bank_account_id = $1 AND company_id = $2 AND is_active = TRUE)
+			`, *t.Voucher.BankAccountID, companyID).Scan(&ok); err != nil {
+				return fmt.Errorf("failed to verify bank account: %w", err)
+			}
+			if !ok {
+				return fmt.Errorf("bank account not found")
+			}
+		}
+		if _, err := validateAnalyticTags(s.db, companyID, t.Voucher.Dimensions); err != nil {
+			return err
+		}
+		for _, l := range t.Voucher.Lines {
+			if _, err := validateAnalyticTags(s.db, companyID, l.Dimensions); err != nil {
+				return err
+			}
+		}
+	}
+	if t.Expense != nil {
+		checks := []struct {
+			name  string
+			id    *int
+			query string
+		}{
+			{"location", t.LocationID, `SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2`},
+			{"expense category", &t.Expense.CategoryID, `SELECT 1 FROM expense_categories WHERE category_id = $1 AND company_id = $2`},
+			{"department", t.Expense.DepartmentID, `SELECT 1 FROM departments WHERE department_id = $1 AND company_id = $2 AND is_deleted = FALSE`},
+		}
+		for _, c := range checks {
+			if c.id == nil {
+				continue
+			}
+			var one int
+			err := s.db.QueryRow(c.query, *c.id, companyID).Scan(&one)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%s not found", c.name)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to verify %s: %w", c.name, err)
+			}
+		}
+		if _, err := validateAnalyticTags(s.db, companyID, t.Expense.Dimensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseRecurringDates(start string, end *string) (time.Time, *time.Time, error) {
+	startDate, err := time.Parse("2006-01-02", strings.TrimSpace(start))
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("start_date must be YYYY-MM-DD")
+	}
+	if end == nil || strings.TrimSpace(*end) == "" {
+		return startDate, nil, nil
+	}
+	endDate, err := time.Parse("2006-01-02", strings.TrimSpace(*end))
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("end_date must be YYYY-MM-DD")
+	}
+	return startDate, &endDate, nil
+}
+
+// CreateTemplate saves a template. Its first run is the first occurrence on
+// or after today; earlier occurrences are not back-filled.
+func (s *RecurringTemplateService) CreateTemplate(companyID, userID int, req *models.CreateRecurringTemplateRequest) (*models.RecurringTemplate, error) {
+	start, end, err := parseRecurringDates(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	t := &models.RecurringTemplate{
+		CompanyID:     companyID,
+		Name:          strings.TrimSpace(req.Name),
+		DocumentType:  req.DocumentType,
+		VoucherType:   req.VoucherType,
+		LocationID:    req.LocationID,
+		Frequency:     req.Frequency,
+		IntervalCount: req.IntervalCount,
+		StartDate:     start,
+		EndDate:       end,
+		AmountMode:    req.AmountMode,
+		AmountFormula: req.AmountFormula,
+		AutoReverse:   req.AutoReverse,
+		Voucher:       req.Voucher,
+		Expense:       req.Expense,
+		IsActive:      true,
+	}
+	if req.IsActive != nil {
+		t.IsActive = *req.IsActive
+	}
+	if err := validateRecurringTemplate(t); err != nil {
+		return nil, err
+	}
+	if err := s.verifyRecurringTemplateRefs(companyID, t); err != nil {
+		return nil, err
+	}
+	scheduleRecurringTemplate(t, time.Now())
+
+	payload, err := json.Marshal(recurringPayload{Voucher: t.Voucher, Expense: t.Expense})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recurring template: %w", err)
+	}
+	var id int
+	err = s.db.QueryRow(`
+		INSERT INTO recurring_templates
+		  (company_id, name, document_type, voucher_type, location_id, frequency, interval_count,
+		   start_date, end_date, next_run_date, next_occurrence, amount_mode, amount_formula, auto_reverse,
+		   payload, is_active, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $17)
+		RETURNING template_id
+	`, companyID, t.Name, t.DocumentType, t.VoucherType, t.LocationID, t.Frequency, t.IntervalCount,
+		t.StartDate, t.EndDate, t.NextRunDate, t.NextOccurrence, t.AmountMode, t.AmountFormula, t.AutoReverse,
+		payload, t.IsActive, userID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recurring template: %w", err)
+	}
+	wakeRecurringTemplateRunner()
+	return s.GetTemplate(companyID, id)
+}
+
+// UpdateTemplate edits a template. A changed schedule, or resuming a paused
+// template, restarts it from the first occurrence on or after today.
+func (s *RecurringTemplateService) UpdateTemplate(companyID, userID, templateID int, req *models.UpdateRecurringTemplateRequest) (*models.RecurringTemplate, error) {
+	t, err := s.GetTemplate(companyID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	reschedule := false
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Frequency != nil && *req.Frequency != t.Frequency {
+		t.Frequency = *req.Frequency
+		reschedule = true
+	}
+	if req.IntervalCount != nil && *req.IntervalCount != t.IntervalCount {
+		t.IntervalCount = *req.IntervalCount
+		reschedule = true
+	}
+	if req.StartDate != nil || req.EndDate != nil {
+		start := t.StartDate.Format("2006-01-02")
+		if req.StartDate != nil {
+			start = *req.StartDate
+		}
+		end := req.EndDate
+		if end == nil && t.EndDate != nil {
+			current := t.EndDate.Format("2006-01-02")
+			end = &current
+		}
+		if t.StartDate, t.EndDate, err = parseRecurringDates(start, end); err != nil {
+			return nil, err
+		}
+		reschedule = true
+	}
+	if req.AmountMode != nil {
+		t.AmountMode = *req.AmountMode
+	}
+	if req.AmountFormula != nil {
+		t.AmountFormula = req.AmountFormula
+	}
+	if req.AutoReverse != nil {
+		t.AutoReverse = *req.AutoReverse
+	}
+	if req.Voucher != nil {
+		t.Voucher = req.Voucher
+	}
+	if req.Expense != nil {
+		t.Expense = req.Expense
+	}
+	if req.IsActive != nil {
+		if *req.IsActive && !t.IsActive {
+			reschedule = true
+		}
+		t.IsActive = *req.IsActive
+	}
+	if err := validateRecurringTemplate(t); err != nil {
+		return nil, err
+	}
+	if err := s.verifyRecurringTemplateRefs(companyID, t); err != nil {
+		return nil, err
+	}
+	if reschedule {
+		scheduleRecurringTemplate(t, time.Now())
+	}
+
+	payload, err := json.Marshal(recurringPayload{Voucher: t.Voucher, Expense: t.Expense})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recurring template: %w", err)
+	}
+	_, err = s.db.Exec(`
+		UPDATE recurring_templates
+		SET name = $3, frequency = $4, interval_count = $5, start_date = $6, end_date = $7,
+		    next_run_date = $8, next_occurrence = $9, amount_mode = $10, amount_formula = $11,
+		    auto_reverse = $12, payload = $13, is_active = $14, updated_by = $15, updated_at = CURRENT_TIMESTAMP
+		WHERE template_id = $1 AND company_id = $2
+	`, templateID, companyID, t.Name, t.Frequency, t.IntervalCount, t.StartDate, t.EndDate,
+		t.NextRunDate, t.NextOccurrence, t.AmountMode, t.AmountFormula, t.AutoReverse, payload, t.IsActive, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update recurring template: %w", err)
+	}
+	wakeRecurringTemplateRunner()
+	return s.GetTemplate(companyID, templateID)
+}
+
+// DeleteTemplate removes a template and its run log. Documents it created
+// are kept.
+func (s *RecurringTemplateService) DeleteTemplate(companyID, templateID int) error {
+	res, err := s.db.Exec(`
+		DELETE FROM recurring_templates WHERE template_id = $1 AND company_id = $2
+	`, templateID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recurring template: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("recurring template not found")
+	}
+	return nil
+}
+
+const recurringTemplateSelect = `
+	SELECT template_id, company_id, name, document_type, voucher_type, location_id, frequency,
+	       interval_count, start_date, end_date, next_run_date, next_occurrence, amount_mode,
+	       amount_formula, auto_reverse, payload, is_active, last_run_date, last_status,
+	       created_by, updated_by, created_at, updated_at
+	FROM recurring_templates
+`
+
+func scanRecurringTemplate(row interface{ Scan(...interface{}) error }) (*models.RecurringTemplate, error) {
+	var (
+		t       models.RecurringTemplate
+		payload []byte
+	)
+	err := row.Scan(&t.TemplateID, &t.CompanyID, &t.Name, &t.DocumentType, &t.VoucherType, &t.LocationID,
+		&t.Frequency, &t.IntervalCount, &t.StartDate, &t.EndDate, &t.NextRunDate, &t.NextOccurrence,
+		&t.AmountMode, &t.AmountFormula, &t.AutoReverse, &payload, &t.IsActive, &t.LastRunDate,
+		&t.LastStatus, &t.CreatedBy, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	var p recurringPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode recurring template %d: %w", t.TemplateID, err)
+	}
+	t.Voucher, t.Expense = p.Voucher, p.Expense
+	return &t, nil
+}
+
+func (s *RecurringTemplateService) GetTemplate(companyID, templateID int) (*models.RecurringTemplate, error) {
+	t, err := scanRecurringTemplate(s.db.QueryRow(recurringTemplateSelect+`
+		WHERE template_id = $1 AND company_id = $2
+	`, templateID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("recurring template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring template: %w", err)
+	}
+	return t, nil
+}
+
+// ListTemplates supports document_type and is_active filters.
+func (s *RecurringTemplateService) ListTemplates(companyID int, filters map[string]string) ([]models.RecurringTemplate, error) {
+	query := recurringTemplateSelect + ` WHERE company_id = $1`
+	args := []interface{}{companyID}
+	if v := strings.ToUpper(strings.TrimSpace(filters["document_type"])); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND document_type = $%d", len(args))
+	}
+	if v := strings.TrimSpace(filters["is_active"]); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("is_active must be true or false")
+		}
+		args = append(args, active)
+		query += fmt.Sprintf(" AND is_active = $%d", len(args))
+	}
+	query += " ORDER BY name, template_id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurring templates: %w", err)
+	}
+	defer rows.Close()
+	templates := []models.RecurringTemplate{}
+	for rows.Next() {
+		t, err := scanRecurringTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recurring template: %w", err)
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+// ListRuns returns a template's run log, newest occurrence first.
+func (s *RecurringTemplateService) ListRuns(companyID, templateID int, filter models.RecurringTemplateRunFilter) ([]models.RecurringTemplateRun, error) {
+	if _, err := s.GetTemplate(companyID, templateID); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT run_id, template_id, occurrence_date, occurrence_no, status, amount::float8, document_id,
+		       message, reversal_date, reversal_status, reversal_voucher_id, reversal_message,
+		       started_at, finished_at
+		FROM recurring_template_runs
+		WHERE template_id = $1 AND company_id = $2
+	`
+	args := []interface{}{templateID, companyID}
+	if status := strings.ToUpper(strings.TrimSpace(filter.Status)); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY occurrence_date DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurring runs: %w", err)
+	}
+	defer rows.Close()
+	runs := []models.RecurringTemplateRun{}
+	for rows.Next() {
+		var r models.RecurringTemplateRun
+		if err := rows.Scan(&r.RunID, &r.TemplateID, &r.OccurrenceDate, &r.OccurrenceNo, &r.Status, &r.Amount,
+			&r.DocumentID, &r.Message, &r.ReversalDate, &r.ReversalStatus, &r.ReversalVoucherID,
+			&r.ReversalMessage, &r.StartedAt, &r.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recurring run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// PreviewTemplate lists the next count occurrences with their amounts and,
+// for auto-reversing vouchers, reversal dates.
+func (s *RecurringTemplateService) PreviewTemplate(companyID, templateID, count int) ([]models.RecurringOccurrencePreview, error) {
+	t, err := s.GetTemplate(companyID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 || count > 36 {
+		count = 12
+	}
+	if !t.IsActive {
+		scheduleRecurringTemplate(t, time.Now())
+	}
+	previews := []models.RecurringOccurrencePreview{}
+	if t.NextRunDate == nil {
+		return previews, nil
+	}
+	for n := t.NextOccurrence; len(previews) < count; n++ {
+		date := recurringOccurrenceDate(t.StartDate, t.Frequency, t.IntervalCount, n)
+		if t.EndDate != nil && date.After(recurringDate(*t.EndDate)) {
+			break
+		}
+		amount, err := recurringOccurrenceAmount(t, n, date)
+		if err != nil {
+			return nil, err
+		}
+		p := models.RecurringOccurrencePreview{OccurrenceDate: date.Format("2006-01-02"), Amount: amount}
+		if t.AutoReverse {
+			rev, err := recurringReversalDate(s.db, companyID, date)
+			if err != nil {
+				return nil, err
+			}
+			revStr := rev.Format("2006-01-02")
+			p.ReversalDate = &revStr
+		}
+		previews = append(previews, p)
+	}
+	return previews, nil
+}
+
+var recurringTemplateWake = make(chan struct{}, 1)
+
+// wakeRecurringTemplateRunner makes the runner look for due occurrences now,
+// so a template starting today runs without waiting for the next poll.
+func wakeRecurringTemplateRunner() {
+	select {
+	case recurringTemplateWake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+)
+
+func recurringTestDate(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestRecurringOccurrenceDateClampsMonthEnd(t *testing.T) {
+	start := recurringTestDate("2026-01-31")
+	want := []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}
+	for n, w := range want {
+		if got := recurringOccurrenceDate(start, models.RecurringMonthly, 1, n).Format("2006-01-02"); got != w {
+			t.Fatalf("occurrence %d: expected %s, got %s", n, w, got)
+		}
+	}
+	if got := recurringOccurrenceDate(start, models.RecurringQuarterly, 1, 1).Format("2006-01-02"); got != "2026-04-30" {
+		t.Fatalf("unexpected quarterly occurrence %s", got)
+	}
+	if got := recurringOccurrenceDate(start, models.RecurringWeekly, 2, 1).Format("2006-01-02"); got != "2026-02-14" {
+		t.Fatalf("unexpected fortnightly occurrence %s", got)
+	}
+}
+
+func TestScheduleRecurringTemplateStartsOnOrAfterToday(t *testing.T) {
+	tpl := &models.RecurringTemplate{Frequency: models.RecurringMonthly, IntervalCount: 1, StartDate: recurringTestDate("2025-01-15")}
+	scheduleRecurringTemplate(tpl, recurringTestDate("2026-03-16"))
+	if tpl.NextRunDate == nil || tpl.NextRunDate.Format("2006-01-02") != "2026-04-15" || tpl.NextOccurrence != 15 {
+		t.Fatalf("unexpected schedule %v #%d", tpl.NextRunDate, tpl.NextOccurrence)
+	}
+
+	end := recurringTestDate("2026-04-01")
+	tpl.EndDate = &end
+	scheduleRecurringTemplate(tpl, recurringTestDate("2026-03-16"))
+	if tpl.NextRunDate != nil {
+		t.Fatalf("expected a finished schedule, got %v", tpl.NextRunDate)
+	}
+}
+
+func TestRecurringOccurrenceAmountFormula(t *testing.T) {
+	formula := "round(amount * days_in_month / 365, 2) + occurrence"
+	tpl := &models.RecurringTemplate{
+		Frequency:     models.RecurringMonthly,
+		IntervalCount: 1,
+		StartDate:     recurringTestDate("2026-01-01"),
+		AmountMode:    models.RecurringAmountFormula,
+		AmountFormula: &formula,
+		Expense:       &models.CreateExpenseRequest{Amount: 3650},
+	}
+	got, err := recurringOccurrenceAmount(tpl, 1, recurringTestDate("2026-02-01"))
+	if err != nil || got != 282 {
+		t.Fatalf("expected 280 + 2, got %v (%v)", got, err)
+	}
+
+	for _, bad := range []string{"amount +", "rent * 2", "sqrt(amount)", "min(amount)", "(amount"} {
+		if _, err := parseRecurringFormula(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+	f, _ := parseRecurringFormula("amount / (occurrence - 1)")
+	if _, err := f.eval(map[string]float64{"amount": 10, "occurrence": 1}); err == nil {
+		t.Fatalf("expected division by zero to fail")
+	}
+}
+
+func TestScaleJournalLinesStaysBalanced(t *testing.T) {
+	lines := []models.CreateVoucherLineRequest{
+		{AccountID: 1, Debit: 100},
+		{AccountID: 2, Debit: 200},
+		{AccountID: 3, Credit: 300},
+	}
+	scaled := scaleJournalLines(lines, 100)
+	debits, credits := 0.0, 0.0
+	for _, l := range scaled {
+		debits += l.Debit
+		credits += l.Credit
+	}
+	if math.Abs(debits-100) > 0.001 || math.Abs(credits-100) > 0.001 || scaled[0].Debit != 33.33 || scaled[1].Debit != 66.67 {
+		t.Fatalf("unexpected scaled lines %+v", scaled)
+	}
+	if lines[0].Debit != 100 {
+		t.Fatalf("template lines were modified")
+	}
+}
+
+func TestValidateRecurringTemplateRules(t *testing.T) {
+	payment := "payment"
+	tpl := &models.RecurringTemplate{
+		Name:         "Rent",
+		DocumentType: models.RecurringDocumentVoucher,
+		VoucherType:  &payment,
+		Frequency:    models.RecurringMonthly,
+		StartDate:    recurringTestDate("2026-01-01"),
+		AutoReverse:  true,
+		Voucher:      &models.CreateVoucherRequest{AccountID: 5, Amount: 1000, Reference: "RENT"},
+	}
+	if err := validateRecurringTemplate(tpl); err == nil || err.Error() != "only journal voucher templates can auto-reverse" {
+		t.Fatalf("expected auto-reverse to need a journal, got %v", err)
+	}
+
+	tpl.AutoReverse = false
+	if err := validateRecurringTemplate(tpl); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tpl.IntervalCount != 1 || tpl.AmountMode != models.RecurringAmountFixed {
+		t.Fatalf("expected defaults, got %+v", tpl)
+	}
+
+	expense := &models.RecurringTemplate{
+		Name:         "Internet",
+		DocumentType: models.RecurringDocumentExpense,
+		Frequency:    models.RecurringMonthly,
+		StartDate:    recurringTestDate("2026-01-01"),
+		Expense:      &models.CreateExpenseRequest{CategoryID: 2, Amount: 50},
+	}
+	if err := validateRecurringTemplate(expense); err == nil {
+		t.Fatalf("expected expense templates to require a location")
+	}
+}
@@ -0,0 +1,589 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const defaultReplenishmentWindowDays = 30
+
+type ReplenishmentService struct {
+	db *sql.DB
+}
+
+func NewReplenishmentService() *ReplenishmentService {
+	return &ReplenishmentService{db: database.GetDB()}
+}
+
+type replenishmentInput struct {
+	OnHand            float64
+	InTransit         float64
+	PendingPurchase   float64
+	ReorderLevel      float64
+	MaxStock          *float64
+	AverageDailySales float64
+	LeadTimeDays      int
+	WindowDays        int
+}
+
+type replenishmentNeed struct {
+	ReorderPoint float64
+	TargetStock  float64
+	Quantity     float64
+}
+
+type replenishmentCandidate struct {
+	Suggestion     models.ReplenishmentSuggestion
+	SupplierName   string
+	IsSerialized   bool
+	PurchaseFactor float64
+}
+
+// computeReplenishmentNeed derives the reorder point and order quantity for a
+// single product/location. The reorder point is the larger of the configured
+// reorder level and the demand expected during the supplier lead time. When no
+// max stock is configured the target covers one window of average sales on top
+// of the reorder point, or doubles the reorder point when there is no history.
+func computeReplenishmentNeed(in replenishmentInput) replenishmentNeed {
+	leadDemand := in.AverageDailySales * float64(max(in.LeadTimeDays, 0))
+	reorderPoint := math.Max(in.ReorderLevel, leadDemand)
+
+	target := 0.0
+	if in.MaxStock != nil && *in.MaxStock > 0 {
+		target = math.Max(*in.MaxStock, reorderPoint)
+	} else {
+		target = reorderPoint + in.AverageDailySales*float64(max(in.WindowDays, 0))
+		if target <= reorderPoint {
+			target = reorderPoint * 2
+		}
+	}
+
+	need := replenishmentNeed{ReorderPoint: round4(reorderPoint), TargetStock: round4(target)}
+	if reorderPoint <= 0 && target <= 0 {
+		return need
+	}
+	projected := in.OnHand + in.InTransit + in.PendingPurchase
+	if projected > reorderPoint {
+		return need
+	}
+	need.Quantity = math.Ceil(target - projected)
+	if need.Quantity < 0 {
+		need.Quantity = 0
+	}
+	return need
+}
+
+func (s *ReplenishmentService) ListRules(companyID, locationID int) ([]models.ReplenishmentRule, error) {
+	query := `
+		SELECT rr.rule_id, rr.company_id, rr.location_id, rr.product_id, COALESCE(p.name, ''),
+		       rr.preferred_supplier_id, rr.reorder_level::float8, rr.max_stock::float8, rr.lead_time_days,
+		       rr.is_active, rr.created_by, rr.updated_by, rr.created_at, rr.updated_at
+		FROM replenishment_rules rr
+		JOIN products p ON p.product_id = rr.product_id
+		WHERE rr.company_id = $1
+	`
+	args := []interface{}{companyID}
+	if locationID > 0 {
+		query += " AND rr.location_id = $2"
+		args = append(args, locationID)
+	}
+	query += " ORDER BY rr.location_id, p.name"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replenishment rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]models.ReplenishmentRule, 0)
+	for rows.Next() {
+		var rule models.ReplenishmentRule
+		if err := rows.Scan(
+			&rule.RuleID, &rule.CompanyID, &rule.LocationID, &rule.ProductID, &rule.ProductName,
+			&rule.PreferredSupplierID, &rule.ReorderLevel, &rule.MaxStock, &rule.LeadTimeDays,
+			&rule.IsActive, &rule.CreatedBy, &rule.UpdatedBy, &rule.CreatedAt, &rule.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan replenishment rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (s *ReplenishmentService) UpsertRule(companyID, userID int, req *models.UpsertReplenishmentRuleRequest) (*models.ReplenishmentRule, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM locations WHERE location_id = $1 AND company_id = $2`, req.LocationID, companyID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to verify location: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("location not found")
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM products WHERE product_id = $1 AND company_id = $2 AND is_deleted = FALSE`, req.ProductID, companyID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to verify product: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("product not found")
+	}
+	if req.PreferredSupplierID != nil {
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM suppliers WHERE supplier_id = $1 AND company_id = $2`, *req.PreferredSupplierID, companyID).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to verify supplier: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("supplier not found")
+		}
+	}
+	if req.ReorderLevel != nil && req.MaxStock != nil && *req.MaxStock > 0 && *req.MaxStock < *req.ReorderLevel {
+		return nil, fmt.Errorf("max stock cannot be lower than reorder level")
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	rule := models.ReplenishmentRule{
+		CompanyID:           companyID,
+		LocationID:          req.LocationID,
+		ProductID:           req.ProductID,
+		PreferredSupplierID: req.PreferredSupplierID,
+		ReorderLevel:        req.ReorderLevel,
+		MaxStock:            req.MaxStock,
+		LeadTimeDays:        req.LeadTimeDays,
+		IsActive:            isActive,
+		UpdatedBy:           &userID,
+	}
+	err := s.db.QueryRow(`
+		INSERT INTO replenishment_rules (
+			company_id, location_id, product_id, preferred_supplier_id, reorder_level, max_stock,
+			lead_time_days, is_active, created_by, updated_by
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (location_id, product_id) DO UPDATE
+		SET preferred_supplier_id = EXCLUDED.preferred_supplier_id,
+		    reorder_level = EXCLUDED.reorder_level,
+		    max_stock = EXCLUDED.max_stock,
+		    lead_time_days = EXCLUDED.lead_time_days,
+		    is_active = EXCLUDED.is_active,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING rule_id, created_by, created_at, updated_at
+	`, companyID, req.LocationID, req.ProductID, req.PreferredSupplierID, req.ReorderLevel, req.MaxStock,
+		req.LeadTimeDays, isActive, userID,
+	).Scan(&rule.RuleID, &rule.CreatedBy, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save replenishment rule: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *ReplenishmentService) DeleteRule(companyID, ruleID int) error {
+	result, err := s.db.Exec(`DELETE FROM replenishment_rules WHERE rule_id = $1 AND company_id = $2`, ruleID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete replenishment rule: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to inspect replenishment rule delete: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("replenishment rule not found")
+	}
+	return nil
+}
+
+func (s *ReplenishmentService) loadCandidates(companyID, locationID, windowDays int) ([]replenishmentCandidate, error) {
+	rows, err := s.db.Query(`
+		WITH sales_window AS (
+			SELECT sd.product_id,
+			       SUM(COALESCE(NULLIF(sd.stock_quantity, 0), sd.quantity))::float8 AS qty
+			FROM sale_details sd
+			JOIN sales sa ON sa.sale_id = sd.sale_id
+			WHERE sa.location_id = $2
+			  AND sa.is_deleted = FALSE
+			  AND sa.status = 'COMPLETED'
+			  AND sa.sale_date >= CURRENT_DATE - $3::int
+			  AND sd.product_id IS NOT NULL
+			GROUP BY sd.product_id
+		), transit AS (
+			SELECT std.product_id,
+			       SUM(GREATEST(std.quantity - COALESCE(std.received_quantity, 0), 0))::float8 AS qty
+			FROM stock_transfer_details std
+			JOIN stock_transfers st ON st.transfer_id = std.transfer_id
+			WHERE st.to_location_id = $2
+			  AND st.status IN ('PENDING', 'IN_TRANSIT')
+			GROUP BY std.product_id
+		), pending AS (
+			SELECT pd.product_id,
+			       SUM(GREATEST(pd.quantity - COALESCE(pd.received_quantity, 0), 0)
+			           * COALESCE(NULLIF(pd.purchase_to_stock_factor, 0), 1))::float8 AS qty
+			FROM purchase_details pd
+			JOIN purchases pu ON pu.purchase_id = pd.purchase_id
+			WHERE pu.location_id = $2
+			  AND pu.is_deleted = FALSE
+			  AND pu.status IN ('PENDING', 'APPROVED', 'PARTIALLY_RECEIVED')
+			GROUP BY pd.product_id
+		)
+		SELECT p.product_id, p.name, p.sku,
+		       COALESCE(st.quantity, 0)::float8,
+		       COALESCE(tr.qty, 0),
+		       COALESCE(pe.qty, 0),
+		       COALESCE(rr.reorder_level, p.reorder_level, 0)::float8,
+		       rr.max_stock::float8,
+		       COALESCE(sw.qty, 0),
+		       sup.supplier_id,
+		       COALESCE(sup.name, ''),
+		       COALESCE(rr.lead_time_days, sup.lead_time_days, 0),
+		       COALESCE(p.cost_price, 0)::float8,
+		       COALESCE(p.is_serialized, FALSE),
+		       COALESCE(NULLIF(p.purchase_to_stock_factor, 0), 1)::float8
+		FROM products p
+		LEFT JOIN replenishment_rules rr
+		       ON rr.product_id = p.product_id AND rr.location_id = $2 AND rr.is_active = TRUE
+		LEFT JOIN stock st ON st.product_id = p.product_id AND st.location_id = $2
+		LEFT JOIN suppliers sup
+		       ON sup.supplier_id = COALESCE(rr.preferred_supplier_id, p.default_supplier_id)
+		      AND sup.company_id = p.company_id
+		      AND sup.is_active = TRUE
+		LEFT JOIN sales_window sw ON sw.product_id = p.product_id
+		LEFT JOIN transit tr ON tr.product_id = p.product_id
+		LEFT JOIN pending pe ON pe.product_id = p.product_id
+		WHERE p.company_id = $1
+		  AND p.is_deleted = FALSE
+		  AND p.is_active = TRUE
+		  AND COALESCE(p.item_type, 'PRODUCT') <> 'ASSET'
+		  AND (COALESCE(rr.reorder_level, p.reorder_level, 0) > 0 OR rr.max_stock IS NOT NULL OR COALESCE(sw.qty, 0) > 0)
+		ORDER BY p.name
+	`, companyID, locationID, windowDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replenishment candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]replenishmentCandidate, 0)
+	for rows.Next() {
+		var c replenishmentCandidate
+		var soldQty float64
+		sg := &c.Suggestion
+		if err := rows.Scan(
+			&sg.ProductID, &sg.ProductName, &sg.SKU,
+			&sg.OnHand, &sg.InTransit, &sg.PendingPurchase,
+			&sg.ReorderLevel, &sg.MaxStock, &soldQty,
+			&sg.SupplierID, &c.SupplierName, &sg.LeadTimeDays,
+			&sg.UnitCost, &c.IsSerialized, &c.PurchaseFactor,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan replenishment candidate: %w", err)
+		}
+		sg.LocationID = locationID
+		sg.AverageDailySales = round4(soldQty / float64(windowDays))
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// loadSurplus returns stock above each branch's own reorder point for the given
+// products, keyed by product and ordered by the largest surplus first.
+func (s *ReplenishmentService) loadSurplus(companyID, excludeLocationID int, productIDs []int) (map[int][]models.ReplenishmentTransferSuggestion, error) {
+	surplus := make(map[int][]models.ReplenishmentTransferSuggestion)
+	if len(productIDs) == 0 {
+		return surplus, nil
+	}
+	rows, err := s.db.Query(`
+		SELECT st.product_id, st.location_id, l.name,
+		       (COALESCE(st.quantity, 0) - COALESCE(st.reserved_quantity, 0)
+		        - GREATEST(COALESCE(rr.max_stock, 0), COALESCE(rr.reorder_level, p.reorder_level, 0)))::float8 AS surplus
+		FROM stock st
+		JOIN locations l ON l.location_id = st.location_id
+		JOIN products p ON p.product_id = st.product_id
+		LEFT JOIN replenishment_rules rr
+		       ON rr.product_id = st.product_id AND rr.location_id = st.location_id AND rr.is_active = TRUE
+		WHERE l.company_id = $1
+		  AND l.is_active = TRUE
+		  AND st.location_id <> $2
+		  AND st.product_id = ANY($3)
+		  AND COALESCE(p.is_serialized, FALSE) = FALSE
+	`, companyID, excludeLocationID, pq.Array(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load branch surplus: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.ReplenishmentTransferSuggestion
+		if err := rows.Scan(&item.ProductID, &item.FromLocationID, &item.FromLocationName, &item.AvailableSurplus); err != nil {
+			return nil, fmt.Errorf("failed to scan branch surplus: %w", err)
+		}
+		item.AvailableSurplus = math.Floor(item.AvailableSurplus)
+		if item.AvailableSurplus <= 0 {
+			continue
+		}
+		surplus[item.ProductID] = append(surplus[item.ProductID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for productID := range surplus {
+		list := surplus[productID]
+		sort.SliceStable(list, func(i, j int) bool { return list[i].AvailableSurplus > list[j].AvailableSurplus })
+	}
+	return surplus, nil
+}
+
+// BuildPlan computes replenishment suggestions for a location. Surplus stock at
+// other branches is proposed as transfers first; only the remainder is
+// suggested for purchase, grouped by preferred supplier.
+func (s *ReplenishmentService) BuildPlan(companyID, locationID, windowDays int) (*models.ReplenishmentPlan, error) {
+	plan, _, err := s.buildPlan(companyID, locationID, windowDays)
+	return plan, err
+}
+
+func (s *ReplenishmentService) buildPlan(companyID, locationID, windowDays int) (*models.ReplenishmentPlan, []replenishmentCandidate, error) {
+	if locationID == 0 {
+		return nil, nil, fmt.Errorf("location is required")
+	}
+	if windowDays <= 0 {
+		windowDays = defaultReplenishmentWindowDays
+	}
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM locations WHERE location_id = $1 AND company_id = $2 AND is_active = TRUE`, locationID, companyID).Scan(&count); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify location: %w", err)
+	}
+	if count == 0 {
+		return nil, nil, fmt.Errorf("location not found")
+	}
+
+	candidates, err := s.loadCandidates(companyID, locationID, windowDays)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	needed := make([]replenishmentCandidate, 0, len(candidates))
+	productIDs := make([]int, 0, len(candidates))
+	for _, c := range candidates {
+		sg := c.Suggestion
+		need := computeReplenishmentNeed(replenishmentInput{
+			OnHand:            sg.OnHand,
+			InTransit:         sg.InTransit,
+			PendingPurchase:   sg.PendingPurchase,
+			ReorderLevel:      sg.ReorderLevel,
+			MaxStock:          sg.MaxStock,
+			AverageDailySales: sg.AverageDailySales,
+			LeadTimeDays:      sg.LeadTimeDays,
+			WindowDays:        windowDays,
+		})
+		if need.Quantity <= 0 {
+			continue
+		}
+		c.Suggestion.ReorderPoint = need.ReorderPoint
+		c.Suggestion.TargetStock = need.TargetStock
+		c.Suggestion.SuggestedQuantity = need.Quantity
+		needed = append(needed, c)
+		productIDs = append(productIDs, sg.ProductID)
+	}
+
+	surplus, err := s.loadSurplus(companyID, locationID, productIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &models.ReplenishmentPlan{
+		LocationID:  locationID,
+		WindowDays:  windowDays,
+		GeneratedAt: time.Now(),
+		Suppliers:   []models.ReplenishmentSupplierGroup{},
+		Unassigned:  []models.ReplenishmentSuggestion{},
+		Transfers:   []models.ReplenishmentTransferSuggestion{},
+	}
+	groups := make(map[int]*models.ReplenishmentSupplierGroup)
+	order := make([]int, 0)
+	purchasable := make([]replenishmentCandidate, 0, len(needed))
+	for _, c := range needed {
+		remaining := c.Suggestion.SuggestedQuantity
+		for i := range surplus[c.Suggestion.ProductID] {
+			source := &surplus[c.Suggestion.ProductID][i]
+			if remaining <= 0 || source.AvailableSurplus <= 0 {
+				continue
+			}
+			qty := math.Min(remaining, source.AvailableSurplus)
+			plan.Transfers = append(plan.Transfers, models.ReplenishmentTransferSuggestion{
+				ProductID:         c.Suggestion.ProductID,
+				ProductName:       c.Suggestion.ProductName,
+				FromLocationID:    source.FromLocationID,
+				FromLocationName:  source.FromLocationName,
+				ToLocationID:      locationID,
+				AvailableSurplus:  source.AvailableSurplus,
+				SuggestedQuantity: qty,
+			})
+			source.AvailableSurplus -= qty
+			remaining -= qty
+			c.Suggestion.TransferQuantity += qty
+		}
+		c.Suggestion.SuggestedQuantity = remaining
+		if remaining <= 0 {
+			continue
+		}
+		c.Suggestion.EstimatedCost = round2(remaining * c.Suggestion.UnitCost)
+		purchasable = append(purchasable, c)
+
+		if c.Suggestion.SupplierID == nil {
+			plan.Unassigned = append(plan.Unassigned, c.Suggestion)
+			continue
+		}
+		supplierID := *c.Suggestion.SupplierID
+		group, ok := groups[supplierID]
+		if !ok {
+			group = &models.ReplenishmentSupplierGroup{SupplierID: supplierID, SupplierName: c.SupplierName}
+			groups[supplierID] = group
+			order = append(order, supplierID)
+		}
+		if c.Suggestion.LeadTimeDays > group.LeadTimeDays {
+			group.LeadTimeDays = c.Suggestion.LeadTimeDays
+		}
+		group.EstimatedTotal = round2(group.EstimatedTotal + c.Suggestion.EstimatedCost)
+		group.Items = append(group.Items, c.Suggestion)
+	}
+	for _, supplierID := range order {
+		plan.Suppliers = append(plan.Suppliers, *groups[supplierID])
+	}
+	return plan, purchasable, nil
+}
+
+// CreatePurchaseDrafts turns supplier-grouped suggestions into PENDING
+// purchases through PurchaseService.CreatePurchase, which raises the usual
+// purchase approval workflow request for each draft.
+func (s *ReplenishmentService) CreatePurchaseDrafts(companyID, locationID, userID int, req *models.CreateReplenishmentDraftsRequest) (*models.ReplenishmentPurchaseDraftResult, error) {
+	if req.LocationID != nil && *req.LocationID > 0 {
+		locationID = *req.LocationID
+	}
+	windowDays := 0
+	if req.WindowDays != nil {
+		windowDays = *req.WindowDays
+	}
+	_, candidates, err := s.buildPlan(companyID, locationID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	supplierFilter := intSet(req.SupplierIDs)
+	productFilter := intSet(req.ProductIDs)
+	result := &models.ReplenishmentPurchaseDraftResult{Purchases: []models.Purchase{}}
+	bySupplier := make(map[int][]models.CreatePurchaseDetailRequest)
+	supplierOrder := make([]int, 0)
+	for _, c := range candidates {
+		sg := c.Suggestion
+		if len(productFilter) > 0 && !productFilter[sg.ProductID] {
+			continue
+		}
+		if sg.SupplierID == nil {
+			continue
+		}
+		if len(supplierFilter) > 0 && !supplierFilter[*sg.SupplierID] {
+			continue
+		}
+		if c.IsSerialized {
+			supplierID := *sg.SupplierID
+			result.Skipped = append(result.Skipped, models.ReplenishmentDraftSkip{
+				SupplierID: &supplierID,
+				Reason:     fmt.Sprintf("product %s is serialized and must be ordered manually", sg.ProductName),
+			})
+			continue
+		}
+		factor := c.PurchaseFactor
+		if factor <= 0 {
+			factor = 1
+		}
+		if _, ok := bySupplier[*sg.SupplierID]; !ok {
+			supplierOrder = append(supplierOrder, *sg.SupplierID)
+		}
+		bySupplier[*sg.SupplierID] = append(bySupplier[*sg.SupplierID], models.CreatePurchaseDetailRequest{
+			ProductID: sg.ProductID,
+			Quantity:  math.Ceil(sg.SuggestedQuantity / factor),
+			UnitPrice: round2(sg.UnitCost * factor),
+		})
+	}
+
+	note := "Generated from replenishment suggestions"
+	for _, supplierID := range supplierOrder {
+		loc := locationID
+		purchase, err := NewPurchaseService().CreatePurchase(companyID, locationID, userID, &models.CreatePurchaseRequest{
+			SupplierID: supplierID,
+			LocationID: &loc,
+			Notes:      &note,
+			Items:      bySupplier[supplierID],
+		}, "")
+		if err != nil {
+			id := supplierID
+			result.Skipped = append(result.Skipped, models.ReplenishmentDraftSkip{SupplierID: &id, Reason: err.Error()})
+			continue
+		}
+		result.Purchases = append(result.Purchases, *purchase)
+	}
+	return result, nil
+}
+
+// CreateTransferDrafts creates PENDING stock transfers from branches with
+// surplus into the requesting location. Batch-tracked items that need an
+// explicit batch selection are reported as skipped.
+func (s *ReplenishmentService) CreateTransferDrafts(companyID, locationID, userID int, req *models.CreateReplenishmentDraftsRequest) (*models.ReplenishmentTransferDraftResult, error) {
+	if req.LocationID != nil && *req.LocationID > 0 {
+		locationID = *req.LocationID
+	}
+	windowDays := 0
+	if req.WindowDays != nil {
+		windowDays = *req.WindowDays
+	}
+	plan, err := s.BuildPlan(companyID, locationID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	productFilter := intSet(req.ProductIDs)
+	bySource := make(map[int][]models.CreateStockTransferDetailRequest)
+	sourceOrder := make([]int, 0)
+	for _, t := range plan.Transfers {
+		if len(productFilter) > 0 && !productFilter[t.ProductID] {
+			continue
+		}
+		if _, ok := bySource[t.FromLocationID]; !ok {
+			sourceOrder = append(sourceOrder, t.FromLocationID)
+		}
+		bySource[t.FromLocationID] = append(bySource[t.FromLocationID], models.CreateStockTransferDetailRequest{
+			ProductID: t.ProductID,
+			Quantity:  t.SuggestedQuantity,
+		})
+	}
+
+	result := &models.ReplenishmentTransferDraftResult{Transfers: []models.StockTransfer{}}
+	note := "Generated from replenishment suggestions"
+	inventory := NewInventoryService()
+	for _, sourceID := range sourceOrder {
+		transfer, err := inventory.CreateStockTransfer(companyID, sourceID, userID, &models.CreateStockTransferRequest{
+			ToLocationID: locationID,
+			Notes:        &note,
+			Items:        bySource[sourceID],
+		})
+		if err != nil {
+			id := sourceID
+			result.Skipped = append(result.Skipped, models.ReplenishmentDraftSkip{LocationID: &id, Reason: strings.TrimSpace(err.Error())})
+			continue
+		}
+		result.Transfers = append(result.Transfers, *transfer)
+	}
+	return result, nil
+}
+
+func intSet(values []int) map[int]bool {
+	set := make(map[int]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
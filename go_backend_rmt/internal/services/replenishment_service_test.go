@@ -0,0 +1,62 @@
+package services
+
+import "testing"
+
+func TestComputeReplenishmentNeed_UsesLeadTimeDemandAsReorderPoint(t *testing.T) {
+	need := computeReplenishmentNeed(replenishmentInput{
+		OnHand:            12,
+		ReorderLevel:      5,
+		AverageDailySales: 2,
+		LeadTimeDays:      7,
+		WindowDays:        30,
+	})
+	if need.ReorderPoint != 14 {
+		t.Fatalf("expected reorder point 14, got %v", need.ReorderPoint)
+	}
+	if need.TargetStock != 74 {
+		t.Fatalf("expected target 74, got %v", need.TargetStock)
+	}
+	if need.Quantity != 62 {
+		t.Fatalf("expected quantity 62, got %v", need.Quantity)
+	}
+}
+
+func TestComputeReplenishmentNeed_CountsIncomingStock(t *testing.T) {
+	maxStock := 100.0
+	need := computeReplenishmentNeed(replenishmentInput{
+		OnHand:          10,
+		InTransit:       5,
+		PendingPurchase: 20,
+		ReorderLevel:    30,
+		MaxStock:        &maxStock,
+	})
+	if need.Quantity != 0 {
+		t.Fatalf("expected no suggestion when incoming stock covers the reorder point, got %v", need.Quantity)
+	}
+
+	need = computeReplenishmentNeed(replenishmentInput{
+		OnHand:       10,
+		InTransit:    5,
+		ReorderLevel: 30,
+		MaxStock:     &maxStock,
+	})
+	if need.Quantity != 85 {
+		t.Fatalf("expected quantity up to max stock (85), got %v", need.Quantity)
+	}
+}
+
+func TestComputeReplenishmentNeed_NoHistoryDoublesReorderLevel(t *testing.T) {
+	need := computeReplenishmentNeed(replenishmentInput{
+		OnHand:       4,
+		ReorderLevel: 10,
+		WindowDays:   30,
+	})
+	if need.TargetStock != 20 || need.Quantity != 16 {
+		t.Fatalf("expected target 20 and quantity 16, got %+v", need)
+	}
+
+	need = computeReplenishmentNeed(replenishmentInput{OnHand: 0, WindowDays: 30})
+	if need.Quantity != 0 {
+		t.Fatalf("expected no suggestion without reorder level or sales, got %v", need.Quantity)
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+const (
+	reportJobPollInterval    = 5 * time.Second
+	reportJobHousekeeping    = 10 * time.Minute
+	reportJobStaleAfter      = 30 * time.Minute
+	reportJobMaxAttempts     = 3
+	reportDataChangeRetained = 24 * time.Hour
+)
+
+// ReportJobRunner drains report_jobs with a fixed number of workers. Jobs are
+// claimed with SKIP LOCKED so several replicas can share the queue.
+type ReportJobRunner struct {
+	db       *sql.DB
+	workers  int
+	workerID string
+	wg       sync.WaitGroup
+}
+
+func NewReportJobRunner(workers int) *ReportJobRunner {
+	host, _ := os.Hostname()
+	return &ReportJobRunner{
+		db:       database.GetDB(),
+		workers:  workers,
+		workerID: fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+// Start launches the workers and the housekeeping loop. It returns at once;
+// cancel ctx and call Shutdown to stop.
+func (r *ReportJobRunner) Start(ctx context.Context) {
+	if r.workers <= 0 {
+		log.Printf("report jobs: no workers configured on this replica")
+		return
+	}
+	for i := 0; i < r.workers; i++ {
+		r.wg.Add(1)
+		go r.work(ctx, fmt.Sprintf("%s/%d", r.workerID, i))
+	}
+	r.wg.Add(1)
+	go r.housekeep(ctx)
+}
+
+// Shutdown waits up to timeout for running jobs, then hands whatever this
+// process still holds back to the queue.
+func (r *ReportJobRunner) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	if _, err := r.db.Exec(`
+		UPDATE report_jobs SET status = 'QUEUED', worker_id = NULL, started_at = NULL
+		WHERE status = 'RUNNING' AND worker_id LIKE $1::text || '/%'
+	`, r.workerID); err != nil {
+		log.Printf("warning: failed to release report jobs: %v", err)
+	}
+}
+
+func (r *ReportJobRunner) work(ctx context.Context, workerID string) {
+	defer r.wg.Done()
+	for {
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			job, err := r.claim(workerID)
+			if err != nil {
+				log.Printf("warning: failed to claim report job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			r.execute(job, workerID)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-reportJobWake:
+		case <-time.After(reportJobPollInterval):
+		}
+	}
+}
+
+func (r *ReportJobRunner) claim(workerID string) (*models.ReportJob, error) {
+	job, err := scanReportJob(r.db.QueryRow(`
+		UPDATE report_jobs
+		SET status = 'RUNNING', worker_id = $1, started_at = NOW(), attempts = attempts + 1, error = NULL
+		WHERE job_id = (
+			SELECT job_id FROM report_jobs
+			WHERE status = 'QUEUED'
+			ORDER BY created_at, job_id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING job_id, company_id, requested_by, report_type, params, data_version, status, attempts,
+		          row_count, error, created_at, started_at, finished_at, expires_at
+	`, workerID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+func (r *ReportJobRunner) execute(job *models.ReportJob, workerID string) {
+	rowCount, err := r.runSafely(job)
+	if err != nil {
+		log.Printf("report job %d (%s) failed: %v", job.JobID, job.ReportType, err)
+		if _, uerr := r.db.Exec(`
+			UPDATE report_jobs SET status = 'FAILED', error = $3, finished_at = NOW()
+			WHERE job_id = $1 AND worker_id = $2 AND status = 'RUNNING'
+		`, job.JobID, workerID, err.Error()); uerr != nil {
+			log.Printf("warning: failed to mark report job %d failed: %v", job.JobID, uerr)
+		}
+		return
+	}
+	if _, err := r.db.Exec(`
+		UPDATE report_jobs
+		SET status = 'SUCCEEDED', row_count = $3, finished_at = NOW(),
+		    expires_at = NOW() + make_interval(secs => $4),
+		    result_json_key = $5::text || '.json', result_excel_key = $5::text || '.xlsx', result_pdf_key = $5::text || '.pdf'
+		WHERE job_id = $1 AND worker_id = $2 AND status = 'RUNNING'
+	`, job.JobID, workerID, rowCount, int64(reportJobResultTTL()/time.Second), reportJobResultBase(job.CompanyID, job.JobID)); err != nil {
+		log.Printf("warning: failed to mark report job %d succeeded: %v", job.JobID, err)
+	}
+}
+
+func (r *ReportJobRunner) runSafely(job *models.ReportJob) (n int, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("report job panicked: %v", p)
+		}
+	}()
+	store, err := GetObjectStorage()
+	if err != nil {
+		return 0, err
+	}
+	return runReportJob(r.db, store, job)
+}
+
+func (r *ReportJobRunner) housekeep(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		r.recoverStale()
+		r.expireResults()
+		r.pruneDataChanges()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reportJobHousekeeping):
+		}
+	}
+}
+
+// recoverStale requeues jobs whose worker disappeared, giving up after
+// reportJobMaxAttempts.
+func (r *ReportJobRunner) recoverStale() {
+	cutoff := int64(reportJobStaleAfter / time.Second)
+	if _, err := r.db.Exec(`
+		UPDATE report_jobs
+		SET status = CASE WHEN attempts >= $2 THEN 'FAILED' ELSE 'QUEUED' END,
+		    error = CASE WHEN attempts >= $2 THEN 'worker stopped responding' ELSE error END,
+		    finished_at = CASE WHEN attempts >= $2 THEN NOW() ELSE finished_at END,
+		    worker_id = NULL
+		WHERE status = 'RUNNING' AND started_at < NOW() - make_interval(secs => $1)
+	`, cutoff, reportJobMaxAttempts); err != nil {
+		log.Printf("warning: failed to recover stale report jobs: %v", err)
+	}
+}
+
+func (r *ReportJobRunner) expireResults() {
+	rows, err := r.db.Query(`
+		UPDATE report_jobs SET status = 'EXPIRED'
+		WHERE status = 'SUCCEEDED' AND expires_at <= NOW()
+		RETURNING result_json_key, result_excel_key, result_pdf_key
+	`)
+	if err != nil {
+		log.Printf("warning: failed to expire report jobs: %v", err)
+		return
+	}
+	var keys []string
+	for rows.Next() {
+		var a, b, c sql.NullString
+		if err := rows.Scan(&a, &b, &c); err != nil {
+			log.Printf("warning: failed to scan expired report job: %v", err)
+			continue
+		}
+		for _, k := range []sql.NullString{a, b, c} {
+			if k.Valid && strings.TrimSpace(k.String) != "" {
+				keys = append(keys, k.String)
+			}
+		}
+	}
+	rows.Close()
+	if len(keys) == 0 {
+		return
+	}
+
+	store, err := GetObjectStorage()
+	if err != nil {
+		log.Printf("warning: failed to open object storage: %v", err)
+		return
+	}
+	for _, key := range keys {
+		if err := store.Delete(key); err != nil {
+			log.Printf("warning: failed to delete expired report output %s: %v", key, err)
+		}
+	}
+}
+
+// pruneDataChanges drops old change markers but always keeps the latest one
+// per company and domain.
+func (r *ReportJobRunner) pruneDataChanges() {
+	if _, err := r.db.Exec(`
+		DELETE FROM report_data_changes d
+		WHERE d.changed_at < NOW() - make_interval(secs => $1)
+		  AND d.change_id < (
+		    SELECT MAX(m.change_id) FROM report_data_changes m
+		    WHERE m.company_id = d.company_id AND m.domain = d.domain
+		  )
+	`, int64(reportDataChangeRetained/time.Second)); err != nil {
+		log.Printf("warning: failed to prune report data changes: %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"erp-backend/internal/config"
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+)
+
+// Data domains whose changes invalidate cached report results. The migration
+// keeps report_data_changes up to date for each domain.
+const (
+	reportDomainLedger = "ledger"
+	reportDomainStock  = "stock"
+)
+
+const (
+	reportJobDefaultLedgerRows = 5000
+	reportJobMaxLedgerRows     = 100000
+)
+
+// reportJobDefinition maps a background report onto the synchronous
+// ReportsService call and its export schema.
+type reportJobDefinition struct {
+	domain   string
+	endpoint string
+	run      func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error)
+}
+
+var reportJobDefinitions = map[string]reportJobDefinition{
+	"general-ledger": {
+		domain:   reportDomainLedger,
+		endpoint: "/reports/general-ledger",
+		run: func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error) {
+			return r.generalLedger(companyID, p.FromDate, p.ToDate, p.Limit, p.LedgerDimensionFilter())
+		},
+	},
+	"trial-balance": {
+		domain:   reportDomainLedger,
+		endpoint: "/reports/trial-balance",
+		run: func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error) {
+			return r.GetTrialBalance(companyID, p.FromDate, p.ToDate, p.LedgerDimensionFilter())
+		},
+	},
+	"balance-sheet": {
+		domain:   reportDomainLedger,
+		endpoint: "/reports/balance-sheet",
+		run: func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error) {
+			return r.GetBalanceSheet(companyID, p.AsOfDate)
+		},
+	},
+	"item-movement": {
+		domain:   reportDomainStock,
+		endpoint: "/reports/item-movement",
+		run: func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error) {
+			return r.GetItemMovement(companyID, reportJobLocations(p), p.FromDate, p.ToDate)
+		},
+	},
+	"valuation": {
+		domain:   reportDomainStock,
+		endpoint: "/reports/valuation",
+		run: func(r *ReportsService, companyID int, p models.ReportJobParams) ([]map[string]interface{}, error) {
+			return r.GetValuationReport(companyID, reportJobLocations(p))
+		},
+	},
+}
+
+// reportJobLocations is the location filter a job runs with; jobs queued
+// before LocationIDs existed only carry LocationID.
+func reportJobLocations(p models.ReportJobParams) []int {
+	if len(p.LocationIDs) > 0 {
+		return p.LocationIDs
+	}
+	if p.LocationID != nil {
+		return []int{*p.LocationID}
+	}
+	return nil
+}
+
+// reportJobWake nudges this process's workers when a job is queued; other
+// replicas pick it up on their next poll.
+var reportJobWake = make(chan struct{}, 1)
+
+type ReportJobService struct {
+	db *sql.DB
+}
+
+func NewReportJobService() *ReportJobService {
+	return &ReportJobService{db: database.GetDB()}
+}
+
+// normalizeReportJobParams validates p for reportType and drops fields the
+// report ignores so equivalent requests hash the same.
+func normalizeReportJobParams(reportType string, p models.ReportJobParams) (models.ReportJobParams, error) {
+	out := models.ReportJobParams{}
+	for _, d := range []struct{ name, value string }{
+		{"from_date", p.FromDate}, {"to_date", p.ToDate}, {"as_of_date", p.AsOfDate},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return out, fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+		}
+	}
+	if p.FromDate != "" && p.ToDate != "" && p.FromDate > p.ToDate {
+		return out, fmt.Errorf("from_date must not be after to_date")
+	}
+
+	switch reportType {
+	case "general-ledger":
+		out.FromDate, out.ToDate = p.FromDate, p.ToDate
+		out.Limit = p.Limit
+		if out.Limit <= 0 {
+			out.Limit = reportJobDefaultLedgerRows
+		}
+		if out.Limit > reportJobMaxLedgerRows {
+			return out, fmt.Errorf("limit must not exceed %d", reportJobMaxLedgerRows)
+		}
+		out.DimensionValueIDs, out.GroupByDimension = normalizeDimensionValueIDs(p.DimensionValueIDs), p.GroupByDimension
+	case "trial-balance":
+		out.FromDate, out.ToDate = p.FromDate, p.ToDate
+		out.DimensionValueIDs, out.GroupByDimension = normalizeDimensionValueIDs(p.DimensionValueIDs), p.GroupByDimension
+	case "balance-sheet":
+		out.AsOfDate = p.AsOfDate
+		if out.AsOfDate == "" {
+			out.AsOfDate = p.ToDate
+		}
+	case "item-movement":
+		out.FromDate, out.ToDate = p.FromDate, p.ToDate
+		out.LocationID = p.LocationID
+	case "valuation":
+		out.LocationID = p.LocationID
+	default:
+		return out, fmt.Errorf("unsupported report type: %s", reportType)
+	}
+	if out.LocationID != nil && *out.LocationID <= 0 {
+		return out, fmt.Errorf("location_id must be positive")
+	}
+	if out.GroupByDimension < 0 {
+		return out, fmt.Errorf("group_by_dimension must be positive")
+	}
+	for _, id := range out.DimensionValueIDs {
+		if id <= 0 {
+			return out, fmt.Errorf("dimension_value_ids must be positive")
+		}
+	}
+	return out, nil
+}
+
+// normalizeDimensionValueIDs sorts and dedupes ids so equivalent filters
+// share a cache entry.
+func normalizeDimensionValueIDs(ids []int) []int {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := append([]int(nil), ids...)
+	sort.Ints(out)
+	n := 1
+	for i := 1; i < len(out); i++ {
+		if out[i] != out[n-1] {
+			out[n] = out[i]
+			n++
+		}
+	}
+	return out[:n]
+}
+
+func reportJobParamsHash(reportType string, p models.ReportJobParams) (string, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report params: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(reportType+"\n"), raw...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reportDataVersion fingerprints the committed changes in a data domain. Rows
+// are only removed by pruning, which merely causes a cache miss.
+func reportDataVersion(q sqlQueryRower, companyID int, domain string) (string, error) {
+	var (
+		count  int64
+		latest int64
+	)
+	err := q.QueryRow(`
+		SELECT COUNT(*), COALESCE(MAX(change_id), 0)
+		FROM report_data_changes
+		WHERE company_id = $1 AND domain = $2
+	`, companyID, domain).Scan(&count, &latest)
+	if err != nil {
+		return "", fmt.Errorf("failed to get report data version: %w", err)
+	}
+	return fmt.Sprintf("%s:%d:%d", domain, count, latest), nil
+}
+
+// Submit queues a report, or returns an in-flight or cached job computed for
+// the same parameters against the same data.
+func (s *ReportJobService) Submit(companyID, userID, roleID int, req *models.CreateReportJobRequest) (*models.ReportJob, error) {
+	def, ok := reportJobDefinitions[req.ReportType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report type: %s", req.ReportType)
+	}
+	params, err := normalizeReportJobParams(req.ReportType, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	if params.LocationID != nil {
+		var one int
+		err := s.db.QueryRow(`SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2`, *params.LocationID, companyID).Scan(&one)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("location not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify location: %w", err)
+		}
+	}
+	if req.ReportType == "item-movement" || req.ReportType == "valuation" {
+		params.LocationIDs, err = (&ReportsService{db: s.db}).ReportLocations(companyID, userID, roleID, params.LocationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.ReportType == "valuation" {
+		method, err := loadCompanyCostingMethod(s.db, companyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inventory costing method: %w", err)
+		}
+		params.CostingMethod = method
+	}
+
+	hash, err := reportJobParamsHash(req.ReportType, params)
+	if err != nil {
+		return nil, err
+	}
+	version, err := reportDataVersion(s.db, companyID, def.domain)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := scanReportJob(s.db.QueryRow(reportJobSelect+`
+		WHERE company_id = $1 AND report_type = $2 AND params_hash = $3 AND data_version = $4
+		  AND (status IN ('QUEUED', 'RUNNING') OR (status = 'SUCCEEDED' AND expires_at > NOW()))
+		ORDER BY job_id DESC
+		LIMIT 1
+	`, companyID, req.ReportType, hash, version))
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up cached report: %w", err)
+	}
+	if err == nil {
+		existing.CacheHit = true
+		if err := s.attachDownloads(existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report params: %w", err)
+	}
+	var jobID int
+	err = s.db.QueryRow(`
+		INSERT INTO report_jobs (company_id, requested_by, report_type, params, params_hash, data_version)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING job_id
+	`, companyID, userID, req.ReportType, paramsJSON, hash, version).Scan(&jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue report job: %w", err)
+	}
+
+	select {
+	case reportJobWake <- struct{}{}:
+	default:
+	}
+	return s.GetJob(companyID, jobID)
+}
+
+const reportJobSelect = `
+	SELECT job_id, company_id, requested_by, report_type, params, data_version, status, attempts,
+	       row_count, error, created_at, started_at, finished_at, expires_at
+	FROM report_jobs
+`
+
+func scanReportJob(row interface{ Scan(...interface{}) error }) (*models.ReportJob, error) {
+	var (
+		job    models.ReportJob
+		params []byte
+	)
+	err := row.Scan(&job.JobID, &job.CompanyID, &job.RequestedBy, &job.ReportType, &params, &job.DataVersion,
+		&job.Status, &job.Attempts, &job.RowCount, &job.Error, &job.CreatedAt, &job.StartedAt, &job.FinishedAt, &job.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &job.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode report params: %w", err)
+		}
+	}
+	return &job, nil
+}
+
+func (s *ReportJobService) GetJob(companyID, jobID int) (*models.ReportJob, error) {
+	job, err := scanReportJob(s.db.QueryRow(reportJobSelect+`WHERE company_id = $1 AND job_id = $2`, companyID, jobID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("report job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report job: %w", err)
+	}
+	if err := s.attachDownloads(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *ReportJobService) ListJobs(companyID int, filter models.ReportJobFilter) ([]models.ReportJob, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(reportJobSelect+`
+		WHERE company_id = $1
+		  AND ($2::text = '' OR status = $2)
+		  AND ($3::text = '' OR report_type = $3)
+		ORDER BY created_at DESC, job_id DESC
+		LIMIT $4
+	`, companyID, filter.Status, filter.ReportType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.ReportJob{}
+	for rows.Next() {
+		job, err := scanReportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list report jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ResultURL returns a signed download URL for one output of a finished job.
+// format is json, excel or pdf.
+func (s *ReportJobService) ResultURL(companyID, jobID int, format string) (string, error) {
+	job, err := s.GetJob(companyID, jobID)
+	if err != nil {
+		return "", err
+	}
+	if job.Downloads == nil {
+		return "", fmt.Errorf("report job is %s", strings.ToLower(job.Status))
+	}
+	switch format {
+	case "", "json":
+		return job.Downloads.JSON, nil
+	case "excel", "xlsx":
+		return job.Downloads.Excel, nil
+	case "pdf":
+		return job.Downloads.PDF, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func (s *ReportJobService) attachDownloads(job *models.ReportJob) error {
+	if job.Status != models.ReportJobSucceeded {
+		return nil
+	}
+	store, err := GetObjectStorage()
+	if err != nil {
+		return err
+	}
+	ttl := StorageSignedURLTTL()
+	base := reportJobResultBase(job.CompanyID, job.JobID)
+	downloads := &models.ReportJobDownloads{ExpiresAt: time.Now().Add(ttl).UTC()}
+	for _, out := range []struct {
+		ext string
+		dst *string
+	}{{"json", &downloads.JSON}, {"xlsx", &downloads.Excel}, {"pdf", &downloads.PDF}} {
+		url, err := store.SignedURL(base+"."+out.ext, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to sign report download: %w", err)
+		}
+		*out.dst = url
+	}
+	job.Downloads = downloads
+	return nil
+}
+
+// reportJobResultBase is the object key results are stored under. It is
+// outside the public /uploads directories; clients download results only
+// through signed URLs.
+func reportJobResultBase(companyID, jobID int) string {
+	return fmt.Sprintf("report-jobs/%d/%d/result", companyID, jobID)
+}
+
+// runReportJob computes the report and uploads its JSON, XLSX and PDF forms.
+func runReportJob(db *sql.DB, store ObjectStorage, job *models.ReportJob) (int, error) {
+	def, ok := reportJobDefinitions[job.ReportType]
+	if !ok {
+		return 0, fmt.Errorf("unsupported report type: %s", job.ReportType)
+	}
+	rows, err := def.run(&ReportsService{db: db}, job.CompanyID, job.Params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run report: %w", err)
+	}
+
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode report: %w", err)
+	}
+	excel, err := utils.GenerateExcel(def.endpoint, rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate Excel: %w", err)
+	}
+	pdf, err := utils.GeneratePDF(def.endpoint, rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate PDF: %w", err)
+	}
+
+	base := reportJobResultBase(job.CompanyID, job.JobID)
+	for _, out := range []struct {
+		ext, contentType string
+		body             []byte
+	}{
+		{"json", "application/json", payload},
+		{"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", excel},
+		{"pdf", "application/pdf", pdf},
+	} {
+		if err := store.Put(base+"."+out.ext, bytes.NewReader(out.body), int64(len(out.body)), out.contentType); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+func reportJobResultTTL() time.Duration {
+	if ttl := config.Load().ReportJobResultTTL; ttl > 0 {
+		return ttl
+	}
+	return 24 * time.Hour
+}
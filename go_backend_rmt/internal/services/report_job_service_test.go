@@ -0,0 +1,103 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"erp-backend/internal/models"
+)
+
+func TestNormalizeReportJobParamsDropsIrrelevantFields(t *testing.T) {
+	loc := 3
+	p, err := normalizeReportJobParams("valuation", models.ReportJobParams{
+		FromDate:   "2026-01-01",
+		ToDate:     "2026-01-31",
+		Limit:      10,
+		LocationID: &loc,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.FromDate != "" || p.ToDate != "" || p.Limit != 0 {
+		t.Fatalf("expected only location to survive, got %+v", p)
+	}
+	if p.LocationID == nil || *p.LocationID != 3 {
+		t.Fatalf("expected location 3, got %+v", p.LocationID)
+	}
+
+	a, _ := reportJobParamsHash("valuation", p)
+	other, _ := normalizeReportJobParams("valuation", models.ReportJobParams{LocationID: &loc})
+	b, _ := reportJobParamsHash("valuation", other)
+	if a != b {
+		t.Fatalf("expected equivalent requests to hash the same")
+	}
+	c, _ := reportJobParamsHash("item-movement", other)
+	if a == c {
+		t.Fatalf("expected report type to change the hash")
+	}
+}
+
+func TestNormalizeReportJobParamsDefaults(t *testing.T) {
+	gl, err := normalizeReportJobParams("general-ledger", models.ReportJobParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gl.Limit != reportJobDefaultLedgerRows {
+		t.Fatalf("expected default ledger limit, got %d", gl.Limit)
+	}
+
+	bs, err := normalizeReportJobParams("balance-sheet", models.ReportJobParams{ToDate: "2026-03-31"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bs.AsOfDate != "2026-03-31" || bs.ToDate != "" {
+		t.Fatalf("expected to_date to become as_of_date, got %+v", bs)
+	}
+}
+
+func TestNormalizeReportJobParamsRejectsBadInput(t *testing.T) {
+	zero := 0
+	cases := []struct {
+		name       string
+		reportType string
+		params     models.ReportJobParams
+	}{
+		{"bad date", "trial-balance", models.ReportJobParams{FromDate: "01/02/2026"}},
+		{"reversed range", "trial-balance", models.ReportJobParams{FromDate: "2026-02-01", ToDate: "2026-01-01"}},
+		{"limit too high", "general-ledger", models.ReportJobParams{Limit: reportJobMaxLedgerRows + 1}},
+		{"bad location", "item-movement", models.ReportJobParams{LocationID: &zero}},
+		{"unknown type", "cash-book", models.ReportJobParams{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := normalizeReportJobParams(tc.reportType, tc.params); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestReportDataVersionFingerprintsDomain(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM report_data_changes")).
+		WithArgs(7, "stock").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(12, 340))
+
+	v, err := reportDataVersion(db, 7, "stock")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "stock:12:340" {
+		t.Fatalf("unexpected version %q", v)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
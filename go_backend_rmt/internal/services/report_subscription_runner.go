@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+)
+
+const (
+	reportSubscriptionPollInterval = 30 * time.Second
+	reportDeliveryStaleAfter       = 30 * time.Minute
+)
+
+// ReportSubscriptionRunner sends due report subscriptions. Each due row is
+// claimed and advanced to its next firing in one transaction with SKIP LOCKED,
+// so replicas never send the same slot twice. A subscription that was due
+// several times while the server was down is sent once.
+type ReportSubscriptionRunner struct {
+	db *sql.DB
+	wg sync.WaitGroup
+}
+
+func NewReportSubscriptionRunner() *ReportSubscriptionRunner {
+	return &ReportSubscriptionRunner{db: database.GetDB()}
+}
+
+// Start launches the delivery loop. It returns at once; cancel ctx and call
+// Shutdown to stop.
+func (r *ReportSubscriptionRunner) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+}
+
+// Shutdown waits up to timeout for an in-flight delivery to finish.
+func (r *ReportSubscriptionRunner) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (r *ReportSubscriptionRunner) loop(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		r.failStaleDeliveries()
+		for ctx.Err() == nil {
+			sub, deliveryID, scheduledFor, err := r.claim()
+			if err != nil {
+				log.Printf("warning: failed to claim report subscription: %v", err)
+				break
+			}
+			if sub == nil {
+				break
+			}
+			r.deliver(sub, deliveryID, scheduledFor)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-reportSubscriptionWake:
+		case <-time.After(reportSubscriptionPollInterval):
+		}
+	}
+}
+
+// claim picks one due subscription, moves next_run_at past now and opens a
+// RUNNING delivery row for it.
+func (r *ReportSubscriptionRunner) claim() (*models.ReportSubscription, int, time.Time, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sub, err := scanReportSubscription(tx.QueryRow(reportSubscriptionSelect + `
+		WHERE is_active = TRUE AND next_run_at <= NOW()
+		ORDER BY next_run_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	`))
+	if err == sql.ErrNoRows {
+		return nil, 0, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to load due subscription: %w", err)
+	}
+	scheduledFor := *sub.NextRunAt
+
+	// A schedule that no longer parses (e.g. a timezone removed from tzdata)
+	// pauses the subscription instead of failing every poll.
+	next, nextErr := nextReportSubscriptionRun(sub.Schedule, sub.Timezone, time.Now())
+	if nextErr != nil {
+		if _, err := tx.Exec(`
+			UPDATE report_subscriptions
+			SET is_active = FALSE, next_run_at = NULL, last_run_at = NOW(), last_status = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE subscription_id = $1
+		`, sub.SubscriptionID, models.ReportDeliveryFailed); err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("failed to pause report subscription: %w", err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO report_subscription_deliveries
+			  (subscription_id, company_id, scheduled_for, status, error, finished_at)
+			VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		`, sub.SubscriptionID, sub.CompanyID, scheduledFor, models.ReportDeliveryFailed,
+			"subscription paused: "+nextErr.Error()); err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("failed to record report delivery: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, 0, time.Time{}, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return r.claim()
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE report_subscriptions SET next_run_at = $2, last_run_at = NOW()
+		WHERE subscription_id = $1
+	`, sub.SubscriptionID, next); err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to advance report subscription: %w", err)
+	}
+	recipients, err := json.Marshal(sub.Recipients)
+	if err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to encode recipients: %w", err)
+	}
+	var deliveryID int
+	if err := tx.QueryRow(`
+		INSERT INTO report_subscription_deliveries (subscription_id, company_id, scheduled_for, recipients)
+		VALUES ($1, $2, $3, $4)
+		RETURNING delivery_id
+	`, sub.SubscriptionID, sub.CompanyID, scheduledFor, recipients).Scan(&deliveryID); err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to record report delivery: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, time.Time{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sub, deliveryID, scheduledFor, nil
+}
+
+func (r *ReportSubscriptionRunner) deliver(sub *models.ReportSubscription, deliveryID int, scheduledFor time.Time) {
+	out := r.deliverSafely(sub, scheduledFor)
+	if out.status != models.ReportDeliverySucceeded {
+		log.Printf("report subscription %d delivery %d %s: %s", sub.SubscriptionID, deliveryID, out.status, out.err)
+	}
+
+	failed, _ := json.Marshal(append([]string{}, out.failed...))
+	var fileName *string
+	var fileSize *int
+	if out.fileName != "" {
+		fileName, fileSize = &out.fileName, &out.fileSize
+	}
+	var errText *string
+	if out.err != "" {
+		errText = &out.err
+	}
+	if _, err := r.db.Exec(`
+		UPDATE report_subscription_deliveries
+		SET status = $2, failed_recipients = $3, row_count = $4, file_name = $5, file_size = $6,
+		    error = $7, finished_at = CURRENT_TIMESTAMP
+		WHERE delivery_id = $1
+	`, deliveryID, out.status, failed, out.rowCount, fileName, fileSize, errText); err != nil {
+		log.Printf("warning: failed to record report delivery %d: %v", deliveryID, err)
+	}
+	if _, err := r.db.Exec(`
+		UPDATE report_subscriptions SET last_status = $2 WHERE subscription_id = $1
+	`, sub.SubscriptionID, out.status); err != nil {
+		log.Printf("warning: failed to update report subscription %d: %v", sub.SubscriptionID, err)
+	}
+}
+
+func (r *ReportSubscriptionRunner) deliverSafely(sub *models.ReportSubscription, runAt time.Time) (out reportSubscriptionOutcome) {
+	defer func() {
+		if p := recover(); p != nil {
+			out = reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: fmt.Sprintf("report delivery panicked: %v", p)}
+		}
+	}()
+	return deliverReportSubscription(r.db, sub, runAt)
+}
+
+// failStaleDeliveries closes deliveries left RUNNING by a process that died
+// mid-send. They are not retried, since some recipients may already have the
+// email.
+func (r *ReportSubscriptionRunner) failStaleDeliveries() {
+	if _, err := r.db.Exec(`
+		UPDATE report_subscription_deliveries
+		SET status = 'FAILED', error = 'delivery interrupted', finished_at = CURRENT_TIMESTAMP
+		WHERE status = 'RUNNING' AND started_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+	`, int64(reportDeliveryStaleAfter/time.Second)); err != nil {
+		log.Printf("warning: failed to close stale report deliveries: %v", err)
+	}
+}
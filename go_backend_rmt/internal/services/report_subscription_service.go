@@ -0,0 +1,721 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+	"erp-backend/internal/utils"
+)
+
+// reportSubscriptionPermission is what every /reports endpoint requires; it is
+// re-checked for the subscriber before each delivery.
+const reportSubscriptionPermission = "VIEW_REPORTS"
+
+type reportSubscriptionRun func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error)
+
+// reportSubscriptionEndpoints mirrors the query parameters each /reports
+// handler reads, so a subscription produces the same data as the live call.
+var reportSubscriptionEndpoints = map[string]reportSubscriptionRun{
+	"/reports/sales-summary": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetSalesSummary(companyID, p.FromDate, p.ToDate, p.GroupBy)
+	},
+	"/reports/stock-summary": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetStockSummary(companyID, p.LocationIDs, p.ProductID)
+	},
+	"/reports/top-products": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetTopProducts(companyID, p.FromDate, p.ToDate, reportSubscriptionLimit(p, 10))
+	},
+	"/reports/customer-balances": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetCustomerBalances(companyID)
+	},
+	"/reports/expenses-summary": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetExpensesSummary(companyID, p.GroupBy)
+	},
+	"/reports/item-movement": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetItemMovement(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/valuation": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetValuationReport(companyID, p.LocationIDs)
+	},
+	"/reports/purchase-vs-returns": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetPurchaseVsReturns(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/supplier": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetSupplierReport(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/invoice-match-variances": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetInvoiceMatchVarianceReport(companyID, p.LocationIDs, p.FromDate, p.ToDate, p.Status)
+	},
+	"/reports/daily-cash": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetDailyCashReport(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/cash-book": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetCashBookReport(companyID, nil, p.FromDate, p.ToDate)
+	},
+	"/reports/bank-book": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetBankBookReport(companyID, p.BankAccountID, p.FromDate, p.ToDate)
+	},
+	"/reports/reconciliation-summary": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetReconciliationSummaryReport(companyID, p.BankAccountID, p.FromDate, p.ToDate)
+	},
+	"/reports/income-expense": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetIncomeExpenseReport(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/general-ledger": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetGeneralLedger(companyID, p.FromDate, p.ToDate, p.Limit, p.LedgerDimensionFilter())
+	},
+	"/reports/trial-balance": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetTrialBalance(companyID, p.FromDate, p.ToDate, p.LedgerDimensionFilter())
+	},
+	"/reports/profit-loss": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetProfitLoss(companyID, p.FromDate, p.ToDate, p.LedgerDimensionFilter())
+	},
+	"/reports/balance-sheet": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		asOf := p.AsOfDate
+		if asOf == "" {
+			asOf = p.ToDate
+		}
+		return r.GetBalanceSheet(companyID, asOf)
+	},
+	"/reports/outstanding": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetOutstandingReport(companyID, p.LocationIDs)
+	},
+	"/reports/tax": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetTaxReport(companyID, p.FromDate, p.ToDate)
+	},
+	"/reports/tax-review": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetTaxReviewReport(companyID, p.FromDate, p.ToDate)
+	},
+	"/reports/top-performers": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetTopPerformers(companyID, p.FromDate, p.ToDate, reportSubscriptionLimit(p, 10))
+	},
+	"/reports/asset-register": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetAssetRegisterReport(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/asset-value-summary": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetAssetValueSummary(companyID, p.LocationIDs)
+	},
+	"/reports/consumable-consumption": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetConsumableConsumptionReport(companyID, p.LocationIDs, p.FromDate, p.ToDate)
+	},
+	"/reports/consumable-balance": func(r *ReportsService, companyID int, p models.ReportSubscriptionParams) (interface{}, error) {
+		return r.GetConsumableBalanceReport(companyID, p.LocationIDs)
+	},
+}
+
+func reportSubscriptionLimit(p models.ReportSubscriptionParams, def int) int {
+	if p.Limit > 0 {
+		return p.Limit
+	}
+	return def
+}
+
+var reportSubscriptionFormats = map[string]struct {
+	contentType string
+	generate    func(endpoint string, data interface{}) ([]byte, error)
+}{
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", utils.GenerateExcel},
+	"pdf":  {"application/pdf", utils.GeneratePDF},
+	"csv":  {"text/csv; charset=utf-8", utils.GenerateCSV},
+}
+
+type ReportSubscriptionService struct {
+	db *sql.DB
+}
+
+func NewReportSubscriptionService() *ReportSubscriptionService {
+	return &ReportSubscriptionService{db: database.GetDB()}
+}
+
+// normalizeReportSubscriptionEndpoint accepts "/reports/x", "/api/v1/reports/x"
+// or just "x".
+func normalizeReportSubscriptionEndpoint(endpoint string) (string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if i := strings.Index(endpoint, "/reports/"); i >= 0 {
+		endpoint = endpoint[i:]
+	} else {
+		endpoint = "/reports/" + strings.TrimPrefix(endpoint, "/")
+	}
+	if i := strings.IndexAny(endpoint, "?#"); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if _, ok := reportSubscriptionEndpoints[endpoint]; !ok {
+		return "", fmt.Errorf("unsupported report endpoint: %s", endpoint)
+	}
+	return endpoint, nil
+}
+
+func validateReportSubscriptionParams(endpoint string, p models.ReportSubscriptionParams) error {
+	for _, d := range []struct{ name, value string }{
+		{"from_date", p.FromDate}, {"to_date", p.ToDate}, {"as_of_date", p.AsOfDate},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+		}
+	}
+	if p.DateRange == "" && p.FromDate != "" && p.ToDate != "" && p.FromDate > p.ToDate {
+		return fmt.Errorf("from_date must not be after to_date")
+	}
+	for _, id := range []struct {
+		name  string
+		value *int
+	}{
+		{"location_id", p.LocationID}, {"product_id", p.ProductID}, {"bank_account_id", p.BankAccountID},
+	} {
+		if id.value != nil && *id.value <= 0 {
+			return fmt.Errorf("%s must be positive", id.name)
+		}
+	}
+	if p.GroupByDimension < 0 {
+		return fmt.Errorf("group_by_dimension must be positive")
+	}
+	for _, id := range p.DimensionValueIDs {
+		if id <= 0 {
+			return fmt.Errorf("dimension_value_ids must be positive")
+		}
+	}
+	switch endpoint {
+	case "/reports/sales-summary":
+		if p.GroupBy != "day" && p.GroupBy != "month" && p.GroupBy != "year" {
+			return fmt.Errorf("group_by must be day, month or year")
+		}
+	case "/reports/expenses-summary":
+		if p.GroupBy != "" && p.GroupBy != "day" && p.GroupBy != "month" {
+			return fmt.Errorf("group_by must be day or month")
+		}
+	}
+	return nil
+}
+
+// resolveReportDateRange turns a relative range into concrete dates for the
+// day containing now (already in the subscription's timezone).
+func resolveReportDateRange(name string, now time.Time) (from, to string, err error) {
+	const layout = "2006-01-02"
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch name {
+	case "today":
+		return today.Format(layout), today.Format(layout), nil
+	case "yesterday":
+		y := today.AddDate(0, 0, -1)
+		return y.Format(layout), y.Format(layout), nil
+	case "last_7_days":
+		return today.AddDate(0, 0, -7).Format(layout), today.AddDate(0, 0, -1).Format(layout), nil
+	case "last_30_days":
+		return today.AddDate(0, 0, -30).Format(layout), today.AddDate(0, 0, -1).Format(layout), nil
+	case "month_to_date":
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		return first.Format(layout), today.Format(layout), nil
+	case "last_month":
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		return first.AddDate(0, -1, 0).Format(layout), first.AddDate(0, 0, -1).Format(layout), nil
+	case "year_to_date":
+		first := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, today.Location())
+		return first.Format(layout), today.Format(layout), nil
+	default:
+		return "", "", fmt.Errorf("unsupported date_range: %s", name)
+	}
+}
+
+// effectiveReportSubscriptionParams applies DateRange for a run at now.
+func effectiveReportSubscriptionParams(p models.ReportSubscriptionParams, now time.Time) (models.ReportSubscriptionParams, error) {
+	if p.DateRange == "" {
+		return p, nil
+	}
+	from, to, err := resolveReportDateRange(p.DateRange, now)
+	if err != nil {
+		return p, err
+	}
+	p.FromDate, p.ToDate, p.AsOfDate = from, to, to
+	return p, nil
+}
+
+func loadReportSubscriptionLocation(tz string) (*time.Location, error) {
+	if strings.TrimSpace(tz) == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", tz)
+	}
+	return loc, nil
+}
+
+// nextReportSubscriptionRun validates schedule and timezone and returns the
+// next firing after from.
+func nextReportSubscriptionRun(schedule, tz string, from time.Time) (time.Time, error) {
+	cron, err := parseCronSchedule(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := loadReportSubscriptionLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := cron.Next(from, loc)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("schedule never fires")
+	}
+	return next.UTC(), nil
+}
+
+func normalizeReportSubscriptionRecipients(recipients []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		r = strings.TrimSpace(r)
+		key := strings.ToLower(r)
+		if r == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	return out, nil
+}
+
+func (s *ReportSubscriptionService) verifyReportSubscriptionRefs(companyID int, p models.ReportSubscriptionParams) error {
+	checks := []struct {
+		id    *int
+		query string
+		name  string
+	}{
+		{p.LocationID, `SELECT 1 FROM locations WHERE location_id = $1 AND company_id = $2`, "location"},
+		{p.ProductID, `SELECT 1 FROM products WHERE product_id = $1 AND company_id = $2`, "product"},
+		{p.BankAccountID, `SELECT 1 FROM bank_accounts WHERE bank_account_id = $1 AND company_id = $2`, "bank account"},
+	}
+	for _, c := range checks {
+		if c.id == nil {
+			continue
+		}
+		var one int
+		err := s.db.QueryRow(c.query, *c.id, companyID).Scan(&one)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%s not found", c.name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *ReportSubscriptionService) CreateSubscription(companyID, userID int, req *models.CreateReportSubscriptionRequest) (*models.ReportSubscription, error) {
+	endpoint, err := normalizeReportSubscriptionEndpoint(req.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateReportSubscriptionParams(endpoint, req.Params); err != nil {
+		return nil, err
+	}
+	if err := s.verifyReportSubscriptionRefs(companyID, req.Params); err != nil {
+		return nil, err
+	}
+	tz := strings.TrimSpace(req.Timezone)
+	if tz == "" {
+		tz = "UTC"
+	}
+	schedule := strings.TrimSpace(req.Schedule)
+	next, err := nextReportSubscriptionRun(schedule, tz, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := normalizeReportSubscriptionRecipients(req.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+
+	paramsJSON, err := json.Marshal(req.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report params: %w", err)
+	}
+	recipientsJSON, err := json.Marshal(recipients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recipients: %w", err)
+	}
+
+	var id int
+	err = s.db.QueryRow(`
+		INSERT INTO report_subscriptions
+		  (company_id, user_id, name, endpoint, params, schedule, timezone, format, recipients, is_active, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING subscription_id
+	`, companyID, userID, strings.TrimSpace(req.Name), endpoint, paramsJSON, schedule, tz, req.Format,
+		recipientsJSON, active, next).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report subscription: %w", err)
+	}
+	return s.GetSubscription(companyID, userID, id)
+}
+
+func (s *ReportSubscriptionService) UpdateSubscription(companyID, userID, subscriptionID int, req *models.UpdateReportSubscriptionRequest) (*models.ReportSubscription, error) {
+	sub, err := s.GetSubscription(companyID, userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return nil, fmt.Errorf("name is required")
+		}
+		sub.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Endpoint != nil {
+		if sub.Endpoint, err = normalizeReportSubscriptionEndpoint(*req.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+	if req.Params != nil {
+		sub.Params = *req.Params
+	}
+	if req.Schedule != nil {
+		sub.Schedule = strings.TrimSpace(*req.Schedule)
+	}
+	if req.Timezone != nil {
+		sub.Timezone = strings.TrimSpace(*req.Timezone)
+		if sub.Timezone == "" {
+			sub.Timezone = "UTC"
+		}
+	}
+	if req.Format != nil {
+		sub.Format = *req.Format
+	}
+	if req.Recipients != nil {
+		if sub.Recipients, err = normalizeReportSubscriptionRecipients(req.Recipients); err != nil {
+			return nil, err
+		}
+	}
+	if req.IsActive != nil {
+		sub.IsActive = *req.IsActive
+	}
+
+	if err := validateReportSubscriptionParams(sub.Endpoint, sub.Params); err != nil {
+		return nil, err
+	}
+	if err := s.verifyReportSubscriptionRefs(companyID, sub.Params); err != nil {
+		return nil, err
+	}
+	next, err := nextReportSubscriptionRun(sub.Schedule, sub.Timezone, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	paramsJSON, err := json.Marshal(sub.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report params: %w", err)
+	}
+	recipientsJSON, err := json.Marshal(sub.Recipients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recipients: %w", err)
+	}
+	_, err = s.db.Exec(`
+		UPDATE report_subscriptions
+		SET name = $3, endpoint = $4, params = $5, schedule = $6, timezone = $7, format = $8,
+		    recipients = $9, is_active = $10, next_run_at = $11, updated_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = $1 AND company_id = $2
+	`, subscriptionID, companyID, sub.Name, sub.Endpoint, paramsJSON, sub.Schedule, sub.Timezone, sub.Format,
+		recipientsJSON, sub.IsActive, next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update report subscription: %w", err)
+	}
+	return s.GetSubscription(companyID, userID, subscriptionID)
+}
+
+func (s *ReportSubscriptionService) DeleteSubscription(companyID, userID, subscriptionID int) error {
+	res, err := s.db.Exec(`
+		DELETE FROM report_subscriptions
+		WHERE subscription_id = $1 AND company_id = $2 AND user_id = $3
+	`, subscriptionID, companyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete report subscription: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("report subscription not found")
+	}
+	return nil
+}
+
+const reportSubscriptionSelect = `
+	SELECT subscription_id, company_id, user_id, name, endpoint, params, schedule, timezone, format,
+	       recipients, is_active, next_run_at, last_run_at, last_status, created_at, updated_at
+	FROM report_subscriptions
+`
+
+func scanReportSubscription(row interface{ Scan(...interface{}) error }) (*models.ReportSubscription, error) {
+	var (
+		sub        models.ReportSubscription
+		params     []byte
+		recipients []byte
+	)
+	err := row.Scan(&sub.SubscriptionID, &sub.CompanyID, &sub.UserID, &sub.Name, &sub.Endpoint, &params,
+		&sub.Schedule, &sub.Timezone, &sub.Format, &recipients, &sub.IsActive, &sub.NextRunAt, &sub.LastRunAt,
+		&sub.LastStatus, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &sub.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode report params: %w", err)
+		}
+	}
+	sub.Recipients = []string{}
+	if len(recipients) > 0 {
+		if err := json.Unmarshal(recipients, &sub.Recipients); err != nil {
+			return nil, fmt.Errorf("failed to decode recipients: %w", err)
+		}
+	}
+	return &sub, nil
+}
+
+// GetSubscription returns one of the user's own subscriptions.
+func (s *ReportSubscriptionService) GetSubscription(companyID, userID, subscriptionID int) (*models.ReportSubscription, error) {
+	sub, err := scanReportSubscription(s.db.QueryRow(reportSubscriptionSelect+`
+		WHERE subscription_id = $1 AND company_id = $2 AND user_id = $3
+	`, subscriptionID, companyID, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("report subscription not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *ReportSubscriptionService) ListSubscriptions(companyID, userID int) ([]models.ReportSubscription, error) {
+	rows, err := s.db.Query(reportSubscriptionSelect+`
+		WHERE company_id = $1 AND user_id = $2
+		ORDER BY name, subscription_id
+	`, companyID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.ReportSubscription{}
+	for rows.Next() {
+		sub, err := scanReportSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// ListDeliveries returns the delivery history of one of the user's
+// subscriptions, newest first.
+func (s *ReportSubscriptionService) ListDeliveries(companyID, userID, subscriptionID int, filter models.ReportSubscriptionDeliveryFilter) ([]models.ReportSubscriptionDelivery, error) {
+	if _, err := s.GetSubscription(companyID, userID, subscriptionID); err != nil {
+		return nil, err
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT delivery_id, subscription_id, scheduled_for, status, recipients, failed_recipients,
+		       row_count, file_name, file_size, error, started_at, finished_at
+		FROM report_subscription_deliveries
+		WHERE subscription_id = $1 AND company_id = $2 AND ($3::text = '' OR status = $3)
+		ORDER BY delivery_id DESC
+		LIMIT $4
+	`, subscriptionID, companyID, filter.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.ReportSubscriptionDelivery{}
+	for rows.Next() {
+		var (
+			d                 models.ReportSubscriptionDelivery
+			recipients, fails []byte
+		)
+		if err := rows.Scan(&d.DeliveryID, &d.SubscriptionID, &d.ScheduledFor, &d.Status, &recipients, &fails,
+			&d.RowCount, &d.FileName, &d.FileSize, &d.Error, &d.StartedAt, &d.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan report delivery: %w", err)
+		}
+		_ = json.Unmarshal(recipients, &d.Recipients)
+		_ = json.Unmarshal(fails, &d.FailedRecipients)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RunNow makes an active subscription due immediately. The regular schedule
+// resumes after the delivery.
+func (s *ReportSubscriptionService) RunNow(companyID, userID, subscriptionID int) (*models.ReportSubscription, error) {
+	sub, err := s.GetSubscription(companyID, userID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.IsActive {
+		return nil, fmt.Errorf("report subscription is paused")
+	}
+	if _, err := s.db.Exec(`
+		UPDATE report_subscriptions SET next_run_at = NOW(), updated_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = $1 AND company_id = $2
+	`, subscriptionID, companyID); err != nil {
+		return nil, fmt.Errorf("failed to queue report subscription: %w", err)
+	}
+	select {
+	case reportSubscriptionWake <- struct{}{}:
+	default:
+	}
+	return s.GetSubscription(companyID, userID, subscriptionID)
+}
+
+var reportSubscriptionWake = make(chan struct{}, 1)
+
+// reportSubscriberAccess reports why the subscriber may not receive the report
+// right now, or "" if they may. It reflects the user's current state, so
+// deactivation, a company move or a revoked permission stops delivery.
+func reportSubscriberAccess(db *sql.DB, sub *models.ReportSubscription) (string, error) {
+	var (
+		companyID      int
+		active, locked sql.NullBool
+		deleted        sql.NullBool
+	)
+	err := db.QueryRow(`
+		SELECT company_id, is_active, is_locked, is_deleted FROM users WHERE user_id = $1
+	`, sub.UserID).Scan(&companyID, &active, &locked, &deleted)
+	if err == sql.ErrNoRows {
+		return "subscriber no longer exists", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load subscriber: %w", err)
+	}
+	switch {
+	case deleted.Bool:
+		return "subscriber no longer exists", nil
+	case !active.Bool:
+		return "subscriber is inactive", nil
+	case locked.Bool:
+		return "subscriber is locked", nil
+	case companyID != sub.CompanyID:
+		return "subscriber no longer belongs to this company", nil
+	}
+	ok, err := (&PermissionService{db: db}).UserCan(sub.UserID, 0, reportSubscriptionPermission)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "subscriber no longer has " + reportSubscriptionPermission + " permission", nil
+	}
+	if _, err := (&ReportsService{db: db}).ReportLocations(sub.CompanyID, sub.UserID, 0, sub.Params.LocationID); err != nil {
+		if err.Error() == "location access denied" {
+			return "subscriber no longer has access to the report location", nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// reportSubscriptionOutcome is what a delivery attempt records.
+type reportSubscriptionOutcome struct {
+	status   string
+	rowCount *int
+	fileName string
+	fileSize int
+	failed   []string
+	err      string
+}
+
+// deliverReportSubscription checks access, renders the report for runAt and
+// emails it to every recipient.
+func deliverReportSubscription(db *sql.DB, sub *models.ReportSubscription, runAt time.Time) reportSubscriptionOutcome {
+	reason, err := reportSubscriberAccess(db, sub)
+	if err != nil {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: err.Error()}
+	}
+	if reason != "" {
+		return reportSubscriptionOutcome{status: models.ReportDeliverySkipped, err: reason}
+	}
+
+	run, ok := reportSubscriptionEndpoints[sub.Endpoint]
+	if !ok {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: "unsupported report endpoint: " + sub.Endpoint}
+	}
+	format, ok := reportSubscriptionFormats[sub.Format]
+	if !ok {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: "unsupported format: " + sub.Format}
+	}
+	loc, err := loadReportSubscriptionLocation(sub.Timezone)
+	if err != nil {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: err.Error()}
+	}
+	localRun := runAt.In(loc)
+	params, err := effectiveReportSubscriptionParams(sub.Params, localRun)
+	if err != nil {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: err.Error()}
+	}
+
+	reports := &ReportsService{db: db}
+	// Restricted subscribers only ever receive their own locations.
+	if params.LocationIDs, err = reports.ReportLocations(sub.CompanyID, sub.UserID, 0, params.LocationID); err != nil {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: err.Error()}
+	}
+	data, err := run(reports, sub.CompanyID, params)
+	if err != nil {
+		return reportSubscriptionOutcome{status: models.ReportDeliveryFailed, err: err.Error()}
+	}
+	out := reportSubscriptionOutcome{}
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		n := v.Len()
+		out.rowCount = &n
+	}
+	content, err := format.generate(sub.Endpoint, data)
+	if err != nil {
+		out.status, out.err = models.ReportDeliveryFailed, "failed to generate report: "+err.Error()
+		return out
+	}
+	out.fileName = utils.ReportExportFilename(sub.Endpoint, sub.Format)
+	out.fileSize = len(content)
+
+	subject := fmt.Sprintf("%s - %s", sub.Name, localRun.Format("2006-01-02"))
+	body := reportSubscriptionEmailBody(sub, params, localRun)
+	attachment := []utils.EmailAttachment{{Filename: out.fileName, ContentType: format.contentType, Content: content}}
+	var errs []string
+	for _, to := range sub.Recipients {
+		if err := utils.SendEmailWithAttachments(to, subject, body, attachment); err != nil {
+			out.failed = append(out.failed, to)
+			errs = append(errs, fmt.Sprintf("%s: %v", to, err))
+		}
+	}
+	out.status = models.ReportDeliverySucceeded
+	if len(errs) > 0 {
+		out.status = models.ReportDeliveryFailed
+		out.err = fmt.Sprintf("failed to send to %d of %d recipients: %s", len(errs), len(sub.Recipients), strings.Join(errs, "; "))
+	}
+	return out
+}
+
+func reportSubscriptionEmailBody(sub *models.ReportSubscription, p models.ReportSubscriptionParams, runAt time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Attached is your scheduled report \"%s\".\n\n", sub.Name)
+	fmt.Fprintf(&b, "Report: %s\n", sub.Endpoint)
+	if p.FromDate != "" || p.ToDate != "" {
+		fmt.Fprintf(&b, "Period: %s to %s\n", p.FromDate, p.ToDate)
+	} else if p.AsOfDate != "" {
+		fmt.Fprintf(&b, "As of: %s\n", p.AsOfDate)
+	}
+	fmt.Fprintf(&b, "Generated: %s\n\n", runAt.Format("2006-01-02 15:04 MST"))
+	b.WriteString("You receive this email because of a report subscription. Its owner can pause or change it under report subscriptions.\n")
+	return b.String()
+}
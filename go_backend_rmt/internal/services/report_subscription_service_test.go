@@ -0,0 +1,127 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"erp-backend/internal/models"
+)
+
+func TestNormalizeReportSubscriptionEndpoint(t *testing.T) {
+	for _, in := range []string{"/reports/daily-cash", "/api/v1/reports/daily-cash?format=pdf", "daily-cash", "/reports/daily-cash/"} {
+		got, err := normalizeReportSubscriptionEndpoint(in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", in, err)
+		}
+		if got != "/reports/daily-cash" {
+			t.Fatalf("%q: got %q", in, got)
+		}
+	}
+	if _, err := normalizeReportSubscriptionEndpoint("/reports/../users"); err == nil {
+		t.Fatalf("expected unknown endpoint to be rejected")
+	}
+}
+
+func TestValidateReportSubscriptionParams(t *testing.T) {
+	if err := validateReportSubscriptionParams("/reports/sales-summary", models.ReportSubscriptionParams{}); err == nil {
+		t.Fatalf("expected sales summary without group_by to fail")
+	}
+	if err := validateReportSubscriptionParams("/reports/sales-summary", models.ReportSubscriptionParams{GroupBy: "day", DateRange: "yesterday"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := validateReportSubscriptionParams("/reports/daily-cash", models.ReportSubscriptionParams{FromDate: "2026-02-01", ToDate: "2026-01-01"}); err == nil {
+		t.Fatalf("expected reversed range to fail")
+	}
+	bad := 0
+	if err := validateReportSubscriptionParams("/reports/outstanding", models.ReportSubscriptionParams{LocationID: &bad}); err == nil {
+		t.Fatalf("expected non-positive location to fail")
+	}
+}
+
+func TestResolveReportDateRange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	cases := map[string][2]string{
+		"today":         {"2026-03-01", "2026-03-01"},
+		"yesterday":     {"2026-02-28", "2026-02-28"},
+		"last_7_days":   {"2026-02-22", "2026-02-28"},
+		"month_to_date": {"2026-03-01", "2026-03-01"},
+		"last_month":    {"2026-02-01", "2026-02-28"},
+		"year_to_date":  {"2026-01-01", "2026-03-01"},
+	}
+	for name, want := range cases {
+		from, to, err := resolveReportDateRange(name, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if from != want[0] || to != want[1] {
+			t.Fatalf("%s: got %s..%s want %s..%s", name, from, to, want[0], want[1])
+		}
+	}
+
+	p, err := effectiveReportSubscriptionParams(models.ReportSubscriptionParams{DateRange: "yesterday", FromDate: "2020-01-01"}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.FromDate != "2026-02-28" || p.ToDate != "2026-02-28" || p.AsOfDate != "2026-02-28" {
+		t.Fatalf("expected date range to override saved dates, got %+v", p)
+	}
+}
+
+func TestNormalizeReportSubscriptionRecipientsDedupes(t *testing.T) {
+	got, err := normalizeReportSubscriptionRecipients([]string{" a@example.com", "A@example.com", "b@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "a@example.com" || got[1] != "b@example.com" {
+		t.Fatalf("unexpected recipients %v", got)
+	}
+}
+
+func TestDeliverReportSubscriptionSkipsWhenPermissionRevoked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT company_id, is_active, is_locked, is_deleted FROM users")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"company_id", "is_active", "is_locked", "is_deleted"}).AddRow(1, true, false, false))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+		WithArgs(9, 0, "VIEW_REPORTS").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	sub := &models.ReportSubscription{
+		SubscriptionID: 4, CompanyID: 1, UserID: 9, Name: "Daily cash",
+		Endpoint: "/reports/daily-cash", Format: "pdf", Timezone: "UTC",
+		Recipients: []string{"boss@example.com"},
+	}
+	out := deliverReportSubscription(db, sub, time.Now())
+	if out.status != models.ReportDeliverySkipped {
+		t.Fatalf("expected skipped delivery, got %+v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeliverReportSubscriptionSkipsMovedUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"company_id", "is_active", "is_locked", "is_deleted"}).AddRow(2, true, false, false))
+
+	sub := &models.ReportSubscription{CompanyID: 1, UserID: 9, Endpoint: "/reports/daily-cash", Format: "pdf"}
+	out := deliverReportSubscription(db, sub, time.Now())
+	if out.status != models.ReportDeliverySkipped {
+		t.Fatalf("expected skipped delivery, got %+v", out)
+	}
+}
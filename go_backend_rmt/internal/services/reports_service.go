@@ -6,6 +6,8 @@ import (
 
 	"erp-backend/internal/database"
 	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
 )
 
 // ReportsService provides report related operations
@@ -21,6 +23,26 @@ func NewReportsService() *ReportsService {
 	return &ReportsService{db: database.GetDB()}
 }
 
+// ReportLocations resolves the locations a user's report covers. Users
+// limited to some locations without REPORT_ALL_LOCATIONS see all of their
+// locations together, or the requested one when it is among them; nil
+// means every location.
+func (s *ReportsService) ReportLocations(companyID, userID, roleID int, requested *int) ([]int, error) {
+	allowed, err := userReportLocations(s.db, companyID, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return scopeReportLocations(allowed, requested)
+}
+
+// reportLocationsArg binds a location filter; nil matches every location.
+func reportLocationsArg(locationIDs []int) interface{} {
+	if len(locationIDs) == 0 {
+		return nil
+	}
+	return pq.Array(locationIDs)
+}
+
 // GetSalesSummary returns total sales grouped by period
 func (s *ReportsService) GetSalesSummary(companyID int, fromDate, toDate, groupBy string) ([]models.SalesSummary, error) {
 	var dateFormat string
@@ -78,7 +100,7 @@ func (s *ReportsService) GetSalesSummary(companyID int, fromDate, toDate, groupB
 }
 
 // GetStockSummary returns stock levels and values
-func (s *ReportsService) GetStockSummary(companyID int, locationID, productID *int) ([]models.StockSummary, error) {
+func (s *ReportsService) GetStockSummary(companyID int, locationIDs []int, productID *int) ([]models.StockSummary, error) {
 	method, err := loadCompanyCostingMethod(s.db, companyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inventory costing method: %w", err)
@@ -146,13 +168,13 @@ func (s *ReportsService) GetStockSummary(companyID int, locationID, productID *i
 
 	args := []interface{}{companyID}
 	idx := 2
-	if locationID != nil {
-		locationClause := " AND sv.location_id = $%d"
+	if len(locationIDs) > 0 {
+		locationClause := " AND sv.location_id = ANY($%d)"
 		if method == costingMethodFIFO {
-			locationClause = " AND location_id = $%d"
+			locationClause = " AND location_id = ANY($%d)"
 		}
 		query += fmt.Sprintf(locationClause, idx)
-		args = append(args, *locationID)
+		args = append(args, pq.Array(locationIDs))
 		idx++
 	}
 	if productID != nil {
@@ -426,7 +448,7 @@ func (s *ReportsService) GetTaxReviewReport(companyID int, fromDate, toDate stri
 // The following report methods use simplified aggregations intended for MVP reporting.
 
 // GetItemMovement returns stock movement details for products
-func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetItemMovement(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		SELECT p.product_id,
 		       p.name AS product_name,
@@ -449,7 +471,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 			JOIN purchases pur ON pur.purchase_id = pd.purchase_id AND pur.is_deleted = FALSE
 			JOIN locations l ON l.location_id = pur.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR pur.location_id = $2)
+			  AND ($2::int[] IS NULL OR pur.location_id = ANY($2))
 			  AND ($3::date IS NULL OR pur.purchase_date >= $3)
 			  AND ($4::date IS NULL OR pur.purchase_date <= $4)
 			GROUP BY pd.product_id
@@ -460,7 +482,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 			JOIN purchase_returns pr ON pr.return_id = prd.return_id AND pr.is_deleted = FALSE
 			JOIN locations l ON l.location_id = pr.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR pr.location_id = $2)
+			  AND ($2::int[] IS NULL OR pr.location_id = ANY($2))
 			  AND ($3::date IS NULL OR pr.return_date >= $3)
 			  AND ($4::date IS NULL OR pr.return_date <= $4)
 			GROUP BY prd.product_id
@@ -471,7 +493,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 			JOIN sales s ON s.sale_id = sd.sale_id AND s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE
 			JOIN locations l ON l.location_id = s.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR s.location_id = $2)
+			  AND ($2::int[] IS NULL OR s.location_id = ANY($2))
 			  AND ($3::date IS NULL OR s.sale_date >= $3)
 			  AND ($4::date IS NULL OR s.sale_date <= $4)
 			GROUP BY sd.product_id
@@ -482,7 +504,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 			JOIN sale_returns sr ON sr.return_id = srd.return_id AND sr.is_deleted = FALSE
 			JOIN locations l ON l.location_id = sr.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR sr.location_id = $2)
+			  AND ($2::int[] IS NULL OR sr.location_id = ANY($2))
 			  AND ($3::date IS NULL OR sr.return_date >= $3)
 			  AND ($4::date IS NULL OR sr.return_date <= $4)
 			GROUP BY srd.product_id
@@ -492,7 +514,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 			FROM stock_adjustments sa
 			JOIN locations l ON l.location_id = sa.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR sa.location_id = $2)
+			  AND ($2::int[] IS NULL OR sa.location_id = ANY($2))
 			  AND ($3::date IS NULL OR sa.created_at::date >= $3)
 			  AND ($4::date IS NULL OR sa.created_at::date <= $4)
 			GROUP BY sa.product_id
@@ -506,10 +528,7 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 		ORDER BY net_movement DESC, product_name
 	`
 
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -522,15 +541,32 @@ func (s *ReportsService) GetItemMovement(companyID int, locationID *int, fromDat
 	return queryToMaps(s.db, query, companyID, locArg, fromArg, toArg)
 }
 
-// GetValuationReport returns inventory valuation information
-func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]map[string]interface{}, error) {
+// GetValuationReport returns inventory valuation information. Vendor-owned
+// consignment-in lots are not our stock and are left out of quantity and
+// value; consignment-out stock at dealer locations is still ours and stays in.
+func (s *ReportsService) GetValuationReport(companyID int, locationIDs []int) ([]map[string]interface{}, error) {
 	method, err := loadCompanyCostingMethod(s.db, companyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inventory costing method: %w", err)
 	}
 
 	query := `
-		WITH valuation AS (
+		WITH consignment_stock AS (
+			SELECT
+				sl.product_id,
+				sl.location_id,
+				COALESCE(SUM(sl.remaining_quantity) FILTER (WHERE sl.ownership_type = 'CONSIGNMENT_IN'), 0)::float8 AS vendor_quantity,
+				COALESCE(SUM(sl.remaining_quantity * sl.cost_price) FILTER (WHERE sl.ownership_type = 'CONSIGNMENT_IN'), 0)::float8 AS vendor_value,
+				COALESCE(SUM(sl.remaining_quantity) FILTER (WHERE sl.ownership_type = 'CONSIGNMENT_OUT'), 0)::float8 AS consigned_out_quantity
+			FROM stock_lots sl
+			JOIN locations l ON l.location_id = sl.location_id
+			WHERE l.company_id = $1
+			  AND ($2::int[] IS NULL OR sl.location_id = ANY($2))
+			  AND sl.ownership_type <> 'OWNED'
+			  AND sl.remaining_quantity > 0
+			GROUP BY sl.product_id, sl.location_id
+		),
+		variant_totals AS (
 			SELECT
 				sv.product_id,
 				sv.location_id,
@@ -539,13 +575,28 @@ func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]m
 			FROM stock_variants sv
 			JOIN locations l ON l.location_id = sv.location_id
 			WHERE l.company_id = $1
-			  AND ($2::int IS NULL OR sv.location_id = $2)
+			  AND ($2::int[] IS NULL OR sv.location_id = ANY($2))
 			GROUP BY sv.product_id, sv.location_id
+		),
+		valuation AS (
+			SELECT
+				vt.product_id,
+				vt.location_id,
+				(vt.quantity - COALESCE(cs.vendor_quantity, 0))::float8 AS quantity,
+				(vt.stock_value - COALESCE(cs.vendor_value, 0))::float8 AS stock_value,
+				COALESCE(cs.vendor_quantity, 0)::float8 AS vendor_owned_quantity,
+				COALESCE(cs.consigned_out_quantity, 0)::float8 AS consigned_out_quantity
+			FROM variant_totals vt
+			LEFT JOIN consignment_stock cs
+				ON cs.product_id = vt.product_id
+			   AND cs.location_id = vt.location_id
 		)
 		SELECT p.product_id,
 		       p.name AS product_name,
 		       COALESCE(SUM(v.quantity), 0)::float8 AS quantity,
-		       COALESCE(SUM(v.stock_value), 0)::float8 AS stock_value
+		       COALESCE(SUM(v.stock_value), 0)::float8 AS stock_value,
+		       COALESCE(SUM(v.vendor_owned_quantity), 0)::float8 AS vendor_owned_quantity,
+		       COALESCE(SUM(v.consigned_out_quantity), 0)::float8 AS consigned_out_quantity
 		FROM products p
 		LEFT JOIN valuation v ON v.product_id = p.product_id
 		WHERE p.company_id = $1 AND p.is_deleted = FALSE
@@ -564,7 +615,7 @@ func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]m
 				FROM stock_variants sv
 				JOIN locations l ON l.location_id = sv.location_id
 				WHERE l.company_id = $1
-				  AND ($2::int IS NULL OR sv.location_id = $2)
+				  AND ($2::int[] IS NULL OR sv.location_id = ANY($2))
 			),
 			lot_stock AS (
 				SELECT
@@ -572,22 +623,26 @@ func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]m
 					sl.location_id,
 					sl.barcode_id,
 					COALESCE(SUM(sl.remaining_quantity), 0)::float8 AS lot_quantity,
-					COALESCE(SUM(sl.remaining_quantity * sl.cost_price), 0)::float8 AS lot_value
+					COALESCE(SUM(sl.remaining_quantity * sl.cost_price) FILTER (WHERE sl.ownership_type <> 'CONSIGNMENT_IN'), 0)::float8 AS lot_value,
+					COALESCE(SUM(sl.remaining_quantity) FILTER (WHERE sl.ownership_type = 'CONSIGNMENT_IN'), 0)::float8 AS vendor_quantity,
+					COALESCE(SUM(sl.remaining_quantity) FILTER (WHERE sl.ownership_type = 'CONSIGNMENT_OUT'), 0)::float8 AS consigned_out_quantity
 				FROM stock_lots sl
 				JOIN locations l ON l.location_id = sl.location_id
 				WHERE l.company_id = $1
-				  AND ($2::int IS NULL OR sl.location_id = $2)
+				  AND ($2::int[] IS NULL OR sl.location_id = ANY($2))
 				GROUP BY sl.product_id, sl.location_id, sl.barcode_id
 			),
 			variant_valuation AS (
 				SELECT
 					vs.product_id,
 					vs.location_id,
-					vs.quantity,
+					(vs.quantity - COALESCE(ls.vendor_quantity, 0))::float8 AS quantity,
 					(
 						COALESCE(ls.lot_value, 0) +
 						((vs.quantity - COALESCE(ls.lot_quantity, 0)) * vs.average_cost)
-					)::float8 AS stock_value
+					)::float8 AS stock_value,
+					COALESCE(ls.vendor_quantity, 0)::float8 AS vendor_owned_quantity,
+					COALESCE(ls.consigned_out_quantity, 0)::float8 AS consigned_out_quantity
 				FROM variant_stock vs
 				LEFT JOIN lot_stock ls
 					ON ls.product_id = vs.product_id
@@ -599,14 +654,18 @@ func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]m
 					product_id,
 					location_id,
 					COALESCE(SUM(quantity), 0)::float8 AS quantity,
-					COALESCE(SUM(stock_value), 0)::float8 AS stock_value
+					COALESCE(SUM(stock_value), 0)::float8 AS stock_value,
+					COALESCE(SUM(vendor_owned_quantity), 0)::float8 AS vendor_owned_quantity,
+					COALESCE(SUM(consigned_out_quantity), 0)::float8 AS consigned_out_quantity
 				FROM variant_valuation
 				GROUP BY product_id, location_id
 			)
 			SELECT p.product_id,
 			       p.name AS product_name,
 			       COALESCE(SUM(v.quantity), 0)::float8 AS quantity,
-			       COALESCE(SUM(v.stock_value), 0)::float8 AS stock_value
+			       COALESCE(SUM(v.stock_value), 0)::float8 AS stock_value,
+			       COALESCE(SUM(v.vendor_owned_quantity), 0)::float8 AS vendor_owned_quantity,
+			       COALESCE(SUM(v.consigned_out_quantity), 0)::float8 AS consigned_out_quantity
 			FROM products p
 			LEFT JOIN valuation v ON v.product_id = p.product_id
 			WHERE p.company_id = $1 AND p.is_deleted = FALSE
@@ -614,15 +673,12 @@ func (s *ReportsService) GetValuationReport(companyID int, locationID *int) ([]m
 			ORDER BY stock_value DESC, product_name
 		`
 	}
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	return queryToMaps(s.db, query, companyID, locArg)
 }
 
 // GetPurchaseVsReturns compares purchases against returns
-func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		WITH purchases AS (
 			SELECT
@@ -631,7 +687,7 @@ func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationID *int, fr
 			FROM purchases p
 			JOIN locations l ON l.location_id = p.location_id
 			WHERE l.company_id = $1 AND p.is_deleted = FALSE
-			  AND ($2::int IS NULL OR p.location_id = $2)
+			  AND ($2::int[] IS NULL OR p.location_id = ANY($2))
 			  AND ($3::date IS NULL OR p.purchase_date >= $3)
 			  AND ($4::date IS NULL OR p.purchase_date <= $4)
 		),
@@ -641,7 +697,7 @@ func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationID *int, fr
 			FROM purchase_returns pr
 			JOIN locations l ON l.location_id = pr.location_id
 			WHERE l.company_id = $1 AND pr.is_deleted = FALSE
-			  AND ($2::int IS NULL OR pr.location_id = $2)
+			  AND ($2::int[] IS NULL OR pr.location_id = ANY($2))
 			  AND ($3::date IS NULL OR pr.return_date >= $3)
 			  AND ($4::date IS NULL OR pr.return_date <= $4)
 		)
@@ -652,10 +708,7 @@ func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationID *int, fr
 			purchases.purchases_outstanding
 		FROM purchases, returns
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -668,7 +721,7 @@ func (s *ReportsService) GetPurchaseVsReturns(companyID int, locationID *int, fr
 }
 
 // GetSupplierReport aggregates supplier performance metrics
-func (s *ReportsService) GetSupplierReport(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetSupplierReport(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		WITH purchases_by_supplier AS (
 			SELECT p.supplier_id,
@@ -678,7 +731,7 @@ func (s *ReportsService) GetSupplierReport(companyID int, locationID *int, fromD
 			FROM purchases p
 			JOIN locations l ON l.location_id = p.location_id
 			WHERE l.company_id = $1 AND p.is_deleted = FALSE
-			  AND ($2::int IS NULL OR p.location_id = $2)
+			  AND ($2::int[] IS NULL OR p.location_id = ANY($2))
 			  AND ($3::date IS NULL OR p.purchase_date >= $3)
 			  AND ($4::date IS NULL OR p.purchase_date <= $4)
 			GROUP BY p.supplier_id
@@ -689,7 +742,7 @@ func (s *ReportsService) GetSupplierReport(companyID int, locationID *int, fromD
 			FROM purchase_returns pr
 			JOIN locations l ON l.location_id = pr.location_id
 			WHERE l.company_id = $1 AND pr.is_deleted = FALSE
-			  AND ($2::int IS NULL OR pr.location_id = $2)
+			  AND ($2::int[] IS NULL OR pr.location_id = ANY($2))
 			  AND ($3::date IS NULL OR pr.return_date >= $3)
 			  AND ($4::date IS NULL OR pr.return_date <= $4)
 			GROUP BY pr.supplier_id
@@ -706,10 +759,7 @@ func (s *ReportsService) GetSupplierReport(companyID int, locationID *int, fromD
 		WHERE s.company_id = $1
 		ORDER BY purchases_total DESC, supplier_name
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -722,7 +772,58 @@ func (s *ReportsService) GetSupplierReport(companyID int, locationID *int, fromD
 }
 
 // GetDailyCashReport summarizes daily cash activity
-func (s *ReportsService) GetDailyCashReport(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+// GetInvoiceMatchVarianceReport lists supplier invoice lines whose three-way
+// match found a quantity or price difference.
+func (s *ReportsService) GetInvoiceMatchVarianceReport(companyID int, locationIDs []int, fromDate, toDate, status string) ([]map[string]interface{}, error) {
+	query := `
+		SELECT m.match_id,
+		       p.purchase_id,
+		       p.purchase_number,
+		       sup.name AS supplier_name,
+		       m.supplier_invoice_number,
+		       m.invoice_date,
+		       m.status AS match_status,
+		       pr.name AS product_name,
+		       l.ordered_quantity::float8,
+		       l.received_quantity::float8,
+		       l.invoiced_quantity::float8,
+		       l.quantity_variance::float8,
+		       l.quantity_variance_percent::float8,
+		       l.ordered_unit_price::float8,
+		       l.invoiced_unit_price::float8,
+		       l.price_variance_percent::float8,
+		       l.price_variance_amount::float8,
+		       l.status AS line_status
+		FROM supplier_invoice_match_lines l
+		JOIN supplier_invoice_matches m ON m.match_id = l.match_id
+		JOIN purchases p ON p.purchase_id = m.purchase_id
+		JOIN suppliers sup ON sup.supplier_id = m.supplier_id
+		JOIN products pr ON pr.product_id = l.product_id
+		WHERE m.company_id = $1
+		  AND (l.status <> 'MATCHED' OR l.price_variance_amount <> 0)
+		  AND ($2::int[] IS NULL OR p.location_id = ANY($2))
+		  AND ($3::date IS NULL OR m.invoice_date >= $3)
+		  AND ($4::date IS NULL OR m.invoice_date <= $4)
+		  AND ($5::text IS NULL OR m.status = UPPER($5))
+		ORDER BY m.invoice_date DESC, m.match_id, l.purchase_detail_id
+	`
+	locArg := reportLocationsArg(locationIDs)
+	var fromArg interface{}
+	if fromDate != "" {
+		fromArg = fromDate
+	}
+	var toArg interface{}
+	if toDate != "" {
+		toArg = toDate
+	}
+	var statusArg interface{}
+	if status != "" {
+		statusArg = status
+	}
+	return queryToMaps(s.db, query, companyID, locArg, fromArg, toArg, statusArg)
+}
+
+func (s *ReportsService) GetDailyCashReport(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		SELECT cr.date,
 		       cr.location_id,
@@ -736,15 +837,12 @@ func (s *ReportsService) GetDailyCashReport(companyID int, locationID *int, from
 		FROM cash_register cr
 		JOIN locations l ON l.location_id = cr.location_id
 		WHERE l.company_id = $1
-		  AND ($2::int IS NULL OR cr.location_id = $2)
+		  AND ($2::int[] IS NULL OR cr.location_id = ANY($2))
 		  AND ($3::date IS NULL OR cr.date >= $3)
 		  AND ($4::date IS NULL OR cr.date <= $4)
 		ORDER BY cr.date DESC, cr.location_id
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -883,14 +981,14 @@ func (s *ReportsService) GetReconciliationSummaryReport(companyID int, bankAccou
 }
 
 // GetIncomeExpenseReport returns income vs expense details
-func (s *ReportsService) GetIncomeExpenseReport(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetIncomeExpenseReport(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		WITH sales_by_day AS (
 			SELECT s.sale_date AS day, COALESCE(SUM(s.total_amount),0)::float8 AS sales_total
 			FROM sales s
 			JOIN locations l ON l.location_id = s.location_id
 			WHERE l.company_id = $1 AND s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE
-			  AND ($2::int IS NULL OR s.location_id = $2)
+			  AND ($2::int[] IS NULL OR s.location_id = ANY($2))
 			  AND ($3::date IS NULL OR s.sale_date >= $3)
 			  AND ($4::date IS NULL OR s.sale_date <= $4)
 			GROUP BY s.sale_date
@@ -900,7 +998,7 @@ func (s *ReportsService) GetIncomeExpenseReport(companyID int, locationID *int,
 			FROM expenses e
 			JOIN locations l ON l.location_id = e.location_id
 			WHERE l.company_id = $1 AND e.is_deleted = FALSE
-			  AND ($2::int IS NULL OR e.location_id = $2)
+			  AND ($2::int[] IS NULL OR e.location_id = ANY($2))
 			  AND ($3::date IS NULL OR e.expense_date >= $3)
 			  AND ($4::date IS NULL OR e.expense_date <= $4)
 			GROUP BY e.expense_date
@@ -913,10 +1011,7 @@ func (s *ReportsService) GetIncomeExpenseReport(companyID int, locationID *int,
 		FULL OUTER JOIN expenses_by_day e ON e.day = s.day
 		ORDER BY day DESC
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -928,11 +1023,23 @@ func (s *ReportsService) GetIncomeExpenseReport(companyID int, locationID *int,
 	return queryToMaps(s.db, query, companyID, locArg, fromArg, toArg)
 }
 
-// GetGeneralLedger returns general ledger entries
-func (s *ReportsService) GetGeneralLedger(companyID int, fromDate, toDate string, limit int) ([]map[string]interface{}, error) {
+// GetGeneralLedger returns general ledger entries, optionally narrowed to or
+// ordered by analytic dimension values.
+func (s *ReportsService) GetGeneralLedger(companyID int, fromDate, toDate string, limit int, dims models.LedgerDimensionFilter) ([]map[string]interface{}, error) {
 	if limit <= 0 || limit > 5000 {
 		limit = 500
 	}
+	return s.generalLedger(companyID, fromDate, toDate, limit, dims)
+}
+
+// generalLedger is GetGeneralLedger without the interactive row cap; background
+// report jobs pass their own limit.
+func (s *ReportsService) generalLedger(companyID int, fromDate, toDate string, limit int, dims models.LedgerDimensionFilter) ([]map[string]interface{}, error) {
+	dim := compileLedgerDimensionFilter(dims, 5)
+	orderBy := "le.date DESC, le.entry_id DESC"
+	if dim.columns != "" {
+		orderBy = "gv.code NULLS LAST, " + orderBy
+	}
 	query := `
 		SELECT le.entry_id,
 		       le.date,
@@ -946,7 +1053,12 @@ func (s *ReportsService) GetGeneralLedger(companyID int, fromDate, toDate string
 		       le.voucher_id,
 		       le.transaction_type,
 		       le.transaction_id,
-		       COALESCE(v.reference, s.sale_number, p.purchase_number, c.collection_number, pay.payment_number, e.expense_number) AS source_number
+		       COALESCE(v.reference, s.sale_number, p.purchase_number, c.collection_number, pay.payment_number, e.expense_number) AS source_number,
+		       (SELECT string_agg(ad.name || ': ' || av.name, ', ' ORDER BY ad.code)
+		        FROM ledger_entry_dimensions led
+		        JOIN analytic_dimensions ad ON ad.dimension_id = led.dimension_id
+		        JOIN analytic_dimension_values av ON av.value_id = led.value_id
+		        WHERE led.entry_id = le.entry_id) AS dimensions` + dim.columns + `
 		FROM ledger_entries le
 		JOIN chart_of_accounts coa ON le.account_id = coa.account_id
 		LEFT JOIN vouchers v ON v.voucher_id = le.voucher_id
@@ -954,11 +1066,11 @@ func (s *ReportsService) GetGeneralLedger(companyID int, fromDate, toDate string
 		LEFT JOIN purchases p ON le.transaction_type = 'purchase' AND p.purchase_id = le.transaction_id
 		LEFT JOIN collections c ON le.transaction_type = 'collection' AND c.collection_id = le.transaction_id
 		LEFT JOIN payments pay ON le.transaction_type = 'payment' AND pay.payment_id = le.transaction_id
-		LEFT JOIN expenses e ON le.transaction_type = 'expense' AND e.expense_id = le.transaction_id
+		LEFT JOIN expenses e ON le.transaction_type = 'expense' AND e.expense_id = le.transaction_id` + dim.join + `
 		WHERE le.company_id = $1
 		  AND ($2::date IS NULL OR le.date >= $2)
-		  AND ($3::date IS NULL OR le.date <= $3)
-		ORDER BY le.date DESC, le.entry_id DESC
+		  AND ($3::date IS NULL OR le.date <= $3)` + dim.where + `
+		ORDER BY ` + orderBy + `
 		LIMIT $4
 	`
 	var fromArg interface{}
@@ -969,16 +1081,19 @@ func (s *ReportsService) GetGeneralLedger(companyID int, fromDate, toDate string
 	if toDate != "" {
 		toArg = toDate
 	}
-	return queryToMaps(s.db, query, companyID, fromArg, toArg, limit)
+	args := append([]interface{}{companyID, fromArg, toArg, limit}, dim.args...)
+	return queryToMaps(s.db, query, args...)
 }
 
-// GetTrialBalance returns the trial balance
-func (s *ReportsService) GetTrialBalance(companyID int, fromDate, toDate string) ([]map[string]interface{}, error) {
+// GetTrialBalance returns the trial balance, optionally narrowed to or split
+// by analytic dimension values.
+func (s *ReportsService) GetTrialBalance(companyID int, fromDate, toDate string, dims models.LedgerDimensionFilter) ([]map[string]interface{}, error) {
+	dim := compileLedgerDimensionFilter(dims, 4)
 	query := `
 		SELECT coa.account_id,
 		       coa.account_code,
 		       coa.name AS account_name,
-		       coa.type AS account_type,
+		       coa.type AS account_type` + dim.columns + `,
 		       COALESCE(SUM(le.debit),0)::float8 AS total_debit,
 		       COALESCE(SUM(le.credit),0)::float8 AS total_credit,
 		       (COALESCE(SUM(le.debit),0) - COALESCE(SUM(le.credit),0))::float8 AS balance
@@ -986,10 +1101,10 @@ func (s *ReportsService) GetTrialBalance(companyID int, fromDate, toDate string)
 		LEFT JOIN ledger_entries le
 		       ON le.company_id = $1 AND le.account_id = coa.account_id
 		      AND ($2::date IS NULL OR le.date >= $2)
-		      AND ($3::date IS NULL OR le.date <= $3)
+		      AND ($3::date IS NULL OR le.date <= $3)` + dim.where + dim.join + `
 		WHERE coa.company_id = $1
-		GROUP BY coa.account_id, coa.account_code, coa.name, coa.type
-		ORDER BY coa.account_code, coa.name
+		GROUP BY coa.account_id, coa.account_code, coa.name, coa.type` + dim.groupBy + `
+		ORDER BY coa.account_code, coa.name` + dim.groupBy + `
 	`
 	var fromArg interface{}
 	if fromDate != "" {
@@ -999,16 +1114,25 @@ func (s *ReportsService) GetTrialBalance(companyID int, fromDate, toDate string)
 	if toDate != "" {
 		toArg = toDate
 	}
-	return queryToMaps(s.db, query, companyID, fromArg, toArg)
+	args := append([]interface{}{companyID, fromArg, toArg}, dim.args...)
+	return queryToMaps(s.db, query, args...)
 }
 
-// GetProfitLoss returns profit and loss information
-func (s *ReportsService) GetProfitLoss(companyID int, fromDate, toDate string) ([]map[string]interface{}, error) {
+// GetProfitLoss returns profit and loss information. When grouped by an
+// analytic dimension, accounts and totals are reported per dimension value.
+func (s *ReportsService) GetProfitLoss(companyID int, fromDate, toDate string, dims models.LedgerDimensionFilter) ([]map[string]interface{}, error) {
+	dim := compileLedgerDimensionFilter(dims, 4)
+	outerColumns, totalsGroupBy, orderPrefix := "", "", ""
+	if dim.columns != "" {
+		outerColumns = ", dimension_value_code, dimension_value_name"
+		totalsGroupBy = "GROUP BY dimension_value_code, dimension_value_name"
+		orderPrefix = "dimension_value_code NULLS LAST, "
+	}
 	query := `
 		WITH pl AS (
 			SELECT coa.type AS section,
 			       coa.account_code,
-			       coa.name AS account_name,
+			       coa.name AS account_name` + dim.columns + `,
 			       CASE
 			         WHEN coa.type = 'REVENUE' THEN COALESCE(SUM(le.credit - le.debit),0)
 			         WHEN coa.type = 'EXPENSE' THEN COALESCE(SUM(le.debit - le.credit),0)
@@ -1018,25 +1142,26 @@ func (s *ReportsService) GetProfitLoss(companyID int, fromDate, toDate string) (
 			LEFT JOIN ledger_entries le
 			       ON le.company_id = $1 AND le.account_id = coa.account_id
 			      AND ($2::date IS NULL OR le.date >= $2)
-			      AND ($3::date IS NULL OR le.date <= $3)
+			      AND ($3::date IS NULL OR le.date <= $3)` + dim.where + dim.join + `
 			WHERE coa.company_id = $1 AND coa.type IN ('REVENUE','EXPENSE')
-			GROUP BY coa.type, coa.account_code, coa.name
+			GROUP BY coa.type, coa.account_code, coa.name` + dim.groupBy + `
 		),
 		totals AS (
 			SELECT
 				COALESCE(SUM(CASE WHEN section = 'REVENUE' THEN amount ELSE 0 END),0)::float8 AS total_revenue,
-				COALESCE(SUM(CASE WHEN section = 'EXPENSE' THEN amount ELSE 0 END),0)::float8 AS total_expense
+				COALESCE(SUM(CASE WHEN section = 'EXPENSE' THEN amount ELSE 0 END),0)::float8 AS total_expense` + outerColumns + `
 			FROM pl
+			` + totalsGroupBy + `
 		)
-		SELECT section, account_code, account_name, amount
+		SELECT section, account_code, account_name` + outerColumns + `, amount
 		FROM pl
 		UNION ALL
-		SELECT 'TOTAL_REVENUE'::text, NULL::text, NULL::text, totals.total_revenue FROM totals
+		SELECT 'TOTAL_REVENUE'::text, NULL::text, NULL::text` + outerColumns + `, totals.total_revenue FROM totals
 		UNION ALL
-		SELECT 'TOTAL_EXPENSE'::text, NULL::text, NULL::text, totals.total_expense FROM totals
+		SELECT 'TOTAL_EXPENSE'::text, NULL::text, NULL::text` + outerColumns + `, totals.total_expense FROM totals
 		UNION ALL
-		SELECT 'NET_PROFIT'::text, NULL::text, NULL::text, (totals.total_revenue - totals.total_expense)::float8 FROM totals
-		ORDER BY section, account_code NULLS LAST
+		SELECT 'NET_PROFIT'::text, NULL::text, NULL::text` + outerColumns + `, (totals.total_revenue - totals.total_expense)::float8 FROM totals
+		ORDER BY ` + orderPrefix + `section, account_code NULLS LAST
 	`
 	var fromArg interface{}
 	if fromDate != "" {
@@ -1046,7 +1171,8 @@ func (s *ReportsService) GetProfitLoss(companyID int, fromDate, toDate string) (
 	if toDate != "" {
 		toArg = toDate
 	}
-	return queryToMaps(s.db, query, companyID, fromArg, toArg)
+	args := append([]interface{}{companyID, fromArg, toArg}, dim.args...)
+	return queryToMaps(s.db, query, args...)
 }
 
 // GetBalanceSheet returns balance sheet data
@@ -1094,21 +1220,21 @@ func (s *ReportsService) GetBalanceSheet(companyID int, asOfDate string) ([]map[
 }
 
 // GetOutstandingReport returns outstanding invoices or payments
-func (s *ReportsService) GetOutstandingReport(companyID int, locationID *int) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetOutstandingReport(companyID int, locationIDs []int) ([]map[string]interface{}, error) {
 	query := `
 		WITH sales_outstanding AS (
 			SELECT COALESCE(SUM(s.total_amount - s.paid_amount),0)::float8 AS amount
 			FROM sales s
 			JOIN locations l ON l.location_id = s.location_id
 			WHERE l.company_id = $1 AND s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE
-			  AND ($2::int IS NULL OR s.location_id = $2)
+			  AND ($2::int[] IS NULL OR s.location_id = ANY($2))
 		),
 		purchase_outstanding AS (
 			SELECT COALESCE(SUM(p.total_amount - p.paid_amount),0)::float8 AS amount
 			FROM purchases p
 			JOIN locations l ON l.location_id = p.location_id
 			WHERE l.company_id = $1 AND p.is_deleted = FALSE
-			  AND ($2::int IS NULL OR p.location_id = $2)
+			  AND ($2::int[] IS NULL OR p.location_id = ANY($2))
 		)
 		SELECT 'sales'::text AS type, sales_outstanding.amount
 		FROM sales_outstanding
@@ -1116,10 +1242,7 @@ func (s *ReportsService) GetOutstandingReport(companyID int, locationID *int) ([
 		SELECT 'purchases'::text AS type, purchase_outstanding.amount
 		FROM purchase_outstanding
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	return queryToMaps(s.db, query, companyID, locArg)
 }
 
@@ -1154,7 +1277,7 @@ func (s *ReportsService) GetTopPerformers(companyID int, fromDate, toDate string
 	return queryToMaps(s.db, query, companyID, fromArg, toArg, limit)
 }
 
-func (s *ReportsService) GetAssetRegisterReport(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetAssetRegisterReport(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		SELECT
 			ae.asset_tag,
@@ -1173,15 +1296,12 @@ func (s *ReportsService) GetAssetRegisterReport(companyID int, locationID *int,
 		LEFT JOIN asset_categories ac ON ac.category_id = ae.category_id
 		LEFT JOIN suppliers sup ON sup.supplier_id = ae.supplier_id
 		WHERE ae.company_id = $1
-		  AND ($2::int IS NULL OR ae.location_id = $2)
+		  AND ($2::int[] IS NULL OR ae.location_id = ANY($2))
 		  AND ($3::timestamp IS NULL OR ae.acquisition_date >= $3)
 		  AND ($4::timestamp IS NULL OR ae.acquisition_date <= $4)
 		ORDER BY ae.acquisition_date DESC, ae.asset_entry_id DESC
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -1193,7 +1313,7 @@ func (s *ReportsService) GetAssetRegisterReport(companyID int, locationID *int,
 	return queryToMaps(s.db, query, companyID, locArg, fromArg, toArg)
 }
 
-func (s *ReportsService) GetAssetValueSummary(companyID int, locationID *int) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetAssetValueSummary(companyID int, locationIDs []int) ([]map[string]interface{}, error) {
 	query := `
 		SELECT
 			COALESCE(ac.name, 'Uncategorized') AS category_name,
@@ -1203,18 +1323,15 @@ func (s *ReportsService) GetAssetValueSummary(companyID int, locationID *int) ([
 		FROM asset_register_entries ae
 		LEFT JOIN asset_categories ac ON ac.category_id = ae.category_id
 		WHERE ae.company_id = $1
-		  AND ($2::int IS NULL OR ae.location_id = $2)
+		  AND ($2::int[] IS NULL OR ae.location_id = ANY($2))
 		GROUP BY COALESCE(ac.name, 'Uncategorized'), ae.status
 		ORDER BY total_value DESC, category_name
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	return queryToMaps(s.db, query, companyID, locArg)
 }
 
-func (s *ReportsService) GetConsumableConsumptionReport(companyID int, locationID *int, fromDate, toDate string) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetConsumableConsumptionReport(companyID int, locationIDs []int, fromDate, toDate string) ([]map[string]interface{}, error) {
 	query := `
 		SELECT
 			ce.entry_number,
@@ -1231,15 +1348,12 @@ func (s *ReportsService) GetConsumableConsumptionReport(companyID int, locationI
 		LEFT JOIN consumable_categories cc ON cc.category_id = ce.category_id
 		LEFT JOIN suppliers sup ON sup.supplier_id = ce.supplier_id
 		WHERE ce.company_id = $1
-		  AND ($2::int IS NULL OR ce.location_id = $2)
+		  AND ($2::int[] IS NULL OR ce.location_id = ANY($2))
 		  AND ($3::timestamp IS NULL OR ce.consumed_at >= $3)
 		  AND ($4::timestamp IS NULL OR ce.consumed_at <= $4)
 		ORDER BY ce.consumed_at DESC, ce.consumption_id DESC
 	`
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	var fromArg interface{}
 	if fromDate != "" {
 		fromArg = fromDate
@@ -1251,7 +1365,7 @@ func (s *ReportsService) GetConsumableConsumptionReport(companyID int, locationI
 	return queryToMaps(s.db, query, companyID, locArg, fromArg, toArg)
 }
 
-func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *int) ([]map[string]interface{}, error) {
+func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationIDs []int) ([]map[string]interface{}, error) {
 	method, err := loadCompanyCostingMethod(s.db, companyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get inventory costing method: %w", err)
@@ -1270,13 +1384,13 @@ func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *i
 			WHERE l.company_id = $1
 			  AND p.is_deleted = FALSE
 			  AND COALESCE(p.item_type, 'PRODUCT') = 'CONSUMABLE'
-			  AND ($2::int IS NULL OR sv.location_id = $2)
+			  AND ($2::int[] IS NULL OR sv.location_id = ANY($2))
 			GROUP BY sv.product_id, sv.location_id
 		)
 		SELECT
 			p.product_id,
 			p.name AS product_name,
-			COALESCE(v.location_id, $2::int) AS location_id,
+			COALESCE(v.location_id, CASE WHEN cardinality($2::int[]) = 1 THEN ($2::int[])[1] END) AS location_id,
 			COALESCE(v.quantity, 0)::float8 AS quantity,
 			COALESCE(v.stock_value, 0)::float8 AS stock_value
 		FROM products p
@@ -1301,7 +1415,7 @@ func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *i
 				WHERE l.company_id = $1
 				  AND p.is_deleted = FALSE
 				  AND COALESCE(p.item_type, 'PRODUCT') = 'CONSUMABLE'
-				  AND ($2::int IS NULL OR sv.location_id = $2)
+				  AND ($2::int[] IS NULL OR sv.location_id = ANY($2))
 			),
 			lot_stock AS (
 				SELECT
@@ -1316,7 +1430,7 @@ func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *i
 				WHERE l.company_id = $1
 				  AND p.is_deleted = FALSE
 				  AND COALESCE(p.item_type, 'PRODUCT') = 'CONSUMABLE'
-				  AND ($2::int IS NULL OR sl.location_id = $2)
+				  AND ($2::int[] IS NULL OR sl.location_id = ANY($2))
 				GROUP BY sl.product_id, sl.location_id, sl.barcode_id
 			),
 			variant_valuation AS (
@@ -1346,7 +1460,7 @@ func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *i
 			SELECT
 				p.product_id,
 				p.name AS product_name,
-				COALESCE(v.location_id, $2::int) AS location_id,
+				COALESCE(v.location_id, CASE WHEN cardinality($2::int[]) = 1 THEN ($2::int[])[1] END) AS location_id,
 				COALESCE(v.quantity, 0)::float8 AS quantity,
 				COALESCE(v.stock_value, 0)::float8 AS stock_value
 			FROM products p
@@ -1357,10 +1471,7 @@ func (s *ReportsService) GetConsumableBalanceReport(companyID int, locationID *i
 			ORDER BY stock_value DESC, product_name
 		`
 	}
-	var locArg interface{}
-	if locationID != nil {
-		locArg = *locationID
-	}
+	locArg := reportLocationsArg(locationIDs)
 	return queryToMaps(s.db, query, companyID, locArg)
 }
 
@@ -312,6 +312,7 @@ func (s *ReturnsService) CreateSaleReturn(companyID, userID int, req *models.Cre
 		return nil, err
 	}
 	trackingSvc := newInventoryTrackingService(s.db)
+	var returnedBarcodeIDs []int
 
 	totalAmount := float64(0)
 
@@ -365,7 +366,7 @@ func (s *ReturnsService) CreateSaleReturn(companyID, userID int, req *models.Cre
 				if len(serialNumbers) == 0 {
 					serialNumbers = allocation.SerialNumbers
 				}
-				if _, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_return_detail", &returnDetailID, nil, inventorySelection{
+				variant, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_return_detail", &returnDetailID, nil, inventorySelection{
 					ProductID:      item.ProductID,
 					BarcodeID:      firstNonNilInt(item.BarcodeID, allocation.BarcodeID),
 					ComboProductID: allocation.ComboProductID,
@@ -374,9 +375,11 @@ func (s *ReturnsService) CreateSaleReturn(companyID, userID int, req *models.Cre
 					BatchNumber:    item.BatchNumber,
 					ExpiryDate:     item.ExpiryDate,
 					UnitCost:       allocation.CostPrice,
-				}); err != nil {
+				})
+				if err != nil {
 					return nil, fmt.Errorf("failed to update stock: %w", err)
 				}
+				returnedBarcodeIDs = append(returnedBarcodeIDs, variant.BarcodeID)
 			}
 		}
 	}
@@ -411,7 +414,13 @@ func (s *ReturnsService) CreateSaleReturn(companyID, userID int, req *models.Cre
 	}
 
 	if !isTraining {
-		if err := NewFinanceIntegrityServiceWithDB(s.db).EnqueueTx(tx, &models.FinanceOutboxEntry{
+		// Serve waiting sales order backorders before the returned goods
+		// become free stock.
+		if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, locationID, returnedBarcodeIDs); err != nil {
+			return nil, err
+		}
+		finance := NewFinanceIntegrityServiceWithDB(s.db)
+		if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
 			CompanyID:     companyID,
 			LocationID:    &locationID,
 			EventType:     financeEventLedgerSaleReturn,
@@ -422,6 +431,19 @@ func (s *ReturnsService) CreateSaleReturn(companyID, userID int, req *models.Cre
 		}); err != nil {
 			return nil, fmt.Errorf("failed to enqueue sale return ledger posting: %w", err)
 		}
+		if err := enqueueEInvoiceTx(tx, finance, companyID, locationID, einvoiceSourceSaleReturn, returnID, userID); err != nil {
+			return nil, err
+		}
+		if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventSaleReturnCreated, "sale_return", returnID, models.JSONB{
+			"return_id":     returnID,
+			"return_number": returnNumber,
+			"sale_id":       req.SaleID,
+			"location_id":   locationID,
+			"customer_id":   customerID,
+			"total_amount":  totalAmount,
+		}); err != nil {
+			return nil, err
+		}
 	}
 
 	// Commit transaction
@@ -19,14 +19,16 @@ func NewRoleService() *RoleService {
 	}
 }
 
-func (s *RoleService) GetRoles() ([]models.Role, error) {
+// GetRoles lists the company's own roles.
+func (s *RoleService) GetRoles(companyID int) ([]models.Role, error) {
 	query := `
-		SELECT role_id, name, description, is_system_role, created_at
+		SELECT role_id, company_id, template_role_id, name, COALESCE(description, ''), is_system_role, created_at
 		FROM roles 
+		WHERE company_id = $1
 		ORDER BY name
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Query(query, companyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get roles: %w", err)
 	}
@@ -36,7 +38,7 @@ func (s *RoleService) GetRoles() ([]models.Role, error) {
 	for rows.Next() {
 		var role models.Role
 		err := rows.Scan(
-			&role.RoleID, &role.Name, &role.Description,
+			&role.RoleID, &role.CompanyID, &role.TemplateRoleID, &role.Name, &role.Description,
 			&role.IsSystemRole, &role.CreatedAt,
 		)
 		if err != nil {
@@ -48,16 +50,16 @@ func (s *RoleService) GetRoles() ([]models.Role, error) {
 	return roles, nil
 }
 
-func (s *RoleService) GetRoleByID(roleID int) (*models.Role, error) {
+func (s *RoleService) GetRoleByID(companyID, roleID int) (*models.Role, error) {
 	query := `
-		SELECT role_id, name, description, is_system_role, created_at
+		SELECT role_id, company_id, template_role_id, name, COALESCE(description, ''), is_system_role, created_at
 		FROM roles 
-		WHERE role_id = $1
+		WHERE role_id = $1 AND company_id = $2
 	`
 
 	var role models.Role
-	err := s.db.QueryRow(query, roleID).Scan(
-		&role.RoleID, &role.Name, &role.Description,
+	err := s.db.QueryRow(query, roleID, companyID).Scan(
+		&role.RoleID, &role.CompanyID, &role.TemplateRoleID, &role.Name, &role.Description,
 		&role.IsSystemRole, &role.CreatedAt,
 	)
 
@@ -71,9 +73,9 @@ func (s *RoleService) GetRoleByID(roleID int) (*models.Role, error) {
 	return &role, nil
 }
 
-func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.Role, error) {
+func (s *RoleService) CreateRole(companyID int, req *models.CreateRoleRequest) (*models.Role, error) {
 	// Check if role name already exists
-	exists, err := s.checkRoleNameExists(req.Name)
+	exists, err := s.checkRoleNameExists(companyID, req.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check role existence: %w", err)
 	}
@@ -82,13 +84,13 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.Role, e
 	}
 
 	query := `
-		INSERT INTO roles (name, description)
-		VALUES ($1, $2)
+		INSERT INTO roles (company_id, name, description)
+		VALUES ($1, $2, $3)
 		RETURNING role_id, created_at
 	`
 
 	var role models.Role
-	err = s.db.QueryRow(query, req.Name, req.Description).Scan(
+	err = s.db.QueryRow(query, companyID, req.Name, req.Description).Scan(
 		&role.RoleID, &role.CreatedAt,
 	)
 
@@ -96,6 +98,7 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.Role, e
 		return nil, fmt.Errorf("failed to create role: %w", err)
 	}
 
+	role.CompanyID = &companyID
 	role.Name = req.Name
 	role.Description = req.Description
 	role.IsSystemRole = false
@@ -103,9 +106,9 @@ func (s *RoleService) CreateRole(req *models.CreateRoleRequest) (*models.Role, e
 	return &role, nil
 }
 
-func (s *RoleService) UpdateRole(roleID int, req *models.UpdateRoleRequest) error {
+func (s *RoleService) UpdateRole(companyID, roleID int, req *models.UpdateRoleRequest) error {
 	// Check if role is system role (cannot be updated)
-	isSystemRole, err := s.checkIsSystemRole(roleID)
+	isSystemRole, err := s.checkIsSystemRole(companyID, roleID)
 	if err != nil {
 		return fmt.Errorf("failed to check role type: %w", err)
 	}
@@ -119,7 +122,7 @@ func (s *RoleService) UpdateRole(roleID int, req *models.UpdateRoleRequest) erro
 
 	if req.Name != nil {
 		// Check if new name already exists (excluding current role)
-		exists, err := s.checkRoleNameExistsExcluding(roleID, *req.Name)
+		exists, err := s.checkRoleNameExistsExcluding(companyID, roleID, *req.Name)
 		if err != nil {
 			return fmt.Errorf("failed to check role name existence: %w", err)
 		}
@@ -141,10 +144,9 @@ func (s *RoleService) UpdateRole(roleID int, req *models.UpdateRoleRequest) erro
 		return fmt.Errorf("no fields to update")
 	}
 
-	argCount++
-	query := fmt.Sprintf("UPDATE roles SET %s WHERE role_id = $%d",
-		strings.Join(setParts, ", "), argCount)
-	args = append(args, roleID)
+	query := fmt.Sprintf("UPDATE roles SET %s WHERE role_id = $%d AND company_id = $%d",
+		strings.Join(setParts, ", "), argCount+1, argCount+2)
+	args = append(args, roleID, companyID)
 
 	result, err := s.db.Exec(query, args...)
 	if err != nil {
@@ -163,9 +165,9 @@ func (s *RoleService) UpdateRole(roleID int, req *models.UpdateRoleRequest) erro
 	return nil
 }
 
-func (s *RoleService) DeleteRole(roleID int) error {
+func (s *RoleService) DeleteRole(companyID, roleID int) error {
 	// Check if role is system role (cannot be deleted)
-	isSystemRole, err := s.checkIsSystemRole(roleID)
+	isSystemRole, err := s.checkIsSystemRole(companyID, roleID)
 	if err != nil {
 		return fmt.Errorf("failed to check role type: %w", err)
 	}
@@ -182,9 +184,9 @@ func (s *RoleService) DeleteRole(roleID int) error {
 		return fmt.Errorf("role is in use and cannot be deleted")
 	}
 
-	query := `DELETE FROM roles WHERE role_id = $1`
+	query := `DELETE FROM roles WHERE role_id = $1 AND company_id = $2`
 
-	result, err := s.db.Exec(query, roleID)
+	result, err := s.db.Exec(query, roleID, companyID)
 	if err != nil {
 		return fmt.Errorf("failed to delete role: %w", err)
 	}
@@ -230,9 +232,9 @@ func (s *RoleService) GetPermissions() ([]models.Permission, error) {
 	return permissions, nil
 }
 
-func (s *RoleService) GetRolePermissions(roleID int) (*models.RoleWithPermissions, error) {
+func (s *RoleService) GetRolePermissions(companyID, roleID int) (*models.RoleWithPermissions, error) {
 	// Get role details
-	role, err := s.GetRoleByID(roleID)
+	role, err := s.GetRoleByID(companyID, roleID)
 	if err != nil {
 		return nil, err
 	}
@@ -271,9 +273,9 @@ func (s *RoleService) GetRolePermissions(roleID int) (*models.RoleWithPermission
 	}, nil
 }
 
-func (s *RoleService) AssignPermissions(roleID int, req *models.AssignPermissionsRequest) error {
+func (s *RoleService) AssignPermissions(companyID, roleID int, req *models.AssignPermissionsRequest) error {
 	// Check if role exists and is not system role
-	isSystemRole, err := s.checkIsSystemRole(roleID)
+	isSystemRole, err := s.checkIsSystemRole(companyID, roleID)
 	if err != nil {
 		return fmt.Errorf("failed to check role type: %w", err)
 	}
@@ -305,11 +307,11 @@ func (s *RoleService) AssignPermissions(roleID int, req *models.AssignPermission
 }
 
 // Helper methods
-func (s *RoleService) checkRoleNameExists(name string) (bool, error) {
-	query := `SELECT COUNT(*) FROM roles WHERE name = $1`
+func (s *RoleService) checkRoleNameExists(companyID int, name string) (bool, error) {
+	query := `SELECT COUNT(*) FROM roles WHERE company_id = $1 AND name = $2`
 
 	var count int
-	err := s.db.QueryRow(query, name).Scan(&count)
+	err := s.db.QueryRow(query, companyID, name).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -317,11 +319,11 @@ func (s *RoleService) checkRoleNameExists(name string) (bool, error) {
 	return count > 0, nil
 }
 
-func (s *RoleService) checkRoleNameExistsExcluding(roleID int, name string) (bool, error) {
-	query := `SELECT COUNT(*) FROM roles WHERE name = $1 AND role_id != $2`
+func (s *RoleService) checkRoleNameExistsExcluding(companyID, roleID int, name string) (bool, error) {
+	query := `SELECT COUNT(*) FROM roles WHERE company_id = $1 AND name = $2 AND role_id != $3`
 
 	var count int
-	err := s.db.QueryRow(query, name, roleID).Scan(&count)
+	err := s.db.QueryRow(query, companyID, name, roleID).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -329,11 +331,11 @@ func (s *RoleService) checkRoleNameExistsExcluding(roleID int, name string) (boo
 	return count > 0, nil
 }
 
-func (s *RoleService) checkIsSystemRole(roleID int) (bool, error) {
-	query := `SELECT is_system_role FROM roles WHERE role_id = $1`
+func (s *RoleService) checkIsSystemRole(companyID, roleID int) (bool, error) {
+	query := `SELECT is_system_role FROM roles WHERE role_id = $1 AND company_id = $2`
 
 	var isSystemRole bool
-	err := s.db.QueryRow(query, roleID).Scan(&isSystemRole)
+	err := s.db.QueryRow(query, roleID, companyID).Scan(&isSystemRole)
 	if err == sql.ErrNoRows {
 		return false, fmt.Errorf("role not found")
 	}
@@ -345,7 +347,10 @@ func (s *RoleService) checkIsSystemRole(roleID int) (bool, error) {
 }
 
 func (s *RoleService) checkRoleInUse(roleID int) (bool, error) {
-	query := `SELECT COUNT(*) FROM users WHERE role_id = $1 AND is_deleted = FALSE`
+	query := `
+		SELECT (SELECT COUNT(*) FROM users WHERE role_id = $1 AND is_deleted = FALSE)
+		     + (SELECT COUNT(*) FROM user_locations WHERE role_id = $1)
+	`
 
 	var count int
 	err := s.db.QueryRow(query, roleID).Scan(&count)
@@ -355,3 +360,53 @@ func (s *RoleService) checkRoleInUse(roleID int) (bool, error) {
 
 	return count > 0, nil
 }
+
+// cloneTemplateRolesTx gives a new company its own copy of every template
+// role, with the template's permissions and POS limits. The copies are the
+// company's to edit; only the admin roles, which grant access by name, stay
+// system roles.
+func cloneTemplateRolesTx(tx *sql.Tx, companyID int) error {
+	if _, err := tx.Exec(`
+		INSERT INTO roles (company_id, template_role_id, name, description, is_system_role)
+		SELECT $1, r.role_id, r.name, r.description,
+		       r.is_system_role AND LOWER(r.name) IN ('super admin', 'admin')
+		FROM roles r
+		WHERE r.company_id IS NULL
+		ON CONFLICT DO NOTHING
+	`, companyID); err != nil {
+		return fmt.Errorf("failed to copy template roles: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT cr.role_id, rp.permission_id
+		FROM roles cr
+		JOIN role_permissions rp ON rp.role_id = cr.template_role_id
+		WHERE cr.company_id = $1
+		ON CONFLICT DO NOTHING
+	`, companyID); err != nil {
+		return fmt.Errorf("failed to copy template role permissions: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO role_pos_limits (role_id, max_line_discount_pct, max_bill_discount_pct)
+		SELECT cr.role_id, l.max_line_discount_pct, l.max_bill_discount_pct
+		FROM roles cr
+		JOIN role_pos_limits l ON l.role_id = cr.template_role_id
+		WHERE cr.company_id = $1
+		ON CONFLICT (role_id) DO NOTHING
+	`, companyID); err != nil {
+		return fmt.Errorf("failed to copy template role limits: %w", err)
+	}
+	return nil
+}
+
+// ensureCompanyRole rejects roles that belong to another company.
+func ensureCompanyRole(q queryer, companyID, roleID int) error {
+	var ok bool
+	if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE role_id = $1 AND company_id = $2)`, roleID, companyID).Scan(&ok); err != nil {
+		return fmt.Errorf("failed to check role: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("role not found")
+	}
+	return nil
+}
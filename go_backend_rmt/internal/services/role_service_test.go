@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRoleService_EditsCompanyCopyOfTemplateRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Copies are made editable; only the admin roles stay system roles.
+	mock.ExpectBegin()
+	mock.ExpectExec(`(?s)INSERT INTO roles .*r.is_system_role AND LOWER\(r.name\) IN \('super admin', 'admin'\).*WHERE r.company_id IS NULL`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("INSERT INTO role_permissions").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 20))
+	mock.ExpectExec("INSERT INTO role_pos_limits").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 4))
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := cloneTemplateRolesTx(tx, 1); err != nil {
+		t.Fatalf("unexpected clone error: %v", err)
+	}
+
+	// The copied "Manager" role (template_role_id set, company-owned) can be
+	// renamed.
+	mock.ExpectQuery("SELECT is_system_role FROM roles").
+		WithArgs(12, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"is_system_role"}).AddRow(false))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM roles").
+		WithArgs(1, "Store Manager", 12).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("UPDATE roles SET name = \\$1 WHERE role_id = \\$2 AND company_id = \\$3").
+		WithArgs("Store Manager", 12, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	svc := &RoleService{db: db}
+	name := "Store Manager"
+	if err := svc.UpdateRole(1, 12, &models.UpdateRoleRequest{Name: &name}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,1303 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const salesOrderQuantityEpsilon = 0.0005
+
+// SalesOrderService manages the order stage between quotes and invoices.
+// Confirmed lines reserve stock on stock_variants.reserved_quantity, delivery
+// notes issue it, and invoices are raised from deliveries without issuing
+// stock a second time.
+type SalesOrderService struct {
+	db *sql.DB
+}
+
+func NewSalesOrderService() *SalesOrderService {
+	return &SalesOrderService{db: database.GetDB()}
+}
+
+type salesOrderLineRow struct {
+	models.SalesOrderLine
+	SellingToStock float64
+}
+
+// openQuantity is what is still expected to ship: not delivered and not
+// cancelled. Part of it may already be reserved.
+func (l salesOrderLineRow) openQuantity() float64 {
+	return math.Max(l.Quantity-l.DeliveredQuantity-l.CancelledQuantity, 0)
+}
+
+func backorderedQuantity(line models.SalesOrderLine) float64 {
+	open := line.Quantity - line.DeliveredQuantity - line.CancelledQuantity - line.ReservedQuantity
+	if open < salesOrderQuantityEpsilon {
+		return 0
+	}
+	return round4(open)
+}
+
+// splitReservation reserves as much of the requested quantity as free stock
+// allows; the rest is backordered.
+func splitReservation(requested, free float64) (reserved, backordered float64) {
+	if requested <= 0 {
+		return 0, 0
+	}
+	reserved = math.Min(requested, math.Max(free, 0))
+	return round4(reserved), round4(requested - reserved)
+}
+
+// salesOrderStatusFor derives the delivery status from line quantities.
+func salesOrderStatusFor(lines []models.SalesOrderLine) string {
+	delivered := false
+	complete := true
+	for _, line := range lines {
+		if line.DeliveredQuantity > salesOrderQuantityEpsilon {
+			delivered = true
+		}
+		if line.Quantity-line.DeliveredQuantity-line.CancelledQuantity > salesOrderQuantityEpsilon {
+			complete = false
+		}
+	}
+	switch {
+	case complete && delivered:
+		return models.SalesOrderStatusDelivered
+	case delivered:
+		return models.SalesOrderStatusPartiallyDelivered
+	default:
+		return models.SalesOrderStatusOpen
+	}
+}
+
+type backorderNeed struct {
+	Quantity       float64
+	SellingToStock float64
+}
+
+// allocateBackorders hands free stock (in stock units) to backordered lines in
+// the given order and returns the allocation per line in selling units.
+func allocateBackorders(free float64, needs []backorderNeed) []float64 {
+	allocations := make([]float64, len(needs))
+	for i, need := range needs {
+		if free <= salesOrderQuantityEpsilon {
+			break
+		}
+		factor := normalizeProductUOMFactor(&need.SellingToStock)
+		take := math.Min(need.Quantity*factor, free)
+		allocations[i] = round4(take / factor)
+		free -= take
+	}
+	return allocations
+}
+
+// lockFreeStockTx locks the variant balance and returns its unreserved
+// quantity in stock units.
+func lockFreeStockTx(tx *sql.Tx, locationID, barcodeID int) (float64, error) {
+	var free float64
+	err := tx.QueryRow(`
+		SELECT (quantity - reserved_quantity)::float8
+		FROM stock_variants
+		WHERE location_id = $1 AND barcode_id = $2
+		FOR UPDATE
+	`, locationID, barcodeID).Scan(&free)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock stock balance: %w", err)
+	}
+	return math.Max(free, 0), nil
+}
+
+// adjustReservedStockTx moves the reservation on a variant balance and keeps
+// the aggregate stock row in sync.
+func adjustReservedStockTx(tx *sql.Tx, locationID, productID, barcodeID int, delta float64) error {
+	if math.Abs(delta) < salesOrderQuantityEpsilon {
+		return nil
+	}
+	if _, err := tx.Exec(`
+		UPDATE stock_variants
+		SET reserved_quantity = GREATEST(reserved_quantity + $1, 0),
+		    last_updated = CURRENT_TIMESTAMP
+		WHERE location_id = $2 AND barcode_id = $3
+	`, delta, locationID, barcodeID); err != nil {
+		return fmt.Errorf("failed to update stock reservation: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO stock (location_id, product_id, quantity, reserved_quantity, last_updated)
+		SELECT $1, $2, COALESCE(SUM(quantity), 0), COALESCE(SUM(reserved_quantity), 0), CURRENT_TIMESTAMP
+		FROM stock_variants
+		WHERE location_id = $1 AND product_id = $2
+		ON CONFLICT (location_id, product_id)
+		DO UPDATE SET reserved_quantity = EXCLUDED.reserved_quantity, last_updated = CURRENT_TIMESTAMP
+	`, locationID, productID); err != nil {
+		return fmt.Errorf("failed to sync aggregate reservation: %w", err)
+	}
+	return nil
+}
+
+// releaseExcessReservationsTx hands excess stock units of reservation back to
+// backorder, newest order first, once stock written down leaves less on hand
+// than sales orders hold.
+func releaseExcessReservationsTx(tx *sql.Tx, companyID, locationID, productID, barcodeID int, excess float64) error {
+	rows, err := tx.Query(`
+		SELECT l.sales_order_line_id, l.reserved_quantity::float8, l.selling_to_stock::float8
+		FROM sales_order_lines l
+		JOIN sales_orders o ON o.sales_order_id = l.sales_order_id
+		WHERE o.company_id = $1
+		  AND o.location_id = $2
+		  AND l.barcode_id = $3
+		  AND l.reserved_quantity > 0
+		ORDER BY o.order_date DESC, o.sales_order_id DESC, l.sales_order_line_id DESC
+		FOR UPDATE OF l
+	`, companyID, locationID, barcodeID)
+	if err != nil {
+		return fmt.Errorf("failed to load stock reservations: %w", err)
+	}
+	var (
+		lineIDs  []int
+		reserved []backorderNeed
+	)
+	for rows.Next() {
+		var lineID int
+		var need backorderNeed
+		if err := rows.Scan(&lineID, &need.Quantity, &need.SellingToStock); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan stock reservation: %w", err)
+		}
+		lineIDs = append(lineIDs, lineID)
+		reserved = append(reserved, need)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	releasedStock := 0.0
+	for i, quantity := range allocateBackorders(excess, reserved) {
+		if quantity <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE sales_order_lines
+			SET reserved_quantity = GREATEST(reserved_quantity - $1, 0)
+			WHERE sales_order_line_id = $2
+		`, quantity, lineIDs[i]); err != nil {
+			return fmt.Errorf("failed to release stock reservation: %w", err)
+		}
+		releasedStock += quantity * normalizeProductUOMFactor(&reserved[i].SellingToStock)
+	}
+	return adjustReservedStockTx(tx, locationID, productID, barcodeID, -releasedStock)
+}
+
+func parseOptionalSalesOrderDate(value *string) (*string, error) {
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+	parsed, err := parseAPDate(value)
+	if err != nil {
+		return nil, err
+	}
+	formatted := parsed.Format("2006-01-02")
+	return &formatted, nil
+}
+
+func normalizeInvoicingMode(value *string) string {
+	if value != nil && strings.ToUpper(strings.TrimSpace(*value)) == models.SalesOrderInvoicingConsolidated {
+		return models.SalesOrderInvoicingConsolidated
+	}
+	return models.SalesOrderInvoicingPerDelivery
+}
+
+// CreateSalesOrder records the order and reserves available stock for each
+// line; any shortfall stays on backorder.
+func (s *SalesOrderService) CreateSalesOrder(companyID, locationID, userID int, req *models.CreateSalesOrderRequest) (*models.SalesOrder, error) {
+	orderID, err := s.createSalesOrder(companyID, locationID, userID, nil, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSalesOrder(companyID, orderID)
+}
+
+// ConvertQuoteToSalesOrder turns an accepted quote into a sales order at the
+// quote's location and marks the quote converted.
+func (s *SalesOrderService) ConvertQuoteToSalesOrder(companyID, quoteID, userID int, req *models.ConvertQuoteToSalesOrderRequest) (*models.SalesOrder, error) {
+	var (
+		locationID      int
+		quoteNumber     string
+		customerID      sql.NullInt64
+		transactionType string
+		status          string
+		notes           sql.NullString
+		convertedSale   sql.NullInt64
+	)
+	err := s.db.QueryRow(`
+		SELECT q.location_id, q.quote_number, q.customer_id, q.transaction_type, q.status, q.notes, q.converted_sale_id
+		FROM quotes q
+		JOIN locations l ON q.location_id = l.location_id
+		WHERE q.quote_id = $1 AND l.company_id = $2 AND q.is_deleted = FALSE
+	`, quoteID, companyID).Scan(&locationID, &quoteNumber, &customerID, &transactionType, &status, &notes, &convertedSale)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("quote not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	var existingOrderID int
+	err = s.db.QueryRow(`
+		SELECT sales_order_id FROM sales_orders
+		WHERE quote_id = $1 AND company_id = $2 AND status <> 'CANCELLED'
+	`, quoteID, companyID).Scan(&existingOrderID)
+	if err == nil {
+		return s.GetSalesOrder(companyID, existingOrderID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check quote conversion: %w", err)
+	}
+	if convertedSale.Valid {
+		return nil, fmt.Errorf("quote has already been converted to a sale")
+	}
+	if status != "ACCEPTED" {
+		return nil, fmt.Errorf("quote must be ACCEPTED before conversion")
+	}
+	if !customerID.Valid {
+		return nil, fmt.Errorf("sales orders require a customer")
+	}
+
+	items, err := (&SalesService{db: s.db}).getQuoteItems(quoteID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("quote has no items")
+	}
+	lines := make([]models.SalesOrderLineInput, 0, len(items))
+	for _, item := range items {
+		if item.ProductID == nil {
+			return nil, fmt.Errorf("quote items without a product cannot be ordered")
+		}
+		lines = append(lines, models.SalesOrderLineInput{
+			ProductID:       *item.ProductID,
+			ProductName:     item.ProductName,
+			Quantity:        item.Quantity,
+			UnitPrice:       item.UnitPrice,
+			DiscountPercent: item.DiscountPercent,
+			TaxID:           item.TaxID,
+			Notes:           item.Notes,
+		})
+	}
+
+	origin := fmt.Sprintf("Converted from Quote %s", strings.TrimSpace(quoteNumber))
+	orderNotes := strings.TrimSpace(notes.String)
+	if orderNotes == "" {
+		orderNotes = origin
+	} else {
+		orderNotes = orderNotes + "\n" + origin
+	}
+	orderReq := &models.CreateSalesOrderRequest{
+		CustomerID:      int(customerID.Int64),
+		TransactionType: &transactionType,
+		Notes:           &orderNotes,
+		Lines:           lines,
+	}
+	if req != nil {
+		orderReq.ExpectedDeliveryDate = req.ExpectedDeliveryDate
+		orderReq.InvoicingMode = req.InvoicingMode
+	}
+
+	orderID, err := s.createSalesOrder(companyID, locationID, userID, &quoteID, orderReq)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetSalesOrder(companyID, orderID)
+}
+
+func (s *SalesOrderService) createSalesOrder(companyID, locationID, userID int, quoteID *int, req *models.CreateSalesOrderRequest) (int, error) {
+	salesSvc := &SalesService{db: s.db}
+	if err := salesSvc.validateCustomerInCompany(req.CustomerID, companyID); err != nil {
+		return 0, err
+	}
+	if err := salesSvc.validateLocationInCompany(locationID, companyID); err != nil {
+		return 0, err
+	}
+	if len(req.Lines) == 0 {
+		return 0, fmt.Errorf("sales order requires at least one line")
+	}
+	expectedDate, err := parseOptionalSalesOrderDate(req.ExpectedDeliveryDate)
+	if err != nil {
+		return 0, err
+	}
+	transactionType := "B2B"
+	if req.TransactionType != nil {
+		transactionType = normalizeTransactionType(*req.TransactionType)
+		if transactionType == "" {
+			return 0, fmt.Errorf("invalid transaction_type")
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	orderNumber, err := NewNumberingSequenceService().NextNumber(tx, "sales_order", companyID, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate sales order number: %w", err)
+	}
+
+	var orderID int
+	if err := tx.QueryRow(`
+		INSERT INTO sales_orders (order_number, company_id, location_id, customer_id, quote_id,
+		                          expected_delivery_date, status, invoicing_mode, transaction_type,
+		                          notes, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, 'OPEN', $7, $8, $9, $10, $10)
+		RETURNING sales_order_id
+	`, orderNumber, companyID, locationID, req.CustomerID, quoteID, expectedDate,
+		normalizeInvoicingMode(req.InvoicingMode), transactionType, req.Notes, userID).Scan(&orderID); err != nil {
+		if isUniqueViolation(err) && quoteID != nil {
+			return 0, fmt.Errorf("quote has already been converted to a sales order")
+		}
+		return 0, fmt.Errorf("failed to create sales order: %w", err)
+	}
+
+	productIDs := make([]int, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	metaByID, err := fetchProductMeta(tx, companyID, productIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	for _, input := range req.Lines {
+		if input.Quantity <= 0 {
+			return 0, fmt.Errorf("quantity must be greater than zero")
+		}
+		meta, ok := metaByID[input.ProductID]
+		if !ok {
+			return 0, fmt.Errorf("product not found")
+		}
+		variant, err := trackingSvc.resolveVariantTx(tx, companyID, input.ProductID, input.BarcodeID)
+		if err != nil {
+			return 0, err
+		}
+		factor := normalizeProductUOMFactor(&meta.SellingToStock)
+		taxID := input.TaxID
+		if taxID == nil {
+			taxID = meta.TaxID
+		}
+
+		line := salesOrderLineRow{
+			SalesOrderLine: models.SalesOrderLine{
+				SalesOrderID: orderID,
+				ProductID:    variant.ProductID,
+				BarcodeID:    variant.BarcodeID,
+				Quantity:     input.Quantity,
+			},
+			SellingToStock: factor,
+		}
+		if err := tx.QueryRow(`
+			INSERT INTO sales_order_lines (sales_order_id, product_id, barcode_id, product_name, quantity,
+			                               selling_to_stock, unit_price, discount_percentage, tax_id, notes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING sales_order_line_id
+		`, orderID, variant.ProductID, variant.BarcodeID, input.ProductName, input.Quantity,
+			factor, input.UnitPrice, input.DiscountPercent, taxID, input.Notes).Scan(&line.SalesOrderLineID); err != nil {
+			return 0, fmt.Errorf("failed to create sales order line: %w", err)
+		}
+		if err := trackingSvc.ensureVariantBalanceTx(tx, locationID, variant); err != nil {
+			return 0, fmt.Errorf("failed to initialize stock balance: %w", err)
+		}
+		if _, err := s.reserveLineTx(tx, locationID, &line); err != nil {
+			return 0, err
+		}
+	}
+
+	if quoteID != nil {
+		if _, err := tx.Exec(`
+			UPDATE quotes
+			SET status = 'CONVERTED',
+			    converted_at = CURRENT_TIMESTAMP,
+			    converted_by = $1,
+			    updated_by = $1,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE quote_id = $2
+		`, userID, *quoteID); err != nil {
+			return 0, fmt.Errorf("failed to mark quote converted: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit sales order: %w", err)
+	}
+	return orderID, nil
+}
+
+// reserveLineTx reserves free stock for the unreserved open part of a line
+// and returns the quantity reserved in selling units.
+func (s *SalesOrderService) reserveLineTx(tx *sql.Tx, locationID int, line *salesOrderLineRow) (float64, error) {
+	need := line.openQuantity() - line.ReservedQuantity
+	if need <= salesOrderQuantityEpsilon {
+		return 0, nil
+	}
+	free, err := lockFreeStockTx(tx, locationID, line.BarcodeID)
+	if err != nil {
+		return 0, err
+	}
+	factor := normalizeProductUOMFactor(&line.SellingToStock)
+	reserved, _ := splitReservation(need, free/factor)
+	if reserved <= 0 {
+		return 0, nil
+	}
+	if err := adjustReservedStockTx(tx, locationID, line.ProductID, line.BarcodeID, reserved*factor); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE sales_order_lines
+		SET reserved_quantity = reserved_quantity + $1
+		WHERE sales_order_line_id = $2
+	`, reserved, line.SalesOrderLineID); err != nil {
+		return 0, fmt.Errorf("failed to update line reservation: %w", err)
+	}
+	line.ReservedQuantity += reserved
+	return reserved, nil
+}
+
+func (s *SalesOrderService) lockSalesOrderTx(tx *sql.Tx, companyID, orderID int) (*models.SalesOrder, error) {
+	var order models.SalesOrder
+	err := tx.QueryRow(`
+		SELECT sales_order_id, order_number, location_id, customer_id, status, invoicing_mode, transaction_type
+		FROM sales_orders
+		WHERE sales_order_id = $1 AND company_id = $2
+		FOR UPDATE
+	`, orderID, companyID).Scan(&order.SalesOrderID, &order.OrderNumber, &order.LocationID, &order.CustomerID,
+		&order.Status, &order.InvoicingMode, &order.TransactionType)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("sales order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock sales order: %w", err)
+	}
+	return &order, nil
+}
+
+func (s *SalesOrderService) lockSalesOrderLinesTx(tx *sql.Tx, orderID int) ([]salesOrderLineRow, error) {
+	rows, err := tx.Query(`
+		SELECT sales_order_line_id, product_id, barcode_id, quantity::float8, selling_to_stock::float8,
+		       reserved_quantity::float8, delivered_quantity::float8, cancelled_quantity::float8
+		FROM sales_order_lines
+		WHERE sales_order_id = $1
+		ORDER BY sales_order_line_id
+		FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock sales order lines: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []salesOrderLineRow
+	for rows.Next() {
+		line := salesOrderLineRow{SalesOrderLine: models.SalesOrderLine{SalesOrderID: orderID}}
+		if err := rows.Scan(&line.SalesOrderLineID, &line.ProductID, &line.BarcodeID, &line.Quantity, &line.SellingToStock,
+			&line.ReservedQuantity, &line.DeliveredQuantity, &line.CancelledQuantity); err != nil {
+			return nil, fmt.Errorf("failed to scan sales order line: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func (s *SalesOrderService) refreshOrderStatusTx(tx *sql.Tx, orderID, userID int, lines []salesOrderLineRow) (string, error) {
+	plain := make([]models.SalesOrderLine, 0, len(lines))
+	for _, line := range lines {
+		plain = append(plain, line.SalesOrderLine)
+	}
+	status := salesOrderStatusFor(plain)
+	if _, err := tx.Exec(`
+		UPDATE sales_orders
+		SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE sales_order_id = $3
+	`, status, userID, orderID); err != nil {
+		return "", fmt.Errorf("failed to update sales order status: %w", err)
+	}
+	return status, nil
+}
+
+// CreateDelivery records a delivery note. Reserved stock is consumed first;
+// anything beyond the reservation must come from unreserved stock so other
+// orders keep what they hold.
+func (s *SalesOrderService) CreateDelivery(companyID, orderID, userID int, req *models.CreateSalesOrderDeliveryRequest) (*models.SalesOrderDelivery, error) {
+	deliveryDate, err := parseAPDate(req.DeliveryDate)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	order, err := s.lockSalesOrderTx(tx, companyID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.SalesOrderStatusOpen && order.Status != models.SalesOrderStatusPartiallyDelivered {
+		return nil, fmt.Errorf("sales order with status %s cannot be delivered", order.Status)
+	}
+	lines, err := s.lockSalesOrderLinesTx(tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	lineIndex := make(map[int]int, len(lines))
+	for i, line := range lines {
+		lineIndex[line.SalesOrderLineID] = i
+	}
+
+	deliveryNumber, err := NewNumberingSequenceService().NextNumber(tx, "delivery_note", companyID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate delivery number: %w", err)
+	}
+	var deliveryID int
+	if err := tx.QueryRow(`
+		INSERT INTO sales_order_deliveries (delivery_number, sales_order_id, company_id, location_id, delivery_date, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING delivery_id
+	`, deliveryNumber, orderID, companyID, order.LocationID, deliveryDate, req.Notes, userID).Scan(&deliveryID); err != nil {
+		return nil, fmt.Errorf("failed to create delivery: %w", err)
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	for _, input := range req.Lines {
+		idx, ok := lineIndex[input.SalesOrderLineID]
+		if !ok {
+			return nil, fmt.Errorf("sales order line %d not found", input.SalesOrderLineID)
+		}
+		line := &lines[idx]
+		if input.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity must be greater than zero")
+		}
+		if input.Quantity > line.openQuantity()+salesOrderQuantityEpsilon {
+			return nil, fmt.Errorf("delivery quantity exceeds open quantity for line %d", line.SalesOrderLineID)
+		}
+		factor := normalizeProductUOMFactor(&line.SellingToStock)
+		fromReservation := math.Min(input.Quantity, line.ReservedQuantity)
+		if extra := input.Quantity - fromReservation; extra > salesOrderQuantityEpsilon {
+			free, err := lockFreeStockTx(tx, order.LocationID, line.BarcodeID)
+			if err != nil {
+				return nil, err
+			}
+			if extra*factor > free+salesOrderQuantityEpsilon {
+				return nil, fmt.Errorf("insufficient unreserved stock for line %d", line.SalesOrderLineID)
+			}
+		}
+		// Release the line's own reservation first: issues only draw on
+		// unreserved stock.
+		if err := adjustReservedStockTx(tx, order.LocationID, line.ProductID, line.BarcodeID, -fromReservation*factor); err != nil {
+			return nil, err
+		}
+
+		var deliveryLineID int
+		if err := tx.QueryRow(`
+			INSERT INTO sales_order_delivery_lines (delivery_id, sales_order_line_id, quantity, serial_numbers)
+			VALUES ($1, $2, $3, $4)
+			RETURNING delivery_line_id
+		`, deliveryID, line.SalesOrderLineID, input.Quantity, pq.Array(input.SerialNumbers)).Scan(&deliveryLineID); err != nil {
+			return nil, fmt.Errorf("failed to create delivery line: %w", err)
+		}
+
+		barcodeID := line.BarcodeID
+		issue, err := trackingSvc.IssueStockTx(tx, companyID, order.LocationID, userID, "SALE", "sales_order_delivery_line", &deliveryLineID, &deliveryNumber, inventorySelection{
+			ProductID:        line.ProductID,
+			BarcodeID:        &barcodeID,
+			Quantity:         quantityInStockUOM(input.Quantity, factor),
+			SerialNumbers:    input.SerialNumbers,
+			BatchAllocations: input.BatchAllocations,
+			OverridePassword: req.OverridePassword,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update stock: %w", err)
+		}
+		if _, err := tx.Exec(`
+			UPDATE sales_order_delivery_lines
+			SET unit_cost = $1, total_cost = $2
+			WHERE delivery_line_id = $3
+		`, round4(issue.TotalCost/input.Quantity), round4(issue.TotalCost), deliveryLineID); err != nil {
+			return nil, fmt.Errorf("failed to update delivery cost: %w", err)
+		}
+		if _, err := tx.Exec(`
+			UPDATE sales_order_lines
+			SET reserved_quantity = GREATEST(reserved_quantity - $1, 0),
+			    delivered_quantity = delivered_quantity + $2
+			WHERE sales_order_line_id = $3
+		`, fromReservation, input.Quantity, line.SalesOrderLineID); err != nil {
+			return nil, fmt.Errorf("failed to update sales order line: %w", err)
+		}
+		line.ReservedQuantity -= fromReservation
+		line.DeliveredQuantity += input.Quantity
+	}
+
+	if _, err := s.refreshOrderStatusTx(tx, orderID, userID, lines); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit delivery: %w", err)
+	}
+
+	invoice := order.InvoicingMode == models.SalesOrderInvoicingPerDelivery
+	if req.Invoice != nil {
+		invoice = *req.Invoice
+	}
+	if invoice {
+		consolidate := false
+		// The delivery stands even if invoicing fails; it stays uninvoiced
+		// and can be invoiced later.
+		if _, err := s.InvoiceDeliveries(companyID, orderID, userID, &models.InvoiceSalesOrderRequest{
+			DeliveryIDs:      []int{deliveryID},
+			Consolidate:      &consolidate,
+			OverridePassword: req.OverridePassword,
+		}); err != nil {
+			log.Printf("sales_order_service: failed to invoice delivery %d: %v", deliveryID, err)
+		}
+	}
+
+	return s.getDelivery(companyID, deliveryID)
+}
+
+type uninvoicedDelivery struct {
+	DeliveryID     int
+	DeliveryNumber string
+}
+
+// InvoiceDeliveries raises invoices for delivered but uninvoiced deliveries,
+// one per delivery or a single consolidated invoice.
+func (s *SalesOrderService) InvoiceDeliveries(companyID, orderID, userID int, req *models.InvoiceSalesOrderRequest) (*models.SalesOrderInvoiceResult, error) {
+	var order models.SalesOrder
+	err := s.db.QueryRow(`
+		SELECT sales_order_id, order_number, location_id, customer_id, invoicing_mode, transaction_type
+		FROM sales_orders
+		WHERE sales_order_id = $1 AND company_id = $2
+	`, orderID, companyID).Scan(&order.SalesOrderID, &order.OrderNumber, &order.LocationID, &order.CustomerID,
+		&order.InvoicingMode, &order.TransactionType)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("sales order not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales order: %w", err)
+	}
+
+	deliveries, err := s.loadUninvoicedDeliveries(orderID, req.DeliveryIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, fmt.Errorf("no uninvoiced deliveries")
+	}
+
+	consolidate := order.InvoicingMode == models.SalesOrderInvoicingConsolidated
+	if req.Consolidate != nil {
+		consolidate = *req.Consolidate
+	}
+	var groups [][]uninvoicedDelivery
+	if consolidate {
+		groups = append(groups, deliveries)
+	} else {
+		for _, delivery := range deliveries {
+			groups = append(groups, []uninvoicedDelivery{delivery})
+		}
+	}
+
+	result := &models.SalesOrderInvoiceResult{SalesOrderID: orderID, Invoices: []models.Sale{}}
+	for _, group := range groups {
+		sale, err := s.invoiceDeliveryGroup(companyID, userID, &order, group, req.OverridePassword)
+		if err != nil {
+			return nil, err
+		}
+		result.Invoices = append(result.Invoices, *sale)
+	}
+	return result, nil
+}
+
+func (s *SalesOrderService) loadUninvoicedDeliveries(orderID int, deliveryIDs []int) ([]uninvoicedDelivery, error) {
+	rows, err := s.db.Query(`
+		SELECT delivery_id, delivery_number, invoice_sale_id
+		FROM sales_order_deliveries
+		WHERE sales_order_id = $1
+		ORDER BY delivery_id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[int]bool, len(deliveryIDs))
+	for _, id := range deliveryIDs {
+		wanted[id] = false
+	}
+	var deliveries []uninvoicedDelivery
+	for rows.Next() {
+		var delivery uninvoicedDelivery
+		var invoiceSaleID sql.NullInt64
+		if err := rows.Scan(&delivery.DeliveryID, &delivery.DeliveryNumber, &invoiceSaleID); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		if len(wanted) > 0 {
+			if _, ok := wanted[delivery.DeliveryID]; !ok {
+				continue
+			}
+			wanted[delivery.DeliveryID] = true
+			if invoiceSaleID.Valid {
+				return nil, fmt.Errorf("delivery %s is already invoiced", delivery.DeliveryNumber)
+			}
+		} else if invoiceSaleID.Valid {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for id, found := range wanted {
+		if !found {
+			return nil, fmt.Errorf("delivery %d not found on this sales order", id)
+		}
+	}
+	return deliveries, nil
+}
+
+// invoiceDeliveryGroup creates one invoice for the given deliveries. Lines of
+// the same order line are merged and carry the cost captured at delivery.
+func (s *SalesOrderService) invoiceDeliveryGroup(companyID, userID int, order *models.SalesOrder, group []uninvoicedDelivery, overridePassword *string) (*models.Sale, error) {
+	ids := make([]int, 0, len(group))
+	numbers := make([]string, 0, len(group))
+	keyParts := make([]string, 0, len(group))
+	for _, delivery := range group {
+		ids = append(ids, delivery.DeliveryID)
+		numbers = append(numbers, delivery.DeliveryNumber)
+		keyParts = append(keyParts, strconv.Itoa(delivery.DeliveryID))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT ol.sales_order_line_id, ol.product_id, ol.barcode_id, ol.product_name, ol.unit_price::float8,
+		       ol.discount_percentage::float8, ol.tax_id, dl.quantity::float8, dl.total_cost::float8,
+		       COALESCE(dl.serial_numbers, '{}')
+		FROM sales_order_delivery_lines dl
+		JOIN sales_order_lines ol ON ol.sales_order_line_id = dl.sales_order_line_id
+		WHERE dl.delivery_id = ANY($1)
+		ORDER BY ol.sales_order_line_id, dl.delivery_line_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery lines: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.CreateSaleDetailRequest
+	var costs []issuedSaleLineCost
+	var lineIDs []int
+	itemIndex := map[int]int{}
+	for rows.Next() {
+		var (
+			lineID, productID, barcodeID int
+			productName                  sql.NullString
+			unitPrice, discountPercent   float64
+			taxID                        sql.NullInt64
+			quantity, totalCost          float64
+			serials                      []string
+		)
+		if err := rows.Scan(&lineID, &productID, &barcodeID, &productName, &unitPrice, &discountPercent, &taxID,
+			&quantity, &totalCost, pq.Array(&serials)); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery line: %w", err)
+		}
+		idx, ok := itemIndex[lineID]
+		if !ok {
+			pid, bid := productID, barcodeID
+			items = append(items, models.CreateSaleDetailRequest{
+				ProductID:       &pid,
+				BarcodeID:       &bid,
+				ProductName:     nullStringPtr(productName),
+				UnitPrice:       unitPrice,
+				DiscountPercent: discountPercent,
+				TaxID:           nullIntToPtr(taxID),
+			})
+			costs = append(costs, issuedSaleLineCost{BarcodeID: &bid})
+			lineIDs = append(lineIDs, lineID)
+			idx = len(items) - 1
+			itemIndex[lineID] = idx
+		}
+		items[idx].Quantity += quantity
+		items[idx].SerialNumbers = append(items[idx].SerialNumbers, serials...)
+		costs[idx].TotalCost += totalCost
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("deliveries have no lines")
+	}
+	for i := range costs {
+		costs[i].TotalCost = round4(costs[i].TotalCost)
+		if items[i].Quantity > 0 {
+			costs[i].CostPricePerUnit = round4(costs[i].TotalCost / items[i].Quantity)
+		}
+	}
+
+	notes := fmt.Sprintf("Sales Order %s, deliveries %s", order.OrderNumber, strings.Join(numbers, ", "))
+	customerID := order.CustomerID
+	saleReq := &models.CreateSaleRequest{
+		CustomerID:       &customerID,
+		Items:            items,
+		PaidAmount:       0,
+		Notes:            &notes,
+		OverridePassword: overridePassword,
+	}
+	idemKey := fmt.Sprintf("sales-order-invoice:%d:%s", order.SalesOrderID, strings.Join(keyParts, "-"))
+	sale, err := (&SalesService{db: s.db}).CreateSaleWithOptions(companyID, order.LocationID, userID, saleReq, &idemKey, CreateSaleOptions{
+		SourceChannel:   "SALES_ORDER",
+		TransactionType: order.TransactionType,
+		PreIssuedCosts:  costs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE sales_order_deliveries
+		SET invoice_sale_id = $1
+		WHERE delivery_id = ANY($2) AND invoice_sale_id IS NULL
+	`, sale.SaleID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to link deliveries to invoice: %w", err)
+	}
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		for i, lineID := range lineIDs {
+			if _, err := tx.Exec(`
+				UPDATE sales_order_lines
+				SET invoiced_quantity = invoiced_quantity + $1
+				WHERE sales_order_line_id = $2
+			`, items[i].Quantity, lineID); err != nil {
+				return nil, fmt.Errorf("failed to update invoiced quantity: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invoice link: %w", err)
+	}
+	return sale, nil
+}
+
+// CloseSalesOrder cancels whatever has not shipped and releases its
+// reservation. Orders without deliveries end up CANCELLED, others CLOSED.
+func (s *SalesOrderService) CloseSalesOrder(companyID, orderID, userID int) (*models.SalesOrder, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	order, err := s.lockSalesOrderTx(tx, companyID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == models.SalesOrderStatusClosed || order.Status == models.SalesOrderStatusCancelled {
+		return nil, fmt.Errorf("sales order is already %s", strings.ToLower(order.Status))
+	}
+	lines, err := s.lockSalesOrderLinesTx(tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivered := false
+	for _, line := range lines {
+		if line.DeliveredQuantity > salesOrderQuantityEpsilon {
+			delivered = true
+		}
+		factor := normalizeProductUOMFactor(&line.SellingToStock)
+		if err := adjustReservedStockTx(tx, order.LocationID, line.ProductID, line.BarcodeID, -line.ReservedQuantity*factor); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`
+			UPDATE sales_order_lines
+			SET reserved_quantity = 0,
+			    cancelled_quantity = GREATEST(quantity - delivered_quantity, 0)
+			WHERE sales_order_line_id = $1
+		`, line.SalesOrderLineID); err != nil {
+			return nil, fmt.Errorf("failed to cancel open quantity: %w", err)
+		}
+	}
+
+	status := models.SalesOrderStatusCancelled
+	if delivered {
+		status = models.SalesOrderStatusClosed
+	}
+	if _, err := tx.Exec(`
+		UPDATE sales_orders
+		SET status = $1, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE sales_order_id = $3
+	`, status, userID, orderID); err != nil {
+		return nil, fmt.Errorf("failed to close sales order: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sales order: %w", err)
+	}
+	return s.GetSalesOrder(companyID, orderID)
+}
+
+// AllocateBackordersTx reserves newly received stock for backordered lines
+// at the location, oldest order first. It runs inside the receiving
+// transaction so the goods cannot be sold before the backorders are served;
+// goods receipts, transfer receipts, sale returns, assembly output and
+// consignment-in receipts all call it.
+func (s *SalesOrderService) AllocateBackordersTx(tx *sql.Tx, companyID, locationID int, barcodeIDs []int) ([]models.BackorderAllocation, error) {
+	var allocations []models.BackorderAllocation
+	for _, barcodeID := range uniqueInts(barcodeIDs) {
+		free, err := lockFreeStockTx(tx, locationID, barcodeID)
+		if err != nil {
+			return nil, err
+		}
+		if free <= salesOrderQuantityEpsilon {
+			continue
+		}
+
+		rows, err := tx.Query(`
+			SELECT l.sales_order_line_id, l.sales_order_id, l.product_id,
+			       (l.quantity - l.reserved_quantity - l.delivered_quantity - l.cancelled_quantity)::float8,
+			       l.selling_to_stock::float8
+			FROM sales_order_lines l
+			JOIN sales_orders o ON o.sales_order_id = l.sales_order_id
+			WHERE o.company_id = $1
+			  AND o.location_id = $2
+			  AND l.barcode_id = $3
+			  AND o.status IN ('OPEN', 'PARTIALLY_DELIVERED')
+			  AND l.quantity - l.reserved_quantity - l.delivered_quantity - l.cancelled_quantity > $4
+			ORDER BY o.order_date, o.sales_order_id, l.sales_order_line_id
+			FOR UPDATE OF l
+		`, companyID, locationID, barcodeID, salesOrderQuantityEpsilon)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load backorders: %w", err)
+		}
+		var (
+			pending []models.BackorderAllocation
+			needs   []backorderNeed
+			product int
+		)
+		for rows.Next() {
+			var allocation models.BackorderAllocation
+			var need backorderNeed
+			if err := rows.Scan(&allocation.SalesOrderLineID, &allocation.SalesOrderID, &product, &need.Quantity, &need.SellingToStock); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan backorder: %w", err)
+			}
+			allocation.BarcodeID = barcodeID
+			pending = append(pending, allocation)
+			needs = append(needs, need)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+
+		reservedStock := 0.0
+		for i, quantity := range allocateBackorders(free, needs) {
+			if quantity <= 0 {
+				continue
+			}
+			if _, err := tx.Exec(`
+				UPDATE sales_order_lines
+				SET reserved_quantity = reserved_quantity + $1
+				WHERE sales_order_line_id = $2
+			`, quantity, pending[i].SalesOrderLineID); err != nil {
+				return nil, fmt.Errorf("failed to allocate backorder: %w", err)
+			}
+			reservedStock += quantity * normalizeProductUOMFactor(&needs[i].SellingToStock)
+			pending[i].Quantity = quantity
+			allocations = append(allocations, pending[i])
+		}
+		if err := adjustReservedStockTx(tx, locationID, product, barcodeID, reservedStock); err != nil {
+			return nil, err
+		}
+	}
+	return allocations, nil
+}
+
+// ListSalesOrders supports status, customer_id and location_id filters.
+func (s *SalesOrderService) ListSalesOrders(companyID int, filters map[string]string) ([]models.SalesOrder, error) {
+	query := `
+		SELECT o.sales_order_id, o.order_number, o.location_id, o.customer_id, c.name, o.quote_id, o.order_date,
+		       o.expected_delivery_date, o.status, o.invoicing_mode, o.transaction_type, o.notes,
+		       COALESCE((
+		         SELECT SUM(l.quantity * l.unit_price * (1 - l.discount_percentage / 100))
+		         FROM sales_order_lines l WHERE l.sales_order_id = o.sales_order_id
+		       ), 0)::float8,
+		       o.created_by, o.created_at, o.updated_at
+		FROM sales_orders o
+		JOIN customers c ON c.customer_id = o.customer_id
+		WHERE o.company_id = $1
+	`
+	args := []interface{}{companyID}
+	if v := filters["status"]; v != "" {
+		args = append(args, strings.ToUpper(v))
+		query += fmt.Sprintf(" AND o.status = $%d", len(args))
+	}
+	for _, key := range []string{"customer_id", "location_id"} {
+		if v := filters[key]; v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", key)
+			}
+			args = append(args, id)
+			query += fmt.Sprintf(" AND o.%s = $%d", key, len(args))
+		}
+	}
+	query += " ORDER BY o.order_date DESC, o.sales_order_id DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.SalesOrder{}
+	for rows.Next() {
+		order, err := scanSalesOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, rows.Err()
+}
+
+func scanSalesOrder(scanner interface{ Scan(...interface{}) error }) (*models.SalesOrder, error) {
+	var order models.SalesOrder
+	var quoteID sql.NullInt64
+	var expected sql.NullTime
+	var notes sql.NullString
+	if err := scanner.Scan(&order.SalesOrderID, &order.OrderNumber, &order.LocationID, &order.CustomerID, &order.CustomerName,
+		&quoteID, &order.OrderDate, &expected, &order.Status, &order.InvoicingMode, &order.TransactionType, &notes,
+		&order.NetAmount, &order.CreatedBy, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sales order not found")
+		}
+		return nil, fmt.Errorf("failed to scan sales order: %w", err)
+	}
+	order.QuoteID = nullIntToPtr(quoteID)
+	if expected.Valid {
+		order.ExpectedDeliveryDate = &expected.Time
+	}
+	order.Notes = nullStringPtr(notes)
+	order.NetAmount = round2(order.NetAmount)
+	return &order, nil
+}
+
+// GetSalesOrder returns the order with its lines and deliveries.
+func (s *SalesOrderService) GetSalesOrder(companyID, orderID int) (*models.SalesOrder, error) {
+	order, err := scanSalesOrder(s.db.QueryRow(`
+		SELECT o.sales_order_id, o.order_number, o.location_id, o.customer_id, c.name, o.quote_id, o.order_date,
+		       o.expected_delivery_date, o.status, o.invoicing_mode, o.transaction_type, o.notes,
+		       COALESCE((
+		         SELECT SUM(l.quantity * l.unit_price * (1 - l.discount_percentage / 100))
+		         FROM sales_order_lines l WHERE l.sales_order_id = o.sales_order_id
+		       ), 0)::float8,
+		       o.created_by, o.created_at, o.updated_at
+		FROM sales_orders o
+		JOIN customers c ON c.customer_id = o.customer_id
+		WHERE o.sales_order_id = $1 AND o.company_id = $2
+	`, orderID, companyID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT sales_order_line_id, product_id, barcode_id, product_name, quantity::float8, unit_price::float8,
+		       discount_percentage::float8, tax_id, reserved_quantity::float8, delivered_quantity::float8,
+		       invoiced_quantity::float8, cancelled_quantity::float8, notes
+		FROM sales_order_lines
+		WHERE sales_order_id = $1
+		ORDER BY sales_order_line_id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales order lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		line := models.SalesOrderLine{SalesOrderID: orderID}
+		var productName, notes sql.NullString
+		var taxID sql.NullInt64
+		if err := rows.Scan(&line.SalesOrderLineID, &line.ProductID, &line.BarcodeID, &productName, &line.Quantity,
+			&line.UnitPrice, &line.DiscountPercent, &taxID, &line.ReservedQuantity, &line.DeliveredQuantity,
+			&line.InvoicedQuantity, &line.CancelledQuantity, &notes); err != nil {
+			return nil, fmt.Errorf("failed to scan sales order line: %w", err)
+		}
+		line.ProductName = nullStringPtr(productName)
+		line.TaxID = nullIntToPtr(taxID)
+		line.Notes = nullStringPtr(notes)
+		line.BackorderedQuantity = backorderedQuantity(line)
+		order.Lines = append(order.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.listDeliveries(orderID)
+	if err != nil {
+		return nil, err
+	}
+	order.Deliveries = deliveries
+	return order, nil
+}
+
+func (s *SalesOrderService) listDeliveries(orderID int) ([]models.SalesOrderDelivery, error) {
+	rows, err := s.db.Query(`
+		SELECT delivery_id, delivery_number, sales_order_id, location_id, delivery_date, invoice_sale_id, notes, created_by, created_at
+		FROM sales_order_deliveries
+		WHERE sales_order_id = $1
+		ORDER BY delivery_id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.SalesOrderDelivery
+	byID := map[int]int{}
+	ids := []int{}
+	for rows.Next() {
+		var delivery models.SalesOrderDelivery
+		var invoiceSaleID sql.NullInt64
+		var notes sql.NullString
+		if err := rows.Scan(&delivery.DeliveryID, &delivery.DeliveryNumber, &delivery.SalesOrderID, &delivery.LocationID,
+			&delivery.DeliveryDate, &invoiceSaleID, &notes, &delivery.CreatedBy, &delivery.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		delivery.InvoiceSaleID = nullIntToPtr(invoiceSaleID)
+		delivery.Notes = nullStringPtr(notes)
+		byID[delivery.DeliveryID] = len(deliveries)
+		ids = append(ids, delivery.DeliveryID)
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	lineRows, err := s.db.Query(`
+		SELECT dl.delivery_line_id, dl.delivery_id, dl.sales_order_line_id, ol.product_id, ol.barcode_id, ol.product_name,
+		       dl.quantity::float8, COALESCE(dl.serial_numbers, '{}'), dl.unit_cost::float8, dl.total_cost::float8
+		FROM sales_order_delivery_lines dl
+		JOIN sales_order_lines ol ON ol.sales_order_line_id = dl.sales_order_line_id
+		WHERE dl.delivery_id = ANY($1)
+		ORDER BY dl.delivery_id, dl.delivery_line_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery lines: %w", err)
+	}
+	defer lineRows.Close()
+	for lineRows.Next() {
+		var line models.SalesOrderDeliveryLine
+		var productName sql.NullString
+		if err := lineRows.Scan(&line.DeliveryLineID, &line.DeliveryID, &line.SalesOrderLineID, &line.ProductID, &line.BarcodeID,
+			&productName, &line.Quantity, pq.Array(&line.SerialNumbers), &line.UnitCost, &line.TotalCost); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery line: %w", err)
+		}
+		line.ProductName = nullStringPtr(productName)
+		idx := byID[line.DeliveryID]
+		deliveries[idx].Lines = append(deliveries[idx].Lines, line)
+	}
+	return deliveries, lineRows.Err()
+}
+
+func (s *SalesOrderService) getDelivery(companyID, deliveryID int) (*models.SalesOrderDelivery, error) {
+	var orderID int
+	err := s.db.QueryRow(`
+		SELECT sales_order_id FROM sales_order_deliveries
+		WHERE delivery_id = $1 AND company_id = $2
+	`, deliveryID, companyID).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("delivery not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	deliveries, err := s.listDeliveries(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deliveries {
+		if deliveries[i].DeliveryID == deliveryID {
+			return &deliveries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("delivery not found")
+}
+
+// ListBackorders returns open order lines that are neither reserved nor
+// delivered, oldest first — the order in which receipts will fill them.
+func (s *SalesOrderService) ListBackorders(companyID int, locationID *int) ([]models.SalesOrderBackorder, error) {
+	query := `
+		SELECT o.sales_order_id, o.order_number, o.order_date, o.expected_delivery_date, o.customer_id, c.name,
+		       o.location_id, l.sales_order_line_id, l.product_id, l.barcode_id, COALESCE(l.product_name, p.name),
+		       l.quantity::float8,
+		       (l.quantity - l.reserved_quantity - l.delivered_quantity - l.cancelled_quantity)::float8
+		FROM sales_order_lines l
+		JOIN sales_orders o ON o.sales_order_id = l.sales_order_id
+		JOIN customers c ON c.customer_id = o.customer_id
+		JOIN products p ON p.product_id = l.product_id
+		WHERE o.company_id = $1
+		  AND o.status IN ('OPEN', 'PARTIALLY_DELIVERED')
+		  AND l.quantity - l.reserved_quantity - l.delivered_quantity - l.cancelled_quantity > $2
+	`
+	args := []interface{}{companyID, salesOrderQuantityEpsilon}
+	if locationID != nil {
+		args = append(args, *locationID)
+		query += fmt.Sprintf(" AND o.location_id = $%d", len(args))
+	}
+	query += " ORDER BY o.order_date, o.sales_order_id, l.sales_order_line_id"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backorders: %w", err)
+	}
+	defer rows.Close()
+
+	backorders := []models.SalesOrderBackorder{}
+	for rows.Next() {
+		var item models.SalesOrderBackorder
+		var expected sql.NullTime
+		var productName sql.NullString
+		if err := rows.Scan(&item.SalesOrderID, &item.OrderNumber, &item.OrderDate, &expected, &item.CustomerID, &item.CustomerName,
+			&item.LocationID, &item.SalesOrderLineID, &item.ProductID, &item.BarcodeID, &productName,
+			&item.Quantity, &item.BackorderedQuantity); err != nil {
+			return nil, fmt.Errorf("failed to scan backorder: %w", err)
+		}
+		if expected.Valid {
+			item.ExpectedDeliveryDate = &expected.Time
+		}
+		item.ProductName = nullStringPtr(productName)
+		item.BackorderedQuantity = round4(item.BackorderedQuantity)
+		backorders = append(backorders, item)
+	}
+	return backorders, rows.Err()
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestSplitReservation(t *testing.T) {
+	if reserved, backordered := splitReservation(10, 4); reserved != 4 || backordered != 6 {
+		t.Fatalf("expected 4 reserved and 6 backordered, got %v/%v", reserved, backordered)
+	}
+	if reserved, backordered := splitReservation(10, 25); reserved != 10 || backordered != 0 {
+		t.Fatalf("expected full reservation, got %v/%v", reserved, backordered)
+	}
+	if reserved, backordered := splitReservation(5, -3); reserved != 0 || backordered != 5 {
+		t.Fatalf("expected negative free stock to backorder everything, got %v/%v", reserved, backordered)
+	}
+}
+
+func TestAllocateBackorders_OldestFirstWithUOM(t *testing.T) {
+	needs := []backorderNeed{
+		{Quantity: 2, SellingToStock: 12}, // two cases of twelve
+		{Quantity: 10, SellingToStock: 1},
+		{Quantity: 5, SellingToStock: 1},
+	}
+	got := allocateBackorders(30, needs)
+	want := []float64{2, 6, 0}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("allocation %d: expected %v, got %v (all %v)", i, want[i], got[i], got)
+		}
+	}
+}
+
+func TestSalesOrderStatusFor(t *testing.T) {
+	lines := []models.SalesOrderLine{
+		{Quantity: 10, DeliveredQuantity: 4},
+		{Quantity: 5},
+	}
+	if status := salesOrderStatusFor(lines); status != models.SalesOrderStatusPartiallyDelivered {
+		t.Fatalf("expected partially delivered, got %s", status)
+	}
+	lines[0].DeliveredQuantity = 10
+	lines[1].DeliveredQuantity = 3
+	lines[1].CancelledQuantity = 2
+	if status := salesOrderStatusFor(lines); status != models.SalesOrderStatusDelivered {
+		t.Fatalf("expected delivered, got %s", status)
+	}
+	if backordered := backorderedQuantity(models.SalesOrderLine{Quantity: 10, ReservedQuantity: 3, DeliveredQuantity: 2}); backordered != 5 {
+		t.Fatalf("expected 5 backordered, got %v", backordered)
+	}
+}
@@ -798,6 +798,15 @@ type CreateSaleOptions struct {
 	AutoFillRaffleCustomerData *bool
 	SourceChannel              string
 	TransactionType            string
+	// SkipCustomerRewards leaves promotions and loyalty points out of system
+	// generated invoices such as dunning late fees.
+	SkipCustomerRewards bool
+	// PreIssuedCosts marks items whose stock already left through sales order
+	// deliveries. It holds one cost per item and replaces the stock issue.
+	PreIssuedCosts []issuedSaleLineCost
+	// AllowExpiredStock lets the sale draw on expired lots when the company
+	// blocks them; POS sets it after a manager override.
+	AllowExpiredStock bool
 }
 
 func normalizeTransactionType(raw string) string {
@@ -834,7 +843,7 @@ func (s *SalesService) CreateSaleWithOptions(
 	// Check for applicable promotions (skip in training mode; training should not consume promotion logic by default).
 	var totalDiscount float64
 	var appliedPromotions []int
-	if !opts.IsTraining && req.CustomerID != nil {
+	if !opts.IsTraining && !opts.SkipCustomerRewards && req.CustomerID != nil {
 		loyaltyService := NewLoyaltyService()
 
 		// Calculate subtotal for promotion eligibility
@@ -1045,9 +1054,13 @@ func (s *SalesService) CreateSaleWithOptions(
 	if err != nil {
 		return nil, err
 	}
+	if opts.PreIssuedCosts != nil && len(opts.PreIssuedCosts) != len(preparedLines) {
+		return nil, fmt.Errorf("pre-issued costs do not match sale items")
+	}
 	actualCosts := make([]issuedSaleLineCost, 0, len(preparedLines))
+	var returnedBarcodeIDs []int
 
-	for _, line := range preparedLines {
+	for idx, line := range preparedLines {
 		var saleDetailID int
 		err = tx.QueryRow(`
 			INSERT INTO sale_details (sale_id, product_id, combo_product_id, barcode_id, product_name, quantity, unit_price,
@@ -1073,6 +1086,20 @@ func (s *SalesService) CreateSaleWithOptions(
 			continue
 		}
 
+		if opts.PreIssuedCosts != nil {
+			actualCost := opts.PreIssuedCosts[idx]
+			actualCosts = append(actualCosts, actualCost)
+			if _, err := tx.Exec(`
+				UPDATE sale_details
+				SET barcode_id = COALESCE($1, barcode_id),
+				    cost_price = $2
+				WHERE sale_detail_id = $3
+			`, actualCost.BarcodeID, actualCost.CostPricePerUnit, saleDetailID); err != nil {
+				return nil, fmt.Errorf("failed to update sale item cost snapshot: %w", err)
+			}
+			continue
+		}
+
 		if line.Quantity > 0 {
 			selection := inventorySelection{
 				ProductID:        *line.ProductID,
@@ -1083,6 +1110,7 @@ func (s *SalesService) CreateSaleWithOptions(
 				BatchAllocations: line.BatchAllocations,
 				Notes:            line.Notes,
 				OverridePassword: req.OverridePassword,
+				AllowExpired:     opts.AllowExpiredStock,
 			}
 
 			issue, err := trackingSvc.IssueStockTx(tx, companyID, locationID, userID, "SALE", "sale_detail", &saleDetailID, nil, selection)
@@ -1122,7 +1150,7 @@ func (s *SalesService) CreateSaleWithOptions(
 		if len(serialNumbers) == 0 {
 			serialNumbers = sourceLine.SerialNumbers
 		}
-		if _, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_detail", &saleDetailID, nil, inventorySelection{
+		variant, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_detail", &saleDetailID, nil, inventorySelection{
 			ProductID:      *line.ProductID,
 			BarcodeID:      line.BarcodeID,
 			ComboProductID: line.ComboProductID,
@@ -1130,9 +1158,11 @@ func (s *SalesService) CreateSaleWithOptions(
 			SerialNumbers:  serialNumbers,
 			UnitCost:       line.Snapshot.CostPricePerUnit,
 			Notes:          line.Notes,
-		}); err != nil {
+		})
+		if err != nil {
 			return nil, fmt.Errorf("failed to receive refund stock: %w", err)
 		}
+		returnedBarcodeIDs = append(returnedBarcodeIDs, variant.BarcodeID)
 		actualCosts = append(actualCosts, issuedSaleLineCost{})
 		if _, err := tx.Exec(`
 			UPDATE sale_details
@@ -1143,6 +1173,12 @@ func (s *SalesService) CreateSaleWithOptions(
 		}
 	}
 
+	// Serve waiting sales order backorders before refunded goods become
+	// free stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, locationID, returnedBarcodeIDs); err != nil {
+		return nil, err
+	}
+
 	if !opts.IsTraining {
 		profitDetails := buildProfitGuardDetails(preparedLines, actualCosts, req.DiscountAmount)
 		if err := s.enforceNegativeProfitPolicyTx(tx, companyID, req.OverridePassword, profitDetails); err != nil {
@@ -1178,6 +1214,9 @@ func (s *SalesService) CreateSaleWithOptions(
 		}); err != nil {
 			return nil, fmt.Errorf("failed to enqueue sale ledger posting: %w", err)
 		}
+		if err := enqueueEInvoiceTx(tx, finance, companyID, locationID, einvoiceSourceSale, saleID, userID); err != nil {
+			return nil, err
+		}
 
 		cashInAmount, err := s.resolveCashInAmountTx(tx, companyID, req, opts)
 		if err != nil {
@@ -1212,7 +1251,7 @@ func (s *SalesService) CreateSaleWithOptions(
 			}
 		}
 
-		if req.CustomerID != nil {
+		if req.CustomerID != nil && !opts.SkipCustomerRewards {
 			if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
 				CompanyID:     companyID,
 				LocationID:    &locationID,
@@ -1278,6 +1317,19 @@ func (s *SalesService) CreateSaleWithOptions(
 		}); err != nil {
 			return nil, fmt.Errorf("failed to enqueue raffle issuance: %w", err)
 		}
+
+		if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventSaleCreated, "sale", saleID, models.JSONB{
+			"sale_id":          saleID,
+			"sale_number":      saleNumber,
+			"location_id":      locationID,
+			"customer_id":      req.CustomerID,
+			"total_amount":     totalAmount,
+			"paid_amount":      req.PaidAmount,
+			"transaction_type": transactionType,
+			"source_channel":   sourceChannel,
+		}); err != nil {
+			return nil, err
+		}
 	}
 
 	// Commit transaction
@@ -1575,24 +1627,34 @@ func (s *SalesService) DeleteSale(saleID, companyID, userID int) error {
 		return fmt.Errorf("completed sales cannot be deleted")
 	}
 
-	query := `UPDATE sales s SET is_deleted = TRUE, updated_by = $2, updated_at = CURRENT_TIMESTAMP
-		FROM locations l WHERE s.sale_id = $1 AND s.location_id = l.location_id AND l.company_id = $3`
-
-	result, err := s.db.Exec(query, saleID, userID, companyID)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete sale: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	query := `UPDATE sales s SET is_deleted = TRUE, updated_by = $2, updated_at = CURRENT_TIMESTAMP
+		FROM locations l WHERE s.sale_id = $1 AND s.location_id = l.location_id AND l.company_id = $3
+		RETURNING s.sale_number`
+
+	var saleNumber string
+	err = tx.QueryRow(query, saleID, userID, companyID).Scan(&saleNumber)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("sale not found")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return fmt.Errorf("failed to delete sale: %w", err)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("sale not found")
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventSaleDeleted, "sale", saleID, models.JSONB{
+		"sale_id":     saleID,
+		"sale_number": saleNumber,
+		"status":      status,
+	}); err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 type refundableSaleLine struct {
@@ -1792,6 +1854,7 @@ func (s *SalesService) CreateRefundInvoice(companyID, sourceSaleID, userID int,
 	}
 
 	trackingSvc := newInventoryTrackingService(s.db)
+	var returnedBarcodeIDs []int
 	for _, line := range refundLines {
 		var refundDetailID int
 		err = tx.QueryRow(`
@@ -1821,7 +1884,7 @@ func (s *SalesService) CreateRefundInvoice(companyID, sourceSaleID, userID int,
 			if receivedStockQty < 0 {
 				receivedStockQty = 0
 			}
-			if _, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_detail", &refundDetailID, nil, inventorySelection{
+			variant, err := trackingSvc.ReceiveStockTx(tx, companyID, locationID, userID, "SALE_RETURN", "sale_detail", &refundDetailID, nil, inventorySelection{
 				ProductID:      *line.ProductID,
 				BarcodeID:      line.BarcodeID,
 				ComboProductID: line.ComboProductID,
@@ -1829,12 +1892,20 @@ func (s *SalesService) CreateRefundInvoice(companyID, sourceSaleID, userID int,
 				SerialNumbers:  line.SerialNumbers,
 				UnitCost:       line.CostPrice,
 				Notes:          req.Reason,
-			}); err != nil {
+			})
+			if err != nil {
 				return nil, fmt.Errorf("failed to receive refunded stock: %w", err)
 			}
+			returnedBarcodeIDs = append(returnedBarcodeIDs, variant.BarcodeID)
 		}
 	}
 
+	// Serve waiting sales order backorders before refunded goods become
+	// free stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, locationID, returnedBarcodeIDs); err != nil {
+		return nil, err
+	}
+
 	if refundPaidAbs > 0 {
 		if _, err := tx.Exec(`
 			UPDATE sales
@@ -1871,6 +1942,9 @@ func (s *SalesService) CreateRefundInvoice(companyID, sourceSaleID, userID int,
 		}); err != nil {
 			return nil, fmt.Errorf("failed to enqueue refund ledger posting: %w", err)
 		}
+		if err := enqueueEInvoiceTx(tx, finance, companyID, locationID, einvoiceSourceSale, refundSaleID, userID); err != nil {
+			return nil, err
+		}
 
 		if refundPaidAbs > 0 && paymentMethodID.Valid {
 			var paymentType string
@@ -1901,6 +1975,19 @@ func (s *SalesService) CreateRefundInvoice(companyID, sourceSaleID, userID int,
 				}
 			}
 		}
+
+		if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventSaleRefunded, "sale", refundSaleID, models.JSONB{
+			"sale_id":            refundSaleID,
+			"sale_number":        refundSaleNumber,
+			"source_sale_id":     sourceSaleID,
+			"source_sale_number": sourceSaleNumber,
+			"location_id":        locationID,
+			"customer_id":        nullIntToPtr(customerID),
+			"total_amount":       refundTotal,
+			"paid_amount":        refundPaidAmount,
+		}); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -2961,12 +3048,20 @@ func (s *SalesService) PrintQuote(quoteID, companyID int) error {
 	return nil
 }
 
-// ShareQuote marks a quote as sent and logs the share request.
+// ShareQuote marks a quote as sent. When an email address is given the
+// server-rendered PDF is mailed to it; delivery failures are logged so client
+// share sheets keep working without SMTP.
 func (s *SalesService) ShareQuote(quoteID, companyID int, req *models.ShareQuoteRequest) error {
 	if err := s.PrintQuote(quoteID, companyID); err != nil {
 		return err
 	}
 	log.Printf("sales_service: share requested for quote %d", quoteID)
+	if req != nil && req.Email != nil && strings.TrimSpace(*req.Email) != "" {
+		renderer := &DocumentRenderService{db: s.db}
+		if _, err := renderer.EmailDocument(companyID, models.DocumentTypeQuote, quoteID, &models.EmailDocumentRequest{Email: req.Email}); err != nil {
+			log.Printf("sales_service: failed to email quote %d: %v", quoteID, err)
+		}
+	}
 	return nil
 }
 
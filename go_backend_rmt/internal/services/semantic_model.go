@@ -0,0 +1,587 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// The semantic layer is the only vocabulary the custom report builder speaks.
+// Each model fixes its base tables, company predicate and optional joins; each
+// dimension and measure is a SQL fragment chosen here, never by the caller.
+// User input only ever reaches the database as bind parameters.
+
+type semanticJoin struct {
+	key  string
+	sql  string
+	deps []string
+}
+
+type semanticDimension struct {
+	key   string
+	label string
+	// expr renders the value as text; filterExpr (default expr) is what
+	// filters compare against, typed by filterType.
+	expr       string
+	filterExpr string
+	filterType string // text or date
+	// idExpr enables "<key>_id" filters so saved reports survive renames.
+	idExpr string
+	joins  []string
+}
+
+type semanticMeasure struct {
+	key    string
+	label  string
+	expr   string
+	format string // money, quantity, percent or count
+	joins  []string
+}
+
+type semanticModel struct {
+	key   string
+	label string
+	// from always joins locations as l when the model is location scoped.
+	from         string
+	where        string // uses $1 for the company
+	locationExpr string
+	dateExpr     string
+	joins        []semanticJoin
+	dimensions   []semanticDimension
+	measures     []semanticMeasure
+}
+
+func semanticDateDimensions(col string) []semanticDimension {
+	return []semanticDimension{
+		{key: "date", label: "Date", expr: "TO_CHAR(" + col + ", 'YYYY-MM-DD')", filterExpr: col, filterType: "date"},
+		{key: "week", label: "Week", expr: "TO_CHAR(" + col + ", 'IYYY-\"W\"IW')"},
+		{key: "month", label: "Month", expr: "TO_CHAR(" + col + ", 'YYYY-MM')"},
+		{key: "year", label: "Year", expr: "TO_CHAR(" + col + ", 'YYYY')"},
+		// Prefixed with the ISO day number so weekdays sort Monday first.
+		{key: "weekday", label: "Weekday", expr: "TO_CHAR(" + col + ", 'ID') || ' ' || TRIM(TO_CHAR(" + col + ", 'Day'))"},
+	}
+}
+
+func semanticProductJoins(productCol string) []semanticJoin {
+	return []semanticJoin{
+		{key: "p", sql: "LEFT JOIN products p ON p.product_id = " + productCol},
+		{key: "c", sql: "LEFT JOIN categories c ON c.category_id = p.category_id", deps: []string{"p"}},
+		{key: "b", sql: "LEFT JOIN brands b ON b.brand_id = p.brand_id", deps: []string{"p"}},
+	}
+}
+
+const semanticUserName = "COALESCE(NULLIF(TRIM(COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')), ''), u.username, 'Unknown')"
+
+func semanticProductDimensions(productIDCol, fallbackName string) []semanticDimension {
+	name := "COALESCE(p.name, 'Unknown')"
+	if fallbackName != "" {
+		name = "COALESCE(p.name, " + fallbackName + ", 'Unknown')"
+	}
+	return []semanticDimension{
+		{key: "category", label: "Category", expr: "COALESCE(c.name, 'Uncategorized')", idExpr: "p.category_id", joins: []string{"c"}},
+		{key: "brand", label: "Brand", expr: "COALESCE(b.name, 'No brand')", idExpr: "p.brand_id", joins: []string{"b"}},
+		{key: "product", label: "Product", expr: name, idExpr: productIDCol, joins: []string{"p"}},
+	}
+}
+
+func joinDimensions(groups ...[]semanticDimension) []semanticDimension {
+	var out []semanticDimension
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+func joinSemanticJoins(groups ...[]semanticJoin) []semanticJoin {
+	var out []semanticJoin
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+var semanticModels = []*semanticModel{
+	{
+		key:   "sales",
+		label: "Sales",
+		from: `sale_details sd
+		JOIN sales s ON s.sale_id = sd.sale_id
+		JOIN locations l ON l.location_id = s.location_id`,
+		where:        "l.company_id = $1 AND s.is_deleted = FALSE AND COALESCE(s.is_training, FALSE) = FALSE",
+		locationExpr: "s.location_id",
+		dateExpr:     "s.sale_date",
+		joins: joinSemanticJoins(semanticProductJoins("sd.product_id"), []semanticJoin{
+			{key: "cu", sql: "LEFT JOIN customers cu ON cu.customer_id = s.customer_id"},
+			{key: "u", sql: "LEFT JOIN users u ON u.user_id = s.created_by"},
+		}),
+		dimensions: joinDimensions(
+			semanticDateDimensions("s.sale_date"),
+			[]semanticDimension{{key: "location", label: "Location", expr: "l.name", idExpr: "s.location_id"}},
+			semanticProductDimensions("sd.product_id", "sd.product_name"),
+			[]semanticDimension{
+				{key: "customer", label: "Customer", expr: "COALESCE(cu.name, 'Walk-in')", idExpr: "s.customer_id", joins: []string{"cu"}},
+				{key: "cashier", label: "Cashier", expr: semanticUserName, idExpr: "s.created_by", joins: []string{"u"}},
+			},
+		),
+		measures: []semanticMeasure{
+			{key: "qty", label: "Quantity", expr: "COALESCE(SUM(sd.quantity), 0)::float8", format: "quantity"},
+			{key: "revenue", label: "Revenue", expr: "COALESCE(SUM(sd.line_total), 0)::float8", format: "money"},
+			{key: "cost", label: "Cost", expr: "COALESCE(SUM(sd.quantity * COALESCE(sd.cost_price, 0)), 0)::float8", format: "money"},
+			{key: "margin", label: "Margin", expr: "COALESCE(SUM(sd.line_total - sd.quantity * COALESCE(sd.cost_price, 0)), 0)::float8", format: "money"},
+			{key: "margin_pct", label: "Margin %", expr: "COALESCE(SUM(sd.line_total - sd.quantity * COALESCE(sd.cost_price, 0)) * 100 / NULLIF(SUM(sd.line_total), 0), 0)::float8", format: "percent"},
+			{key: "tax", label: "Tax", expr: "COALESCE(SUM(sd.tax_amount), 0)::float8", format: "money"},
+			{key: "gross", label: "Revenue incl. tax", expr: "COALESCE(SUM(sd.line_total + COALESCE(sd.tax_amount, 0)), 0)::float8", format: "money"},
+			{key: "transactions", label: "Transactions", expr: "COUNT(DISTINCT s.sale_id)::float8", format: "count"},
+		},
+	},
+	{
+		key:   "purchases",
+		label: "Purchases",
+		from: `purchase_details pd
+		JOIN purchases pu ON pu.purchase_id = pd.purchase_id
+		JOIN locations l ON l.location_id = pu.location_id`,
+		where:        "l.company_id = $1 AND pu.is_deleted = FALSE",
+		locationExpr: "pu.location_id",
+		dateExpr:     "pu.purchase_date",
+		joins: joinSemanticJoins(semanticProductJoins("pd.product_id"), []semanticJoin{
+			{key: "su", sql: "LEFT JOIN suppliers su ON su.supplier_id = pu.supplier_id"},
+			{key: "u", sql: "LEFT JOIN users u ON u.user_id = pu.created_by"},
+		}),
+		dimensions: joinDimensions(
+			semanticDateDimensions("pu.purchase_date"),
+			[]semanticDimension{{key: "location", label: "Location", expr: "l.name", idExpr: "pu.location_id"}},
+			semanticProductDimensions("pd.product_id", ""),
+			[]semanticDimension{
+				{key: "supplier", label: "Supplier", expr: "COALESCE(su.name, 'Unknown')", idExpr: "pu.supplier_id", joins: []string{"su"}},
+				{key: "user", label: "Entered by", expr: semanticUserName, idExpr: "pu.created_by", joins: []string{"u"}},
+			},
+		),
+		measures: []semanticMeasure{
+			{key: "qty", label: "Quantity", expr: "COALESCE(SUM(pd.quantity), 0)::float8", format: "quantity"},
+			{key: "received_qty", label: "Received quantity", expr: "COALESCE(SUM(pd.received_quantity), 0)::float8", format: "quantity"},
+			{key: "cost", label: "Cost", expr: "COALESCE(SUM(pd.line_total), 0)::float8", format: "money"},
+			{key: "tax", label: "Tax", expr: "COALESCE(SUM(pd.tax_amount), 0)::float8", format: "money"},
+			{key: "documents", label: "Purchases", expr: "COUNT(DISTINCT pu.purchase_id)::float8", format: "count"},
+		},
+	},
+	{
+		key:   "stock_movements",
+		label: "Stock movements",
+		from: `inventory_movements im
+		JOIN locations l ON l.location_id = im.location_id`,
+		where:        "im.company_id = $1",
+		locationExpr: "im.location_id",
+		dateExpr:     "im.occurred_at::date",
+		joins: joinSemanticJoins(semanticProductJoins("im.product_id"), []semanticJoin{
+			{key: "u", sql: "LEFT JOIN users u ON u.user_id = im.created_by"},
+		}),
+		dimensions: joinDimensions(
+			semanticDateDimensions("im.occurred_at::date"),
+			[]semanticDimension{{key: "location", label: "Location", expr: "l.name", idExpr: "im.location_id"}},
+			semanticProductDimensions("im.product_id", ""),
+			[]semanticDimension{
+				{key: "movement_type", label: "Movement type", expr: "im.movement_type"},
+				{key: "source_type", label: "Source", expr: "im.source_type"},
+				{key: "user", label: "User", expr: semanticUserName, idExpr: "im.created_by", joins: []string{"u"}},
+			},
+		),
+		measures: []semanticMeasure{
+			{key: "qty", label: "Net quantity", expr: "COALESCE(SUM(im.quantity), 0)::float8", format: "quantity"},
+			{key: "qty_in", label: "Quantity in", expr: "COALESCE(SUM(CASE WHEN im.quantity > 0 THEN im.quantity ELSE 0 END), 0)::float8", format: "quantity"},
+			{key: "qty_out", label: "Quantity out", expr: "COALESCE(SUM(CASE WHEN im.quantity < 0 THEN -im.quantity ELSE 0 END), 0)::float8", format: "quantity"},
+			{key: "cost", label: "Net cost", expr: "COALESCE(SUM(im.total_cost), 0)::float8", format: "money"},
+			{key: "movements", label: "Movements", expr: "COUNT(*)::float8", format: "count"},
+		},
+	},
+	{
+		key:   "ledger",
+		label: "General ledger",
+		from: `ledger_entries le
+		JOIN chart_of_accounts a ON a.account_id = le.account_id`,
+		where:    "le.company_id = $1",
+		dateExpr: "le.date",
+		dimensions: joinDimensions(
+			semanticDateDimensions("le.date"),
+			[]semanticDimension{
+				{key: "account", label: "Account", expr: "TRIM(COALESCE(a.account_code, '') || ' ' || a.name)", idExpr: "le.account_id"},
+				{key: "account_type", label: "Account type", expr: "a.type"},
+				{key: "transaction_type", label: "Source type", expr: "COALESCE(le.transaction_type, 'manual')"},
+			},
+		),
+		measures: []semanticMeasure{
+			{key: "debit", label: "Debit", expr: "COALESCE(SUM(le.debit), 0)::float8", format: "money"},
+			{key: "credit", label: "Credit", expr: "COALESCE(SUM(le.credit), 0)::float8", format: "money"},
+			{key: "net", label: "Net (debit - credit)", expr: "COALESCE(SUM(le.debit - le.credit), 0)::float8", format: "money"},
+			{key: "entries", label: "Entries", expr: "COUNT(*)::float8", format: "count"},
+		},
+	},
+}
+
+func findSemanticModel(key string) *semanticModel {
+	for _, m := range semanticModels {
+		if m.key == key {
+			return m
+		}
+	}
+	return nil
+}
+
+func (m *semanticModel) dimension(key string) *semanticDimension {
+	for i := range m.dimensions {
+		if m.dimensions[i].key == key {
+			return &m.dimensions[i]
+		}
+	}
+	return nil
+}
+
+func (m *semanticModel) measure(key string) *semanticMeasure {
+	for i := range m.measures {
+		if m.measures[i].key == key {
+			return &m.measures[i]
+		}
+	}
+	return nil
+}
+
+// semanticCatalog lists the models for the report builder UI.
+func semanticCatalog() []models.SemanticModelInfo {
+	out := make([]models.SemanticModelInfo, 0, len(semanticModels))
+	for _, m := range semanticModels {
+		info := models.SemanticModelInfo{Key: m.key, Label: m.label, LocationScoped: m.locationExpr != ""}
+		for _, d := range m.dimensions {
+			typ := d.filterType
+			if typ == "" {
+				typ = "text"
+			}
+			info.Dimensions = append(info.Dimensions, models.SemanticFieldInfo{
+				Key: d.key, Label: d.label, Type: typ, Filterable: true, HasID: d.idExpr != "",
+			})
+		}
+		for _, ms := range m.measures {
+			info.Measures = append(info.Measures, models.SemanticFieldInfo{Key: ms.key, Label: ms.label, Type: ms.format})
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+const (
+	customReportDefaultLimit   = 1000
+	customReportMaxLimit       = 10000
+	customReportMaxPivotValues = 60
+)
+
+// customReportScope is who the query runs for. Empty LocationIDs means every
+// location in the company.
+type customReportScope struct {
+	CompanyID   int
+	LocationIDs []int
+}
+
+type compiledCustomReport struct {
+	model      *semanticModel
+	sql        string
+	args       []interface{}
+	dimensions []*semanticDimension
+	measures   []*semanticMeasure
+	limit      int
+}
+
+// compileCustomReport validates def against its model and renders one
+// parameterized statement. now (in the caller's timezone) resolves DateRange.
+func compileCustomReport(def models.CustomReportDefinition, scope customReportScope, now time.Time) (*compiledCustomReport, error) {
+	m := findSemanticModel(def.Model)
+	if m == nil {
+		return nil, fmt.Errorf("unknown report model: %s", def.Model)
+	}
+	if scope.CompanyID <= 0 {
+		return nil, fmt.Errorf("company is required")
+	}
+	if len(scope.LocationIDs) > 0 && m.locationExpr == "" {
+		return nil, fmt.Errorf("%s data is not location scoped and requires access to all locations", m.label)
+	}
+	if len(def.Measures) == 0 {
+		return nil, fmt.Errorf("at least one measure is required")
+	}
+
+	c := &compiledCustomReport{model: m, args: []interface{}{scope.CompanyID}}
+	needed := map[string]bool{}
+	seen := map[string]bool{}
+	for _, key := range def.Dimensions {
+		d := m.dimension(key)
+		if d == nil {
+			return nil, fmt.Errorf("unknown dimension %q for %s", key, m.key)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("dimension %q is listed twice", key)
+		}
+		seen[key] = true
+		c.dimensions = append(c.dimensions, d)
+		for _, j := range d.joins {
+			needed[j] = true
+		}
+	}
+	for _, key := range def.Measures {
+		ms := m.measure(key)
+		if ms == nil {
+			return nil, fmt.Errorf("unknown measure %q for %s", key, m.key)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("field %q is listed twice", key)
+		}
+		seen[key] = true
+		c.measures = append(c.measures, ms)
+		for _, j := range ms.joins {
+			needed[j] = true
+		}
+	}
+	if def.Pivot != nil {
+		if !containsString(def.Dimensions, def.Pivot.Dimension) {
+			return nil, fmt.Errorf("pivot dimension must be one of the selected dimensions")
+		}
+		if len(def.Dimensions) < 2 {
+			return nil, fmt.Errorf("pivot needs at least one other dimension for rows")
+		}
+	}
+
+	where := []string{m.where}
+	bind := func(v interface{}) string {
+		c.args = append(c.args, v)
+		return fmt.Sprintf("$%d", len(c.args))
+	}
+	if len(scope.LocationIDs) > 0 {
+		where = append(where, m.locationExpr+" = ANY("+bind(pq.Array(scope.LocationIDs))+")")
+	}
+
+	from, to, err := customReportDates(def, now)
+	if err != nil {
+		return nil, err
+	}
+	if from != "" {
+		where = append(where, m.dateExpr+" >= "+bind(from)+"::date")
+	}
+	if to != "" {
+		where = append(where, m.dateExpr+" <= "+bind(to)+"::date")
+	}
+
+	for _, f := range def.Filters {
+		clause, joins, err := compileCustomReportFilter(m, f, bind)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range joins {
+			needed[j] = true
+		}
+		where = append(where, clause)
+	}
+
+	var selects, groups []string
+	for _, d := range c.dimensions {
+		selects = append(selects, d.expr+" AS \""+d.key+"\"")
+		groups = append(groups, d.expr)
+	}
+	for _, ms := range c.measures {
+		selects = append(selects, ms.expr+" AS \""+ms.key+"\"")
+	}
+
+	var order []string
+	for _, s := range def.Sort {
+		dir := "ASC"
+		if strings.EqualFold(s.Direction, "desc") {
+			dir = "DESC"
+		}
+		if d := m.dimension(s.Field); d != nil && seen[s.Field] {
+			order = append(order, d.expr+" "+dir)
+			continue
+		}
+		if ms := m.measure(s.Field); ms != nil && seen[s.Field] {
+			order = append(order, ms.expr+" "+dir)
+			continue
+		}
+		return nil, fmt.Errorf("sort field %q must be a selected dimension or measure", s.Field)
+	}
+	if len(order) == 0 {
+		for _, d := range c.dimensions {
+			order = append(order, d.expr+" ASC")
+		}
+	}
+
+	c.limit = def.Limit
+	if c.limit <= 0 {
+		c.limit = customReportDefaultLimit
+	}
+	if c.limit > customReportMaxLimit {
+		return nil, fmt.Errorf("limit must not exceed %d", customReportMaxLimit)
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(selects, ",\n       "))
+	b.WriteString("\nFROM ")
+	b.WriteString(m.from)
+	resolveSemanticJoins(m, needed)
+	for _, j := range m.joins {
+		if needed[j.key] {
+			b.WriteString("\n")
+			b.WriteString(j.sql)
+		}
+	}
+	b.WriteString("\nWHERE ")
+	b.WriteString(strings.Join(where, "\n  AND "))
+	if len(groups) > 0 {
+		b.WriteString("\nGROUP BY ")
+		b.WriteString(strings.Join(groups, ", "))
+	}
+	if len(order) > 0 {
+		b.WriteString("\nORDER BY ")
+		b.WriteString(strings.Join(order, ", "))
+	}
+	// One extra row tells the caller the result was cut off.
+	b.WriteString("\nLIMIT " + bind(c.limit+1))
+	c.sql = b.String()
+	return c, nil
+}
+
+// resolveSemanticJoins adds the joins that needed ones depend on.
+func resolveSemanticJoins(m *semanticModel, needed map[string]bool) {
+	for changed := true; changed; {
+		changed = false
+		for _, j := range m.joins {
+			if !needed[j.key] {
+				continue
+			}
+			for _, dep := range j.deps {
+				if !needed[dep] {
+					needed[dep] = true
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func compileCustomReportFilter(m *semanticModel, f models.CustomReportFilter, bind func(interface{}) string) (string, []string, error) {
+	field := strings.TrimSpace(f.Field)
+	var (
+		expr, typ string
+		joins     []string
+	)
+	if d := m.dimension(field); d != nil {
+		expr, typ, joins = d.expr, "text", d.joins
+		if d.filterExpr != "" {
+			expr = d.filterExpr
+		}
+		if d.filterType != "" {
+			typ = d.filterType
+		}
+	} else if d := m.dimension(strings.TrimSuffix(field, "_id")); d != nil && strings.HasSuffix(field, "_id") && d.idExpr != "" {
+		expr, typ = d.idExpr, "int"
+		// The id columns live on the base tables except for product attributes.
+		if strings.HasPrefix(d.idExpr, "p.") {
+			joins = []string{"p"}
+		}
+	} else {
+		return "", nil, fmt.Errorf("unknown filter field %q for %s", field, m.key)
+	}
+
+	values := f.Values
+	for _, v := range values {
+		if err := checkCustomReportFilterValue(typ, v); err != nil {
+			return "", nil, fmt.Errorf("filter %s: %w", field, err)
+		}
+	}
+	cast := map[string]string{"text": "::text", "date": "::date", "int": "::int"}[typ]
+	one := func() (string, error) {
+		if len(values) != 1 {
+			return "", fmt.Errorf("filter %s: %s takes exactly one value", field, f.Op)
+		}
+		return bind(values[0]) + cast, nil
+	}
+
+	switch f.Op {
+	case "eq", "neq", "gte", "lte":
+		p, err := one()
+		if err != nil {
+			return "", nil, err
+		}
+		op := map[string]string{"eq": "=", "neq": "<>", "gte": ">=", "lte": "<="}[f.Op]
+		if f.Op == "neq" {
+			return "(" + expr + " IS NULL OR " + expr + " " + op + " " + p + ")", joins, nil
+		}
+		return expr + " " + op + " " + p, joins, nil
+	case "between":
+		if len(values) != 2 {
+			return "", nil, fmt.Errorf("filter %s: between takes two values", field)
+		}
+		return expr + " BETWEEN " + bind(values[0]) + cast + " AND " + bind(values[1]) + cast, joins, nil
+	case "in", "not_in":
+		arr := bind(pq.Array(values)) + cast + "[]"
+		if f.Op == "in" {
+			return expr + " = ANY(" + arr + ")", joins, nil
+		}
+		return "(" + expr + " IS NULL OR NOT (" + expr + " = ANY(" + arr + ")))", joins, nil
+	case "contains":
+		if typ != "text" {
+			return "", nil, fmt.Errorf("filter %s: contains only applies to text fields", field)
+		}
+		if len(values) != 1 {
+			return "", nil, fmt.Errorf("filter %s: contains takes exactly one value", field)
+		}
+		p := bind(escapeLikePattern(values[0]))
+		return expr + " ILIKE '%' || " + p + " || '%' ESCAPE '\\'", joins, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported filter operator: %s", f.Op)
+	}
+}
+
+func checkCustomReportFilterValue(typ, v string) error {
+	switch typ {
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("%q is not a YYYY-MM-DD date", v)
+		}
+	case "int":
+		var n int
+		if _, err := fmt.Sscanf(v, "%d", &n); err != nil || fmt.Sprint(n) != v {
+			return fmt.Errorf("%q is not an id", v)
+		}
+	}
+	return nil
+}
+
+// escapeLikePattern makes a contains filter match its value literally.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func customReportDates(def models.CustomReportDefinition, now time.Time) (string, string, error) {
+	if def.DateRange != "" {
+		return resolveReportDateRange(def.DateRange, now)
+	}
+	for _, d := range []struct{ name, value string }{{"from_date", def.FromDate}, {"to_date", def.ToDate}} {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d.value); err != nil {
+			return "", "", fmt.Errorf("%s must be YYYY-MM-DD", d.name)
+		}
+	}
+	if def.FromDate != "" && def.ToDate != "" && def.FromDate > def.ToDate {
+		return "", "", fmt.Errorf("from_date must not be after to_date")
+	}
+	return def.FromDate, def.ToDate, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"erp-backend/internal/utils"
@@ -27,6 +28,9 @@ type inventorySettingsRecord struct {
 	NegativeStockPolicy               string `json:"negative_stock_policy,omitempty"`
 	NegativeProfitPolicy              string `json:"negative_profit_policy,omitempty"`
 	NegativeStockApprovalPasswordHash string `json:"negative_stock_approval_password_hash,omitempty"`
+	BatchIssuePolicy                  string `json:"batch_issue_policy,omitempty"`
+	BlockExpiredSales                 bool   `json:"block_expired_sales,omitempty"`
+	ExpiryAlertDays                   int    `json:"expiry_alert_days,omitempty"`
 }
 
 // NewSettingsService creates a new SettingsService
@@ -196,11 +200,17 @@ func (s *SettingsService) GetInventorySettings(companyID int) (*models.Inventory
 	record.InventoryCostingMethod = normalizeCostingMethod(record.InventoryCostingMethod)
 	record.NegativeStockPolicy = normalizeNegativeStockPolicy(record.NegativeStockPolicy)
 	record.NegativeProfitPolicy = normalizeNegativeStockPolicy(record.NegativeProfitPolicy)
+	if record.ExpiryAlertDays <= 0 {
+		record.ExpiryAlertDays = defaultExpiryAlertDays
+	}
 	return &models.InventorySettings{
 		InventoryCostingMethod:           record.InventoryCostingMethod,
 		NegativeStockPolicy:              record.NegativeStockPolicy,
 		NegativeProfitPolicy:             record.NegativeProfitPolicy,
 		HasNegativeStockApprovalPassword: strings.TrimSpace(record.NegativeStockApprovalPasswordHash) != "",
+		BatchIssuePolicy:                 normalizeBatchIssuePolicy(record.BatchIssuePolicy),
+		BlockExpiredSales:                record.BlockExpiredSales,
+		ExpiryAlertDays:                  record.ExpiryAlertDays,
 	}, nil
 }
 
@@ -241,6 +251,18 @@ func (s *SettingsService) applyInventorySettingsTx(tx *sql.Tx, companyID int, re
 	if existing.NegativeProfitPolicy == "" {
 		existing.NegativeProfitPolicy = negativeStockPolicyDisallow
 	}
+	if req.BatchIssuePolicy != nil {
+		existing.BatchIssuePolicy = normalizeBatchIssuePolicy(*req.BatchIssuePolicy)
+	}
+	if req.BlockExpiredSales != nil {
+		existing.BlockExpiredSales = *req.BlockExpiredSales
+	}
+	if req.ExpiryAlertDays != nil {
+		if *req.ExpiryAlertDays < 1 || *req.ExpiryAlertDays > 365 {
+			return fmt.Errorf("expiry_alert_days must be between 1 and 365")
+		}
+		existing.ExpiryAlertDays = *req.ExpiryAlertDays
+	}
 
 	requiresApprovalPassword := existing.NegativeStockPolicy == negativeStockPolicyApproval ||
 		existing.NegativeProfitPolicy == negativeStockPolicyApproval
@@ -324,6 +346,42 @@ func (s *SettingsService) UpdateDeviceControlSettings(companyID int, cfg models.
 	return s.updateJSONSetting(companyID, "device_control", cfg)
 }
 
+// Three-way match settings
+func (s *SettingsService) GetThreeWayMatchSettings(companyID int) (*models.ThreeWayMatchSettings, error) {
+	var cfg models.ThreeWayMatchSettings
+	if err := s.getJSONSetting(companyID, "three_way_match", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (s *SettingsService) UpdateThreeWayMatchSettings(companyID int, cfg models.ThreeWayMatchSettings) error {
+	return s.updateJSONSetting(companyID, "three_way_match", cfg)
+}
+
+// Dunning settings
+func (s *SettingsService) GetDunningSettings(companyID int) (*models.DunningSettings, error) {
+	cfg := &models.DunningSettings{Levels: []models.DunningLevel{}}
+	if err := s.getJSONSetting(companyID, "dunning", cfg); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(cfg.Levels, func(i, j int) bool { return cfg.Levels[i].Level < cfg.Levels[j].Level })
+	return cfg, nil
+}
+
+func (s *SettingsService) UpdateDunningSettings(companyID int, cfg models.DunningSettings) error {
+	sort.SliceStable(cfg.Levels, func(i, j int) bool { return cfg.Levels[i].Level < cfg.Levels[j].Level })
+	for i := 1; i < len(cfg.Levels); i++ {
+		if cfg.Levels[i].Level == cfg.Levels[i-1].Level {
+			return fmt.Errorf("duplicate dunning level %d", cfg.Levels[i].Level)
+		}
+		if cfg.Levels[i].DaysOverdue <= cfg.Levels[i-1].DaysOverdue {
+			return fmt.Errorf("dunning level %d must start after level %d", cfg.Levels[i].Level, cfg.Levels[i-1].Level)
+		}
+	}
+	return s.updateJSONSetting(companyID, "dunning", cfg)
+}
+
 // Security policy settings
 func (s *SettingsService) GetSecurityPolicy(companyID int) (*models.SecurityPolicySettings, error) {
 	defaults := utils.DefaultPasswordPolicy()
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const maxExpiryHorizonDays = 730
+
+var defaultExpiryHorizons = []int{30, 60, 90}
+
+// StockExpiryService reports on expiring batches and quarantines expired ones.
+type StockExpiryService struct {
+	db *sql.DB
+}
+
+func NewStockExpiryService() *StockExpiryService {
+	return &StockExpiryService{db: database.GetDB()}
+}
+
+// normalizeExpiryHorizons sorts and de-duplicates report horizons, falling
+// back to 30/60/90 days.
+func normalizeExpiryHorizons(horizons []int) ([]int, error) {
+	if len(horizons) == 0 {
+		return append([]int(nil), defaultExpiryHorizons...), nil
+	}
+	seen := make(map[int]struct{}, len(horizons))
+	out := make([]int, 0, len(horizons))
+	for _, days := range horizons {
+		if days < 1 || days > maxExpiryHorizonDays {
+			return nil, fmt.Errorf("horizons must be between 1 and %d days", maxExpiryHorizonDays)
+		}
+		if _, ok := seen[days]; ok {
+			continue
+		}
+		seen[days] = struct{}{}
+		out = append(out, days)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// expiryBucket places a lot in the first horizon it falls within.
+func expiryBucket(daysToExpiry int, horizons []int) string {
+	if daysToExpiry < 0 {
+		return models.StockExpiryBucketExpired
+	}
+	for _, days := range horizons {
+		if daysToExpiry <= days {
+			return fmt.Sprintf("WITHIN_%d_DAYS", days)
+		}
+	}
+	return ""
+}
+
+func (s *StockExpiryService) GetExpiryReport(companyID int, locationID *int, horizons []int) (*models.StockExpiryReport, error) {
+	horizons, err := normalizeExpiryHorizons(horizons)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := loadCompanyInventoryPolicy(s.db, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT sl.lot_id, sl.location_id, l.name, sl.product_id, p.name, sl.barcode_id, pb.barcode,
+		       sl.batch_number, sl.expiry_date, (sl.expiry_date - CURRENT_DATE)::int,
+		       sl.remaining_quantity::float8,
+		       CASE WHEN $4 = 'WAC' THEN COALESCE(sv.average_cost, sl.cost_price) ELSE sl.cost_price END::float8
+		FROM stock_lots sl
+		JOIN locations l ON l.location_id = sl.location_id
+		JOIN products p ON p.product_id = sl.product_id
+		LEFT JOIN product_barcodes pb ON pb.barcode_id = sl.barcode_id
+		LEFT JOIN stock_variants sv ON sv.location_id = sl.location_id AND sv.barcode_id = sl.barcode_id
+		WHERE sl.company_id = $1
+		  AND ($2::int IS NULL OR sl.location_id = $2)
+		  AND sl.remaining_quantity > 0
+		  AND sl.expiry_date IS NOT NULL
+		  AND sl.expiry_date <= CURRENT_DATE + $3::int
+		  AND p.is_deleted = FALSE
+		ORDER BY sl.expiry_date, l.name, p.name, sl.lot_id
+	`, companyID, locationID, horizons[len(horizons)-1], policy.CostingMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring lots: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.StockExpiryReport{
+		AsOf:     time.Now().Format("2006-01-02"),
+		Horizons: horizons,
+		Lots:     make([]models.StockExpiryLot, 0),
+	}
+	totals := make(map[string]*models.StockExpiryBucket)
+	for rows.Next() {
+		var lot models.StockExpiryLot
+		if err := rows.Scan(&lot.LotID, &lot.LocationID, &lot.LocationName, &lot.ProductID, &lot.ProductName,
+			&lot.BarcodeID, &lot.Barcode, &lot.BatchNumber, &lot.ExpiryDate, &lot.DaysToExpiry,
+			&lot.RemainingQuantity, &lot.UnitCost); err != nil {
+			return nil, fmt.Errorf("failed to scan expiring lot: %w", err)
+		}
+		lot.Value = round2(lot.RemainingQuantity * lot.UnitCost)
+		lot.Bucket = expiryBucket(lot.DaysToExpiry, horizons)
+		bucket, ok := totals[lot.Bucket]
+		if !ok {
+			bucket = &models.StockExpiryBucket{Bucket: lot.Bucket}
+			totals[lot.Bucket] = bucket
+		}
+		bucket.LotCount++
+		bucket.Quantity += lot.RemainingQuantity
+		bucket.Value = round2(bucket.Value + lot.Value)
+		report.Lots = append(report.Lots, lot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expiring lots: %w", err)
+	}
+
+	report.Buckets = make([]models.StockExpiryBucket, 0, len(horizons)+1)
+	expired := models.StockExpiryBucket{Bucket: models.StockExpiryBucketExpired}
+	if bucket, ok := totals[expired.Bucket]; ok {
+		expired = *bucket
+	}
+	report.Buckets = append(report.Buckets, expired)
+	for _, days := range horizons {
+		maxDays := days
+		entry := models.StockExpiryBucket{Bucket: expiryBucket(days, horizons), MaxDays: &maxDays}
+		if bucket, ok := totals[entry.Bucket]; ok {
+			entry.LotCount, entry.Quantity, entry.Value = bucket.LotCount, bucket.Quantity, bucket.Value
+		}
+		report.Buckets = append(report.Buckets, entry)
+	}
+	return report, nil
+}
+
+type expiredLot struct {
+	LotID       int
+	ProductID   int
+	BarcodeID   *int
+	BatchNumber *string
+	ExpiryDate  *time.Time
+	Quantity    float64
+	CostPrice   float64
+}
+
+// QuarantineExpiredStock moves expired lots out of sellable stock and posts
+// their cost to inventory shrinkage. Quarantined quantity stays on the lot.
+func (s *StockExpiryService) QuarantineExpiredStock(companyID, locationID, userID int, req *models.QuarantineExpiredStockRequest) (*models.StockExpiryWriteOff, error) {
+	if locationID == 0 {
+		return nil, fmt.Errorf("location is required")
+	}
+	lotIDs := make([]int, 0, len(req.LotIDs))
+	seen := make(map[int]struct{}, len(req.LotIDs))
+	for _, id := range req.LotIDs {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			lotIDs = append(lotIDs, id)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT lot_id, product_id, barcode_id, batch_number, expiry_date,
+		       remaining_quantity::float8, cost_price::float8
+		FROM stock_lots
+		WHERE company_id = $1
+		  AND location_id = $2
+		  AND remaining_quantity > 0
+		  AND expiry_date < CURRENT_DATE
+		  AND (cardinality($3::int[]) = 0 OR lot_id = ANY($3::int[]))
+		ORDER BY expiry_date, lot_id
+		FOR UPDATE
+	`, companyID, locationID, pq.Array(lotIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expired lots: %w", err)
+	}
+	lots := make([]expiredLot, 0)
+	for rows.Next() {
+		var lot expiredLot
+		if err := rows.Scan(&lot.LotID, &lot.ProductID, &lot.BarcodeID, &lot.BatchNumber, &lot.ExpiryDate, &lot.Quantity, &lot.CostPrice); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan expired lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load expired lots: %w", err)
+	}
+	if len(lotIDs) > 0 && len(lots) != len(lotIDs) {
+		return nil, fmt.Errorf("one or more lots are not expired stock at this location")
+	}
+	if len(lots) == 0 {
+		return nil, fmt.Errorf("no expired stock to quarantine")
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	policy, err := trackingSvc.getCompanyInventoryPolicyTx(tx, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	writeOff := &models.StockExpiryWriteOff{CompanyID: companyID, LocationID: locationID, Reason: req.Reason, CreatedBy: userID}
+	if err := tx.QueryRow(`
+		INSERT INTO stock_expiry_write_offs (company_id, location_id, reason, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING write_off_id, created_at
+	`, companyID, locationID, req.Reason, userID).Scan(&writeOff.WriteOffID, &writeOff.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create expiry write-off: %w", err)
+	}
+	sourceRef := fmt.Sprintf("EXP-WO-%d", writeOff.WriteOffID)
+	touchedProducts := make(map[int]struct{})
+
+	for _, lot := range lots {
+		variant, err := trackingSvc.resolveVariantTx(tx, companyID, lot.ProductID, lot.BarcodeID)
+		if err != nil {
+			return nil, err
+		}
+		unitCost := lot.CostPrice
+		if policy.CostingMethod == costingMethodWAC {
+			if err := tx.QueryRow(`
+				SELECT COALESCE(average_cost, 0)::float8
+				FROM stock_variants
+				WHERE location_id = $1 AND barcode_id = $2
+			`, locationID, variant.BarcodeID).Scan(&unitCost); err != nil && err != sql.ErrNoRows {
+				return nil, fmt.Errorf("failed to get average cost: %w", err)
+			}
+		}
+
+		line := models.StockExpiryWriteOffLine{
+			WriteOffID:  writeOff.WriteOffID,
+			LotID:       lot.LotID,
+			ProductID:   lot.ProductID,
+			BarcodeID:   &variant.BarcodeID,
+			BatchNumber: lot.BatchNumber,
+			ExpiryDate:  lot.ExpiryDate,
+			Quantity:    lot.Quantity,
+			UnitCost:    round4(unitCost),
+			TotalCost:   round2(lot.Quantity * unitCost),
+		}
+		serials, err := s.quarantineLotSerialsTx(tx, companyID, locationID, lot.LotID)
+		if err != nil {
+			return nil, err
+		}
+		for _, serial := range serials {
+			line.SerialNumbers = append(line.SerialNumbers, serial.SerialNumber)
+		}
+		if err := tx.QueryRow(`
+			INSERT INTO stock_expiry_write_off_lines (
+				write_off_id, lot_id, product_id, barcode_id, batch_number, expiry_date,
+				quantity, unit_cost, total_cost, serial_numbers
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+			RETURNING write_off_line_id
+		`, line.WriteOffID, line.LotID, line.ProductID, line.BarcodeID, line.BatchNumber, line.ExpiryDate,
+			line.Quantity, line.UnitCost, line.TotalCost, pq.Array(line.SerialNumbers)).Scan(&line.WriteOffLineID); err != nil {
+			return nil, fmt.Errorf("failed to create expiry write-off line: %w", err)
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE stock_lots
+			SET quarantined_quantity = quarantined_quantity + remaining_quantity,
+			    remaining_quantity = 0,
+			    quarantined_at = CURRENT_TIMESTAMP
+			WHERE lot_id = $1
+		`, lot.LotID); err != nil {
+			return nil, fmt.Errorf("failed to quarantine stock lot: %w", err)
+		}
+
+		lotID := lot.LotID
+		if len(serials) > 0 {
+			for _, serial := range serials {
+				serialID := serial.ProductSerialID
+				if err := trackingSvc.createMovementTx(tx, companyID, locationID, variant, "EXPIRY_QUARANTINE", "stock_expiry_write_off_line", &line.WriteOffLineID, &sourceRef, nil, &lotID, &serialID, -1, unitCost, userID, req.Reason); err != nil {
+					return nil, err
+				}
+			}
+		} else if err := trackingSvc.createMovementTx(tx, companyID, locationID, variant, "EXPIRY_QUARANTINE", "stock_expiry_write_off_line", &line.WriteOffLineID, &sourceRef, nil, &lotID, nil, -lot.Quantity, unitCost, userID, req.Reason); err != nil {
+			return nil, err
+		}
+		if _, _, err := trackingSvc.adjustVariantBalanceTx(tx, companyID, locationID, variant, -lot.Quantity, 0, nil); err != nil {
+			return nil, err
+		}
+		touchedProducts[variant.ProductID] = struct{}{}
+
+		writeOff.TotalQuantity += line.Quantity
+		writeOff.TotalCost = round2(writeOff.TotalCost + line.TotalCost)
+		writeOff.Lines = append(writeOff.Lines, line)
+	}
+	for productID := range touchedProducts {
+		if err := trackingSvc.updateProductCostSnapshotTx(tx, companyID, productID); err != nil {
+			return nil, fmt.Errorf("failed to update product cost snapshot: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE stock_expiry_write_offs
+		SET total_quantity = $1, total_cost = $2
+		WHERE write_off_id = $3
+	`, writeOff.TotalQuantity, writeOff.TotalCost, writeOff.WriteOffID); err != nil {
+		return nil, fmt.Errorf("failed to update expiry write-off totals: %w", err)
+	}
+
+	financeSvc := NewFinanceIntegrityServiceWithDB(s.db)
+	if writeOff.TotalCost > 0 {
+		if err := financeSvc.EnqueueTx(tx, &models.FinanceOutboxEntry{
+			CompanyID:     companyID,
+			LocationID:    &locationID,
+			EventType:     financeEventLedgerExpiryWriteOff,
+			AggregateType: "stock_expiry_write_off",
+			AggregateID:   writeOff.WriteOffID,
+			Payload:       models.JSONB{},
+			CreatedBy:     &userID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit expiry write-off: %w", err)
+	}
+
+	if err := financeSvc.ProcessAggregate(companyID, "stock_expiry_write_off", writeOff.WriteOffID); err != nil {
+		log.Printf("warning: failed to process finance outbox for expiry write-off %d: %v", writeOff.WriteOffID, err)
+	}
+	return writeOff, nil
+}
+
+func (s *StockExpiryService) quarantineLotSerialsTx(tx *sql.Tx, companyID, locationID, lotID int) ([]serialRecord, error) {
+	rows, err := tx.Query(`
+		UPDATE product_serials
+		SET status = 'QUARANTINED',
+		    last_movement_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1
+		  AND location_id = $2
+		  AND stock_lot_id = $3
+		  AND status = 'IN_STOCK'
+		RETURNING product_serial_id, serial_number
+	`, companyID, locationID, lotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quarantine serial numbers: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]serialRecord, 0)
+	for rows.Next() {
+		var rec serialRecord
+		if err := rows.Scan(&rec.ProductSerialID, &rec.SerialNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined serial: %w", err)
+		}
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].SerialNumber < records[j].SerialNumber })
+	return records, rows.Err()
+}
+
+func (s *StockExpiryService) GetExpiryWriteOffs(companyID int, locationID *int) ([]models.StockExpiryWriteOff, error) {
+	rows, err := s.db.Query(`
+		SELECT w.write_off_id, w.company_id, w.location_id, l.name, w.reason,
+		       w.total_quantity::float8, w.total_cost::float8, w.created_by, w.created_at
+		FROM stock_expiry_write_offs w
+		JOIN locations l ON l.location_id = w.location_id
+		WHERE w.company_id = $1
+		  AND ($2::int IS NULL OR w.location_id = $2)
+		ORDER BY w.created_at DESC, w.write_off_id DESC
+	`, companyID, locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiry write-offs: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]models.StockExpiryWriteOff, 0)
+	for rows.Next() {
+		w, err := scanExpiryWriteOff(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expiry write-offs: %w", err)
+	}
+	return items, nil
+}
+
+func (s *StockExpiryService) GetExpiryWriteOff(companyID, writeOffID int) (*models.StockExpiryWriteOff, error) {
+	w, err := scanExpiryWriteOff(s.db.QueryRow(`
+		SELECT w.write_off_id, w.company_id, w.location_id, l.name, w.reason,
+		       w.total_quantity::float8, w.total_cost::float8, w.created_by, w.created_at
+		FROM stock_expiry_write_offs w
+		JOIN locations l ON l.location_id = w.location_id
+		WHERE w.write_off_id = $1 AND w.company_id = $2
+	`, writeOffID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("expiry write-off not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT wl.write_off_line_id, wl.write_off_id, wl.lot_id, wl.product_id, p.name, wl.barcode_id,
+		       wl.batch_number, wl.expiry_date, wl.quantity::float8, wl.unit_cost::float8,
+		       wl.total_cost::float8, wl.serial_numbers
+		FROM stock_expiry_write_off_lines wl
+		JOIN products p ON p.product_id = wl.product_id
+		WHERE wl.write_off_id = $1
+		ORDER BY wl.write_off_line_id
+	`, writeOffID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiry write-off lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.StockExpiryWriteOffLine
+		var serials pq.StringArray
+		if err := rows.Scan(&line.WriteOffLineID, &line.WriteOffID, &line.LotID, &line.ProductID, &line.ProductName,
+			&line.BarcodeID, &line.BatchNumber, &line.ExpiryDate, &line.Quantity, &line.UnitCost,
+			&line.TotalCost, &serials); err != nil {
+			return nil, fmt.Errorf("failed to scan expiry write-off line: %w", err)
+		}
+		line.SerialNumbers = []string(serials)
+		w.Lines = append(w.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expiry write-off lines: %w", err)
+	}
+	return w, nil
+}
+
+func scanExpiryWriteOff(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.StockExpiryWriteOff, error) {
+	var w models.StockExpiryWriteOff
+	err := row.Scan(&w.WriteOffID, &w.CompanyID, &w.LocationID, &w.LocationName, &w.Reason,
+		&w.TotalQuantity, &w.TotalCost, &w.CreatedBy, &w.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan expiry write-off: %w", err)
+	}
+	return &w, nil
+}
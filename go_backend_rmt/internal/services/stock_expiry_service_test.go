@@ -0,0 +1,79 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOrderLotsForIssue(t *testing.T) {
+	today := time.Date(2026, 5, 10, 15, 0, 0, 0, time.UTC)
+	date := func(day int) *time.Time {
+		d := time.Date(2026, 5, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+	lots := func() []availableLot {
+		return []availableLot{
+			{LotID: 1, ReceivedDate: *date(1), ExpiryDate: date(30)},
+			{LotID: 2, ReceivedDate: *date(2), ExpiryDate: date(9)},
+			{LotID: 3, ReceivedDate: *date(3)},
+			{LotID: 4, ReceivedDate: *date(4), ExpiryDate: date(10)},
+		}
+	}
+	ids := func(in []availableLot) []int {
+		out := make([]int, 0, len(in))
+		for _, lot := range in {
+			out = append(out, lot.LotID)
+		}
+		return out
+	}
+
+	cases := []struct {
+		policy        string
+		demoteExpired bool
+		want          []int
+	}{
+		{batchIssuePolicyFIFO, false, []int{1, 2, 3, 4}},
+		{batchIssuePolicyFEFO, false, []int{2, 4, 1, 3}},
+		{batchIssuePolicyFEFO, true, []int{4, 1, 3, 2}},
+		{batchIssuePolicyFIFO, true, []int{1, 3, 4, 2}},
+	}
+	for _, tc := range cases {
+		got := lots()
+		orderLotsForIssue(got, tc.policy, tc.demoteExpired, today)
+		if !reflect.DeepEqual(ids(got), tc.want) {
+			t.Fatalf("%s demote=%v: expected %v, got %v", tc.policy, tc.demoteExpired, tc.want, ids(got))
+		}
+	}
+
+	if lotExpired(date(10), today) || !lotExpired(date(9), today) || lotExpired(nil, today) {
+		t.Fatalf("a lot should stay sellable through its expiry date")
+	}
+}
+
+func TestExpiryHorizonBuckets(t *testing.T) {
+	horizons, err := normalizeExpiryHorizons([]int{90, 30, 60, 30})
+	if err != nil || !reflect.DeepEqual(horizons, []int{30, 60, 90}) {
+		t.Fatalf("unexpected horizons %v (%v)", horizons, err)
+	}
+	if defaults, _ := normalizeExpiryHorizons(nil); !reflect.DeepEqual(defaults, []int{30, 60, 90}) {
+		t.Fatalf("unexpected default horizons %v", defaults)
+	}
+	if _, err := normalizeExpiryHorizons([]int{0}); err == nil {
+		t.Fatalf("expected an error for a zero-day horizon")
+	}
+
+	cases := map[int]string{
+		-1: "EXPIRED",
+		0:  "WITHIN_30_DAYS",
+		30: "WITHIN_30_DAYS",
+		31: "WITHIN_60_DAYS",
+		90: "WITHIN_90_DAYS",
+		91: "",
+	}
+	for days, want := range cases {
+		if got := expiryBucket(days, horizons); got != want {
+			t.Fatalf("expiryBucket(%d) = %q, want %q", days, got, want)
+		}
+	}
+}
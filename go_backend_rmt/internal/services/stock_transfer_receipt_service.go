@@ -0,0 +1,641 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// transferReceiptLine is a shipped transfer line as it stands when the
+// destination receives it.
+type transferReceiptLine struct {
+	TransferDetailID int
+	ProductID        int
+	BarcodeID        *int
+	Quantity         float64
+	TransitValue     float64
+	Serials          []string
+	Batches          []models.InventoryBatchSelectionInput
+}
+
+// transferLinePlan is how a shipped line splits between stock received at the
+// destination and the discrepancy.
+type transferLinePlan struct {
+	ReceivedQuantity float64
+	ShortQuantity    float64
+	DamagedQuantity  float64
+	ReceivedSerials  []string
+	DamagedSerials   []string
+	ShortSerials     []string
+	ReceivedBatches  []models.InventoryBatchSelectionInput
+	Reason           *string
+}
+
+func (p *transferLinePlan) hasDiscrepancy() bool {
+	return p.ShortQuantity > 1e-9 || p.DamagedQuantity > 1e-9
+}
+
+// planTransferLineReceipt validates what the destination reports for a line.
+// A nil item receives the whole line. Serialized lines are confirmed serial
+// by serial; batch lines take the received lots from what was issued, in
+// issue order unless the receiver names them.
+func planTransferLineReceipt(line transferReceiptLine, item *models.ReceiveStockTransferItem) (*transferLinePlan, error) {
+	if item == nil {
+		return &transferLinePlan{
+			ReceivedQuantity: line.Quantity,
+			ReceivedSerials:  line.Serials,
+			ReceivedBatches:  line.Batches,
+		}, nil
+	}
+	if item.ReceivedQuantity < 0 || item.ShortQuantity < 0 || item.DamagedQuantity < 0 {
+		return nil, fmt.Errorf("receipt quantities cannot be negative")
+	}
+	if math.Abs(item.ReceivedQuantity+item.ShortQuantity+item.DamagedQuantity-line.Quantity) > 1e-9 {
+		return nil, fmt.Errorf("received, short and damaged quantities must add up to the shipped quantity")
+	}
+	plan := &transferLinePlan{
+		ReceivedQuantity: item.ReceivedQuantity,
+		ShortQuantity:    item.ShortQuantity,
+		DamagedQuantity:  item.DamagedQuantity,
+		Reason:           item.Reason,
+	}
+
+	if len(line.Serials) > 0 {
+		for _, qty := range []float64{item.ReceivedQuantity, item.ShortQuantity, item.DamagedQuantity} {
+			if qty != math.Trunc(qty) {
+				return nil, fmt.Errorf("serialized quantities must be whole numbers")
+			}
+		}
+		received := item.ReceivedSerialNumbers
+		if len(received) == 0 && int(item.ReceivedQuantity) == len(line.Serials) {
+			received = line.Serials
+		}
+		if len(received) != int(item.ReceivedQuantity) {
+			return nil, fmt.Errorf("received serial numbers count must equal received quantity")
+		}
+		if len(item.DamagedSerialNumbers) != int(item.DamagedQuantity) {
+			return nil, fmt.Errorf("damaged serial numbers count must equal damaged quantity")
+		}
+		shipped := make(map[string]bool, len(line.Serials))
+		for _, serial := range line.Serials {
+			shipped[serial] = true
+		}
+		seen := make(map[string]bool, len(line.Serials))
+		for _, serial := range append(append([]string{}, received...), item.DamagedSerialNumbers...) {
+			if !shipped[serial] {
+				return nil, fmt.Errorf("serial number %s was not shipped on this transfer", serial)
+			}
+			if seen[serial] {
+				return nil, fmt.Errorf("serial number %s is listed more than once", serial)
+			}
+			seen[serial] = true
+		}
+		plan.ReceivedSerials = received
+		plan.DamagedSerials = item.DamagedSerialNumbers
+		for _, serial := range line.Serials {
+			if !seen[serial] {
+				plan.ShortSerials = append(plan.ShortSerials, serial)
+			}
+		}
+		return plan, nil
+	}
+
+	if len(item.ReceivedBatches) > 0 {
+		issued := make(map[int]float64, len(line.Batches))
+		for _, alloc := range line.Batches {
+			issued[alloc.LotID] += alloc.Quantity
+		}
+		covered := 0.0
+		for _, alloc := range item.ReceivedBatches {
+			if alloc.Quantity <= 0 {
+				return nil, fmt.Errorf("received batch quantity must be greater than zero")
+			}
+			if alloc.Quantity > issued[alloc.LotID]+1e-9 {
+				return nil, fmt.Errorf("received quantity exceeds the quantity shipped from batch %d", alloc.LotID)
+			}
+			issued[alloc.LotID] -= alloc.Quantity
+			covered += alloc.Quantity
+		}
+		if math.Abs(covered-item.ReceivedQuantity) > 1e-9 {
+			return nil, fmt.Errorf("received batches must add up to the received quantity")
+		}
+		plan.ReceivedBatches = item.ReceivedBatches
+		return plan, nil
+	}
+
+	remaining := item.ReceivedQuantity
+	for _, alloc := range line.Batches {
+		if remaining <= 1e-9 {
+			break
+		}
+		qty := alloc.Quantity
+		if qty > remaining {
+			qty = remaining
+		}
+		plan.ReceivedBatches = append(plan.ReceivedBatches, models.InventoryBatchSelectionInput{LotID: alloc.LotID, Quantity: qty})
+		remaining -= qty
+	}
+	return plan, nil
+}
+
+// ReceiveStockTransfer receives an in-transit transfer at its destination.
+// Goods received in good condition go into stock; short and damaged goods
+// are recorded on a discrepancy that is routed for approval.
+func (s *InventoryService) ReceiveStockTransfer(transferID, companyID, actingLocationID, userID int, req *models.ReceiveStockTransferRequest) (*models.StockTransferReceipt, error) {
+	if req == nil {
+		req = &models.ReceiveStockTransferRequest{}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fromLocationID, toLocationID int
+	var status, transferNumber string
+	err = tx.QueryRow(`
+		SELECT st.from_location_id, st.to_location_id, st.status, st.transfer_number
+		FROM stock_transfers st
+		JOIN locations fl ON fl.location_id = st.from_location_id
+		JOIN locations tl ON tl.location_id = st.to_location_id
+		WHERE st.transfer_id = $1
+		  AND (fl.company_id = $2 OR tl.company_id = $2)
+		FOR UPDATE OF st
+	`, transferID, companyID).Scan(&fromLocationID, &toLocationID, &status, &transferNumber)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transfer not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	if status != "IN_TRANSIT" {
+		return nil, fmt.Errorf("transfer is not in transit")
+	}
+	if actingLocationID != toLocationID {
+		return nil, fmt.Errorf("completion must be done at destination location")
+	}
+
+	lines, err := s.loadTransferReceiptLinesTx(tx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[int]*models.ReceiveStockTransferItem, len(req.Items))
+	for i := range req.Items {
+		item := &req.Items[i]
+		if _, dup := items[item.TransferDetailID]; dup {
+			return nil, fmt.Errorf("transfer line %d is listed more than once", item.TransferDetailID)
+		}
+		items[item.TransferDetailID] = item
+	}
+	known := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		known[line.TransferDetailID] = true
+	}
+	for id := range items {
+		if !known[id] {
+			return nil, fmt.Errorf("transfer line %d is not on this transfer", id)
+		}
+	}
+
+	trackingSvc := newInventoryTrackingService(s.db)
+	var discrepancyLines []models.StockTransferDiscrepancyLine
+	var receivedBarcodeIDs []int
+	shortValue, damagedValue := 0.0, 0.0
+	webhookItems := make([]models.JSONB, 0, len(lines))
+	for _, line := range lines {
+		plan, err := planTransferLineReceipt(line, items[line.TransferDetailID])
+		if err != nil {
+			return nil, fmt.Errorf("transfer line %d: %w", line.TransferDetailID, err)
+		}
+		sourceRef := transferNumber
+		if plan.ReceivedQuantity > 1e-9 {
+			variant, err := s.receiveTransferTrackedStockTx(tx, companyID, fromLocationID, toLocationID, userID, &line.TransferDetailID, &sourceRef, inventorySelection{
+				ProductID:        line.ProductID,
+				BarcodeID:        line.BarcodeID,
+				Quantity:         plan.ReceivedQuantity,
+				SerialNumbers:    plan.ReceivedSerials,
+				BatchAllocations: plan.ReceivedBatches,
+				Notes:            req.Notes,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to transfer stock: %w", err)
+			}
+			receivedBarcodeIDs = append(receivedBarcodeIDs, variant.BarcodeID)
+		}
+		if lost := append(append([]string{}, plan.ShortSerials...), plan.DamagedSerials...); len(lost) > 0 {
+			if err := trackingSvc.writeOffTransitSerialsTx(tx, companyID, line.ProductID, lost); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.Exec(`
+			UPDATE stock_transfer_details
+			SET received_quantity = $2,
+			    short_quantity = $3,
+			    damaged_quantity = $4,
+			    received_serial_numbers = $5,
+			    damaged_serial_numbers = $6
+			WHERE transfer_detail_id = $1
+		`, line.TransferDetailID, plan.ReceivedQuantity, plan.ShortQuantity, plan.DamagedQuantity,
+			pq.Array(plan.ReceivedSerials), pq.Array(plan.DamagedSerials)); err != nil {
+			return nil, fmt.Errorf("failed to update received quantity: %w", err)
+		}
+
+		if plan.hasDiscrepancy() {
+			unitCost := 0.0
+			if line.Quantity > 0 {
+				unitCost = round4(line.TransitValue / line.Quantity)
+			}
+			dl := models.StockTransferDiscrepancyLine{
+				TransferDetailID:     line.TransferDetailID,
+				ProductID:            line.ProductID,
+				ShortQuantity:        plan.ShortQuantity,
+				DamagedQuantity:      plan.DamagedQuantity,
+				UnitCost:             unitCost,
+				ShortValue:           round2(unitCost * plan.ShortQuantity),
+				DamagedValue:         round2(unitCost * plan.DamagedQuantity),
+				ShortSerialNumbers:   plan.ShortSerials,
+				DamagedSerialNumbers: plan.DamagedSerials,
+				Reason:               plan.Reason,
+			}
+			shortValue += dl.ShortValue
+			damagedValue += dl.DamagedValue
+			discrepancyLines = append(discrepancyLines, dl)
+		}
+		webhookItems = append(webhookItems, models.JSONB{
+			"product_id":       line.ProductID,
+			"barcode_id":       line.BarcodeID,
+			"quantity":         plan.ReceivedQuantity,
+			"short_quantity":   plan.ShortQuantity,
+			"damaged_quantity": plan.DamagedQuantity,
+			"shipped_quantity": line.Quantity,
+		})
+	}
+
+	var transitValue float64
+	if err := tx.QueryRow(`
+		UPDATE stock_transfers
+		SET status = 'COMPLETED', received_by = $1, received_at = CURRENT_TIMESTAMP,
+		    updated_by = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE transfer_id = $2
+		RETURNING transit_value::float8
+	`, userID, transferID).Scan(&transitValue); err != nil {
+		return nil, fmt.Errorf("failed to complete transfer: %w", err)
+	}
+
+	receipt := &models.StockTransferReceipt{
+		TransferID:    transferID,
+		Status:        "COMPLETED",
+		ReceivedValue: round2(transitValue - shortValue - damagedValue),
+	}
+	if len(discrepancyLines) > 0 {
+		discrepancy, err := s.createTransferDiscrepancyTx(tx, companyID, userID, transferID, transferNumber, fromLocationID, toLocationID, round2(shortValue), round2(damagedValue), req.Notes, discrepancyLines)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Discrepancy = discrepancy
+	}
+
+	// Serve waiting sales order backorders before the goods become free stock.
+	if _, err := (&SalesOrderService{db: s.db}).AllocateBackordersTx(tx, companyID, toLocationID, receivedBarcodeIDs); err != nil {
+		return nil, err
+	}
+
+	if err := NewFinanceIntegrityServiceWithDB(s.db).EnqueueTx(tx, &models.FinanceOutboxEntry{
+		CompanyID:     companyID,
+		LocationID:    &toLocationID,
+		EventType:     financeEventLedgerTransferIn,
+		AggregateType: "stock_transfer",
+		AggregateID:   transferID,
+		Payload:       models.JSONB{},
+		CreatedBy:     &userID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to enqueue transfer receipt ledger posting: %w", err)
+	}
+
+	if err := enqueueWebhookEventTx(tx, companyID, models.WebhookEventStockTransferCompleted, "stock_transfer", transferID, models.JSONB{
+		"transfer_id":      transferID,
+		"transfer_number":  transferNumber,
+		"from_location_id": fromLocationID,
+		"to_location_id":   toLocationID,
+		"items":            webhookItems,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transfer receipt: %w", err)
+	}
+	if err := NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "stock_transfer", transferID); err != nil {
+		log.Printf("inventory_service: failed to process finance outbox for stock_transfer %d: %v", transferID, err)
+	}
+	return receipt, nil
+}
+
+func (s *InventoryService) loadTransferReceiptLinesTx(tx *sql.Tx, transferID int) ([]transferReceiptLine, error) {
+	rows, err := tx.Query(`
+		SELECT transfer_detail_id, product_id, barcode_id, quantity::float8, transit_value::float8,
+		       serial_numbers, COALESCE(batch_allocations, '[]'::jsonb)
+		FROM stock_transfer_details
+		WHERE transfer_id = $1
+		ORDER BY transfer_detail_id
+	`, transferID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer items: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []transferReceiptLine
+	for rows.Next() {
+		var line transferReceiptLine
+		var serials pq.StringArray
+		var batchAllocRaw []byte
+		if err := rows.Scan(&line.TransferDetailID, &line.ProductID, &line.BarcodeID, &line.Quantity, &line.TransitValue, &serials, &batchAllocRaw); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer item: %w", err)
+		}
+		line.Serials = []string(serials)
+		line.Batches = decodeBatchAllocations(batchAllocRaw)
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transfer items: %w", err)
+	}
+	return lines, nil
+}
+
+// writeOffTransitSerialsTx takes serials that were short or damaged on a
+// transfer out of circulation.
+func (s *inventoryTrackingService) writeOffTransitSerialsTx(tx *sql.Tx, companyID, productID int, serialNumbers []string) error {
+	result, err := tx.Exec(`
+		UPDATE product_serials
+		SET status = 'ADJUSTED_OUT',
+		    location_id = NULL,
+		    last_movement_at = CURRENT_TIMESTAMP
+		WHERE company_id = $1
+		  AND product_id = $2
+		  AND status = 'TRANSFER_IN_TRANSIT'
+		  AND serial_number = ANY($3)
+	`, companyID, productID, pq.Array(serialNumbers))
+	if err != nil {
+		return fmt.Errorf("failed to write off transfer serials: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to inspect transfer serial write-off: %w", err)
+	} else if int(rows) != len(serialNumbers) {
+		return fmt.Errorf("one or more serial numbers are unavailable in transit")
+	}
+	return nil
+}
+
+func (s *InventoryService) createTransferDiscrepancyTx(tx *sql.Tx, companyID, userID, transferID int, transferNumber string, fromLocationID, toLocationID int, shortValue, damagedValue float64, notes *string, lines []models.StockTransferDiscrepancyLine) (*models.StockTransferDiscrepancy, error) {
+	d := &models.StockTransferDiscrepancy{
+		CompanyID:      companyID,
+		TransferID:     transferID,
+		TransferNumber: transferNumber,
+		FromLocationID: fromLocationID,
+		ToLocationID:   toLocationID,
+		Status:         models.TransferDiscrepancyStatusPending,
+		ShortValue:     shortValue,
+		DamagedValue:   damagedValue,
+		Notes:          notes,
+		CreatedBy:      userID,
+	}
+	if err := tx.QueryRow(`
+		INSERT INTO stock_transfer_discrepancies (
+			company_id, transfer_id, status, short_value, damaged_value, notes, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING discrepancy_id, created_at, updated_at
+	`, companyID, transferID, d.Status, shortValue, damagedValue, notes, userID).Scan(&d.DiscrepancyID, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create transfer discrepancy: %w", err)
+	}
+	for i := range lines {
+		line := &lines[i]
+		line.DiscrepancyID = d.DiscrepancyID
+		if err := tx.QueryRow(`
+			INSERT INTO stock_transfer_discrepancy_lines (
+				discrepancy_id, transfer_detail_id, product_id, short_quantity, damaged_quantity,
+				unit_cost, short_value, damaged_value, short_serial_numbers, damaged_serial_numbers, reason
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING discrepancy_line_id
+		`, d.DiscrepancyID, line.TransferDetailID, line.ProductID, line.ShortQuantity, line.DamagedQuantity,
+			line.UnitCost, line.ShortValue, line.DamagedValue, pq.Array(line.ShortSerialNumbers), pq.Array(line.DamagedSerialNumbers), line.Reason,
+		).Scan(&line.DiscrepancyLineID); err != nil {
+			return nil, fmt.Errorf("failed to create transfer discrepancy line: %w", err)
+		}
+	}
+	d.Lines = lines
+
+	workflow := &WorkflowService{db: s.db}
+	approverRoleID, err := workflow.findApproverRoleTx(tx, companyID, "Inventory Manager", "Manager", "Admin", "Super Admin")
+	if err != nil {
+		return nil, err
+	}
+	title := fmt.Sprintf("Transfer discrepancy on %s", transferNumber)
+	summary := fmt.Sprintf("Short %.2f, damaged %.2f", shortValue, damagedValue)
+	dueAt := time.Now().Add(48 * time.Hour)
+	created, err := workflow.createRequestTx(tx, companyID, userID, workflowCreateInput{
+		LocationID:     &toLocationID,
+		Module:         workflowModuleInventory,
+		EntityType:     workflowEntityTransferDiscrepancy,
+		EntityID:       &d.DiscrepancyID,
+		ActionType:     workflowActionResolveTransferDiscrepancy,
+		Title:          title,
+		Summary:        &summary,
+		RequestReason:  notes,
+		Priority:       workflowPriorityHigh,
+		ApproverRoleID: approverRoleID,
+		Payload: models.JSONB{
+			"discrepancy_id":   d.DiscrepancyID,
+			"transfer_id":      transferID,
+			"transfer_number":  transferNumber,
+			"from_location_id": fromLocationID,
+			"to_location_id":   toLocationID,
+			"short_value":      shortValue,
+			"damaged_value":    damagedValue,
+		},
+		DueAt: &dueAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE stock_transfer_discrepancies SET approval_id = $1 WHERE discrepancy_id = $2
+	`, created.ApprovalID, d.DiscrepancyID); err != nil {
+		return nil, fmt.Errorf("failed to link transfer discrepancy approval: %w", err)
+	}
+	d.ApprovalID = &created.ApprovalID
+	return d, nil
+}
+
+// resolveTransferDiscrepancyTx closes a discrepancy once its approval is
+// decided. Damaged goods are always written off at the destination. An
+// approved shortage is charged back to the source location; a rejected one
+// stays with the destination that reported it.
+func (s *InventoryService) resolveTransferDiscrepancyTx(tx *sql.Tx, companyID, discrepancyID, userID int, approved bool) error {
+	var status string
+	var fromLocationID, toLocationID int
+	var shortValue, damagedValue float64
+	err := tx.QueryRow(`
+		SELECT d.status, st.from_location_id, st.to_location_id, d.short_value::float8, d.damaged_value::float8
+		FROM stock_transfer_discrepancies d
+		JOIN stock_transfers st ON st.transfer_id = d.transfer_id
+		WHERE d.discrepancy_id = $1 AND d.company_id = $2
+		FOR UPDATE OF d
+	`, discrepancyID, companyID).Scan(&status, &fromLocationID, &toLocationID, &shortValue, &damagedValue)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("transfer discrepancy not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load transfer discrepancy: %w", err)
+	}
+	if status != models.TransferDiscrepancyStatusPending {
+		return fmt.Errorf("transfer discrepancy is already %s", strings.ToLower(status))
+	}
+
+	newStatus := models.TransferDiscrepancyStatusRejected
+	shortageLocationID := toLocationID
+	if approved {
+		newStatus = models.TransferDiscrepancyStatusApproved
+		shortageLocationID = fromLocationID
+	}
+	if _, err := tx.Exec(`
+		UPDATE stock_transfer_discrepancies
+		SET status = $1, shortage_location_id = $2, resolved_by = $3, resolved_at = CURRENT_TIMESTAMP,
+		    updated_by = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE discrepancy_id = $4
+	`, newStatus, shortageLocationID, userID, discrepancyID); err != nil {
+		return fmt.Errorf("failed to resolve transfer discrepancy: %w", err)
+	}
+
+	finance := NewFinanceIntegrityServiceWithDB(s.db)
+	if shortValue > 0 {
+		if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
+			CompanyID:     companyID,
+			LocationID:    &shortageLocationID,
+			EventType:     financeEventLedgerTransferShort,
+			AggregateType: "stock_transfer_discrepancy",
+			AggregateID:   discrepancyID,
+			Payload:       models.JSONB{},
+			CreatedBy:     &userID,
+		}); err != nil {
+			return fmt.Errorf("failed to enqueue transfer shortage ledger posting: %w", err)
+		}
+	}
+	if damagedValue > 0 {
+		if err := finance.EnqueueTx(tx, &models.FinanceOutboxEntry{
+			CompanyID:     companyID,
+			LocationID:    &toLocationID,
+			EventType:     financeEventLedgerTransferDamage,
+			AggregateType: "stock_transfer_discrepancy",
+			AggregateID:   discrepancyID,
+			Payload:       models.JSONB{},
+			CreatedBy:     &userID,
+		}); err != nil {
+			return fmt.Errorf("failed to enqueue transfer damage ledger posting: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetTransferDiscrepancies lists transfer discrepancies, optionally limited to
+// a status and to transfers from or to a location.
+func (s *InventoryService) GetTransferDiscrepancies(companyID int, status string, locationID *int) ([]models.StockTransferDiscrepancy, error) {
+	rows, err := s.db.Query(`
+		SELECT d.discrepancy_id, d.company_id, d.transfer_id, st.transfer_number, st.from_location_id, st.to_location_id,
+		       d.status, d.short_value::float8, d.damaged_value::float8, d.shortage_location_id, d.approval_id,
+		       d.notes, d.resolved_by, d.resolved_at, d.created_by, d.created_at, d.updated_at
+		FROM stock_transfer_discrepancies d
+		JOIN stock_transfers st ON st.transfer_id = d.transfer_id
+		WHERE d.company_id = $1
+		  AND ($2 = '' OR d.status = $2)
+		  AND ($3::int IS NULL OR st.from_location_id = $3 OR st.to_location_id = $3)
+		ORDER BY d.created_at DESC, d.discrepancy_id DESC
+	`, companyID, strings.ToUpper(strings.TrimSpace(status)), locationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]models.StockTransferDiscrepancy, 0)
+	for rows.Next() {
+		d, err := scanTransferDiscrepancy(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transfer discrepancies: %w", err)
+	}
+	return items, nil
+}
+
+// GetTransferDiscrepancy returns a discrepancy with its lines.
+func (s *InventoryService) GetTransferDiscrepancy(companyID, discrepancyID int) (*models.StockTransferDiscrepancy, error) {
+	d, err := scanTransferDiscrepancy(s.db.QueryRow(`
+		SELECT d.discrepancy_id, d.company_id, d.transfer_id, st.transfer_number, st.from_location_id, st.to_location_id,
+		       d.status, d.short_value::float8, d.damaged_value::float8, d.shortage_location_id, d.approval_id,
+		       d.notes, d.resolved_by, d.resolved_at, d.created_by, d.created_at, d.updated_at
+		FROM stock_transfer_discrepancies d
+		JOIN stock_transfers st ON st.transfer_id = d.transfer_id
+		WHERE d.company_id = $1 AND d.discrepancy_id = $2
+	`, companyID, discrepancyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transfer discrepancy not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT l.discrepancy_line_id, l.discrepancy_id, l.transfer_detail_id, l.product_id, p.name,
+		       l.short_quantity::float8, l.damaged_quantity::float8, l.unit_cost::float8,
+		       l.short_value::float8, l.damaged_value::float8, l.short_serial_numbers, l.damaged_serial_numbers, l.reason
+		FROM stock_transfer_discrepancy_lines l
+		JOIN products p ON p.product_id = l.product_id
+		WHERE l.discrepancy_id = $1
+		ORDER BY l.transfer_detail_id
+	`, discrepancyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer discrepancy lines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line models.StockTransferDiscrepancyLine
+		var shortSerials, damagedSerials pq.StringArray
+		if err := rows.Scan(&line.DiscrepancyLineID, &line.DiscrepancyID, &line.TransferDetailID, &line.ProductID, &line.ProductName,
+			&line.ShortQuantity, &line.DamagedQuantity, &line.UnitCost, &line.ShortValue, &line.DamagedValue,
+			&shortSerials, &damagedSerials, &line.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan transfer discrepancy line: %w", err)
+		}
+		line.ShortSerialNumbers = []string(shortSerials)
+		line.DamagedSerialNumbers = []string(damagedSerials)
+		d.Lines = append(d.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate transfer discrepancy lines: %w", err)
+	}
+	return d, nil
+}
+
+func scanTransferDiscrepancy(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.StockTransferDiscrepancy, error) {
+	var d models.StockTransferDiscrepancy
+	err := row.Scan(&d.DiscrepancyID, &d.CompanyID, &d.TransferID, &d.TransferNumber, &d.FromLocationID, &d.ToLocationID,
+		&d.Status, &d.ShortValue, &d.DamagedValue, &d.ShortageLocationID, &d.ApprovalID,
+		&d.Notes, &d.ResolvedBy, &d.ResolvedAt, &d.CreatedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan transfer discrepancy: %w", err)
+	}
+	return &d, nil
+}
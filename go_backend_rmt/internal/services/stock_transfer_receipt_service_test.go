@@ -0,0 +1,85 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func TestPlanTransferLineReceiptSerials(t *testing.T) {
+	line := transferReceiptLine{TransferDetailID: 1, Quantity: 4, Serials: []string{"A", "B", "C", "D"}}
+
+	plan, err := planTransferLineReceipt(line, nil)
+	if err != nil || plan.hasDiscrepancy() || !reflect.DeepEqual(plan.ReceivedSerials, line.Serials) {
+		t.Fatalf("expected full receipt, got %+v (%v)", plan, err)
+	}
+
+	plan, err = planTransferLineReceipt(line, &models.ReceiveStockTransferItem{
+		TransferDetailID:      1,
+		ReceivedQuantity:      2,
+		ShortQuantity:         1,
+		DamagedQuantity:       1,
+		ReceivedSerialNumbers: []string{"A", "C"},
+		DamagedSerialNumbers:  []string{"D"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !plan.hasDiscrepancy() || !reflect.DeepEqual(plan.ShortSerials, []string{"B"}) || !reflect.DeepEqual(plan.DamagedSerials, []string{"D"}) {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	cases := []struct {
+		item models.ReceiveStockTransferItem
+		want string
+	}{
+		{models.ReceiveStockTransferItem{ReceivedQuantity: 3}, "add up to the shipped quantity"},
+		{models.ReceiveStockTransferItem{ReceivedQuantity: 3, ShortQuantity: 1}, "received serial numbers count"},
+		{models.ReceiveStockTransferItem{ReceivedQuantity: 1, ShortQuantity: 3, ReceivedSerialNumbers: []string{"Z"}}, "was not shipped"},
+		{models.ReceiveStockTransferItem{ReceivedQuantity: 1, DamagedQuantity: 1, ShortQuantity: 2, ReceivedSerialNumbers: []string{"A"}, DamagedSerialNumbers: []string{"A"}}, "more than once"},
+	}
+	for _, tc := range cases {
+		if _, err := planTransferLineReceipt(line, &tc.item); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestPlanTransferLineReceiptBatches(t *testing.T) {
+	line := transferReceiptLine{
+		TransferDetailID: 2,
+		Quantity:         10,
+		Batches: []models.InventoryBatchSelectionInput{
+			{LotID: 11, Quantity: 6},
+			{LotID: 12, Quantity: 4},
+		},
+	}
+
+	plan, err := planTransferLineReceipt(line, &models.ReceiveStockTransferItem{ReceivedQuantity: 8, DamagedQuantity: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []models.InventoryBatchSelectionInput{{LotID: 11, Quantity: 6}, {LotID: 12, Quantity: 2}}
+	if !reflect.DeepEqual(plan.ReceivedBatches, want) {
+		t.Fatalf("expected issue-order allocation %v, got %v", want, plan.ReceivedBatches)
+	}
+
+	plan, err = planTransferLineReceipt(line, &models.ReceiveStockTransferItem{
+		ReceivedQuantity: 4,
+		ShortQuantity:    6,
+		ReceivedBatches:  []models.InventoryBatchSelectionInput{{LotID: 12, Quantity: 4}},
+	})
+	if err != nil || !reflect.DeepEqual(plan.ReceivedBatches, []models.InventoryBatchSelectionInput{{LotID: 12, Quantity: 4}}) {
+		t.Fatalf("unexpected plan %+v (%v)", plan, err)
+	}
+
+	if _, err := planTransferLineReceipt(line, &models.ReceiveStockTransferItem{
+		ReceivedQuantity: 5,
+		ShortQuantity:    5,
+		ReceivedBatches:  []models.InventoryBatchSelectionInput{{LotID: 12, Quantity: 5}},
+	}); err == nil || !strings.Contains(err.Error(), "exceeds the quantity shipped") {
+		t.Fatalf("expected batch over-receipt error, got %v", err)
+	}
+}
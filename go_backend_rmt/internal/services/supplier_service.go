@@ -63,7 +63,8 @@ func resolveSupplierTypesForUpdate(currentMercantile, currentNonMercantile bool,
 func (s *SupplierService) GetSuppliers(companyID int, filters map[string]string) ([]models.SupplierWithStats, error) {
 	query := `
                 SELECT s.supplier_id, s.company_id, s.name, s.contact_person, s.phone, s.email,
-                          s.address, s.tax_number, s.payment_terms, s.credit_limit, s.is_mercantile,
+                          s.address, s.tax_number, s.payment_terms, s.lead_time_days,
+                          s.early_payment_discount_percent::float8, s.early_payment_discount_days, s.credit_limit, s.is_mercantile,
                           s.is_non_mercantile, s.is_active,
                           s.created_by, s.updated_by, s.sync_status, s.created_at, s.updated_at,
                           COALESCE(stats.total_purchases, 0) as total_purchases,
@@ -138,7 +139,8 @@ func (s *SupplierService) GetSuppliers(companyID int, filters map[string]string)
 		err := rows.Scan(
 			&supplier.SupplierID, &supplier.CompanyID, &supplier.Name, &supplier.ContactPerson,
 			&supplier.Phone, &supplier.Email, &supplier.Address, &supplier.TaxNumber,
-			&supplier.PaymentTerms, &supplier.CreditLimit, &supplier.IsMercantile,
+			&supplier.PaymentTerms, &supplier.LeadTimeDays,
+			&supplier.EarlyPaymentDiscountPercent, &supplier.EarlyPaymentDiscountDays, &supplier.CreditLimit, &supplier.IsMercantile,
 			&supplier.IsNonMercantile, &supplier.IsActive,
 			&supplier.CreatedBy, &supplier.UpdatedBy, &supplier.SyncStatus, &supplier.CreatedAt, &supplier.UpdatedAt,
 			&supplier.TotalPurchases, &supplier.TotalReturns, &supplier.TotalDebitNotes, &supplier.OutstandingAmount,
@@ -157,7 +159,8 @@ func (s *SupplierService) GetSuppliers(companyID int, filters map[string]string)
 func (s *SupplierService) GetSupplierByID(supplierID, companyID int) (*models.SupplierWithStats, error) {
 	query := `
                 SELECT s.supplier_id, s.company_id, s.name, s.contact_person, s.phone, s.email,
-                          s.address, s.tax_number, s.payment_terms, s.credit_limit, s.is_mercantile,
+                          s.address, s.tax_number, s.payment_terms, s.lead_time_days,
+                          s.early_payment_discount_percent::float8, s.early_payment_discount_days, s.credit_limit, s.is_mercantile,
                           s.is_non_mercantile, s.is_active,
                           s.created_by, s.updated_by, s.sync_status, s.created_at, s.updated_at,
                           COALESCE(stats.total_purchases, 0) as total_purchases,
@@ -191,7 +194,8 @@ func (s *SupplierService) GetSupplierByID(supplierID, companyID int) (*models.Su
 	err := s.db.QueryRow(query, supplierID, companyID).Scan(
 		&supplier.SupplierID, &supplier.CompanyID, &supplier.Name, &supplier.ContactPerson,
 		&supplier.Phone, &supplier.Email, &supplier.Address, &supplier.TaxNumber,
-		&supplier.PaymentTerms, &supplier.CreditLimit, &supplier.IsMercantile,
+		&supplier.PaymentTerms, &supplier.LeadTimeDays,
+		&supplier.EarlyPaymentDiscountPercent, &supplier.EarlyPaymentDiscountDays, &supplier.CreditLimit, &supplier.IsMercantile,
 		&supplier.IsNonMercantile, &supplier.IsActive,
 		&supplier.CreatedBy, &supplier.UpdatedBy, &supplier.SyncStatus, &supplier.CreatedAt, &supplier.UpdatedAt,
 		&supplier.TotalPurchases, &supplier.TotalReturns, &supplier.TotalDebitNotes, &supplier.OutstandingAmount,
@@ -226,6 +230,20 @@ func (s *SupplierService) CreateSupplier(companyID, userID int, req *models.Crea
 		paymentTerms = *req.PaymentTerms
 	}
 
+	leadTimeDays := 0
+	if req.LeadTimeDays != nil {
+		leadTimeDays = *req.LeadTimeDays
+	}
+
+	discountPercent := float64(0)
+	if req.EarlyPaymentDiscountPercent != nil {
+		discountPercent = *req.EarlyPaymentDiscountPercent
+	}
+	discountDays := 0
+	if req.EarlyPaymentDiscountDays != nil {
+		discountDays = *req.EarlyPaymentDiscountDays
+	}
+
 	creditLimit := float64(0)
 	if req.CreditLimit != nil {
 		creditLimit = *req.CreditLimit
@@ -239,16 +257,17 @@ func (s *SupplierService) CreateSupplier(companyID, userID int, req *models.Crea
 	// Insert supplier
 	insertQuery := `
                 INSERT INTO suppliers (company_id, name, contact_person, phone, email, address,
-                                                          tax_number, payment_terms, credit_limit, is_mercantile,
+                                                          tax_number, payment_terms, lead_time_days, early_payment_discount_percent,
+                                                          early_payment_discount_days, credit_limit, is_mercantile,
                                                           is_non_mercantile, is_active, created_by, updated_by)
-                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13)
+                VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $16)
                 RETURNING supplier_id, created_at
         `
 
 	var supplier models.Supplier
 	err = s.db.QueryRow(insertQuery,
 		companyID, req.Name, req.ContactPerson, req.Phone, req.Email, req.Address,
-		req.TaxNumber, paymentTerms, creditLimit, isMercantile, isNonMercantile, true, userID,
+		req.TaxNumber, paymentTerms, leadTimeDays, discountPercent, discountDays, creditLimit, isMercantile, isNonMercantile, true, userID,
 	).Scan(&supplier.SupplierID, &supplier.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert supplier: %w", err)
@@ -263,6 +282,9 @@ func (s *SupplierService) CreateSupplier(companyID, userID int, req *models.Crea
 	supplier.Address = req.Address
 	supplier.TaxNumber = req.TaxNumber
 	supplier.PaymentTerms = paymentTerms
+	supplier.LeadTimeDays = leadTimeDays
+	supplier.EarlyPaymentDiscountPercent = discountPercent
+	supplier.EarlyPaymentDiscountDays = discountDays
 	supplier.CreditLimit = creditLimit
 	supplier.IsMercantile = isMercantile
 	supplier.IsNonMercantile = isNonMercantile
@@ -356,6 +378,24 @@ func (s *SupplierService) UpdateSupplier(supplierID, companyID, userID int, req
 		args = append(args, *req.PaymentTerms)
 	}
 
+	if req.LeadTimeDays != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("lead_time_days = $%d", argCount))
+		args = append(args, *req.LeadTimeDays)
+	}
+
+	if req.EarlyPaymentDiscountPercent != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("early_payment_discount_percent = $%d", argCount))
+		args = append(args, *req.EarlyPaymentDiscountPercent)
+	}
+
+	if req.EarlyPaymentDiscountDays != nil {
+		argCount++
+		updates = append(updates, fmt.Sprintf("early_payment_discount_days = $%d", argCount))
+		args = append(args, *req.EarlyPaymentDiscountDays)
+	}
+
 	if req.CreditLimit != nil {
 		argCount++
 		updates = append(updates, fmt.Sprintf("credit_limit = $%d", argCount))
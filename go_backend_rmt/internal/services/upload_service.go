@@ -8,6 +8,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -67,50 +68,110 @@ func GetUploadPath() string {
 	return cfg.UploadPath
 }
 
+// uploadServedPrefix is the public path uploads are served from. Stored values
+// ("/uploads/logos/<uuid>.png") map one-to-one onto object keys.
+const uploadServedPrefix = "/uploads/"
+
 // SaveUploadedFile saves the provided file under basePath/subdir and returns the served path ("/uploads/...").
 // It enforces the provided allowlist, stores with a random name (UUID), and never trusts client filenames.
 func SaveUploadedFile(basePath, subdir string, fh *multipart.FileHeader, allow UploadAllowlist) (string, error) {
-	if fh == nil {
-		return "", fmt.Errorf("missing file header")
+	return storeUploadedFile(NewLocalObjectStorage(basePath, ""), subdir, fh, allow)
+}
+
+// StoreUploadedFile is SaveUploadedFile against the configured object storage,
+// so every API replica can serve the result.
+func StoreUploadedFile(subdir string, fh *multipart.FileHeader, allow UploadAllowlist) (string, error) {
+	store, err := GetObjectStorage()
+	if err != nil {
+		return "", err
 	}
+	return storeUploadedFile(store, subdir, fh, allow)
+}
 
-	ext, err := validateExtension(fh.Filename, allow)
+func storeUploadedFile(store ObjectStorage, subdir string, fh *multipart.FileHeader, allow UploadAllowlist) (string, error) {
+	contentType, ext, err := validateUpload(fh, allow)
 	if err != nil {
 		return "", err
 	}
 
-	contentType, err := sniffContentType(fh)
+	key, err := cleanObjectKey(path.Join(subdir, uuid.NewString()+ext))
 	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeUploadPath, subdir)
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	if err := store.Put(key, src, fh.Size, contentType); err != nil {
 		return "", err
 	}
-	if !isAllowedContentType(contentType, allow) {
-		return "", fmt.Errorf("%w: %s", ErrInvalidUploadType, contentType)
+	return uploadServedPrefix + key, nil
+}
+
+// publicUploadDirs are the StoreUploadedFile subdirectories served without
+// authentication under /uploads: company logos, purchase invoice scans and
+// return receipts. Every other key (attachments, report job results) is only
+// reachable through signed URLs.
+var publicUploadDirs = []string{"logos", "invoices", "returns"}
+
+// IsPublicUploadKey reports whether the object key may be served from
+// /uploads.
+func IsPublicUploadKey(key string) bool {
+	key, err := cleanObjectKey(key)
+	if err != nil {
+		return false
+	}
+	for _, dir := range publicUploadDirs {
+		if strings.HasPrefix(key, dir+"/") {
+			return true
+		}
 	}
+	return false
+}
 
-	normalizedExt := extensionForContentType(contentType)
-	if normalizedExt == "" {
-		normalizedExt = ext
+// UploadObjectKey maps a served upload path back to its object key.
+func UploadObjectKey(served string) (string, bool) {
+	if !strings.HasPrefix(served, uploadServedPrefix) {
+		return "", false
 	}
-	if normalizedExt == "" {
-		return "", fmt.Errorf("%w: missing extension", ErrInvalidUploadExt)
+	key, err := cleanObjectKey(strings.TrimPrefix(served, uploadServedPrefix))
+	if err != nil {
+		return "", false
 	}
+	return key, true
+}
 
-	if err := os.MkdirAll(filepath.Join(basePath, subdir), 0o755); err != nil {
-		return "", fmt.Errorf("failed to create upload directory: %w", err)
+// validateUpload applies the allowlist to the client extension and the sniffed
+// content type, returning the content type and the extension to store under.
+func validateUpload(fh *multipart.FileHeader, allow UploadAllowlist) (string, string, error) {
+	if fh == nil {
+		return "", "", fmt.Errorf("missing file header")
 	}
 
-	fname := uuid.NewString() + normalizedExt
-	dst, err := safeJoinUnderBase(basePath, subdir, fname)
+	ext, err := validateExtension(fh.Filename, allow)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	if err := saveMultipartFile(fh, dst); err != nil {
-		return "", err
+	contentType, err := sniffContentType(fh)
+	if err != nil {
+		return "", "", err
+	}
+	if !isAllowedContentType(contentType, allow) {
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidUploadType, contentType)
 	}
 
-	served := filepath.ToSlash(filepath.Join("/uploads", subdir, fname))
-	return served, nil
+	normalizedExt := extensionForContentType(contentType)
+	if normalizedExt == "" {
+		normalizedExt = ext
+	}
+	if normalizedExt == "" {
+		return "", "", fmt.Errorf("%w: missing extension", ErrInvalidUploadExt)
+	}
+	return contentType, normalizedExt, nil
 }
 
 func validateExtension(filename string, allow UploadAllowlist) (string, error) {
@@ -216,23 +277,3 @@ func safeJoinUnderBase(basePath, subdir, fname string) (string, error) {
 	}
 	return dstAbs, nil
 }
-
-// saveMultipartFile is a small wrapper because gin.Context.SaveUploadedFile isn't available here.
-func saveMultipartFile(fh *multipart.FileHeader, dst string) error {
-	src, err := fh.Open()
-	if err != nil {
-		return fmt.Errorf("failed to open uploaded file: %w", err)
-	}
-	defer src.Close()
-
-	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-	if err != nil {
-		return fmt.Errorf("failed to create dest file: %w", err)
-	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, src); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
-	}
-	return nil
-}
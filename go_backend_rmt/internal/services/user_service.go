@@ -8,6 +8,8 @@ import (
 	"erp-backend/internal/database"
 	"erp-backend/internal/models"
 	"erp-backend/internal/utils"
+
+	"github.com/lib/pq"
 )
 
 type UserService struct {
@@ -25,9 +27,11 @@ func (s *UserService) GetUsers(companyID *int, locationID *int) ([]models.UserRe
 		SELECT u.user_id, u.username, u.email, u.first_name, u.last_name, u.phone,
 			   u.role_id, u.location_id, u.company_id, u.is_active, u.is_locked,
 			   CASE WHEN COALESCE(NULLIF(TRIM(u.sales_action_password_hash), ''), '') <> '' THEN TRUE ELSE FALSE END,
-			   u.preferred_language, u.secondary_language, u.last_login
+			   u.preferred_language, u.secondary_language, u.last_login,
+			   ARRAY(SELECT ul.location_id FROM user_locations ul WHERE ul.user_id = u.user_id ORDER BY ul.location_id)
 		FROM users u
 		WHERE u.is_deleted = FALSE
+		  AND NOT EXISTS (SELECT 1 FROM api_keys k WHERE k.user_id = u.user_id)
 	`
 
 	args := []interface{}{}
@@ -41,7 +45,7 @@ func (s *UserService) GetUsers(companyID *int, locationID *int) ([]models.UserRe
 
 	if locationID != nil {
 		argCount++
-		query += fmt.Sprintf(" AND u.location_id = $%d", argCount)
+		query += fmt.Sprintf(" AND (u.location_id = $%[1]d OR EXISTS (SELECT 1 FROM user_locations ul WHERE ul.user_id = u.user_id AND ul.location_id = $%[1]d))", argCount)
 		args = append(args, *locationID)
 	}
 
@@ -56,16 +60,21 @@ func (s *UserService) GetUsers(companyID *int, locationID *int) ([]models.UserRe
 	var users []models.UserResponse
 	for rows.Next() {
 		var user models.UserResponse
+		var locationIDs pq.Int64Array
 		err := rows.Scan(
 			&user.UserID, &user.Username, &user.Email, &user.FirstName,
 			&user.LastName, &user.Phone, &user.RoleID, &user.LocationID,
 			&user.CompanyID, &user.IsActive, &user.IsLocked,
 			&user.HasSalesActionPassword,
 			&user.PreferredLanguage, &user.SecondaryLanguage, &user.LastLogin,
+			&locationIDs,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		for _, id := range locationIDs {
+			user.LocationIDs = append(user.LocationIDs, int(id))
+		}
 		users = append(users, user)
 	}
 
@@ -108,6 +117,17 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest, creatorUserID in
 	}
 	defer tx.Rollback()
 
+	if req.RoleID != nil {
+		if err := ensureCompanyRole(tx, req.CompanyID, *req.RoleID); err != nil {
+			return nil, err
+		}
+	}
+	if req.LocationID != nil {
+		if err := ensureCompanyLocation(tx, req.CompanyID, *req.LocationID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Insert user
 	query := `
 		INSERT INTO users (company_id, location_id, role_id, username, email, password_hash,
@@ -155,6 +175,18 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest, creatorUserID in
 		return nil, fmt.Errorf("failed to create employee for user: %w", err)
 	}
 
+	var locationIDs []int
+	if req.LocationID != nil {
+		if _, err := tx.Exec(`
+			INSERT INTO user_locations (user_id, location_id, created_by)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, userID, *req.LocationID, creatorUserID); err != nil {
+			return nil, fmt.Errorf("failed to assign user location: %w", err)
+		}
+		locationIDs = []int{*req.LocationID}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit user create: %w", err)
 	}
@@ -169,6 +201,7 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest, creatorUserID in
 		Phone:                  req.Phone,
 		RoleID:                 req.RoleID,
 		LocationID:             req.LocationID,
+		LocationIDs:            locationIDs,
 		CompanyID:              &req.CompanyID,
 		IsActive:               true,
 		IsLocked:               false,
@@ -178,13 +211,24 @@ func (s *UserService) CreateUser(req *models.CreateUserRequest, creatorUserID in
 	}, nil
 }
 
-func (s *UserService) UpdateUser(userID int, req *models.UpdateUserRequest) error {
+func (s *UserService) UpdateUser(companyID, userID int, req *models.UpdateUserRequest) error {
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if req.RoleID != nil {
+		if err := ensureCompanyRole(tx, companyID, *req.RoleID); err != nil {
+			return err
+		}
+	}
+	if req.LocationID != nil {
+		if err := ensureCompanyLocation(tx, companyID, *req.LocationID); err != nil {
+			return err
+		}
+	}
+
 	setParts := []string{}
 	args := []interface{}{}
 	argCount := 0
@@ -267,9 +311,9 @@ func (s *UserService) UpdateUser(userID int, req *models.UpdateUserRequest) erro
 
 	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
 
-	query := fmt.Sprintf("UPDATE users SET %s WHERE user_id = $%d",
-		strings.Join(setParts, ", "), argCount+1)
-	args = append(args, userID)
+	query := fmt.Sprintf("UPDATE users SET %s WHERE user_id = $%d AND company_id = $%d",
+		strings.Join(setParts, ", "), argCount+1, argCount+2)
+	args = append(args, userID, companyID)
 
 	result, err := tx.Exec(query, args...)
 	if err != nil {
@@ -285,6 +329,17 @@ func (s *UserService) UpdateUser(userID int, req *models.UpdateUserRequest) erro
 		return fmt.Errorf("user not found")
 	}
 
+	// The default location is always one of the user's locations.
+	if req.LocationID != nil {
+		if _, err := tx.Exec(`
+			INSERT INTO user_locations (user_id, location_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, userID, *req.LocationID); err != nil {
+			return fmt.Errorf("failed to assign user location: %w", err)
+		}
+	}
+
 	if len(changes) > 0 {
 		recordID := userID
 		if err := LogAudit(tx, "UPDATE", "users", &recordID, nil, nil, nil, &changes, nil, nil); err != nil {
@@ -326,3 +381,131 @@ func (s *UserService) checkUserExists(username, email string) (bool, error) {
 
 	return count > 0, nil
 }
+
+// loadUserLocations lists a user's locations within the company, with the
+// default location flagged.
+func loadUserLocations(q sqlQueryer, companyID, userID int) ([]models.UserLocation, error) {
+	rows, err := q.Query(`
+		SELECT ul.user_id, ul.location_id, l.name, ul.role_id, r.name,
+			   (u.location_id = ul.location_id), ul.created_at
+		FROM user_locations ul
+		JOIN users u ON u.user_id = ul.user_id
+		JOIN locations l ON l.location_id = ul.location_id
+		LEFT JOIN roles r ON r.role_id = ul.role_id
+		WHERE ul.user_id = $1 AND l.company_id = $2 AND COALESCE(l.is_active, TRUE) = TRUE
+		ORDER BY ul.location_id
+	`, userID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []models.UserLocation{}
+	for rows.Next() {
+		var ul models.UserLocation
+		var isDefault sql.NullBool
+		if err := rows.Scan(&ul.UserID, &ul.LocationID, &ul.LocationName, &ul.RoleID, &ul.RoleName,
+			&isDefault, &ul.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user location: %w", err)
+		}
+		ul.IsDefault = isDefault.Bool
+		locations = append(locations, ul)
+	}
+	return locations, rows.Err()
+}
+
+func (s *UserService) GetUserLocations(companyID, userID int) ([]models.UserLocation, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1 AND company_id = $2 AND is_deleted = FALSE)`,
+		userID, companyID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	return loadUserLocations(s.db, companyID, userID)
+}
+
+// SetUserLocations replaces the locations a user may work in and moves the
+// user's default location to one of them.
+func (s *UserService) SetUserLocations(companyID, actorID, userID int, req *models.SetUserLocationsRequest) ([]models.UserLocation, error) {
+	defaultLocationID := req.Locations[0].LocationID
+	if req.DefaultLocationID != nil {
+		defaultLocationID = *req.DefaultLocationID
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1 AND company_id = $2 AND is_deleted = FALSE)`,
+		userID, companyID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	seen := map[int]bool{}
+	hasDefault := false
+	for _, loc := range req.Locations {
+		if seen[loc.LocationID] {
+			return nil, fmt.Errorf("location %d is listed more than once", loc.LocationID)
+		}
+		seen[loc.LocationID] = true
+		if loc.LocationID == defaultLocationID {
+			hasDefault = true
+		}
+		if err := ensureCompanyLocation(tx, companyID, loc.LocationID); err != nil {
+			return nil, err
+		}
+		if loc.RoleID != nil {
+			if err := ensureCompanyRole(tx, companyID, *loc.RoleID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !hasDefault {
+		return nil, fmt.Errorf("default location must be one of the user's locations")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_locations WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to clear user locations: %w", err)
+	}
+	assigned := make([]interface{}, 0, len(req.Locations))
+	for _, loc := range req.Locations {
+		if _, err := tx.Exec(`
+			INSERT INTO user_locations (user_id, location_id, role_id, created_by)
+			VALUES ($1, $2, $3, $4)
+		`, userID, loc.LocationID, loc.RoleID, actorID); err != nil {
+			return nil, fmt.Errorf("failed to assign user location: %w", err)
+		}
+		entry := map[string]interface{}{"location_id": loc.LocationID}
+		if loc.RoleID != nil {
+			entry["role_id"] = *loc.RoleID
+		}
+		assigned = append(assigned, entry)
+	}
+	if _, err := tx.Exec(`UPDATE users SET location_id = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2`,
+		defaultLocationID, userID); err != nil {
+		return nil, fmt.Errorf("failed to update default location: %w", err)
+	}
+
+	changes := models.JSONB{"locations": assigned, "location_id": defaultLocationID}
+	recordID := userID
+	if err := LogAudit(tx, "UPDATE", "user_locations", &recordID, &actorID, nil, nil, &changes, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to log audit: %w", err)
+	}
+
+	locations, err := loadUserLocations(tx, companyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user locations: %w", err)
+	}
+	return locations, nil
+}
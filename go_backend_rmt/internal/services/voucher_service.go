@@ -87,6 +87,9 @@ func (s *VoucherService) createVoucherTx(tx *sql.Tx, companyID, userID int, vTyp
 	if err != nil {
 		return 0, err
 	}
+	if err := applyVoucherDimensionsTx(tx, companyID, req.Dimensions, lines); err != nil {
+		return 0, err
+	}
 
 	var voucherID int
 	err = tx.QueryRow(`
@@ -286,6 +289,26 @@ func (s *VoucherService) recordVoucherEntriesTx(
 		if err != nil {
 			return fmt.Errorf("failed to record voucher ledger entry: %w", err)
 		}
+		if err := tagLedgerReference(tx, companyID, ref, line.Dimensions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyVoucherDimensionsTx validates the voucher's and lines' analytic tags
+// and gives each line the voucher-level tags it does not override.
+func applyVoucherDimensionsTx(tx *sql.Tx, companyID int, header []models.AnalyticTag, lines []models.CreateVoucherLineRequest) error {
+	header, err := validateAnalyticTags(tx, companyID, header)
+	if err != nil {
+		return err
+	}
+	for i := range lines {
+		own, err := validateAnalyticTags(tx, companyID, lines[i].Dimensions)
+		if err != nil {
+			return err
+		}
+		lines[i].Dimensions = mergeAnalyticTags(header, own)
 	}
 	return nil
 }
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"erp-backend/internal/database"
+)
+
+const (
+	webhookPollInterval   = 10 * time.Second
+	webhookTimeout        = 10 * time.Second
+	webhookMaxAttempts    = 8
+	webhookBaseRetry      = 30 * time.Second
+	webhookMaxRetry       = 6 * time.Hour
+	webhookResponseMaxLog = 1024
+)
+
+var webhookWake = make(chan struct{}, 1)
+
+// wakeWebhookRunner makes the runner send queued deliveries now instead of
+// at the next poll.
+func wakeWebhookRunner() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// webhookRetryDelay is the wait after the given failed attempt: 30s doubling
+// per attempt, capped at webhookMaxRetry.
+func webhookRetryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := webhookBaseRetry
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= webhookMaxRetry {
+			return webhookMaxRetry
+		}
+	}
+	return delay
+}
+
+// WebhookRunner POSTs queued webhook deliveries to their subscriptions,
+// signed with the subscription secret. Any 2xx response delivers; anything
+// else is retried with exponential backoff, and after webhookMaxAttempts the
+// delivery is dead-lettered until someone redelivers it. Every attempt is
+// logged with its response.
+type WebhookRunner struct {
+	db     *sql.DB
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+func NewWebhookRunner() *WebhookRunner {
+	return &WebhookRunner{
+		db:     database.GetDB(),
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Start launches the delivery loop. It returns at once; cancel ctx and call
+// Shutdown to stop.
+func (r *WebhookRunner) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+}
+
+// Shutdown waits up to timeout for an in-flight delivery to finish.
+func (r *WebhookRunner) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (r *WebhookRunner) loop(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		for ctx.Err() == nil {
+			worked, err := r.deliverOne()
+			if err != nil {
+				log.Printf("warning: webhook delivery: %v", err)
+				break
+			}
+			if !worked {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-webhookWake:
+		case <-time.After(webhookPollInterval):
+		}
+	}
+}
+
+// deliverOne sends the oldest due delivery. It reports whether there was
+// anything to send.
+func (r *WebhookRunner) deliverOne() (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		deliveryID     int64
+		attempts       int
+		eventID        int64
+		companyID      int
+		eventType      string
+		resourceType   string
+		resourceID     int
+		data           []byte
+		createdAt      time.Time
+		url            string
+		secret         string
+		previousSecret sql.NullString
+	)
+	err = tx.QueryRow(`
+		SELECT d.delivery_id, d.attempts, e.event_id, e.company_id, e.event_type, e.resource_type,
+		       e.resource_id, e.data, e.created_at, s.url, s.secret,
+		       CASE WHEN s.previous_secret_expires_at > CURRENT_TIMESTAMP THEN s.previous_secret END
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.event_id = d.event_id
+		JOIN webhook_subscriptions s ON s.subscription_id = d.subscription_id
+		WHERE d.status = 'PENDING' AND d.next_attempt_at <= CURRENT_TIMESTAMP AND s.is_active = TRUE
+		ORDER BY d.next_attempt_at, d.delivery_id
+		FOR UPDATE OF d SKIP LOCKED
+		LIMIT 1
+	`).Scan(&deliveryID, &attempts, &eventID, &companyID, &eventType, &resourceType,
+		&resourceID, &data, &createdAt, &url, &secret, &previousSecret)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load due delivery: %w", err)
+	}
+
+	secrets := []string{secret}
+	if previousSecret.Valid && previousSecret.String != "" {
+		secrets = append(secrets, previousSecret.String)
+	}
+	body := webhookEventBody(eventID, companyID, eventType, resourceType, resourceID, data, createdAt)
+	started := time.Now()
+	statusCode, responseBody, sendErr := r.post(url, deliveryID, eventType, secrets, body)
+	duration := int(time.Since(started) / time.Millisecond)
+
+	var codeArg, errArg, respArg interface{}
+	if statusCode != 0 {
+		codeArg = statusCode
+		respArg = responseBody
+	}
+	if sendErr == nil && (statusCode < 200 || statusCode > 299) {
+		sendErr = fmt.Errorf("endpoint responded %d", statusCode)
+	}
+	if sendErr != nil {
+		errArg = sendErr.Error()
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, response_body, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+	`, deliveryID, codeArg, errArg, respArg, duration); err != nil {
+		return false, fmt.Errorf("failed to log webhook attempt: %w", err)
+	}
+
+	attempts++
+	if sendErr == nil {
+		_, err = tx.Exec(`
+			UPDATE webhook_deliveries
+			SET status = 'SUCCEEDED', attempts = $2, last_status_code = $3, last_error = NULL,
+			    delivered_at = CURRENT_TIMESTAMP
+			WHERE delivery_id = $1
+		`, deliveryID, attempts, codeArg)
+	} else {
+		status := "PENDING"
+		if attempts >= webhookMaxAttempts {
+			status = "DEAD"
+		}
+		_, err = tx.Exec(`
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, last_status_code = $4, last_error = $5,
+			    next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $6)
+			WHERE delivery_id = $1
+		`, deliveryID, status, attempts, codeArg, errArg, webhookRetryDelay(attempts).Seconds())
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// post sends one signed request. It returns the status code (0 when no
+// response arrived) and the start of the response body.
+func (r *WebhookRunner) post(url string, deliveryID int64, eventType string, secrets []string, body []byte) (int, string, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "erp-webhooks/1")
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(deliveryID, 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", signWebhookPayload(secrets, timestamp, body))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseMaxLog))
+	// Text columns take neither NUL bytes nor invalid UTF-8.
+	logged := strings.ToValidUTF8(strings.ReplaceAll(string(respBody), "\x00", ""), "")
+	return resp.StatusCode, logged, nil
+}
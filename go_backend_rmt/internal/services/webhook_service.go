@@ -0,0 +1,454 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"erp-backend/internal/database"
+	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultWebhookSecretGrace = 24 * time.Hour
+	defaultWebhookDeliveries  = 50
+	maxWebhookDeliveries      = 200
+)
+
+type WebhookService struct {
+	db *sql.DB
+}
+
+func NewWebhookService() *WebhookService {
+	return &WebhookService{db: database.GetDB()}
+}
+
+// enqueueWebhookEventTx records a business event in the transaction of the
+// change it announces and queues one delivery per active subscription that
+// wants it, so a rolled-back change is never sent. Nothing is stored when no
+// subscription matches.
+func enqueueWebhookEventTx(tx sqlExecutor, companyID int, eventType, resourceType string, resourceID int, data models.JSONB) error {
+	if data == nil {
+		data = models.JSONB{}
+	}
+	_, err := tx.Exec(`
+		WITH subs AS (
+			SELECT subscription_id FROM webhook_subscriptions
+			WHERE company_id = $1 AND is_active = TRUE
+			  AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		), event AS (
+			INSERT INTO webhook_events (company_id, event_type, resource_type, resource_id, data)
+			SELECT $1, $2, $3, $4, $5
+			WHERE EXISTS (SELECT 1 FROM subs)
+			RETURNING event_id
+		)
+		INSERT INTO webhook_deliveries (event_id, subscription_id, company_id)
+		SELECT event.event_id, subs.subscription_id, $1
+		FROM event CROSS JOIN subs
+	`, companyID, eventType, resourceType, resourceID, data)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s webhook: %w", eventType, err)
+	}
+	return nil
+}
+
+// signWebhookPayload returns the X-Webhook-Signature value: the timestamp
+// and one v1 HMAC-SHA256 of "<timestamp>.<body>" per secret. During a
+// secret rotation both the new and the old secret sign.
+func signWebhookPayload(secrets []string, timestamp int64, body []byte) string {
+	parts := []string{"t=" + strconv.FormatInt(timestamp, 10)}
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+		mac.Write([]byte("."))
+		mac.Write(body)
+		parts = append(parts, "v1="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(parts, ",")
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+func webhookSecretHint(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+func normalizeWebhookEventTypes(types []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, t := range models.WebhookEventTypes {
+		known[t] = true
+	}
+	out := []string{}
+	seen := map[string]bool{}
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if !known[t] {
+			return nil, fmt.Errorf("unknown webhook event type %s", t)
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+const webhookSubscriptionSelect = `
+	SELECT subscription_id, company_id, name, url, event_types, secret, previous_secret_expires_at,
+	       is_active, created_by, updated_by, created_at, updated_at
+	FROM webhook_subscriptions
+`
+
+func scanWebhookSubscription(row interface{ Scan(...interface{}) error }) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	var secret string
+	var eventTypes pq.StringArray
+	if err := row.Scan(&sub.SubscriptionID, &sub.CompanyID, &sub.Name, &sub.URL, &eventTypes, &secret,
+		&sub.PreviousSecretExpiresAt, &sub.IsActive, &sub.CreatedBy, &sub.UpdatedBy, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	sub.EventTypes = []string(eventTypes)
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	sub.SecretHint = webhookSecretHint(secret)
+	if sub.PreviousSecretExpiresAt != nil && !sub.PreviousSecretExpiresAt.After(time.Now()) {
+		sub.PreviousSecretExpiresAt = nil
+	}
+	return &sub, nil
+}
+
+func (s *WebhookService) ListSubscriptions(companyID int) ([]models.WebhookSubscription, error) {
+	rows, err := s.db.Query(webhookSubscriptionSelect+` WHERE company_id = $1 ORDER BY name, subscription_id`, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *WebhookService) GetSubscription(companyID, subscriptionID int) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(s.db.QueryRow(webhookSubscriptionSelect+`
+		WHERE subscription_id = $1 AND company_id = $2
+	`, subscriptionID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook subscription not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// CreateSubscription returns the signing secret; it is not shown again.
+func (s *WebhookService) CreateSubscription(companyID, userID int, req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	eventTypes, err := normalizeWebhookEventTypes(req.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+	var id int
+	if err := s.db.QueryRow(`
+		INSERT INTO webhook_subscriptions (company_id, name, url, event_types, secret, is_active, created_by, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING subscription_id
+	`, companyID, strings.TrimSpace(req.Name), strings.TrimSpace(req.URL), pq.Array(eventTypes), secret, active, userID).Scan(&id); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	sub, err := s.GetSubscription(companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+func (s *WebhookService) UpdateSubscription(companyID, userID, subscriptionID int, req *models.UpdateWebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	sets := []string{}
+	args := []interface{}{}
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if req.Name != nil {
+		add("name", strings.TrimSpace(*req.Name))
+	}
+	if req.URL != nil {
+		add("url", strings.TrimSpace(*req.URL))
+	}
+	if req.EventTypes != nil {
+		eventTypes, err := normalizeWebhookEventTypes(*req.EventTypes)
+		if err != nil {
+			return nil, err
+		}
+		add("event_types", pq.Array(eventTypes))
+	}
+	if req.IsActive != nil {
+		add("is_active", *req.IsActive)
+	}
+	if len(sets) == 0 {
+		return s.GetSubscription(companyID, subscriptionID)
+	}
+	add("updated_by", userID)
+	args = append(args, subscriptionID, companyID)
+	res, err := s.db.Exec(fmt.Sprintf(`
+		UPDATE webhook_subscriptions SET %s, updated_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = $%d AND company_id = $%d
+	`, strings.Join(sets, ", "), len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("webhook subscription not found")
+	}
+	return s.GetSubscription(companyID, subscriptionID)
+}
+
+// DeleteSubscription removes the subscription with its queued and logged
+// deliveries.
+func (s *WebhookService) DeleteSubscription(companyID, subscriptionID int) error {
+	res, err := s.db.Exec(`
+		DELETE FROM webhook_subscriptions WHERE subscription_id = $1 AND company_id = $2
+	`, subscriptionID, companyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook subscription not found")
+	}
+	return nil
+}
+
+// RotateSecret issues a new signing secret. The old one keeps signing next
+// to it for the grace period (24 hours unless set).
+func (s *WebhookService) RotateSecret(companyID, userID, subscriptionID int, req *models.RotateWebhookSecretRequest) (*models.WebhookSubscription, error) {
+	grace := defaultWebhookSecretGrace
+	if req != nil && req.GraceHours != nil {
+		grace = time.Duration(*req.GraceHours) * time.Hour
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	var previousExpires *time.Time
+	if grace > 0 {
+		t := time.Now().Add(grace)
+		previousExpires = &t
+	}
+	res, err := s.db.Exec(`
+		UPDATE webhook_subscriptions
+		SET previous_secret = CASE WHEN $3::timestamp IS NULL THEN NULL ELSE secret END,
+		    previous_secret_expires_at = $3,
+		    secret = $4,
+		    updated_by = $5,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = $1 AND company_id = $2
+	`, subscriptionID, companyID, previousExpires, secret, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("webhook subscription not found")
+	}
+	sub, err := s.GetSubscription(companyID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = secret
+	return sub, nil
+}
+
+const webhookDeliverySelect = `
+	SELECT d.delivery_id, d.event_id, d.subscription_id, e.event_type, e.resource_type, e.resource_id,
+	       d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at,
+	       d.created_at
+	FROM webhook_deliveries d
+	JOIN webhook_events e ON e.event_id = d.event_id
+`
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.DeliveryID, &d.EventID, &d.SubscriptionID, &d.EventType, &d.ResourceType, &d.ResourceID,
+		&d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDeliveries is the delivery log, newest first. Filter on status DEAD
+// for the dead-letter queue.
+func (s *WebhookService) ListDeliveries(companyID int, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := webhookDeliverySelect + ` WHERE d.company_id = $1`
+	args := []interface{}{companyID}
+	if filter.SubscriptionID != nil {
+		args = append(args, *filter.SubscriptionID)
+		query += fmt.Sprintf(" AND d.subscription_id = $%d", len(args))
+	}
+	if v := strings.ToUpper(strings.TrimSpace(filter.Status)); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	}
+	if v := strings.TrimSpace(filter.EventType); v != "" {
+		args = append(args, v)
+		query += fmt.Sprintf(" AND e.event_type = $%d", len(args))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultWebhookDeliveries
+	}
+	if limit > maxWebhookDeliveries {
+		limit = maxWebhookDeliveries
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY d.created_at DESC, d.delivery_id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns the delivery with the payload sent and every attempt's
+// response.
+func (s *WebhookService) GetDelivery(companyID int, deliveryID int64) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(s.db.QueryRow(webhookDeliverySelect+`
+		WHERE d.delivery_id = $1 AND d.company_id = $2
+	`, deliveryID, companyID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	var data []byte
+	var createdAt time.Time
+	if err := s.db.QueryRow(`SELECT data, created_at FROM webhook_events WHERE event_id = $1`, d.EventID).Scan(&data, &createdAt); err != nil {
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+	var payload models.JSONB
+	if err := json.Unmarshal(webhookEventBody(d.EventID, companyID, d.EventType, d.ResourceType, d.ResourceID, data, createdAt), &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook payload: %w", err)
+	}
+	d.Payload = payload
+
+	rows, err := s.db.Query(`
+		SELECT attempt_id, status_code, error, response_body, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempted_at DESC, attempt_id DESC
+	`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.WebhookDeliveryAttempt
+		if err := rows.Scan(&a.AttemptID, &a.StatusCode, &a.Error, &a.ResponseBody, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery attempt: %w", err)
+		}
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return d, rows.Err()
+}
+
+// Redeliver queues a delivery again now with a fresh retry budget, whatever
+// its status.
+func (s *WebhookService) Redeliver(companyID int, deliveryID int64) (*models.WebhookDelivery, error) {
+	res, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'PENDING', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		WHERE delivery_id = $1 AND company_id = $2
+	`, deliveryID, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("webhook delivery not found")
+	}
+	wakeWebhookRunner()
+	return s.GetDelivery(companyID, deliveryID)
+}
+
+// RedeliverDead requeues the subscription's dead-letter queue and returns how
+// many deliveries were requeued.
+func (s *WebhookService) RedeliverDead(companyID, subscriptionID int) (int, error) {
+	if _, err := s.GetSubscription(companyID, subscriptionID); err != nil {
+		return 0, err
+	}
+	res, err := s.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'PENDING', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+		WHERE subscription_id = $1 AND company_id = $2 AND status = 'DEAD'
+	`, subscriptionID, companyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redeliver webhooks: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		wakeWebhookRunner()
+	}
+	return int(n), nil
+}
+
+// webhookEventBody is the JSON body POSTed for an event. data is the event's
+// stored JSON.
+func webhookEventBody(eventID int64, companyID int, eventType, resourceType string, resourceID int, data []byte, createdAt time.Time) []byte {
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"type":       eventType,
+		"company_id": companyID,
+		"created_at": createdAt.UTC().Format(time.RFC3339),
+		"resource":   map[string]interface{}{"type": resourceType, "id": resourceID},
+		"data":       json.RawMessage(data),
+	})
+	return body
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"erp-backend/internal/models"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSignWebhookPayload_SignsWithEverySecret(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	sig := signWebhookPayload([]string{"whsec_new", "whsec_old"}, 1700000000, body)
+
+	parts := strings.Split(sig, ",")
+	if len(parts) != 3 || parts[0] != "t=1700000000" {
+		t.Fatalf("unexpected signature header %q", sig)
+	}
+	for i, secret := range []string{"whsec_new", "whsec_old"} {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("1700000000." + string(body)))
+		if want := "v1=" + hex.EncodeToString(mac.Sum(nil)); parts[i+1] != want {
+			t.Fatalf("signature %d: expected %s, got %s", i, want, parts[i+1])
+		}
+	}
+}
+
+func TestWebhookRetryDelay_DoublesUpToCap(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		7:  32 * time.Minute,
+		20: webhookMaxRetry,
+	}
+	for attempt, want := range cases {
+		if got := webhookRetryDelay(attempt); got != want {
+			t.Fatalf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestNormalizeWebhookEventTypes(t *testing.T) {
+	types, err := normalizeWebhookEventTypes([]string{" Sale.Created", "", "sale.created", "customer.updated"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(types) != 2 || types[0] != models.WebhookEventSaleCreated || types[1] != models.WebhookEventCustomerUpdated {
+		t.Fatalf("unexpected event types %v", types)
+	}
+	if _, err := normalizeWebhookEventTypes([]string{"sale.exploded"}); err == nil || err.Error() != "unknown webhook event type sale.exploded" {
+		t.Fatalf("expected unknown event type error, got %v", err)
+	}
+}
+
+func TestEnqueueWebhookEventTx_FansOutInOneStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("(?s)WITH subs AS.*cardinality\\(event_types\\) = 0 OR \\$2 = ANY\\(event_types\\).*INSERT INTO webhook_events.*WHERE EXISTS.*INSERT INTO webhook_deliveries").
+		WithArgs(1, models.WebhookEventCustomerDeleted, "customer", 7, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := enqueueWebhookEventTx(tx, 1, models.WebhookEventCustomerDeleted, "customer", 7, models.JSONB{"name": "ACME"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = tx.Rollback()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestWebhookEventBody_WrapsStoredData(t *testing.T) {
+	created := time.Date(2026, 4, 26, 10, 0, 0, 0, time.UTC)
+	body := webhookEventBody(42, 1, models.WebhookEventSaleCreated, "sale", 9, []byte(`{"sale_number":"S-1"}`), created)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if got["id"] != float64(42) || got["type"] != "sale.created" || got["created_at"] != "2026-04-26T10:00:00Z" {
+		t.Fatalf("unexpected envelope %v", got)
+	}
+	resource, _ := got["resource"].(map[string]interface{})
+	data, _ := got["data"].(map[string]interface{})
+	if resource["type"] != "sale" || resource["id"] != float64(9) || data["sale_number"] != "S-1" {
+		t.Fatalf("unexpected resource or data %v", got)
+	}
+}
+
+func TestWebhookService_CreateSubscription_RejectsUnknownEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	svc := &WebhookService{db: db}
+	_, err = svc.CreateSubscription(1, 2, &models.CreateWebhookSubscriptionRequest{
+		Name:       "Shop",
+		URL:        "https://shop.example.com/hooks",
+		EventTypes: []string{"order.created"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown webhook event type") {
+		t.Fatalf("expected unknown event type error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
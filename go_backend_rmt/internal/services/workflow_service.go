@@ -3,11 +3,14 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"erp-backend/internal/database"
 	"erp-backend/internal/models"
+
+	"github.com/lib/pq"
 )
 
 const (
@@ -22,16 +25,21 @@ const (
 	workflowModuleSettings  = "SETTINGS"
 	workflowModuleSuppliers = "SUPPLIERS"
 	workflowModuleReturns   = "RETURNS"
-
-	workflowEntityPurchaseOrder    = "PURCHASE_ORDER"
-	workflowEntityInventorySetting = "INVENTORY_SETTINGS"
-	workflowEntitySupplier         = "SUPPLIER"
-	workflowEntityPurchaseReturn   = "PURCHASE_RETURN"
-
-	workflowActionApprovePurchaseOrder = "APPROVE_PURCHASE_ORDER"
-	workflowActionUpdateInventory      = "UPDATE_INVENTORY_SETTINGS"
-	workflowActionReviewSupplier       = "REVIEW_SUPPLIER_CHANGE"
-	workflowActionReviewPurchaseReturn = "REVIEW_PURCHASE_RETURN"
+	workflowModuleInventory = "INVENTORY"
+
+	workflowEntityPurchaseOrder       = "PURCHASE_ORDER"
+	workflowEntityInventorySetting    = "INVENTORY_SETTINGS"
+	workflowEntitySupplier            = "SUPPLIER"
+	workflowEntityPurchaseReturn      = "PURCHASE_RETURN"
+	workflowEntityInvoiceMatch        = "SUPPLIER_INVOICE_MATCH"
+	workflowEntityTransferDiscrepancy = "STOCK_TRANSFER_DISCREPANCY"
+
+	workflowActionApprovePurchaseOrder       = "APPROVE_PURCHASE_ORDER"
+	workflowActionUpdateInventory            = "UPDATE_INVENTORY_SETTINGS"
+	workflowActionReviewSupplier             = "REVIEW_SUPPLIER_CHANGE"
+	workflowActionReviewPurchaseReturn       = "REVIEW_PURCHASE_RETURN"
+	workflowActionOverrideInvoiceMatch       = "OVERRIDE_INVOICE_MATCH"
+	workflowActionResolveTransferDiscrepancy = "RESOLVE_TRANSFER_DISCREPANCY"
 )
 
 type WorkflowService struct {
@@ -96,21 +104,28 @@ func overdueWorkflowLevel(dueAt *time.Time, now time.Time) (bool, int) {
 	}
 }
 
-func (s *WorkflowService) getUserRoleID(userID int) (int, error) {
-	var roleID int
-	if err := s.db.QueryRow(`SELECT COALESCE(role_id, 0) FROM users WHERE user_id = $1`, userID).Scan(&roleID); err != nil {
+// getUserRoleIDs returns the user's own role followed by the roles granted
+// at their locations; a request is actionable under any of them.
+func (s *WorkflowService) getUserRoleIDs(userID int) (pq.Int64Array, error) {
+	var roleID int64
+	var locationRoleIDs pq.Int64Array
+	if err := s.db.QueryRow(`
+		SELECT COALESCE(role_id, 0),
+		       ARRAY(SELECT ul.role_id FROM user_locations ul WHERE ul.user_id = users.user_id AND ul.role_id IS NOT NULL)
+		FROM users WHERE user_id = $1
+	`, userID).Scan(&roleID, &locationRoleIDs); err != nil {
 		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("user not found")
+			return nil, fmt.Errorf("user not found")
 		}
-		return 0, fmt.Errorf("failed to get user role: %w", err)
+		return nil, fmt.Errorf("failed to get user role: %w", err)
 	}
-	return roleID, nil
+	return append(pq.Int64Array{roleID}, locationRoleIDs...), nil
 }
 
-func (s *WorkflowService) findApproverRoleTx(tx *sql.Tx, preferredNames ...string) (int, error) {
+func (s *WorkflowService) findApproverRoleTx(tx *sql.Tx, companyID int, preferredNames ...string) (int, error) {
 	for _, name := range preferredNames {
 		var roleID int
-		err := tx.QueryRow(`SELECT role_id FROM roles WHERE LOWER(name) = LOWER($1) LIMIT 1`, name).Scan(&roleID)
+		err := tx.QueryRow(`SELECT role_id FROM roles WHERE company_id = $1 AND LOWER(name) = LOWER($2) LIMIT 1`, companyID, name).Scan(&roleID)
 		if err == nil {
 			return roleID, nil
 		}
@@ -276,6 +291,9 @@ func (s *WorkflowService) createRequestTx(tx *sql.Tx, companyID, userID int, inp
 	if input.ApproverRoleID == 0 {
 		return nil, fmt.Errorf("approver role is required")
 	}
+	if err := ensureCompanyRole(tx, companyID, input.ApproverRoleID); err != nil {
+		return nil, fmt.Errorf("approver role: %w", err)
+	}
 
 	var approvalID int
 	var createdAt time.Time
@@ -389,16 +407,16 @@ func (s *WorkflowService) CreateRequest(companyID, userID int, req *models.Creat
 }
 
 func (s *WorkflowService) ListRequests(companyID, userID int, status string) ([]models.WorkflowRequest, error) {
-	roleID, err := s.getUserRoleID(userID)
+	roleIDs, err := s.getUserRoleIDs(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	query := s.loadRequestAccessQuery() + `
 		WHERE wr.company_id = $1
-		  AND (wr.created_by = $2 OR wr.approver_role_id = $3)
+		  AND (wr.created_by = $2 OR wr.approver_role_id = ANY($3))
 	`
-	args := []interface{}{companyID, userID, roleID}
+	args := []interface{}{companyID, userID, roleIDs}
 	if trimmed := strings.ToUpper(strings.TrimSpace(status)); trimmed != "" {
 		query += ` AND wr.status = $4`
 		args = append(args, trimmed)
@@ -425,7 +443,7 @@ func (s *WorkflowService) ListRequests(companyID, userID int, status string) ([]
 }
 
 func (s *WorkflowService) GetRequestByID(companyID, userID, approvalID int) (*models.WorkflowRequest, error) {
-	roleID, err := s.getUserRoleID(userID)
+	roleIDs, err := s.getUserRoleIDs(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -434,12 +452,12 @@ func (s *WorkflowService) GetRequestByID(companyID, userID, approvalID int) (*mo
 		s.loadRequestAccessQuery()+`
 		WHERE wr.company_id = $1
 		  AND wr.approval_id = $2
-		  AND (wr.created_by = $3 OR wr.approver_role_id = $4)
+		  AND (wr.created_by = $3 OR wr.approver_role_id = ANY($4))
 	`,
 		companyID,
 		approvalID,
 		userID,
-		roleID,
+		roleIDs,
 	)
 
 	req, err := s.scanWorkflowRequest(row)
@@ -516,6 +534,37 @@ func (s *WorkflowService) applyApprovedActionTx(tx *sql.Tx, req *models.Workflow
 			"entity_type": "inventory_settings",
 			"applied":     true,
 		}, nil
+	case workflowActionOverrideInvoiceMatch:
+		if req.EntityID == nil || *req.EntityID == 0 {
+			return nil, fmt.Errorf("invoice match workflow is missing entity_id")
+		}
+		adjustmentID, err := (&InvoiceMatchService{db: s.db}).applyOverrideTx(tx, req.CompanyID, *req.EntityID, userID)
+		if err != nil {
+			return nil, err
+		}
+		result := models.JSONB{
+			"entity_type": "supplier_invoice_match",
+			"entity_id":   *req.EntityID,
+			"status":      models.InvoiceMatchStatusOverridden,
+			"applied":     true,
+		}
+		if adjustmentID != nil {
+			result["price_variance_adjustment_id"] = *adjustmentID
+		}
+		return result, nil
+	case workflowActionResolveTransferDiscrepancy:
+		if req.EntityID == nil || *req.EntityID == 0 {
+			return nil, fmt.Errorf("transfer discrepancy workflow is missing entity_id")
+		}
+		if err := (&InventoryService{db: s.db}).resolveTransferDiscrepancyTx(tx, req.CompanyID, *req.EntityID, userID, true); err != nil {
+			return nil, err
+		}
+		return models.JSONB{
+			"entity_type": "stock_transfer_discrepancy",
+			"entity_id":   *req.EntityID,
+			"status":      models.TransferDiscrepancyStatusApproved,
+			"applied":     true,
+		}, nil
 	default:
 		return models.JSONB{
 			"reviewed": true,
@@ -534,18 +583,33 @@ func decodeInventorySettingsPayload(payload models.JSONB) models.UpdateInventory
 	if value, ok := payload["negative_stock_approval_password"].(string); ok && strings.TrimSpace(value) != "" {
 		req.NegativeStockApprovalPassword = &value
 	}
+	if value, ok := payload["batch_issue_policy"].(string); ok {
+		req.BatchIssuePolicy = &value
+	}
+	if value, ok := payload["block_expired_sales"].(bool); ok {
+		req.BlockExpiredSales = &value
+	}
+	switch value := payload["expiry_alert_days"].(type) {
+	case float64:
+		days := int(value)
+		req.ExpiryAlertDays = &days
+	case int:
+		req.ExpiryAlertDays = &value
+	}
 	return req
 }
 
 func (s *WorkflowService) ensureApproverRole(userID int, approverRoleID int) error {
-	roleID, err := s.getUserRoleID(userID)
+	roleIDs, err := s.getUserRoleIDs(userID)
 	if err != nil {
 		return err
 	}
-	if roleID != approverRoleID {
-		return fmt.Errorf("workflow request is assigned to a different approver role")
+	for _, roleID := range roleIDs {
+		if int(roleID) == approverRoleID {
+			return nil
+		}
 	}
-	return nil
+	return fmt.Errorf("workflow request is assigned to a different approver role")
 }
 
 func (s *WorkflowService) decideRequest(companyID, approvalID, userID int, remarks *string, approve bool) error {
@@ -576,6 +640,14 @@ func (s *WorkflowService) decideRequest(companyID, approvalID, userID int, remar
 		if err != nil {
 			return err
 		}
+	} else if req.ActionType == workflowActionOverrideInvoiceMatch && req.EntityID != nil {
+		if err := (&InvoiceMatchService{db: s.db}).releaseOverrideTx(tx, companyID, *req.EntityID, userID); err != nil {
+			return err
+		}
+	} else if req.ActionType == workflowActionResolveTransferDiscrepancy && req.EntityID != nil {
+		if err := (&InventoryService{db: s.db}).resolveTransferDiscrepancyTx(tx, companyID, *req.EntityID, userID, false); err != nil {
+			return err
+		}
 	}
 
 	_, err = tx.Exec(`
@@ -602,6 +674,14 @@ func (s *WorkflowService) decideRequest(companyID, approvalID, userID int, remar
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit workflow decision: %w", err)
 	}
+	if adjustmentID, ok := resultSnapshot["price_variance_adjustment_id"].(int); ok && approve {
+		_ = (&LedgerService{db: s.db}).RecordPurchaseCostAdjustment(companyID, adjustmentID, userID)
+	}
+	if req.ActionType == workflowActionResolveTransferDiscrepancy && req.EntityID != nil {
+		if err := NewFinanceIntegrityServiceWithDB(s.db).ProcessAggregate(companyID, "stock_transfer_discrepancy", *req.EntityID); err != nil {
+			log.Printf("workflow_service: failed to process finance outbox for stock_transfer_discrepancy %d: %v", *req.EntityID, err)
+		}
+	}
 	return nil
 }
 
@@ -614,7 +694,7 @@ func (s *WorkflowService) RejectRequest(companyID, approvalID, userID int, remar
 }
 
 func (s *WorkflowService) CreatePurchaseApprovalRequestTx(tx *sql.Tx, companyID, locationID, userID, purchaseID int, purchaseNumber string, supplierName string, totalAmount float64) (*models.WorkflowRequest, error) {
-	approverRoleID, err := s.findApproverRoleTx(tx, "Purchase Manager", "Manager", "Admin", "Super Admin")
+	approverRoleID, err := s.findApproverRoleTx(tx, companyID, "Purchase Manager", "Manager", "Admin", "Super Admin")
 	if err != nil {
 		return nil, err
 	}
@@ -644,13 +724,16 @@ func (s *WorkflowService) CreatePurchaseApprovalRequestTx(tx *sql.Tx, companyID,
 }
 
 func (s *WorkflowService) CreateInventorySettingsApproval(companyID, userID int, req models.UpdateInventorySettingsRequest) (*models.WorkflowRequest, error) {
+	if req.ExpiryAlertDays != nil && (*req.ExpiryAlertDays < 1 || *req.ExpiryAlertDays > 365) {
+		return nil, fmt.Errorf("expiry_alert_days must be between 1 and 365")
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin settings workflow transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	approverRoleID, err := s.findApproverRoleTx(tx, "Super Admin", "Admin", "Manager")
+	approverRoleID, err := s.findApproverRoleTx(tx, companyID, "Super Admin", "Admin", "Manager")
 	if err != nil {
 		return nil, err
 	}
@@ -665,6 +748,15 @@ func (s *WorkflowService) CreateInventorySettingsApproval(companyID, userID int,
 	if req.NegativeStockApprovalPassword != nil && strings.TrimSpace(*req.NegativeStockApprovalPassword) != "" {
 		payload["negative_stock_approval_password"] = strings.TrimSpace(*req.NegativeStockApprovalPassword)
 	}
+	if req.BatchIssuePolicy != nil {
+		payload["batch_issue_policy"] = normalizeBatchIssuePolicy(*req.BatchIssuePolicy)
+	}
+	if req.BlockExpiredSales != nil {
+		payload["block_expired_sales"] = *req.BlockExpiredSales
+	}
+	if req.ExpiryAlertDays != nil {
+		payload["expiry_alert_days"] = *req.ExpiryAlertDays
+	}
 
 	created, err := s.createRequestTx(tx, companyID, userID, workflowCreateInput{
 		Module:         workflowModuleSettings,
@@ -694,7 +786,7 @@ func (s *WorkflowService) CreateSupplierReviewRequest(companyID, userID, supplie
 	}
 	defer tx.Rollback()
 
-	approverRoleID, err := s.findApproverRoleTx(tx, "Admin", "Manager", "Super Admin")
+	approverRoleID, err := s.findApproverRoleTx(tx, companyID, "Admin", "Manager", "Super Admin")
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,6 @@
 package services
 
 import (
-	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -83,9 +82,9 @@ func TestWorkflowService_ApproveRequest_TransitionsPendingPurchase(t *testing.T)
 	mock.ExpectQuery("(?s)FROM workflow_requests wr.*WHERE wr\\.company_id = \\$1.*wr\\.approval_id = \\$2.*FOR UPDATE").
 		WithArgs(1, 11).
 		WillReturnRows(workflowRequestRows(now))
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(role_id, 0) FROM users WHERE user_id = $1")).
+	mock.ExpectQuery("(?s)SELECT COALESCE\\(role_id, 0\\),.*FROM user_locations ul.*FROM users WHERE user_id = \\$1").
 		WithArgs(22).
-		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(7))
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "location_role_ids"}).AddRow(7, "{}"))
 	mock.ExpectExec("(?s)UPDATE purchases p.*SET status = 'APPROVED'.*WHERE p\\.purchase_id = \\$2.*").
 		WithArgs(22, 12, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
@@ -138,9 +137,9 @@ func TestWorkflowService_ApproveRequest_RejectsWrongApproverRole(t *testing.T) {
 	mock.ExpectQuery("(?s)FROM workflow_requests wr.*WHERE wr\\.company_id = \\$1.*wr\\.approval_id = \\$2.*FOR UPDATE").
 		WithArgs(1, 11).
 		WillReturnRows(workflowRequestRows(now))
-	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(role_id, 0) FROM users WHERE user_id = $1")).
+	mock.ExpectQuery("(?s)SELECT COALESCE\\(role_id, 0\\),.*FROM user_locations ul.*FROM users WHERE user_id = \\$1").
 		WithArgs(22).
-		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(3))
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "location_role_ids"}).AddRow(3, "{5}"))
 	mock.ExpectRollback()
 
 	err = svc.ApproveRequest(1, 11, 22, nil)
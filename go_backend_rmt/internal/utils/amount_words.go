@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	wordsOnes = []string{"Zero", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine",
+		"Ten", "Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+	wordsTens   = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+	wordsScales = []string{"", "Thousand", "Million", "Billion", "Trillion"}
+)
+
+// AmountInWords spells out an amount the way it is printed on invoices, e.g.
+// "US Dollar One Hundred Twenty-Three and 45/100 Only". The currency name is
+// optional.
+func AmountInWords(amount float64, currencyName string) string {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole, fraction := cents/100, cents%100
+
+	var parts []string
+	if amount < 0 && cents > 0 {
+		parts = append(parts, "Minus")
+	}
+	if name := strings.TrimSpace(currencyName); name != "" {
+		parts = append(parts, name)
+	}
+	parts = append(parts, integerInWords(whole))
+	if fraction > 0 {
+		parts = append(parts, fmt.Sprintf("and %02d/100", fraction))
+	}
+	parts = append(parts, "Only")
+	return strings.Join(parts, " ")
+}
+
+func integerInWords(n int64) string {
+	if n == 0 {
+		return wordsOnes[0]
+	}
+	var groups []string
+	for scale := 0; n > 0 && scale < len(wordsScales); scale++ {
+		chunk := int(n % 1000)
+		n /= 1000
+		if chunk == 0 {
+			continue
+		}
+		words := hundredsInWords(chunk)
+		if wordsScales[scale] != "" {
+			words += " " + wordsScales[scale]
+		}
+		groups = append([]string{words}, groups...)
+	}
+	return strings.Join(groups, " ")
+}
+
+func hundredsInWords(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, wordsOnes[n/100]+" Hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		parts = append(parts, wordsOnes[n])
+	case n%10 == 0:
+		parts = append(parts, wordsTens[n/10])
+	default:
+		parts = append(parts, wordsTens[n/10]+"-"+wordsOnes[n%10])
+	}
+	return strings.Join(parts, " ")
+}
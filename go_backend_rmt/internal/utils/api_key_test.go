@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey_PrefixIdentifiesKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(prefix, APIKeyScheme) || !strings.HasPrefix(key, prefix+"_") {
+		t.Fatalf("unexpected key %q with prefix %q", key, prefix)
+	}
+	if got, ok := APIKeyPrefix(key); !ok || got != prefix {
+		t.Fatalf("expected prefix %q, got %q (%v)", prefix, got, ok)
+	}
+	if _, ok := APIKeyPrefix("eyJhbGciOiJIUzI1NiJ9"); ok {
+		t.Fatalf("expected a JWT not to parse as an API key")
+	}
+	if HashAPIKey(key) == HashAPIKey(key+"x") || len(HashAPIKey(key)) != 64 {
+		t.Fatalf("unexpected key hash")
+	}
+}
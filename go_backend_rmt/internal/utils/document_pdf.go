@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const documentPDFLinesPerPage = 64
+
+// GenerateDocumentPDF lays out pre-formatted text lines as a monospaced,
+// multi-page PDF. It is used for documents such as customer statements where
+// column alignment matters more than typography.
+func GenerateDocumentPDF(lines []string) []byte {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	var pages []string
+	for start := 0; start < len(lines); start += documentPDFLinesPerPage {
+		end := start + documentPDFLinesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		var sb strings.Builder
+		sb.WriteString("BT /F1 9 Tf 11 TL 36 756 Td ")
+		for i, line := range lines[start:end] {
+			if i > 0 {
+				sb.WriteString(" T* ")
+			}
+			sb.WriteString("(")
+			sb.WriteString(escapePDFText(line))
+			sb.WriteString(") Tj")
+		}
+		sb.WriteString(" ET")
+		pages = append(pages, sb.String())
+	}
+
+	var buf bytes.Buffer
+	offsets := []int{}
+	write := func(format string, a ...interface{}) {
+		fmt.Fprintf(&buf, format, a...)
+	}
+	writeObj := func(format string, a ...interface{}) {
+		offsets = append(offsets, buf.Len())
+		write(format, a...)
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content
+	// stream for every page.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+	write("%%PDF-1.4\n")
+	writeObj("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	writeObj("2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pages))
+	writeObj("3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>\nendobj\n")
+	for i, content := range pages {
+		pageObj := 4 + i*2
+		writeObj("%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 3 0 R >> >> >>\nendobj\n", pageObj, pageObj+1)
+		writeObj("%d 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", pageObj+1, len(content), content)
+	}
+
+	xrefOffset := buf.Len()
+	write("xref\n0 %d\n", len(offsets)+1)
+	write("0000000000 65535 f \n")
+	for _, off := range offsets {
+		write("%010d 00000 n \n", off)
+	}
+	write("trailer << /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%EOF\n", len(offsets)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+func escapePDFText(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "(", "\\(")
+	return strings.ReplaceAll(s, ")", "\\)")
+}
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 
 	"erp-backend/internal/config"
 )
@@ -21,3 +25,55 @@ func SendEmail(to, subject, body string) error {
 
 	return smtp.SendMail(addr, auth, cfg.FromEmail, []string{to}, msg)
 }
+
+// EmailAttachment is a file sent along with an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// SendEmailWithAttachments sends a plain text email with file attachments
+func SendEmailWithAttachments(to, subject, body string, attachments []EmailAttachment) error {
+	cfg := config.Load()
+	if cfg.SMTPHost == "" {
+		return fmt.Errorf("smtp host not configured")
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	auth := smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+
+	return smtp.SendMail(addr, auth, cfg.FromEmail, []string{to}, buildMultipartEmail(to, subject, body, attachments))
+}
+
+func buildMultipartEmail(to, subject, body string, attachments []EmailAttachment) []byte {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "To: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%q\r\n\r\n", to, subject, writer.Boundary())
+
+	textHeader := textproto.MIMEHeader{}
+	textHeader.Set("Content-Type", "text/plain; charset=\"UTF-8\"")
+	if part, err := writer.CreatePart(textHeader); err == nil {
+		part.Write([]byte(body))
+	}
+
+	for _, attachment := range attachments {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", attachment.ContentType)
+		header.Set("Content-Transfer-Encoding", "base64")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Filename))
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			continue
+		}
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	writer.Close()
+	return buf.Bytes()
+}
@@ -26,13 +26,14 @@ func InitializeJWT(secret string) {
 func GenerateAccessToken(user *models.User, sessionID string, expiry time.Duration) (string, error) {
 	claims := &Claims{
 		JWTClaims: models.JWTClaims{
-			SessionID:  sessionID,
-			UserID:     user.UserID,
-			CompanyID:  user.CompanyID,
-			LocationID: user.LocationID,
-			RoleID:     user.RoleID,
-			Email:      user.Email,
-			Type:       "access",
+			SessionID:   sessionID,
+			UserID:      user.UserID,
+			CompanyID:   user.CompanyID,
+			LocationID:  user.LocationID,
+			LocationIDs: user.LocationIDs,
+			RoleID:      user.RoleID,
+			Email:       user.Email,
+			Type:        "access",
 		},
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"erp-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResolveActiveLocation picks the location a session works in and the role
+// that applies there. preferred (the session's choice) wins when it is one
+// of grants, then the user's default location, then the first grant. A user
+// without grants is not restricted and keeps preferred or the default.
+func ResolveActiveLocation(grants []models.UserLocation, defaultLocationID, defaultRoleID, preferred *int) (locationID, roleID *int) {
+	if len(grants) == 0 {
+		if preferred != nil {
+			return preferred, defaultRoleID
+		}
+		return defaultLocationID, defaultRoleID
+	}
+	pick := func(id *int) *models.UserLocation {
+		if id == nil {
+			return nil
+		}
+		for i := range grants {
+			if grants[i].LocationID == *id {
+				return &grants[i]
+			}
+		}
+		return nil
+	}
+	grant := pick(preferred)
+	if grant == nil {
+		grant = pick(defaultLocationID)
+	}
+	if grant == nil {
+		grant = &grants[0]
+	}
+	id := grant.LocationID
+	locationID = &id
+	if grant.RoleID != nil {
+		return locationID, grant.RoleID
+	}
+	return locationID, defaultRoleID
+}
+
+// UserLocationIDs lists the granted location IDs in order.
+func UserLocationIDs(grants []models.UserLocation) []int {
+	if len(grants) == 0 {
+		return nil
+	}
+	ids := make([]int, len(grants))
+	for i, g := range grants {
+		ids[i] = g.LocationID
+	}
+	return ids
+}
+
+// LocationAllowed reports whether locationID is in allowed; an empty list
+// allows every location.
+func LocationAllowed(allowed []int, locationID int) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, id := range allowed {
+		if id == locationID {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextLocationIDs returns the locations RequireAuth allowed for the
+// request; empty means the caller is not restricted.
+func ContextLocationIDs(c *gin.Context) []int {
+	ids, _ := c.Get("location_ids")
+	allowed, _ := ids.([]int)
+	return allowed
+}
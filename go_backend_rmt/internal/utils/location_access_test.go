@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+
+	"erp-backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestResolveActiveLocation(t *testing.T) {
+	grants := []models.UserLocation{
+		{LocationID: 2},
+		{LocationID: 5, RoleID: intPtr(40)},
+	}
+	cases := []struct {
+		name      string
+		grants    []models.UserLocation
+		preferred *int
+		wantLoc   *int
+		wantRole  int
+	}{
+		{"no grants keeps default", nil, nil, intPtr(9), 7},
+		{"no grants keeps preferred", nil, intPtr(3), intPtr(3), 7},
+		{"preferred grant uses its role", grants, intPtr(5), intPtr(5), 40},
+		{"default grant keeps own role", grants, nil, intPtr(2), 7},
+		{"revoked preferred falls back", grants, intPtr(8), intPtr(2), 7},
+		{"default outside grants picks first", grants[1:], nil, intPtr(5), 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			def := intPtr(9)
+			if tc.grants != nil {
+				def = intPtr(2)
+			}
+			loc, role := ResolveActiveLocation(tc.grants, def, intPtr(7), tc.preferred)
+			if loc == nil || *loc != *tc.wantLoc {
+				t.Fatalf("location = %v, want %d", loc, *tc.wantLoc)
+			}
+			if role == nil || *role != tc.wantRole {
+				t.Fatalf("role = %v, want %d", role, tc.wantRole)
+			}
+		})
+	}
+}
+
+func TestLocationAllowed(t *testing.T) {
+	if !LocationAllowed(nil, 4) {
+		t.Fatal("empty list should allow every location")
+	}
+	if !LocationAllowed([]int{1, 4}, 4) || LocationAllowed([]int{1, 4}, 3) {
+		t.Fatal("unexpected location check result")
+	}
+}
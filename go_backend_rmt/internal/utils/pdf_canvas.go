@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Standard page sizes in PDF points.
+const (
+	PDFPageA4Width      = 595.28
+	PDFPageA4Height     = 841.89
+	PDFPageLetterWidth  = 612
+	PDFPageLetterHeight = 792
+)
+
+// Helvetica and Helvetica-Bold advance widths for ASCII 32..126 in 1/1000 em.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// winAnsiExtras maps the non-Latin-1 characters WinAnsiEncoding can show.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// PDFCanvas draws text, rules, rectangles and images on fixed-size pages with
+// the standard Helvetica fonts. Coordinates are PDF points measured from the
+// bottom-left corner of the page.
+type PDFCanvas struct {
+	Width  float64
+	Height float64
+	pages  []*bytes.Buffer
+	images [][]byte
+}
+
+// NewPDFCanvas starts a document with a single empty page.
+func NewPDFCanvas(width, height float64) *PDFCanvas {
+	c := &PDFCanvas{Width: width, Height: height}
+	c.AddPage()
+	return c
+}
+
+// AddPage starts a new page; later drawing goes to it.
+func (c *PDFCanvas) AddPage() {
+	c.pages = append(c.pages, &bytes.Buffer{})
+}
+
+// PageCount returns the number of pages drawn so far.
+func (c *PDFCanvas) PageCount() int {
+	return len(c.pages)
+}
+
+func (c *PDFCanvas) page() *bytes.Buffer {
+	return c.pages[len(c.pages)-1]
+}
+
+// Text draws a single line with its baseline at y.
+func (c *PDFCanvas) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(c.page(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, encodePDFWinAnsi(text))
+}
+
+// SetColor sets the fill and stroke colour for later drawing; components are
+// in the 0..1 range.
+func (c *PDFCanvas) SetColor(r, g, b float64) {
+	fmt.Fprintf(c.page(), "%.3f %.3f %.3f rg %.3f %.3f %.3f RG\n", r, g, b, r, g, b)
+}
+
+// Rect draws a rectangle whose bottom-left corner is (x, y).
+func (c *PDFCanvas) Rect(x, y, w, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(c.page(), "%.2f %.2f %.2f %.2f re %s\n", x, y, w, h, op)
+}
+
+// Line draws a straight rule.
+func (c *PDFCanvas) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(c.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// Image places img scaled into the w x h box whose bottom-left corner is
+// (x, y). Transparent pixels are flattened onto white.
+func (c *PDFCanvas) Image(img image.Image, x, y, w, h float64) {
+	bounds := img.Bounds()
+	raw := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			r, g, b, a := img.At(px, py).RGBA()
+			white := 0xffff - a
+			raw = append(raw, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(raw)
+	zw.Close()
+
+	var obj bytes.Buffer
+	fmt.Fprintf(&obj, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n", bounds.Dx(), bounds.Dy(), compressed.Len())
+	obj.Write(compressed.Bytes())
+	obj.WriteString("\nendstream")
+	c.images = append(c.images, obj.Bytes())
+	fmt.Fprintf(c.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, y, len(c.images))
+}
+
+// Bytes serialises the document.
+func (c *PDFCanvas) Bytes() []byte {
+	var buf bytes.Buffer
+	offsets := []int{}
+	writeObj := func(body []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3-4 fonts, then images, then a page
+	// and its content stream for every page.
+	firstPage := 5 + len(c.images)
+	kids := make([]string, len(c.pages))
+	for i := range c.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	var xobjects strings.Builder
+	for i := range c.images {
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i+1, 5+i)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	writeObj([]byte("<< /Type /Catalog /Pages 2 0 R >>"))
+	writeObj([]byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(c.pages))))
+	writeObj([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+	writeObj([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"))
+	for _, img := range c.images {
+		writeObj(img)
+	}
+	for i, content := range c.pages {
+		pageObj := firstPage + i*2
+		writeObj([]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject << %s>> >> >>", c.Width, c.Height, pageObj+1, xobjects.String())))
+		writeObj([]byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String())))
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n", len(offsets)+1)
+	buf.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer << /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%EOF\n", len(offsets)+1, xrefOffset)
+	return buf.Bytes()
+}
+
+// PDFTextWidth measures text set in Helvetica at the given size.
+func PDFTextWidth(text string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range text {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// PDFRepresentable reports whether every character of text can be drawn with
+// the standard fonts; scripts such as Arabic or Sinhala cannot.
+func PDFRepresentable(text string) bool {
+	for _, r := range text {
+		if _, ok := winAnsiByte(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func winAnsiByte(r rune) (byte, bool) {
+	if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+		return byte(r), true
+	}
+	b, ok := winAnsiExtras[r]
+	return b, ok
+}
+
+// encodePDFWinAnsi converts text into an escaped literal string body;
+// characters outside WinAnsiEncoding become '?'.
+func encodePDFWinAnsi(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		b, ok := winAnsiByte(r)
+		if !ok {
+			b = '?'
+		}
+		switch {
+		case b == '\\' || b == '(' || b == ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case b < 32 || b >= 0x80:
+			fmt.Fprintf(&sb, "\\%03o", b)
+		default:
+			sb.WriteByte(b)
+		}
+	}
+	return sb.String()
+}
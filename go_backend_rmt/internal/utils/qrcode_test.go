@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQRReedSolomonMatchesSpecExample(t *testing.T) {
+	// Version 1-M encoding of "01234567" from ISO/IEC 18004 Annex I.
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := qrReedSolomonRemainder(data, qrReedSolomonDivisor(10)); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected error correction codewords % X", got)
+	}
+}
+
+func TestQRFormatBits(t *testing.T) {
+	if got := qrFormatBitsFor(QRErrorCorrectionMedium, 0); got != 0x5412 {
+		t.Fatalf("expected 0x5412 for M/0, got %#x", got)
+	}
+	if got := qrFormatBitsFor(QRErrorCorrectionLow, 0); got != 0x77C4 {
+		t.Fatalf("expected 0x77C4 for L/0, got %#x", got)
+	}
+}
+
+func TestEncodeQRStructure(t *testing.T) {
+	qr, err := EncodeQR("INV-000123", QRErrorCorrectionMedium)
+	if err != nil {
+		t.Fatalf("EncodeQR returned error: %v", err)
+	}
+	if qr.Version != 1 || qr.Size != 21 {
+		t.Fatalf("expected a version 1 symbol, got version %d size %d", qr.Version, qr.Size)
+	}
+	// Finder pattern centres and the always-dark module.
+	for _, p := range [][2]int{{3, 3}, {qr.Size - 4, 3}, {3, qr.Size - 4}, {8, qr.Size - 8}} {
+		if !qr.Dark(p[0], p[1]) {
+			t.Fatalf("expected module %v to be dark", p)
+		}
+	}
+	for i := 8; i < qr.Size-8; i++ {
+		if qr.Dark(i, 6) != (i%2 == 0) {
+			t.Fatalf("timing pattern broken at %d", i)
+		}
+	}
+
+	// The stored format word must decode to level M with one of the masks.
+	word := 0
+	for i := 0; i <= 5; i++ {
+		if qr.Dark(8, i) {
+			word |= 1 << uint(i)
+		}
+	}
+	for i, p := range [][2]int{{8, 7}, {8, 8}, {7, 8}} {
+		if qr.Dark(p[0], p[1]) {
+			word |= 1 << uint(6+i)
+		}
+	}
+	for i := 9; i < 15; i++ {
+		if qr.Dark(14-i, 8) {
+			word |= 1 << uint(i)
+		}
+	}
+	matched := false
+	for mask := 0; mask < 8; mask++ {
+		matched = matched || qrFormatBitsFor(QRErrorCorrectionMedium, mask) == word
+	}
+	if !matched {
+		t.Fatalf("format word %#x does not match level M", word)
+	}
+}
+
+func TestEncodeQRPicksLargerVersionsForLongText(t *testing.T) {
+	qr, err := EncodeQR(strings.Repeat("a", 300), QRErrorCorrectionMedium)
+	if err != nil {
+		t.Fatalf("EncodeQR returned error: %v", err)
+	}
+	if qr.Version < 7 || qr.Size != qr.Version*4+17 {
+		t.Fatalf("unexpected version %d size %d", qr.Version, qr.Size)
+	}
+	if !strings.HasPrefix(qr.SVG(2), "<svg") {
+		t.Fatalf("expected svg output")
+	}
+	if _, err := EncodeQR(strings.Repeat("a", 3000), QRErrorCorrectionHigh); err == nil {
+		t.Fatalf("expected oversize data to be rejected")
+	}
+}
+
+func TestEncodeCode128(t *testing.T) {
+	for i, pattern := range code128Patterns {
+		sum := 0
+		for _, w := range pattern {
+			sum += int(w - '0')
+		}
+		want := 11
+		if i == code128Stop {
+			want = 13
+		}
+		if sum != want {
+			t.Fatalf("pattern %d sums to %d", i, sum)
+		}
+	}
+
+	widths, err := EncodeCode128("ABC")
+	if err != nil {
+		t.Fatalf("EncodeCode128 returned error: %v", err)
+	}
+	// start + 3 characters + checksum are six widths each, stop is seven.
+	if len(widths) != 5*6+7 {
+		t.Fatalf("unexpected width count %d", len(widths))
+	}
+	// (104 + 33*1 + 34*2 + 35*3) % 103 = 1
+	checksum := widths[4*6 : 5*6]
+	for i, w := range code128Patterns[1] {
+		if checksum[i] != int(w-'0') {
+			t.Fatalf("unexpected checksum symbol %v", checksum)
+		}
+	}
+	if _, err := EncodeCode128("café"); err == nil {
+		t.Fatalf("expected non-ASCII input to be rejected")
+	}
+}
+
+func TestAmountInWords(t *testing.T) {
+	cases := map[float64]string{
+		1234.56: "US Dollar One Thousand Two Hundred Thirty-Four and 56/100 Only",
+		0:       "US Dollar Zero Only",
+		1000000: "US Dollar One Million Only",
+		-15.5:   "Minus US Dollar Fifteen and 50/100 Only",
+	}
+	for amount, want := range cases {
+		if got := AmountInWords(amount, "US Dollar"); got != want {
+			t.Fatalf("AmountInWords(%v) = %q, want %q", amount, got, want)
+		}
+	}
+	if got := AmountInWords(90, ""); got != "Ninety Only" {
+		t.Fatalf("unexpected words without currency: %q", got)
+	}
+}
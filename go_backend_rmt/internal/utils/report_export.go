@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -41,10 +42,22 @@ var reportExportSchemas = map[string]reportExportSchema{
 		Title:         "Supplier Purchases and Balances",
 		PreferredCols: []string{"supplier_id", "supplier_name", "purchases_total", "purchases_paid", "purchases_outstanding", "returns_total"},
 	},
+	"/reports/invoice-match-variances": {
+		Title:         "Invoice Match Variances",
+		PreferredCols: []string{"purchase_number", "supplier_name", "supplier_invoice_number", "invoice_date", "match_status", "product_name", "received_quantity", "invoiced_quantity", "quantity_variance_percent", "ordered_unit_price", "invoiced_unit_price", "price_variance_percent", "price_variance_amount", "line_status"},
+	},
 	"/reports/daily-cash": {
 		Title:         "Cash Register Summary",
 		PreferredCols: []string{"date", "location_id", "status", "opening_balance", "cash_in", "cash_out", "expected_balance", "closing_balance", "variance"},
 	},
+	"/reports/cash-register-x": {
+		Title:         "Cash Register X Report",
+		PreferredCols: []string{"section", "label", "count", "amount"},
+	},
+	"/reports/cash-register-z": {
+		Title:         "Cash Register Z Report",
+		PreferredCols: []string{"section", "label", "count", "amount"},
+	},
 	"/reports/cash-book": {
 		Title:         "Cash Book",
 		PreferredCols: []string{"date", "account_code", "account_name", "debit", "credit", "running_balance", "transaction_type", "transaction_id", "reference", "description"},
@@ -135,6 +148,7 @@ var reportExportLabels = map[string]string{
 	"category_name":         "Category",
 	"closing_balance":       "Closing Balance",
 	"consumed_at":           "Consumed At",
+	"count":                 "Count",
 	"credit":                "Credit",
 	"customer_id":           "Customer ID",
 	"date":                  "Date",
@@ -149,6 +163,7 @@ var reportExportLabels = map[string]string{
 	"in_service_date":       "In Service Date",
 	"item_count":            "Item Count",
 	"item_name":             "Item Name",
+	"label":                 "Line",
 	"location_id":           "Location ID",
 	"name":                  "Name",
 	"net_income":            "Net Income",
@@ -217,6 +232,13 @@ var reportExportValueLabels = map[string]map[string]string{
 		"TOTAL_EXPENSE":                   "Total Expenses",
 		"NET_PROFIT":                      "Net Profit",
 		"ASSETS_MINUS_LIABILITIES_EQUITY": "Balance Sheet Difference",
+		"DRAWER":                          "Drawer",
+		"SALES":                           "Sales",
+		"RETURNS":                         "Returns",
+		"VOIDS":                           "Voids",
+		"TAX":                             "Tax",
+		"TENDER":                          "Tenders",
+		"MOVEMENT":                        "Movements",
 	},
 	"type": {
 		"sales":     "Accounts Receivable",
@@ -231,6 +253,9 @@ var reportExportValueLabels = map[string]map[string]string{
 
 // GenerateExcel creates an Excel file from the provided data.
 func GenerateExcel(endpoint string, data interface{}) ([]byte, error) {
+	if table, ok := data.(*ReportTable); ok {
+		return generateTableExcel(table)
+	}
 	file := excelize.NewFile()
 
 	schema := lookupReportSchema(endpoint)
@@ -314,25 +339,7 @@ func writeListAsTable(file *excelize.File, sheet string, schema reportExportSche
 		return nil
 	}
 
-	columnSet := map[string]struct{}{}
-	for i := 0; i < len(list) && i < 200; i++ {
-		if rowMap, ok := list[i].(map[string]interface{}); ok {
-			for _, key := range mapKeys(rowMap) {
-				columnSet[key] = struct{}{}
-			}
-		} else {
-			columnSet["value"] = struct{}{}
-		}
-	}
-	if len(columnSet) == 0 {
-		columnSet["value"] = struct{}{}
-	}
-
-	ordered := make([]string, 0, len(columnSet))
-	for key := range columnSet {
-		ordered = append(ordered, key)
-	}
-	ordered = orderedColumns(schema, ordered)
+	ordered := tabularColumns(schema, list)
 
 	file.SetCellValue(sheet, "A1", schema.Title)
 	for i, col := range ordered {
@@ -370,6 +377,97 @@ func writeListAsTable(file *excelize.File, sheet string, schema reportExportSche
 	return nil
 }
 
+// tabularColumns collects the columns of a list report from its first rows.
+func tabularColumns(schema reportExportSchema, list []interface{}) []string {
+	columnSet := map[string]struct{}{}
+	for i := 0; i < len(list) && i < 200; i++ {
+		if rowMap, ok := list[i].(map[string]interface{}); ok {
+			for _, key := range mapKeys(rowMap) {
+				columnSet[key] = struct{}{}
+			}
+		} else {
+			columnSet["value"] = struct{}{}
+		}
+	}
+	if len(columnSet) == 0 {
+		columnSet["value"] = struct{}{}
+	}
+
+	ordered := make([]string, 0, len(columnSet))
+	for key := range columnSet {
+		ordered = append(ordered, key)
+	}
+	return orderedColumns(schema, ordered)
+}
+
+// GenerateCSV creates a CSV file with the same headers and value formatting as
+// the Excel export. Single-record reports are written as field/value pairs.
+func GenerateCSV(endpoint string, data interface{}) ([]byte, error) {
+	if table, ok := data.(*ReportTable); ok {
+		return generateTableCSV(table)
+	}
+	schema := lookupReportSchema(endpoint)
+	normalized, err := normalizeForTabular(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	switch v := normalized.(type) {
+	case []interface{}:
+		ordered := tabularColumns(schema, v)
+		header := make([]string, len(ordered))
+		for i, col := range ordered {
+			header[i] = labelForKey(col)
+		}
+		if err := w.Write(header); err != nil {
+			return nil, err
+		}
+		for _, raw := range v {
+			rowMap, isMap := raw.(map[string]interface{})
+			record := make([]string, len(ordered))
+			for i, col := range ordered {
+				if isMap {
+					record[i] = csvCellValue(formatDisplayValue(col, rowMap[col]))
+				} else if col == "value" {
+					record[i] = csvCellValue(formatDisplayValue(col, raw))
+				}
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		if err := w.Write([]string{"Field", "Value"}); err != nil {
+			return nil, err
+		}
+		for _, key := range orderedColumns(schema, mapKeys(v)) {
+			if err := w.Write([]string{labelForKey(key), csvCellValue(formatDisplayValue(key, v[key]))}); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if err := w.Write([]string{csvCellValue(formatCellValue(normalized))}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func csvCellValue(v interface{}) string {
+	switch t := formatCellValue(v).(type) {
+	case string:
+		return t
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
 func styleTitleRow(file *excelize.File, sheet, cellRange string) error {
 	styleID, err := file.NewStyle(&excelize.Style{
 		Font:      &excelize.Font{Bold: true, Size: 14},
@@ -415,6 +513,9 @@ func formatCellValue(v interface{}) interface{} {
 // GeneratePDF creates a minimal PDF file containing a labeled JSON-style
 // representation of the provided report data.
 func GeneratePDF(endpoint string, data interface{}) ([]byte, error) {
+	if table, ok := data.(*ReportTable); ok {
+		return renderTextPDF(tableText(table)), nil
+	}
 	schema := lookupReportSchema(endpoint)
 	normalized, err := normalizeForTabular(data)
 	if err != nil {
@@ -425,8 +526,11 @@ func GeneratePDF(endpoint string, data interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return renderTextPDF(schema.Title + "\n\n" + string(jsonData)), nil
+}
 
-	text := schema.Title + "\n\n" + string(jsonData)
+// renderTextPDF lays text out on a single page in a fixed-size font.
+func renderTextPDF(text string) []byte {
 	text = strings.ReplaceAll(text, "\\", "\\\\")
 	text = strings.ReplaceAll(text, "(", "\\(")
 	text = strings.ReplaceAll(text, ")", "\\)")
@@ -456,7 +560,7 @@ func GeneratePDF(endpoint string, data interface{}) ([]byte, error) {
 		write(format, a...)
 	}
 
-	write("%PDF-1.4\n")
+	write("%%PDF-1.4\n")
 	writeObj("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
 	writeObj("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
 	writeObj("3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>\nendobj\n")
@@ -471,7 +575,7 @@ func GeneratePDF(endpoint string, data interface{}) ([]byte, error) {
 	}
 	write("trailer << /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%EOF\n", len(offsets)+1, xrefOffset)
 
-	return buf.Bytes(), nil
+	return buf.Bytes()
 }
 
 func splitPdfLines(s string, maxWidth int, maxLines int) []string {
@@ -637,7 +741,7 @@ func looksLikePercentKey(key string) bool {
 }
 
 func looksLikeQuantityKey(key string) bool {
-	return key == "quantity" || strings.HasSuffix(key, "_qty") || key == "transactions"
+	return key == "quantity" || strings.HasSuffix(key, "_qty") || key == "transactions" || key == "count"
 }
 
 func relabelNormalizedData(schema reportExportSchema, data interface{}) interface{} {
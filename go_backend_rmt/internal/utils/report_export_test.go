@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"encoding/csv"
 	"strings"
 	"testing"
 
@@ -76,6 +77,43 @@ func TestGeneratePDFUsesFriendlyLabels(t *testing.T) {
 	}
 }
 
+func TestGenerateCSVMatchesExcelHeadersAndFormatting(t *testing.T) {
+	data := []map[string]interface{}{
+		{
+			"account_code":     "4000",
+			"account_name":     "Sales, Retail",
+			"credit":           150.0,
+			"date":             "2026-03-09T00:00:00Z",
+			"debit":            0.0,
+			"transaction_type": "sale_return",
+		},
+	}
+
+	content, err := GenerateCSV("/reports/general-ledger", data)
+	if err != nil {
+		t.Fatalf("GenerateCSV returned error: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse generated csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and one row, got %d records", len(records))
+	}
+	wantHeader := []string{"Date", "Account Code", "Account Name", "Debit", "Credit", "Source Type"}
+	for i, want := range wantHeader {
+		if records[0][i] != want {
+			t.Fatalf("unexpected header %d: got %q want %q", i, records[0][i], want)
+		}
+	}
+	wantRow := []string{"2026-03-09", "4000", "Sales, Retail", "0.00", "150.00"}
+	for i, want := range wantRow {
+		if records[1][i] != want {
+			t.Fatalf("unexpected value %d: got %q want %q", i, records[1][i], want)
+		}
+	}
+}
+
 func TestReportExportFilenameUsesFriendlySlug(t *testing.T) {
 	got := ReportExportFilename("/reports/trial-balance", "xlsx")
 	if got != "trial-balance.xlsx" {
@@ -87,3 +125,43 @@ func TestReportExportFilenameUsesFriendlySlug(t *testing.T) {
 		t.Fatalf("unexpected filename: %s", got)
 	}
 }
+
+func TestGenerateExcelKeepsReportTableColumnOrder(t *testing.T) {
+	table := &ReportTable{
+		Title: "Margin by cashier",
+		Columns: []ReportTableColumn{
+			{Key: "cashier", Label: "Cashier", Format: "text"},
+			{Key: "margin_pct", Label: "Margin %", Format: "percent"},
+			{Key: "revenue", Label: "Revenue", Format: "money"},
+		},
+		Rows: []map[string]interface{}{{"cashier": "Dana", "margin_pct": 31.5, "revenue": 1200.0}},
+	}
+
+	content, err := GenerateExcel("", table)
+	if err != nil {
+		t.Fatalf("GenerateExcel returned error: %v", err)
+	}
+	file, err := excelize.OpenReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to open generated workbook: %v", err)
+	}
+	defer file.Close()
+
+	sheet := file.GetSheetName(0)
+	for cell, want := range map[string]string{
+		"A1": "Margin by cashier", "A3": "Cashier", "B3": "Margin %", "C3": "Revenue",
+		"A4": "Dana", "B4": "31.50%", "C4": "1200.00",
+	} {
+		got, err := file.GetCellValue(sheet, cell)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", cell, err)
+		}
+		if got != want {
+			t.Fatalf("unexpected value at %s: got %q want %q", cell, got, want)
+		}
+	}
+
+	if got := ReportTableFilename(table, "csv"); got != "margin-by-cashier.csv" {
+		t.Fatalf("unexpected filename: %s", got)
+	}
+}
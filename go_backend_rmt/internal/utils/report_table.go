@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReportTable is report data whose columns are only known at run time, such
+// as user-defined reports. The export functions keep its title, column order
+// and headers instead of looking them up by endpoint.
+type ReportTable struct {
+	Title   string
+	Columns []ReportTableColumn
+	Rows    []map[string]interface{}
+}
+
+// ReportTableColumn describes one column. Format is one of text, date, money,
+// quantity, percent or count; empty falls back to the key-based guesses used
+// for fixed reports.
+type ReportTableColumn struct {
+	Key    string
+	Label  string
+	Format string
+}
+
+func generateTableExcel(table *ReportTable) ([]byte, error) {
+	file := excelize.NewFile()
+	title := strings.TrimSpace(table.Title)
+	if title == "" {
+		title = "Report"
+	}
+	sheet := sanitizeSheetName(title)
+	_ = file.SetSheetName(file.GetSheetName(0), sheet)
+
+	file.SetCellValue(sheet, "A1", title)
+	cols := len(table.Columns)
+	if err := styleTitleRow(file, sheet, fmt.Sprintf("A1:%s1", mustColumnName(max(1, cols)))); err != nil {
+		return nil, err
+	}
+	if cols == 0 || len(table.Rows) == 0 {
+		file.SetCellValue(sheet, "A3", "No data")
+	}
+	if cols > 0 {
+		for i, col := range table.Columns {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 3)
+			file.SetCellValue(sheet, cell, col.Label)
+		}
+		if err := styleHeaderRow(file, sheet, cols, 3); err != nil {
+			return nil, err
+		}
+		_ = file.SetPanes(sheet, &excelize.Panes{Freeze: true, Split: true, XSplit: 0, YSplit: 3, TopLeftCell: "A4"})
+		for r, row := range table.Rows {
+			for c, col := range table.Columns {
+				cell, _ := excelize.CoordinatesToCellName(c+1, r+4)
+				file.SetCellValue(sheet, cell, formatTableValue(col, row[col.Key]))
+			}
+		}
+		if len(table.Rows) > 0 {
+			_ = file.AutoFilter(sheet, fmt.Sprintf("A3:%s%d", mustColumnName(cols), len(table.Rows)+3), []excelize.AutoFilterOptions{})
+		}
+		for i := 1; i <= cols; i++ {
+			name := mustColumnName(i)
+			_ = file.SetColWidth(sheet, name, name, 18)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func generateTableCSV(table *ReportTable) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		header[i] = col.Label
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(table.Columns))
+		for i, col := range table.Columns {
+			record[i] = csvCellValue(formatTableValue(col, row[col.Key]))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func tableText(table *ReportTable) string {
+	var b strings.Builder
+	b.WriteString(table.Title)
+	b.WriteString("\n\n")
+	labels := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		labels[i] = col.Label
+	}
+	b.WriteString(strings.Join(labels, " | "))
+	b.WriteString("\n")
+	for _, row := range table.Rows {
+		values := make([]string, len(table.Columns))
+		for i, col := range table.Columns {
+			values[i] = csvCellValue(formatTableValue(col, row[col.Key]))
+		}
+		b.WriteString(strings.Join(values, " | "))
+		b.WriteString("\n")
+	}
+	if len(table.Rows) == 0 {
+		b.WriteString("No data\n")
+	}
+	return b.String()
+}
+
+func formatTableValue(col ReportTableColumn, value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+	f, isNumber := value.(float64)
+	switch col.Format {
+	case "money":
+		if isNumber {
+			return fmt.Sprintf("%.2f", f)
+		}
+	case "quantity":
+		if isNumber {
+			if f == float64(int64(f)) {
+				return fmt.Sprintf("%.0f", f)
+			}
+			return fmt.Sprintf("%.3f", f)
+		}
+	case "percent":
+		if isNumber {
+			return fmt.Sprintf("%.2f%%", f)
+		}
+	case "count":
+		if isNumber {
+			return fmt.Sprintf("%.0f", f)
+		}
+	case "text", "date":
+		return formatCellValue(value)
+	}
+	return formatDisplayValue(col.Key, value)
+}
+
+// ReportTableFilename names an export of table after its title.
+func ReportTableFilename(table *ReportTable, ext string) string {
+	base := slugifyReportTitle(table.Title)
+	if base == "" {
+		base = "report"
+	}
+	return base + "." + strings.TrimPrefix(ext, ".")
+}
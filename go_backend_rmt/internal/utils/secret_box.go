@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedSecretPrefix marks values sealed by SealSecret and versions the format.
+const sealedSecretPrefix = "v1:"
+
+// SealSecret encrypts plaintext with AES-256-GCM under a key derived from
+// secret, for values that must be stored but never read back in clear.
+func SealSecret(secret, plaintext string) (string, error) {
+	gcm, err := secretBoxCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret reverses SealSecret. It fails when the value was sealed under a
+// different secret or has been tampered with.
+func OpenSecret(secret, sealed string) (string, error) {
+	if !IsSealedSecret(sealed) {
+		return "", errors.New("value is not sealed")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedSecretPrefix))
+	if err != nil {
+		return "", errors.New("invalid sealed value")
+	}
+	gcm, err := secretBoxCipher(secret)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("invalid sealed value")
+	}
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to open sealed value")
+	}
+	return string(plaintext), nil
+}
+
+// IsSealedSecret reports whether value looks like SealSecret output.
+func IsSealedSecret(value string) bool {
+	return strings.HasPrefix(value, sealedSecretPrefix)
+}
+
+func secretBoxCipher(secret string) (cipher.AEAD, error) {
+	if strings.TrimSpace(secret) == "" {
+		return nil, errors.New("encryption secret not configured")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,32 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS replenishment_rules (
+  rule_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+  preferred_supplier_id INTEGER REFERENCES suppliers(supplier_id),
+  reorder_level NUMERIC(12,3),
+  max_stock NUMERIC(12,3),
+  lead_time_days INTEGER,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (location_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_replenishment_rules_company_location
+  ON replenishment_rules(company_id, location_id);
+
+ALTER TABLE suppliers
+  ADD COLUMN IF NOT EXISTS lead_time_days INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+
+ALTER TABLE suppliers
+  DROP COLUMN IF EXISTS lead_time_days;
+
+DROP INDEX IF EXISTS idx_replenishment_rules_company_location;
+DROP TABLE IF EXISTS replenishment_rules;
@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Invoice price variances accepted through three-way matching are posted as
+-- purchase cost adjustments of their own type.
+ALTER TABLE purchase_cost_adjustments
+  DROP CONSTRAINT IF EXISTS purchase_cost_adjustments_adjustment_type_check;
+
+ALTER TABLE purchase_cost_adjustments
+  ADD CONSTRAINT purchase_cost_adjustments_adjustment_type_check
+  CHECK (adjustment_type IN ('GRN_ADDON', 'SUPPLIER_DEBIT_NOTE', 'INVOICE_PRICE_VARIANCE'));
+
+CREATE TABLE IF NOT EXISTS supplier_invoice_matches (
+  match_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  purchase_id INTEGER NOT NULL UNIQUE REFERENCES purchases(purchase_id) ON DELETE CASCADE,
+  supplier_id INTEGER NOT NULL REFERENCES suppliers(supplier_id),
+  supplier_invoice_number VARCHAR(100) NOT NULL,
+  invoice_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  invoice_total NUMERIC(12,2) NOT NULL DEFAULT 0,
+  status VARCHAR(20) NOT NULL CHECK (status IN ('MATCHED', 'VARIANCE', 'OVERRIDE_PENDING', 'OVERRIDDEN')),
+  quantity_tolerance_percent NUMERIC(6,2) NOT NULL DEFAULT 0,
+  price_tolerance_percent NUMERIC(6,2) NOT NULL DEFAULT 0,
+  price_variance_total NUMERIC(12,2) NOT NULL DEFAULT 0,
+  price_variance_adjustment_id INTEGER REFERENCES purchase_cost_adjustments(adjustment_id),
+  override_approval_id INTEGER REFERENCES workflow_requests(approval_id),
+  override_reason TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_invoice_matches_company_status
+  ON supplier_invoice_matches(company_id, status);
+
+CREATE TABLE IF NOT EXISTS supplier_invoice_match_lines (
+  match_line_id SERIAL PRIMARY KEY,
+  match_id INTEGER NOT NULL REFERENCES supplier_invoice_matches(match_id) ON DELETE CASCADE,
+  purchase_detail_id INTEGER NOT NULL REFERENCES purchase_details(purchase_detail_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  ordered_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  received_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  invoiced_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  ordered_unit_price NUMERIC(12,4) NOT NULL DEFAULT 0,
+  invoiced_unit_price NUMERIC(12,4) NOT NULL DEFAULT 0,
+  quantity_variance NUMERIC(12,3) NOT NULL DEFAULT 0,
+  quantity_variance_percent NUMERIC(8,2) NOT NULL DEFAULT 0,
+  price_variance_percent NUMERIC(8,2) NOT NULL DEFAULT 0,
+  price_variance_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  status VARCHAR(30) NOT NULL CHECK (status IN ('MATCHED', 'QUANTITY_VARIANCE', 'PRICE_VARIANCE', 'QUANTITY_PRICE_VARIANCE')),
+  UNIQUE (match_id, purchase_detail_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_invoice_match_lines_match
+  ON supplier_invoice_match_lines(match_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_supplier_invoice_match_lines_match;
+DROP TABLE IF EXISTS supplier_invoice_match_lines;
+DROP INDEX IF EXISTS idx_supplier_invoice_matches_company_status;
+DROP TABLE IF EXISTS supplier_invoice_matches;
+-- The adjustment type constraint is left widened: reverting it could fail if
+-- INVOICE_PRICE_VARIANCE adjustments already exist.
+
+-- +goose StatementEnd
@@ -0,0 +1,74 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE suppliers
+  ADD COLUMN IF NOT EXISTS early_payment_discount_percent NUMERIC(5,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS early_payment_discount_days INTEGER NOT NULL DEFAULT 0;
+
+-- Early payment discounts taken in a payment run reduce the purchase cost
+-- through a purchase cost adjustment of their own type.
+ALTER TABLE purchase_cost_adjustments
+  DROP CONSTRAINT IF EXISTS purchase_cost_adjustments_adjustment_type_check;
+
+ALTER TABLE purchase_cost_adjustments
+  ADD CONSTRAINT purchase_cost_adjustments_adjustment_type_check
+  CHECK (adjustment_type IN ('GRN_ADDON', 'SUPPLIER_DEBIT_NOTE', 'INVOICE_PRICE_VARIANCE', 'EARLY_PAYMENT_DISCOUNT'));
+
+CREATE TABLE IF NOT EXISTS supplier_payment_runs (
+  run_id SERIAL PRIMARY KEY,
+  run_number VARCHAR(100) NOT NULL UNIQUE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  payment_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  payment_method_id INTEGER REFERENCES payment_methods(method_id),
+  reference_number VARCHAR(100),
+  notes TEXT,
+  payment_count INTEGER NOT NULL DEFAULT 0,
+  total_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  total_discount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplier_payment_runs_company_date
+  ON supplier_payment_runs(company_id, payment_date DESC);
+
+ALTER TABLE payments
+  ADD COLUMN IF NOT EXISTS payment_run_id INTEGER REFERENCES supplier_payment_runs(run_id),
+  ADD COLUMN IF NOT EXISTS discount_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS discount_adjustment_id INTEGER REFERENCES purchase_cost_adjustments(adjustment_id);
+
+CREATE INDEX IF NOT EXISTS idx_payments_payment_run
+  ON payments(payment_run_id);
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'supplier_payment_run', 'SPR-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id
+    AND ns.location_id IS NULL
+    AND ns.name = 'supplier_payment_run'
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM numbering_sequences WHERE name = 'supplier_payment_run';
+
+DROP INDEX IF EXISTS idx_payments_payment_run;
+ALTER TABLE payments
+  DROP COLUMN IF EXISTS discount_adjustment_id,
+  DROP COLUMN IF EXISTS discount_amount,
+  DROP COLUMN IF EXISTS payment_run_id;
+
+DROP INDEX IF EXISTS idx_supplier_payment_runs_company_date;
+DROP TABLE IF EXISTS supplier_payment_runs;
+
+ALTER TABLE suppliers
+  DROP COLUMN IF EXISTS early_payment_discount_days,
+  DROP COLUMN IF EXISTS early_payment_discount_percent;
+
+-- +goose StatementEnd
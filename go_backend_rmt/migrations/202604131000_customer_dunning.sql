@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Dunning status is recomputed by dunning runs and after collections; credit
+-- sales are refused while dunning_credit_blocked is set.
+ALTER TABLE customers
+  ADD COLUMN IF NOT EXISTS dunning_level INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS dunning_credit_blocked BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS dunning_updated_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS dunning_reminders (
+  reminder_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  customer_id INTEGER NOT NULL REFERENCES customers(customer_id),
+  sale_id INTEGER NOT NULL REFERENCES sales(sale_id),
+  level INTEGER NOT NULL CHECK (level > 0),
+  level_name VARCHAR(100) NOT NULL,
+  days_overdue INTEGER NOT NULL,
+  amount_due NUMERIC(12,2) NOT NULL,
+  late_fee_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  late_fee_sale_id INTEGER REFERENCES sales(sale_id),
+  channel VARCHAR(20) NOT NULL DEFAULT 'NONE' CHECK (channel IN ('NONE', 'EMAIL')),
+  status VARCHAR(20) NOT NULL DEFAULT 'RECORDED' CHECK (status IN ('RECORDED', 'SENT', 'FAILED')),
+  error_message TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (sale_id, level)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dunning_reminders_company_customer
+  ON dunning_reminders(company_id, customer_id, created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_dunning_reminders_company_customer;
+DROP TABLE IF EXISTS dunning_reminders;
+
+ALTER TABLE customers
+  DROP COLUMN IF EXISTS dunning_updated_at,
+  DROP COLUMN IF EXISTS dunning_credit_blocked,
+  DROP COLUMN IF EXISTS dunning_level;
+
+-- +goose StatementEnd
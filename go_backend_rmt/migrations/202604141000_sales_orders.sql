@@ -0,0 +1,139 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Sales orders sit between quotes and invoices: confirmed lines reserve stock
+-- through stock_variants.reserved_quantity, delivery notes issue it and
+-- invoices are raised per delivery or consolidated.
+CREATE TABLE IF NOT EXISTS sales_orders (
+  sales_order_id SERIAL PRIMARY KEY,
+  order_number VARCHAR(100) NOT NULL,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  customer_id INTEGER NOT NULL REFERENCES customers(customer_id),
+  quote_id INTEGER REFERENCES quotes(quote_id),
+  order_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  expected_delivery_date DATE,
+  status VARCHAR(30) NOT NULL DEFAULT 'OPEN'
+    CHECK (status IN ('OPEN', 'PARTIALLY_DELIVERED', 'DELIVERED', 'CLOSED', 'CANCELLED')),
+  invoicing_mode VARCHAR(20) NOT NULL DEFAULT 'PER_DELIVERY'
+    CHECK (invoicing_mode IN ('PER_DELIVERY', 'CONSOLIDATED')),
+  transaction_type VARCHAR(20) NOT NULL DEFAULT 'B2B',
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, order_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_orders_company_status
+  ON sales_orders(company_id, status, order_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sales_orders_quote
+  ON sales_orders(quote_id) WHERE quote_id IS NOT NULL AND status <> 'CANCELLED';
+
+-- Quantities are in selling units; selling_to_stock converts them for the
+-- stock_variants reservation. Backordered quantity is the part that is neither
+-- reserved nor delivered yet.
+CREATE TABLE IF NOT EXISTS sales_order_lines (
+  sales_order_line_id SERIAL PRIMARY KEY,
+  sales_order_id INTEGER NOT NULL REFERENCES sales_orders(sales_order_id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER NOT NULL REFERENCES product_barcodes(barcode_id),
+  product_name VARCHAR(255),
+  quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+  selling_to_stock NUMERIC(12,6) NOT NULL DEFAULT 1,
+  unit_price NUMERIC(12,2) NOT NULL DEFAULT 0,
+  discount_percentage NUMERIC(5,2) NOT NULL DEFAULT 0,
+  tax_id INTEGER REFERENCES taxes(tax_id),
+  reserved_quantity NUMERIC(12,3) NOT NULL DEFAULT 0 CHECK (reserved_quantity >= 0),
+  delivered_quantity NUMERIC(12,3) NOT NULL DEFAULT 0 CHECK (delivered_quantity >= 0),
+  invoiced_quantity NUMERIC(12,3) NOT NULL DEFAULT 0 CHECK (invoiced_quantity >= 0),
+  cancelled_quantity NUMERIC(12,3) NOT NULL DEFAULT 0 CHECK (cancelled_quantity >= 0),
+  notes TEXT,
+  CHECK (reserved_quantity + delivered_quantity + cancelled_quantity <= quantity + 0.0005)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_lines_order
+  ON sales_order_lines(sales_order_id);
+CREATE INDEX IF NOT EXISTS idx_sales_order_lines_barcode
+  ON sales_order_lines(barcode_id);
+
+CREATE TABLE IF NOT EXISTS sales_order_deliveries (
+  delivery_id SERIAL PRIMARY KEY,
+  delivery_number VARCHAR(100) NOT NULL,
+  sales_order_id INTEGER NOT NULL REFERENCES sales_orders(sales_order_id),
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  delivery_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  invoice_sale_id INTEGER REFERENCES sales(sale_id),
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, delivery_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_deliveries_order
+  ON sales_order_deliveries(sales_order_id);
+
+CREATE TABLE IF NOT EXISTS sales_order_delivery_lines (
+  delivery_line_id SERIAL PRIMARY KEY,
+  delivery_id INTEGER NOT NULL REFERENCES sales_order_deliveries(delivery_id) ON DELETE CASCADE,
+  sales_order_line_id INTEGER NOT NULL REFERENCES sales_order_lines(sales_order_line_id),
+  quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+  serial_numbers TEXT[],
+  unit_cost NUMERIC(12,4) NOT NULL DEFAULT 0,
+  total_cost NUMERIC(14,4) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_delivery_lines_delivery
+  ON sales_order_delivery_lines(delivery_id);
+
+ALTER TABLE sales
+  DROP CONSTRAINT IF EXISTS sales_source_channel_check;
+
+ALTER TABLE sales
+  ADD CONSTRAINT sales_source_channel_check
+  CHECK (
+    source_channel IS NULL
+    OR source_channel IN ('POS', 'INVOICE', 'QUOTE', 'POS_REFUND', 'SALES_ORDER')
+  );
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'sales_order', 'SO-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1 FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id AND ns.location_id IS NULL AND ns.name = 'sales_order'
+);
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'delivery_note', 'DN-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1 FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id AND ns.location_id IS NULL AND ns.name = 'delivery_note'
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM numbering_sequences WHERE name IN ('sales_order', 'delivery_note');
+
+ALTER TABLE sales
+  DROP CONSTRAINT IF EXISTS sales_source_channel_check;
+
+ALTER TABLE sales
+  ADD CONSTRAINT sales_source_channel_check
+  CHECK (
+    source_channel IS NULL
+    OR source_channel IN ('POS', 'INVOICE', 'QUOTE', 'POS_REFUND')
+  );
+
+DROP TABLE IF EXISTS sales_order_delivery_lines;
+DROP TABLE IF EXISTS sales_order_deliveries;
+DROP TABLE IF EXISTS sales_order_lines;
+DROP TABLE IF EXISTS sales_orders;
+
+-- +goose StatementEnd
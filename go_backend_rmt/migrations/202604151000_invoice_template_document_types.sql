@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Templates are rendered server-side for every printable document, not only
+-- invoices, receipts and quotes.
+ALTER TABLE invoice_templates
+  DROP CONSTRAINT IF EXISTS invoice_templates_template_type_check;
+
+ALTER TABLE invoice_templates
+  ADD CONSTRAINT invoice_templates_template_type_check
+  CHECK (template_type IN ('INVOICE', 'RECEIPT', 'QUOTE', 'SALE_RETURN', 'PURCHASE', 'PAYSLIP', 'WARRANTY'));
+
+CREATE INDEX IF NOT EXISTS idx_invoice_templates_company_type
+  ON invoice_templates(company_id, template_type);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_invoice_templates_company_type;
+
+DELETE FROM invoice_templates
+WHERE template_type IN ('SALE_RETURN', 'PURCHASE', 'PAYSLIP', 'WARRANTY');
+
+ALTER TABLE invoice_templates
+  DROP CONSTRAINT IF EXISTS invoice_templates_template_type_check;
+
+ALTER TABLE invoice_templates
+  ADD CONSTRAINT invoice_templates_template_type_check
+  CHECK (template_type IN ('INVOICE', 'RECEIPT', 'QUOTE'));
+
+-- +goose StatementEnd
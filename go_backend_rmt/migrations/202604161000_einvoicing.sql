@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- One signing key per company is active at a time; retired keys stay so that
+-- older invoices can still be verified.
+CREATE TABLE IF NOT EXISTS einvoice_signing_keys (
+  key_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  algorithm VARCHAR(40) NOT NULL DEFAULT 'ECDSA_P256_SHA256',
+  private_key_pem TEXT NOT NULL,
+  public_key_pem TEXT NOT NULL,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  retired_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_einvoice_signing_keys_active
+  ON einvoice_signing_keys(company_id) WHERE is_active;
+
+-- The chain head is locked while an invoice is issued so that counters and
+-- previous-hash links stay gapless per location.
+CREATE TABLE IF NOT EXISTS einvoice_chain_heads (
+  location_id INTEGER PRIMARY KEY REFERENCES locations(location_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  last_counter BIGINT NOT NULL DEFAULT 0,
+  last_hash TEXT,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS einvoices (
+  einvoice_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('sale', 'sale_return')),
+  source_id INTEGER NOT NULL,
+  sale_id INTEGER NOT NULL REFERENCES sales(sale_id),
+  document_type VARCHAR(20) NOT NULL CHECK (document_type IN ('INVOICE', 'REFUND_INVOICE', 'CREDIT_NOTE')),
+  invoice_number VARCHAR(100) NOT NULL,
+  uuid UUID NOT NULL,
+  counter BIGINT NOT NULL,
+  previous_hash TEXT NOT NULL,
+  invoice_hash TEXT NOT NULL,
+  signature TEXT NOT NULL,
+  key_id INTEGER NOT NULL REFERENCES einvoice_signing_keys(key_id),
+  qr_payload TEXT NOT NULL,
+  xml TEXT NOT NULL,
+  total_amount NUMERIC(12,2) NOT NULL,
+  tax_amount NUMERIC(12,2) NOT NULL,
+  issued_at TIMESTAMP NOT NULL,
+  submission_status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (submission_status IN ('PENDING', 'ACCEPTED', 'REJECTED', 'FAILED')),
+  submission_reference VARCHAR(255),
+  submission_error TEXT,
+  submitted_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (source_type, source_id),
+  UNIQUE (location_id, counter)
+);
+
+CREATE INDEX IF NOT EXISTS idx_einvoices_company_issued
+  ON einvoices(company_id, issued_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_einvoices_sale
+  ON einvoices(sale_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_einvoices_sale;
+DROP INDEX IF EXISTS idx_einvoices_company_issued;
+DROP TABLE IF EXISTS einvoices;
+DROP TABLE IF EXISTS einvoice_chain_heads;
+DROP INDEX IF EXISTS uq_einvoice_signing_keys_active;
+DROP TABLE IF EXISTS einvoice_signing_keys;
+
+-- +goose StatementEnd
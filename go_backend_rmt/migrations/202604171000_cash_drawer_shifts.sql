@@ -0,0 +1,140 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Registers are bound to a terminal (device) instead of a location/day, so a
+-- location can run several tills at once. Legacy rows keep an empty terminal.
+ALTER TABLE cash_register
+  DROP CONSTRAINT IF EXISTS cash_register_location_id_date_key;
+
+ALTER TABLE cash_register
+  ADD COLUMN IF NOT EXISTS terminal_id VARCHAR(255) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS terminal_name VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_cash_register_open_terminal
+  ON cash_register(location_id, terminal_id)
+  WHERE status = 'OPEN';
+
+-- Per-cashier shifts within a register session. Only one shift may be open on
+-- a register at a time; a hand-over ends one shift and starts the next.
+CREATE TABLE IF NOT EXISTS cash_register_shifts (
+  shift_id SERIAL PRIMARY KEY,
+  register_id INTEGER NOT NULL REFERENCES cash_register(register_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  cashier_id INTEGER NOT NULL REFERENCES users(user_id),
+  status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'CLOSED')),
+  opening_cash NUMERIC(12,2) NOT NULL DEFAULT 0,
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ended_at TIMESTAMP,
+  ended_by INTEGER REFERENCES users(user_id),
+  session_id UUID,
+  notes TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_cash_register_shifts_open
+  ON cash_register_shifts(register_id)
+  WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_cash_register_shifts_cashier
+  ON cash_register_shifts(location_id, cashier_id, started_at);
+
+-- Backfill an open shift for registers that are already open.
+INSERT INTO cash_register_shifts (register_id, location_id, cashier_id, status, opening_cash, started_at)
+SELECT cr.register_id, cr.location_id, cr.opened_by, 'OPEN', COALESCE(cr.opening_balance, 0),
+       COALESCE(cr.opened_at, cr.created_at, CURRENT_TIMESTAMP)
+FROM cash_register cr
+WHERE cr.status = 'OPEN' AND cr.opened_by IS NOT NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM cash_register_shifts sh
+    WHERE sh.register_id = cr.register_id AND sh.status = 'OPEN'
+  );
+
+-- Declared vs expected per-tender counts captured at tallies and blind closes.
+CREATE TABLE IF NOT EXISTS cash_register_tender_counts (
+  count_id SERIAL PRIMARY KEY,
+  register_id INTEGER NOT NULL REFERENCES cash_register(register_id) ON DELETE CASCADE,
+  shift_id INTEGER REFERENCES cash_register_shifts(shift_id) ON DELETE SET NULL,
+  count_type VARCHAR(20) NOT NULL CHECK (count_type IN ('TALLY', 'SHIFT_CLOSE', 'REGISTER_CLOSE')),
+  tender VARCHAR(20) NOT NULL CHECK (tender IN ('CASH', 'CARD', 'WALLET', 'STORE_CREDIT', 'OTHER')),
+  declared NUMERIC(12,2) NOT NULL DEFAULT 0,
+  expected NUMERIC(12,2) NOT NULL DEFAULT 0,
+  variance NUMERIC(12,2) NOT NULL DEFAULT 0,
+  denominations JSONB,
+  counted_by INTEGER NOT NULL REFERENCES users(user_id),
+  counted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cash_register_tender_counts_register
+  ON cash_register_tender_counts(register_id, counted_at);
+
+-- Z report numbering is per location and only ever moves forward.
+CREATE TABLE IF NOT EXISTS cash_register_z_counters (
+  location_id INTEGER PRIMARY KEY REFERENCES locations(location_id) ON DELETE CASCADE,
+  last_number INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE OR REPLACE FUNCTION prevent_cash_register_z_counter_reset()
+RETURNS trigger AS $$
+BEGIN
+  IF NEW.last_number <= OLD.last_number THEN
+    RAISE EXCEPTION 'cash register Z counter cannot be reset';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_cash_register_z_counters_forward ON cash_register_z_counters;
+CREATE TRIGGER trg_cash_register_z_counters_forward
+BEFORE UPDATE ON cash_register_z_counters
+FOR EACH ROW EXECUTE FUNCTION prevent_cash_register_z_counter_reset();
+
+DROP TRIGGER IF EXISTS trg_cash_register_z_counters_no_delete ON cash_register_z_counters;
+CREATE TRIGGER trg_cash_register_z_counters_no_delete
+BEFORE DELETE ON cash_register_z_counters
+FOR EACH ROW EXECUTE FUNCTION prevent_cash_register_events_mutation();
+
+-- Z reports are frozen snapshots taken when a register is closed.
+CREATE TABLE IF NOT EXISTS cash_register_z_reports (
+  z_report_id SERIAL PRIMARY KEY,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  register_id INTEGER NOT NULL UNIQUE REFERENCES cash_register(register_id),
+  z_number INTEGER NOT NULL,
+  period_start TIMESTAMP NOT NULL,
+  period_end TIMESTAMP NOT NULL,
+  report JSONB NOT NULL,
+  generated_by INTEGER NOT NULL REFERENCES users(user_id),
+  generated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (location_id, z_number)
+);
+
+DROP TRIGGER IF EXISTS trg_cash_register_z_reports_no_update ON cash_register_z_reports;
+CREATE TRIGGER trg_cash_register_z_reports_no_update
+BEFORE UPDATE ON cash_register_z_reports
+FOR EACH ROW EXECUTE FUNCTION prevent_cash_register_events_mutation();
+
+DROP TRIGGER IF EXISTS trg_cash_register_z_reports_no_delete ON cash_register_z_reports;
+CREATE TRIGGER trg_cash_register_z_reports_no_delete
+BEFORE DELETE ON cash_register_z_reports
+FOR EACH ROW EXECUTE FUNCTION prevent_cash_register_events_mutation();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_cash_register_z_reports_no_update ON cash_register_z_reports;
+DROP TRIGGER IF EXISTS trg_cash_register_z_reports_no_delete ON cash_register_z_reports;
+DROP TABLE IF EXISTS cash_register_z_reports;
+
+DROP TRIGGER IF EXISTS trg_cash_register_z_counters_forward ON cash_register_z_counters;
+DROP TRIGGER IF EXISTS trg_cash_register_z_counters_no_delete ON cash_register_z_counters;
+DROP FUNCTION IF EXISTS prevent_cash_register_z_counter_reset();
+DROP TABLE IF EXISTS cash_register_z_counters;
+
+DROP TABLE IF EXISTS cash_register_tender_counts;
+DROP TABLE IF EXISTS cash_register_shifts;
+
+-- UNIQUE(location_id, date) is not restored: multi-terminal history would violate it.
+DROP INDEX IF EXISTS uq_cash_register_open_terminal;
+ALTER TABLE cash_register
+  DROP COLUMN IF EXISTS terminal_id,
+  DROP COLUMN IF EXISTS terminal_name;
+-- +goose StatementEnd
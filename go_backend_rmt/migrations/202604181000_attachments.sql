@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Stored blobs, deduplicated per company by content hash. The same bytes
+-- attached to several records share one object.
+CREATE TABLE IF NOT EXISTS file_objects (
+  object_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  storage_key VARCHAR(512) NOT NULL,
+  sha256 CHAR(64) NOT NULL,
+  size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+  content_type VARCHAR(100) NOT NULL,
+  created_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, sha256)
+);
+
+-- Files attached to any supported record.
+CREATE TABLE IF NOT EXISTS attachments (
+  attachment_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  entity_type VARCHAR(30) NOT NULL CHECK (entity_type IN (
+    'sale', 'purchase', 'expense', 'voucher', 'employee', 'asset', 'support_issue'
+  )),
+  entity_id INTEGER NOT NULL,
+  object_id INTEGER NOT NULL REFERENCES file_objects(object_id),
+  file_name VARCHAR(255) NOT NULL,
+  description TEXT,
+  uploaded_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+  deleted_by INTEGER REFERENCES users(user_id),
+  deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_entity
+  ON attachments(company_id, entity_type, entity_id)
+  WHERE is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_attachments_object
+  ON attachments(object_id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS file_objects;
+-- +goose StatementEnd
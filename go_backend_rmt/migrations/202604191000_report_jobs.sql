@@ -0,0 +1,127 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Heavy reports run in the background. Results live in object storage and are
+-- reused for identical parameters until the underlying data changes.
+CREATE TABLE IF NOT EXISTS report_jobs (
+  job_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  requested_by INTEGER NOT NULL REFERENCES users(user_id),
+  report_type VARCHAR(50) NOT NULL,
+  params JSONB NOT NULL DEFAULT '{}'::jsonb,
+  params_hash CHAR(64) NOT NULL,
+  data_version VARCHAR(100) NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'QUEUED'
+    CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED', 'EXPIRED')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  worker_id VARCHAR(100),
+  row_count INTEGER,
+  result_json_key VARCHAR(512),
+  result_excel_key VARCHAR(512),
+  result_pdf_key VARCHAR(512),
+  error TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  started_at TIMESTAMP,
+  finished_at TIMESTAMP,
+  expires_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_jobs_queue
+  ON report_jobs(created_at)
+  WHERE status = 'QUEUED';
+CREATE INDEX IF NOT EXISTS idx_report_jobs_cache
+  ON report_jobs(company_id, report_type, params_hash, data_version)
+  WHERE status IN ('QUEUED', 'RUNNING', 'SUCCEEDED');
+CREATE INDEX IF NOT EXISTS idx_report_jobs_company
+  ON report_jobs(company_id, created_at DESC);
+
+-- Append-only change markers written once per statement that touches ledger or
+-- stock data. A report's data version is the count and latest id for its
+-- domain, so any committed change (even one with an older id) moves it.
+CREATE TABLE IF NOT EXISTS report_data_changes (
+  change_id BIGSERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL,
+  domain VARCHAR(20) NOT NULL CHECK (domain IN ('ledger', 'stock')),
+  changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_data_changes_company_domain
+  ON report_data_changes(company_id, domain, change_id);
+
+-- TG_ARGV[0] is the domain, TG_ARGV[1] says whether rows carry company_id or
+-- location_id. Every trigger exposes its transition table as changed_rows.
+CREATE OR REPLACE FUNCTION record_report_data_change()
+RETURNS trigger AS $$
+BEGIN
+  IF TG_ARGV[1] = 'location' THEN
+    INSERT INTO report_data_changes (company_id, domain)
+    SELECT DISTINCT l.company_id, TG_ARGV[0]
+    FROM changed_rows c
+    JOIN locations l ON l.location_id = c.location_id;
+  ELSE
+    INSERT INTO report_data_changes (company_id, domain)
+    SELECT DISTINCT c.company_id, TG_ARGV[0]
+    FROM changed_rows c
+    WHERE c.company_id IS NOT NULL;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+  t RECORD;
+BEGIN
+  FOR t IN
+    SELECT * FROM (VALUES
+      ('ledger_entries', 'ledger', 'company'),
+      ('chart_of_accounts', 'ledger', 'company'),
+      ('products', 'stock', 'company'),
+      ('stock_variants', 'stock', 'location'),
+      ('stock_lots', 'stock', 'location'),
+      ('stock_adjustments', 'stock', 'location'),
+      ('sales', 'stock', 'location'),
+      ('sale_returns', 'stock', 'location'),
+      ('purchases', 'stock', 'location'),
+      ('purchase_returns', 'stock', 'location')
+    ) AS v(tbl, domain, scope)
+  LOOP
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_ins ON %I', t.tbl, t.tbl);
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_upd ON %I', t.tbl, t.tbl);
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_del ON %I', t.tbl, t.tbl);
+    EXECUTE format('CREATE TRIGGER trg_%s_report_change_ins AFTER INSERT ON %I
+      REFERENCING NEW TABLE AS changed_rows FOR EACH STATEMENT
+      EXECUTE FUNCTION record_report_data_change(%L, %L)', t.tbl, t.tbl, t.domain, t.scope);
+    EXECUTE format('CREATE TRIGGER trg_%s_report_change_upd AFTER UPDATE ON %I
+      REFERENCING NEW TABLE AS changed_rows FOR EACH STATEMENT
+      EXECUTE FUNCTION record_report_data_change(%L, %L)', t.tbl, t.tbl, t.domain, t.scope);
+    EXECUTE format('CREATE TRIGGER trg_%s_report_change_del AFTER DELETE ON %I
+      REFERENCING OLD TABLE AS changed_rows FOR EACH STATEMENT
+      EXECUTE FUNCTION record_report_data_change(%L, %L)', t.tbl, t.tbl, t.domain, t.scope);
+  END LOOP;
+END;
+$$;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+  tbl TEXT;
+BEGIN
+  FOREACH tbl IN ARRAY ARRAY[
+    'ledger_entries', 'chart_of_accounts', 'products', 'stock_variants', 'stock_lots',
+    'stock_adjustments', 'sales', 'sale_returns', 'purchases', 'purchase_returns'
+  ]
+  LOOP
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_ins ON %I', tbl, tbl);
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_upd ON %I', tbl, tbl);
+    EXECUTE format('DROP TRIGGER IF EXISTS trg_%s_report_change_del ON %I', tbl, tbl);
+  END LOOP;
+END;
+$$;
+DROP FUNCTION IF EXISTS record_report_data_change();
+DROP TABLE IF EXISTS report_data_changes;
+DROP TABLE IF EXISTS report_jobs;
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Users subscribe to a report endpoint with saved parameters and receive the
+-- export by email on a cron schedule evaluated in their chosen timezone.
+CREATE TABLE IF NOT EXISTS report_subscriptions (
+  subscription_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  name VARCHAR(150) NOT NULL,
+  endpoint VARCHAR(100) NOT NULL,
+  params JSONB NOT NULL DEFAULT '{}'::jsonb,
+  schedule VARCHAR(100) NOT NULL,
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+  format VARCHAR(10) NOT NULL CHECK (format IN ('xlsx', 'pdf', 'csv')),
+  recipients JSONB NOT NULL DEFAULT '[]'::jsonb,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at TIMESTAMPTZ,
+  last_run_at TIMESTAMPTZ,
+  last_status VARCHAR(20),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_subscriptions_due
+  ON report_subscriptions(next_run_at)
+  WHERE is_active = TRUE;
+CREATE INDEX IF NOT EXISTS idx_report_subscriptions_company_user
+  ON report_subscriptions(company_id, user_id);
+
+CREATE TABLE IF NOT EXISTS report_subscription_deliveries (
+  delivery_id SERIAL PRIMARY KEY,
+  subscription_id INTEGER NOT NULL REFERENCES report_subscriptions(subscription_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  scheduled_for TIMESTAMPTZ NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'RUNNING'
+    CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED', 'SKIPPED')),
+  recipients JSONB NOT NULL DEFAULT '[]'::jsonb,
+  failed_recipients JSONB NOT NULL DEFAULT '[]'::jsonb,
+  row_count INTEGER,
+  file_name VARCHAR(255),
+  file_size INTEGER,
+  error TEXT,
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_subscription_deliveries_subscription
+  ON report_subscription_deliveries(subscription_id, delivery_id DESC);
+CREATE INDEX IF NOT EXISTS idx_report_subscription_deliveries_running
+  ON report_subscription_deliveries(started_at)
+  WHERE status = 'RUNNING';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS report_subscription_deliveries;
+DROP TABLE IF EXISTS report_subscriptions;
+-- +goose StatementEnd
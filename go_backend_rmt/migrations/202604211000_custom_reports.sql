@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Saved report builder definitions. The definition only names semantic-layer
+-- dimensions and measures; SQL is compiled from it at run time.
+CREATE TABLE IF NOT EXISTS custom_reports (
+  report_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  name VARCHAR(150) NOT NULL,
+  description TEXT,
+  definition JSONB NOT NULL,
+  is_shared BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  is_deleted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_reports_company
+  ON custom_reports(company_id, created_by)
+  WHERE is_deleted = FALSE;
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('MANAGE_CUSTOM_REPORTS', 'Create and update saved custom reports', 'reports', 'manage'),
+  ('REPORT_ALL_LOCATIONS', 'Report across all locations regardless of assigned location', 'reports', 'all_locations')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'MANAGE_CUSTOM_REPORTS',
+    'REPORT_ALL_LOCATIONS'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS custom_reports;
+
+DELETE FROM permissions
+WHERE name IN ('MANAGE_CUSTOM_REPORTS', 'REPORT_ALL_LOCATIONS');
+-- +goose StatementEnd
@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A budget is one version of a fiscal year's plan. Versions of the same
+-- company/year/location/department share a lineage; at most one is ACTIVE.
+CREATE TABLE IF NOT EXISTS budgets (
+  budget_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  name VARCHAR(150) NOT NULL,
+  fiscal_year INTEGER NOT NULL CHECK (fiscal_year BETWEEN 2000 AND 2100),
+  start_month SMALLINT NOT NULL DEFAULT 1 CHECK (start_month BETWEEN 1 AND 12),
+  location_id INTEGER REFERENCES locations(location_id),
+  department_id INTEGER REFERENCES departments(department_id),
+  version_type VARCHAR(20) NOT NULL DEFAULT 'ORIGINAL' CHECK (version_type IN ('ORIGINAL', 'REVISED')),
+  version_no INTEGER NOT NULL DEFAULT 1,
+  revised_from_id INTEGER REFERENCES budgets(budget_id),
+  status VARCHAR(20) NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'ACTIVE', 'ARCHIVED')),
+  control_mode VARCHAR(10) NOT NULL DEFAULT 'NONE' CHECK (control_mode IN ('NONE', 'WARN', 'BLOCK')),
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  is_deleted BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budgets_one_active
+  ON budgets(company_id, fiscal_year, COALESCE(location_id, 0), COALESCE(department_id, 0))
+  WHERE status = 'ACTIVE' AND is_deleted = FALSE;
+CREATE INDEX IF NOT EXISTS idx_budgets_company_year
+  ON budgets(company_id, fiscal_year)
+  WHERE is_deleted = FALSE;
+
+-- period_month is the month of the fiscal year (1-12), not the calendar month.
+CREATE TABLE IF NOT EXISTS budget_lines (
+  budget_line_id SERIAL PRIMARY KEY,
+  budget_id INTEGER NOT NULL REFERENCES budgets(budget_id) ON DELETE CASCADE,
+  account_id INTEGER NOT NULL REFERENCES chart_of_accounts(account_id),
+  period_month SMALLINT NOT NULL CHECK (period_month BETWEEN 1 AND 12),
+  amount NUMERIC(14,2) NOT NULL DEFAULT 0,
+  UNIQUE (budget_id, account_id, period_month)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_lines_account
+  ON budget_lines(account_id);
+
+-- Lets department budgets see expenses; payroll is attributed through the
+-- employee's department.
+ALTER TABLE expenses
+  ADD COLUMN IF NOT EXISTS department_id INTEGER REFERENCES departments(department_id) ON DELETE SET NULL;
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('VIEW_BUDGETS', 'View budgets and budget vs actual', 'budgets', 'view'),
+  ('MANAGE_BUDGETS', 'Create, revise and activate budgets', 'budgets', 'manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'VIEW_BUDGETS',
+    'MANAGE_BUDGETS'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE expenses DROP COLUMN IF EXISTS department_id;
+DROP TABLE IF EXISTS budget_lines;
+DROP TABLE IF EXISTS budgets;
+
+DELETE FROM permissions
+WHERE name IN ('VIEW_BUDGETS', 'MANAGE_BUDGETS');
+-- +goose StatementEnd
@@ -0,0 +1,94 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Company-defined analytic dimensions (cost center, project, ...) and their values.
+CREATE TABLE IF NOT EXISTS analytic_dimensions (
+  dimension_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  code VARCHAR(30) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, code)
+);
+
+CREATE TABLE IF NOT EXISTS analytic_dimension_values (
+  value_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  dimension_id INTEGER NOT NULL REFERENCES analytic_dimensions(dimension_id) ON DELETE CASCADE,
+  code VARCHAR(30) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (dimension_id, code)
+);
+
+-- Default value per dimension for documents from a location, department,
+-- employee or expense category. source_id points at that table's key.
+CREATE TABLE IF NOT EXISTS analytic_dimension_defaults (
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  dimension_id INTEGER NOT NULL REFERENCES analytic_dimensions(dimension_id) ON DELETE CASCADE,
+  source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('LOCATION', 'DEPARTMENT', 'EMPLOYEE', 'EXPENSE_CATEGORY')),
+  source_id INTEGER NOT NULL,
+  value_id INTEGER NOT NULL REFERENCES analytic_dimension_values(value_id) ON DELETE CASCADE,
+  PRIMARY KEY (dimension_id, source_type, source_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytic_dimension_defaults_source
+  ON analytic_dimension_defaults(company_id, source_type, source_id);
+
+-- One value per dimension per ledger entry.
+CREATE TABLE IF NOT EXISTS ledger_entry_dimensions (
+  entry_id INTEGER NOT NULL REFERENCES ledger_entries(entry_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  dimension_id INTEGER NOT NULL REFERENCES analytic_dimensions(dimension_id),
+  value_id INTEGER NOT NULL REFERENCES analytic_dimension_values(value_id),
+  PRIMARY KEY (entry_id, dimension_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entry_dimensions_value
+  ON ledger_entry_dimensions(value_id, entry_id);
+
+-- Tagging changes ledger report results, so it moves the ledger data version
+-- that cached report jobs are keyed on.
+DROP TRIGGER IF EXISTS trg_ledger_entry_dimensions_report_change_ins ON ledger_entry_dimensions;
+DROP TRIGGER IF EXISTS trg_ledger_entry_dimensions_report_change_del ON ledger_entry_dimensions;
+CREATE TRIGGER trg_ledger_entry_dimensions_report_change_ins AFTER INSERT ON ledger_entry_dimensions
+  REFERENCING NEW TABLE AS changed_rows FOR EACH STATEMENT
+  EXECUTE FUNCTION record_report_data_change('ledger', 'company');
+CREATE TRIGGER trg_ledger_entry_dimensions_report_change_del AFTER DELETE ON ledger_entry_dimensions
+  REFERENCING OLD TABLE AS changed_rows FOR EACH STATEMENT
+  EXECUTE FUNCTION record_report_data_change('ledger', 'company');
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('VIEW_ANALYTIC_DIMENSIONS', 'View analytic dimensions and defaults', 'accounting', 'view'),
+  ('MANAGE_ANALYTIC_DIMENSIONS', 'Manage analytic dimensions, values and defaults', 'accounting', 'manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'VIEW_ANALYTIC_DIMENSIONS',
+    'MANAGE_ANALYTIC_DIMENSIONS'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ledger_entry_dimensions;
+DROP TABLE IF EXISTS analytic_dimension_defaults;
+DROP TABLE IF EXISTS analytic_dimension_values;
+DROP TABLE IF EXISTS analytic_dimensions;
+
+DELETE FROM permissions
+WHERE name IN ('VIEW_ANALYTIC_DIMENSIONS', 'MANAGE_ANALYTIC_DIMENSIONS');
+-- +goose StatementEnd
@@ -0,0 +1,96 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Templates that create a voucher or expense on a schedule. payload holds the
+-- saved create request; each occurrence replays it with its own date, amount
+-- and idempotency key. next_occurrence is the schedule index of next_run_date.
+CREATE TABLE IF NOT EXISTS recurring_templates (
+  template_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  name VARCHAR(150) NOT NULL,
+  document_type VARCHAR(10) NOT NULL CHECK (document_type IN ('VOUCHER', 'EXPENSE')),
+  voucher_type VARCHAR(10) CHECK (voucher_type IN ('payment', 'receipt', 'journal')),
+  location_id INTEGER REFERENCES locations(location_id),
+  frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('DAILY', 'WEEKLY', 'MONTHLY', 'QUARTERLY', 'YEARLY')),
+  interval_count INTEGER NOT NULL DEFAULT 1 CHECK (interval_count > 0),
+  start_date DATE NOT NULL,
+  end_date DATE,
+  next_run_date DATE,
+  next_occurrence INTEGER NOT NULL DEFAULT 0,
+  amount_mode VARCHAR(10) NOT NULL DEFAULT 'FIXED' CHECK (amount_mode IN ('FIXED', 'FORMULA')),
+  amount_formula VARCHAR(500),
+  auto_reverse BOOLEAN NOT NULL DEFAULT FALSE,
+  payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  last_run_date DATE,
+  last_status VARCHAR(20),
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CHECK (end_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_templates_due
+  ON recurring_templates(next_run_date)
+  WHERE is_active = TRUE;
+CREATE INDEX IF NOT EXISTS idx_recurring_templates_company
+  ON recurring_templates(company_id);
+
+-- One row per occurrence, so an occurrence is never created twice. Reversal
+-- columns track the auto-reversal of accrual vouchers.
+CREATE TABLE IF NOT EXISTS recurring_template_runs (
+  run_id SERIAL PRIMARY KEY,
+  template_id INTEGER NOT NULL REFERENCES recurring_templates(template_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  occurrence_date DATE NOT NULL,
+  occurrence_no INTEGER NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'RUNNING'
+    CHECK (status IN ('RUNNING', 'CREATED', 'SKIPPED', 'FAILED')),
+  amount NUMERIC(14,2),
+  document_id INTEGER,
+  message TEXT,
+  reversal_date DATE,
+  reversal_status VARCHAR(20) CHECK (reversal_status IN ('PENDING', 'CREATED', 'FAILED')),
+  reversal_voucher_id INTEGER,
+  reversal_message TEXT,
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP,
+  UNIQUE (template_id, occurrence_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_template_runs_template
+  ON recurring_template_runs(template_id, occurrence_date DESC);
+CREATE INDEX IF NOT EXISTS idx_recurring_template_runs_running
+  ON recurring_template_runs(started_at)
+  WHERE status = 'RUNNING';
+CREATE INDEX IF NOT EXISTS idx_recurring_template_runs_reversal_due
+  ON recurring_template_runs(reversal_date)
+  WHERE reversal_status = 'PENDING';
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('VIEW_RECURRING_TEMPLATES', 'View recurring voucher and expense templates and their run log', 'accounting', 'view'),
+  ('MANAGE_RECURRING_TEMPLATES', 'Manage recurring voucher and expense templates', 'accounting', 'manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'VIEW_RECURRING_TEMPLATES',
+    'MANAGE_RECURRING_TEMPLATES'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS recurring_template_runs;
+DROP TABLE IF EXISTS recurring_templates;
+
+DELETE FROM permissions
+WHERE name IN ('VIEW_RECURRING_TEMPLATES', 'MANAGE_RECURRING_TEMPLATES');
+-- +goose StatementEnd
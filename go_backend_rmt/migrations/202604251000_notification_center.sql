@@ -0,0 +1,105 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Notifications are stored per recipient when a domain event happens instead
+-- of being recomputed on every read. dedupe_key makes an event fire once per
+-- user (e.g. one alert per expiring lot).
+CREATE TABLE IF NOT EXISTS notifications (
+  notification_id BIGSERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  event_type VARCHAR(50) NOT NULL,
+  title VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL DEFAULT '',
+  severity VARCHAR(10) NOT NULL DEFAULT 'INFO' CHECK (severity IN ('INFO', 'WARNING', 'CRITICAL')),
+  entity_type VARCHAR(50),
+  entity_id INTEGER,
+  location_id INTEGER REFERENCES locations(location_id) ON DELETE SET NULL,
+  action_label VARCHAR(100),
+  due_at TIMESTAMP,
+  dedupe_key VARCHAR(200),
+  in_app BOOLEAN NOT NULL DEFAULT TRUE,
+  read_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedupe
+  ON notifications(company_id, user_id, dedupe_key)
+  WHERE dedupe_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created
+  ON notifications(company_id, user_id, created_at DESC)
+  WHERE in_app = TRUE;
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+  ON notifications(company_id, user_id)
+  WHERE in_app = TRUE AND read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_created
+  ON notifications(created_at);
+
+-- Per user and event type channel choices. Missing rows use the event's
+-- defaults.
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  event_type VARCHAR(50) NOT NULL,
+  in_app BOOLEAN NOT NULL DEFAULT TRUE,
+  email BOOLEAN NOT NULL DEFAULT FALSE,
+  webhook BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (company_id, user_id, event_type)
+);
+
+CREATE TABLE IF NOT EXISTS notification_user_settings (
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  email_digest VARCHAR(10) NOT NULL DEFAULT 'NONE' CHECK (email_digest IN ('NONE', 'HOURLY', 'DAILY')),
+  webhook_url VARCHAR(500),
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (company_id, user_id)
+);
+
+-- Email and webhook sends. Digest emails hold deliveries until send_after and
+-- send everything due for the user in one message.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+  delivery_id BIGSERIAL PRIMARY KEY,
+  notification_id BIGINT NOT NULL REFERENCES notifications(notification_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  channel VARCHAR(10) NOT NULL CHECK (channel IN ('EMAIL', 'WEBHOOK')),
+  status VARCHAR(10) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SENT', 'FAILED')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  send_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  sent_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_due
+  ON notification_deliveries(send_after)
+  WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_notification_deliveries_user
+  ON notification_deliveries(user_id, channel)
+  WHERE status = 'PENDING';
+
+-- Read state now lives on the notification rows.
+DROP TABLE IF EXISTS notification_reads;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notification_reads (
+  company_id INTEGER NOT NULL REFERENCES companies(company_id),
+  user_id INTEGER NOT NULL REFERENCES users(user_id),
+  notification_key TEXT NOT NULL,
+  read_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (company_id, user_id, notification_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_reads_user_company
+  ON notification_reads(company_id, user_id);
+
+DROP TABLE IF EXISTS notification_deliveries;
+DROP TABLE IF EXISTS notification_user_settings;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
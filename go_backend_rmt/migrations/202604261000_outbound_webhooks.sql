@@ -0,0 +1,103 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Endpoints a company sends business events to. An empty event_types
+-- receives everything. previous_secret keeps signing until
+-- previous_secret_expires_at after a rotation.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+  subscription_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  name VARCHAR(150) NOT NULL,
+  url VARCHAR(500) NOT NULL,
+  event_types TEXT[] NOT NULL DEFAULT '{}',
+  secret VARCHAR(100) NOT NULL,
+  previous_secret VARCHAR(100),
+  previous_secret_expires_at TIMESTAMP,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_company
+  ON webhook_subscriptions(company_id)
+  WHERE is_active = TRUE;
+
+-- Events are written in the transaction of the change they announce, and
+-- only when an active subscription wants them.
+CREATE TABLE IF NOT EXISTS webhook_events (
+  event_id BIGSERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  event_type VARCHAR(50) NOT NULL,
+  resource_type VARCHAR(50) NOT NULL,
+  resource_id INTEGER NOT NULL,
+  data JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_company_created
+  ON webhook_events(company_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  delivery_id BIGSERIAL PRIMARY KEY,
+  event_id BIGINT NOT NULL REFERENCES webhook_events(event_id) ON DELETE CASCADE,
+  subscription_id INTEGER NOT NULL REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  status VARCHAR(10) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SUCCEEDED', 'DEAD')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_status_code INTEGER,
+  last_error TEXT,
+  delivered_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (event_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+  ON webhook_deliveries(next_attempt_at)
+  WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_company
+  ON webhook_deliveries(company_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+  attempt_id BIGSERIAL PRIMARY KEY,
+  delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(delivery_id) ON DELETE CASCADE,
+  status_code INTEGER,
+  error TEXT,
+  response_body TEXT,
+  duration_ms INTEGER NOT NULL DEFAULT 0,
+  attempted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery
+  ON webhook_delivery_attempts(delivery_id);
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('VIEW_WEBHOOKS', 'View webhook subscriptions and delivery logs', 'settings', 'view'),
+  ('MANAGE_WEBHOOKS', 'Manage webhook subscriptions, secrets and redeliveries', 'settings', 'manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'VIEW_WEBHOOKS',
+    'MANAGE_WEBHOOKS'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_events;
+DROP TABLE IF EXISTS webhook_subscriptions;
+
+DELETE FROM permissions
+WHERE name IN ('VIEW_WEBHOOKS', 'MANAGE_WEBHOOKS');
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Company-scoped keys for machine-to-machine integrations. Only a SHA-256
+-- hash of the key is stored; key_prefix is the public part that identifies
+-- it. Each key acts through its own service account (user_id) so created_by
+-- columns and audit entries name the key, never a person. After a rotation
+-- previous_key_hash keeps working until previous_key_expires_at.
+CREATE TABLE IF NOT EXISTS api_keys (
+  api_key_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL UNIQUE REFERENCES users(user_id),
+  name VARCHAR(150) NOT NULL,
+  key_prefix VARCHAR(30) NOT NULL UNIQUE,
+  key_hash VARCHAR(64) NOT NULL,
+  previous_key_hash VARCHAR(64),
+  previous_key_expires_at TIMESTAMP,
+  permissions TEXT[] NOT NULL DEFAULT '{}',
+  location_id INTEGER REFERENCES locations(location_id),
+  ip_allowlist TEXT[] NOT NULL DEFAULT '{}',
+  rate_limit_per_minute INTEGER CHECK (rate_limit_per_minute > 0),
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP,
+  last_used_ip VARCHAR(45),
+  revoked_at TIMESTAMP,
+  revoked_by INTEGER REFERENCES users(user_id),
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_company
+  ON api_keys(company_id);
+
+ALTER TABLE audit_log
+  ADD COLUMN IF NOT EXISTS api_key_id INTEGER REFERENCES api_keys(api_key_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_api_key
+  ON audit_log(api_key_id)
+  WHERE api_key_id IS NOT NULL;
+
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('VIEW_API_KEYS', 'View API keys and their usage', 'settings', 'view'),
+  ('MANAGE_API_KEYS', 'Create, rotate and revoke API keys', 'settings', 'manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.name IN ('Super Admin', 'Admin')
+  AND p.name IN (
+    'VIEW_API_KEYS',
+    'MANAGE_API_KEYS'
+  )
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE users
+SET is_active = FALSE, is_deleted = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE user_id IN (SELECT user_id FROM api_keys);
+
+DROP INDEX IF EXISTS idx_audit_log_api_key;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS api_key_id;
+DROP TABLE IF EXISTS api_keys;
+
+DELETE FROM permissions
+WHERE name IN ('VIEW_API_KEYS', 'MANAGE_API_KEYS');
+-- +goose StatementEnd
@@ -0,0 +1,126 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Roles belong to a company. Roles without a company are templates: every
+-- company gets its own copy (template_role_id) when it is created, and the
+-- copies can then be edited without affecting other companies.
+ALTER TABLE roles
+  ADD COLUMN IF NOT EXISTS company_id INTEGER REFERENCES companies(company_id) ON DELETE CASCADE;
+ALTER TABLE roles
+  ADD COLUMN IF NOT EXISTS template_role_id INTEGER REFERENCES roles(role_id) ON DELETE SET NULL;
+
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_roles_template_name
+  ON roles(name)
+  WHERE company_id IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_roles_company_name
+  ON roles(company_id, name)
+  WHERE company_id IS NOT NULL;
+
+-- Existing companies shared the global roles; give each its own copy of
+-- every one of them and move references over. Copies are editable except
+-- the admin roles, which grant access by name.
+INSERT INTO roles (company_id, template_role_id, name, description, is_system_role, created_at)
+SELECT c.company_id, r.role_id, r.name, r.description,
+       r.is_system_role AND LOWER(r.name) IN ('super admin', 'admin'), r.created_at
+FROM companies c
+CROSS JOIN roles r
+WHERE r.company_id IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT cr.role_id, rp.permission_id
+FROM roles cr
+JOIN role_permissions rp ON rp.role_id = cr.template_role_id
+WHERE cr.company_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_pos_limits (role_id, max_line_discount_pct, max_bill_discount_pct)
+SELECT cr.role_id, l.max_line_discount_pct, l.max_bill_discount_pct
+FROM roles cr
+JOIN role_pos_limits l ON l.role_id = cr.template_role_id
+WHERE cr.company_id IS NOT NULL
+ON CONFLICT (role_id) DO NOTHING;
+
+UPDATE users u
+SET role_id = cr.role_id
+FROM roles cr
+WHERE cr.template_role_id = u.role_id
+  AND cr.company_id = u.company_id;
+
+UPDATE designations d
+SET default_app_role_id = cr.role_id
+FROM roles cr
+WHERE cr.template_role_id = d.default_app_role_id
+  AND cr.company_id = d.company_id;
+
+UPDATE workflow_requests w
+SET approver_role_id = cr.role_id
+FROM roles cr
+WHERE cr.template_role_id = w.approver_role_id
+  AND cr.company_id = w.company_id;
+
+-- Locations a user may work in. role_id, when set, replaces the user's own
+-- role while that location is active. users.location_id stays the default
+-- location and always has a row here.
+CREATE TABLE IF NOT EXISTS user_locations (
+  user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id) ON DELETE CASCADE,
+  role_id INTEGER REFERENCES roles(role_id) ON DELETE SET NULL,
+  created_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, location_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_locations_location
+  ON user_locations(location_id);
+
+INSERT INTO user_locations (user_id, location_id)
+SELECT user_id, location_id
+FROM users
+WHERE location_id IS NOT NULL AND is_deleted = FALSE
+ON CONFLICT DO NOTHING;
+
+-- The location a session is working in; NULL means the user's default.
+ALTER TABLE device_sessions
+  ADD COLUMN IF NOT EXISTS active_location_id INTEGER REFERENCES locations(location_id) ON DELETE SET NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE device_sessions DROP COLUMN IF EXISTS active_location_id;
+DROP TABLE IF EXISTS user_locations;
+
+UPDATE users u
+SET role_id = cr.template_role_id
+FROM roles cr
+WHERE cr.role_id = u.role_id
+  AND cr.template_role_id IS NOT NULL;
+
+UPDATE designations d
+SET default_app_role_id = cr.template_role_id
+FROM roles cr
+WHERE cr.role_id = d.default_app_role_id
+  AND cr.template_role_id IS NOT NULL;
+
+UPDATE workflow_requests w
+SET approver_role_id = cr.template_role_id
+FROM roles cr
+WHERE cr.role_id = w.approver_role_id
+  AND cr.template_role_id IS NOT NULL;
+
+UPDATE users
+SET role_id = NULL
+WHERE role_id IN (SELECT role_id FROM roles WHERE company_id IS NOT NULL);
+
+DELETE FROM roles WHERE company_id IS NOT NULL;
+
+DROP INDEX IF EXISTS ux_roles_company_name;
+DROP INDEX IF EXISTS ux_roles_template_name;
+ALTER TABLE roles ADD CONSTRAINT roles_name_key UNIQUE (name);
+ALTER TABLE roles DROP COLUMN IF EXISTS template_role_id;
+ALTER TABLE roles DROP COLUMN IF EXISTS company_id;
+-- +goose StatementEnd
@@ -0,0 +1,92 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Transfers are received line by line: whatever did not arrive in good
+-- condition is recorded as short or damaged instead of being received.
+ALTER TABLE stock_transfer_details
+  ADD COLUMN IF NOT EXISTS short_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS damaged_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS received_serial_numbers TEXT[],
+  ADD COLUMN IF NOT EXISTS damaged_serial_numbers TEXT[],
+  ADD COLUMN IF NOT EXISTS transit_value NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE stock_transfers
+  ADD COLUMN IF NOT EXISTS transit_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS received_by INTEGER REFERENCES users(user_id),
+  ADD COLUMN IF NOT EXISTS received_at TIMESTAMP;
+
+-- A discrepancy is raised when a receipt is short or damaged. Its value stays
+-- in the in-transit account until the discrepancy is approved or rejected.
+CREATE TABLE IF NOT EXISTS stock_transfer_discrepancies (
+  discrepancy_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  transfer_id INTEGER NOT NULL UNIQUE REFERENCES stock_transfers(transfer_id) ON DELETE CASCADE,
+  status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+  short_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  damaged_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  shortage_location_id INTEGER REFERENCES locations(location_id),
+  approval_id INTEGER REFERENCES workflow_requests(approval_id),
+  notes TEXT,
+  resolved_by INTEGER REFERENCES users(user_id),
+  resolved_at TIMESTAMP,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  updated_by INTEGER REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfer_discrepancies_company_status
+  ON stock_transfer_discrepancies(company_id, status);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_discrepancy_lines (
+  discrepancy_line_id SERIAL PRIMARY KEY,
+  discrepancy_id INTEGER NOT NULL REFERENCES stock_transfer_discrepancies(discrepancy_id) ON DELETE CASCADE,
+  transfer_detail_id INTEGER NOT NULL REFERENCES stock_transfer_details(transfer_detail_id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  short_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  damaged_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  unit_cost NUMERIC(12,4) NOT NULL DEFAULT 0,
+  short_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  damaged_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  short_serial_numbers TEXT[],
+  damaged_serial_numbers TEXT[],
+  reason TEXT,
+  UNIQUE (discrepancy_id, transfer_detail_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_transfer_discrepancy_lines_discrepancy
+  ON stock_transfer_discrepancy_lines(discrepancy_id);
+
+INSERT INTO chart_of_accounts (company_id, account_code, name, type, subtype, is_active)
+SELECT c.company_id, v.account_code, v.name, v.type, v.subtype, TRUE
+FROM companies c
+JOIN (
+  VALUES
+    ('1220', 'Inventory In Transit', 'ASSET', 'INVENTORY_IN_TRANSIT'),
+    ('5010', 'Inventory Shrinkage', 'EXPENSE', 'INVENTORY_SHRINKAGE')
+) AS v(account_code, name, type, subtype) ON TRUE
+ON CONFLICT (company_id, account_code) DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_stock_transfer_discrepancy_lines_discrepancy;
+DROP TABLE IF EXISTS stock_transfer_discrepancy_lines;
+DROP INDEX IF EXISTS idx_stock_transfer_discrepancies_company_status;
+DROP TABLE IF EXISTS stock_transfer_discrepancies;
+
+ALTER TABLE stock_transfers
+  DROP COLUMN IF EXISTS received_at,
+  DROP COLUMN IF EXISTS received_by,
+  DROP COLUMN IF EXISTS transit_value;
+
+ALTER TABLE stock_transfer_details
+  DROP COLUMN IF EXISTS transit_value,
+  DROP COLUMN IF EXISTS damaged_serial_numbers,
+  DROP COLUMN IF EXISTS received_serial_numbers,
+  DROP COLUMN IF EXISTS damaged_quantity,
+  DROP COLUMN IF EXISTS short_quantity;
+
+-- +goose StatementEnd
@@ -0,0 +1,87 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Quarantined stock leaves remaining_quantity so it can no longer be issued,
+-- but stays on the lot for traceability.
+ALTER TABLE stock_lots
+  ADD COLUMN IF NOT EXISTS quarantined_quantity NUMERIC(10,3) NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMP;
+
+ALTER TABLE product_serials DROP CONSTRAINT IF EXISTS chk_product_serials_status;
+ALTER TABLE product_serials
+  ADD CONSTRAINT chk_product_serials_status
+  CHECK (status IN ('IN_STOCK', 'SOLD', 'RETURNED', 'TRANSFER_IN_TRANSIT', 'ADJUSTED_OUT', 'VOID', 'QUARANTINED'));
+
+-- An expiry write-off quarantines lots and posts their cost as a loss.
+CREATE TABLE IF NOT EXISTS stock_expiry_write_offs (
+  write_off_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  reason TEXT,
+  total_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  total_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_expiry_write_offs_company_location
+  ON stock_expiry_write_offs(company_id, location_id, created_at);
+
+CREATE TABLE IF NOT EXISTS stock_expiry_write_off_lines (
+  write_off_line_id SERIAL PRIMARY KEY,
+  write_off_id INTEGER NOT NULL REFERENCES stock_expiry_write_offs(write_off_id) ON DELETE CASCADE,
+  lot_id INTEGER NOT NULL REFERENCES stock_lots(lot_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  batch_number VARCHAR(100),
+  expiry_date DATE,
+  quantity NUMERIC(12,3) NOT NULL,
+  unit_cost NUMERIC(12,4) NOT NULL DEFAULT 0,
+  total_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  serial_numbers TEXT[],
+  UNIQUE (write_off_id, lot_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_expiry_write_off_lines_write_off
+  ON stock_expiry_write_off_lines(write_off_id);
+
+-- Manager override scope for selling expired lots at POS.
+INSERT INTO permissions (name, description, module, action) VALUES
+  ('SELL_EXPIRED_STOCK', 'Approve the sale of expired batches', 'pos', 'sell_expired')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.role_id, p.permission_id
+FROM roles r
+JOIN permissions p ON p.name = 'SELL_EXPIRED_STOCK'
+WHERE r.name IN ('Super Admin', 'Admin', 'Manager')
+ON CONFLICT DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM role_permissions rp
+USING permissions p
+WHERE rp.permission_id = p.permission_id AND p.name = 'SELL_EXPIRED_STOCK';
+
+DELETE FROM permissions
+WHERE name = 'SELL_EXPIRED_STOCK';
+
+DROP INDEX IF EXISTS idx_stock_expiry_write_off_lines_write_off;
+DROP TABLE IF EXISTS stock_expiry_write_off_lines;
+DROP INDEX IF EXISTS idx_stock_expiry_write_offs_company_location;
+DROP TABLE IF EXISTS stock_expiry_write_offs;
+
+UPDATE product_serials SET status = 'ADJUSTED_OUT' WHERE status = 'QUARANTINED';
+ALTER TABLE product_serials DROP CONSTRAINT IF EXISTS chk_product_serials_status;
+ALTER TABLE product_serials
+  ADD CONSTRAINT chk_product_serials_status
+  CHECK (status IN ('IN_STOCK', 'SOLD', 'RETURNED', 'TRANSFER_IN_TRANSIT', 'ADJUSTED_OUT', 'VOID'));
+
+ALTER TABLE stock_lots
+  DROP COLUMN IF EXISTS quarantined_at,
+  DROP COLUMN IF EXISTS quarantined_quantity;
+
+-- +goose StatementEnd
@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Variant dimensions are SELECT attributes (size, colour, ...) on a parent
+-- product. Their options expand into a matrix of child barcodes.
+CREATE TABLE IF NOT EXISTS product_variant_dimensions (
+  dimension_id SERIAL PRIMARY KEY,
+  product_id INTEGER NOT NULL REFERENCES products(product_id) ON DELETE CASCADE,
+  attribute_id INTEGER NOT NULL REFERENCES product_attributes(attribute_id),
+  position INTEGER NOT NULL DEFAULT 0,
+  follow_attribute_options BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (product_id, attribute_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variant_dimensions_attribute
+  ON product_variant_dimensions(attribute_id);
+
+-- Per-option SKU code and price/cost deltas. Options of a dimension that
+-- follows its attribute and are not listed here use defaults.
+CREATE TABLE IF NOT EXISTS product_variant_dimension_options (
+  option_id SERIAL PRIMARY KEY,
+  dimension_id INTEGER NOT NULL REFERENCES product_variant_dimensions(dimension_id) ON DELETE CASCADE,
+  value VARCHAR(255) NOT NULL,
+  code VARCHAR(20) NOT NULL,
+  price_delta NUMERIC(12,2) NOT NULL DEFAULT 0,
+  cost_delta NUMERIC(12,2) NOT NULL DEFAULT 0,
+  position INTEGER NOT NULL DEFAULT 0,
+  UNIQUE (dimension_id, value)
+);
+
+-- Generated barcodes carry their own SKU and the option combination they
+-- were built from so later syncs update them in place.
+ALTER TABLE product_barcodes
+  ADD COLUMN IF NOT EXISTS sku VARCHAR(100),
+  ADD COLUMN IF NOT EXISTS matrix_key VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcodes_matrix_key
+  ON product_barcodes(product_id, matrix_key)
+  WHERE matrix_key IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_product_barcodes_matrix_key;
+
+ALTER TABLE product_barcodes
+  DROP COLUMN IF EXISTS matrix_key,
+  DROP COLUMN IF EXISTS sku;
+
+DROP TABLE IF EXISTS product_variant_dimension_options;
+DROP INDEX IF EXISTS idx_product_variant_dimensions_attribute;
+DROP TABLE IF EXISTS product_variant_dimensions;
+
+-- +goose StatementEnd
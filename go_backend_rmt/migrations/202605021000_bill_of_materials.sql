@@ -0,0 +1,118 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A bill of materials lists the components that make output_quantity units
+-- of a finished good. BOMs are versioned per product and only one version
+-- per finished variant is active at a time; versions are never edited.
+CREATE TABLE IF NOT EXISTS bill_of_materials (
+  bom_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  version INTEGER NOT NULL,
+  name VARCHAR(255),
+  output_quantity NUMERIC(12,3) NOT NULL DEFAULT 1 CHECK (output_quantity > 0),
+  labor_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  overhead_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  is_active BOOLEAN NOT NULL DEFAULT FALSE,
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, product_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bill_of_materials_active
+  ON bill_of_materials(product_id, COALESCE(barcode_id, 0))
+  WHERE is_active = TRUE;
+
+-- Component quantities are per BOM output in the line unit; stock_factor
+-- converts them to the component's stock unit.
+CREATE TABLE IF NOT EXISTS bom_components (
+  bom_component_id SERIAL PRIMARY KEY,
+  bom_id INTEGER NOT NULL REFERENCES bill_of_materials(bom_id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  quantity NUMERIC(12,4) NOT NULL CHECK (quantity > 0),
+  unit_id INTEGER REFERENCES units(unit_id),
+  stock_factor NUMERIC(12,6) NOT NULL DEFAULT 1,
+  scrap_percent NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (scrap_percent >= 0 AND scrap_percent < 100),
+  position INTEGER NOT NULL DEFAULT 0,
+  notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_bom_components_bom
+  ON bom_components(bom_id);
+
+-- Assembly orders consume components and receive the finished good;
+-- disassembly orders run the other way. Costs are fixed on completion.
+CREATE TABLE IF NOT EXISTS assembly_orders (
+  assembly_order_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  order_number VARCHAR(50) NOT NULL,
+  order_type VARCHAR(20) NOT NULL CHECK (order_type IN ('ASSEMBLY', 'DISASSEMBLY')),
+  status VARCHAR(20) NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'COMPLETED', 'CANCELLED')),
+  bom_id INTEGER NOT NULL REFERENCES bill_of_materials(bom_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+  labor_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  overhead_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  input_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  output_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  batch_number VARCHAR(100),
+  expiry_date DATE,
+  serial_numbers TEXT[],
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  completed_by INTEGER REFERENCES users(user_id),
+  completed_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assembly_orders_company_status
+  ON assembly_orders(company_id, status, created_at);
+
+CREATE TABLE IF NOT EXISTS assembly_order_lines (
+  assembly_order_line_id SERIAL PRIMARY KEY,
+  assembly_order_id INTEGER NOT NULL REFERENCES assembly_orders(assembly_order_id) ON DELETE CASCADE,
+  bom_component_id INTEGER REFERENCES bom_components(bom_component_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+  scrap_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  unit_cost NUMERIC(12,4) NOT NULL DEFAULT 0,
+  total_cost NUMERIC(12,2) NOT NULL DEFAULT 0,
+  serial_numbers TEXT[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_assembly_order_lines_order
+  ON assembly_order_lines(assembly_order_id);
+
+INSERT INTO chart_of_accounts (company_id, account_code, name, type, subtype, is_active)
+SELECT c.company_id, v.account_code, v.name, v.type, v.subtype, TRUE
+FROM companies c
+JOIN (
+  VALUES
+    ('1230', 'Work In Progress', 'ASSET', 'WORK_IN_PROGRESS'),
+    ('5020', 'Applied Labor & Overhead', 'EXPENSE', 'APPLIED_OVERHEAD')
+) AS v(account_code, name, type, subtype) ON TRUE
+ON CONFLICT (company_id, account_code) DO NOTHING;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_assembly_order_lines_order;
+DROP TABLE IF EXISTS assembly_order_lines;
+DROP INDEX IF EXISTS idx_assembly_orders_company_status;
+DROP TABLE IF EXISTS assembly_orders;
+DROP INDEX IF EXISTS idx_bom_components_bom;
+DROP TABLE IF EXISTS bom_components;
+DROP INDEX IF EXISTS idx_bill_of_materials_active;
+DROP TABLE IF EXISTS bill_of_materials;
+
+-- +goose StatementEnd
@@ -0,0 +1,101 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A landed cost voucher spreads freight, duty and clearing charges billed by
+-- third-party suppliers over the lines of one or more goods receipts. Each
+-- charge is payable to its own supplier; the goods purchases are not touched.
+CREATE TABLE IF NOT EXISTS landed_cost_vouchers (
+  voucher_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  voucher_number VARCHAR(100) NOT NULL,
+  voucher_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  allocation_method VARCHAR(20) NOT NULL CHECK (allocation_method IN ('VALUE', 'QUANTITY', 'WEIGHT', 'VOLUME')),
+  reference_number VARCHAR(100),
+  notes TEXT,
+  total_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  inventory_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  cogs_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, voucher_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_landed_cost_vouchers_company_date
+  ON landed_cost_vouchers(company_id, voucher_date DESC);
+
+CREATE TABLE IF NOT EXISTS landed_cost_voucher_receipts (
+  voucher_id INTEGER NOT NULL REFERENCES landed_cost_vouchers(voucher_id) ON DELETE CASCADE,
+  goods_receipt_id INTEGER NOT NULL REFERENCES goods_receipts(goods_receipt_id),
+  PRIMARY KEY (voucher_id, goods_receipt_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_landed_cost_voucher_receipts_receipt
+  ON landed_cost_voucher_receipts(goods_receipt_id);
+
+CREATE TABLE IF NOT EXISTS landed_cost_charges (
+  charge_id SERIAL PRIMARY KEY,
+  voucher_id INTEGER NOT NULL REFERENCES landed_cost_vouchers(voucher_id) ON DELETE CASCADE,
+  supplier_id INTEGER NOT NULL REFERENCES suppliers(supplier_id),
+  label VARCHAR(255) NOT NULL,
+  invoice_number VARCHAR(100),
+  allocation_method VARCHAR(20) NOT NULL CHECK (allocation_method IN ('VALUE', 'QUANTITY', 'WEIGHT', 'VOLUME')),
+  amount NUMERIC(12,2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_landed_cost_charges_voucher
+  ON landed_cost_charges(voucher_id);
+CREATE INDEX IF NOT EXISTS idx_landed_cost_charges_supplier
+  ON landed_cost_charges(supplier_id);
+
+-- One row per charge and receipt line. inventory_amount went onto lots still
+-- on hand; cogs_amount is the share of quantities already consumed.
+CREATE TABLE IF NOT EXISTS landed_cost_allocations (
+  allocation_id SERIAL PRIMARY KEY,
+  voucher_id INTEGER NOT NULL REFERENCES landed_cost_vouchers(voucher_id) ON DELETE CASCADE,
+  charge_id INTEGER NOT NULL REFERENCES landed_cost_charges(charge_id) ON DELETE CASCADE,
+  goods_receipt_item_id INTEGER NOT NULL REFERENCES goods_receipt_items(goods_receipt_item_id),
+  purchase_detail_id INTEGER REFERENCES purchase_details(purchase_detail_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  stock_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  basis NUMERIC(14,4) NOT NULL DEFAULT 0,
+  amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  inventory_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  cogs_amount NUMERIC(12,2) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_landed_cost_allocations_voucher
+  ON landed_cost_allocations(voucher_id);
+CREATE INDEX IF NOT EXISTS idx_landed_cost_allocations_receipt_item
+  ON landed_cost_allocations(goods_receipt_item_id);
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'landed_cost_voucher', 'LCV-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id
+    AND ns.location_id IS NULL
+    AND ns.name = 'landed_cost_voucher'
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM numbering_sequences WHERE name = 'landed_cost_voucher';
+DROP INDEX IF EXISTS idx_landed_cost_allocations_receipt_item;
+DROP INDEX IF EXISTS idx_landed_cost_allocations_voucher;
+DROP TABLE IF EXISTS landed_cost_allocations;
+DROP INDEX IF EXISTS idx_landed_cost_charges_supplier;
+DROP INDEX IF EXISTS idx_landed_cost_charges_voucher;
+DROP TABLE IF EXISTS landed_cost_charges;
+DROP INDEX IF EXISTS idx_landed_cost_voucher_receipts_receipt;
+DROP TABLE IF EXISTS landed_cost_voucher_receipts;
+DROP INDEX IF EXISTS idx_landed_cost_vouchers_company_date;
+DROP TABLE IF EXISTS landed_cost_vouchers;
+
+-- +goose StatementEnd
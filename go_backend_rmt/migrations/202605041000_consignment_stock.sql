@@ -0,0 +1,164 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Lots now record who owns them. CONSIGNMENT_IN stock belongs to the
+-- consignor supplier until it is sold; CONSIGNMENT_OUT stock sits at a dealer
+-- location but stays ours until the dealer reports the sale.
+ALTER TABLE stock_lots
+  ADD COLUMN IF NOT EXISTS ownership_type VARCHAR(20) NOT NULL DEFAULT 'OWNED',
+  ADD COLUMN IF NOT EXISTS consignor_supplier_id INTEGER REFERENCES suppliers(supplier_id),
+  ADD COLUMN IF NOT EXISTS consignee_customer_id INTEGER REFERENCES customers(customer_id);
+
+ALTER TABLE stock_lots DROP CONSTRAINT IF EXISTS stock_lots_ownership_type_check;
+ALTER TABLE stock_lots
+  ADD CONSTRAINT stock_lots_ownership_type_check
+  CHECK (ownership_type IN ('OWNED', 'CONSIGNMENT_IN', 'CONSIGNMENT_OUT'));
+
+CREATE INDEX IF NOT EXISTS idx_stock_lots_consignor
+  ON stock_lots(company_id, consignor_supplier_id)
+  WHERE ownership_type = 'CONSIGNMENT_IN';
+
+-- A location with a consignee customer is a dealer location: stock
+-- transferred there becomes consignment-out stock held by that customer.
+ALTER TABLE locations
+  ADD COLUMN IF NOT EXISTS consignee_customer_id INTEGER REFERENCES customers(customer_id);
+
+-- Dealer sales reports are invoiced as sales from the dealer location.
+ALTER TABLE sales
+  DROP CONSTRAINT IF EXISTS sales_source_channel_check;
+
+ALTER TABLE sales
+  ADD CONSTRAINT sales_source_channel_check
+  CHECK (
+    source_channel IS NULL
+    OR source_channel IN ('POS', 'INVOICE', 'QUOTE', 'POS_REFUND', 'SALES_ORDER', 'CONSIGNMENT')
+  );
+
+CREATE TABLE IF NOT EXISTS consignment_receipts (
+  consignment_receipt_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  supplier_id INTEGER NOT NULL REFERENCES suppliers(supplier_id),
+  receipt_number VARCHAR(100) NOT NULL,
+  receipt_date DATE NOT NULL DEFAULT CURRENT_DATE,
+  reference_number VARCHAR(100),
+  notes TEXT,
+  total_value NUMERIC(12,2) NOT NULL DEFAULT 0,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, receipt_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_receipts_supplier
+  ON consignment_receipts(company_id, supplier_id, receipt_date DESC);
+
+CREATE TABLE IF NOT EXISTS consignment_receipt_items (
+  consignment_receipt_item_id SERIAL PRIMARY KEY,
+  consignment_receipt_id INTEGER NOT NULL REFERENCES consignment_receipts(consignment_receipt_id) ON DELETE CASCADE,
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  quantity NUMERIC(12,3) NOT NULL CHECK (quantity > 0),
+  unit_cost NUMERIC(12,4) NOT NULL CHECK (unit_cost >= 0),
+  batch_number VARCHAR(100),
+  expiry_date DATE,
+  serial_numbers TEXT[]
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_receipt_items_receipt
+  ON consignment_receipt_items(consignment_receipt_id);
+
+-- A settlement bills the consignor for consignment-in stock sold up to
+-- period_end. purchase_id is the supplier invoice raised for it.
+CREATE TABLE IF NOT EXISTS consignment_settlements (
+  settlement_id SERIAL PRIMARY KEY,
+  company_id INTEGER NOT NULL REFERENCES companies(company_id) ON DELETE CASCADE,
+  location_id INTEGER NOT NULL REFERENCES locations(location_id),
+  supplier_id INTEGER NOT NULL REFERENCES suppliers(supplier_id),
+  settlement_number VARCHAR(100) NOT NULL,
+  period_start DATE,
+  period_end DATE NOT NULL,
+  total_quantity NUMERIC(12,3) NOT NULL DEFAULT 0,
+  total_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+  purchase_id INTEGER REFERENCES purchases(purchase_id),
+  notes TEXT,
+  created_by INTEGER NOT NULL REFERENCES users(user_id),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (company_id, settlement_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_settlements_supplier
+  ON consignment_settlements(company_id, supplier_id, period_end DESC);
+
+-- One row per sale movement; the unique movement keeps a sale from being
+-- billed twice.
+CREATE TABLE IF NOT EXISTS consignment_settlement_lines (
+  settlement_line_id SERIAL PRIMARY KEY,
+  settlement_id INTEGER NOT NULL REFERENCES consignment_settlements(settlement_id) ON DELETE CASCADE,
+  movement_id INTEGER NOT NULL REFERENCES inventory_movements(movement_id),
+  stock_lot_id INTEGER NOT NULL REFERENCES stock_lots(lot_id),
+  product_id INTEGER NOT NULL REFERENCES products(product_id),
+  barcode_id INTEGER REFERENCES product_barcodes(barcode_id),
+  quantity NUMERIC(12,3) NOT NULL,
+  unit_cost NUMERIC(12,4) NOT NULL,
+  amount NUMERIC(12,2) NOT NULL,
+  sold_at TIMESTAMP NOT NULL,
+  UNIQUE (movement_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_settlement_lines_settlement
+  ON consignment_settlement_lines(settlement_id);
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'consignment_receipt', 'CNR-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id
+    AND ns.location_id IS NULL
+    AND ns.name = 'consignment_receipt'
+);
+
+INSERT INTO numbering_sequences (company_id, location_id, name, prefix, sequence_length, current_number)
+SELECT c.company_id, NULL, 'consignment_settlement', 'CNS-', 6, 0
+FROM companies c
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM numbering_sequences ns
+  WHERE ns.company_id = c.company_id
+    AND ns.location_id IS NULL
+    AND ns.name = 'consignment_settlement'
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DELETE FROM numbering_sequences WHERE name IN ('consignment_receipt', 'consignment_settlement');
+DROP INDEX IF EXISTS idx_consignment_settlement_lines_settlement;
+DROP TABLE IF EXISTS consignment_settlement_lines;
+DROP INDEX IF EXISTS idx_consignment_settlements_supplier;
+DROP TABLE IF EXISTS consignment_settlements;
+DROP INDEX IF EXISTS idx_consignment_receipt_items_receipt;
+DROP TABLE IF EXISTS consignment_receipt_items;
+DROP INDEX IF EXISTS idx_consignment_receipts_supplier;
+DROP TABLE IF EXISTS consignment_receipts;
+ALTER TABLE sales
+  DROP CONSTRAINT IF EXISTS sales_source_channel_check;
+
+ALTER TABLE sales
+  ADD CONSTRAINT sales_source_channel_check
+  CHECK (
+    source_channel IS NULL
+    OR source_channel IN ('POS', 'INVOICE', 'QUOTE', 'POS_REFUND', 'SALES_ORDER')
+  );
+ALTER TABLE locations DROP COLUMN IF EXISTS consignee_customer_id;
+DROP INDEX IF EXISTS idx_stock_lots_consignor;
+ALTER TABLE stock_lots DROP CONSTRAINT IF EXISTS stock_lots_ownership_type_check;
+ALTER TABLE stock_lots
+  DROP COLUMN IF EXISTS consignee_customer_id,
+  DROP COLUMN IF EXISTS consignor_supplier_id,
+  DROP COLUMN IF EXISTS ownership_type;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Signing keys are sealed with EINVOICE_KEY_SECRET. Keys stored before this
+-- still hold their PEM in clear; the server seals each one the next time it
+-- signs with it.
+ALTER TABLE einvoice_signing_keys RENAME COLUMN private_key_pem TO private_key_sealed;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Keys sealed since stay sealed; rotate them after rolling back.
+ALTER TABLE einvoice_signing_keys RENAME COLUMN private_key_sealed TO private_key_pem;
+-- +goose StatementEnd